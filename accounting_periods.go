@@ -0,0 +1,184 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrPeriodLocked = errors.New("accounting period is locked")
+var ErrPeriodNotFound = errors.New("accounting period not found")
+var ErrPeriodChecklistFailed = errors.New("period close checklist has not passed")
+var ErrInvalidPeriodTransition = errors.New("invalid accounting period transition")
+
+// periodOverride is an admin's permission to post into soft-closed periods
+type periodOverride struct {
+	userID uint
+	reason string
+}
+
+// WithPeriodOverride returns a copy of the app that may post into soft-closed periods on behalf of an
+// admin. Every posting made through it into a soft-closed period is logged with the reason.
+func (a *App) WithPeriodOverride(userID uint, reason string) *App {
+	override := *a
+	override.periodOverride = &periodOverride{userID: userID, reason: reason}
+	return &override
+}
+
+// monthStart returns midnight UTC on the first of the month containing t
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// OpenAccountingPeriod creates the monthly period containing the given date, or returns it if it exists
+func (a *App) OpenAccountingPeriod(tenantID uint, on time.Time) (*AccountingPeriod, error) {
+	start := monthStart(on)
+	period := AccountingPeriod{
+		TenantID:    tenantID,
+		PeriodStart: start,
+		PeriodEnd:   start.AddDate(0, 1, 0),
+		State:       AccountingPeriodStateOpen.String(),
+	}
+	if err := a.DB.Where(AccountingPeriod{TenantID: tenantID, PeriodStart: start}).FirstOrCreate(&period).Error; err != nil {
+		return nil, fmt.Errorf("failed to open accounting period: %w", err)
+	}
+	return &period, nil
+}
+
+// ListAccountingPeriods returns the tenant's periods, most recent first
+func (a *App) ListAccountingPeriods(tenantID uint) ([]AccountingPeriod, error) {
+	var periods []AccountingPeriod
+	if err := a.DB.Scopes(TenantScope(tenantID)).Order("period_start desc").Find(&periods).Error; err != nil {
+		return nil, fmt.Errorf("failed to load accounting periods: %w", err)
+	}
+	return periods, nil
+}
+
+// checkPeriodLock decides whether a transaction may be posted on its effective date. Closed periods
+// refuse everything; soft-closed ones only accept postings made through WithPeriodOverride, in which
+// case the period is returned so the override can be logged.
+func (a *App) checkPeriodLock(db *gorm.DB, txn *JournalTransaction) (*AccountingPeriod, error) {
+	var period AccountingPeriod
+	if err := db.Scopes(TenantScope(txn.TenantID)).
+		Where("period_start <= ? AND period_end > ?", txn.EffectiveDate, txn.EffectiveDate).
+		Limit(1).Find(&period).Error; err != nil {
+		return nil, fmt.Errorf("failed to load accounting period: %w", err)
+	}
+
+	switch period.State {
+	case AccountingPeriodStateClosed.String():
+		return nil, fmt.Errorf("%w: %s is closed", ErrPeriodLocked, period.PeriodStart.Format("January 2006"))
+	case AccountingPeriodStateSoftClosed.String():
+		if a.periodOverride == nil {
+			return nil, fmt.Errorf("%w: %s is soft-closed and needs an admin override", ErrPeriodLocked, period.PeriodStart.Format("January 2006"))
+		}
+		return &period, nil
+	}
+	return nil, nil
+}
+
+// CloseChecklistItem is one check that has to pass before a period can be closed
+type CloseChecklistItem struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// CloseChecklist is the result of running every close check for a period
+type CloseChecklist struct {
+	PeriodID uint                 `json:"period_id"`
+	Passed   bool                 `json:"passed"`
+	Items    []CloseChecklistItem `json:"items"`
+}
+
+// RunCloseChecklist checks that a period is ready to close: the trial balance up to the end of the
+// period balances, no offline journals in it are waiting for review, and every bill covering it has
+// been accepted
+func (a *App) RunCloseChecklist(period *AccountingPeriod) (*CloseChecklist, error) {
+	checklist := &CloseChecklist{PeriodID: period.ID, Passed: true}
+	add := func(name string, passed bool, detail string) {
+		checklist.Items = append(checklist.Items, CloseChecklistItem{Name: name, Passed: passed, Detail: detail})
+		checklist.Passed = checklist.Passed && passed
+	}
+
+	var totals struct {
+		Debits  int64
+		Credits int64
+	}
+	if err := a.DB.Model(&Journal{}).Scopes(TenantScope(period.TenantID)).
+		Select("COALESCE(SUM(debit), 0) AS debits, COALESCE(SUM(credit), 0) AS credits").
+		Where("effective_date < ?", period.PeriodEnd).Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to total trial balance: %w", err)
+	}
+	add("Trial balance balances", totals.Debits == totals.Credits,
+		fmt.Sprintf("Debits $%.2f, credits $%.2f", float64(totals.Debits)/100, float64(totals.Credits)/100))
+
+	var pending int64
+	if err := a.DB.Model(&OfflineJournal{}).Scopes(TenantScope(period.TenantID)).
+		Where("status = ? AND date >= ? AND date < ?", "pending_review", period.PeriodStart, period.PeriodEnd).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to count pending offline journals: %w", err)
+	}
+	add("No offline journals pending review", pending == 0, fmt.Sprintf("%d pending review", pending))
+
+	var draftBills int64
+	if err := a.DB.Model(&Bill{}).Scopes(TenantScope(period.TenantID)).
+		Where("state = ? AND period_start < ? AND period_end >= ?", BillStateDraft, period.PeriodEnd, period.PeriodStart).
+		Count(&draftBills).Error; err != nil {
+		return nil, fmt.Errorf("failed to count draft bills: %w", err)
+	}
+	add("All bills accepted", draftBills == 0, fmt.Sprintf("%d bills not yet accepted", draftBills))
+
+	return checklist, nil
+}
+
+// SetAccountingPeriodState moves a period between OPEN, SOFT_CLOSED and CLOSED. Closing (soft or hard)
+// runs the checklist first and is refused unless it passes. Closed periods are final.
+func (a *App) SetAccountingPeriodState(tenantID, periodID uint, state AccountingPeriodState, userID uint) (*AccountingPeriod, *CloseChecklist, error) {
+	var period AccountingPeriod
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&period, periodID).Error; err != nil {
+		return nil, nil, ErrPeriodNotFound
+	}
+	if period.State == AccountingPeriodStateClosed.String() || period.State == state.String() {
+		return nil, nil, fmt.Errorf("%w: %s to %s", ErrInvalidPeriodTransition, period.State, state)
+	}
+
+	var checklist *CloseChecklist
+	updates := map[string]interface{}{"state": state.String()}
+	switch state {
+	case AccountingPeriodStateOpen:
+		updates["closed_at"] = nil
+		updates["closed_by_id"] = nil
+	case AccountingPeriodStateSoftClosed, AccountingPeriodStateClosed:
+		var err error
+		if checklist, err = a.RunCloseChecklist(&period); err != nil {
+			return nil, nil, err
+		}
+		if !checklist.Passed {
+			return nil, checklist, ErrPeriodChecklistFailed
+		}
+		updates["closed_at"] = time.Now()
+		updates["closed_by_id"] = userID
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown state %q", ErrInvalidPeriodTransition, state)
+	}
+
+	if err := a.DB.Model(&period).Updates(updates).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to update accounting period: %w", err)
+	}
+	a.DB.First(&period, period.ID)
+	log.Printf("Accounting period %s for tenant %d is now %s", period.PeriodStart.Format("2006-01"), tenantID, period.State)
+	return &period, checklist, nil
+}
+
+// ListPeriodOverrides returns the postings admins have made into soft-closed periods, newest first
+func (a *App) ListPeriodOverrides(tenantID uint) ([]PeriodOverride, error) {
+	var overrides []PeriodOverride
+	if err := a.DB.Scopes(TenantScope(tenantID)).Order("created_at desc").Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to load period overrides: %w", err)
+	}
+	return overrides, nil
+}
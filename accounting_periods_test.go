@@ -0,0 +1,83 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestAccountingPeriodClose tests the close checklist and that soft-closed periods only take logged
+// admin overrides while closed periods take nothing
+func TestAccountingPeriodClose(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "close", Name: "Close Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	january := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	period, err := app.OpenAccountingPeriod(tenant.ID, january)
+	if err != nil {
+		t.Fatalf("Failed to open period: %v", err)
+	}
+	if again, _ := app.OpenAccountingPeriod(tenant.ID, january.AddDate(0, 0, 10)); again.ID != period.ID {
+		t.Errorf("Expected reopening the same month to return period %d, got %d", period.ID, again.ID)
+	}
+
+	post := func(app *App) error {
+		return app.PostJournalTransaction(&JournalTransaction{TenantID: tenant.ID, EffectiveDate: january,
+			SourceType: JournalSourceManual.String(), Lines: []Journal{
+				{Account: AccountCash.String(), Debit: 5000},
+				{Account: AccountRevenue.String(), Credit: 5000},
+			}})
+	}
+	if err := post(app); err != nil {
+		t.Fatalf("Expected posting into an open period to work, got %v", err)
+	}
+
+	// A draft bill for the month holds up the close
+	bill := Bill{TenantID: tenant.ID, Name: "January", State: BillStateDraft,
+		PeriodStart: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), PeriodEnd: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)}
+	db.Create(&bill)
+	_, checklist, err := app.SetAccountingPeriodState(tenant.ID, period.ID, AccountingPeriodStateSoftClosed, 1)
+	if !errors.Is(err, ErrPeriodChecklistFailed) || checklist == nil {
+		t.Fatalf("Expected the checklist to fail with a draft bill, got %v", err)
+	}
+	for _, item := range checklist.Items {
+		if item.Passed != (item.Name != "All bills accepted") {
+			t.Errorf("Unexpected checklist result for %q: %v (%s)", item.Name, item.Passed, item.Detail)
+		}
+	}
+
+	db.Model(&bill).Update("state", BillStateAccepted)
+	if _, _, err := app.SetAccountingPeriodState(tenant.ID, period.ID, AccountingPeriodStateSoftClosed, 1); err != nil {
+		t.Fatalf("Failed to soft-close period: %v", err)
+	}
+	if err := post(app); !errors.Is(err, ErrPeriodLocked) {
+		t.Errorf("Expected a soft-closed period to refuse postings without an override, got %v", err)
+	}
+	if err := post(app.WithPeriodOverride(7, "Late vendor invoice")); err != nil {
+		t.Fatalf("Expected an admin override to post, got %v", err)
+	}
+	overrides, _ := app.ListPeriodOverrides(tenant.ID)
+	if len(overrides) != 1 || overrides[0].UserID != 7 || overrides[0].Reason != "Late vendor invoice" || overrides[0].PeriodID != period.ID {
+		t.Errorf("Expected the override to be logged, got %+v", overrides)
+	}
+
+	// February is unaffected
+	if err := app.PostJournalTransaction(&JournalTransaction{TenantID: tenant.ID, EffectiveDate: january.AddDate(0, 1, 0),
+		Lines: []Journal{{Account: AccountCash.String(), Debit: 100}, {Account: AccountRevenue.String(), Credit: 100}}}); err != nil {
+		t.Errorf("Expected the next month to stay open, got %v", err)
+	}
+
+	if _, _, err := app.SetAccountingPeriodState(tenant.ID, period.ID, AccountingPeriodStateClosed, 1); err != nil {
+		t.Fatalf("Failed to close period: %v", err)
+	}
+	if err := post(app.WithPeriodOverride(7, "Another late invoice")); !errors.Is(err, ErrPeriodLocked) {
+		t.Errorf("Expected a closed period to refuse even overrides, got %v", err)
+	}
+	if _, _, err := app.SetAccountingPeriodState(tenant.ID, period.ID, AccountingPeriodStateOpen, 1); !errors.Is(err, ErrInvalidPeriodTransition) {
+		t.Errorf("Expected a closed period to stay closed, got %v", err)
+	}
+}
@@ -22,6 +22,10 @@ type App struct {
 	DB      *gorm.DB
 	Project string
 	Bucket  string
+
+	periodOverride *periodOverride                                   // set by WithPeriodOverride
+	sendReminder   func(to, subject, htmlBody, replyTo string) error // delivers dunning reminders, SendReminderEmail when nil
+	sendNotice     func(to, subject, htmlBody string) error          // delivers staff notices such as returned timesheets, SendReminderEmail when nil
 }
 
 // InitializeSQLite allows us to initialize our application and connect to the local database
@@ -275,12 +279,23 @@ func (a *App) Migrate() {
 	models := []interface{}{
 		// Level 0: No foreign keys
 		&Tenant{},
+		&TaxJurisdiction{},
+		&TaxRate{},
+		&TaxProfile{},
 		&ChartOfAccount{},
 		&ExpenseCategory{},
 		&ExpenseTag{},
+		&SchedulerLock{},
+		&InvoiceNumberSequence{},
 
 		// Level 1: Only references Tenant
 		&Subaccount{},
+		&ExchangeRate{},
+		&JournalTransaction{},
+		&AccountingPeriod{},
+		&PeriodOverride{},
+		&DunningStep{},
+		&RateCard{},
 		&Account{},
 		&Client{},
 
@@ -304,6 +319,26 @@ func (a *App) Migrate() {
 		&StaffingAssignment{},
 		&Expense{},
 		&RecurringEntry{},
+		&ScheduledJob{},
+		&Payment{},
+		&CreditNote{},
+		&RetainerBlock{},
+		&Milestone{},
+		&RateCardLine{},
+		&Timesheet{},
+		&CalendarMappingRule{},
+		&Timer{},
+		&EntryImportBatch{},
+		&LeaveType{},
+		&LeaveBalance{},
+		&LeaveRequest{},
+		&Holiday{},
+		&StaffingScenario{},
+		&ScenarioAssignment{},
+		&Skill{},
+		&EmployeeSkill{},
+		&SkillRequirement{},
+		&BudgetAlert{},
 
 		// Level 5: Junction tables and line items
 		&InvoiceLineItem{},
@@ -311,6 +346,12 @@ func (a *App) Migrate() {
 		&Adjustment{},
 		&ExpenseTagAssignment{},
 		&RecurringBillLineItem{},
+		&JobRun{},
+		&PaymentAllocation{},
+		&CreditNoteLineItem{},
+		&InvoiceLineItemTax{},
+		&DunningReminder{},
+		&RetainerDrawdown{},
 	}
 
 	for _, model := range models {
@@ -322,6 +363,9 @@ func (a *App) Migrate() {
 			}
 		}
 	}
+
+	// Journals booked before transactions existed take their accounting date from when they were written
+	a.DB.Model(&Journal{}).Where("effective_date IS NULL").Update("effective_date", gorm.Expr("created_at"))
 }
 
 // GenerateSecureFilename generates a hash from the filename of an invoice
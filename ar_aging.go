@@ -0,0 +1,163 @@
+package cronos
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Aging buckets by days past due
+const (
+	AgingBucketCurrent = "current"
+	AgingBucket1To30   = "1-30"
+	AgingBucket31To60  = "31-60"
+	AgingBucket61To90  = "61-90"
+	AgingBucketOver90  = "90+"
+)
+
+// ARAgingBuckets are open balances in functional-currency cents by days past due
+type ARAgingBuckets struct {
+	Current    int64 `json:"current"`
+	Days1To30  int64 `json:"days_1_30"`
+	Days31To60 int64 `json:"days_31_60"`
+	Days61To90 int64 `json:"days_61_90"`
+	Over90     int64 `json:"days_over_90"`
+	Total      int64 `json:"total"`
+}
+
+func (b *ARAgingBuckets) add(bucket string, amount int64) {
+	switch bucket {
+	case AgingBucketCurrent:
+		b.Current += amount
+	case AgingBucket1To30:
+		b.Days1To30 += amount
+	case AgingBucket31To60:
+		b.Days31To60 += amount
+	case AgingBucket61To90:
+		b.Days61To90 += amount
+	default:
+		b.Over90 += amount
+	}
+	b.Total += amount
+}
+
+// ARAgingInvoice is an open invoice on the aging report
+type ARAgingInvoice struct {
+	InvoiceID   uint      `json:"invoice_id"`
+	Number      string    `json:"number"`
+	SentAt      time.Time `json:"sent_at"`
+	DueAt       time.Time `json:"due_at"`
+	DaysPastDue int       `json:"days_past_due"` // negative while the invoice is not yet due
+	Bucket      string    `json:"bucket"`
+	Currency    string    `json:"currency"`
+	BalanceDue  float64   `json:"balance_due"` // In the invoice's currency
+	Amount      int64     `json:"amount"`      // Open balance in functional cents
+}
+
+// ARAgingAccount is one client's row on the aging report
+type ARAgingAccount struct {
+	AccountID   uint             `json:"account_id"`
+	AccountName string           `json:"account_name"`
+	Buckets     ARAgingBuckets   `json:"buckets"`
+	Invoices    []ARAgingInvoice `json:"invoices"`
+}
+
+// ARAgingReport breaks down what clients owe by how overdue it is
+type ARAgingReport struct {
+	TenantID uint             `json:"tenant_id"`
+	AsOf     time.Time        `json:"as_of"`
+	Currency string           `json:"currency"` // Functional currency the buckets are in
+	Accounts []ARAgingAccount `json:"accounts"`
+	Totals   ARAgingBuckets   `json:"totals"`
+}
+
+// agingBucket places a number of days past due into its bucket
+func agingBucket(daysPastDue int) string {
+	switch {
+	case daysPastDue <= 0:
+		return AgingBucketCurrent
+	case daysPastDue <= 30:
+		return AgingBucket1To30
+	case daysPastDue <= 60:
+		return AgingBucket31To60
+	case daysPastDue <= 90:
+		return AgingBucket61To90
+	default:
+		return AgingBucketOver90
+	}
+}
+
+// invoiceDueDate is the day an invoice falls due. Invoices sent before due dates were tracked fall
+// back to their send date.
+func invoiceDueDate(invoice *Invoice) time.Time {
+	due := invoice.DueAt
+	if due.IsZero() {
+		due = invoice.SentAt
+	}
+	return time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysPastDue counts whole days from an invoice's due date to the given time
+func daysPastDue(invoice *Invoice, on time.Time) int {
+	day := time.Date(on.Year(), on.Month(), on.Day(), 0, 0, 0, 0, time.UTC)
+	return int(day.Sub(invoiceDueDate(invoice)).Hours() / 24)
+}
+
+// openInvoices loads a tenant's sent invoices that still have a balance, with their accounts
+func (a *App) openInvoices(tenantID uint) ([]Invoice, error) {
+	var invoices []Invoice
+	err := a.DB.Scopes(TenantScope(tenantID)).Preload("Account").
+		Where("state IN ? AND balance_due > ?", []string{InvoiceStateSent.String(), InvoiceStatePartiallyPaid.String()}, 0.005).
+		Order("due_at").Find(&invoices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open invoices: %w", err)
+	}
+	return invoices, nil
+}
+
+// GenerateARAging ages every open invoice by its due date as of the given day. Balances are the
+// invoices' current open balances, translated to the functional currency at their accrual rates.
+func (a *App) GenerateARAging(tenantID uint, asOf time.Time) (*ARAgingReport, error) {
+	invoices, err := a.openInvoices(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	functional := a.functionalCurrency(tenantID)
+	report := &ARAgingReport{TenantID: tenantID, AsOf: asOf, Currency: functional, Accounts: []ARAgingAccount{}}
+	byAccount := make(map[uint]*ARAgingAccount)
+	for i := range invoices {
+		invoice := &invoices[i]
+		if invoice.SentAt.After(asOf) {
+			continue
+		}
+		days := daysPastDue(invoice, asOf)
+		row := ARAgingInvoice{
+			InvoiceID:   invoice.ID,
+			Number:      invoice.Number,
+			SentAt:      invoice.SentAt,
+			DueAt:       invoiceDueDate(invoice),
+			DaysPastDue: days,
+			Bucket:      agingBucket(days),
+			Currency:    currencyOrFunctional(invoice.Currency, functional),
+			BalanceDue:  invoice.BalanceDue,
+			Amount:      ToFunctional(int64(math.Round(invoice.BalanceDue*100)), invoice.ExchangeRate),
+		}
+
+		account, ok := byAccount[invoice.AccountID]
+		if !ok {
+			account = &ARAgingAccount{AccountID: invoice.AccountID, AccountName: invoice.Account.Name}
+			byAccount[invoice.AccountID] = account
+		}
+		account.Invoices = append(account.Invoices, row)
+		account.Buckets.add(row.Bucket, row.Amount)
+		report.Totals.add(row.Bucket, row.Amount)
+	}
+
+	for _, account := range byAccount {
+		report.Accounts = append(report.Accounts, *account)
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool { return report.Accounts[i].AccountName < report.Accounts[j].AccountName })
+	return report, nil
+}
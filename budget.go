@@ -0,0 +1,521 @@
+package cronos
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultBurnWeeks = 4
+
+// defaultBudgetThresholds are the percents of a project's budget that send an alert when the tenant
+// hasn't set its own
+var defaultBudgetThresholds = []float64{80, 100}
+
+// BudgetAlertSettings configures budget monitoring for a tenant
+type BudgetAlertSettings struct {
+	Thresholds    []float64 `json:"thresholds"`     // Percents of the budget that send an alert
+	NotifyClients bool      `json:"notify_clients"` // Also alert the client users of the project's account
+	BurnWeeks     int       `json:"burn_weeks"`     // Weeks of entries the burn rate is averaged over
+}
+
+// BudgetAlertConfig reads the "budget_alerts" tenant setting, filling in the defaults, e.g.
+// { "budget_alerts": { "thresholds": [75, 90, 100], "notify_clients": true, "burn_weeks": 6 } }
+func BudgetAlertConfig(tenant *Tenant) BudgetAlertSettings {
+	var settings struct {
+		BudgetAlerts BudgetAlertSettings `json:"budget_alerts"`
+	}
+	if len(tenant.Settings) > 0 {
+		if err := json.Unmarshal(tenant.Settings, &settings); err != nil {
+			log.Printf("Warning: invalid settings for tenant %d, using the default budget alerts: %v", tenant.ID, err)
+		}
+	}
+	config := settings.BudgetAlerts
+	if len(config.Thresholds) == 0 {
+		config.Thresholds = defaultBudgetThresholds
+	}
+	if config.BurnWeeks <= 0 {
+		config.BurnWeeks = defaultBurnWeeks
+	}
+	return config
+}
+
+// BudgetMetric is a project's budget in hours or dollars, what's been used of it and where the
+// trailing burn rate takes it
+type BudgetMetric struct {
+	Budget               float64    `json:"budget"` // Total budget, 0 when none is set
+	Cap                  float64    `json:"cap"`    // Hard cap, 0 when none is set
+	Used                 float64    `json:"used"`
+	Percent              float64    `json:"percent"`                // Used as a percent of the budget
+	BurnRate             float64    `json:"burn_rate"`              // Average used per week over the trailing weeks
+	EstimateAtCompletion float64    `json:"estimate_at_completion"` // Used plus the burn rate through ActiveEnd
+	ProjectedCompletion  *time.Time `json:"projected_completion"`   // When the budget runs out at the burn rate, nil when it won't
+	ProjectedOverCap     bool       `json:"projected_over_cap"`     // The estimate at completion is over the cap
+}
+
+// ProjectBudgetForecast is a project's budget position and burn-rate projection as of a date
+type ProjectBudgetForecast struct {
+	ProjectID   uint         `json:"project_id"`
+	ProjectName string       `json:"project_name"`
+	AsOf        time.Time    `json:"as_of"`
+	ActiveEnd   time.Time    `json:"active_end"`
+	BurnWeeks   int          `json:"burn_weeks"`
+	Hours       BudgetMetric `json:"hours"`
+	Dollars     BudgetMetric `json:"dollars"`
+}
+
+// budgetPeriods counts the billing periods a project's per-period budget repeats over. Budgets billed
+// by project, unknown frequencies and projects without valid dates count once.
+func budgetPeriods(project *Project) float64 {
+	start, end := project.ActiveStart, project.ActiveEnd
+	if start.IsZero() || end.IsZero() || !end.After(start) {
+		return 1
+	}
+	days := end.Sub(start).Hours() / 24
+	switch project.BillingFrequency {
+	case BillingFrequencyMonthly.String():
+		// Every month the project is active in counts
+		return float64((end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month()) + 1)
+	case BillingFrequencyWeekly.String():
+		return math.Ceil(days / 7)
+	case BillingFrequencyBiweekly.String():
+		return math.Ceil(days / 14)
+	}
+	return 1
+}
+
+// ProjectBudgetTotals returns a project's total budget in hours and dollars: its caps when they're
+// set, otherwise its per-period budgets over every billing period it's active
+func ProjectBudgetTotals(project *Project) (float64, float64) {
+	periods := budgetPeriods(project)
+	hours := float64(project.BudgetHours) * periods
+	if project.BudgetCapHours > 0 {
+		hours = float64(project.BudgetCapHours)
+	}
+	dollars := float64(project.BudgetDollars) * periods
+	if project.BudgetCapDollars > 0 {
+		dollars = float64(project.BudgetCapDollars)
+	}
+	return hours, dollars
+}
+
+// budgetFeeRates returns the rate that converts the fees logged under each billing code into the
+// currency a project's dollar budget is in: the currency its account is billed in, or the functional
+// currency when the account doesn't set one. Rates without a currency bill in the account's, as they
+// do on invoices, so their fees aren't converted.
+func budgetFeeRates(db *gorm.DB, project *Project, billingCodeIDs []uint, now time.Time) (map[uint]float64, error) {
+	a := &App{DB: db}
+	var account Account
+	db.Select("id", "currency").Limit(1).Find(&account, project.AccountID)
+	to := currencyOrFunctional(account.Currency, a.functionalCurrency(project.TenantID))
+	var codes []BillingCode
+	if err := db.Preload("Rate").Where("id IN ?", billingCodeIDs).Find(&codes).Error; err != nil {
+		return nil, fmt.Errorf("failed to load billing codes: %w", err)
+	}
+	rates := make(map[uint]float64, len(codes))
+	for _, code := range codes {
+		if code.Rate.Currency == "" {
+			continue
+		}
+		fx, err := a.GetExchangeRate(project.TenantID, code.Rate.Currency, to, now)
+		if err != nil {
+			return nil, err
+		}
+		rates[code.ID] = fx
+	}
+	return rates, nil
+}
+
+// budgetFee converts a fee in dollars at a rate from budgetFeeRates
+func budgetFee(fee float64, billingCodeID uint, rates map[uint]float64) float64 {
+	if fx, ok := rates[billingCodeID]; ok {
+		return fee * fx
+	}
+	return fee
+}
+
+// entryBillingCodes returns the distinct billing codes of entries
+func entryBillingCodes(entries []Entry) []uint {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, entry := range entries {
+		if entry.BillingCodeID != 0 && !seen[entry.BillingCodeID] {
+			seen[entry.BillingCodeID] = true
+			ids = append(ids, entry.BillingCodeID)
+		}
+	}
+	return ids
+}
+
+// burnMetric projects a budget from what's been used and what was used over the trailing weeks
+func burnMetric(budget, budgetCap, used, trailing, trailingWeeks float64, now, end time.Time) BudgetMetric {
+	metric := BudgetMetric{Budget: budget, Cap: budgetCap, Used: used, BurnRate: trailing / trailingWeeks, EstimateAtCompletion: used}
+	projecting := end.After(now)
+	if projecting {
+		metric.EstimateAtCompletion += metric.BurnRate * end.Sub(now).Hours() / (24 * 7)
+	}
+	if budget > 0 {
+		metric.Percent = used / budget * 100
+		switch {
+		case used >= budget:
+			exhausted := now
+			metric.ProjectedCompletion = &exhausted
+		case metric.BurnRate > 0:
+			weeks := (budget - used) / metric.BurnRate
+			exhausted := now.Add(time.Duration(weeks * float64(7*24*time.Hour)))
+			metric.ProjectedCompletion = &exhausted
+		}
+	}
+	metric.ProjectedOverCap = projecting && budgetCap > 0 && metric.EstimateAtCompletion > budgetCap
+	return metric
+}
+
+// ForecastProjectBudget totals the hours and fees logged against a project and projects them through
+// its end at the burn rate of the trailing weeks. Fees are converted into the project's budget
+// currency at today's rates. Projects that started more recently are averaged
+// over the time they've been active, but never less than a week.
+func (a *App) ForecastProjectBudget(project *Project, burnWeeks int, now time.Time) (ProjectBudgetForecast, error) {
+	if burnWeeks <= 0 {
+		burnWeeks = defaultBurnWeeks
+	}
+	forecast := ProjectBudgetForecast{
+		ProjectID:   project.ID,
+		ProjectName: project.Name,
+		AsOf:        now,
+		ActiveEnd:   project.ActiveEnd,
+		BurnWeeks:   burnWeeks,
+	}
+	var entries []Entry
+	if err := a.DB.Scopes(TenantScope(project.TenantID)).Select("start", "end", "fee", "billing_code_id").
+		Where("project_id = ? AND state != ?", project.ID, EntryStateVoid.String()).Find(&entries).Error; err != nil {
+		return forecast, fmt.Errorf("failed to load entries: %w", err)
+	}
+	rates, err := budgetFeeRates(a.DB, project, entryBillingCodes(entries), now)
+	if err != nil {
+		return forecast, err
+	}
+
+	windowStart := now.AddDate(0, 0, -7*burnWeeks)
+	if project.ActiveStart.After(windowStart) {
+		windowStart = project.ActiveStart
+	}
+	windowWeeks := math.Max(now.Sub(windowStart).Hours()/(24*7), 1)
+
+	var hours, dollars, trailingHours, trailingDollars float64
+	for _, entry := range entries {
+		if !entry.End.After(entry.Start) {
+			continue
+		}
+		entryHours, entryDollars := entry.Duration().Hours(), budgetFee(float64(entry.Fee)/100, entry.BillingCodeID, rates)
+		hours += entryHours
+		dollars += entryDollars
+		if !entry.Start.Before(windowStart) && entry.Start.Before(now) {
+			trailingHours += entryHours
+			trailingDollars += entryDollars
+		}
+	}
+
+	budgetHours, budgetDollars := ProjectBudgetTotals(project)
+	forecast.Hours = burnMetric(budgetHours, float64(project.BudgetCapHours), hours, trailingHours, windowWeeks, now, project.ActiveEnd)
+	forecast.Dollars = burnMetric(budgetDollars, float64(project.BudgetCapDollars), dollars, trailingDollars, windowWeeks, now, project.ActiveEnd)
+	return forecast, nil
+}
+
+// budgetAlertCheck is a condition a budget alert is sent for and whether the project meets it
+type budgetAlertCheck struct {
+	kind      BudgetAlertKind
+	threshold float64
+	percent   float64
+	crossed   bool
+	message   string
+}
+
+// budgetAlertChecks lists the alert conditions of a forecast: each threshold of the hours and dollar
+// budgets that are set, and running past each cap that's set
+func budgetAlertChecks(forecast *ProjectBudgetForecast, thresholds []float64) []budgetAlertCheck {
+	formatHours := func(hours float64) string { return fmt.Sprintf("%.1f hours", hours) }
+	formatDollars := func(dollars float64) string { return fmt.Sprintf("$%.2f", dollars) }
+	metrics := []struct {
+		kind, projected BudgetAlertKind
+		metric          BudgetMetric
+		format          func(float64) string
+	}{
+		{BudgetAlertHours, BudgetAlertProjectedHours, forecast.Hours, formatHours},
+		{BudgetAlertDollars, BudgetAlertProjectedDollars, forecast.Dollars, formatDollars},
+	}
+
+	var checks []budgetAlertCheck
+	for _, m := range metrics {
+		if m.metric.Budget > 0 {
+			for _, threshold := range thresholds {
+				checks = append(checks, budgetAlertCheck{
+					kind:      m.kind,
+					threshold: threshold,
+					percent:   m.metric.Percent,
+					crossed:   m.metric.Percent >= threshold,
+					message: fmt.Sprintf("%s of the %s budget used (%.0f%%), past the %g%% alert",
+						m.format(m.metric.Used), m.format(m.metric.Budget), m.metric.Percent, threshold),
+				})
+			}
+		}
+		if m.metric.Cap > 0 {
+			checks = append(checks, budgetAlertCheck{
+				kind:    m.projected,
+				percent: m.metric.EstimateAtCompletion / m.metric.Cap * 100,
+				crossed: m.metric.ProjectedOverCap,
+				message: fmt.Sprintf("projected to reach %s by %s at %s a week, over the cap of %s",
+					m.format(m.metric.EstimateAtCompletion), forecast.ActiveEnd.Format("Jan 2, 2006"), m.format(m.metric.BurnRate), m.format(m.metric.Cap)),
+			})
+		}
+	}
+	return checks
+}
+
+// CheckProjectBudget forecasts a project's budget and alerts on every threshold it has newly crossed.
+// It returns the alerts sent.
+func (a *App) CheckProjectBudget(tenantID, projectID uint, now time.Time) ([]BudgetAlert, error) {
+	var tenant Tenant
+	if err := a.DB.First(&tenant, tenantID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	var project Project
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("AE.User").Preload("Lead.User").First(&project, projectID).Error; err != nil {
+		return nil, fmt.Errorf("project %d not found: %w", projectID, err)
+	}
+	return a.checkProjectBudget(&tenant, &project, now)
+}
+
+// checkProjectBudget records an alert for each condition the project newly meets and sends them in
+// one notice. Alerts for conditions it no longer meets, or for thresholds no longer configured, are
+// removed so they can fire again.
+func (a *App) checkProjectBudget(tenant *Tenant, project *Project, now time.Time) ([]BudgetAlert, error) {
+	settings := BudgetAlertConfig(tenant)
+	forecast, err := a.ForecastProjectBudget(project, settings.BurnWeeks, now)
+	if err != nil {
+		return nil, err
+	}
+	var existing []BudgetAlert
+	if err := a.DB.Where("project_id = ?", project.ID).Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load budget alerts: %w", err)
+	}
+	sent := make(map[string]BudgetAlert, len(existing))
+	for _, alert := range existing {
+		sent[fmt.Sprintf("%s/%g", alert.Kind, alert.Threshold)] = alert
+	}
+
+	var alerts []BudgetAlert
+	for _, check := range budgetAlertChecks(&forecast, settings.Thresholds) {
+		key := fmt.Sprintf("%s/%g", check.kind, check.threshold)
+		_, alerted := sent[key]
+		switch {
+		case check.crossed && alerted:
+			delete(sent, key)
+		case check.crossed:
+			alerts = append(alerts, BudgetAlert{
+				TenantID:  project.TenantID,
+				ProjectID: project.ID,
+				Kind:      check.kind.String(),
+				Threshold: check.threshold,
+				Percent:   check.percent,
+				Message:   check.message,
+				SentAt:    now,
+			})
+		}
+	}
+	// What's left has dropped back under its threshold, or its threshold, budget or cap is no longer
+	// set, so it's cleared to alert again
+	if len(sent) > 0 {
+		stale := make([]uint, 0, len(sent))
+		for _, alert := range sent {
+			stale = append(stale, alert.ID)
+		}
+		if err := a.DB.Unscoped().Delete(&BudgetAlert{}, stale).Error; err != nil {
+			return nil, fmt.Errorf("failed to reset budget alerts: %w", err)
+		}
+	}
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+	// Recorded before sending so a check running alongside can't send them again
+	if err := a.DB.Create(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to record budget alerts: %w", err)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<p>%s needs attention:</p><ul>", html.EscapeString(project.Name))
+	for _, alert := range alerts {
+		fmt.Fprintf(&body, "<li>%s</li>", html.EscapeString(alert.Message))
+	}
+	body.WriteString("</ul>")
+	if exhausted := forecast.Dollars.ProjectedCompletion; exhausted != nil && exhausted.After(now) {
+		fmt.Fprintf(&body, "<p>At the current burn rate the budget runs out around %s.</p>", exhausted.Format("Jan 2, 2006"))
+	}
+	for _, recipient := range a.budgetAlertRecipients(project, settings.NotifyClients, now) {
+		if err := a.sendStaffNotice(recipient, "Budget alert: "+project.Name, body.String()); err != nil {
+			log.Printf("Failed to send budget alert for project %d to %s: %v", project.ID, recipient, err)
+		}
+	}
+	return alerts, nil
+}
+
+// budgetAlertRecipients returns who a project's budget alerts go to: its AE and lead, the employees
+// staffed on it now, and the client users of its account when the tenant notifies clients
+func (a *App) budgetAlertRecipients(project *Project, notifyClients bool, now time.Time) []string {
+	var recipients []string
+	seen := make(map[string]bool)
+	add := func(email string) {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		recipients = append(recipients, email)
+	}
+	if project.AE != nil {
+		add(project.AE.User.Email)
+	}
+	if project.Lead != nil {
+		add(project.Lead.User.Email)
+	}
+
+	var assignments []StaffingAssignment
+	if err := a.DB.Scopes(TenantScope(project.TenantID)).Preload("Employee.User").
+		Where("project_id = ? AND start_date <= ? AND end_date >= ?", project.ID, now, now).Find(&assignments).Error; err != nil {
+		log.Printf("Failed to load staffing for budget alerts on project %d: %v", project.ID, err)
+	}
+	for _, assignment := range assignments {
+		add(assignment.Employee.User.Email)
+	}
+
+	if notifyClients {
+		var users []User
+		if err := a.DB.Scopes(TenantScope(project.TenantID)).
+			Where("account_id = ? AND role = ?", project.AccountID, UserRoleClient.String()).Find(&users).Error; err != nil {
+			log.Printf("Failed to load client users for budget alerts on project %d: %v", project.ID, err)
+		}
+		for _, user := range users {
+			add(user.Email)
+		}
+	}
+	return recipients
+}
+
+// activeBudgetedProjects loads the tenant's active client projects that have a budget or cap set
+func (a *App) activeBudgetedProjects(tenantID uint, now time.Time) ([]Project, error) {
+	var projects []Project
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("AE.User").Preload("Lead.User").
+		Where("internal = ? AND active_start <= ? AND active_end >= ?", false, now, now).
+		Where("budget_hours > 0 OR budget_dollars > 0 OR budget_cap_hours > 0 OR budget_cap_dollars > 0").
+		Order("name").Find(&projects).Error; err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+	return projects, nil
+}
+
+// ForecastBudgets forecasts the budget of each of the tenant's active budgeted projects
+func (a *App) ForecastBudgets(tenantID uint, now time.Time) ([]ProjectBudgetForecast, error) {
+	var tenant Tenant
+	if err := a.DB.First(&tenant, tenantID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	projects, err := a.activeBudgetedProjects(tenantID, now)
+	if err != nil {
+		return nil, err
+	}
+	burnWeeks := BudgetAlertConfig(&tenant).BurnWeeks
+	forecasts := make([]ProjectBudgetForecast, 0, len(projects))
+	for i := range projects {
+		forecast, err := a.ForecastProjectBudget(&projects[i], burnWeeks, now)
+		if err != nil {
+			return nil, err
+		}
+		forecasts = append(forecasts, forecast)
+	}
+	return forecasts, nil
+}
+
+// MonitorBudgets checks the budget of each of the tenant's active budgeted projects and returns how
+// many alerts went out
+func (a *App) MonitorBudgets(tenantID uint, now time.Time) (int, error) {
+	var tenant Tenant
+	if err := a.DB.First(&tenant, tenantID).Error; err != nil {
+		return 0, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	projects, err := a.activeBudgetedProjects(tenantID, now)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range projects {
+		alerts, err := a.checkProjectBudget(&tenant, &projects[i], now)
+		if err != nil {
+			return sent, fmt.Errorf("project %d: %w", projects[i].ID, err)
+		}
+		sent += len(alerts)
+	}
+	return sent, nil
+}
+
+// queueBudgetCheck marks an entry's project for the budget_checks job. It's called from the entry
+// hooks, so every way of logging, changing or removing time is covered, and runs in the entry's
+// transaction so a rolled back change queues nothing. Projects without a budget are skipped.
+func queueBudgetCheck(tx *gorm.DB, projectID uint) error {
+	if projectID == 0 {
+		return nil
+	}
+	if err := tx.Model(&Project{}).
+		Where("id = ? AND budget_check_due = ?", projectID, false).
+		Where("budget_hours > 0 OR budget_dollars > 0 OR budget_cap_hours > 0 OR budget_cap_dollars > 0").
+		UpdateColumn("budget_check_due", true).Error; err != nil {
+		return fmt.Errorf("failed to queue budget check: %w", err)
+	}
+	return nil
+}
+
+// AfterDelete queues a check of the project's budget, which may drop back under a threshold
+func (e *Entry) AfterDelete(tx *gorm.DB) (err error) {
+	return queueBudgetCheck(tx, e.ProjectID)
+}
+
+// CheckDueBudgets checks the budgets of the tenant's projects whose entries have changed since they
+// were last checked and returns how many alerts went out. Each project is taken off the queue before
+// it's checked, so changes made while it runs queue it again.
+func (a *App) CheckDueBudgets(tenantID uint, now time.Time) (int, error) {
+	var tenant Tenant
+	if err := a.DB.First(&tenant, tenantID).Error; err != nil {
+		return 0, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	var projects []Project
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("AE.User").Preload("Lead.User").
+		Where("budget_check_due = ?", true).Order("id").Find(&projects).Error; err != nil {
+		return 0, fmt.Errorf("failed to load projects: %w", err)
+	}
+	sent := 0
+	for i := range projects {
+		if err := a.DB.Model(&Project{}).Where("id = ?", projects[i].ID).UpdateColumn("budget_check_due", false).Error; err != nil {
+			return sent, fmt.Errorf("failed to dequeue project %d: %w", projects[i].ID, err)
+		}
+		alerts, err := a.checkProjectBudget(&tenant, &projects[i], now)
+		if err != nil {
+			return sent, fmt.Errorf("project %d: %w", projects[i].ID, err)
+		}
+		sent += len(alerts)
+	}
+	return sent, nil
+}
+
+// ListBudgetAlerts returns the alerts standing on a project, newest first
+func (a *App) ListBudgetAlerts(tenantID, projectID uint) ([]BudgetAlert, error) {
+	var alerts []BudgetAlert
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("project_id = ?", projectID).
+		Order("sent_at DESC, id DESC").Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load budget alerts: %w", err)
+	}
+	return alerts, nil
+}
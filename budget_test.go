@@ -0,0 +1,183 @@
+package cronos
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// TestProjectBudgetAlerts tests that a project's budget is projected from its trailing burn rate, that
+// each threshold is alerted once to the project's team and clients and re-arms when the budget is
+// raised or the threshold dropped, that changing entries queues a check, and that the budget_cap rule
+// stops entries past the hours and dollar caps
+func TestProjectBudgetAlerts(t *testing.T) {
+	db := setupTestDB(t)
+	var sentTo []string
+	app := &App{DB: db, sendNotice: func(to, subject, body string) error {
+		sentTo = append(sentTo, to)
+		return nil
+	}}
+	tenant := Tenant{Slug: "budget", Name: "Budget Tenant",
+		Settings: datatypes.JSON(`{"budget_alerts":{"notify_clients":true},"entry_rules":{"budget_cap":{"mode":"block"}}}`)}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	newEmployee := func(name, email string) Employee {
+		user := User{TenantID: tenant.ID, Email: email, Role: UserRoleStaff.String()}
+		db.Create(&user)
+		employee := Employee{TenantID: tenant.ID, UserID: user.ID, FirstName: name, IsActive: true}
+		db.Create(&employee)
+		return employee
+	}
+	ae := newEmployee("Harper", "harper@example.com")
+	consultant := newEmployee("Jordan", "jordan@example.com")
+	account := Account{TenantID: tenant.ID, Name: "Budget Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	db.Create(&User{TenantID: tenant.ID, Email: "client@example.com", Role: UserRoleClient.String(), AccountID: account.ID})
+
+	start, end := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC)
+	project := Project{TenantID: tenant.ID, Name: "Migration", AccountID: account.ID, ActiveStart: start, ActiveEnd: end,
+		BillingFrequency: BillingFrequencyProject.String(), BudgetDollars: 10000, BudgetCapHours: 80, AEID: &ae.ID}
+	db.Create(&project)
+	db.Create(&StaffingAssignment{TenantID: tenant.ID, EmployeeID: consultant.ID, ProjectID: project.ID, Commitment: 10, StartDate: start, EndDate: end})
+	addEntry := func(day time.Time, hours int) {
+		entry := Entry{TenantID: tenant.ID, ProjectID: project.ID, EmployeeID: consultant.ID, Start: day.Add(9 * time.Hour),
+			End: day.Add(time.Duration(9+hours) * time.Hour), State: EntryStateDraft.String(), Fee: hours * 15000}
+		if err := db.Session(&gorm.Session{SkipHooks: true}).Create(&entry).Error; err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+	}
+	// 10 hours a week at 150.00 for the four weeks of March
+	for day := 3; day <= 24; day += 7 {
+		addEntry(time.Date(2025, 3, day, 0, 0, 0, 0, time.UTC), 10)
+	}
+
+	now := time.Date(2025, 3, 29, 0, 0, 0, 0, time.UTC)
+	forecast, err := app.ForecastProjectBudget(&project, 4, now)
+	if err != nil {
+		t.Fatalf("Failed to forecast budget: %v", err)
+	}
+	if forecast.Hours.Used != 40 || forecast.Hours.BurnRate != 10 || !forecast.Hours.ProjectedOverCap {
+		t.Errorf("Expected 40 hours burning 10 a week to run past the 80 hour cap, got %+v", forecast.Hours)
+	}
+	// 32 days are left, so 40 + 10 * 32/7 hours
+	if eac := forecast.Hours.EstimateAtCompletion; math.Abs(eac-(40+320.0/7)) > 0.001 {
+		t.Errorf("Expected an estimate at completion of 85.71 hours, got %.2f", eac)
+	}
+	if forecast.Dollars.Percent != 60 || forecast.Dollars.ProjectedCompletion == nil ||
+		forecast.Dollars.ProjectedCompletion.Format("2006-01-02") != "2025-04-16" {
+		t.Errorf("Expected 60%% of the dollars used, running out on April 16, got %+v", forecast.Dollars)
+	}
+
+	sent, err := app.MonitorBudgets(tenant.ID, now)
+	if err != nil {
+		t.Fatalf("Failed to monitor budgets: %v", err)
+	}
+	sort.Strings(sentTo)
+	if sent != 1 || len(sentTo) != 3 || sentTo[0] != "client@example.com" || sentTo[1] != "harper@example.com" || sentTo[2] != "jordan@example.com" {
+		t.Fatalf("Expected one projection alert to the client, AE and consultant, got %d to %v", sent, sentTo)
+	}
+	if sent, _ := app.MonitorBudgets(tenant.ID, now); sent != 0 {
+		t.Errorf("Expected the projection alert not to be sent twice, got %d", sent)
+	}
+
+	// 20 more hours takes the fees to 90% of the dollar budget
+	addEntry(time.Date(2025, 3, 27, 0, 0, 0, 0, time.UTC), 20)
+	alerts, err := app.CheckProjectBudget(tenant.ID, project.ID, now)
+	if err != nil {
+		t.Fatalf("Failed to check budget: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Kind != BudgetAlertDollars.String() || alerts[0].Threshold != 80 {
+		t.Errorf("Expected the 80%% dollar alert, got %+v", alerts)
+	}
+	// Dropping the 80% threshold clears its alert
+	db.Model(&tenant).Update("settings", datatypes.JSON(`{"budget_alerts":{"thresholds":[100],"notify_clients":true},"entry_rules":{"budget_cap":{"mode":"block"}}}`))
+	if _, err := app.CheckProjectBudget(tenant.ID, project.ID, now); err != nil {
+		t.Fatalf("Failed to check budget: %v", err)
+	}
+	if standing, _ := app.ListBudgetAlerts(tenant.ID, project.ID); len(standing) != 1 || standing[0].Kind != BudgetAlertProjectedHours.String() {
+		t.Errorf("Expected the 80%% alert cleared with its threshold, got %+v", standing)
+	}
+
+	// With 60 of the 80 capped hours logged, 20 more fit but 21 don't
+	entry := Entry{TenantID: tenant.ID, ProjectID: project.ID, EmployeeID: ae.ID,
+		Start: time.Date(2025, 3, 28, 8, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 29, 4, 0, 0, 0, time.UTC)}
+	if validation, _ := app.ValidateEntry(&entry, nil, now); validation.Err() != nil {
+		t.Errorf("Expected 20 hours to fit under the cap, got %v", validation.Err())
+	}
+	entry.End = entry.End.Add(time.Hour)
+	if validation, _ := app.ValidateEntry(&entry, nil, now); !errors.Is(validation.Err(), ErrEntryBlocked) {
+		t.Errorf("Expected 21 hours to be blocked by the cap, got %v", validation.Violations)
+	}
+
+	// With 9,000.00 of a 10,000.00 cap billed, 6 hours at 150.00 fit but 7 don't
+	db.Model(&project).Update("budget_cap_dollars", 10000)
+	rate := Rate{TenantID: tenant.ID, Name: "Senior", Amount: 150}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Build", Code: "BLD", ProjectID: project.ID, RateID: rate.ID}
+	db.Create(&code)
+	billed := Entry{TenantID: tenant.ID, ProjectID: project.ID, BillingCodeID: code.ID, EmployeeID: ae.ID,
+		Start: time.Date(2025, 3, 28, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 28, 15, 0, 0, 0, time.UTC)}
+	if validation, _ := app.ValidateEntry(&billed, nil, now); validation.Err() != nil {
+		t.Errorf("Expected 900.00 of fees to fit under the cap, got %v", validation.Err())
+	}
+	billed.End = billed.End.Add(time.Hour)
+	if validation, _ := app.ValidateEntry(&billed, nil, now); !errors.Is(validation.Err(), ErrEntryBlocked) {
+		t.Errorf("Expected 1,050.00 of fees to be blocked by the cap, got %v", validation.Violations)
+	}
+
+	// Fees billed in another currency count against the cap in the account's: 150.00 EUR is 300.00 USD
+	db.Model(&rate).Update("currency", "EUR")
+	billed.End = time.Date(2025, 3, 28, 15, 0, 0, 0, time.UTC)
+	if validation, _ := app.ValidateEntry(&billed, nil, now); !errors.Is(validation.Err(), ErrEntryBlocked) {
+		t.Errorf("Expected EUR fees with no exchange rate to be blocked, got %v", validation.Violations)
+	}
+	db.Create(&ExchangeRate{TenantID: tenant.ID, FromCurrency: "EUR", ToCurrency: "USD", Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rate: 2})
+	if validation, _ := app.ValidateEntry(&billed, nil, now); !errors.Is(validation.Err(), ErrEntryBlocked) {
+		t.Errorf("Expected 1,800.00 USD of fees to be blocked by the cap, got %v", validation.Violations)
+	}
+	billed.End = time.Date(2025, 3, 28, 12, 0, 0, 0, time.UTC)
+	if validation, _ := app.ValidateEntry(&billed, nil, now); validation.Err() != nil {
+		t.Errorf("Expected 900.00 USD of fees to fit under the cap, got %v", validation.Err())
+	}
+	db.Model(&rate).Update("currency", "")
+	billed.End = time.Date(2025, 3, 28, 16, 0, 0, 0, time.UTC)
+	db.Model(&project).Update("budget_cap_dollars", 0)
+
+	// Logging time queues the project for the budget_checks job, which takes it off the queue
+	dueProject := func() bool {
+		var due Project
+		db.Select("budget_check_due").First(&due, project.ID)
+		return due.BudgetCheckDue
+	}
+	if err := db.Create(&billed).Error; err != nil || !dueProject() {
+		t.Fatalf("Expected a new entry to queue a budget check: %v", err)
+	}
+	if sent, err := app.CheckDueBudgets(tenant.ID, now); err != nil || sent != 1 || dueProject() {
+		t.Errorf("Expected the 100%% dollar alert and the project dequeued, got %d: %v", sent, err)
+	}
+	if err := db.Delete(&billed).Error; err != nil || !dueProject() {
+		t.Errorf("Expected removing an entry to queue a budget check: %v", err)
+	}
+
+	// Raising the budget drops the fees back under 80%, re-arming the alert
+	db.Model(&project).Update("budget_dollars", 20000)
+	if _, err := app.CheckProjectBudget(tenant.ID, project.ID, now); err != nil {
+		t.Fatalf("Failed to check budget: %v", err)
+	}
+	standing, _ := app.ListBudgetAlerts(tenant.ID, project.ID)
+	if len(standing) != 1 || standing[0].Kind != BudgetAlertProjectedHours.String() {
+		t.Errorf("Expected only the projection alert left standing, got %+v", standing)
+	}
+
+	monthly := Project{BillingFrequency: BillingFrequencyMonthly.String(), BudgetHours: 10, BudgetDollars: 1500,
+		ActiveStart: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), ActiveEnd: time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)}
+	if hours, dollars := ProjectBudgetTotals(&monthly); hours != 30 || dollars != 4500 {
+		t.Errorf("Expected three months of budget, got %.0f hours and %.0f dollars", hours, dollars)
+	}
+}
@@ -0,0 +1,255 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCalendarMapping = errors.New("invalid calendar mapping rule")
+
+// CalendarEvent is a timed event read from an employee's calendar
+type CalendarEvent struct {
+	ID          string    `json:"id"`
+	CalendarID  string    `json:"calendar_id"`
+	Summary     string    `json:"summary"`
+	Description string    `json:"description"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Attendees   []string  `json:"attendees"` // Email addresses
+}
+
+// CalendarService reads events from an employee's calendar. The server implements it over the Google
+// Calendar API.
+type CalendarService interface {
+	ListEvents(calendarID string, start, end time.Time) ([]CalendarEvent, error)
+	GetEvent(calendarID, eventID string) (CalendarEvent, error)
+}
+
+// CalendarImport selects the events to import: the listed events, or every event between Start and
+// End when none are listed
+type CalendarImport struct {
+	CalendarID string    `json:"calendar_id"` // Defaults to "primary"
+	EventIDs   []string  `json:"event_ids"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+}
+
+// CalendarImportSkip is an event that wasn't imported and why
+type CalendarImportSkip struct {
+	EventID string `json:"event_id"`
+	Summary string `json:"summary"`
+	Reason  string `json:"reason"`
+}
+
+// CalendarImportResult is the entries an import created and the events it skipped
+type CalendarImportResult struct {
+	Created []Entry              `json:"created"`
+	Skipped []CalendarImportSkip `json:"skipped"`
+}
+
+// ValidateCalendarMappingRule checks a rule's match type and that it has something to match on. Domain
+// rules may leave the pattern empty to match the domains of the billing code's account.
+func ValidateCalendarMappingRule(rule *CalendarMappingRule) error {
+	switch CalendarMatchType(rule.MatchType) {
+	case CalendarMatchDomain:
+		rule.Pattern = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(rule.Pattern)), "@")
+	case CalendarMatchKeyword, CalendarMatchCalendar:
+		rule.Pattern = strings.TrimSpace(rule.Pattern)
+		if rule.Pattern == "" {
+			return fmt.Errorf("%w: a pattern is required to match on %s", ErrInvalidCalendarMapping, rule.MatchType)
+		}
+	default:
+		return fmt.Errorf("%w: unknown match type %q", ErrInvalidCalendarMapping, rule.MatchType)
+	}
+	if rule.BillingCodeID == 0 {
+		return fmt.Errorf("%w: a billing code is required", ErrInvalidCalendarMapping)
+	}
+	return nil
+}
+
+// emailDomain returns the lowercased domain of an email address or website, without "www."
+func emailDomain(address string) string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if at := strings.LastIndex(address, "@"); at != -1 {
+		return address[at+1:]
+	}
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(parsed.Hostname(), "www.")
+}
+
+// calendarRule is a mapping rule with the domains it matches resolved
+type calendarRule struct {
+	CalendarMappingRule
+	domains []string
+}
+
+// calendarRules loads a tenant's mapping rules in the order they're tried. Domain rules without a
+// pattern match the website and email domains of their billing code's account.
+func (a *App) calendarRules(tenantID uint) ([]calendarRule, error) {
+	var mappings []CalendarMappingRule
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("BillingCode").Order("priority, id").Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load calendar mapping rules: %w", err)
+	}
+	rules := make([]calendarRule, 0, len(mappings))
+	for _, mapping := range mappings {
+		rule := calendarRule{CalendarMappingRule: mapping}
+		if CalendarMatchType(mapping.MatchType) == CalendarMatchDomain {
+			if mapping.Pattern != "" {
+				rule.domains = []string{mapping.Pattern}
+			} else {
+				var project Project
+				a.DB.Preload("Account").Limit(1).Find(&project, mapping.BillingCode.ProjectID)
+				for _, address := range []string{project.Account.Website, project.Account.Email} {
+					if domain := emailDomain(address); domain != "" {
+						rule.domains = append(rule.domains, domain)
+					}
+				}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matches reports whether the rule applies to an event
+func (r calendarRule) matches(event CalendarEvent) bool {
+	switch CalendarMatchType(r.MatchType) {
+	case CalendarMatchDomain:
+		for _, attendee := range event.Attendees {
+			domain := emailDomain(attendee)
+			for _, d := range r.domains {
+				if domain == d {
+					return true
+				}
+			}
+		}
+	case CalendarMatchKeyword:
+		return strings.Contains(strings.ToLower(event.Summary), strings.ToLower(r.Pattern))
+	case CalendarMatchCalendar:
+		return event.CalendarID == r.Pattern
+	}
+	return false
+}
+
+// ImportCalendarEvents creates meeting entries for an employee's calendar events, billed to the code
+// of the first mapping rule that matches each event. Events that were already imported, that no rule
+// matches, or that fall in a submitted timesheet or break a blocking entry rule are skipped.
+func (a *App) ImportCalendarEvents(tenantID, employeeID uint, service CalendarService, req CalendarImport, now time.Time) (*CalendarImportResult, error) {
+	if req.CalendarID == "" {
+		req.CalendarID = "primary"
+	}
+	result := &CalendarImportResult{Created: []Entry{}, Skipped: []CalendarImportSkip{}}
+
+	var events []CalendarEvent
+	if len(req.EventIDs) > 0 {
+		for _, id := range req.EventIDs {
+			event, err := service.GetEvent(req.CalendarID, id)
+			if err != nil {
+				result.Skipped = append(result.Skipped, CalendarImportSkip{EventID: id, Reason: fmt.Sprintf("failed to load event: %v", err)})
+				continue
+			}
+			events = append(events, event)
+		}
+	} else {
+		if !req.End.After(req.Start) {
+			return nil, errors.New("event IDs or a date range are required")
+		}
+		listed, err := service.ListEvents(req.CalendarID, req.Start, req.End)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list calendar events: %w", err)
+		}
+		events = listed
+	}
+
+	rules, err := a.calendarRules(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	imported := make(map[string]bool)
+	if len(events) > 0 {
+		ids := make([]string, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		var existing []string
+		a.DB.Model(&Entry{}).Scopes(TenantScope(tenantID)).
+			Where("employee_id = ? AND external_event_id IN ?", employeeID, ids).
+			Pluck("external_event_id", &existing)
+		for _, id := range existing {
+			imported[id] = true
+		}
+	}
+
+	for _, event := range events {
+		if event.CalendarID == "" {
+			event.CalendarID = req.CalendarID
+		}
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, CalendarImportSkip{EventID: event.ID, Summary: event.Summary, Reason: reason})
+		}
+		if imported[event.ID] {
+			skip("already imported")
+			continue
+		}
+		if event.Start.IsZero() || !event.End.After(event.Start) {
+			skip("not a timed event")
+			continue
+		}
+		var rule *calendarRule
+		for i := range rules {
+			if rules[i].matches(event) {
+				rule = &rules[i]
+				break
+			}
+		}
+		if rule == nil {
+			skip("no mapping rule matches")
+			continue
+		}
+
+		entry := Entry{
+			TenantID:        tenantID,
+			ProjectID:       rule.BillingCode.ProjectID,
+			BillingCodeID:   rule.BillingCodeID,
+			EmployeeID:      employeeID,
+			Start:           event.Start.UTC(),
+			End:             event.End.UTC(),
+			Notes:           event.Summary,
+			IsMeeting:       true,
+			ExternalEventID: event.ID,
+			State:           EntryStateUnaffiliated.String(),
+		}
+		if err := a.CheckTimesheetOpen(tenantID, employeeID, entry.Start); err != nil {
+			skip(err.Error())
+			continue
+		}
+		validation, err := a.ValidateEntry(&entry, nil, now)
+		if err != nil {
+			return nil, err
+		}
+		if err := validation.Err(); err != nil {
+			skip(err.Error())
+			continue
+		}
+		if err := a.DB.Create(&entry).Error; err != nil {
+			return nil, fmt.Errorf("failed to create entry for event %s: %w", event.ID, err)
+		}
+		if err := a.AssociateEntry(&entry, entry.ProjectID); err != nil {
+			log.Printf("Imported entry %d for event %s was left unaffiliated: %v", entry.ID, event.ID, err)
+		}
+		imported[event.ID] = true
+		result.Created = append(result.Created, entry)
+	}
+	log.Printf("Imported %d calendar events for employee %d, skipped %d", len(result.Created), employeeID, len(result.Skipped))
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeCalendarService serves a fixed set of events in place of Google Calendar
+type fakeCalendarService struct {
+	events []CalendarEvent
+}
+
+func (f fakeCalendarService) ListEvents(calendarID string, start, end time.Time) ([]CalendarEvent, error) {
+	var events []CalendarEvent
+	for _, event := range f.events {
+		if !event.Start.Before(start) && event.Start.Before(end) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f fakeCalendarService) GetEvent(calendarID, eventID string) (CalendarEvent, error) {
+	for _, event := range f.events {
+		if event.ID == eventID {
+			return event, nil
+		}
+	}
+	return CalendarEvent{}, errors.New("event not found")
+}
+
+// TestImportCalendarEvents tests that events are mapped to billing codes by attendee domain, title
+// keyword and calendar in priority order, and that importing again skips events already imported
+func TestImportCalendarEvents(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "calendar", Name: "Calendar Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	client := Account{TenantID: tenant.ID, Name: "Acme", Type: AccountTypeClient.String(), Website: "https://www.acme.com/about"}
+	internal := Account{TenantID: tenant.ID, Name: "Internal", Type: AccountTypeInternal.String()}
+	db.Create(&client)
+	db.Create(&internal)
+	clientProject := Project{TenantID: tenant.ID, Name: "Acme Rollout", AccountID: client.ID}
+	internalProject := Project{TenantID: tenant.ID, Name: "Operations", AccountID: internal.ID}
+	db.Create(&clientProject)
+	db.Create(&internalProject)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 150}
+	db.Create(&rate)
+	acmeCode := BillingCode{TenantID: tenant.ID, Name: "Acme Meetings", Code: "ACME", ProjectID: clientProject.ID, RateID: rate.ID, RoundedTo: 15}
+	opsCode := BillingCode{TenantID: tenant.ID, Name: "Standups", Code: "OPS", ProjectID: internalProject.ID, RateID: rate.ID, RoundedTo: 15}
+	hiringCode := BillingCode{TenantID: tenant.ID, Name: "Hiring", Code: "HIRE", ProjectID: internalProject.ID, RateID: rate.ID, RoundedTo: 15}
+	for _, code := range []*BillingCode{&acmeCode, &opsCode, &hiringCode} {
+		db.Create(code)
+	}
+	employee := Employee{TenantID: tenant.ID, FirstName: "Jordan"}
+	db.Create(&employee)
+
+	rules := []CalendarMappingRule{
+		{TenantID: tenant.ID, MatchType: CalendarMatchKeyword.String(), Pattern: "Standup", BillingCodeID: opsCode.ID, Priority: 1},
+		{TenantID: tenant.ID, MatchType: CalendarMatchDomain.String(), BillingCodeID: acmeCode.ID, Priority: 2},
+		{TenantID: tenant.ID, MatchType: CalendarMatchCalendar.String(), Pattern: "interviews@group.calendar.google.com", BillingCodeID: hiringCode.ID, Priority: 3},
+	}
+	for i := range rules {
+		if err := ValidateCalendarMappingRule(&rules[i]); err != nil {
+			t.Fatalf("Invalid mapping rule: %v", err)
+		}
+		db.Create(&rules[i])
+	}
+	if err := ValidateCalendarMappingRule(&CalendarMappingRule{MatchType: CalendarMatchKeyword.String(), BillingCodeID: opsCode.ID}); !errors.Is(err, ErrInvalidCalendarMapping) {
+		t.Errorf("Expected a keyword rule without a pattern to be refused, got %v", err)
+	}
+
+	monday := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	at := func(day, hour int) time.Time { return monday.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour) }
+	service := fakeCalendarService{events: []CalendarEvent{
+		// The standup has an Acme attendee too, but the keyword rule comes first
+		{ID: "standup", Summary: "Daily standup", Start: at(0, 9), End: at(0, 10), Attendees: []string{"pat@acme.com"}},
+		{ID: "kickoff", Summary: "Rollout kickoff", Start: at(1, 13), End: at(1, 15), Attendees: []string{"jordan@example.com", "Sam@ACME.com"}},
+		{ID: "interview", CalendarID: "interviews@group.calendar.google.com", Summary: "Candidate interview", Start: at(2, 11), End: at(2, 12)},
+		{ID: "lunch", Summary: "Lunch", Start: at(3, 12), End: at(3, 13)},
+		{ID: "offsite", Summary: "Offsite"},
+	}}
+
+	result, err := app.ImportCalendarEvents(tenant.ID, employee.ID, service, CalendarImport{Start: monday, End: monday.AddDate(0, 0, 7)}, monday)
+	if err != nil {
+		t.Fatalf("Failed to import events: %v", err)
+	}
+	codes := make(map[string]uint)
+	for _, entry := range result.Created {
+		codes[entry.ExternalEventID] = entry.BillingCodeID
+		if !entry.IsMeeting || entry.EmployeeID != employee.ID {
+			t.Errorf("Expected a meeting entry for the employee, got %+v", entry)
+		}
+	}
+	if len(result.Created) != 3 || codes["standup"] != opsCode.ID || codes["kickoff"] != acmeCode.ID || codes["interview"] != hiringCode.ID {
+		t.Errorf("Expected standup, kickoff and interview mapped to OPS, ACME and HIRE, got %v", codes)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].EventID != "lunch" {
+		t.Errorf("Expected only lunch to be skipped for having no matching rule, got %+v", result.Skipped)
+	}
+
+	// Selecting events again skips the ones already imported
+	result, err = app.ImportCalendarEvents(tenant.ID, employee.ID, service, CalendarImport{EventIDs: []string{"kickoff", "offsite", "missing"}}, monday)
+	if err != nil {
+		t.Fatalf("Failed to import selected events: %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, skipped := range result.Skipped {
+		reasons[skipped.EventID] = skipped.Reason
+	}
+	if len(result.Created) != 0 || reasons["kickoff"] != "already imported" || reasons["offsite"] != "not a timed event" || reasons["missing"] == "" {
+		t.Errorf("Expected every selected event to be skipped, got %d created and %v", len(result.Created), reasons)
+	}
+	var entries int64
+	db.Model(&Entry{}).Where("employee_id = ?", employee.ID).Count(&entries)
+	if entries != 3 {
+		t.Errorf("Expected 3 imported entries, got %d", entries)
+	}
+}
@@ -0,0 +1,187 @@
+package cronos
+
+import (
+	"fmt"
+	"time"
+)
+
+// WeeklyUtilization is an assignment's logged hours against its commitment in one week
+type WeeklyUtilization struct {
+	WeekStart           string  `json:"week_start"`
+	ActualHours         float64 `json:"actual_hours"`
+	Commitment          int     `json:"commitment"`
+	LeaveHours          float64 `json:"leave_hours"`          // Approved leave and holidays in the week
+	AvailableCommitment float64 `json:"available_commitment"` // Commitment less the share of the week off
+	Utilization         float64 `json:"utilization"`          // Percentage (0-100+) of the available commitment
+}
+
+// AssignmentUtilization is a staffing assignment with its utilization in each week it has a commitment,
+// keyed by the week's Sunday
+type AssignmentUtilization struct {
+	StaffingAssignment
+	Segments          []CommitmentSegment          `json:"segments"` // Parsed commitment segments
+	WeeklyUtilization map[string]WeeklyUtilization `json:"weekly_utilization"`
+}
+
+// WeeklyHoursSummaryItem is the hours logged on a set of projects in a week against the hours staffed
+type WeeklyHoursSummaryItem struct {
+	WeekStartDate string  `json:"week_start_date"`
+	BilledHours   float64 `json:"billed_hours"`
+	TargetHours   float64 `json:"target_hours"`
+}
+
+// CapacityWeekStart returns the Sunday, in UTC, of the week containing t. The capacity view lays out
+// weeks from Sunday, unlike timesheets, which start on Monday.
+func CapacityWeekStart(t time.Time) time.Time {
+	day := leaveDay(t)
+	return day.AddDate(0, 0, -int(day.Weekday()))
+}
+
+// WeeklyAssignmentHours totals the hours logged against each staffing assignment by the Sunday of the
+// week they started in, keyed by assignment and then "2006-01-02". Entries are bucketed here rather
+// than in SQL so it runs the same on Postgres and SQLite.
+func (a *App) WeeklyAssignmentHours(tenantID uint, assignmentIDs []uint) (map[uint]map[string]float64, error) {
+	hours := make(map[uint]map[string]float64)
+	if len(assignmentIDs) == 0 {
+		return hours, nil
+	}
+	var rows []struct {
+		StaffingAssignmentID uint
+		Start                time.Time
+		DurationMinutes      float64
+	}
+	if err := a.DB.Model(&Entry{}).Scopes(TenantScope(tenantID)).
+		Select("staffing_assignment_id", "start", "duration_minutes").
+		Where("staffing_assignment_id IN ?", assignmentIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load assignment hours: %w", err)
+	}
+	for _, row := range rows {
+		if _, ok := hours[row.StaffingAssignmentID]; !ok {
+			hours[row.StaffingAssignmentID] = make(map[string]float64)
+		}
+		hours[row.StaffingAssignmentID][CapacityWeekStart(row.Start).Format("2006-01-02")] += row.DurationMinutes / 60.0
+	}
+	return hours, nil
+}
+
+// assignmentsTimeOff loads the time off of every employee across the assignments' date range
+func (a *App) assignmentsTimeOff(tenantID uint, assignments []StaffingAssignment) (map[uint]map[string]float64, error) {
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+	from, to := assignments[0].StartDate, assignments[0].EndDate
+	for _, assignment := range assignments[1:] {
+		if assignment.StartDate.Before(from) {
+			from = assignment.StartDate
+		}
+		if assignment.EndDate.After(to) {
+			to = assignment.EndDate
+		}
+	}
+	// Widen to whole weeks so partial weeks at either end are counted in full
+	return a.TimeOffByDay(tenantID, from.AddDate(0, 0, -7), to.AddDate(0, 0, 7))
+}
+
+// CapacityUtilization works out each assignment's utilization in every week of its range that has a
+// commitment: the hours logged against the commitment left once leave and holidays are taken out. The
+// assignments should have their Employee loaded.
+func (a *App) CapacityUtilization(tenantID uint, assignments []StaffingAssignment) ([]AssignmentUtilization, error) {
+	assignmentIDs := make([]uint, len(assignments))
+	for i := range assignments {
+		assignmentIDs[i] = assignments[i].ID
+	}
+	hours, err := a.WeeklyAssignmentHours(tenantID, assignmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	timeOff, err := a.assignmentsTimeOff(tenantID, assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AssignmentUtilization, len(assignments))
+	for i := range assignments {
+		assignment := &assignments[i]
+		weekly := make(map[string]WeeklyUtilization)
+		end := leaveDay(assignment.EndDate)
+		for week := CapacityWeekStart(assignment.StartDate); !week.After(end); week = week.AddDate(0, 0, 7) {
+			commitment := assignment.GetCommitmentForWeek(week)
+			if commitment <= 0 {
+				continue
+			}
+			key := week.Format("2006-01-02")
+			actual := hours[assignment.ID][key]
+			// Leave and holidays reduce what the employee can deliver that week
+			leaveHours := TimeOffInWeek(timeOff[assignment.EmployeeID], week)
+			available := AvailableCommitment(commitment, &assignment.Employee, leaveHours)
+			utilization := 0.0
+			if available > 0 {
+				utilization = actual / available * 100
+			}
+			weekly[key] = WeeklyUtilization{
+				WeekStart:           key,
+				ActualHours:         actual,
+				Commitment:          commitment,
+				LeaveHours:          leaveHours,
+				AvailableCommitment: available,
+				Utilization:         utilization,
+			}
+		}
+		results[i] = AssignmentUtilization{
+			StaffingAssignment: *assignment,
+			Segments:           assignment.GetSegments(),
+			WeeklyUtilization:  weekly,
+		}
+	}
+	return results, nil
+}
+
+// WeeklyHoursSummary returns, for each of the given number of Monday-start weeks up to the one
+// containing now, the hours logged on the projects and the commitments of the assignments staffed on
+// them that week, oldest week first
+func (a *App) WeeklyHoursSummary(tenantID uint, projectIDs []uint, now time.Time, weeks int) ([]WeeklyHoursSummaryItem, error) {
+	results := make([]WeeklyHoursSummaryItem, 0, weeks)
+	if len(projectIDs) == 0 || weeks <= 0 {
+		return results, nil
+	}
+	offset := (int(now.Weekday()) + 6) % 7
+	currentMonday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -offset)
+	first := currentMonday.AddDate(0, 0, -7*(weeks-1))
+	last := currentMonday.AddDate(0, 0, 7)
+
+	var entries []Entry
+	if err := a.DB.Scopes(TenantScope(tenantID)).Select("start", "end").
+		Where("project_id IN ? AND state != ? AND start >= ? AND start < ?", projectIDs, EntryStateVoid.String(), first, last).
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load entries: %w", err)
+	}
+	var assignments []StaffingAssignment
+	if err := a.DB.Scopes(TenantScope(tenantID)).
+		Where("project_id IN ? AND start_date < ? AND end_date >= ?", projectIDs, last, first).
+		Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load staffing assignments: %w", err)
+	}
+
+	for i := 0; i < weeks; i++ {
+		weekStart := first.AddDate(0, 0, 7*i)
+		weekEnd := weekStart.AddDate(0, 0, 7)
+		item := WeeklyHoursSummaryItem{WeekStartDate: weekStart.Format("2006-01-02")}
+		for _, entry := range entries {
+			if entry.Start.Before(weekStart) || !entry.Start.Before(weekEnd) {
+				continue
+			}
+			if !entry.End.IsZero() && entry.End.After(entry.Start) {
+				item.BilledHours += entry.End.Sub(entry.Start).Hours()
+			}
+		}
+		// An assignment counts toward every week it overlaps
+		for _, assignment := range assignments {
+			if assignment.StartDate.Before(weekEnd) && !assignment.EndDate.Before(weekStart) {
+				item.TargetHours += float64(assignment.Commitment)
+			}
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
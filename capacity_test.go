@@ -0,0 +1,72 @@
+package cronos
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCapacityUtilization tests that hours are bucketed into Sunday-start weeks on SQLite, that a
+// holiday reduces the week's available commitment, and that the weekly hours summary totals Monday-start
+// weeks
+func TestCapacityUtilization(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "capacity", Name: "Capacity Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	employee := Employee{TenantID: tenant.ID, FirstName: "Jordan", IsActive: true, CapacityWeekly: 40}
+	db.Create(&employee)
+	account := Account{TenantID: tenant.ID, Name: "Capacity Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Capacity Project", AccountID: account.ID}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 100}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Build", Code: "BLD", ProjectID: project.ID, RateID: rate.ID}
+	db.Create(&code)
+	assignment := StaffingAssignment{TenantID: tenant.ID, EmployeeID: employee.ID, ProjectID: project.ID, Commitment: 20,
+		StartDate: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 3, 22, 0, 0, 0, 0, time.UTC)}
+	db.Create(&assignment)
+	db.Create(&Holiday{TenantID: tenant.ID, Date: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), Name: "Founders Day"})
+
+	logHours := func(start time.Time, hours int) {
+		entry := Entry{TenantID: tenant.ID, ProjectID: project.ID, BillingCodeID: code.ID, EmployeeID: employee.ID,
+			StaffingAssignmentID: &assignment.ID, Start: start, End: start.Add(time.Duration(hours) * time.Hour),
+			State: EntryStateDraft.String()}
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+	}
+	logHours(time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC), 10)
+	logHours(time.Date(2025, 3, 9, 9, 0, 0, 0, time.UTC), 4) // A Sunday opens the next capacity week
+	logHours(time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC), 4)
+
+	db.Preload("Employee").First(&assignment, assignment.ID)
+	results, err := app.CapacityUtilization(tenant.ID, []StaffingAssignment{assignment})
+	if err != nil {
+		t.Fatalf("Failed to calculate utilization: %v", err)
+	}
+	weeks := results[0].WeeklyUtilization
+	if len(weeks) != 3 {
+		t.Fatalf("Expected three weeks of utilization, got %+v", weeks)
+	}
+	if week := weeks["2025-03-02"]; week.ActualHours != 10 || week.Utilization != 50 {
+		t.Errorf("Expected 10 hours and 50%% in the first week, got %+v", week)
+	}
+	if week := weeks["2025-03-09"]; week.ActualHours != 8 || week.LeaveHours != 8 || week.AvailableCommitment != 16 || week.Utilization != 50 {
+		t.Errorf("Expected 8 of the 16 hours left after the holiday, got %+v", week)
+	}
+	if week := weeks["2025-03-16"]; week.ActualHours != 0 || week.Commitment != 20 {
+		t.Errorf("Expected an empty third week with the full commitment, got %+v", week)
+	}
+
+	summary, err := app.WeeklyHoursSummary(tenant.ID, []uint{project.ID}, time.Date(2025, 3, 19, 12, 0, 0, 0, time.UTC), 3)
+	if err != nil {
+		t.Fatalf("Failed to summarize weekly hours: %v", err)
+	}
+	if len(summary) != 3 || summary[0].WeekStartDate != "2025-03-03" || summary[0].BilledHours != 14 ||
+		summary[1].BilledHours != 4 || summary[2].BilledHours != 0 || summary[2].TargetHours != 20 {
+		t.Errorf("Expected 14, 4 and 0 hours against 20 in the weeks from March 3, got %+v", summary)
+	}
+}
@@ -163,6 +163,9 @@ func (a *App) SeedSystemAccounts() error {
 		{AccountCode: "ACCRUED_PAYROLL", AccountName: "Accrued Payroll", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Payroll owed but not yet paid"},
 		{AccountCode: "ACCOUNTS_PAYABLE", AccountName: "Accounts Payable", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Bills owed to employees and vendors"},
 		{AccountCode: "ACCRUED_EXPENSES_PAYABLE", AccountName: "Accrued Expenses Payable", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Expenses recorded but not yet reconciled with bank statements"},
+		{AccountCode: "CUSTOMER_CREDITS", AccountName: "Customer Credits", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Client overpayments held as unapplied credit"},
+		{AccountCode: "SALES_TAX_PAYABLE", AccountName: "Sales Tax Payable", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Sales tax and VAT collected on invoices, owed to each jurisdiction"},
+		{AccountCode: "DEFERRED_REVENUE", AccountName: "Deferred Revenue", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Retainers billed in advance, recognized as the work is done"},
 		{AccountCode: "CREDIT_CARD_PAYABLE", AccountName: "Credit Card Payable", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Credit card balances"},
 		{AccountCode: "OTHER_LIABILITIES", AccountName: "Other Liabilities", AccountType: "LIABILITY", IsSystemDefined: true, Description: "Miscellaneous liabilities"},
 
@@ -193,6 +196,7 @@ func (a *App) SeedSystemAccounts() error {
 		{AccountCode: "OPERATING_EXPENSES_REIMBURSABLE", AccountName: "Operating Expenses - Reimbursable", AccountType: "EXPENSE", IsSystemDefined: true, Description: "Employee expenses to be reimbursed"},
 		{AccountCode: "EXPENSE_PASS_THROUGH", AccountName: "Pass-Through Expenses", AccountType: "EXPENSE", IsSystemDefined: true, Description: "Client expenses to be reimbursed"},
 		{AccountCode: "OTHER_EXPENSES", AccountName: "Other Expenses", AccountType: "EXPENSE", IsSystemDefined: true, Description: "Miscellaneous expenses"},
+		{AccountCode: "REALIZED_FX_GAIN_LOSS", AccountName: "Realized FX Gain/Loss", AccountType: "EXPENSE", IsSystemDefined: true, Description: "Exchange differences on settling foreign-currency invoices (gains are credits)"},
 
 		// Equity
 		{AccountCode: "EQUITY_OWNERSHIP", AccountName: "Equity - Ownership", AccountType: "EQUITY", IsSystemDefined: true, Description: "Owner equity"},
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ledgerApp returns the app to post journals with. Admins who give a reason may post into soft-closed
+// periods; anyone else gets the regular app, which refuses them.
+func (a *App) ledgerApp(r *http.Request, overrideReason string) *cronos.App {
+	role, _ := r.Context().Value("user_role").(string)
+	userID, _ := r.Context().Value("user_id").(uint)
+	if overrideReason == "" || role != cronos.UserRoleAdmin.String() {
+		return a.cronosApp
+	}
+	return a.cronosApp.WithPeriodOverride(userID, overrideReason)
+}
+
+// ListAccountingPeriodsHandler lists the tenant's accounting periods
+// GET /api/cronos/accounting-periods
+func (a *App) ListAccountingPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	periods, err := a.cronosApp.ListAccountingPeriods(tenant.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load accounting periods")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, periods)
+}
+
+// OpenAccountingPeriodHandler opens the monthly period containing a date
+// POST /api/cronos/accounting-periods
+// Body: { "month": "2025-01" }
+func (a *App) OpenAccountingPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var reqBody struct {
+		Month string `json:"month"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	month, err := time.Parse("2006-01", reqBody.Month)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid month format (use YYYY-MM)")
+		return
+	}
+
+	period, err := a.cronosApp.OpenAccountingPeriod(tenant.ID, month)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to open accounting period")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, period)
+}
+
+// AccountingPeriodChecklistHandler runs the close checklist for a period without changing it
+// GET /api/cronos/accounting-periods/{id}/checklist
+func (a *App) AccountingPeriodChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var period cronos.AccountingPeriod
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&period, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Accounting period not found")
+		return
+	}
+
+	checklist, err := a.cronosApp.RunCloseChecklist(&period)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to run close checklist")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, checklist)
+}
+
+// AccountingPeriodStateHandler soft-closes, closes or reopens a period. Admin only.
+// POST /api/cronos/accounting-periods/{id}/{state:soft-close|close|reopen}
+func (a *App) AccountingPeriodStateHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if role, _ := r.Context().Value("user_role").(string); role != cronos.UserRoleAdmin.String() {
+		respondWithError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+	userID, _ := r.Context().Value("user_id").(uint)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid period ID")
+		return
+	}
+	states := map[string]cronos.AccountingPeriodState{
+		"soft-close": cronos.AccountingPeriodStateSoftClosed,
+		"close":      cronos.AccountingPeriodStateClosed,
+		"reopen":     cronos.AccountingPeriodStateOpen,
+	}
+
+	period, checklist, err := a.cronosApp.SetAccountingPeriodState(tenant.ID, uint(id), states[vars["state"]], userID)
+	switch {
+	case errors.Is(err, cronos.ErrPeriodNotFound):
+		respondWithError(w, http.StatusNotFound, "Accounting period not found")
+	case errors.Is(err, cronos.ErrPeriodChecklistFailed):
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":     err.Error(),
+			"checklist": checklist,
+		})
+	case errors.Is(err, cronos.ErrInvalidPeriodTransition):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Failed to update accounting period")
+	default:
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"period":    period,
+			"checklist": checklist,
+		})
+	}
+}
+
+// ListPeriodOverridesHandler lists postings admins have made into soft-closed periods
+// GET /api/cronos/accounting-periods/overrides
+func (a *App) ListPeriodOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	overrides, err := a.cronosApp.ListPeriodOverrides(tenant.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load period overrides")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, overrides)
+}
@@ -130,85 +130,7 @@ func (a *App) PortalProjectBudgetsHandler(w http.ResponseWriter, r *http.Request
 		}
 
 		// Calculate Total Project Budget Hours and Dollars
-		if !project.ActiveStart.IsZero() && !project.ActiveEnd.IsZero() && project.ActiveEnd.After(project.ActiveStart) {
-			durationDays := project.ActiveEnd.Sub(project.ActiveStart).Hours() / 24.0
-			var numPeriods float64 = 1.0 // Default for project-based or if calculation below is not met
-
-			// If BudgetCapHours and BudgetCapDollars are set, use them directly
-			if project.BudgetCapHours > 0 {
-				status.CalculatedTotalProjectBudgetHours = float64(project.BudgetCapHours)
-			} else {
-				// Otherwise, calculate based on billing frequency and periodic budget
-				switch project.BillingFrequency {
-				case cronos.BillingFrequencyProject.String():
-					status.CalculatedTotalProjectBudgetHours = float64(project.BudgetHours)
-				case cronos.BillingFrequencyMonthly.String():
-					// Calculate number of months project is active, rounding up.
-					// (Year2 - Year1)*12 + Month2 - Month1 + 1
-					numMonths := float64((project.ActiveEnd.Year()-project.ActiveStart.Year())*12 + int(project.ActiveEnd.Month()) - int(project.ActiveStart.Month()) + 1)
-					if project.ActiveEnd.Day() < project.ActiveStart.Day() && int(project.ActiveEnd.Month()) == int(project.ActiveStart.Month()) && project.ActiveEnd.Year() == project.ActiveStart.Year() {
-						// If end day is before start day in the same month/year (e.g. start Jan 15, end Jan 10), effectively 0 full months by this simple calc.
-						// This needs to be handled more carefully if precise month counting is critical vs. "active in month X".
-						// For simplicity, if it spans at least one day into a month, that month counts.
-						// The +1 above generally handles "active in this month"
-					}
-					numPeriods = numMonths
-					status.CalculatedTotalProjectBudgetHours = float64(project.BudgetHours) * numPeriods
-				case cronos.BillingFrequencyWeekly.String():
-					if durationDays > 0 {
-						numPeriods = math.Ceil(durationDays / 7.0)
-					}
-					status.CalculatedTotalProjectBudgetHours = float64(project.BudgetHours) * numPeriods
-				case cronos.BillingFrequencyBiweekly.String():
-					if durationDays > 0 {
-						numPeriods = math.Ceil(durationDays / 14.0)
-					}
-					status.CalculatedTotalProjectBudgetHours = float64(project.BudgetHours) * numPeriods
-				default: // Unknown or other types
-					status.CalculatedTotalProjectBudgetHours = float64(project.BudgetHours) // Default to face value
-				}
-			}
-
-			// If BudgetCapDollars is set, use it directly
-			if project.BudgetCapDollars > 0 {
-				status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetCapDollars)
-			} else {
-				// Otherwise, calculate based on billing frequency and periodic budget
-				switch project.BillingFrequency {
-				case cronos.BillingFrequencyProject.String():
-					status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetDollars)
-				case cronos.BillingFrequencyMonthly.String():
-					numMonths := float64((project.ActiveEnd.Year()-project.ActiveStart.Year())*12 + int(project.ActiveEnd.Month()) - int(project.ActiveStart.Month()) + 1)
-					status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetDollars) * numMonths
-				case cronos.BillingFrequencyWeekly.String():
-					if durationDays > 0 {
-						numPeriods = math.Ceil(durationDays / 7.0)
-					}
-					status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetDollars) * numPeriods
-				case cronos.BillingFrequencyBiweekly.String():
-					if durationDays > 0 {
-						numPeriods = math.Ceil(durationDays / 14.0)
-					}
-					status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetDollars) * numPeriods
-				default: // Unknown or other types
-					status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetDollars) // Default to face value
-				}
-			}
-		} else { // If project dates are invalid, use face values
-			// If BudgetCapHours is set, use it directly
-			if project.BudgetCapHours > 0 {
-				status.CalculatedTotalProjectBudgetHours = float64(project.BudgetCapHours)
-			} else {
-				status.CalculatedTotalProjectBudgetHours = float64(project.BudgetHours)
-			}
-
-			// If BudgetCapDollars is set, use it directly
-			if project.BudgetCapDollars > 0 {
-				status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetCapDollars)
-			} else {
-				status.CalculatedTotalProjectBudgetDollars = float64(project.BudgetDollars)
-			}
-		}
+		status.CalculatedTotalProjectBudgetHours, status.CalculatedTotalProjectBudgetDollars = cronos.ProjectBudgetTotals(&project)
 
 		// Calculate Current Period Budget & Usage
 		var currentPeriodStart, currentPeriodEnd time.Time
@@ -285,13 +207,6 @@ func (a *App) PortalProjectBudgetsHandler(w http.ResponseWriter, r *http.Request
 	respondWithJSON(w, http.StatusOK, results)
 }
 
-// WeeklyHoursSummaryItem defines the structure for the weekly hours summary.
-type WeeklyHoursSummaryItem struct {
-	WeekStartDate string  `json:"week_start_date"`
-	BilledHours   float64 `json:"billed_hours"`
-	TargetHours   float64 `json:"target_hours"`
-}
-
 const numWeeksForSummary = 12
 
 // PortalWeeklyHoursSummaryHandler serves weekly billed vs target hours.
@@ -312,101 +227,27 @@ func (a *App) PortalWeeklyHoursSummaryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if len(projects) == 0 {
-		respondWithJSON(w, http.StatusOK, []WeeklyHoursSummaryItem{}) // No projects, return empty summary
-		return
-	}
-
 	var projectIDs []uint
 	for _, p := range projects {
 		projectIDs = append(projectIDs, p.ID)
 	}
 
-	var results []WeeklyHoursSummaryItem
-	now := time.Now()
-
-	// Determine the Monday of the current week
-	weekday := now.Weekday()
-	offset := int(time.Monday - weekday)
-	if weekday == time.Sunday { // In Go, Sunday is 0, Monday is 1. If today is Sunday, Monday was 6 days ago.
-		offset = -6
-	} else if weekday == time.Saturday { // If Saturday, Monday was 5 days ago
-		offset = -5
-	} // For Mon-Fri, offset will be 0 to -4
-
-	currentWeekMonday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, offset)
-
-	for i := 0; i < numWeeksForSummary; i++ {
-		// Iterate backwards from the current week
-		weekStart := currentWeekMonday.AddDate(0, 0, -7*(numWeeksForSummary-1-i))
-		weekEnd := weekStart.AddDate(0, 0, 7) // Exclusive end (start of next Monday)
-
-		var totalBilledHoursThisWeek float64
-		var entries []cronos.Entry
-		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where(
-			"project_id IN (?) AND state != ? AND deleted_at IS NULL AND start >= ? AND start < ?",
-			projectIDs, cronos.EntryStateVoid.String(), weekStart, weekEnd,
-		).Find(&entries).Error; err != nil {
-			log.Printf("Error: PortalWeeklyHoursSummary - Failed to retrieve entries for week starting %s: %v", weekStart.Format("2006-01-02"), err)
-			// Continue to next week or return error? For robustness, let's try to build partial data.
-			// Or, one might choose to respondWithError here.
-		}
-		for _, entry := range entries {
-			if !entry.Start.IsZero() && !entry.End.IsZero() && entry.End.After(entry.Start) {
-				duration := entry.End.Sub(entry.Start)
-				totalBilledHoursThisWeek += duration.Hours()
-			}
-		}
-
-		var totalTargetHoursThisWeek float64
-		var assignments []cronos.StaffingAssignment // Assuming cronos.StaffingAssignment exists
-		// An assignment is active in a week if: assignment.StartDate <= weekEnd (exclusive end of our week i.e. start of next Monday)
-		// AND assignment.EndDate >= weekStart (inclusive start of our week i.e. Monday 00:00)
-		// Note: weekEnd for query should be the actual end of Sunday for assignments that might end on Sunday.
-		actualWeekEnd := weekEnd.Add(-time.Nanosecond) // End of Sunday for precise overlap query
-
-		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where(
-			"project_id IN (?) AND start_date <= ? AND end_date >= ? AND deleted_at IS NULL",
-			projectIDs, actualWeekEnd, weekStart,
-		).Find(&assignments).Error; err != nil {
-			log.Printf("Error: PortalWeeklyHoursSummary - Failed to retrieve staffing assignments for week starting %s: %v", weekStart.Format("2006-01-02"), err)
-		}
-		for _, assignment := range assignments {
-			totalTargetHoursThisWeek += float64(assignment.Commitment)
-		}
-
-		results = append(results, WeeklyHoursSummaryItem{
-			WeekStartDate: weekStart.Format("2006-01-02"),
-			BilledHours:   totalBilledHoursThisWeek,
-			TargetHours:   totalTargetHoursThisWeek,
-		})
+	results, err := a.cronosApp.WeeklyHoursSummary(tenant.ID, projectIDs, time.Now(), numWeeksForSummary)
+	if err != nil {
+		log.Printf("Error: PortalWeeklyHoursSummary - %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve weekly hours.")
+		return
 	}
-
 	respondWithJSON(w, http.StatusOK, results)
 }
 
-// WeeklyUtilization represents actual hours worked in a specific week
-type WeeklyUtilization struct {
-	WeekStart   string  `json:"week_start"`
-	ActualHours float64 `json:"actual_hours"`
-	Commitment  int     `json:"commitment"`
-	Utilization float64 `json:"utilization"` // Percentage (0-100+)
-}
-
-// AssignmentWithUtilization combines staffing assignment with weekly utilization data
-type AssignmentWithUtilization struct {
-	cronos.StaffingAssignment
-	Segments          []cronos.CommitmentSegment   `json:"segments"` // Parsed commitment segments
-	WeeklyUtilization map[string]WeeklyUtilization `json:"weekly_utilization"`
-}
-
 // CapacityDataHandler fetches staffing assignments for capacity management view with utilization data
 func (a *App) CapacityDataHandler(w http.ResponseWriter, r *http.Request) {
 	tenant := MustGetTenant(r.Context())
 	var assignments []cronos.StaffingAssignment
 
 	// Fetch all staffing assignments with employee and project preloaded (but NOT entries)
-	if err := a.cronosApp.DB.
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).
 		Preload("Employee.HeadshotAsset").
 		Preload("Project").
 		Preload("Project.Account").
@@ -416,101 +257,12 @@ func (a *App) CapacityDataHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch aggregated hours by assignment and week using a single SQL query
-	// This is MUCH faster than loading all entries into memory
-	type WeeklyHours struct {
-		StaffingAssignmentID uint
-		WeekStart            time.Time
-		TotalMinutes         float64
-	}
-
-	var weeklyHours []WeeklyHours
-	// Use raw SQL for optimal performance - aggregate hours by week for each assignment (within tenant)
-	// Note: We calculate week start as Sunday to match frontend logic
-	err := a.cronosApp.DB.Raw(`
-		SELECT 
-			staffing_assignment_id,
-			DATE_TRUNC('week', start AT TIME ZONE 'UTC') - INTERVAL '1 day' as week_start,
-			SUM(duration_minutes) as total_minutes
-		FROM entries
-		WHERE staffing_assignment_id IS NOT NULL
-		  AND deleted_at IS NULL
-		  AND tenant_id = ?
-		GROUP BY staffing_assignment_id, week_start
-		ORDER BY staffing_assignment_id, week_start
-	`, tenant.ID).Scan(&weeklyHours).Error
-
+	response, err := a.cronosApp.CapacityUtilization(tenant.ID, assignments)
 	if err != nil {
-		log.Printf("Error fetching weekly hours: %v", err)
+		log.Printf("Error calculating utilization: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch utilization data")
 		return
 	}
-
-	// Build a map of assignment_id -> week_start -> total_hours for fast lookup
-	hoursMap := make(map[uint]map[string]float64)
-	for _, wh := range weeklyHours {
-		if _, exists := hoursMap[wh.StaffingAssignmentID]; !exists {
-			hoursMap[wh.StaffingAssignmentID] = make(map[string]float64)
-		}
-		weekStartStr := wh.WeekStart.Format("2006-01-02")
-		hoursMap[wh.StaffingAssignmentID][weekStartStr] = wh.TotalMinutes / 60.0
-	}
-
-	// Build response with utilization data
-	response := make([]AssignmentWithUtilization, len(assignments))
-	for i, assignment := range assignments {
-		weeklyUtil := make(map[string]WeeklyUtilization)
-
-		// Initialize utilization for all weeks in the assignment period with commitments
-		assignmentStart := assignment.StartDate
-		assignmentEnd := assignment.EndDate
-
-		// Iterate through all weeks in the assignment range, normalized to UTC
-		currentWeek := assignmentStart.UTC()
-		currentWeek = time.Date(currentWeek.Year(), currentWeek.Month(), currentWeek.Day(), 0, 0, 0, 0, time.UTC)
-		currentWeek = currentWeek.AddDate(0, 0, -int(currentWeek.Weekday())) // Start of week (Sunday)
-
-		assignmentEndUTC := assignmentEnd.UTC()
-		assignmentEndUTC = time.Date(assignmentEndUTC.Year(), assignmentEndUTC.Month(), assignmentEndUTC.Day(), 0, 0, 0, 0, time.UTC)
-
-		for currentWeek.Before(assignmentEndUTC) || currentWeek.Equal(assignmentEndUTC) {
-			weekStartStr := currentWeek.Format("2006-01-02")
-			weekCommitment := assignment.GetCommitmentForWeek(currentWeek)
-
-			// Only initialize if there's a commitment for this week
-			if weekCommitment > 0 {
-				// Get actual hours from the precomputed map
-				actualHours := 0.0
-				if assignmentHours, exists := hoursMap[assignment.ID]; exists {
-					if hours, hasHours := assignmentHours[weekStartStr]; hasHours {
-						actualHours = hours
-					}
-				}
-
-				utilization := 0.0
-				if weekCommitment > 0 {
-					utilization = (actualHours / float64(weekCommitment)) * 100
-				}
-
-				weeklyUtil[weekStartStr] = WeeklyUtilization{
-					WeekStart:   weekStartStr,
-					Commitment:  weekCommitment,
-					ActualHours: actualHours,
-					Utilization: utilization,
-				}
-			}
-
-			// Move to next week
-			currentWeek = currentWeek.AddDate(0, 0, 7)
-		}
-
-		response[i] = AssignmentWithUtilization{
-			StaffingAssignment: assignment,
-			Segments:           assignment.GetSegments(),
-			WeeklyUtilization:  weeklyUtil,
-		}
-	}
-
 	respondWithJSON(w, http.StatusOK, response)
 }
 
@@ -584,7 +336,7 @@ func (a *App) PortalCapacityDataHandler(w http.ResponseWriter, r *http.Request)
 	// Fetch staffing assignments for projects belonging to this account only (but NOT entries)
 	if err := a.cronosApp.DB.
 		Joins("JOIN projects ON projects.id = staffing_assignments.project_id").
-		Where("projects.account_id = ?", accountID).
+		Where("projects.account_id = ? AND staffing_assignments.tenant_id = ?", accountID, tenant.ID).
 		Preload("Employee").
 		Preload("Project").
 		Preload("Project.Account").
@@ -594,107 +346,12 @@ func (a *App) PortalCapacityDataHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Fetch aggregated hours by assignment and week using a single SQL query for this account
-	type WeeklyHours struct {
-		StaffingAssignmentID uint
-		WeekStart            time.Time
-		TotalMinutes         float64
-	}
-
-	var weeklyHours []WeeklyHours
-	// Use raw SQL for optimal performance - aggregate hours by week for assignments in this account (within tenant)
-	// Note: We calculate week start as Sunday to match frontend logic
-	err := a.cronosApp.DB.Raw(`
-		SELECT 
-			e.staffing_assignment_id,
-			DATE_TRUNC('week', e.start AT TIME ZONE 'UTC') - INTERVAL '1 day' as week_start,
-			SUM(e.duration_minutes) as total_minutes
-		FROM entries e
-		INNER JOIN staffing_assignments sa ON sa.id = e.staffing_assignment_id
-		INNER JOIN projects p ON p.id = sa.project_id
-		WHERE e.staffing_assignment_id IS NOT NULL
-		  AND e.deleted_at IS NULL
-		  AND sa.deleted_at IS NULL
-		  AND p.account_id = ?
-		  AND e.tenant_id = ?
-		  AND sa.tenant_id = ?
-		  AND p.tenant_id = ?
-		GROUP BY e.staffing_assignment_id, week_start
-		ORDER BY e.staffing_assignment_id, week_start
-	`, accountID, tenant.ID, tenant.ID, tenant.ID).Scan(&weeklyHours).Error
-
+	response, err := a.cronosApp.CapacityUtilization(tenant.ID, assignments)
 	if err != nil {
-		log.Printf("Error fetching weekly hours for account %d: %v", accountID, err)
+		log.Printf("Error calculating utilization for account %d: %v", accountID, err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch utilization data")
 		return
 	}
-
-	// Build a map of assignment_id -> week_start -> total_hours for fast lookup
-	hoursMap := make(map[uint]map[string]float64)
-	for _, wh := range weeklyHours {
-		if _, exists := hoursMap[wh.StaffingAssignmentID]; !exists {
-			hoursMap[wh.StaffingAssignmentID] = make(map[string]float64)
-		}
-		weekStartStr := wh.WeekStart.Format("2006-01-02")
-		hoursMap[wh.StaffingAssignmentID][weekStartStr] = wh.TotalMinutes / 60.0
-	}
-
-	// Build response with utilization data
-	response := make([]AssignmentWithUtilization, len(assignments))
-	for i, assignment := range assignments {
-		weeklyUtil := make(map[string]WeeklyUtilization)
-
-		// Initialize utilization for all weeks in the assignment period with commitments
-		assignmentStart := assignment.StartDate
-		assignmentEnd := assignment.EndDate
-
-		// Iterate through all weeks in the assignment range, normalized to UTC
-		currentWeek := assignmentStart.UTC()
-		// Normalize to start of day at midnight UTC
-		currentWeek = time.Date(currentWeek.Year(), currentWeek.Month(), currentWeek.Day(), 0, 0, 0, 0, time.UTC)
-		currentWeek = currentWeek.AddDate(0, 0, -int(currentWeek.Weekday())) // Start of week (Sunday)
-
-		assignmentEndUTC := assignmentEnd.UTC()
-		assignmentEndUTC = time.Date(assignmentEndUTC.Year(), assignmentEndUTC.Month(), assignmentEndUTC.Day(), 0, 0, 0, 0, time.UTC)
-
-		for currentWeek.Before(assignmentEndUTC) || currentWeek.Equal(assignmentEndUTC) {
-			weekStartStr := currentWeek.Format("2006-01-02")
-			weekCommitment := assignment.GetCommitmentForWeek(currentWeek)
-
-			// Only initialize if there's a commitment for this week
-			if weekCommitment > 0 {
-				// Get actual hours from the precomputed map
-				actualHours := 0.0
-				if assignmentHours, exists := hoursMap[assignment.ID]; exists {
-					if hours, hasHours := assignmentHours[weekStartStr]; hasHours {
-						actualHours = hours
-					}
-				}
-
-				utilization := 0.0
-				if weekCommitment > 0 {
-					utilization = (actualHours / float64(weekCommitment)) * 100
-				}
-
-				weeklyUtil[weekStartStr] = WeeklyUtilization{
-					WeekStart:   weekStartStr,
-					Commitment:  weekCommitment,
-					ActualHours: actualHours,
-					Utilization: utilization,
-				}
-			}
-
-			// Move to next week
-			currentWeek = currentWeek.AddDate(0, 0, 7)
-		}
-
-		response[i] = AssignmentWithUtilization{
-			StaffingAssignment: assignment,
-			Segments:           assignment.GetSegments(),
-			WeeklyUtilization:  weeklyUtil,
-		}
-	}
-
 	respondWithJSON(w, http.StatusOK, response)
 }
 
@@ -703,6 +360,7 @@ type ProjectProfitabilityData struct {
 	ProjectID          uint      `json:"project_id"`
 	ProjectName        string    `json:"project_name"`
 	BillingFrequency   string    `json:"billing_frequency"`
+	BillingMethod      string    `json:"billing_method"`
 	ProjectActiveStart time.Time `json:"project_active_start"`
 	ProjectActiveEnd   time.Time `json:"project_active_end"`
 
@@ -723,6 +381,10 @@ type ProjectProfitabilityData struct {
 	TotalInvoiced         float64 `json:"total_invoiced"`          // Total amount invoiced
 	TotalInvoicedAccepted float64 `json:"total_invoiced_accepted"` // Total accepted invoices
 
+	// Fixed-fee projects earn their revenue from completed milestones, not from hours
+	FixedFeeTotal    float64 `json:"fixed_fee_total"`   // Sum of all the project's milestones
+	MilestoneRevenue float64 `json:"milestone_revenue"` // Sum of the completed milestones, included in TotalRevenue
+
 	// Progress indicators
 	HoursCompletion   float64 `json:"hours_completion"`   // Percentage
 	DollarsCompletion float64 `json:"dollars_completion"` // Percentage
@@ -798,6 +460,15 @@ func (a *App) ProjectProfitabilityHandler(w http.ResponseWriter, r *http.Request
 		// Continue without invoice data
 	}
 
+	// Milestones on fixed-fee projects, whose entries carry cost but no fee
+	var milestones []cronos.Milestone
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).
+		Where("project_id = ?", project.ID).
+		Find(&milestones).Error; err != nil {
+		log.Printf("Error fetching milestones for project %d: %v", projectID, err)
+		// Continue without milestone data
+	}
+
 	// Pre-calculate all entry costs in a single pass to avoid N+1 queries
 	entriesWithCosts := make([]entryWithCost, 0, len(entries))
 	var totalHours, totalRevenue, totalCost float64
@@ -831,6 +502,15 @@ func (a *App) ProjectProfitabilityHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	var fixedFeeTotal, milestoneRevenue float64
+	for _, milestone := range milestones {
+		fixedFeeTotal += float64(milestone.Amount) / 100.0
+		if milestone.Completed {
+			milestoneRevenue += float64(milestone.Amount) / 100.0
+		}
+	}
+	totalRevenue += milestoneRevenue
+
 	totalProfit := totalRevenue - totalCost
 	profitMargin := 0.0
 	if totalRevenue > 0 {
@@ -849,12 +529,17 @@ func (a *App) ProjectProfitabilityHandler(w http.ResponseWriter, r *http.Request
 			if invoice.ProjectID != nil && *invoice.ProjectID == project.ID {
 				invoiceAmount = invoice.TotalAmount
 			} else {
-				// Account-level invoice - calculate this project's portion from entries
+				// Account-level invoice - calculate this project's portion from entries and milestones
 				for _, entry := range invoice.Entries {
 					if entry.ProjectID == project.ID && entry.State != cronos.EntryStateVoid.String() {
 						invoiceAmount += float64(entry.Fee) / 100.0
 					}
 				}
+				for _, milestone := range milestones {
+					if milestone.InvoiceID != nil && *milestone.InvoiceID == invoice.ID {
+						invoiceAmount += float64(milestone.Amount) / 100.0
+					}
+				}
 			}
 
 			totalInvoiced += invoiceAmount
@@ -929,12 +614,13 @@ func (a *App) ProjectProfitabilityHandler(w http.ResponseWriter, r *http.Request
 	isOnBudget := hoursCompletion >= 90 && hoursCompletion <= 110 && dollarsCompletion >= 90 && dollarsCompletion <= 110
 	isBehind := hoursCompletion > 110 || dollarsCompletion > 110
 
-	burndownData := a.generateBurndownData(&project, entriesWithCosts, invoices, totalBudgetHours, totalBudgetDollars)
+	burndownData := a.generateBurndownData(&project, entriesWithCosts, milestones, invoices, totalBudgetHours, totalBudgetDollars)
 
 	result := ProjectProfitabilityData{
 		ProjectID:          project.ID,
 		ProjectName:        project.Name,
 		BillingFrequency:   project.BillingFrequency,
+		BillingMethod:      project.BillingMethod,
 		ProjectActiveStart: project.ActiveStart,
 		ProjectActiveEnd:   project.ActiveEnd,
 
@@ -953,6 +639,9 @@ func (a *App) ProjectProfitabilityHandler(w http.ResponseWriter, r *http.Request
 		TotalInvoiced:         totalInvoiced,
 		TotalInvoicedAccepted: totalInvoicedAccepted,
 
+		FixedFeeTotal:    fixedFeeTotal,
+		MilestoneRevenue: milestoneRevenue,
+
 		HoursCompletion:   hoursCompletion,
 		DollarsCompletion: dollarsCompletion,
 		IsAheadOfBudget:   isAhead,
@@ -966,7 +655,7 @@ func (a *App) ProjectProfitabilityHandler(w http.ResponseWriter, r *http.Request
 }
 
 // generateBurndownData creates daily burndown data showing planned vs actual budget consumption
-func (a *App) generateBurndownData(project *cronos.Project, entriesWithCosts []entryWithCost, invoices []cronos.Invoice, totalBudgetHours, totalBudgetDollars float64) []BurndownDataPoint {
+func (a *App) generateBurndownData(project *cronos.Project, entriesWithCosts []entryWithCost, milestones []cronos.Milestone, invoices []cronos.Invoice, totalBudgetHours, totalBudgetDollars float64) []BurndownDataPoint {
 	if project.ActiveStart.IsZero() || project.ActiveEnd.IsZero() {
 		return []BurndownDataPoint{}
 	}
@@ -991,6 +680,14 @@ func (a *App) generateBurndownData(project *cronos.Project, entriesWithCosts []e
 		}
 	}
 
+	// Milestone revenue is earned on the day the milestone is completed
+	milestoneRevenueByDate := make(map[string]float64)
+	for _, milestone := range milestones {
+		if milestone.Completed && milestone.CompletedAt != nil {
+			milestoneRevenueByDate[milestone.CompletedAt.Format("2006-01-02")] += float64(milestone.Amount) / 100.0
+		}
+	}
+
 	// Generate daily data points
 	currentDate := project.ActiveStart
 	cumulativeSpent := 0.0
@@ -1015,6 +712,7 @@ func (a *App) generateBurndownData(project *cronos.Project, entriesWithCosts []e
 				cumulativeCost += ewc.cost
 			}
 		}
+		cumulativeSpent += milestoneRevenueByDate[dateKey]
 
 		// Calculate invoiced amount up to this date
 		for _, invoice := range invoices {
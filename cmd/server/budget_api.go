@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// BudgetsHandler forecasts the budget of every active project that has one. The burn rate is averaged
+// over the "burn_weeks" of the "budget_alerts" tenant setting, e.g.
+// { "budget_alerts": { "thresholds": [80, 100], "notify_clients": true, "burn_weeks": 4 } }
+// GET /api/budgets
+func (a *App) BudgetsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	forecasts, err := a.cronosApp.ForecastBudgets(tenant.ID, time.Now())
+	if err != nil {
+		log.Printf("Error forecasting budgets: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to forecast budgets")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, forecasts)
+}
+
+// ProjectBudgetHandler returns a project's budget forecast and the alerts standing on it
+// GET /api/projects/{id}/budget
+func (a *App) ProjectBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var project cronos.Project
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&project, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	forecast, err := a.cronosApp.ForecastProjectBudget(&project, cronos.BudgetAlertConfig(tenant).BurnWeeks, time.Now())
+	if err != nil {
+		log.Printf("Error forecasting budget for project %d: %v", project.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to forecast budget")
+		return
+	}
+	alerts, err := a.cronosApp.ListBudgetAlerts(tenant.ID, project.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load budget alerts")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"forecast": forecast,
+		"alerts":   alerts,
+	})
+}
+
+// CheckProjectBudgetHandler checks a project's budget now, sending any alerts it has newly crossed
+// POST /api/projects/{id}/budget/check
+func (a *App) CheckProjectBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var project cronos.Project
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&project, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	alerts, err := a.cronosApp.CheckProjectBudget(tenant.ID, project.ID, time.Now())
+	if err != nil {
+		log.Printf("Error checking budget for project %d: %v", project.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to check budget")
+		return
+	}
+	if alerts == nil {
+		alerts = []cronos.BudgetAlert{}
+	}
+	respondWithJSON(w, http.StatusOK, alerts)
+}
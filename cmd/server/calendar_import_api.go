@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+	"google.golang.org/api/calendar/v3"
+)
+
+// googleCalendarService reads events for import from the Google Calendar API
+type googleCalendarService struct {
+	service *calendar.Service
+}
+
+// toCalendarEvent converts a Google event. All-day events keep a zero start and end.
+func toCalendarEvent(calendarID string, event *calendar.Event) cronos.CalendarEvent {
+	converted := cronos.CalendarEvent{
+		ID:          event.Id,
+		CalendarID:  calendarID,
+		Summary:     event.Summary,
+		Description: event.Description,
+	}
+	if event.Start != nil && event.End != nil && event.Start.DateTime != "" && event.End.DateTime != "" {
+		converted.Start, _ = time.Parse(time.RFC3339, event.Start.DateTime)
+		converted.End, _ = time.Parse(time.RFC3339, event.End.DateTime)
+	}
+	if event.Organizer != nil && event.Organizer.Email != "" {
+		converted.Attendees = append(converted.Attendees, event.Organizer.Email)
+	}
+	for _, attendee := range event.Attendees {
+		converted.Attendees = append(converted.Attendees, attendee.Email)
+	}
+	return converted
+}
+
+func (g googleCalendarService) ListEvents(calendarID string, start, end time.Time) ([]cronos.CalendarEvent, error) {
+	var events []cronos.CalendarEvent
+	err := g.service.Events.List(calendarID).
+		TimeMin(start.Format(time.RFC3339)).
+		TimeMax(end.Format(time.RFC3339)).
+		SingleEvents(true).
+		OrderBy("startTime").
+		Pages(context.Background(), func(page *calendar.Events) error {
+			for _, event := range page.Items {
+				events = append(events, toCalendarEvent(calendarID, event))
+			}
+			return nil
+		})
+	return events, err
+}
+
+func (g googleCalendarService) GetEvent(calendarID, eventID string) (cronos.CalendarEvent, error) {
+	event, err := g.service.Events.Get(calendarID, eventID).Do()
+	if err != nil {
+		return cronos.CalendarEvent{}, err
+	}
+	return toCalendarEvent(calendarID, event), nil
+}
+
+// importCalendarService opens the user's calendar for import
+func (a *App) importCalendarService(userID interface{}) (cronos.CalendarService, error) {
+	if a.calendarService != nil {
+		return a.calendarService(userID)
+	}
+	service, err := a.getCalendarService(userID)
+	if err != nil {
+		return nil, err
+	}
+	return googleCalendarService{service: service}, nil
+}
+
+// GoogleCalendarImportHandler creates draft meeting entries for the signed-in employee's calendar
+// events, either the selected events or every event in a date range. Billing codes come from the
+// calendar mapping rules; events already imported or matching no rule are skipped and reported.
+// POST /api/google/calendar/import
+// Body: { "event_ids": ["abc123", "def456"] } or { "start_date": "2025-03-03", "end_date": "2025-03-07" }
+func (a *App) GoogleCalendarImportHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	userID := r.Context().Value("user_id")
+	employee, _ := a.currentEmployee(r, tenant.ID)
+	if employee.ID == 0 {
+		respondWithError(w, http.StatusForbidden, "No employee record for this user")
+		return
+	}
+
+	var reqBody struct {
+		CalendarID string   `json:"calendar_id"`
+		EventIDs   []string `json:"event_ids"`
+		StartDate  string   `json:"start_date"`
+		EndDate    string   `json:"end_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req := cronos.CalendarImport{CalendarID: reqBody.CalendarID, EventIDs: reqBody.EventIDs}
+	if len(req.EventIDs) == 0 {
+		start, err := time.Parse("2006-01-02", reqBody.StartDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "event_ids or start_date and end_date (YYYY-MM-DD) are required")
+			return
+		}
+		end, err := time.Parse("2006-01-02", reqBody.EndDate)
+		if err != nil || end.Before(start) {
+			respondWithError(w, http.StatusBadRequest, "Invalid end_date (use YYYY-MM-DD, on or after start_date)")
+			return
+		}
+		req.Start, req.End = start, end.AddDate(0, 0, 1)
+	}
+
+	service, err := a.importCalendarService(userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	result, err := a.cronosApp.ImportCalendarEvents(tenant.ID, employee.ID, service, req, time.Now())
+	if err != nil {
+		log.Printf("Calendar import failed for employee %d: %v", employee.ID, err)
+		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to import calendar events: %v", err))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// ListCalendarMappingRulesHandler lists the tenant's calendar mapping rules in the order they're tried
+// GET /api/google/calendar/rules
+func (a *App) ListCalendarMappingRulesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var rules []cronos.CalendarMappingRule
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("BillingCode").Order("priority, id").Find(&rules).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load calendar mapping rules")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rules)
+}
+
+// SaveCalendarMappingRuleHandler creates a calendar mapping rule, or updates one when an ID is in the
+// path. A domain rule without a pattern matches attendees from the billing code's account.
+// POST /api/google/calendar/rules
+// PUT /api/google/calendar/rules/{id}
+// Body: { "match_type": "CALENDAR_MATCH_KEYWORD", "pattern": "standup", "billing_code_id": 12, "priority": 10 }
+func (a *App) SaveCalendarMappingRuleHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var rule cronos.CalendarMappingRule
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&rule, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Calendar mapping rule not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		MatchType     string `json:"match_type"`
+		Pattern       string `json:"pattern"`
+		BillingCodeID uint   `json:"billing_code_id"`
+		Priority      int    `json:"priority"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule.TenantID = tenant.ID
+	rule.MatchType = reqBody.MatchType
+	rule.Pattern = reqBody.Pattern
+	rule.BillingCodeID = reqBody.BillingCodeID
+	rule.Priority = reqBody.Priority
+	if err := cronos.ValidateCalendarMappingRule(&rule); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var billingCode cronos.BillingCode
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&billingCode, rule.BillingCodeID).Error; err != nil {
+		respondWithError(w, http.StatusBadRequest, "Billing code not found")
+		return
+	}
+
+	status := http.StatusOK
+	if rule.ID == 0 {
+		status = http.StatusCreated
+	}
+	if err := a.cronosApp.DB.Omit("BillingCode").Save(&rule).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save calendar mapping rule")
+		return
+	}
+	rule.BillingCode = billingCode
+	respondWithJSON(w, status, rule)
+}
+
+// DeleteCalendarMappingRuleHandler removes a calendar mapping rule. Entries already imported keep
+// their billing codes.
+// DELETE /api/google/calendar/rules/{id}
+func (a *App) DeleteCalendarMappingRuleHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	result := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Delete(&cronos.CalendarMappingRule{}, mux.Vars(r)["id"])
+	if result.Error != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete calendar mapping rule")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondWithError(w, http.StatusNotFound, "Calendar mapping rule not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
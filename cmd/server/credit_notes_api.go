@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ListCreditNotesHandler lists credit notes, optionally filtered by invoice or account
+// GET /api/cronos/credit-notes?invoice_id=123&account_id=1
+func (a *App) ListCreditNotesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	query := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("Account").Preload("LineItems")
+	if invoiceID := r.URL.Query().Get("invoice_id"); invoiceID != "" {
+		query = query.Where("invoice_id = ?", invoiceID)
+	}
+	if accountID := r.URL.Query().Get("account_id"); accountID != "" {
+		query = query.Where("account_id = ?", accountID)
+	}
+
+	var notes []cronos.CreditNote
+	if err := query.Order("created_at desc").Find(&notes).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load credit notes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, notes)
+}
+
+// GetCreditNoteHandler returns a single credit note with its line items
+// GET /api/cronos/credit-notes/{id}
+func (a *App) GetCreditNoteHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+
+	var note cronos.CreditNote
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).
+		Preload("Account").Preload("LineItems").
+		First(&note, vars["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Credit note not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, note)
+}
+
+// CreateCreditNoteHandler drafts a credit note against an invoice for chosen line items or a fixed amount
+// POST /api/cronos/credit-notes
+// Body: { "invoice_id": 10, "line_item_ids": [1, 2], "amount": 0, "reason": "...", "disposition": "CREDIT_NOTE_DISPOSITION_CREDIT" }
+func (a *App) CreateCreditNoteHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var reqBody cronos.CreditNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.InvoiceID == 0 {
+		respondWithError(w, http.StatusBadRequest, "invoice_id is required")
+		return
+	}
+
+	note, err := a.cronosApp.CreateCreditNote(tenant.ID, reqBody)
+	if err != nil {
+		log.Printf("Failed to create credit note for invoice %d: %v", reqBody.InvoiceID, err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, note)
+}
+
+// IssueCreditNoteHandler issues a draft credit note, booking it and generating its PDF
+// POST /api/cronos/credit-notes/{id}/issue
+func (a *App) IssueCreditNoteHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid credit note ID")
+		return
+	}
+
+	note, err := a.cronosApp.IssueCreditNote(tenant.ID, uint(id), time.Now())
+	if errors.Is(err, cronos.ErrCreditNoteNotFound) {
+		respondWithError(w, http.StatusNotFound, "Credit note not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to issue credit note %d: %v", id, err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, note)
+}
+
+// VoidCreditNoteHandler discards a draft credit note
+// POST /api/cronos/credit-notes/{id}/void
+func (a *App) VoidCreditNoteHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid credit note ID")
+		return
+	}
+
+	note, err := a.cronosApp.VoidCreditNote(tenant.ID, uint(id))
+	if errors.Is(err, cronos.ErrCreditNoteNotFound) {
+		respondWithError(w, http.StatusNotFound, "Credit note not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, note)
+}
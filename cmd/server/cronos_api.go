@@ -184,6 +184,18 @@ func (a *App) StaffHandler(w http.ResponseWriter, r *http.Request) {
 				employee.SalaryAnnualized = int(baseSalaryFloat * 100)
 			}
 		}
+		// Sent empty to clear the manager
+		if managerID, sent, err := a.formEmployeeID(r, "manager_id", tenant.ID); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		} else if sent {
+			if managerID != nil && *managerID == employee.ID {
+				respondWithError(w, http.StatusBadRequest, "An employee can't be their own manager")
+				return
+			}
+			employee.ManagerID = managerID
+			employee.Manager = nil
+		}
 
 		// Handle email update - update associated user's email (within tenant)
 		if r.FormValue("email") != "" && employee.UserID != 0 {
@@ -351,6 +363,12 @@ func (a *App) StaffHandler(w http.ResponseWriter, r *http.Request) {
 		employee.LastName = r.FormValue("last_name")
 		employee.Title = r.FormValue("title")
 		employee.IsActive = r.FormValue("is_active") == "true"
+		if managerID, _, err := a.formEmployeeID(r, "manager_id", tenant.ID); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		} else {
+			employee.ManagerID = managerID
+		}
 
 		// Parse dates
 		if r.FormValue("start_date") != "" {
@@ -720,9 +738,10 @@ func (a *App) InvoiceListHandler(w http.ResponseWriter, r *http.Request) {
 		Preload("LineItems").
 		Preload("LineItems.BillingCode").
 		Preload("LineItems.Employee").
-		Where("state = ? or state = ? or state = ?",
+		Where("state = ? or state = ? or state = ? or state = ?",
 			cronos.InvoiceStateApproved.String(),
 			cronos.InvoiceStateSent.String(),
+			cronos.InvoiceStatePartiallyPaid.String(),
 			cronos.InvoiceStatePaid.String()).
 		Find(&invoices)
 
@@ -811,6 +830,55 @@ func (a *App) ProjectHandler(w http.ResponseWriter, r *http.Request) {
 		if r.FormValue("billing_frequency") != "" {
 			project.BillingFrequency = r.FormValue("billing_frequency")
 		}
+		// Sent empty to bill the project as time and materials again
+		if _, ok := r.Form["retainer_type"]; ok {
+			project.RetainerType = r.FormValue("retainer_type")
+		}
+		if r.FormValue("retainer_hours") != "" {
+			project.RetainerHours, _ = strconv.ParseFloat(r.FormValue("retainer_hours"), 64)
+		}
+		if r.FormValue("retainer_amount") != "" {
+			project.RetainerAmount, _ = strconv.ParseInt(r.FormValue("retainer_amount"), 10, 64)
+		}
+		if r.FormValue("retainer_rollover") != "" {
+			project.RetainerRollover = r.FormValue("retainer_rollover")
+		}
+		if err := cronos.ValidateProjectRetainer(&project); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		billingMethodChanged := false
+		if r.FormValue("billing_method") != "" && r.FormValue("billing_method") != project.BillingMethod {
+			project.BillingMethod = r.FormValue("billing_method")
+			billingMethodChanged = true
+		}
+		if err := cronos.ValidateProjectBillingMethod(&project); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Sent empty to go back to the account's rate card
+		rateCardID, rateCardSent, err := a.formRateCardID(r, tenant.ID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		rateCardChanged := rateCardSent && !sameUintPtr(rateCardID, project.RateCardID)
+		if rateCardSent {
+			project.RateCardID = rateCardID
+			project.RateCard = nil
+		}
+		// Sent empty to go back to the account's terms
+		if _, ok := r.Form["payment_terms"]; ok {
+			project.PaymentTerms = ""
+			if r.FormValue("payment_terms") != "" {
+				terms, err := cronos.ParsePaymentTerms(r.FormValue("payment_terms"))
+				if err != nil {
+					respondWithError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				project.PaymentTerms = terms.String()
+			}
+		}
 		if r.FormValue("ae_id") != "" && r.FormValue("ae_id") != "null" {
 			aeID, _ := strconv.ParseUint(r.FormValue("ae_id"), 10, 64)
 			uintAEID := uint(aeID)
@@ -825,6 +893,14 @@ func (a *App) ProjectHandler(w http.ResponseWriter, r *http.Request) {
 		} else {
 			project.SDRID = nil
 		}
+		// Sent empty to clear the lead
+		if leadID, sent, err := a.formEmployeeID(r, "lead_id", tenant.ID); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		} else if sent {
+			project.LeadID = leadID
+			project.Lead = nil
+		}
 
 		// Check if dates were updated
 		datesUpdated := false
@@ -847,6 +923,14 @@ func (a *App) ProjectHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// Reprice draft entries when the billing method or rate card changes, since hours on fixed-fee
+		// projects carry no fee and the card sets the rate
+		if billingMethodChanged || rateCardChanged {
+			if _, err := a.cronosApp.RerateDraftEntries([]uint{project.ID}, time.Time{}); err != nil {
+				log.Printf("Failed to reprice draft entries for project %d: %v", project.ID, err)
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		_ = json.NewEncoder(w).Encode(&project)
 		return
@@ -862,6 +946,33 @@ func (a *App) ProjectHandler(w http.ResponseWriter, r *http.Request) {
 		project.Description = r.FormValue("description")
 		project.BudgetCapHours, _ = strconv.Atoi(r.FormValue("budget_cap_hours"))
 		project.BudgetCapDollars, _ = strconv.Atoi(r.FormValue("budget_cap_dollars"))
+		if r.FormValue("payment_terms") != "" {
+			terms, err := cronos.ParsePaymentTerms(r.FormValue("payment_terms"))
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			project.PaymentTerms = terms.String()
+		}
+		project.RetainerType = r.FormValue("retainer_type")
+		project.RetainerHours, _ = strconv.ParseFloat(r.FormValue("retainer_hours"), 64)
+		project.RetainerAmount, _ = strconv.ParseInt(r.FormValue("retainer_amount"), 10, 64)
+		project.RetainerRollover = r.FormValue("retainer_rollover")
+		if err := cronos.ValidateProjectRetainer(&project); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		project.BillingMethod = r.FormValue("billing_method")
+		if err := cronos.ValidateProjectBillingMethod(&project); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if rateCardID, _, err := a.formRateCardID(r, tenant.ID); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		} else {
+			project.RateCardID = rateCardID
+		}
 
 		if r.FormValue("ae_id") != "" && r.FormValue("ae_id") != "null" {
 			aeID, _ := strconv.ParseUint(r.FormValue("ae_id"), 10, 64)
@@ -874,6 +985,12 @@ func (a *App) ProjectHandler(w http.ResponseWriter, r *http.Request) {
 			uintSDRID := uint(sdrID)
 			project.SDRID = &uintSDRID
 		}
+		if leadID, _, err := a.formEmployeeID(r, "lead_id", tenant.ID); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		} else {
+			project.LeadID = leadID
+		}
 
 		var account cronos.Account
 		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("id = ?", r.FormValue("account_id")).First(&account)
@@ -1156,6 +1273,38 @@ func (a *App) AccountHandler(w http.ResponseWriter, r *http.Request) {
 			singleInvoice, _ := strconv.ParseBool(r.FormValue("projects_single_invoice"))
 			account.ProjectsSingleInvoice = singleInvoice
 		}
+		if r.FormValue("currency") != "" {
+			account.Currency = strings.ToUpper(r.FormValue("currency"))
+		}
+		if r.FormValue("payment_terms") != "" {
+			terms, err := cronos.ParsePaymentTerms(r.FormValue("payment_terms"))
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			account.PaymentTerms = terms.String()
+		}
+		// Sent empty to bill at the billing codes' rates again
+		rateCardID, rateCardSent, err := a.formRateCardID(r, tenant.ID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		rateCardChanged := rateCardSent && !sameUintPtr(rateCardID, account.RateCardID)
+		if rateCardSent {
+			account.RateCardID = rateCardID
+			account.RateCard = nil
+		}
+		// Sent empty to truncate entries to their billing code's increment again
+		roundingChanged := false
+		if _, ok := r.Form["rounding_policy"]; ok {
+			if err := cronos.ValidateRoundingPolicy(r.FormValue("rounding_policy")); err != nil {
+				respondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			roundingChanged = r.FormValue("rounding_policy") != account.RoundingPolicy
+			account.RoundingPolicy = r.FormValue("rounding_policy")
+		}
 
 		// Handle logo upload
 		log.Printf("AccountHandler: Checking for logo file in form")
@@ -1225,6 +1374,20 @@ func (a *App) AccountHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		log.Printf("Account %d saved successfully. LogoAssetID: %v", account.ID, account.LogoAssetID)
 
+		// Reprice draft entries on the projects that take their rates from the account's card, or on all
+		// of its projects when the rounding policy changes
+		if rateCardChanged || roundingChanged {
+			var projectIDs []uint
+			query := a.cronosApp.DB.Model(&cronos.Project{}).Scopes(cronos.TenantScope(tenant.ID)).Where("account_id = ?", account.ID)
+			if !roundingChanged {
+				query = query.Where("rate_card_id IS NULL")
+			}
+			query.Pluck("id", &projectIDs)
+			if _, err := a.cronosApp.RerateDraftEntries(projectIDs, time.Time{}); err != nil {
+				log.Printf("Failed to reprice draft entries for account %d: %v", account.ID, err)
+			}
+		}
+
 		// Reload account with LogoAsset preloaded to include URL in response
 		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("LogoAsset").First(&account, account.ID).Error; err != nil {
 			log.Printf("Error reloading account: %v", err)
@@ -1247,6 +1410,23 @@ func (a *App) AccountHandler(w http.ResponseWriter, r *http.Request) {
 		account.BudgetDollars = budgetDollars
 		singleInvoice, _ := strconv.ParseBool(r.FormValue("projects_single_invoice"))
 		account.ProjectsSingleInvoice = singleInvoice
+		account.Currency = strings.ToUpper(r.FormValue("currency"))
+		terms, err := cronos.ParsePaymentTerms(r.FormValue("payment_terms"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		account.PaymentTerms = terms.String()
+		account.RateCardID, _, err = a.formRateCardID(r, tenant.ID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		account.RoundingPolicy = r.FormValue("rounding_policy")
+		if err := cronos.ValidateRoundingPolicy(account.RoundingPolicy); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		account.TenantID = tenant.ID
 		a.cronosApp.DB.Create(&account)
 
@@ -1434,10 +1614,24 @@ func (a *App) BillingCodeHandler(w http.ResponseWriter, r *http.Request) {
 		if r.FormValue("code") != "" {
 			billingCode.Code = r.FormValue("code")
 		}
+		roundingChanged := false
 		if r.FormValue("rounded_to") != "" {
 			roundedToInt, _ := strconv.Atoi(r.FormValue("rounded_to"))
+			roundingChanged = roundedToInt != billingCode.RoundedTo
 			billingCode.RoundedTo = roundedToInt
 		}
+		// Sent empty to go back to the account's rounding policy
+		if _, ok := r.Form["rounding_policy"]; ok {
+			if err := cronos.ValidateRoundingPolicy(r.FormValue("rounding_policy")); err != nil {
+				respondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			roundingChanged = roundingChanged || r.FormValue("rounding_policy") != billingCode.RoundingPolicy
+			billingCode.RoundingPolicy = r.FormValue("rounding_policy")
+		}
+		if r.FormValue("notes_required") != "" {
+			billingCode.NotesRequired = r.FormValue("notes_required") == "true"
+		}
 		if r.FormValue("project_id") != "" {
 			var project cronos.Project
 			a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("id = ?", r.FormValue("project_id")).First(&project)
@@ -1474,6 +1668,12 @@ func (a *App) BillingCodeHandler(w http.ResponseWriter, r *http.Request) {
 			billingCode.InternalRate = internalRate
 		}
 		a.cronosApp.DB.Save(&billingCode)
+		// Reprice the code's draft entries under the new rounding
+		if roundingChanged {
+			if _, err := a.cronosApp.RerateDraftEntries([]uint{billingCode.ProjectID}, time.Time{}); err != nil {
+				log.Printf("Failed to reprice draft entries for billing code %d: %v", billingCode.ID, err)
+			}
+		}
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		_ = json.NewEncoder(w).Encode(&billingCode)
 		return
@@ -1482,6 +1682,12 @@ func (a *App) BillingCodeHandler(w http.ResponseWriter, r *http.Request) {
 		billingCode.RateType = r.FormValue("type")
 		billingCode.Category = r.FormValue("category")
 		billingCode.RoundedTo, _ = strconv.Atoi(r.FormValue("rounded_to"))
+		billingCode.RoundingPolicy = r.FormValue("rounding_policy")
+		if err := cronos.ValidateRoundingPolicy(billingCode.RoundingPolicy); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		billingCode.NotesRequired = r.FormValue("notes_required") == "true"
 		billingCode.ActiveStart, _ = time.Parse("2006-01-02", r.FormValue("active_start"))
 		billingCode.ActiveEnd, _ = time.Parse("2006-01-02", r.FormValue("active_end"))
 
@@ -1574,6 +1780,9 @@ func (a *App) RateHandler(w http.ResponseWriter, r *http.Request) {
 			internalOnly, _ := strconv.ParseBool(r.FormValue("internal_only"))
 			rate.InternalOnly = internalOnly
 		}
+		if r.FormValue("currency") != "" {
+			rate.Currency = strings.ToUpper(r.FormValue("currency"))
+		}
 		a.cronosApp.DB.Save(&rate)
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		_ = json.NewEncoder(w).Encode(&rate)
@@ -1584,6 +1793,7 @@ func (a *App) RateHandler(w http.ResponseWriter, r *http.Request) {
 		rate.ActiveFrom, _ = time.Parse("2006-01-02", r.FormValue("active_from"))
 		rate.ActiveTo, _ = time.Parse("2006-01-02", r.FormValue("active_to"))
 		rate.InternalOnly, _ = strconv.ParseBool(r.FormValue("internal_only"))
+		rate.Currency = strings.ToUpper(r.FormValue("currency"))
 		a.cronosApp.DB.Create(&rate)
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusCreated)
@@ -1657,6 +1867,11 @@ func (a *App) EntryHandler(w http.ResponseWriter, r *http.Request) {
 			_ = json.NewEncoder(w).Encode(map[string]string{"error": "You do not have permission to edit this entry"})
 			return
 		}
+		if err := a.cronosApp.CheckTimesheetOpen(tenant.ID, entry.EmployeeID, entry.Start); err != nil {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		previous := entry
 
 		if r.FormValue("billing_code_id") != "" {
 			var billingCode cronos.BillingCode
@@ -1722,17 +1937,29 @@ func (a *App) EntryHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// Entries can't be moved into a submitted week either
+		if err := a.cronosApp.CheckTimesheetOpen(tenant.ID, entry.EmployeeID, entry.Start); err != nil {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+
 		// If this entry was REJECTED, reset it to DRAFT when updated
 		if entry.State == cronos.EntryStateRejected.String() {
 			entry.State = cronos.EntryStateDraft.String()
 		}
 
+		warnings, ok := a.checkEntryRules(w, &entry, &previous)
+		if !ok {
+			return
+		}
 		a.cronosApp.DB.Save(&entry)
 
 		// Get the updated entry with all relationships loaded (within tenant)
 		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("BillingCode.Rate").Preload("BillingCode.InternalRate").Preload("Employee").Preload("ImpersonateAsUser").First(&entry, entry.ID)
 		apiEntry := entry.GetAPIEntry()
 
+		apiEntry.Warnings = warnings
+
 		// Set a flag for UI to identify if this entry was created by someone else impersonating this user
 		if entry.ImpersonateAsUserID != nil && *entry.ImpersonateAsUserID == employee.ID && entry.EmployeeID != employee.ID {
 			apiEntry.IsBeingImpersonated = true
@@ -1750,6 +1977,10 @@ func (a *App) EntryHandler(w http.ResponseWriter, r *http.Request) {
 		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("user_id = ?", userID).First(&employee)
 		entry.EmployeeID = employee.ID
 		entry.TenantID = tenant.ID
+		if err := a.cronosApp.CheckTimesheetOpen(tenant.ID, entry.EmployeeID, entry.Start); err != nil {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
 
 		// Retrieve the billing code with all its relationships (within tenant)
 		var billingCode cronos.BillingCode
@@ -1796,6 +2027,11 @@ func (a *App) EntryHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		warnings, ok := a.checkEntryRules(w, &entry, nil)
+		if !ok {
+			return
+		}
+
 		// Need to first create the entries before we can associate them
 		a.cronosApp.DB.Create(&entry)
 
@@ -1807,6 +2043,7 @@ func (a *App) EntryHandler(w http.ResponseWriter, r *http.Request) {
 		// Get the created entry with all relationships loaded (within tenant)
 		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("BillingCode.Rate").Preload("BillingCode.InternalRate").Preload("Employee").Preload("ImpersonateAsUser").First(&entry, entry.ID)
 		apiEntry := entry.GetAPIEntry()
+		apiEntry.Warnings = warnings
 
 		// Set a flag for UI to identify if this entry was created by someone else impersonating this user
 		if entry.ImpersonateAsUserID != nil && *entry.ImpersonateAsUserID == employee.ID && entry.EmployeeID != employee.ID {
@@ -1822,7 +2059,20 @@ func (a *App) EntryHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	case r.Method == "DELETE":
-		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("id = ?", vars["id"]).Delete(&cronos.Entry{})
+		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Limit(1).Find(&entry, vars["id"])
+		if entry.ID != 0 {
+			if err := a.cronosApp.CheckTimesheetOpen(tenant.ID, entry.EmployeeID, entry.Start); err != nil {
+				respondWithError(w, http.StatusConflict, err.Error())
+				return
+			}
+			if validation, err := a.cronosApp.ValidateEntryDelete(&entry, time.Now()); err != nil {
+				log.Printf("Failed to check entry rules for entry %d: %v", entry.ID, err)
+			} else if err := validation.Err(); err != nil {
+				respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": err.Error(), "violations": validation.Violations})
+				return
+			}
+		}
+		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("id = ?", vars["id"]).Delete(&entry)
 		_ = json.NewEncoder(w).Encode("Deleted Record")
 		return
 	default:
@@ -2121,7 +2371,11 @@ func (a *App) InvoiceStateHandler(w http.ResponseWriter, r *http.Request) {
 		}{cronos.InvoiceStateApproved.String(), invoice.ID})
 	case state == "void":
 		// Use the VoidInvoice function which handles reversing journal entries
-		err := a.cronosApp.VoidInvoice(invoice.ID)
+		err := a.ledgerApp(r, r.URL.Query().Get("override_reason")).VoidInvoice(invoice.ID)
+		if errors.Is(err, cronos.ErrPeriodLocked) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -2315,8 +2569,16 @@ func (a *App) SendInvoiceEmailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if PDF exists, if not generate it first
-	if invoice.GCSFile == "" {
+	// Number the invoice now so the PDF and subject carry it, even when the tenant numbers at send
+	hadNumber := invoice.Number != ""
+	if err := a.cronosApp.AssignInvoiceNumber(&invoice, time.Now()); err != nil {
+		log.Printf("Error assigning invoice number: %v", err)
+		http.Error(w, "Failed to assign invoice number", http.StatusInternalServerError)
+		return
+	}
+
+	// Check if PDF exists (and shows the invoice number), if not generate it first
+	if invoice.GCSFile == "" || !hadNumber {
 		log.Printf("Invoice #%d has no PDF, generating now...", invoice.ID)
 		err := a.cronosApp.SaveInvoiceToGCS(&invoice)
 		if err != nil {
@@ -2333,8 +2595,17 @@ func (a *App) SendInvoiceEmailHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Invoice #%d PDF generated successfully: %s", invoice.ID, invoice.GCSFile)
 	}
 
-	// Format invoice number (6 digits, zero-padded)
-	invoiceNumber := fmt.Sprintf("%06d", invoice.ID)
+	// Use the legal invoice number, falling back to the ID (6 digits, zero-padded)
+	invoiceNumber := invoice.Number
+	if invoiceNumber == "" {
+		invoiceNumber = fmt.Sprintf("%06d", invoice.ID)
+	}
+	subject := emailData.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("Invoice %s from %s", invoiceNumber, tenant.Name)
+	} else if !strings.Contains(subject, invoiceNumber) {
+		subject = fmt.Sprintf("%s (Invoice %s)", subject, invoiceNumber)
+	}
 
 	// Generate HTML email
 	htmlBody := generateInvoiceEmailHTML(emailData.Body, invoice.GCSFile, invoiceNumber)
@@ -2352,7 +2623,7 @@ func (a *App) SendInvoiceEmailHandler(w http.ResponseWriter, r *http.Request) {
 	if err := a.cronosApp.SendInvoiceEmail(
 		emailData.To,
 		emailData.CC,
-		emailData.Subject,
+		subject,
 		htmlBody,
 		invoice.GCSFile,
 		&invoice,
@@ -2490,34 +2761,13 @@ func (a *App) AdjustmentStateHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case status == "void":
-		// Reverse any existing journal entries for this adjustment before voiding (within tenant)
-		var existingJournals []cronos.Journal
-		if adjustment.InvoiceID != nil {
-			a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("invoice_id = ? AND memo LIKE ?", *adjustment.InvoiceID, "%adjustment%").Find(&existingJournals)
-		} else if adjustment.BillID != nil {
-			a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("bill_id = ? AND memo LIKE ?", *adjustment.BillID, "%adjustment%").Find(&existingJournals)
-		}
-
-		// Reverse the journals
-		for _, journal := range existingJournals {
-			reversal := cronos.Journal{
-				Account:    journal.Account,
-				SubAccount: journal.SubAccount,
-				InvoiceID:  journal.InvoiceID,
-				BillID:     journal.BillID,
-				Memo:       fmt.Sprintf("VOID: Reverse %s", journal.Memo),
-				Debit:      journal.Credit, // Swap
-				Credit:     journal.Debit,
-				TenantID:   tenant.ID,
-			}
-			if err := a.cronosApp.DB.Create(&reversal).Error; err != nil {
-				log.Printf("Warning: Failed to reverse adjustment journal: %v", err)
-			}
+		// Reverse the journals booked for the adjustment as of today and void it
+		err := a.ledgerApp(r, r.URL.Query().Get("override_reason")).VoidAdjustment(&adjustment, time.Now())
+		if errors.Is(err, cronos.ErrPeriodLocked) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		}
-
-		// Update state
-		adjustment.State = cronos.AdjustmentStateVoid.String()
-		if err := a.cronosApp.DB.Save(&adjustment).Error; err != nil {
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -2682,10 +2932,11 @@ func (a *App) PortalInvoiceListHandler(w http.ResponseWriter, r *http.Request) {
 	var invoices []cronos.Invoice
 	// Assuming cronos.Invoice has an AccountID field (within tenant)
 	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)). /*Preload("Account").*/ Preload("Project").Preload("Entries").Order("sent_at DESC"). // Project might implicitly link to account or might need Preload("Project.Account")
-																				Where("account_id = ? AND (state = ? OR state = ?)",
+																				Where("account_id = ? AND (state = ? OR state = ? OR state = ?)",
 			accountID,
 			// cronos.InvoiceStateApproved.String(),
 			cronos.InvoiceStateSent.String(),
+			cronos.InvoiceStatePartiallyPaid.String(),
 			cronos.InvoiceStatePaid.String(),
 		).
 		Find(&invoices).Error; err != nil {
@@ -3617,7 +3868,7 @@ func (a *App) JournalsListHandler(w http.ResponseWriter, r *http.Request) {
 	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
 		startDate, err := time.Parse("2006-01-02", startDateStr)
 		if err == nil {
-			query = query.Where("created_at >= ?", startDate)
+			query = query.Where("effective_date >= ?", startDate)
 		}
 	}
 	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
@@ -3625,7 +3876,7 @@ func (a *App) JournalsListHandler(w http.ResponseWriter, r *http.Request) {
 		if err == nil {
 			// Add 23:59:59 to include the entire end date
 			endDate = endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
-			query = query.Where("created_at <= ?", endDate)
+			query = query.Where("effective_date <= ?", endDate)
 		}
 	}
 
@@ -3656,7 +3907,7 @@ func (a *App) JournalsListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var journals []cronos.Journal
-	query.Preload("Invoice").Preload("Bill").Order("created_at DESC").Find(&journals)
+	query.Preload("Invoice").Preload("Bill").Order("effective_date DESC, id DESC").Find(&journals)
 
 	// Check if we should include approved offline journals
 	includeOffline := r.URL.Query().Get("include_offline") == "true"
@@ -3699,6 +3950,7 @@ func (a *App) JournalsListHandler(w http.ResponseWriter, r *http.Request) {
 				}
 				journal.ID = offline.ID
 				journal.CreatedAt = offline.Date
+				journal.EffectiveDate = offline.Date
 				journal.UpdatedAt = offline.UpdatedAt
 				journals = append(journals, journal)
 			}
@@ -3734,7 +3986,7 @@ func (a *App) AccountBalancesHandler(w http.ResponseWriter, r *http.Request) {
 	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
 		startDate, err := time.Parse("2006-01-02", startDateStr)
 		if err == nil {
-			query = query.Where("created_at >= ?", startDate)
+			query = query.Where("effective_date >= ?", startDate)
 		}
 	}
 	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
@@ -3742,7 +3994,7 @@ func (a *App) AccountBalancesHandler(w http.ResponseWriter, r *http.Request) {
 		if err == nil {
 			// Add 23:59:59 to include the entire end date
 			endDate = endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
-			query = query.Where("created_at <= ?", endDate)
+			query = query.Where("effective_date <= ?", endDate)
 		}
 	}
 
@@ -3786,12 +4038,15 @@ func (a *App) AccountBalancesHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, summary)
 }
 
-// ManualJournalEntryHandler creates manual journal entries (offline bookings)
+// ManualJournalEntryHandler creates manual journal entries (offline bookings) as one balanced transaction
+// effective on the given date
 func (a *App) ManualJournalEntryHandler(w http.ResponseWriter, r *http.Request) {
 	tenant := MustGetTenant(r.Context())
 	var request struct {
-		Date  string `json:"date"`
-		Lines []struct {
+		Date           string `json:"date"`
+		Memo           string `json:"memo"`
+		OverrideReason string `json:"override_reason"` // lets admins post into a soft-closed period
+		Lines          []struct {
 			Account    string `json:"account"`
 			SubAccount string `json:"sub_account"`
 			Debit      int64  `json:"debit"`  // in cents
@@ -3848,40 +4103,44 @@ func (a *App) ManualJournalEntryHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		journal := cronos.Journal{
+		journals = append(journals, cronos.Journal{
 			Account:    line.Account,
 			SubAccount: line.SubAccount,
 			Debit:      line.Debit,
 			Credit:     line.Credit,
 			Memo:       line.Memo,
-			TenantID:   tenant.ID,
-		}
-		journal.CreatedAt = entryDate
-		journal.UpdatedAt = entryDate
-
-		journals = append(journals, journal)
+		})
 	}
 
 	// Save all journals in a transaction
-	tx := a.cronosApp.DB.Begin()
-	for _, journal := range journals {
-		if err := tx.Create(&journal).Error; err != nil {
-			tx.Rollback()
-			log.Printf("Error creating manual journal entry: %v", err)
-			http.Error(w, "Failed to create journal entry", http.StatusInternalServerError)
+	txn := cronos.JournalTransaction{
+		TenantID:      tenant.ID,
+		EffectiveDate: entryDate,
+		SourceType:    cronos.JournalSourceManual.String(),
+		Memo:          request.Memo,
+		Lines:         journals,
+	}
+	if userID, ok := r.Context().Value("user_id").(uint); ok {
+		txn.PostedByID = &userID
+	}
+	if err := a.ledgerApp(r, request.OverrideReason).PostJournalTransaction(&txn); err != nil {
+		log.Printf("Error creating manual journal entries: %v", err)
+		if errors.Is(err, cronos.ErrUnbalancedTransaction) || errors.Is(err, cronos.ErrNegativeJournalAmount) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("Error committing manual journal entries: %v", err)
-		http.Error(w, "Failed to commit journal entries", http.StatusInternalServerError)
+		if errors.Is(err, cronos.ErrPeriodLocked) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create journal entry", http.StatusInternalServerError)
 		return
 	}
 
 	log.Printf("Created %d manual journal entries for date %s", len(journals), request.Date)
 	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
-		"success": true,
-		"count":   len(journals),
+		"success":        true,
+		"count":          len(journals),
+		"transaction_id": txn.ID,
 	})
 }
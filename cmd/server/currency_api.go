@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/snowpackdata/cronos"
+)
+
+// ListExchangeRatesHandler lists the tenant's exchange rates, newest first
+// GET /api/exchange-rates?from=EUR&to=USD
+func (a *App) ListExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	query := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID))
+	if from := r.URL.Query().Get("from"); from != "" {
+		query = query.Where("from_currency = ?", strings.ToUpper(from))
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		query = query.Where("to_currency = ?", strings.ToUpper(to))
+	}
+
+	var rates []cronos.ExchangeRate
+	if err := query.Order("date desc, from_currency, to_currency").Limit(1000).Find(&rates).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load exchange rates")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"functional_currency": cronos.FunctionalCurrency(tenant),
+		"rates":               rates,
+	})
+}
+
+// ImportExchangeRatesHandler loads exchange rates from an uploaded CSV with date,from,to,rate columns
+// POST /api/exchange-rates/import (multipart form, field "file")
+func (a *App) ImportExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to get file")
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	imported, err := a.cronosApp.ImportExchangeRatesCSV(tenant.ID, content, fileHeader.Filename)
+	if err != nil {
+		log.Printf("Failed to import exchange rates: %v", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"imported": imported,
+		"message":  "Exchange rates imported",
+	})
+}
+
+// GetExchangeRateHandler returns the rate that would be used to convert between two currencies on a date
+// GET /api/exchange-rates/lookup?from=EUR&to=USD&date=2025-03-31
+func (a *App) GetExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = cronos.FunctionalCurrency(tenant)
+	}
+	if from == "" {
+		respondWithError(w, http.StatusBadRequest, "from is required")
+		return
+	}
+
+	on := time.Now()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+		on = parsed
+	}
+
+	rate, err := a.cronosApp.GetExchangeRate(tenant.ID, from, to, on)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"from": strings.ToUpper(from),
+		"to":   strings.ToUpper(to),
+		"date": on.Format("2006-01-02"),
+		"rate": rate,
+	})
+}
+
+// SetFunctionalCurrencyHandler sets the currency the tenant keeps its books in
+// PUT /api/exchange-rates/functional-currency
+// Body: { "currency": "USD" }
+func (a *App) SetFunctionalCurrencyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var reqBody struct {
+		Currency string `json:"currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil || len(reqBody.Currency) != 3 {
+		respondWithError(w, http.StatusBadRequest, "currency must be a 3-letter ISO code")
+		return
+	}
+
+	settings := map[string]interface{}{}
+	if len(tenant.Settings) > 0 {
+		json.Unmarshal(tenant.Settings, &settings)
+	}
+	settings["functional_currency"] = strings.ToUpper(reqBody.Currency)
+	encoded, _ := json.Marshal(settings)
+	if err := a.cronosApp.DB.Model(&cronos.Tenant{}).Where("id = ?", tenant.ID).Update("settings", encoded).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save functional currency")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"functional_currency": strings.ToUpper(reqBody.Currency)})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ARAgingHandler ages open invoices into current, 1-30, 31-60, 61-90 and 90+ day buckets by account
+// GET /api/cronos/ar-aging?as_of_date=2025-03-31
+func (a *App) ARAgingHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	asOf := time.Now()
+	if asOfStr := r.URL.Query().Get("as_of_date"); asOfStr != "" {
+		parsed, err := time.Parse("2006-01-02", asOfStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid as_of_date format (use YYYY-MM-DD)")
+			return
+		}
+		asOf = parsed
+	}
+
+	report, err := a.cronosApp.GenerateARAging(tenant.ID, asOf)
+	if err != nil {
+		log.Printf("Failed to generate AR aging: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate AR aging")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// ListDunningStepsHandler lists the tenant's dunning sequence in firing order
+// GET /api/cronos/dunning/steps
+func (a *App) ListDunningStepsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	steps, err := a.cronosApp.ListDunningSteps(tenant.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load dunning steps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, steps)
+}
+
+// SaveDunningStepHandler creates a dunning step, or updates one when an ID is in the path. Subject
+// and body are templates that can use {{.AccountName}}, {{.InvoiceNumber}}, {{.BalanceDue}},
+// {{.Currency}}, {{.DueDate}}, {{.DaysPastDue}}, {{.LateFee}} and {{.TenantName}}.
+// POST /api/cronos/dunning/steps
+// PUT /api/cronos/dunning/steps/{id}
+// Body: { "name": "Second notice", "day_offset": 14, "subject": "Invoice {{.InvoiceNumber}} is overdue", "body": "<p>...</p>", "late_fee_percent": 1.5, "late_fee_flat": 0, "active": true }
+func (a *App) SaveDunningStepHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var step cronos.DunningStep
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&step, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Dunning step not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		Name           string  `json:"name"`
+		DayOffset      int     `json:"day_offset"`
+		Subject        string  `json:"subject"`
+		Body           string  `json:"body"`
+		LateFeePercent float64 `json:"late_fee_percent"`
+		LateFeeFlat    float64 `json:"late_fee_flat"`
+		Active         bool    `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	step.TenantID = tenant.ID
+	step.Name = reqBody.Name
+	step.DayOffset = reqBody.DayOffset
+	step.Subject = reqBody.Subject
+	step.Body = reqBody.Body
+	step.LateFeePercent = reqBody.LateFeePercent
+	step.LateFeeFlat = reqBody.LateFeeFlat
+	step.Active = reqBody.Active
+	if err := cronos.ValidateDunningStep(&step); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := a.cronosApp.DB.Save(&step).Error; err != nil {
+		log.Printf("Failed to save dunning step: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save dunning step")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, step)
+}
+
+// DeleteDunningStepHandler removes a step from the sequence. Reminders already sent keep their history.
+// DELETE /api/cronos/dunning/steps/{id}
+func (a *App) DeleteDunningStepHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	result := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Delete(&cronos.DunningStep{}, mux.Vars(r)["id"])
+	if result.Error != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete dunning step")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondWithError(w, http.StatusNotFound, cronos.ErrDunningStepNotFound.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Dunning step deleted"})
+}
+
+// RunDunningHandler sends any reminders that are due now instead of waiting for the daily job
+// POST /api/cronos/dunning/run
+func (a *App) RunDunningHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	sent, err := a.cronosApp.RunDunning(tenant.ID, time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to run dunning: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to run dunning")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"sent": sent})
+}
+
+// InvoiceDunningRemindersHandler lists every reminder recorded against an invoice
+// GET /api/cronos/invoices/{id}/reminders
+func (a *App) InvoiceDunningRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+	reminders, err := a.cronosApp.ListDunningReminders(tenant.ID, uint(id))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load reminders")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, reminders)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// formColumn reads a CSV column index from the form, falling back to def. -1 leaves it unmapped.
+func formColumn(r *http.Request, field string, def int) int {
+	if n, err := strconv.Atoi(r.FormValue(field)); err == nil {
+		return n
+	}
+	return def
+}
+
+// ImportEntriesHandler imports time entries exported from another tool. The file is CSV, mapped with
+// the column fields (zero-based, -1 for none), or a JSON array of
+// { "email", "code", "date", "start", "end", "hours", "notes" } objects. With dry_run=true nothing is
+// saved and each row's result and errors are returned; otherwise every row is imported or, if any has
+// an error, none are.
+// POST /api/entries/import (multipart/form-data)
+// Fields: file, format (csv|json), dry_run, has_header, date_format, email_col, code_col, date_col,
+// start_col, end_col, hours_col, notes_col
+func (a *App) ImportEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to get file")
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	var records []cronos.EntryImportRecord
+	if r.FormValue("format") == "json" || (r.FormValue("format") == "" && strings.HasSuffix(strings.ToLower(fileHeader.Filename), ".json")) {
+		records, err = cronos.ParseEntryImportJSON(content)
+	} else {
+		records, err = cronos.ParseEntryImportCSV(content, cronos.EntryImportMapping{
+			EmailCol:   formColumn(r, "email_col", 0),
+			CodeCol:    formColumn(r, "code_col", 1),
+			DateCol:    formColumn(r, "date_col", 2),
+			StartCol:   formColumn(r, "start_col", -1),
+			EndCol:     formColumn(r, "end_col", -1),
+			HoursCol:   formColumn(r, "hours_col", 3),
+			NotesCol:   formColumn(r, "notes_col", 4),
+			HasHeader:  r.FormValue("has_header") != "false",
+			DateFormat: r.FormValue("date_format"),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.FormValue("dry_run") == "true" {
+		result, err := a.cronosApp.PreviewEntryImport(tenant.ID, records, time.Now())
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusOK, result)
+		return
+	}
+
+	var importedBy *uint
+	if userID, ok := r.Context().Value("user_id").(uint); ok {
+		importedBy = &userID
+	}
+	source := fileHeader.Filename
+	if source == "" {
+		source = "upload"
+	}
+	result, err := a.cronosApp.CommitEntryImport(tenant.ID, records, source, importedBy, time.Now())
+	if errors.Is(err, cronos.ErrEntryImportInvalid) {
+		respondWithJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, result)
+}
+
+// ListEntryImportsHandler lists the tenant's entry imports, newest first
+// GET /api/entries/imports
+func (a *App) ListEntryImportsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	batches, err := a.cronosApp.ListEntryImportBatches(tenant.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load entry imports")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, batches)
+}
+
+// UndoEntryImportHandler removes the entries an import created, as long as none has been approved or
+// invoiced
+// POST /api/entries/imports/{id}/undo
+func (a *App) UndoEntryImportHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid import ID")
+		return
+	}
+	batch, err := a.cronosApp.UndoEntryImport(tenant.ID, uint(id), time.Now())
+	switch {
+	case errors.Is(err, cronos.ErrEntryImportLocked), errors.Is(err, cronos.ErrEntryImportUndone), errors.Is(err, cronos.ErrTimesheetLocked):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithJSON(w, http.StatusOK, batch)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/snowpackdata/cronos"
+)
+
+// checkEntryRules validates an entry before it's saved. When a rule blocks the entry it responds with
+// 422 and the violations and returns false; otherwise it returns the warnings to send back with the
+// saved entry. Pass the stored entry as previous when updating one.
+func (a *App) checkEntryRules(w http.ResponseWriter, entry *cronos.Entry, previous *cronos.Entry) ([]string, bool) {
+	validation, err := a.cronosApp.ValidateEntry(entry, previous, time.Now())
+	if err != nil {
+		// Don't hold up time entry when the rules can't be loaded
+		log.Printf("Failed to check entry rules for entry %d: %v", entry.ID, err)
+		return nil, true
+	}
+	if err := validation.Err(); err != nil {
+		respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":      err.Error(),
+			"violations": validation.Violations,
+		})
+		return nil, false
+	}
+	return validation.Warnings(), true
+}
+
+// EntryRulesHandler returns the tenant's entry validation rules with their defaults filled in. Rules
+// are configured under "entry_rules" in the tenant settings, e.g.
+// { "entry_rules": { "daily_limit": { "mode": "block", "limit": 10 }, "lock": { "mode": "block", "limit": 45 } } }
+// GET /api/entries/rules
+func (a *App) EntryRulesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	respondWithJSON(w, http.StatusOK, cronos.EntryRuleSettings(tenant))
+}
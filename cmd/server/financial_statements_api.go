@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// FinancialStatementHandler generates an income statement, balance sheet or cash-flow statement from the GL
+// GET /api/cronos/statements/income-statement?start_date=2025-01-01&end_date=2025-03-31&compare=previous_year&format=pdf
+// GET /api/cronos/statements/balance-sheet?as_of_date=2025-03-31&compare=previous_period&compare_periods=2&format=csv
+// GET /api/cronos/statements/cash-flow?start_date=2025-01-01&end_date=2025-03-31
+// End dates are inclusive and default to the current month; format is json (default), csv or pdf.
+func (a *App) FinancialStatementHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	query := r.URL.Query()
+	statementType := mux.Vars(r)["type"]
+
+	parseDate := func(name string, fallback time.Time) (time.Time, error) {
+		value := query.Get(name)
+		if value == "" {
+			return fallback, nil
+		}
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s format (use YYYY-MM-DD)", name)
+		}
+		return parsed, nil
+	}
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start, err := parseDate("start_date", monthStart)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	end, err := parseDate("end_date", monthStart.AddDate(0, 1, -1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comparePeriods := 1
+	if value := query.Get("compare_periods"); value != "" {
+		comparePeriods, err = strconv.Atoi(value)
+		if err != nil || comparePeriods < 1 || comparePeriods > 12 {
+			respondWithError(w, http.StatusBadRequest, "compare_periods must be between 1 and 12")
+			return
+		}
+	}
+
+	var statement *cronos.FinancialStatement
+	if statementType == "balance-sheet" {
+		var asOf time.Time
+		var dates []time.Time
+		if asOf, err = parseDate("as_of_date", now); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if dates, err = cronos.BalanceSheetDates(asOf, query.Get("compare"), comparePeriods); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		statement, err = a.cronosApp.GenerateBalanceSheet(tenant.ID, dates)
+	} else {
+		var columns []cronos.StatementColumn
+		if columns, err = cronos.StatementColumns(start, end.AddDate(0, 0, 1), query.Get("compare"), comparePeriods); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if statementType == "cash-flow" {
+			statement, err = a.cronosApp.GenerateCashFlowStatement(tenant.ID, columns)
+		} else {
+			statement, err = a.cronosApp.GenerateIncomeStatement(tenant.ID, columns)
+		}
+	}
+	if errors.Is(err, cronos.ErrInvalidStatementPeriod) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to generate %s: %v", statementType, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate statement")
+		return
+	}
+
+	filename := statementType + "-" + statement.Columns[0].End.AddDate(0, 0, -1).Format("2006-01-02")
+	switch query.Get("format") {
+	case "", "json":
+		respondWithJSON(w, http.StatusOK, statement)
+	case "csv":
+		data, err := cronos.StatementCSV(statement)
+		if err != nil {
+			log.Printf("Failed to render %s as CSV: %v", statementType, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to render statement")
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		w.Write(data)
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".pdf\"")
+		w.Write(a.cronosApp.GenerateStatementPDF(statement))
+	default:
+		respondWithError(w, http.StatusBadRequest, "format must be json, csv or pdf")
+	}
+}
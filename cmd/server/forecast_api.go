@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+	"gorm.io/gorm"
+)
+
+const defaultForecastWeeks = 12
+
+// respondWithScenarioError maps forecast and scenario errors onto status codes
+func respondWithScenarioError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cronos.ErrInvalidScenario):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, cronos.ErrScenarioNotDraft):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("Scenario error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process scenario")
+	}
+}
+
+// forecastParams reads the from, weeks and scenarios query parameters shared by the forecast endpoints
+func forecastParams(r *http.Request) (from time.Time, weeks int, scenarioIDs []uint, err error) {
+	from, weeks = time.Now(), defaultForecastWeeks
+	query := r.URL.Query()
+	if fromStr := query.Get("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return from, weeks, nil, errors.New("invalid from date format (use YYYY-MM-DD)")
+		}
+	}
+	if weeksStr := query.Get("weeks"); weeksStr != "" {
+		if weeks, err = strconv.Atoi(weeksStr); err != nil {
+			return from, weeks, nil, errors.New("invalid weeks")
+		}
+	}
+	for _, idStr := range strings.Split(query.Get("scenarios"), ",") {
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(idStr), 10, 32)
+		if err != nil {
+			return from, weeks, nil, errors.New("invalid scenario ID " + idStr)
+		}
+		scenarioIDs = append(scenarioIDs, uint(id))
+	}
+	return from, weeks, scenarioIDs, nil
+}
+
+// ForecastHandler projects each employee's capacity, committed hours and revenue over the coming weeks,
+// with the listed draft scenarios weighted in
+// GET /api/forecast?from=2025-03-02&weeks=12&scenarios=3,4
+func (a *App) ForecastHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	from, weeks, scenarioIDs, err := forecastParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	forecast, err := a.cronosApp.ForecastStaffing(tenant.ID, from, weeks, scenarioIDs)
+	if err != nil {
+		respondWithScenarioError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, forecast)
+}
+
+// CompareScenariosHandler forecasts the committed baseline beside the baseline plus each scenario
+// GET /api/forecast/compare?weeks=12&scenarios=3,4
+func (a *App) CompareScenariosHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	from, weeks, scenarioIDs, err := forecastParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	comparisons, err := a.cronosApp.CompareScenarios(tenant.ID, from, weeks, scenarioIDs)
+	if err != nil {
+		respondWithScenarioError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, comparisons)
+}
+
+// ListScenariosHandler lists the tenant's staffing scenarios with their assignments, newest first
+// GET /api/scenarios
+func (a *App) ListScenariosHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var scenarios []cronos.StaffingScenario
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("Assignments.Employee").Preload("Assignments.Project").
+		Order("created_at desc").Find(&scenarios).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load scenarios")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, scenarios)
+}
+
+// SaveScenarioHandler creates a draft scenario or updates one. Setting the state to
+// SCENARIO_STATE_ARCHIVED shelves a lost deal.
+// POST /api/scenarios
+// PUT /api/scenarios/{id}
+// Body: { "name": "Data migration deal", "description": "Phase one", "probability": 0.6, "state": "SCENARIO_STATE_DRAFT" }
+func (a *App) SaveScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var scenario cronos.StaffingScenario
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&scenario, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Scenario not found")
+			return
+		}
+		if scenario.State == cronos.ScenarioStatePromoted.String() {
+			respondWithError(w, http.StatusConflict, cronos.ErrScenarioNotDraft.Error())
+			return
+		}
+	}
+
+	var reqBody struct {
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		Probability float64 `json:"probability"`
+		State       string  `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	switch cronos.ScenarioState(reqBody.State) {
+	case "":
+	case cronos.ScenarioStateDraft, cronos.ScenarioStateArchived:
+		scenario.State = reqBody.State
+	default:
+		respondWithError(w, http.StatusBadRequest, "Scenarios are promoted with /promote; state must be draft or archived")
+		return
+	}
+	scenario.TenantID = tenant.ID
+	scenario.Name = reqBody.Name
+	scenario.Description = reqBody.Description
+	scenario.Probability = reqBody.Probability
+	if err := cronos.ValidateStaffingScenario(&scenario); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if scenario.ID == 0 {
+		status = http.StatusCreated
+	}
+	if err := a.cronosApp.DB.Omit("Assignments").Save(&scenario).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save scenario")
+		return
+	}
+	respondWithJSON(w, status, scenario)
+}
+
+// DeleteScenarioHandler removes a scenario and its draft assignments. Staffing assignments a promoted
+// scenario created are kept.
+// DELETE /api/scenarios/{id}
+func (a *App) DeleteScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var scenario cronos.StaffingScenario
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&scenario, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Scenario not found")
+		return
+	}
+	if err := a.cronosApp.DB.Where("scenario_id = ?", scenario.ID).Delete(&cronos.ScenarioAssignment{}).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete scenario assignments")
+		return
+	}
+	if err := a.cronosApp.DB.Delete(&scenario).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete scenario")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// SaveScenarioAssignmentHandler adds a draft assignment to a scenario or updates one. project_id can
+// be left out until the deal has a project, with rate pricing the forecast meanwhile.
+// POST /api/scenarios/{id}/assignments
+// PUT /api/scenarios/{id}/assignments/{assignmentId}
+// Body: { "employee_id": 4, "project_id": null, "rate": 185, "commitment": 30, "start_date": "2025-04-06",
+// "end_date": "2025-06-28", "segments": [{ "start_date": "2025-04-06", "end_date": "2025-04-26", "commitment": 10 }] }
+func (a *App) SaveScenarioAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scenario ID")
+		return
+	}
+	scenario, err := a.cronosApp.DraftScenario(tenant.ID, uint(id))
+	if err != nil {
+		respondWithScenarioError(w, err)
+		return
+	}
+	assignment := cronos.ScenarioAssignment{TenantID: tenant.ID, ScenarioID: scenario.ID}
+	if assignmentID, ok := vars["assignmentId"]; ok {
+		if err := a.cronosApp.DB.Where("scenario_id = ?", scenario.ID).First(&assignment, assignmentID).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Scenario assignment not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		EmployeeID uint                       `json:"employee_id"`
+		ProjectID  *uint                      `json:"project_id"`
+		Rate       float64                    `json:"rate"`
+		Commitment int                        `json:"commitment"`
+		StartDate  string                     `json:"start_date"`
+		EndDate    string                     `json:"end_date"`
+		Segments   []cronos.CommitmentSegment `json:"segments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if assignment.StartDate, err = time.Parse("2006-01-02", reqBody.StartDate); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid start_date format (use YYYY-MM-DD)")
+		return
+	}
+	if assignment.EndDate, err = time.Parse("2006-01-02", reqBody.EndDate); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid end_date format (use YYYY-MM-DD)")
+		return
+	}
+	var employee cronos.Employee
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&employee, reqBody.EmployeeID).Error; err != nil {
+		respondWithError(w, http.StatusBadRequest, "Employee not found")
+		return
+	}
+	if reqBody.ProjectID != nil {
+		var project cronos.Project
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&project, *reqBody.ProjectID).Error; err != nil {
+			respondWithError(w, http.StatusBadRequest, "Project not found")
+			return
+		}
+	}
+	assignment.EmployeeID = employee.ID
+	assignment.ProjectID = reqBody.ProjectID
+	assignment.Rate = reqBody.Rate
+	assignment.Commitment = reqBody.Commitment
+	assignment.CommitmentSchedule = ""
+	if len(reqBody.Segments) > 0 {
+		scheduleJSON, _ := json.Marshal(cronos.CommitmentSchedule{Segments: reqBody.Segments})
+		assignment.CommitmentSchedule = string(scheduleJSON)
+	}
+	if err := cronos.ValidateScenarioAssignment(&assignment); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if assignment.ID == 0 {
+		status = http.StatusCreated
+	}
+	if err := a.cronosApp.DB.Omit("Employee", "Project").Save(&assignment).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save scenario assignment")
+		return
+	}
+	assignment.Employee = employee
+	respondWithJSON(w, status, assignment)
+}
+
+// DeleteScenarioAssignmentHandler removes an assignment from a draft scenario
+// DELETE /api/scenarios/{id}/assignments/{assignmentId}
+func (a *App) DeleteScenarioAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scenario ID")
+		return
+	}
+	scenario, err := a.cronosApp.DraftScenario(tenant.ID, uint(id))
+	if err != nil {
+		respondWithScenarioError(w, err)
+		return
+	}
+	result := a.cronosApp.DB.Where("scenario_id = ?", scenario.ID).Delete(&cronos.ScenarioAssignment{}, vars["assignmentId"])
+	if result.Error != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete scenario assignment")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondWithError(w, http.StatusNotFound, "Scenario assignment not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// PromoteScenarioHandler turns a draft scenario's assignments into staffing assignments once the deal
+// is signed. project_id is used for assignments that don't have a project yet.
+// POST /api/scenarios/{id}/promote
+// Body: { "project_id": 12 }
+func (a *App) PromoteScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scenario ID")
+		return
+	}
+	var reqBody struct {
+		ProjectID *uint `json:"project_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	scenario, err := a.cronosApp.PromoteScenario(tenant.ID, uint(id), reqBody.ProjectID, time.Now())
+	if err != nil {
+		respondWithScenarioError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, scenario)
+}
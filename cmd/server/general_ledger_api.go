@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -21,6 +22,7 @@ func (a *App) ReverseJournalEntryHandler(w http.ResponseWriter, r *http.Request)
 
 	var req struct {
 		Reason          string `json:"reason"`
+		OverrideReason  string `json:"override_reason"` // lets admins post into a soft-closed period
 		CreateCorrected bool   `json:"create_corrected"`
 		Corrected       *struct {
 			Account    string  `json:"account"`
@@ -58,7 +60,15 @@ func (a *App) ReverseJournalEntryHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	err = a.cronosApp.ReverseJournalEntry(uint(id), req.Reason, correctedEntry)
+	err = a.ledgerApp(r, req.OverrideReason).ReverseJournalEntry(uint(id), req.Reason, correctedEntry)
+	if errors.Is(err, cronos.ErrPeriodLocked) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, cronos.ErrUnbalancedTransaction) {
+		http.Error(w, "Reversal does not balance: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		a.logger.Printf("Failed to reverse journal entry %d: %v", id, err)
 		http.Error(w, "Failed to reverse journal entry: "+err.Error(), http.StatusInternalServerError)
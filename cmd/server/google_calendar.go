@@ -21,8 +21,9 @@ type CalendarEvent struct {
 	ID          string `json:"id"`
 	Summary     string `json:"summary"`
 	Description string `json:"description"`
-	Start       string `json:"start"` // RFC3339 string to preserve timezone
-	End         string `json:"end"`   // RFC3339 string to preserve timezone
+	Start       string `json:"start"`    // RFC3339 string to preserve timezone
+	End         string `json:"end"`      // RFC3339 string to preserve timezone
+	Imported    bool   `json:"imported"` // Already imported as a time entry
 }
 
 // getGoogleOAuthConfig returns the OAuth2 configuration for Google Calendar
@@ -373,6 +374,28 @@ func (a *App) GoogleCalendarEventsHandler(w http.ResponseWriter, r *http.Request
 		})
 	}
 
+	// Flag the events the employee has already imported
+	tenant := MustGetTenant(r.Context())
+	var employee cronos.Employee
+	a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("user_id = ?", userID).Limit(1).Find(&employee)
+	if employee.ID != 0 && len(calendarEvents) > 0 {
+		ids := make([]string, 0, len(calendarEvents))
+		for _, event := range calendarEvents {
+			ids = append(ids, event.ID)
+		}
+		var imported []string
+		a.cronosApp.DB.Model(&cronos.Entry{}).Scopes(cronos.TenantScope(tenant.ID)).
+			Where("employee_id = ? AND external_event_id IN ?", employee.ID, ids).
+			Pluck("external_event_id", &imported)
+		importedIDs := make(map[string]bool, len(imported))
+		for _, id := range imported {
+			importedIDs[id] = true
+		}
+		for i := range calendarEvents {
+			calendarEvents[i].Imported = importedIDs[calendarEvents[i].ID]
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(calendarEvents)
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ListJournalTransactionsHandler lists journal transactions with their lines by effective date
+// GET /api/cronos/journal-transactions?start_date=2025-01-01&end_date=2025-01-31&source_type=INVOICE
+func (a *App) ListJournalTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+
+	// Default to the last 30 days; the end date is inclusive for callers
+	end := time.Now()
+	start := end.AddDate(0, 0, -30)
+	if startStr := r.URL.Query().Get("start_date"); startStr != "" {
+		parsed, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start_date format (use YYYY-MM-DD)")
+			return
+		}
+		start = parsed
+	}
+	if endStr := r.URL.Query().Get("end_date"); endStr != "" {
+		parsed, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end_date format (use YYYY-MM-DD)")
+			return
+		}
+		end = parsed
+	}
+
+	txns, err := a.cronosApp.ListJournalTransactions(tenant.ID, start, end.AddDate(0, 0, 1), r.URL.Query().Get("source_type"))
+	if err != nil {
+		log.Printf("Failed to list journal transactions: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load journal transactions")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, txns)
+}
+
+// GetJournalTransactionHandler returns a single journal transaction with its lines
+// GET /api/cronos/journal-transactions/{id}
+func (a *App) GetJournalTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	txn, err := a.cronosApp.GetJournalTransaction(tenant.ID, uint(id))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Journal transaction not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, txn)
+}
+
+// UnbalancedJournalTransactionsHandler lists transactions whose lines no longer net to zero
+// GET /api/cronos/journal-transactions/unbalanced
+func (a *App) UnbalancedJournalTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	ids, err := a.cronosApp.VerifyJournalTransactionBalances(tenant.ID)
+	if err != nil {
+		log.Printf("Failed to verify journal transactions: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify journal transactions")
+		return
+	}
+	if ids == nil {
+		ids = []uint{}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"balanced":        len(ids) == 0,
+		"transaction_ids": ids,
+	})
+}
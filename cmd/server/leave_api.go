@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// respondWithLeaveError maps leave errors onto status codes
+func respondWithLeaveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cronos.ErrNotLeaveApprover):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, cronos.ErrInvalidLeaveTransition), errors.Is(err, cronos.ErrTimesheetLocked):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, cronos.ErrInvalidLeaveRequest), errors.Is(err, cronos.ErrInsufficientLeave):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		log.Printf("Leave error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update leave")
+	}
+}
+
+// requireAdmin responds with 403 unless the signed-in user is an admin
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if role, _ := r.Context().Value("user_role").(string); role != cronos.UserRoleAdmin.String() {
+		respondWithError(w, http.StatusForbidden, "Admin access required")
+		return false
+	}
+	return true
+}
+
+// LeaveTypesHandler lists the tenant's leave types
+// GET /api/leave/types
+func (a *App) LeaveTypesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var leaveTypes []cronos.LeaveType
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("BillingCode").
+		Order("name").Find(&leaveTypes).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load leave types")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, leaveTypes)
+}
+
+// SaveLeaveTypeHandler creates or updates a leave type. Admin only.
+// POST /api/leave/types
+// PUT /api/leave/types/{id}
+// Body: { "name": "Vacation", "paid": true, "billing_code_id": 7, "accrual": "LEAVE_ACCRUAL_SEMIMONTHLY",
+// "accrual_hours_per_period": 5, "max_balance_hours": 160 }
+func (a *App) SaveLeaveTypeHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if !requireAdmin(w, r) {
+		return
+	}
+	var leaveType cronos.LeaveType
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&leaveType, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Leave type not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		Name                  string  `json:"name"`
+		Paid                  bool    `json:"paid"`
+		BillingCodeID         *uint   `json:"billing_code_id"`
+		Accrual               string  `json:"accrual"`
+		AccrualHoursPerPeriod float64 `json:"accrual_hours_per_period"`
+		MaxBalanceHours       float64 `json:"max_balance_hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	leaveType.TenantID = tenant.ID
+	leaveType.Name = reqBody.Name
+	leaveType.Paid = reqBody.Paid
+	leaveType.BillingCodeID = reqBody.BillingCodeID
+	leaveType.Accrual = reqBody.Accrual
+	leaveType.AccrualHoursPerPeriod = reqBody.AccrualHoursPerPeriod
+	leaveType.MaxBalanceHours = reqBody.MaxBalanceHours
+	if err := cronos.ValidateLeaveType(&leaveType); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if leaveType.BillingCodeID != nil {
+		var billingCode cronos.BillingCode
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&billingCode, *leaveType.BillingCodeID).Error; err != nil {
+			respondWithError(w, http.StatusBadRequest, "Billing code not found")
+			return
+		}
+		leaveType.BillingCode = &billingCode
+	}
+
+	status := http.StatusOK
+	if leaveType.ID == 0 {
+		status = http.StatusCreated
+	}
+	if err := a.cronosApp.DB.Omit("BillingCode").Save(&leaveType).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save leave type")
+		return
+	}
+	respondWithJSON(w, status, leaveType)
+}
+
+// DeleteLeaveTypeHandler removes a leave type. Existing requests and balances keep referring to it.
+// Admin only.
+// DELETE /api/leave/types/{id}
+func (a *App) DeleteLeaveTypeHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if !requireAdmin(w, r) {
+		return
+	}
+	result := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Delete(&cronos.LeaveType{}, mux.Vars(r)["id"])
+	if result.Error != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete leave type")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondWithError(w, http.StatusNotFound, "Leave type not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// HolidaysHandler lists the tenant's holidays in a year (default this year)
+// GET /api/leave/holidays?year=2025
+func (a *App) HolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	year := time.Now().Year()
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
+		parsed, err := strconv.Atoi(yearStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid year")
+			return
+		}
+		year = parsed
+	}
+	var holidays []cronos.Holiday
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).
+		Where("date >= ? AND date < ?", time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)).
+		Order("date").Find(&holidays).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load holidays")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, holidays)
+}
+
+// CreateHolidayHandler adds a day to the tenant's holiday calendar. Admin only.
+// POST /api/leave/holidays
+// Body: { "date": "2025-12-25", "name": "Christmas Day" }
+func (a *App) CreateHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if !requireAdmin(w, r) {
+		return
+	}
+	var reqBody struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	date, err := time.Parse("2006-01-02", reqBody.Date)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+		return
+	}
+	if reqBody.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	holiday := cronos.Holiday{TenantID: tenant.ID, Date: date, Name: reqBody.Name}
+	if err := a.cronosApp.DB.Create(&holiday).Error; err != nil {
+		respondWithError(w, http.StatusConflict, "There is already a holiday on "+reqBody.Date)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, holiday)
+}
+
+// DeleteHolidayHandler removes a day from the tenant's holiday calendar. Admin only.
+// DELETE /api/leave/holidays/{id}
+func (a *App) DeleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if !requireAdmin(w, r) {
+		return
+	}
+	// Hard delete so the date can be added again
+	result := a.cronosApp.DB.Unscoped().Scopes(cronos.TenantScope(tenant.ID)).Delete(&cronos.Holiday{}, mux.Vars(r)["id"])
+	if result.Error != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete holiday")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondWithError(w, http.StatusNotFound, "Holiday not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// LeaveBalancesHandler returns the signed-in employee's leave balances. Admins can look up anyone's
+// with employee_id.
+// GET /api/leave/balances?employee_id=4
+func (a *App) LeaveBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, isAdmin := a.currentEmployee(r, tenant.ID)
+	employeeID := employee.ID
+	if idStr := r.URL.Query().Get("employee_id"); idStr != "" && isAdmin {
+		parsed, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid employee ID")
+			return
+		}
+		employeeID = uint(parsed)
+	}
+	if employeeID == 0 {
+		respondWithError(w, http.StatusForbidden, "No employee record for this user")
+		return
+	}
+	balances, err := a.cronosApp.ListLeaveBalances(tenant.ID, employeeID)
+	if err != nil {
+		respondWithLeaveError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, balances)
+}
+
+// AdjustLeaveBalanceHandler adds hours to, or with a negative value takes them from, an employee's
+// accrued leave. Admin only.
+// POST /api/leave/balances/adjust
+// Body: { "employee_id": 4, "leave_type_id": 2, "hours": 24 }
+func (a *App) AdjustLeaveBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	if !requireAdmin(w, r) {
+		return
+	}
+	var reqBody struct {
+		EmployeeID  uint    `json:"employee_id"`
+		LeaveTypeID uint    `json:"leave_type_id"`
+		Hours       float64 `json:"hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	var employee cronos.Employee
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&employee, reqBody.EmployeeID).Error; err != nil {
+		respondWithError(w, http.StatusBadRequest, "Employee not found")
+		return
+	}
+	var leaveType cronos.LeaveType
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&leaveType, reqBody.LeaveTypeID).Error; err != nil {
+		respondWithError(w, http.StatusBadRequest, "Leave type not found")
+		return
+	}
+	balance, err := a.cronosApp.AdjustLeaveBalance(tenant.ID, employee.ID, leaveType.ID, reqBody.Hours)
+	if err != nil {
+		respondWithLeaveError(w, err)
+		return
+	}
+	balance.LeaveType = leaveType
+	respondWithJSON(w, http.StatusOK, balance)
+}
+
+// LeaveRequestsHandler lists the signed-in employee's leave requests, latest first
+// GET /api/leave/requests
+func (a *App) LeaveRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, _ := a.currentEmployee(r, tenant.ID)
+	requests, err := a.cronosApp.ListEmployeeLeave(tenant.ID, employee.ID)
+	if err != nil {
+		respondWithLeaveError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, requests)
+}
+
+// CreateLeaveRequestHandler requests leave for the signed-in employee. hours_per_day defaults to a
+// full day.
+// POST /api/leave/requests
+// Body: { "leave_type_id": 2, "start_date": "2025-07-07", "end_date": "2025-07-11", "hours_per_day": 8, "reason": "Family trip" }
+func (a *App) CreateLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, _ := a.currentEmployee(r, tenant.ID)
+	if employee.ID == 0 {
+		respondWithError(w, http.StatusForbidden, "No employee record for this user")
+		return
+	}
+	var reqBody struct {
+		LeaveTypeID uint    `json:"leave_type_id"`
+		StartDate   string  `json:"start_date"`
+		EndDate     string  `json:"end_date"`
+		HoursPerDay float64 `json:"hours_per_day"`
+		Reason      string  `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	start, err := time.Parse("2006-01-02", reqBody.StartDate)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid start_date format (use YYYY-MM-DD)")
+		return
+	}
+	end := start
+	if reqBody.EndDate != "" {
+		if end, err = time.Parse("2006-01-02", reqBody.EndDate); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end_date format (use YYYY-MM-DD)")
+			return
+		}
+	}
+
+	request, err := a.cronosApp.RequestLeave(tenant.ID, employee.ID, reqBody.LeaveTypeID, start, end, reqBody.HoursPerDay, reqBody.Reason)
+	if err != nil {
+		respondWithLeaveError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, request)
+}
+
+// LeaveApprovalsHandler lists the pending leave requests the signed-in employee can approve
+// GET /api/leave/approvals
+func (a *App) LeaveApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, isAdmin := a.currentEmployee(r, tenant.ID)
+	requests, err := a.cronosApp.ListLeaveForApproval(tenant.ID, employee.ID, isAdmin)
+	if err != nil {
+		respondWithLeaveError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, requests)
+}
+
+// LeaveReviewHandler approves, rejects or cancels a leave request. Rejecting needs a comment.
+// POST /api/leave/requests/{id}/approve
+// POST /api/leave/requests/{id}/reject
+// POST /api/leave/requests/{id}/cancel
+// Body (approve, reject): { "comment": "Please hand over the release first" }
+func (a *App) LeaveReviewHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid leave request ID")
+		return
+	}
+	employee, isAdmin := a.currentEmployee(r, tenant.ID)
+
+	var reqBody struct {
+		Comment string `json:"comment"`
+	}
+	if vars["action"] != "cancel" {
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	var request *cronos.LeaveRequest
+	switch vars["action"] {
+	case "approve":
+		request, err = a.cronosApp.ApproveLeave(tenant.ID, uint(id), employee.ID, isAdmin, reqBody.Comment)
+	case "reject":
+		request, err = a.cronosApp.RejectLeave(tenant.ID, uint(id), employee.ID, isAdmin, reqBody.Comment)
+	case "cancel":
+		request, err = a.cronosApp.CancelLeave(tenant.ID, uint(id), employee.ID, isAdmin)
+	}
+	if err != nil {
+		respondWithLeaveError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, request)
+}
@@ -40,7 +40,7 @@ func (a *App) CombinedGeneralLedgerHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Get combined ledger
-	entries, err := a.cronosApp.GetCombinedGeneralLedger(beancountPath, startDate, endDate)
+	entries, err := a.cronosApp.GetCombinedGeneralLedger(MustGetTenant(r.Context()).ID, beancountPath, startDate, endDate)
 	if err != nil {
 		log.Printf("Error: CombinedGeneralLedger - Failed to get ledger: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve general ledger")
@@ -100,7 +100,7 @@ func (a *App) AccountSummaryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all entries up to date
-	entries, err := a.cronosApp.GetCombinedGeneralLedger(beancountPath, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), asOfDate)
+	entries, err := a.cronosApp.GetCombinedGeneralLedger(MustGetTenant(r.Context()).ID, beancountPath, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), asOfDate)
 	if err != nil {
 		log.Printf("Error: AccountSummary - Failed to get ledger: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve account summary")
@@ -170,7 +170,7 @@ func (a *App) TrialBalanceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all entries
-	entries, err := a.cronosApp.GetCombinedGeneralLedger(beancountPath, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), asOfDate)
+	entries, err := a.cronosApp.GetCombinedGeneralLedger(MustGetTenant(r.Context()).ID, beancountPath, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), asOfDate)
 	if err != nil {
 		log.Printf("Error: TrialBalance - Failed to get ledger: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve trial balance")
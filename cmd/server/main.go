@@ -37,9 +37,13 @@ var brandingAssets embed.FS
 // App holds our information for accessing cronos application and methods across modules
 type App struct {
 	cronosApp *cronos.App
+	scheduler *cronos.Scheduler
 	logger    *log.Logger
 	GitHash   string
 	DevToken  string // JWT token for development environment
+
+	// calendarService opens a user's calendar for import. Nil uses their Google Calendar.
+	calendarService func(userID interface{}) (cronos.CalendarService, error)
 }
 
 // createFileServer creates a file server for embedded assets with proper MIME types
@@ -130,6 +134,13 @@ func main() {
 		GitHash:   gitHash,
 	}
 
+	// Start the background job scheduler. Only the instance holding the leader lease fires jobs,
+	// but it can be switched off entirely (e.g. for one-off tooling) with SCHEDULER_DISABLED=true.
+	a.scheduler = cronosApp.NewScheduler()
+	if os.Getenv("SCHEDULER_DISABLED") != "true" {
+		a.scheduler.Start()
+	}
+
 	// Log credentials for local development
 	env := os.Getenv("ENVIRONMENT")
 	if env == "local" {
@@ -245,12 +256,65 @@ func main() {
 	adminApi.HandleFunc("/projects/{id:[0-9]+}/assets", a.ProjectAssetsCreateHandler).Methods("POST")
 	adminApi.HandleFunc("/projects/{id:[0-9]+}/assets/{assetID}", a.ProjectAssetDeleteHandler).Methods("DELETE")
 	adminApi.HandleFunc("/projects/{id:[0-9]+}/billing_codes", a.ProjectBillingCodesListHandler).Methods("GET")
+	adminApi.HandleFunc("/projects/{id:[0-9]+}/retainer", a.ProjectRetainerHandler).Methods("GET")
+	adminApi.HandleFunc("/projects/{id:[0-9]+}/retainer/invoices", a.IssueRetainerInvoiceHandler).Methods("POST")
+	adminApi.HandleFunc("/projects/{id:[0-9]+}/budget", a.ProjectBudgetHandler).Methods("GET")
+	adminApi.HandleFunc("/projects/{id:[0-9]+}/budget/check", a.CheckProjectBudgetHandler).Methods("POST")
+	adminApi.HandleFunc("/budgets", a.BudgetsHandler).Methods("GET")
+	adminApi.HandleFunc("/projects/{id:[0-9]+}/milestones", a.ProjectMilestonesHandler).Methods("GET")
+	adminApi.HandleFunc("/projects/{id:[0-9]+}/milestones", a.SaveMilestoneHandler).Methods("POST")
+	adminApi.HandleFunc("/milestones/{id:[0-9]+}", a.SaveMilestoneHandler).Methods("PUT")
+	adminApi.HandleFunc("/milestones/{id:[0-9]+}", a.DeleteMilestoneHandler).Methods("DELETE")
+	adminApi.HandleFunc("/milestones/{id:[0-9]+}/{state:(?:complete)|(?:reopen)}", a.MilestoneStateHandler).Methods("POST")
+
+	// Rate card routes
+	adminApi.HandleFunc("/rate_cards", a.ListRateCardsHandler).Methods("GET")
+	adminApi.HandleFunc("/rate_cards", a.SaveRateCardHandler).Methods("POST")
+	adminApi.HandleFunc("/rate_cards/{id:[0-9]+}", a.SaveRateCardHandler).Methods("PUT")
+	adminApi.HandleFunc("/rate_cards/{id:[0-9]+}", a.DeleteRateCardHandler).Methods("DELETE")
+	adminApi.HandleFunc("/rate_cards/{id:[0-9]+}/lines", a.SaveRateCardLineHandler).Methods("POST")
+	adminApi.HandleFunc("/rate_cards/{id:[0-9]+}/lines/{lineID:[0-9]+}", a.SaveRateCardLineHandler).Methods("PUT")
+	adminApi.HandleFunc("/rate_cards/{id:[0-9]+}/lines/{lineID:[0-9]+}", a.DeleteRateCardLineHandler).Methods("DELETE")
+	adminApi.HandleFunc("/rate_cards/{id:[0-9]+}/rerate", a.RerateRateCardHandler).Methods("POST")
 
 	// Entry routes
 	adminApi.HandleFunc("/entries", a.EntriesListHandler).Methods("GET")
+	adminApi.HandleFunc("/entries/rules", a.EntryRulesHandler).Methods("GET")
+	adminApi.HandleFunc("/entries/import", a.ImportEntriesHandler).Methods("POST")
+	adminApi.HandleFunc("/entries/imports", a.ListEntryImportsHandler).Methods("GET")
+	adminApi.HandleFunc("/entries/imports/{id:[0-9]+}/undo", a.UndoEntryImportHandler).Methods("POST")
 	adminApi.HandleFunc("/entries/{id:[0-9]+}", a.EntryHandler).Methods("GET", "PUT", "POST", "DELETE")
 	adminApi.HandleFunc("/entries/state/{id:[0-9]+}/{state:(?:void)|(?:draft)|(?:approve)|(?:reject)|(?:exclude)}", a.EntryStateHandler).Methods("POST")
 
+	// Timesheet routes
+	adminApi.HandleFunc("/timesheets", a.ListTimesheetsHandler).Methods("GET")
+	adminApi.HandleFunc("/timesheets/week", a.TimesheetHandler).Methods("GET")
+	adminApi.HandleFunc("/timesheets/submit", a.SubmitTimesheetHandler).Methods("POST")
+	adminApi.HandleFunc("/timesheets/approvals", a.TimesheetApprovalsHandler).Methods("GET")
+	adminApi.HandleFunc("/timesheets/{id:[0-9]+}/{action:(?:approve)|(?:return)}", a.TimesheetReviewHandler).Methods("POST")
+
+	// Timer routes
+	adminApi.HandleFunc("/timer", a.TimerHandler).Methods("GET")
+	adminApi.HandleFunc("/timer", a.UpdateTimerHandler).Methods("PUT")
+	adminApi.HandleFunc("/timer", a.DiscardTimerHandler).Methods("DELETE")
+	adminApi.HandleFunc("/timer/start", a.StartTimerHandler).Methods("POST")
+	adminApi.HandleFunc("/timer/stop", a.StopTimerHandler).Methods("POST")
+
+	// Leave routes
+	adminApi.HandleFunc("/leave/types", a.LeaveTypesHandler).Methods("GET")
+	adminApi.HandleFunc("/leave/types", a.SaveLeaveTypeHandler).Methods("POST")
+	adminApi.HandleFunc("/leave/types/{id:[0-9]+}", a.SaveLeaveTypeHandler).Methods("PUT")
+	adminApi.HandleFunc("/leave/types/{id:[0-9]+}", a.DeleteLeaveTypeHandler).Methods("DELETE")
+	adminApi.HandleFunc("/leave/holidays", a.HolidaysHandler).Methods("GET")
+	adminApi.HandleFunc("/leave/holidays", a.CreateHolidayHandler).Methods("POST")
+	adminApi.HandleFunc("/leave/holidays/{id:[0-9]+}", a.DeleteHolidayHandler).Methods("DELETE")
+	adminApi.HandleFunc("/leave/balances", a.LeaveBalancesHandler).Methods("GET")
+	adminApi.HandleFunc("/leave/balances/adjust", a.AdjustLeaveBalanceHandler).Methods("POST")
+	adminApi.HandleFunc("/leave/requests", a.LeaveRequestsHandler).Methods("GET")
+	adminApi.HandleFunc("/leave/requests", a.CreateLeaveRequestHandler).Methods("POST")
+	adminApi.HandleFunc("/leave/approvals", a.LeaveApprovalsHandler).Methods("GET")
+	adminApi.HandleFunc("/leave/requests/{id:[0-9]+}/{action:(?:approve)|(?:reject)|(?:cancel)}", a.LeaveReviewHandler).Methods("POST")
+
 	// Staff routes
 	adminApi.HandleFunc("/staff", a.StaffListHandler).Methods("GET")
 	adminApi.HandleFunc("/staff/{id:[0-9]+}", a.StaffHandler).Methods("GET", "PUT", "POST", "DELETE")
@@ -278,6 +342,28 @@ func main() {
 	adminApi.HandleFunc("/capacity", a.CapacityDataHandler).Methods("GET")
 	adminApi.HandleFunc("/capacity/detail", a.CapacityDetailHandler).Methods("GET")
 
+	// Forecast and staffing scenario routes
+	adminApi.HandleFunc("/forecast", a.ForecastHandler).Methods("GET")
+	adminApi.HandleFunc("/forecast/compare", a.CompareScenariosHandler).Methods("GET")
+	adminApi.HandleFunc("/scenarios", a.ListScenariosHandler).Methods("GET")
+	adminApi.HandleFunc("/scenarios", a.SaveScenarioHandler).Methods("POST")
+	adminApi.HandleFunc("/scenarios/{id:[0-9]+}", a.SaveScenarioHandler).Methods("PUT")
+	adminApi.HandleFunc("/scenarios/{id:[0-9]+}", a.DeleteScenarioHandler).Methods("DELETE")
+	adminApi.HandleFunc("/scenarios/{id:[0-9]+}/assignments", a.SaveScenarioAssignmentHandler).Methods("POST")
+	adminApi.HandleFunc("/scenarios/{id:[0-9]+}/assignments/{assignmentId:[0-9]+}", a.SaveScenarioAssignmentHandler).Methods("PUT")
+	adminApi.HandleFunc("/scenarios/{id:[0-9]+}/assignments/{assignmentId:[0-9]+}", a.DeleteScenarioAssignmentHandler).Methods("DELETE")
+	adminApi.HandleFunc("/scenarios/{id:[0-9]+}/promote", a.PromoteScenarioHandler).Methods("POST")
+
+	// Skills and staffing search routes
+	adminApi.HandleFunc("/skills", a.SkillsHandler).Methods("GET")
+	adminApi.HandleFunc("/skills", a.SaveSkillHandler).Methods("POST")
+	adminApi.HandleFunc("/skills/{id:[0-9]+}", a.SaveSkillHandler).Methods("PUT")
+	adminApi.HandleFunc("/skills/{id:[0-9]+}", a.DeleteSkillHandler).Methods("DELETE")
+	adminApi.HandleFunc("/staff/{id:[0-9]+}/skills", a.StaffSkillsHandler).Methods("GET", "PUT")
+	adminApi.HandleFunc("/projects/{id:[0-9]+}/skills", a.ProjectSkillsHandler).Methods("GET", "PUT")
+	adminApi.HandleFunc("/project_assignments/{id:[0-9]+}/skills", a.ProjectAssignmentSkillsHandler).Methods("GET", "PUT")
+	adminApi.HandleFunc("/staffing/search", a.StaffingSearchHandler).Methods("POST")
+
 	// Project analytics routes
 	adminApi.HandleFunc("/project-profitability", a.ProjectProfitabilityHandler).Methods("GET")
 
@@ -297,12 +383,25 @@ func main() {
 	// Journal routes
 	adminApi.HandleFunc("/cronos/journals", a.JournalsListHandler).Methods("GET")
 	adminApi.HandleFunc("/cronos/journals/manual", a.ManualJournalEntryHandler).Methods("POST")
+	adminApi.HandleFunc("/cronos/journal-transactions", a.ListJournalTransactionsHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/journal-transactions/unbalanced", a.UnbalancedJournalTransactionsHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/journal-transactions/{id:[0-9]+}", a.GetJournalTransactionHandler).Methods("GET")
+
+	// Accounting period routes
+	adminApi.HandleFunc("/cronos/accounting-periods", a.ListAccountingPeriodsHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/accounting-periods", a.OpenAccountingPeriodHandler).Methods("POST")
+	adminApi.HandleFunc("/cronos/accounting-periods/overrides", a.ListPeriodOverridesHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/accounting-periods/{id:[0-9]+}/checklist", a.AccountingPeriodChecklistHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/accounting-periods/{id:[0-9]+}/{state:soft-close|close|reopen}", a.AccountingPeriodStateHandler).Methods("POST")
 	adminApi.HandleFunc("/cronos/accounts/balances", a.AccountBalancesHandler).Methods("GET")
 	adminApi.HandleFunc("/cronos/ledger/combined", a.CombinedGeneralLedgerHandler).Methods("GET")
 	adminApi.HandleFunc("/cronos/ledger/reconciliation", a.ReconciliationReportHandler).Methods("GET")
 	adminApi.HandleFunc("/cronos/ledger/account-summary", a.AccountSummaryHandler).Methods("GET")
 	adminApi.HandleFunc("/cronos/ledger/trial-balance", a.TrialBalanceHandler).Methods("GET")
 
+	// Financial statement routes
+	adminApi.HandleFunc("/cronos/statements/{type:income-statement|balance-sheet|cash-flow}", a.FinancialStatementHandler).Methods("GET")
+
 	// Chart of Accounts routes
 	adminApi.HandleFunc("/cronos/chart-of-accounts", a.ListChartOfAccountsHandler).Methods("GET")
 	adminApi.HandleFunc("/cronos/chart-of-accounts", a.CreateChartOfAccountHandler).Methods("POST")
@@ -347,6 +446,49 @@ func main() {
 	adminApi.HandleFunc("/reconciliation/expenses/search", a.SearchExpensesForReconciliationHandler).Methods("GET")
 	adminApi.HandleFunc("/reconciliation/expenses/{id:[0-9]+}/reconcile", a.ReconcileExpenseWithOfflineJournalHandler).Methods("POST")
 	adminApi.HandleFunc("/reconciliation/offline-journals/{id:[0-9]+}/unreconcile", a.UnreconcileTransactionHandler).Methods("POST")
+	adminApi.HandleFunc("/reconciliation/payments/{id:[0-9]+}/reconcile", a.ReconcilePaymentHandler).Methods("POST")
+	adminApi.HandleFunc("/reconciliation/payments/{id:[0-9]+}/unreconcile", a.UnreconcilePaymentHandler).Methods("POST")
+
+	// AR aging and dunning routes
+	adminApi.HandleFunc("/cronos/ar-aging", a.ARAgingHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/dunning/steps", a.ListDunningStepsHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/dunning/steps", a.SaveDunningStepHandler).Methods("POST")
+	adminApi.HandleFunc("/cronos/dunning/steps/{id:[0-9]+}", a.SaveDunningStepHandler).Methods("PUT")
+	adminApi.HandleFunc("/cronos/dunning/steps/{id:[0-9]+}", a.DeleteDunningStepHandler).Methods("DELETE")
+	adminApi.HandleFunc("/cronos/dunning/run", a.RunDunningHandler).Methods("POST")
+	adminApi.HandleFunc("/cronos/invoices/{id:[0-9]+}/reminders", a.InvoiceDunningRemindersHandler).Methods("GET")
+
+	// Client Payments routes
+	adminApi.HandleFunc("/cronos/payments", a.ListPaymentsHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/payments", a.CreatePaymentHandler).Methods("POST")
+	adminApi.HandleFunc("/cronos/payments/{id:[0-9]+}", a.GetPaymentHandler).Methods("GET")
+	adminApi.HandleFunc("/invoices/{id:[0-9]+}/apply-credit", a.ApplyAccountCreditHandler).Methods("POST")
+
+	// Credit Notes routes
+	adminApi.HandleFunc("/cronos/credit-notes", a.ListCreditNotesHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/credit-notes", a.CreateCreditNoteHandler).Methods("POST")
+	adminApi.HandleFunc("/cronos/credit-notes/{id:[0-9]+}", a.GetCreditNoteHandler).Methods("GET")
+	adminApi.HandleFunc("/cronos/credit-notes/{id:[0-9]+}/issue", a.IssueCreditNoteHandler).Methods("POST")
+	adminApi.HandleFunc("/cronos/credit-notes/{id:[0-9]+}/void", a.VoidCreditNoteHandler).Methods("POST")
+
+	// Sales Tax routes
+	adminApi.HandleFunc("/tax/jurisdictions", a.ListTaxJurisdictionsHandler).Methods("GET")
+	adminApi.HandleFunc("/tax/jurisdictions", a.SaveTaxJurisdictionHandler).Methods("POST")
+	adminApi.HandleFunc("/tax/jurisdictions/{id:[0-9]+}", a.SaveTaxJurisdictionHandler).Methods("PUT")
+	adminApi.HandleFunc("/tax/rates", a.ListTaxRatesHandler).Methods("GET")
+	adminApi.HandleFunc("/tax/rates", a.SaveTaxRateHandler).Methods("POST")
+	adminApi.HandleFunc("/tax/rates/{id:[0-9]+}", a.SaveTaxRateHandler).Methods("PUT")
+	adminApi.HandleFunc("/tax/profiles", a.ListTaxProfilesHandler).Methods("GET")
+	adminApi.HandleFunc("/tax/profiles", a.SaveTaxProfileHandler).Methods("POST")
+	adminApi.HandleFunc("/tax/profiles/{id:[0-9]+}", a.SaveTaxProfileHandler).Methods("PUT")
+	adminApi.HandleFunc("/tax/assignments", a.AssignTaxProfileHandler).Methods("PUT")
+	adminApi.HandleFunc("/reports/tax-liability", a.TaxLiabilityReportHandler).Methods("GET")
+
+	// Currency routes
+	adminApi.HandleFunc("/exchange-rates", a.ListExchangeRatesHandler).Methods("GET")
+	adminApi.HandleFunc("/exchange-rates/import", a.ImportExchangeRatesHandler).Methods("POST")
+	adminApi.HandleFunc("/exchange-rates/lookup", a.GetExchangeRateHandler).Methods("GET")
+	adminApi.HandleFunc("/exchange-rates/functional-currency", a.SetFunctionalCurrencyHandler).Methods("PUT")
 
 	// Recurring Entries routes
 	adminApi.HandleFunc("/admin/recurring-entries", a.ListRecurringEntriesHandler).Methods("GET")
@@ -356,6 +498,13 @@ func main() {
 	adminApi.HandleFunc("/admin/recurring-entries/generate", a.GenerateRecurringEntriesHandler).Methods("POST")
 	adminApi.HandleFunc("/admin/recurring-entries/sync", a.SyncEmployeeRecurringEntriesHandler).Methods("POST")
 
+	// Scheduled Jobs routes
+	adminApi.HandleFunc("/admin/jobs", a.ListScheduledJobsHandler).Methods("GET")
+	adminApi.HandleFunc("/admin/jobs/{id:[0-9]+}/runs", a.ListJobRunsHandler).Methods("GET")
+	adminApi.HandleFunc("/admin/jobs/{id:[0-9]+}/pause", a.PauseScheduledJobHandler).Methods("POST")
+	adminApi.HandleFunc("/admin/jobs/{id:[0-9]+}/resume", a.ResumeScheduledJobHandler).Methods("POST")
+	adminApi.HandleFunc("/admin/jobs/{id:[0-9]+}/trigger", a.TriggerScheduledJobHandler).Methods("POST")
+
 	// Expense Categories routes
 	adminApi.HandleFunc("/expense-categories", a.GetExpenseCategoriesHandler).Methods("GET")
 	adminApi.HandleFunc("/expense-categories", a.CreateExpenseCategoryHandler).Methods("POST")
@@ -373,6 +522,11 @@ func main() {
 	adminApi.HandleFunc("/google/auth/status", a.GoogleAuthStatusHandler).Methods("GET")
 	adminApi.HandleFunc("/google/auth/disconnect", a.GoogleAuthDisconnectHandler).Methods("DELETE")
 	adminApi.HandleFunc("/google/calendar/events", a.GoogleCalendarEventsHandler).Methods("GET")
+	adminApi.HandleFunc("/google/calendar/import", a.GoogleCalendarImportHandler).Methods("POST")
+	adminApi.HandleFunc("/google/calendar/rules", a.ListCalendarMappingRulesHandler).Methods("GET")
+	adminApi.HandleFunc("/google/calendar/rules", a.SaveCalendarMappingRuleHandler).Methods("POST")
+	adminApi.HandleFunc("/google/calendar/rules/{id:[0-9]+}", a.SaveCalendarMappingRuleHandler).Methods("PUT")
+	adminApi.HandleFunc("/google/calendar/rules/{id:[0-9]+}", a.DeleteCalendarMappingRuleHandler).Methods("DELETE")
 
 	// Portal API Routes (scoped to client's account)
 	portalApi := r.PathPrefix("/api/portal").Subrouter()
@@ -383,6 +537,7 @@ func main() {
 	portalApi.HandleFunc("/projects", a.PortalProjectsListHandler).Methods("GET")
 	portalApi.HandleFunc("/draft_entries", a.PortalDraftEntriesHandler).Methods("GET")
 	portalApi.HandleFunc("/project_budgets", a.PortalProjectBudgetsHandler).Methods("GET")
+	portalApi.HandleFunc("/retainers", a.PortalRetainersHandler).Methods("GET")
 	portalApi.HandleFunc("/weekly_hours_summary", a.PortalWeeklyHoursSummaryHandler).Methods("GET")
 	portalApi.HandleFunc("/capacity", a.PortalCapacityDataHandler).Methods("GET")
 	portalApi.HandleFunc("/account-details", a.PortalAccountDetailsHandler).Methods("GET")
@@ -473,6 +628,7 @@ func main() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), wait)
 	defer cancel()
+	a.scheduler.Stop()
 	srv.Shutdown(ctx)
 	log.Println("shutting down")
 	os.Exit(0)
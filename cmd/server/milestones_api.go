@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ProjectMilestonesHandler lists a project's milestones in the order they fall due
+// GET /api/projects/{id}/milestones
+func (a *App) ProjectMilestonesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+	milestones, err := a.cronosApp.ListProjectMilestones(tenant.ID, uint(id))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load milestones")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, milestones)
+}
+
+// SaveMilestoneHandler adds a milestone to a fixed-fee project, or updates one when a milestone ID is
+// in the path. Completed milestones can't be changed until they're reopened. Amount is in cents of
+// currency, which defaults to the functional currency.
+// POST /api/projects/{id}/milestones
+// PUT /api/milestones/{id}
+// Body: { "name": "Discovery", "description": "Workshops and findings", "amount": 1500000, "currency": "USD", "due_date": "2025-03-31" }
+func (a *App) SaveMilestoneHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	var milestone cronos.Milestone
+	if r.Method == "PUT" {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&milestone, vars["id"]).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Milestone not found")
+			return
+		}
+		if milestone.Completed {
+			respondWithError(w, http.StatusConflict, cronos.ErrMilestoneCompleted.Error())
+			return
+		}
+	} else {
+		var project cronos.Project
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&project, vars["id"]).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		if project.BillingMethod != cronos.BillingMethodFixedFee.String() {
+			respondWithError(w, http.StatusBadRequest, cronos.ErrNotFixedFeeProject.Error())
+			return
+		}
+		milestone.TenantID = tenant.ID
+		milestone.ProjectID = project.ID
+	}
+
+	var reqBody struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Amount      int64  `json:"amount"`
+		Currency    string `json:"currency"`
+		DueDate     string `json:"due_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	milestone.Name = reqBody.Name
+	milestone.Description = reqBody.Description
+	milestone.Amount = reqBody.Amount
+	milestone.Currency = strings.ToUpper(reqBody.Currency)
+	milestone.DueDate = time.Time{}
+	if reqBody.DueDate != "" {
+		dueDate, err := time.Parse("2006-01-02", reqBody.DueDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid due_date format (use YYYY-MM-DD)")
+			return
+		}
+		milestone.DueDate = dueDate
+	}
+	if err := cronos.ValidateMilestone(&milestone); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := a.cronosApp.DB.Save(&milestone).Error; err != nil {
+		log.Printf("Failed to save milestone: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save milestone")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, milestone)
+}
+
+// DeleteMilestoneHandler removes a milestone that hasn't been completed
+// DELETE /api/milestones/{id}
+func (a *App) DeleteMilestoneHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var milestone cronos.Milestone
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&milestone, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Milestone not found")
+		return
+	}
+	if milestone.Completed {
+		respondWithError(w, http.StatusConflict, cronos.ErrMilestoneCompleted.Error())
+		return
+	}
+	if err := a.cronosApp.DB.Delete(&milestone).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete milestone")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Milestone deleted"})
+}
+
+// MilestoneStateHandler completes a milestone, billing it on the project's draft invoice for the
+// completion date (default today), or reopens it while that invoice is still a draft
+// POST /api/milestones/{id}/complete
+// POST /api/milestones/{id}/reopen
+// Body (complete): { "completed_at": "2025-03-28" }
+func (a *App) MilestoneStateHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid milestone ID")
+		return
+	}
+
+	var milestone *cronos.Milestone
+	switch vars["state"] {
+	case "complete":
+		var reqBody struct {
+			CompletedAt string `json:"completed_at"`
+		}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+		completedAt := time.Now().UTC()
+		if reqBody.CompletedAt != "" {
+			completedAt, err = time.Parse("2006-01-02", reqBody.CompletedAt)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid completed_at format (use YYYY-MM-DD)")
+				return
+			}
+		}
+		milestone, err = a.cronosApp.CompleteMilestone(tenant.ID, uint(id), completedAt)
+	case "reopen":
+		milestone, err = a.cronosApp.ReopenMilestone(tenant.ID, uint(id))
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, cronos.ErrMilestoneCompleted), errors.Is(err, cronos.ErrMilestoneInvoiced):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, cronos.ErrNotFixedFeeProject), errors.Is(err, cronos.ErrNoDraftInvoice):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("Failed to %s milestone %d: %v", vars["state"], id, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to update milestone")
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusOK, milestone)
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
 )
 
 // OfflineJournalsListHandler lists offline journals with optional filters
@@ -185,7 +187,8 @@ func (a *App) DeleteOfflineJournalHandler(w http.ResponseWriter, r *http.Request
 // PostOfflineJournalsToGLHandler posts approved offline journals to the main GL
 func (a *App) PostOfflineJournalsToGLHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		IDs []uint `json:"ids"`
+		IDs            []uint `json:"ids"`
+		OverrideReason string `json:"override_reason"` // lets admins post into a soft-closed period
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -199,7 +202,11 @@ func (a *App) PostOfflineJournalsToGLHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = a.cronosApp.PostOfflineJournalsToGL(req.IDs)
+	err = a.ledgerApp(r, req.OverrideReason).PostOfflineJournalsToGL(req.IDs)
+	if errors.Is(err, cronos.ErrPeriodLocked) {
+		http.Error(w, "Failed to post to GL: "+err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to post to GL: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ListPaymentsHandler lists client payments, optionally filtered by account
+// GET /api/cronos/payments?account_id=123
+func (a *App) ListPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	query := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("Account").Preload("Allocations")
+	if accountID := r.URL.Query().Get("account_id"); accountID != "" {
+		query = query.Where("account_id = ?", accountID)
+	}
+
+	var payments []cronos.Payment
+	if err := query.Order("received_at desc").Find(&payments).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load payments")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, payments)
+}
+
+// GetPaymentHandler returns a single payment with its allocations
+// GET /api/cronos/payments/{id}
+func (a *App) GetPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+
+	var payment cronos.Payment
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).
+		Preload("Account").Preload("Allocations").Preload("ReconciledOfflineJournal").
+		First(&payment, vars["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, payment)
+}
+
+// CreatePaymentHandler records a client payment and allocates it to invoices
+// POST /api/cronos/payments
+// Body: { "account_id": 1, "amount": 150000, "received_at": "2025-01-31", "allocations": [{ "invoice_id": 10, "amount": 100000 }] }
+func (a *App) CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var reqBody struct {
+		AccountID   uint                              `json:"account_id"`
+		Amount      int64                             `json:"amount"`
+		ReceivedAt  string                            `json:"received_at"`
+		Method      string                            `json:"method"`
+		Reference   string                            `json:"reference"`
+		Notes       string                            `json:"notes"`
+		Allocations []cronos.PaymentAllocationRequest `json:"allocations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.AccountID == 0 || reqBody.Amount <= 0 {
+		respondWithError(w, http.StatusBadRequest, "account_id and a positive amount are required")
+		return
+	}
+
+	receivedAt := time.Now()
+	if reqBody.ReceivedAt != "" {
+		parsed, err := time.Parse("2006-01-02", reqBody.ReceivedAt)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid received_at format (use YYYY-MM-DD)")
+			return
+		}
+		receivedAt = parsed
+	}
+
+	payment := cronos.Payment{
+		TenantID:   tenant.ID,
+		AccountID:  reqBody.AccountID,
+		Amount:     reqBody.Amount,
+		ReceivedAt: receivedAt,
+		Method:     reqBody.Method,
+		Reference:  reqBody.Reference,
+		Notes:      reqBody.Notes,
+	}
+	if err := a.cronosApp.RecordPayment(&payment, reqBody.Allocations); err != nil {
+		log.Printf("Failed to record payment: %v", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.cronosApp.DB.Preload("Allocations").First(&payment, payment.ID)
+	respondWithJSON(w, http.StatusCreated, payment)
+}
+
+// ApplyAccountCreditHandler applies an account's unapplied credit to an open invoice
+// POST /api/invoices/{id}/apply-credit
+// Body: { "amount": 50000 }
+func (a *App) ApplyAccountCreditHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	invoiceID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+
+	var reqBody struct {
+		Amount int64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := a.cronosApp.ApplyAccountCredit(tenant.ID, uint(invoiceID), reqBody.Amount); err != nil {
+		log.Printf("Failed to apply account credit to invoice %d: %v", invoiceID, err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var invoice cronos.Invoice
+	a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("PaymentAllocations").First(&invoice, invoiceID)
+	respondWithJSON(w, http.StatusOK, invoice)
+}
+
+// ReconcilePaymentHandler links a payment with the bank deposit it arrived in
+// POST /api/reconciliation/payments/{id}/reconcile
+// Body: { "offline_journal_id": 123 }
+func (a *App) ReconcilePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	userIDVal := r.Context().Value("user_id")
+	userID, ok := userIDVal.(uint)
+	if !ok || userID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var employee cronos.Employee
+	if err := a.cronosApp.DB.Where("user_id = ?", userID).First(&employee).Error; err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Employee record not found")
+		return
+	}
+
+	vars := mux.Vars(r)
+	paymentID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	var reqBody struct {
+		OfflineJournalID uint `json:"offline_journal_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	payment, err := a.cronosApp.ReconcilePayment(tenant.ID, uint(paymentID), reqBody.OfflineJournalID, employee.ID)
+	if errors.Is(err, cronos.ErrPaymentNotFound) {
+		respondWithError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Reconciled payment %d with offline journal %d by employee %d", payment.ID, reqBody.OfflineJournalID, employee.ID)
+	respondWithJSON(w, http.StatusOK, payment)
+}
+
+// UnreconcilePaymentHandler removes the bank reconciliation from a payment
+// POST /api/reconciliation/payments/{id}/unreconcile
+func (a *App) UnreconcilePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	paymentID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	payment, err := a.cronosApp.UnreconcilePayment(tenant.ID, uint(paymentID))
+	if errors.Is(err, cronos.ErrPaymentNotFound) {
+		respondWithError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, payment)
+}
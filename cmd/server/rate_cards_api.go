@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// formRateCardID reads the rate_card_id form field of an account or project. An empty value or "null"
+// clears the card. sent is false when the field wasn't in the request at all.
+func (a *App) formRateCardID(r *http.Request, tenantID uint) (id *uint, sent bool, err error) {
+	value := r.FormValue("rate_card_id")
+	if _, sent = r.Form["rate_card_id"]; !sent {
+		return nil, false, nil
+	}
+	if value == "" || value == "null" {
+		return nil, true, nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, true, errors.New("invalid rate_card_id")
+	}
+	var card cronos.RateCard
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenantID)).First(&card, parsed).Error; err != nil {
+		return nil, true, errors.New("rate card not found")
+	}
+	return &card.ID, true, nil
+}
+
+// sameUintPtr reports whether two optional IDs point at the same record
+func sameUintPtr(x, y *uint) bool {
+	if x == nil || y == nil {
+		return x == nil && y == nil
+	}
+	return *x == *y
+}
+
+// ListRateCardsHandler lists the tenant's rate cards with their rates
+// GET /api/rate_cards
+func (a *App) ListRateCardsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	cards, err := a.cronosApp.ListRateCards(tenant.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load rate cards")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, cards)
+}
+
+// SaveRateCardHandler creates a rate card, or renames one when an ID is in the path. Attach it to
+// accounts and projects with their rate_card_id field.
+// POST /api/rate_cards
+// PUT /api/rate_cards/{id}
+// Body: { "name": "2025 Standard", "description": "Rates from January 2025" }
+func (a *App) SaveRateCardHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var card cronos.RateCard
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&card, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Rate card not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	card.TenantID = tenant.ID
+	card.Name = reqBody.Name
+	card.Description = reqBody.Description
+	if err := a.cronosApp.DB.Omit("Lines").Save(&card).Error; err != nil {
+		log.Printf("Failed to save rate card: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save rate card")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, card)
+}
+
+// DeleteRateCardHandler removes a rate card and its rates. Cards still attached to an account or
+// project can't be removed.
+// DELETE /api/rate_cards/{id}
+func (a *App) DeleteRateCardHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var card cronos.RateCard
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&card, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Rate card not found")
+		return
+	}
+	var accounts, projects int64
+	a.cronosApp.DB.Model(&cronos.Account{}).Where("rate_card_id = ?", card.ID).Count(&accounts)
+	a.cronosApp.DB.Model(&cronos.Project{}).Where("rate_card_id = ?", card.ID).Count(&projects)
+	if accounts+projects > 0 {
+		respondWithError(w, http.StatusConflict, cronos.ErrRateCardInUse.Error())
+		return
+	}
+	a.cronosApp.DB.Where("rate_card_id = ?", card.ID).Delete(&cronos.RateCardLine{})
+	if err := a.cronosApp.DB.Delete(&card).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete rate card")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Rate card deleted"})
+}
+
+// SaveRateCardLineHandler adds a rate to a card, or updates one when a line ID is in the path. A rate
+// is for one employee, one role (matched on the employee's title), or everyone when neither is set.
+// Amounts are hourly, in dollars. When the rate takes effect in the past, draft entries from that
+// date on are re-rated.
+// POST /api/rate_cards/{id}/lines
+// PUT /api/rate_cards/{id}/lines/{lineID}
+// Body: { "employee_id": null, "role": "Senior Consultant", "amount": 225, "internal_amount": 110, "effective_from": "2025-01-01", "effective_to": "2025-06-30" }
+func (a *App) SaveRateCardLineHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	var card cronos.RateCard
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&card, vars["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Rate card not found")
+		return
+	}
+	line := cronos.RateCardLine{TenantID: tenant.ID, RateCardID: card.ID}
+	var previousFrom time.Time
+	if lineID, ok := vars["lineID"]; ok {
+		if err := a.cronosApp.DB.Where("rate_card_id = ?", card.ID).First(&line, lineID).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Rate not found")
+			return
+		}
+		previousFrom = line.EffectiveFrom
+	}
+
+	var reqBody struct {
+		EmployeeID     *uint   `json:"employee_id"`
+		Role           string  `json:"role"`
+		Amount         float64 `json:"amount"`
+		InternalAmount float64 `json:"internal_amount"`
+		EffectiveFrom  string  `json:"effective_from"`
+		EffectiveTo    string  `json:"effective_to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.EmployeeID != nil {
+		var employee cronos.Employee
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&employee, *reqBody.EmployeeID).Error; err != nil {
+			respondWithError(w, http.StatusBadRequest, "Employee not found")
+			return
+		}
+	}
+	line.EmployeeID = reqBody.EmployeeID
+	line.Employee = nil
+	line.Role = reqBody.Role
+	line.Amount = reqBody.Amount
+	line.InternalAmount = reqBody.InternalAmount
+	line.EffectiveFrom = time.Time{}
+	line.EffectiveTo = nil
+	if reqBody.EffectiveFrom != "" {
+		effectiveFrom, err := time.Parse("2006-01-02", reqBody.EffectiveFrom)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid effective_from format (use YYYY-MM-DD)")
+			return
+		}
+		line.EffectiveFrom = effectiveFrom
+	}
+	if reqBody.EffectiveTo != "" {
+		effectiveTo, err := time.Parse("2006-01-02", reqBody.EffectiveTo)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid effective_to format (use YYYY-MM-DD)")
+			return
+		}
+		line.EffectiveTo = &effectiveTo
+	}
+	if err := a.cronosApp.ValidateRateCardLine(&line); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := a.cronosApp.DB.Save(&line).Error; err != nil {
+		log.Printf("Failed to save rate card line: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save rate")
+		return
+	}
+
+	// Re-rate from the earlier of the old and new start dates when the change reaches back in time
+	since := line.EffectiveFrom
+	if !previousFrom.IsZero() && previousFrom.Before(since) {
+		since = previousFrom
+	}
+	if since.Before(time.Now()) {
+		if _, err := a.cronosApp.RerateRateCard(tenant.ID, card.ID, since); err != nil {
+			log.Printf("Failed to re-rate rate card %d: %v", card.ID, err)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, line)
+}
+
+// DeleteRateCardLineHandler removes a rate from a card and re-rates the draft entries it applied to
+// DELETE /api/rate_cards/{id}/lines/{lineID}
+func (a *App) DeleteRateCardLineHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	var line cronos.RateCardLine
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("rate_card_id = ?", vars["id"]).
+		First(&line, vars["lineID"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Rate not found")
+		return
+	}
+	if err := a.cronosApp.DB.Delete(&line).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete rate")
+		return
+	}
+	if _, err := a.cronosApp.RerateRateCard(tenant.ID, line.RateCardID, line.EffectiveFrom); err != nil {
+		log.Printf("Failed to re-rate rate card %d: %v", line.RateCardID, err)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Rate deleted"})
+}
+
+// RerateRateCardHandler recomputes the fees of draft entries on every project billed from the card
+// that started on or after since (default: all draft entries)
+// POST /api/rate_cards/{id}/rerate
+// Body: { "since": "2025-01-01" }
+func (a *App) RerateRateCardHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var card cronos.RateCard
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&card, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Rate card not found")
+		return
+	}
+	var reqBody struct {
+		Since string `json:"since"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	var since time.Time
+	if reqBody.Since != "" {
+		parsed, err := time.Parse("2006-01-02", reqBody.Since)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid since format (use YYYY-MM-DD)")
+			return
+		}
+		since = parsed
+	}
+
+	changed, err := a.cronosApp.RerateRateCard(tenant.ID, card.ID, since)
+	if err != nil {
+		log.Printf("Failed to re-rate rate card %d: %v", card.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to re-rate entries")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]int{"entries_rerated": changed})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -75,7 +76,7 @@ func (a *App) SearchExpensesForReconciliationHandler(w http.ResponseWriter, r *h
 
 // ReconcileExpenseWithOfflineJournalHandler links an expense with an offline journal transaction
 // POST /api/reconciliation/expenses/{id}/reconcile
-// Body: { "offline_journal_id": 123 }
+// Body: { "offline_journal_id": 123, "override_reason": "Late statement" }
 func (a *App) ReconcileExpenseWithOfflineJournalHandler(w http.ResponseWriter, r *http.Request) {
 	tenant := MustGetTenant(r.Context())
 	// Get user ID from context
@@ -103,7 +104,8 @@ func (a *App) ReconcileExpenseWithOfflineJournalHandler(w http.ResponseWriter, r
 
 	// Parse request body
 	var reqBody struct {
-		OfflineJournalID uint `json:"offline_journal_id"`
+		OfflineJournalID uint   `json:"offline_journal_id"`
+		OverrideReason   string `json:"override_reason"` // lets admins post into a soft-closed period
 	}
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
@@ -189,6 +191,51 @@ func (a *App) ReconcileExpenseWithOfflineJournalHandler(w http.ResponseWriter, r
 	offlineJournal.ReconciledBy = &employee.ID
 	offlineJournal.Status = "posted" // Mark as posted (clearing entries will be created below)
 
+	// Book the clearing entry to move from ACCRUED_EXPENSES_PAYABLE to actual payment account
+	// This posts directly to the main GL (not offline journal)
+	//
+	// DR: ACCRUED_EXPENSES_PAYABLE (clear the contra account)
+	// CR: [Actual Payment Account] (e.g., CREDIT_CARD_CHASE)
+	//
+	// IMPORTANT: Use the SAME subaccount that was used when the expense was originally approved
+	// (stored in expense.SubaccountCode) so the entries properly zero out
+	//
+	// Note: The DR expense side from the offline journal is NOT posted to GL because
+	// the expense was already posted when it was approved. We just mark it as "posted"
+	// in the offline journal for record-keeping.
+	//
+	// Both legs post as one transaction dated the day of reconciliation, before anything is marked
+	// reconciled, so a refused posting leaves the expense and transaction as they were.
+	clearing := cronos.JournalTransaction{
+		TenantID:      tenant.ID,
+		EffectiveDate: now,
+		SourceType:    cronos.JournalSourceExpense.String(),
+		SourceID:      &expense.ID,
+		Memo:          fmt.Sprintf("Cleared expense payment via reconciliation: %s (tx date: %s)", expense.Description, offlineJournal.Date.Format("2006-01-02")),
+		Lines: []cronos.Journal{
+			{
+				Account:    "ACCRUED_EXPENSES_PAYABLE",
+				SubAccount: expense.SubaccountCode, // Use the subaccount from original expense booking
+				Debit:      journalAmount,
+			},
+			{
+				Account:    offlineJournal.Account, // The actual payment account (e.g., CREDIT_CARD_CHASE)
+				SubAccount: expense.SubaccountCode, // Use same subaccount for consistency
+				Credit:     journalAmount,
+			},
+		},
+	}
+	err = a.ledgerApp(r, reqBody.OverrideReason).PostJournalTransaction(&clearing)
+	if errors.Is(err, cronos.ErrPeriodLocked) {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to book clearing entry: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to book clearing entry")
+		return
+	}
+
 	// Save expense (within tenant)
 	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Save(&expense).Error; err != nil {
 		log.Printf("Failed to save expense reconciliation: %v", err)
@@ -218,46 +265,6 @@ func (a *App) ReconcileExpenseWithOfflineJournalHandler(w http.ResponseWriter, r
 		}
 	}
 
-	// Book the clearing entry to move from ACCRUED_EXPENSES_PAYABLE to actual payment account
-	// This posts directly to the main GL (not offline journal)
-	//
-	// DR: ACCRUED_EXPENSES_PAYABLE (clear the contra account)
-	// CR: [Actual Payment Account] (e.g., CREDIT_CARD_CHASE)
-	//
-	// IMPORTANT: Use the SAME subaccount that was used when the expense was originally approved
-	// (stored in expense.SubaccountCode) so the entries properly zero out
-	//
-	// Note: The DR expense side from the offline journal is NOT posted to GL because
-	// the expense was already posted when it was approved. We just mark it as "posted"
-	// in the offline journal for record-keeping.
-	clearingDR := cronos.Journal{
-		Account:    "ACCRUED_EXPENSES_PAYABLE",
-		SubAccount: expense.SubaccountCode, // Use the subaccount from original expense booking
-		Memo:       fmt.Sprintf("Cleared expense payment via reconciliation: %s (tx date: %s)", expense.Description, offlineJournal.Date.Format("2006-01-02")),
-		Debit:      journalAmount,
-		Credit:     0,
-		TenantID:   tenant.ID,
-	}
-	if err := a.cronosApp.DB.Create(&clearingDR).Error; err != nil {
-		log.Printf("Failed to book clearing DR: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to book clearing entry")
-		return
-	}
-
-	clearingCR := cronos.Journal{
-		Account:    offlineJournal.Account, // The actual payment account (e.g., CREDIT_CARD_CHASE)
-		SubAccount: expense.SubaccountCode, // Use same subaccount for consistency
-		Memo:       fmt.Sprintf("Cleared expense payment via reconciliation: %s (tx date: %s)", expense.Description, offlineJournal.Date.Format("2006-01-02")),
-		Debit:      0,
-		Credit:     journalAmount,
-		TenantID:   tenant.ID,
-	}
-	if err := a.cronosApp.DB.Create(&clearingCR).Error; err != nil {
-		log.Printf("Failed to book clearing CR: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to book clearing entry")
-		return
-	}
-
 	message := fmt.Sprintf("Reconciled expense %d with offline journal %d, booked clearing entry, and marked both sides as posted",
 		expense.ID, offlineJournal.ID)
 	if hasDRSide {
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ProjectRetainerHandler shows a retainer project's prepaid blocks and the hours left on them
+// GET /api/projects/{id}/retainer
+func (a *App) ProjectRetainerHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+	balance, err := a.cronosApp.GetRetainerBalance(tenant.ID, uint(id))
+	if err != nil {
+		if errors.Is(err, cronos.ErrNotRetainerProject) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, balance)
+}
+
+// IssueRetainerInvoiceHandler creates a draft prepayment invoice for the project's retainer. Monthly
+// retainers are invoiced for the month containing period_start (default this month); block retainers
+// buy another block of hours each time.
+// POST /api/projects/{id}/retainer/invoices
+// Body: { "period_start": "2025-03-01" }
+func (a *App) IssueRetainerInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	var reqBody struct {
+		PeriodStart string `json:"period_start"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	periodStart := time.Now().UTC()
+	if reqBody.PeriodStart != "" {
+		periodStart, err = time.Parse("2006-01-02", reqBody.PeriodStart)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid period_start format (use YYYY-MM-DD)")
+			return
+		}
+	}
+
+	invoice, err := a.cronosApp.IssueRetainerInvoice(tenant.ID, uint(id), periodStart)
+	if err != nil {
+		switch {
+		case errors.Is(err, cronos.ErrNotRetainerProject):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, cronos.ErrRetainerAlreadyIssued):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			log.Printf("Failed to issue retainer invoice: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to issue retainer invoice")
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, invoice)
+}
+
+// PortalRetainersHandler shows the client the remaining balance on each of their retainer projects
+// GET /api/portal/retainers
+func (a *App) PortalRetainersHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	accountID, ok := r.Context().Value("account_id").(uint)
+	if !ok || accountID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: Valid Account ID not found in token claims.")
+		return
+	}
+	balances, err := a.cronosApp.ListRetainerBalances(tenant.ID, accountID)
+	if err != nil {
+		log.Printf("Failed to load retainer balances for account %d: %v", accountID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve retainer balances.")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, balances)
+}
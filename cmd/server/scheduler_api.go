@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ListScheduledJobsHandler lists the tenant's scheduled jobs
+// GET /api/admin/jobs
+func (a *App) ListScheduledJobsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+
+	// Make sure the tenant has rows for every registered job even if the scheduler hasn't ticked yet
+	if err := a.scheduler.EnsureJobs(tenant.ID, time.Now().UTC()); err != nil {
+		log.Printf("Failed to ensure scheduled jobs: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load scheduled jobs")
+		return
+	}
+
+	var jobs []cronos.ScheduledJob
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Order("name").Find(&jobs).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load scheduled jobs")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, jobs)
+}
+
+// ListJobRunsHandler lists the most recent runs of a scheduled job
+// GET /api/admin/jobs/{id}/runs
+func (a *App) ListJobRunsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	var runs []cronos.JobRun
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).
+		Where("scheduled_job_id = ?", id).
+		Order("started_at desc").
+		Limit(50).
+		Find(&runs).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load job runs")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, runs)
+}
+
+// PauseScheduledJobHandler stops a job from firing on its schedule
+// POST /api/admin/jobs/{id}/pause
+func (a *App) PauseScheduledJobHandler(w http.ResponseWriter, r *http.Request) {
+	a.setScheduledJobPaused(w, r, true)
+}
+
+// ResumeScheduledJobHandler re-enables a paused job
+// POST /api/admin/jobs/{id}/resume
+func (a *App) ResumeScheduledJobHandler(w http.ResponseWriter, r *http.Request) {
+	a.setScheduledJobPaused(w, r, false)
+}
+
+func (a *App) setScheduledJobPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := a.scheduler.SetJobPaused(tenant.ID, uint(id), paused)
+	if errors.Is(err, cronos.ErrJobNotFound) {
+		respondWithError(w, http.StatusNotFound, "Scheduled job not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update scheduled job")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// TriggerScheduledJobHandler runs a job immediately and returns the resulting run
+// POST /api/admin/jobs/{id}/trigger
+func (a *App) TriggerScheduledJobHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	log.Printf("Manually triggered scheduled job %d for tenant %d", id, tenant.ID)
+	run, err := a.scheduler.TriggerJob(tenant.ID, uint(id))
+	switch {
+	case errors.Is(err, cronos.ErrJobNotFound):
+		respondWithError(w, http.StatusNotFound, "Scheduled job not found")
+		return
+	case errors.Is(err, cronos.ErrJobAlreadyRunning):
+		respondWithError(w, http.StatusConflict, "Scheduled job is already running")
+		return
+	case run == nil && err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Failed to run scheduled job")
+		return
+	}
+
+	// A failed job still produced a run record, which carries the error for the UI
+	respondWithJSON(w, http.StatusOK, run)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+	"gorm.io/gorm"
+)
+
+// respondWithSkillError maps skill errors onto status codes
+func respondWithSkillError(w http.ResponseWriter, err error) {
+	if errors.Is(err, cronos.ErrInvalidSkill) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("Skill error: %v", err)
+	respondWithError(w, http.StatusInternalServerError, "Failed to update skills")
+}
+
+// SkillsHandler lists the tenant's skills taxonomy
+// GET /api/skills
+func (a *App) SkillsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var skills []cronos.Skill
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Order("category, name").Find(&skills).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load skills")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, skills)
+}
+
+// SaveSkillHandler adds a skill to the taxonomy or renames one
+// POST /api/skills
+// PUT /api/skills/{id}
+// Body: { "name": "dbt", "category": "Data Engineering" }
+func (a *App) SaveSkillHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var skill cronos.Skill
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&skill, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Skill not found")
+			return
+		}
+	}
+	var reqBody struct {
+		Name     string `json:"name"`
+		Category string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	skill.TenantID = tenant.ID
+	skill.Name = reqBody.Name
+	skill.Category = reqBody.Category
+	if err := cronos.ValidateSkill(&skill); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if skill.ID == 0 {
+		status = http.StatusCreated
+	}
+	if err := a.cronosApp.DB.Save(&skill).Error; err != nil {
+		respondWithError(w, http.StatusConflict, "There is already a skill named "+skill.Name)
+		return
+	}
+	respondWithJSON(w, status, skill)
+}
+
+// DeleteSkillHandler removes a skill from the taxonomy, along with every employee's level in it and
+// every requirement for it
+// DELETE /api/skills/{id}
+func (a *App) DeleteSkillHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var skill cronos.Skill
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&skill, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Skill not found")
+		return
+	}
+	err := a.cronosApp.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("skill_id = ?", skill.ID).Delete(&cronos.EmployeeSkill{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("skill_id = ?", skill.ID).Delete(&cronos.SkillRequirement{}).Error; err != nil {
+			return err
+		}
+		// Hard delete so the name can be used again
+		return tx.Unscoped().Delete(&skill).Error
+	})
+	if err != nil {
+		log.Printf("Error deleting skill %d: %v", skill.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete skill")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// decodeSkillLevels reads a list of skill levels from the request body
+func decodeSkillLevels(w http.ResponseWriter, r *http.Request) ([]cronos.SkillLevel, bool) {
+	var levels []cronos.SkillLevel
+	if err := json.NewDecoder(r.Body).Decode(&levels); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+	return levels, true
+}
+
+// StaffSkillsHandler gets or replaces an employee's skills
+// GET /api/staff/{id}/skills
+// PUT /api/staff/{id}/skills
+// Body: [{ "skill_id": 3, "proficiency": 4 }, { "skill_id": 7, "proficiency": 2 }]
+func (a *App) StaffSkillsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var employee cronos.Employee
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&employee, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Employee not found")
+		return
+	}
+	if r.Method == "GET" {
+		skills, err := a.cronosApp.EmployeeSkills(tenant.ID, employee.ID)
+		if err != nil {
+			respondWithSkillError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, skills)
+		return
+	}
+	levels, ok := decodeSkillLevels(w, r)
+	if !ok {
+		return
+	}
+	skills, err := a.cronosApp.SetEmployeeSkills(tenant.ID, employee.ID, levels)
+	if err != nil {
+		respondWithSkillError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, skills)
+}
+
+// ProjectSkillsHandler gets or replaces the skills a project needs
+// GET /api/projects/{id}/skills
+// PUT /api/projects/{id}/skills
+// Body: [{ "skill_id": 3, "proficiency": 3 }]
+func (a *App) ProjectSkillsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var project cronos.Project
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&project, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if r.Method == "GET" {
+		requirements, err := a.cronosApp.ProjectSkills(tenant.ID, project.ID)
+		if err != nil {
+			respondWithSkillError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, requirements)
+		return
+	}
+	levels, ok := decodeSkillLevels(w, r)
+	if !ok {
+		return
+	}
+	requirements, err := a.cronosApp.SetProjectSkills(tenant.ID, project.ID, levels)
+	if err != nil {
+		respondWithSkillError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, requirements)
+}
+
+// ProjectAssignmentSkillsHandler gets or replaces the skills a staffing assignment needs on top of its
+// project's
+// GET /api/project_assignments/{id}/skills
+// PUT /api/project_assignments/{id}/skills
+// Body: [{ "skill_id": 7, "proficiency": 4 }]
+func (a *App) ProjectAssignmentSkillsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var assignment cronos.StaffingAssignment
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&assignment, mux.Vars(r)["id"]).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "Staffing assignment not found")
+		return
+	}
+	if r.Method == "GET" {
+		requirements, err := a.cronosApp.AssignmentSkills(tenant.ID, assignment.ID)
+		if err != nil {
+			respondWithSkillError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, requirements)
+		return
+	}
+	levels, ok := decodeSkillLevels(w, r)
+	if !ok {
+		return
+	}
+	requirements, err := a.cronosApp.SetAssignmentSkills(tenant.ID, assignment.ID, levels)
+	if err != nil {
+		respondWithSkillError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, requirements)
+}
+
+// StaffingSearchHandler ranks active employees by skill match and free capacity. Pass assignment_id to
+// search for a staffing assignment using its skills, dates and commitment, or project_id to use a
+// project's skills; anything in the body overrides them.
+// POST /api/staffing/search
+// Body: { "project_id": 12, "skills": [{ "skill_id": 3, "proficiency": 4 }], "start_date": "2025-04-06",
+// "end_date": "2025-06-28", "hours": 20, "limit": 10 }
+func (a *App) StaffingSearchHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var reqBody struct {
+		AssignmentID *uint               `json:"assignment_id"`
+		ProjectID    *uint               `json:"project_id"`
+		Skills       []cronos.SkillLevel `json:"skills"`
+		StartDate    string              `json:"start_date"`
+		EndDate      string              `json:"end_date"`
+		Hours        float64             `json:"hours"`
+		Limit        int                 `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var search cronos.StaffingSearch
+	switch {
+	case reqBody.AssignmentID != nil:
+		var err error
+		if search, err = a.cronosApp.AssignmentSearch(tenant.ID, *reqBody.AssignmentID); err != nil {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+	case reqBody.ProjectID != nil:
+		requirements, err := a.cronosApp.ProjectSkills(tenant.ID, *reqBody.ProjectID)
+		if err != nil {
+			respondWithSkillError(w, err)
+			return
+		}
+		for _, requirement := range requirements {
+			search.Skills = append(search.Skills, cronos.SkillLevel{SkillID: requirement.SkillID, Proficiency: requirement.MinProficiency})
+		}
+	}
+	if reqBody.Skills != nil {
+		search.Skills = reqBody.Skills
+	}
+	if reqBody.StartDate != "" {
+		start, err := time.Parse("2006-01-02", reqBody.StartDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start_date format (use YYYY-MM-DD)")
+			return
+		}
+		search.Start = start
+	}
+	if reqBody.EndDate != "" {
+		end, err := time.Parse("2006-01-02", reqBody.EndDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end_date format (use YYYY-MM-DD)")
+			return
+		}
+		search.End = end
+	}
+	if search.Start.IsZero() {
+		search.Start = time.Now()
+	}
+	if search.End.IsZero() {
+		search.End = search.Start.AddDate(0, 0, 27)
+	}
+	if reqBody.Hours > 0 {
+		search.Hours = reqBody.Hours
+	}
+	search.Limit = reqBody.Limit
+
+	candidates, err := a.cronosApp.SearchStaffing(tenant.ID, search)
+	if err != nil {
+		respondWithSkillError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"search":     search,
+		"candidates": candidates,
+	})
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// ListTaxJurisdictionsHandler lists the tenant's tax jurisdictions
+// GET /api/tax/jurisdictions
+func (a *App) ListTaxJurisdictionsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var jurisdictions []cronos.TaxJurisdiction
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Order("code").Find(&jurisdictions).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load tax jurisdictions")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, jurisdictions)
+}
+
+// SaveTaxJurisdictionHandler creates a jurisdiction, or updates one when an ID is in the path
+// POST /api/tax/jurisdictions
+// PUT /api/tax/jurisdictions/{id}
+// Body: { "name": "New York", "code": "US-NY", "country": "US", "region": "NY" }
+func (a *App) SaveTaxJurisdictionHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var jurisdiction cronos.TaxJurisdiction
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&jurisdiction, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Tax jurisdiction not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		Name    string `json:"name"`
+		Code    string `json:"code"`
+		Country string `json:"country"`
+		Region  string `json:"region"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.Name == "" || reqBody.Code == "" {
+		respondWithError(w, http.StatusBadRequest, "name and code are required")
+		return
+	}
+
+	jurisdiction.TenantID = tenant.ID
+	jurisdiction.Name = reqBody.Name
+	jurisdiction.Code = reqBody.Code
+	jurisdiction.Country = reqBody.Country
+	jurisdiction.Region = reqBody.Region
+	if err := a.cronosApp.DB.Save(&jurisdiction).Error; err != nil {
+		log.Printf("Failed to save tax jurisdiction: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Failed to save tax jurisdiction (codes must be unique)")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, jurisdiction)
+}
+
+// ListTaxRatesHandler lists the tenant's tax rates with their jurisdictions
+// GET /api/tax/rates
+func (a *App) ListTaxRatesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var rates []cronos.TaxRate
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("Jurisdiction").Order("name").Find(&rates).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load tax rates")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rates)
+}
+
+// SaveTaxRateHandler creates a tax rate, or updates one when an ID is in the path
+// POST /api/tax/rates
+// PUT /api/tax/rates/{id}
+// Body: { "jurisdiction_id": 1, "name": "NY State Sales Tax", "rate": 4.0, "active": true }
+func (a *App) SaveTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var rate cronos.TaxRate
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&rate, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Tax rate not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		JurisdictionID uint    `json:"jurisdiction_id"`
+		Name           string  `json:"name"`
+		Rate           float64 `json:"rate"`
+		Active         bool    `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.Name == "" || reqBody.Rate < 0 || reqBody.Rate > 100 {
+		respondWithError(w, http.StatusBadRequest, "name and a rate between 0 and 100 are required")
+		return
+	}
+	var jurisdiction cronos.TaxJurisdiction
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&jurisdiction, reqBody.JurisdictionID).Error; err != nil {
+		respondWithError(w, http.StatusBadRequest, "Tax jurisdiction not found")
+		return
+	}
+
+	rate.TenantID = tenant.ID
+	rate.JurisdictionID = jurisdiction.ID
+	rate.Name = reqBody.Name
+	rate.Rate = reqBody.Rate
+	rate.Active = reqBody.Active
+	if err := a.cronosApp.DB.Save(&rate).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save tax rate")
+		return
+	}
+	// Creating skips false booleans in favour of the column default, so set it explicitly
+	if !reqBody.Active {
+		a.cronosApp.DB.Model(&rate).Update("active", false)
+	}
+	respondWithJSON(w, http.StatusOK, rate)
+}
+
+// ListTaxProfilesHandler lists the tenant's tax profiles with their rates
+// GET /api/tax/profiles
+func (a *App) ListTaxProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var profiles []cronos.TaxProfile
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("Rates").Preload("Rates.Jurisdiction").
+		Order("name").Find(&profiles).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load tax profiles")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, profiles)
+}
+
+// SaveTaxProfileHandler creates a tax profile, or updates one when an ID is in the path
+// POST /api/tax/profiles
+// PUT /api/tax/profiles/{id}
+// Body: { "name": "NYC services", "description": "...", "exempt": false, "rate_ids": [1, 2] }
+func (a *App) SaveTaxProfileHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var profile cronos.TaxProfile
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).First(&profile, id).Error; err != nil {
+			respondWithError(w, http.StatusNotFound, "Tax profile not found")
+			return
+		}
+	}
+
+	var reqBody struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Exempt      bool   `json:"exempt"`
+		RateIDs     []uint `json:"rate_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	var rates []cronos.TaxRate
+	if len(reqBody.RateIDs) > 0 {
+		a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Where("id IN ?", reqBody.RateIDs).Find(&rates)
+		if len(rates) != len(reqBody.RateIDs) {
+			respondWithError(w, http.StatusBadRequest, "One or more tax rates not found")
+			return
+		}
+	}
+
+	profile.TenantID = tenant.ID
+	profile.Name = reqBody.Name
+	profile.Description = reqBody.Description
+	profile.Exempt = reqBody.Exempt
+	if err := a.cronosApp.DB.Omit("Rates").Save(&profile).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save tax profile")
+		return
+	}
+	if err := a.cronosApp.DB.Model(&profile).Association("Rates").Replace(rates); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save tax profile rates")
+		return
+	}
+
+	a.cronosApp.DB.Preload("Rates").First(&profile, profile.ID)
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+// AssignTaxProfileHandler sets or clears the tax profile of an account, billing code or expense category
+// PUT /api/tax/assignments
+// Body: { "target": "account", "target_id": 3, "tax_profile_id": 2 }
+func (a *App) AssignTaxProfileHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	var reqBody struct {
+		Target       string `json:"target"` // account, billing_code or expense_category
+		TargetID     uint   `json:"target_id"`
+		TaxProfileID *uint  `json:"tax_profile_id"` // null clears the profile
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err := a.cronosApp.AssignTaxProfile(tenant.ID, reqBody.Target, reqBody.TargetID, reqBody.TaxProfileID)
+	if errors.Is(err, cronos.ErrTaxProfileNotFound) {
+		respondWithError(w, http.StatusNotFound, "Tax profile not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Tax profile assigned"})
+}
+
+// TaxLiabilityReportHandler reports tax owed per jurisdiction for invoices approved in a period
+// GET /api/reports/tax-liability?start=2025-01-01&end=2025-03-31
+func (a *App) TaxLiabilityReportHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	start, err := time.Parse("2006-01-02", r.URL.Query().Get("start"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid start date format (use YYYY-MM-DD)")
+		return
+	}
+	end, err := time.Parse("2006-01-02", r.URL.Query().Get("end"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid end date format (use YYYY-MM-DD)")
+		return
+	}
+
+	// The end date is inclusive for callers
+	report, err := a.cronosApp.GetTaxLiabilityReport(tenant.ID, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Failed to build tax liability report: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to build tax liability report")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
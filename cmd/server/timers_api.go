@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/snowpackdata/cronos"
+)
+
+// respondWithTimerError maps timer errors onto status codes
+func respondWithTimerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cronos.ErrNoTimer):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, cronos.ErrTimerRunning), errors.Is(err, cronos.ErrTimesheetLocked):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Timer error: %v", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// timerEmployee loads the signed-in user's employee record, responding with 403 when there isn't one
+func (a *App) timerEmployee(w http.ResponseWriter, r *http.Request, tenantID uint) (cronos.Employee, bool) {
+	employee, _ := a.currentEmployee(r, tenantID)
+	if employee.ID == 0 {
+		respondWithError(w, http.StatusForbidden, "No employee record for this user")
+		return employee, false
+	}
+	return employee, true
+}
+
+// TimerHandler returns the signed-in employee's running timer, or null when none is running
+// GET /api/timer
+func (a *App) TimerHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, ok := a.timerEmployee(w, r, tenant.ID)
+	if !ok {
+		return
+	}
+	timer, err := a.cronosApp.GetTimer(tenant.ID, employee.ID)
+	if errors.Is(err, cronos.ErrNoTimer) {
+		respondWithJSON(w, http.StatusOK, nil)
+		return
+	}
+	if err != nil {
+		respondWithTimerError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, timer)
+}
+
+// StartTimerHandler starts a timer for the signed-in employee. Only one can run at a time.
+// POST /api/timer/start
+// Body: { "billing_code_id": 12, "notes": "Pipeline review", "is_meeting": false }
+func (a *App) StartTimerHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, ok := a.timerEmployee(w, r, tenant.ID)
+	if !ok {
+		return
+	}
+	var reqBody struct {
+		BillingCodeID uint   `json:"billing_code_id"`
+		Notes         string `json:"notes"`
+		IsMeeting     bool   `json:"is_meeting"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if reqBody.BillingCodeID == 0 {
+		respondWithError(w, http.StatusBadRequest, "billing_code_id is required")
+		return
+	}
+	timer, err := a.cronosApp.StartTimer(tenant.ID, employee.ID, reqBody.BillingCodeID, reqBody.Notes, reqBody.IsMeeting, time.Now())
+	if err != nil {
+		respondWithTimerError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, timer)
+}
+
+// UpdateTimerHandler edits the running timer's notes or billing code. Fields left out are unchanged.
+// PUT /api/timer
+// Body: { "notes": "Pipeline review and fixes", "billing_code_id": 14 }
+func (a *App) UpdateTimerHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, ok := a.timerEmployee(w, r, tenant.ID)
+	if !ok {
+		return
+	}
+	var reqBody struct {
+		BillingCodeID *uint   `json:"billing_code_id"`
+		Notes         *string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	timer, err := a.cronosApp.UpdateTimer(tenant.ID, employee.ID, reqBody.BillingCodeID, reqBody.Notes)
+	if err != nil {
+		respondWithTimerError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, timer)
+}
+
+// StopTimerHandler stops the running timer and returns the entry it recorded. When an entry rule
+// blocks the entry the timer keeps running and the violations are returned with a 422.
+// POST /api/timer/stop
+func (a *App) StopTimerHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, ok := a.timerEmployee(w, r, tenant.ID)
+	if !ok {
+		return
+	}
+	entry, validation, err := a.cronosApp.StopTimer(tenant.ID, employee.ID, time.Now())
+	if errors.Is(err, cronos.ErrEntryBlocked) {
+		respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":      err.Error(),
+			"violations": validation.Violations,
+		})
+		return
+	}
+	if err != nil {
+		respondWithTimerError(w, err)
+		return
+	}
+
+	a.cronosApp.DB.Scopes(cronos.TenantScope(tenant.ID)).Preload("BillingCode.Rate").Preload("BillingCode.InternalRate").Preload("Employee").First(entry, entry.ID)
+	apiEntry := entry.GetAPIEntry()
+	apiEntry.Warnings = validation.Warnings()
+	respondWithJSON(w, http.StatusCreated, apiEntry)
+}
+
+// DiscardTimerHandler throws away the running timer without recording an entry
+// DELETE /api/timer
+func (a *App) DiscardTimerHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, ok := a.timerEmployee(w, r, tenant.ID)
+	if !ok {
+		return
+	}
+	if err := a.cronosApp.DiscardTimer(tenant.ID, employee.ID); err != nil {
+		respondWithTimerError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "discarded"})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/snowpackdata/cronos"
+)
+
+// formEmployeeID reads an optional employee ID form field, such as a manager or project lead. An
+// empty value or "null" clears it. sent is false when the field wasn't in the request at all.
+func (a *App) formEmployeeID(r *http.Request, field string, tenantID uint) (id *uint, sent bool, err error) {
+	value := r.FormValue(field)
+	if _, sent = r.Form[field]; !sent {
+		return nil, false, nil
+	}
+	if value == "" || value == "null" {
+		return nil, true, nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, true, errors.New("invalid " + field)
+	}
+	var employee cronos.Employee
+	if err := a.cronosApp.DB.Scopes(cronos.TenantScope(tenantID)).First(&employee, parsed).Error; err != nil {
+		return nil, true, errors.New("employee not found for " + field)
+	}
+	return &employee.ID, true, nil
+}
+
+// currentEmployee loads the signed-in user's employee record and whether they're an admin
+func (a *App) currentEmployee(r *http.Request, tenantID uint) (cronos.Employee, bool) {
+	var employee cronos.Employee
+	a.cronosApp.DB.Scopes(cronos.TenantScope(tenantID)).Where("user_id = ?", r.Context().Value("user_id")).Limit(1).Find(&employee)
+	var user cronos.User
+	a.cronosApp.DB.Scopes(cronos.TenantScope(tenantID)).Limit(1).Find(&user, r.Context().Value("user_id"))
+	return employee, user.Role == cronos.UserRoleAdmin.String()
+}
+
+// respondWithTimesheetError maps timesheet errors onto status codes
+func respondWithTimesheetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cronos.ErrNotTimesheetApprover):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, cronos.ErrInvalidTimesheetTransition), errors.Is(err, cronos.ErrTimesheetLocked):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Timesheet error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update timesheet")
+	}
+}
+
+// TimesheetHandler returns the signed-in employee's timesheet and entries for the week containing
+// the given day (default this week)
+// GET /api/timesheets/week?date=2025-03-05
+func (a *App) TimesheetHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, _ := a.currentEmployee(r, tenant.ID)
+	if employee.ID == 0 {
+		respondWithError(w, http.StatusForbidden, "No employee record for this user")
+		return
+	}
+	day := time.Now()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+		day = parsed
+	}
+
+	timesheet, err := a.cronosApp.GetTimesheet(tenant.ID, employee.ID, day)
+	if err != nil {
+		respondWithTimesheetError(w, err)
+		return
+	}
+	entries, err := a.cronosApp.TimesheetEntries(timesheet)
+	if err != nil {
+		respondWithTimesheetError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, cronos.TimesheetWeek{Timesheet: *timesheet, Entries: entries})
+}
+
+// ListTimesheetsHandler lists the signed-in employee's timesheets, most recent week first
+// GET /api/timesheets
+func (a *App) ListTimesheetsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, _ := a.currentEmployee(r, tenant.ID)
+	timesheets, err := a.cronosApp.ListEmployeeTimesheets(tenant.ID, employee.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load timesheets")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, timesheets)
+}
+
+// SubmitTimesheetHandler submits the signed-in employee's week for approval, locking its entries
+// POST /api/timesheets/submit
+// Body: { "date": "2025-03-05" }
+func (a *App) SubmitTimesheetHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, _ := a.currentEmployee(r, tenant.ID)
+	if employee.ID == 0 {
+		respondWithError(w, http.StatusForbidden, "No employee record for this user")
+		return
+	}
+	var reqBody struct {
+		Date string `json:"date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	day, err := time.Parse("2006-01-02", reqBody.Date)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+		return
+	}
+
+	timesheet, err := a.cronosApp.SubmitTimesheet(tenant.ID, employee.ID, day)
+	if err != nil {
+		respondWithTimesheetError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, timesheet)
+}
+
+// TimesheetApprovalsHandler lists the submitted timesheets the signed-in employee can approve
+// GET /api/timesheets/approvals
+func (a *App) TimesheetApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	employee, isAdmin := a.currentEmployee(r, tenant.ID)
+	timesheets, err := a.cronosApp.ListTimesheetsForApproval(tenant.ID, employee.ID, isAdmin)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load timesheets")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, timesheets)
+}
+
+// TimesheetReviewHandler approves a submitted timesheet, approving its entries, or returns it to
+// the employee with a comment
+// POST /api/timesheets/{id}/approve
+// POST /api/timesheets/{id}/return
+// Body (return): { "comment": "Tuesday's client hours are on the internal code" }
+func (a *App) TimesheetReviewHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := MustGetTenant(r.Context())
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid timesheet ID")
+		return
+	}
+	employee, isAdmin := a.currentEmployee(r, tenant.ID)
+
+	var timesheet *cronos.Timesheet
+	switch vars["action"] {
+	case "approve":
+		timesheet, err = a.cronosApp.ApproveTimesheet(tenant.ID, uint(id), employee.ID, isAdmin)
+	case "return":
+		var reqBody struct {
+			Comment string `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		timesheet, err = a.cronosApp.ReturnTimesheet(tenant.ID, uint(id), employee.ID, isAdmin, reqBody.Comment)
+	}
+	if err != nil {
+		respondWithTimesheetError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, timesheet)
+}
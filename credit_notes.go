@@ -0,0 +1,274 @@
+package cronos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"gorm.io/gorm"
+)
+
+var ErrCreditNoteNotFound = errors.New("credit note not found")
+var ErrCreditNoteNotDraft = errors.New("credit note is not a draft")
+var ErrInvoiceNotCreditable = errors.New("only sent, partially paid or paid invoices can be credited")
+var ErrCreditExceedsInvoice = errors.New("credit exceeds the uncredited invoice total")
+var ErrInvalidCreditDisposition = errors.New("disposition must be credit or refund")
+
+// CreditNoteRequest describes a credit note to draft against an invoice. Either LineItemIDs (credited in
+// full) or a fixed Amount in cents must be given.
+type CreditNoteRequest struct {
+	InvoiceID   uint   `json:"invoice_id"`
+	LineItemIDs []uint `json:"line_item_ids"`
+	Amount      int64  `json:"amount"`
+	Reason      string `json:"reason"`
+	Disposition string `json:"disposition"` // CreditNoteDisposition, defaults to credit
+}
+
+// CreateCreditNote drafts a credit note against a sent invoice. Nothing is booked until it is issued.
+func (a *App) CreateCreditNote(tenantID uint, req CreditNoteRequest) (*CreditNote, error) {
+	disposition := req.Disposition
+	if disposition == "" {
+		disposition = CreditNoteDispositionCredit.String()
+	}
+	if disposition != CreditNoteDispositionCredit.String() && disposition != CreditNoteDispositionRefund.String() {
+		return nil, ErrInvalidCreditDisposition
+	}
+
+	var invoice Invoice
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&invoice, req.InvoiceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load invoice %d: %w", req.InvoiceID, err)
+	}
+	if invoice.State != InvoiceStateSent.String() && invoice.State != InvoiceStatePartiallyPaid.String() &&
+		invoice.State != InvoiceStatePaid.String() {
+		return nil, fmt.Errorf("%w: invoice #%d is %s", ErrInvoiceNotCreditable, invoice.ID, invoice.State)
+	}
+
+	note := CreditNote{
+		TenantID:    tenantID,
+		InvoiceID:   invoice.ID,
+		AccountID:   invoice.AccountID,
+		State:       CreditNoteStateDraft.String(),
+		Disposition: disposition,
+		Reason:      req.Reason,
+	}
+
+	if len(req.LineItemIDs) > 0 {
+		var items []InvoiceLineItem
+		if err := a.DB.Where("invoice_id = ? AND id IN ?", invoice.ID, req.LineItemIDs).Find(&items).Error; err != nil {
+			return nil, fmt.Errorf("failed to load invoice line items: %w", err)
+		}
+		if len(items) != len(req.LineItemIDs) {
+			return nil, fmt.Errorf("line items must belong to invoice #%d", invoice.ID)
+		}
+		for i := range items {
+			note.LineItems = append(note.LineItems, CreditNoteLineItem{
+				TenantID:          tenantID,
+				InvoiceLineItemID: &items[i].ID,
+				Description:       items[i].Description,
+				Amount:            items[i].Amount,
+			})
+			note.Amount += items[i].Amount
+		}
+	} else {
+		if req.Amount <= 0 {
+			return nil, ErrInvalidPaymentAmount
+		}
+		description := req.Reason
+		if description == "" {
+			description = fmt.Sprintf("Credit against invoice %s", invoice.Name)
+		}
+		note.LineItems = []CreditNoteLineItem{{TenantID: tenantID, Description: description, Amount: req.Amount}}
+		note.Amount = req.Amount
+	}
+
+	if note.Amount <= 0 {
+		return nil, ErrInvalidPaymentAmount
+	}
+	creditable, err := a.creditableAmount(&invoice)
+	if err != nil {
+		return nil, err
+	}
+	if note.Amount > creditable {
+		return nil, fmt.Errorf("%w: $%.2f remaining on invoice #%d", ErrCreditExceedsInvoice, float64(creditable)/100, invoice.ID)
+	}
+
+	if err := a.DB.Create(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to create credit note: %w", err)
+	}
+	return &note, nil
+}
+
+// creditableAmount returns how much of an invoice, in cents, is not already covered by other credit notes
+func (a *App) creditableAmount(invoice *Invoice) (int64, error) {
+	var credited int64
+	if err := a.DB.Model(&CreditNote{}).Select("COALESCE(SUM(amount), 0)").
+		Where("invoice_id = ? AND state <> ?", invoice.ID, CreditNoteStateVoid.String()).
+		Scan(&credited).Error; err != nil {
+		return 0, fmt.Errorf("failed to total existing credit notes: %w", err)
+	}
+	return int64(math.Round(invoice.TotalAmount*100)) - credited, nil
+}
+
+// IssueCreditNote finalizes a draft credit note and books it. The full amount is debited to CREDITS_ISSUED.
+// Whatever the invoice still owes is credited to ACCOUNTS_RECEIVABLE, reducing its balance due; the rest
+// is either refunded (CR CASH) or held as account credit (CR CUSTOMER_CREDITS) for future invoices.
+func (a *App) IssueCreditNote(tenantID, creditNoteID uint, issuedAt time.Time) (*CreditNote, error) {
+	var note CreditNote
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&note, creditNoteID).Error; err != nil {
+		return nil, ErrCreditNoteNotFound
+	}
+	if note.State != CreditNoteStateDraft.String() {
+		return nil, ErrCreditNoteNotDraft
+	}
+
+	var invoice Invoice
+	if err := a.DB.Preload("Account").First(&invoice, note.InvoiceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load invoice %d: %w", note.InvoiceID, err)
+	}
+
+	// Apply as much as the invoice still owes directly against it
+	var applied int64
+	if invoice.State == InvoiceStateSent.String() || invoice.State == InvoiceStatePartiallyPaid.String() {
+		applied = InvoiceBalanceDue(&invoice)
+		if applied > note.Amount {
+			applied = note.Amount
+		}
+	}
+	if applied > 0 {
+		if err := a.applyCreditNote(&note, &invoice, applied, issuedAt, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if remainder := note.Amount - applied; remainder > 0 {
+		if err := a.bookCreditNoteRemainder(&note, &invoice.Account, remainder, issuedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	note.State = CreditNoteStateIssued.String()
+	note.IssuedAt = &issuedAt
+	if err := a.DB.Save(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to save credit note: %w", err)
+	}
+
+	if err := a.SaveCreditNoteToGCS(&note); err != nil {
+		log.Printf("Warning: failed to save credit note %d PDF: %v", note.ID, err)
+	}
+
+	log.Printf("Issued credit note %d for $%.2f against invoice %d ($%.2f applied to the balance due)",
+		note.ID, float64(note.Amount)/100, invoice.ID, float64(applied)/100)
+	return &note, nil
+}
+
+// bookCreditNoteRemainder books the part of a credit note that exceeds what the invoice still owes,
+// refunding it in cash or holding it as unapplied credit on the account
+func (a *App) bookCreditNoteRemainder(note *CreditNote, account *Account, amount int64, issuedAt time.Time) error {
+	subAccount := fmt.Sprintf("%d:%s", account.ID, account.Name)
+
+	// The credit note is in its invoice's currency
+	var invoice Invoice
+	a.DB.Select("id", "currency", "exchange_rate").First(&invoice, note.InvoiceID)
+
+	contraEntry := Journal{
+		TenantID:   note.TenantID,
+		Account:    AccountCreditsIssued.String(),
+		SubAccount: subAccount,
+		InvoiceID:  &note.InvoiceID,
+		Memo:       fmt.Sprintf("Credit note #%d issued against invoice #%d", note.ID, note.InvoiceID),
+		Debit:      amount,
+		Credit:     0,
+	}
+	contraEntry.translate(invoice.Currency, invoice.ExchangeRate)
+
+	settleEntry := Journal{
+		TenantID:   note.TenantID,
+		Account:    AccountCustomerCredits.String(),
+		SubAccount: subAccount,
+		Memo:       fmt.Sprintf("Unapplied credit from credit note #%d", note.ID),
+		Debit:      0,
+		Credit:     amount,
+	}
+	if note.Disposition == CreditNoteDispositionRefund.String() {
+		settleEntry.Account = AccountCash.String()
+		settleEntry.SubAccount = "ChaseBusiness"
+		settleEntry.Memo = fmt.Sprintf("Refund for credit note #%d", note.ID)
+	}
+	settleEntry.translate(invoice.Currency, invoice.ExchangeRate)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      note.TenantID,
+		EffectiveDate: issuedAt,
+		SourceType:    JournalSourceCreditNote.String(),
+		SourceID:      &note.ID,
+		Memo:          fmt.Sprintf("Credit note #%d", note.ID),
+		Lines:         []Journal{contraEntry, settleEntry},
+	}); err != nil {
+		return fmt.Errorf("failed to book credit note: %w", err)
+	}
+
+	if note.Disposition == CreditNoteDispositionCredit.String() {
+		note.UnappliedAmount = amount
+		if err := a.DB.Model(&Account{}).Where("id = ?", account.ID).
+			Update("unapplied_credit", gorm.Expr("unapplied_credit + ?", amount)).Error; err != nil {
+			return fmt.Errorf("failed to update account credit: %w", err)
+		}
+	}
+	return nil
+}
+
+// VoidCreditNote discards a draft credit note. Issued credit notes are final and have to be offset by a
+// new invoice instead.
+func (a *App) VoidCreditNote(tenantID, creditNoteID uint) (*CreditNote, error) {
+	var note CreditNote
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&note, creditNoteID).Error; err != nil {
+		return nil, ErrCreditNoteNotFound
+	}
+	if note.State != CreditNoteStateDraft.String() {
+		return nil, ErrCreditNoteNotDraft
+	}
+	note.State = CreditNoteStateVoid.String()
+	if err := a.DB.Save(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to void credit note: %w", err)
+	}
+	return &note, nil
+}
+
+// SaveCreditNoteToGCS renders the credit note PDF and stores it in the tenant's bucket
+func (a *App) SaveCreditNoteToGCS(note *CreditNote) error {
+	ctx := context.Background()
+
+	var tenant Tenant
+	if err := a.DB.First(&tenant, note.TenantID).Error; err != nil {
+		return fmt.Errorf("failed to get tenant for credit note: %w", err)
+	}
+	bucketName := tenant.BucketName
+	if bucketName == "" {
+		return fmt.Errorf("tenant %d has no bucket configured", note.TenantID)
+	}
+
+	// The output must be stored as a list of bytes in-memory because of the readonly filesystem in GAE
+	pdfBytes := a.GenerateCreditNotePDF(note)
+	client := a.InitializeStorageClient(a.Project, bucketName)
+
+	bucket := client.Bucket(bucketName)
+	objectName := "credit_notes/" + GenerateSecureFilename(fmt.Sprintf("credit-note-%d", note.ID)) + ".pdf"
+	writer := bucket.Object(objectName).NewWriter(ctx)
+	if _, err := writer.Write(pdfBytes); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	acl := bucket.Object(objectName).ACL()
+	if err := acl.Set(ctx, storage.AllUsers, storage.RoleReader); err != nil {
+		return err
+	}
+
+	note.GCSFile = "https://storage.googleapis.com/" + bucketName + "/" + objectName
+	return a.DB.Model(note).Update("gcs_file", note.GCSFile).Error
+}
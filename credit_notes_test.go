@@ -0,0 +1,140 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCreditNoteReducesBalanceAndHoldsCredit tests that a credit note settles the open balance first and
+// keeps the rest as account credit that later invoices can use
+func TestCreditNoteReducesBalanceAndHoldsCredit(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "credit-notes", Name: "Credit Note Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Payment Test Account", LegalName: "Credit Note LLC", Type: AccountTypeClient.String()}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	invoice := createSentInvoice(t, db, tenant.ID, account, 100000)
+	payment := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 40000, ReceivedAt: time.Now()}
+	if err := app.RecordPayment(&payment, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 40000}}); err != nil {
+		t.Fatalf("Failed to record payment: %v", err)
+	}
+
+	note, err := app.CreateCreditNote(tenant.ID, CreditNoteRequest{InvoiceID: invoice.ID, Amount: 80000, Reason: "Scope reduced"})
+	if err != nil {
+		t.Fatalf("Failed to create credit note: %v", err)
+	}
+	if note.State != CreditNoteStateDraft.String() || len(note.LineItems) != 1 {
+		t.Errorf("Expected a draft credit note with one line, got %+v", note)
+	}
+
+	// Drafts still count against what is left to credit
+	if _, err := app.CreateCreditNote(tenant.ID, CreditNoteRequest{InvoiceID: invoice.ID, Amount: 30000}); !errors.Is(err, ErrCreditExceedsInvoice) {
+		t.Errorf("Expected ErrCreditExceedsInvoice, got %v", err)
+	}
+
+	note, err = app.IssueCreditNote(tenant.ID, note.ID, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to issue credit note: %v", err)
+	}
+	if note.UnappliedAmount != 20000 {
+		t.Errorf("Expected 20000 left as credit, got %d", note.UnappliedAmount)
+	}
+
+	db.First(&invoice, invoice.ID)
+	if invoice.State != InvoiceStatePaid.String() {
+		t.Errorf("Expected credited invoice to be settled, got %s", invoice.State)
+	}
+	if invoice.AmountCredited != 600 || invoice.AmountPaid != 400 {
+		t.Errorf("Expected $400 paid and $600 credited, got %v / %v", invoice.AmountPaid, invoice.AmountCredited)
+	}
+	if got := arBalance(db, invoice.ID); got != 0 {
+		t.Errorf("Expected AR to be cleared, got %d", got)
+	}
+
+	var contra int64
+	db.Table("journals").Select("COALESCE(SUM(debit - credit), 0)").
+		Where("tenant_id = ? AND account = ?", tenant.ID, AccountCreditsIssued.String()).Scan(&contra)
+	if contra != 80000 {
+		t.Errorf("Expected 80000 booked to CREDITS_ISSUED, got %d", contra)
+	}
+
+	db.First(&account, account.ID)
+	if account.UnappliedCredit != 20000 {
+		t.Errorf("Expected account credit of 20000, got %d", account.UnappliedCredit)
+	}
+
+	// The leftover credit pays down the next invoice
+	next := createSentInvoice(t, db, tenant.ID, account, 15000)
+	if err := app.ApplyAccountCredit(tenant.ID, next.ID, 15000); err != nil {
+		t.Fatalf("Failed to apply credit note credit: %v", err)
+	}
+	db.First(&note, note.ID)
+	if note.UnappliedAmount != 5000 {
+		t.Errorf("Expected 5000 left on the credit note, got %d", note.UnappliedAmount)
+	}
+
+	if _, err := app.IssueCreditNote(tenant.ID, note.ID, time.Now()); !errors.Is(err, ErrCreditNoteNotDraft) {
+		t.Errorf("Expected ErrCreditNoteNotDraft when issuing twice, got %v", err)
+	}
+	if _, err := app.VerifyTenantJournalBalance(tenant.ID); err != nil {
+		t.Errorf("Expected balanced journals: %v", err)
+	}
+}
+
+// TestCreditNoteRefundOnPaidInvoice tests crediting invoice line items on a paid invoice as a cash refund
+func TestCreditNoteRefundOnPaidInvoice(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "credit-refund", Name: "Refund Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Payment Test Account", LegalName: "Refund LLC", Type: AccountTypeClient.String()}
+	db.Create(&account)
+
+	invoice := createSentInvoice(t, db, tenant.ID, account, 50000)
+	line := InvoiceLineItem{TenantID: tenant.ID, InvoiceID: invoice.ID, Description: "Workshop", Amount: 12500}
+	db.Create(&line)
+	payment := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 50000, ReceivedAt: time.Now()}
+	if err := app.RecordPayment(&payment, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 50000}}); err != nil {
+		t.Fatalf("Failed to record payment: %v", err)
+	}
+
+	note, err := app.CreateCreditNote(tenant.ID, CreditNoteRequest{
+		InvoiceID:   invoice.ID,
+		LineItemIDs: []uint{line.ID},
+		Disposition: CreditNoteDispositionRefund.String(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create credit note: %v", err)
+	}
+	if note.Amount != 12500 {
+		t.Errorf("Expected the line item amount to be credited, got %d", note.Amount)
+	}
+	if _, err := app.IssueCreditNote(tenant.ID, note.ID, time.Now()); err != nil {
+		t.Fatalf("Failed to issue credit note: %v", err)
+	}
+
+	var refunded int64
+	db.Table("journals").Select("COALESCE(SUM(credit), 0)").
+		Where("tenant_id = ? AND account = ? AND memo LIKE ?", tenant.ID, AccountCash.String(), "Refund%").Scan(&refunded)
+	if refunded != 12500 {
+		t.Errorf("Expected a 12500 cash refund, got %d", refunded)
+	}
+	db.First(&account, account.ID)
+	if account.UnappliedCredit != 0 {
+		t.Errorf("Expected no account credit for a refund, got %d", account.UnappliedCredit)
+	}
+	if _, err := app.VerifyTenantJournalBalance(tenant.ID); err != nil {
+		t.Errorf("Expected balanced journals: %v", err)
+	}
+}
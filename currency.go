@@ -0,0 +1,237 @@
+package cronos
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// DefaultCurrency is the functional currency for tenants that haven't set one
+const DefaultCurrency = "USD"
+
+var ErrExchangeRateNotFound = errors.New("no exchange rate available")
+var ErrCurrencyMismatch = errors.New("payment and invoice currencies differ")
+
+// FunctionalCurrency returns the currency a tenant keeps its books in, from the
+// "functional_currency" tenant setting
+func FunctionalCurrency(tenant *Tenant) string {
+	var settings struct {
+		FunctionalCurrency string `json:"functional_currency"`
+	}
+	if len(tenant.Settings) > 0 {
+		if err := json.Unmarshal(tenant.Settings, &settings); err != nil {
+			log.Printf("Warning: invalid settings for tenant %d, using %s: %v", tenant.ID, DefaultCurrency, err)
+		}
+	}
+	if settings.FunctionalCurrency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(settings.FunctionalCurrency)
+}
+
+// functionalCurrency loads the tenant and returns its functional currency
+func (a *App) functionalCurrency(tenantID uint) string {
+	var tenant Tenant
+	a.DB.Limit(1).Find(&tenant, tenantID)
+	return FunctionalCurrency(&tenant)
+}
+
+// GetExchangeRate returns how many units of `to` one unit of `from` bought on the given date, using the
+// latest rate loaded on or before it. The inverse pair is used when only that direction was loaded.
+func (a *App) GetExchangeRate(tenantID uint, from, to string, on time.Time) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+
+	var rate ExchangeRate
+	err := a.DB.Scopes(TenantScope(tenantID)).
+		Where("from_currency = ? AND to_currency = ? AND date <= ?", from, to, on).
+		Order("date desc").Limit(1).Find(&rate).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load exchange rate: %w", err)
+	}
+	if rate.ID != 0 && rate.Rate > 0 {
+		return rate.Rate, nil
+	}
+
+	err = a.DB.Scopes(TenantScope(tenantID)).
+		Where("from_currency = ? AND to_currency = ? AND date <= ?", to, from, on).
+		Order("date desc").Limit(1).Find(&rate).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load exchange rate: %w", err)
+	}
+	if rate.ID != 0 && rate.Rate > 0 {
+		return 1 / rate.Rate, nil
+	}
+	return 0, fmt.Errorf("%w: %s/%s on %s", ErrExchangeRateNotFound, from, to, on.Format("2006-01-02"))
+}
+
+// ToFunctional translates cents at the given rate. A zero rate means the amount is already functional.
+func ToFunctional(amount int64, rate float64) int64 {
+	if rate == 0 {
+		return amount
+	}
+	return int64(math.Round(float64(amount) * rate))
+}
+
+// currencyOrFunctional normalizes a currency code, treating an empty one as the functional currency
+func currencyOrFunctional(currency, functional string) string {
+	if currency == "" {
+		return functional
+	}
+	return strings.ToUpper(currency)
+}
+
+// originalBalance nets the original (foreign-currency) amounts of journal entries, debits positive
+func originalBalance(entries []Journal) int64 {
+	var balance int64
+	for _, entry := range entries {
+		if entry.Debit > 0 {
+			balance += entry.OriginalAmount
+		} else {
+			balance -= entry.OriginalAmount
+		}
+	}
+	return balance
+}
+
+// foreignRate returns the rate to translate `currency` into the tenant's functional currency on a date,
+// or zero when the currency is the functional one (or unset)
+func (a *App) foreignRate(tenantID uint, currency string, on time.Time) (float64, error) {
+	functional := a.functionalCurrency(tenantID)
+	if currency == "" || strings.EqualFold(currency, functional) {
+		return 0, nil
+	}
+	return a.GetExchangeRate(tenantID, currency, functional, on)
+}
+
+// translate converts a journal booked in a foreign currency into the functional currency, keeping the
+// original amount and rate on the entry. A zero rate leaves the journal alone.
+func (j *Journal) translate(currency string, rate float64) {
+	if rate == 0 {
+		return
+	}
+	j.Currency = currency
+	j.ExchangeRate = rate
+	j.OriginalAmount = j.Debit + j.Credit
+	j.Debit = ToFunctional(j.Debit, rate)
+	j.Credit = ToFunctional(j.Credit, rate)
+}
+
+// SetInvoiceCurrency fixes an invoice's currency from its account and, for foreign-currency invoices,
+// the accrual rate on the given date. Invoices that already have a rate keep it.
+func (a *App) SetInvoiceCurrency(invoice *Invoice, on time.Time) error {
+	functional := a.functionalCurrency(invoice.TenantID)
+	if invoice.Currency == "" {
+		var account Account
+		a.DB.Select("id", "currency").Limit(1).Find(&account, invoice.AccountID)
+		invoice.Currency = strings.ToUpper(account.Currency)
+		if invoice.Currency == "" {
+			invoice.Currency = functional
+		}
+	}
+	if invoice.Currency == functional {
+		invoice.ExchangeRate = 0
+		return nil
+	}
+	if invoice.ExchangeRate != 0 {
+		return nil
+	}
+
+	rate, err := a.GetExchangeRate(invoice.TenantID, invoice.Currency, functional, on)
+	if err != nil {
+		return err
+	}
+	invoice.ExchangeRate = rate
+	return nil
+}
+
+// realizedFXLine is the journal line for the difference between what a foreign-currency receivable was
+// carried at and what it was settled for. A positive difference is a gain (CR), a negative one a loss (DR);
+// no difference gives an empty line, which PostJournalTransaction drops.
+func realizedFXLine(invoice *Invoice, difference int64) Journal {
+	entry := Journal{
+		Account:    AccountRealizedFX.String(),
+		SubAccount: invoice.Currency,
+		InvoiceID:  &invoice.ID,
+		Memo:       fmt.Sprintf("Realized FX gain on invoice #%d", invoice.ID),
+		Credit:     difference,
+	}
+	if difference < 0 {
+		entry.Memo = fmt.Sprintf("Realized FX loss on invoice #%d", invoice.ID)
+		entry.Debit, entry.Credit = -difference, 0
+	}
+	return entry
+}
+
+// ImportExchangeRatesCSV loads rates from CSV with a header row and the columns
+// date (YYYY-MM-DD), from, to, rate. Rates already loaded for the same pair and date are replaced.
+func (a *App) ImportExchangeRatesCSV(tenantID uint, content []byte, source string) (int, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return 0, fmt.Errorf("no exchange rates found in CSV")
+	}
+
+	columns := map[string]int{"date": -1, "from": -1, "to": -1, "rate": -1}
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+	for name, i := range columns {
+		if i < 0 {
+			return 0, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	rates := make([]ExchangeRate, 0, len(records)-1)
+	for line, record := range records[1:] {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[columns["date"]]))
+		if err != nil {
+			return 0, fmt.Errorf("line %d: invalid date: %w", line+2, err)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[columns["rate"]]), 64)
+		if err != nil || value <= 0 {
+			return 0, fmt.Errorf("line %d: rate must be a positive number", line+2)
+		}
+		from := strings.ToUpper(strings.TrimSpace(record[columns["from"]]))
+		to := strings.ToUpper(strings.TrimSpace(record[columns["to"]]))
+		if len(from) != 3 || len(to) != 3 {
+			return 0, fmt.Errorf("line %d: currencies must be 3-letter ISO codes", line+2)
+		}
+		rates = append(rates, ExchangeRate{
+			TenantID:     tenantID,
+			FromCurrency: from,
+			ToCurrency:   to,
+			Date:         date,
+			Rate:         value,
+			Source:       source,
+		})
+	}
+
+	if err := a.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "from_currency"}, {Name: "to_currency"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(&rates).Error; err != nil {
+		return 0, fmt.Errorf("failed to save exchange rates: %w", err)
+	}
+
+	log.Printf("Loaded %d exchange rates for tenant %d from %s", len(rates), tenantID, source)
+	return len(rates), nil
+}
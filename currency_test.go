@@ -0,0 +1,186 @@
+package cronos
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TestExchangeRates tests loading rates from CSV and looking up the latest, inverse and missing rates
+func TestExchangeRates(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "fx", Name: "FX Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	csv := "date,from,to,rate\n2025-01-01,EUR,USD,1.10\n2025-02-01,eur,usd,1.20\n2025-01-01,USD,GBP,0.80\n"
+	imported, err := app.ImportExchangeRatesCSV(tenant.ID, []byte(csv), "rates.csv")
+	if err != nil || imported != 3 {
+		t.Fatalf("Expected 3 rates imported, got %d: %v", imported, err)
+	}
+	// Reloading the same day replaces the rate rather than duplicating it
+	if _, err := app.ImportExchangeRatesCSV(tenant.ID, []byte("date,from,to,rate\n2025-02-01,EUR,USD,1.25\n"), "fix.csv"); err != nil {
+		t.Fatalf("Failed to reload rate: %v", err)
+	}
+
+	rate, _ := app.GetExchangeRate(tenant.ID, "EUR", "USD", time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC))
+	if rate != 1.10 {
+		t.Errorf("Expected the January rate 1.10, got %v", rate)
+	}
+	rate, _ = app.GetExchangeRate(tenant.ID, "EUR", "USD", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if rate != 1.25 {
+		t.Errorf("Expected the reloaded February rate 1.25, got %v", rate)
+	}
+	rate, _ = app.GetExchangeRate(tenant.ID, "GBP", "USD", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if rate != 1.25 {
+		t.Errorf("Expected the inverse of 0.80, got %v", rate)
+	}
+	if _, err := app.GetExchangeRate(tenant.ID, "EUR", "USD", time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrExchangeRateNotFound) {
+		t.Errorf("Expected no rate before the first one loaded, got %v", err)
+	}
+
+	if _, err := app.ImportExchangeRatesCSV(tenant.ID, []byte("date,from,rate\n2025-01-01,EUR,1.1\n"), "bad.csv"); err == nil {
+		t.Error("Expected a CSV without a to column to be rejected")
+	}
+}
+
+// TestForeignCurrencyPaymentFX tests that a EUR invoice paid in instalments at different rates books the
+// realized gain and loss against the rate it was accrued at
+func TestForeignCurrencyPaymentFX(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "fx-payments", Name: "FX Payments Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Euro Client", LegalName: "Euro Client GmbH", Type: AccountTypeClient.String(), Currency: "EUR"}
+	db.Create(&account)
+	app.ImportExchangeRatesCSV(tenant.ID, []byte("date,from,to,rate\n2025-01-01,EUR,USD,1.10\n2025-02-01,EUR,USD,1.20\n2025-03-01,EUR,USD,1.05\n"), "rates.csv")
+
+	// A €1,000 invoice accrued at 1.10 sits in AR at $1,100
+	invoice := Invoice{TenantID: tenant.ID, Name: "Euro Invoice", AccountID: account.ID, State: InvoiceStateSent.String(),
+		Type: InvoiceTypeAR.String(), TotalAmount: 1000, BalanceDue: 1000, SentAt: time.Now()}
+	db.Create(&invoice)
+	if err := app.SetInvoiceCurrency(&invoice, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Failed to set invoice currency: %v", err)
+	}
+	if invoice.Currency != "EUR" || invoice.ExchangeRate != 1.10 {
+		t.Fatalf("Expected EUR at 1.10, got %s at %v", invoice.Currency, invoice.ExchangeRate)
+	}
+	db.Save(&invoice)
+	db.Create(&Adjustment{TenantID: tenant.ID, InvoiceID: &invoice.ID, Type: AdjustmentTypeFee.String(), State: AdjustmentStateSent.String(), Amount: 1000})
+	ar := Journal{TenantID: tenant.ID, Account: AccountAccountsReceivable.String(), SubAccount: "1:Euro Client", InvoiceID: &invoice.ID, Debit: 100000}
+	ar.translate(invoice.Currency, invoice.ExchangeRate)
+	revenue := Journal{TenantID: tenant.ID, Account: AccountRevenue.String(), SubAccount: "1:Euro Client", InvoiceID: &invoice.ID, Credit: 100000}
+	revenue.translate(invoice.Currency, invoice.ExchangeRate)
+	db.Create(&ar)
+	db.Create(&revenue)
+	if ar.Debit != 110000 || ar.OriginalAmount != 100000 {
+		t.Fatalf("Expected $1,100 carried for €1,000, got %d for %d", ar.Debit, ar.OriginalAmount)
+	}
+
+	// €400 arrives when the euro is worth 1.20: $480 cash against $440 of AR is a $40 gain
+	first := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 40000, ReceivedAt: time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC)}
+	if err := app.RecordPayment(&first, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 40000}}); err != nil {
+		t.Fatalf("Failed to record first payment: %v", err)
+	}
+	if first.Currency != "EUR" || first.ExchangeRate != 1.20 {
+		t.Errorf("Expected the payment in EUR at 1.20, got %s at %v", first.Currency, first.ExchangeRate)
+	}
+
+	// The remaining €600 arrives at 1.05: $630 cash against $660 of AR is a $30 loss
+	second := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 60000, ReceivedAt: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)}
+	if err := app.RecordPayment(&second, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 60000}}); err != nil {
+		t.Fatalf("Failed to record second payment: %v", err)
+	}
+
+	if balance := arBalance(db, invoice.ID); balance != 0 {
+		t.Errorf("Expected AR cleared, got %d", balance)
+	}
+	var cash, fx int64
+	db.Table("journals").Select("COALESCE(SUM(debit - credit), 0)").Where("account = ?", AccountCash.String()).Scan(&cash)
+	db.Table("journals").Select("COALESCE(SUM(credit - debit), 0)").Where("account = ?", AccountRealizedFX.String()).Scan(&fx)
+	if cash != 111000 {
+		t.Errorf("Expected $1,110 of cash, got %d", cash)
+	}
+	if fx != 1000 {
+		t.Errorf("Expected a net $10 FX gain, got %d", fx)
+	}
+	var fxEntries int64
+	db.Model(&Journal{}).Where("account = ?", AccountRealizedFX.String()).Count(&fxEntries)
+	if fxEntries != 2 {
+		t.Errorf("Expected a gain and a loss entry, got %d", fxEntries)
+	}
+	if _, err := app.VerifyTenantJournalBalance(tenant.ID); err != nil {
+		t.Errorf("Expected balanced journals: %v", err)
+	}
+
+	db.First(&invoice, invoice.ID)
+	if invoice.State != InvoiceStatePaid.String() {
+		t.Errorf("Expected the invoice paid, got %s", invoice.State)
+	}
+
+	// A USD payment can't be allocated to a EUR invoice
+	other := Invoice{TenantID: tenant.ID, AccountID: account.ID, State: InvoiceStateSent.String(), TotalAmount: 10, BalanceDue: 10, Currency: "EUR", ExchangeRate: 1.1}
+	db.Create(&other)
+	usd := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 1000, Currency: "USD", ReceivedAt: time.Now()}
+	if err := app.RecordPayment(&usd, []PaymentAllocationRequest{{InvoiceID: other.ID, Amount: 1000}}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected a currency mismatch, got %v", err)
+	}
+}
+
+// TestForeignRateInvoiceTotals tests that an invoice whose billing code is priced in another currency
+// totals the converted fees, matching its line items and the receivable booked from them
+func TestForeignRateInvoiceTotals(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "fx-totals", Name: "FX Totals Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	if _, err := app.ImportExchangeRatesCSV(tenant.ID, []byte("date,from,to,rate\n2025-01-01,USD,EUR,0.90\n"), "rates.csv"); err != nil {
+		t.Fatalf("Failed to load rates: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Euro Client", Type: AccountTypeClient.String(), Currency: "EUR"}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Rollout", AccountID: account.ID}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Senior", Amount: 100, Currency: "USD"}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Build", Code: "FXB", ProjectID: project.ID, RateID: rate.ID}
+	db.Create(&code)
+
+	invoice := Invoice{TenantID: tenant.ID, AccountID: account.ID, ProjectID: &project.ID, Name: "March", State: InvoiceStateDraft.String(), Type: InvoiceTypeAR.String()}
+	db.Create(&invoice)
+	for day := 3; day <= 5; day++ {
+		start := time.Date(2025, 3, day, 9, 0, 0, 0, time.UTC)
+		entry := Entry{TenantID: tenant.ID, ProjectID: project.ID, BillingCodeID: code.ID, InvoiceID: &invoice.ID,
+			Start: start, End: start.Add(time.Hour), State: EntryStateDraft.String(), Fee: 10000, BilledMinutes: 60}
+		if err := db.Session(&gorm.Session{SkipHooks: true}).Create(&entry).Error; err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+	}
+	if err := app.ApproveInvoice(invoice.ID); err != nil {
+		t.Fatalf("Failed to approve invoice: %v", err)
+	}
+
+	db.First(&invoice, invoice.ID)
+	var lineTotal int64
+	db.Model(&InvoiceLineItem{}).Where("invoice_id = ?", invoice.ID).Select("COALESCE(SUM(amount), 0)").Scan(&lineTotal)
+	if lineTotal != 27000 || invoice.TotalAmount != 270 || invoice.BalanceDue != 270 {
+		t.Errorf("Expected 300.00 USD billed as 270.00 EUR, got line items %d and total %.2f due %.2f", lineTotal, invoice.TotalAmount, invoice.BalanceDue)
+	}
+	var receivable Journal
+	db.Where("invoice_id = ? AND account = ? AND debit > 0", invoice.ID, AccountAccruedReceivables.String()).First(&receivable)
+	if receivable.OriginalAmount != int64(math.Round(invoice.TotalAmount*100)) {
+		t.Errorf("Expected the receivable booked at the invoice total of %.2f EUR, got %d", invoice.TotalAmount, receivable.OriginalAmount)
+	}
+}
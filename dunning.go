@@ -0,0 +1,248 @@
+package cronos
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"math"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+var ErrDunningStepNotFound = errors.New("dunning step not found")
+var ErrInvalidDunningStep = errors.New("invalid dunning step")
+
+// DunningTemplateData is what a dunning step's subject and body can refer to, e.g. {{.InvoiceNumber}}
+type DunningTemplateData struct {
+	TenantName    string
+	AccountName   string
+	InvoiceNumber string
+	Currency      string
+	BalanceDue    string // Formatted to two decimals, after any late fee this step added
+	DueDate       string
+	DaysPastDue   int    // Negative before the due date
+	LateFee       string // Empty unless this step added one
+}
+
+// renderDunningStep fills in a step's subject and HTML body
+func renderDunningStep(step *DunningStep, data DunningTemplateData) (string, string, error) {
+	subjectTemplate, err := texttemplate.New("subject").Parse(step.Subject)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: subject: %v", ErrInvalidDunningStep, err)
+	}
+	bodyTemplate, err := htmltemplate.New("body").Parse(step.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: body: %v", ErrInvalidDunningStep, err)
+	}
+
+	var subject, body bytes.Buffer
+	if err := subjectTemplate.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("%w: subject: %v", ErrInvalidDunningStep, err)
+	}
+	if err := bodyTemplate.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("%w: body: %v", ErrInvalidDunningStep, err)
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
+
+// ValidateDunningStep checks a step has what it needs and that its templates render
+func ValidateDunningStep(step *DunningStep) error {
+	if step.Name == "" || step.Subject == "" || step.Body == "" {
+		return fmt.Errorf("%w: name, subject and body are required", ErrInvalidDunningStep)
+	}
+	if step.LateFeePercent < 0 || step.LateFeeFlat < 0 {
+		return fmt.Errorf("%w: late fees cannot be negative", ErrInvalidDunningStep)
+	}
+	if (step.LateFeePercent > 0 || step.LateFeeFlat > 0) && step.DayOffset <= 0 {
+		return fmt.Errorf("%w: late fees can only be charged after the due date", ErrInvalidDunningStep)
+	}
+	_, _, err := renderDunningStep(step, DunningTemplateData{
+		TenantName: "Tenant", AccountName: "Client", InvoiceNumber: "INV-0001", Currency: DefaultCurrency,
+		BalanceDue: "100.00", DueDate: "Jan 1, 2025", DaysPastDue: step.DayOffset, LateFee: "0.00",
+	})
+	return err
+}
+
+// ListDunningSteps returns a tenant's dunning sequence in the order the steps fire
+func (a *App) ListDunningSteps(tenantID uint) ([]DunningStep, error) {
+	var steps []DunningStep
+	if err := a.DB.Scopes(TenantScope(tenantID)).Order("day_offset, id").Find(&steps).Error; err != nil {
+		return nil, fmt.Errorf("failed to load dunning steps: %w", err)
+	}
+	return steps, nil
+}
+
+// ListDunningReminders returns every reminder recorded against an invoice, oldest first
+func (a *App) ListDunningReminders(tenantID, invoiceID uint) ([]DunningReminder, error) {
+	var reminders []DunningReminder
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("DunningStep").
+		Where("invoice_id = ?", invoiceID).Order("id").Find(&reminders).Error; err != nil {
+		return nil, fmt.Errorf("failed to load dunning reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// tenantBillingEmail reads the "billing_email" tenant setting that replies to clients go to
+func tenantBillingEmail(tenant *Tenant) string {
+	var settings struct {
+		BillingEmail string `json:"billing_email"`
+	}
+	if len(tenant.Settings) > 0 {
+		_ = json.Unmarshal(tenant.Settings, &settings)
+	}
+	return settings.BillingEmail
+}
+
+// RunDunning sends the reminders that have come due on a tenant's open invoices and returns how many
+// went out. An invoice only gets the latest step it has reached: steps it passed without a reminder
+// (say, because dunning was set up after it went overdue) are recorded as skipped rather than sent
+// in a burst. Failed sends are retried on the next run.
+func (a *App) RunDunning(tenantID uint, now time.Time) (int, error) {
+	var steps []DunningStep
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("active = ?", true).Order("day_offset, id").Find(&steps).Error; err != nil {
+		return 0, fmt.Errorf("failed to load dunning steps: %w", err)
+	}
+	if len(steps) == 0 {
+		return 0, nil
+	}
+	invoices, err := a.openInvoices(tenantID)
+	if err != nil {
+		return 0, err
+	}
+	var tenant Tenant
+	if err := a.DB.First(&tenant, tenantID).Error; err != nil {
+		return 0, fmt.Errorf("failed to load tenant: %w", err)
+	}
+
+	sent := 0
+	for i := range invoices {
+		invoice := &invoices[i]
+		var existing []DunningReminder
+		if err := a.DB.Where("invoice_id = ?", invoice.ID).Find(&existing).Error; err != nil {
+			return sent, fmt.Errorf("failed to load dunning reminders: %w", err)
+		}
+		byStep := make(map[uint]DunningReminder, len(existing))
+		for _, reminder := range existing {
+			byStep[reminder.DunningStepID] = reminder
+		}
+
+		latest := -1
+		for j, step := range steps {
+			if !now.Before(invoiceDueDate(invoice).AddDate(0, 0, step.DayOffset)) {
+				latest = j
+			}
+		}
+		if latest < 0 {
+			continue
+		}
+
+		for _, step := range steps[:latest] {
+			if _, ok := byStep[step.ID]; !ok {
+				a.DB.Create(&DunningReminder{
+					TenantID: tenantID, InvoiceID: invoice.ID, DunningStepID: step.ID,
+					Status: DunningReminderStatusSkipped.String(), BalanceDue: invoice.BalanceDue,
+					Error: "Superseded by " + steps[latest].Name,
+				})
+			}
+		}
+
+		reminder, ok := byStep[steps[latest].ID]
+		if ok && reminder.Status != DunningReminderStatusFailed.String() {
+			continue
+		}
+		if !ok {
+			reminder = DunningReminder{TenantID: tenantID, InvoiceID: invoice.ID, DunningStepID: steps[latest].ID}
+		}
+		if a.sendDunningReminder(&tenant, invoice, &steps[latest], &reminder, now) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendDunningReminder charges the step's late fee if it has one and sends the reminder, recording
+// the outcome on the reminder row. The fee is only ever charged once per step, even if the email
+// has to be retried.
+func (a *App) sendDunningReminder(tenant *Tenant, invoice *Invoice, step *DunningStep, reminder *DunningReminder, now time.Time) bool {
+	reminder.Error = ""
+	reminder.Status = DunningReminderStatusFailed.String()
+	defer func() {
+		if err := a.DB.Save(reminder).Error; err != nil {
+			log.Printf("Failed to record dunning reminder for invoice %d: %v", invoice.ID, err)
+		}
+	}()
+
+	if invoice.Account.Email == "" {
+		reminder.Status = DunningReminderStatusSkipped.String()
+		reminder.Error = "Account has no email address"
+		return false
+	}
+
+	data := DunningTemplateData{
+		TenantName:    tenant.Name,
+		AccountName:   invoice.Account.Name,
+		InvoiceNumber: invoice.Number,
+		Currency:      currencyOrFunctional(invoice.Currency, FunctionalCurrency(tenant)),
+		DueDate:       invoiceDueDate(invoice).Format("Jan 2, 2006"),
+		DaysPastDue:   daysPastDue(invoice, now),
+	}
+	if data.InvoiceNumber == "" {
+		data.InvoiceNumber = fmt.Sprintf("%06d", invoice.ID)
+	}
+
+	if reminder.LateFeeAdjustmentID == nil && (step.LateFeePercent > 0 || step.LateFeeFlat > 0) {
+		fee := math.Round((invoice.BalanceDue*step.LateFeePercent/100+step.LateFeeFlat)*100) / 100
+		adjustment := Adjustment{
+			TenantID:  invoice.TenantID,
+			InvoiceID: &invoice.ID,
+			Type:      AdjustmentTypeFee.String(),
+			State:     AdjustmentStateApproved.String(),
+			Amount:    fee,
+			Notes:     fmt.Sprintf("Late fee (%s)", step.Name),
+		}
+		if err := a.DB.Create(&adjustment).Error; err != nil {
+			reminder.Error = fmt.Sprintf("failed to add late fee: %v", err)
+			return false
+		}
+		if err := a.RecordAdjustmentJournal(&adjustment); err != nil {
+			// Don't leave a fee on the invoice that never reached the books
+			a.DB.Delete(&adjustment)
+			reminder.Error = fmt.Sprintf("failed to book late fee: %v", err)
+			return false
+		}
+		a.UpdateInvoiceTotals(invoice)
+		reminder.LateFeeAdjustmentID = &adjustment.ID
+	}
+	if reminder.LateFeeAdjustmentID != nil {
+		var fee Adjustment
+		a.DB.Limit(1).Find(&fee, *reminder.LateFeeAdjustmentID)
+		data.LateFee = fmt.Sprintf("%.2f", fee.Amount)
+	}
+	data.BalanceDue = fmt.Sprintf("%.2f", invoice.BalanceDue)
+	reminder.BalanceDue = invoice.BalanceDue
+	reminder.Recipient = invoice.Account.Email
+
+	subject, body, err := renderDunningStep(step, data)
+	if err != nil {
+		reminder.Error = err.Error()
+		return false
+	}
+	reminder.Subject = subject
+
+	send := a.sendReminder
+	if send == nil {
+		send = a.SendReminderEmail
+	}
+	if err := send(invoice.Account.Email, subject, body, tenantBillingEmail(tenant)); err != nil {
+		reminder.Error = err.Error()
+		return false
+	}
+	reminder.Status = DunningReminderStatusSent.String()
+	reminder.SentAt = &now
+	log.Printf("Sent %s reminder for invoice %d to %s", step.Name, invoice.ID, invoice.Account.Email)
+	return true
+}
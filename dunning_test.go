@@ -0,0 +1,111 @@
+package cronos
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestARAgingAndDunning tests aging buckets, that only the latest reached dunning step is sent, and
+// that a late fee is charged once even when its reminder has to be retried
+func TestARAgingAndDunning(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "dunning", Name: "Dunning Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Late Payer", LegalName: "Late Payer LLC", Email: "ap@latepayer.test", Type: AccountTypeClient.String()}
+	quiet := Account{TenantID: tenant.ID, Name: "No Email", LegalName: "No Email LLC", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	db.Create(&quiet)
+
+	now := time.Date(2025, 3, 31, 16, 0, 0, 0, time.UTC)
+	overdue := createSentInvoice(t, db, tenant.ID, account, 100000)
+	ancient := createSentInvoice(t, db, tenant.ID, quiet, 50000)
+	upcoming := createSentInvoice(t, db, tenant.ID, account, 20000)
+	db.Model(&overdue).Updates(map[string]interface{}{"sent_at": now.AddDate(0, 0, -45), "due_at": now.AddDate(0, 0, -15)})
+	db.Model(&ancient).Updates(map[string]interface{}{"sent_at": now.AddDate(0, 0, -130), "due_at": now.AddDate(0, 0, -100)})
+	db.Model(&upcoming).Updates(map[string]interface{}{"sent_at": now.AddDate(0, 0, -20), "due_at": now.AddDate(0, 0, 10)})
+
+	aging, err := app.GenerateARAging(tenant.ID, now)
+	if err != nil {
+		t.Fatalf("Failed to generate aging: %v", err)
+	}
+	if aging.Totals.Current != 20000 || aging.Totals.Days1To30 != 100000 || aging.Totals.Over90 != 50000 || aging.Totals.Total != 170000 {
+		t.Errorf("Unexpected aging totals: %+v", aging.Totals)
+	}
+	if len(aging.Accounts) != 2 || aging.Accounts[0].AccountName != "Late Payer" || len(aging.Accounts[0].Invoices) != 2 {
+		t.Errorf("Expected two accounts with Late Payer's two invoices first, got %+v", aging.Accounts)
+	}
+
+	steps := []DunningStep{
+		{TenantID: tenant.ID, Name: "Friendly reminder", DayOffset: -3, Subject: "Invoice {{.InvoiceNumber}} is due soon", Body: "<p>Due {{.DueDate}}</p>", Active: true},
+		{TenantID: tenant.ID, Name: "Overdue", DayOffset: 10, Subject: "Invoice {{.InvoiceNumber}} is {{.DaysPastDue}} days overdue",
+			Body: "<p>Now {{.Currency}} {{.BalanceDue}} including a {{.LateFee}} late fee</p>", LateFeePercent: 2, Active: true},
+		{TenantID: tenant.ID, Name: "Final notice", DayOffset: 30, Subject: "Final notice", Body: "<p>Please pay</p>", Active: true},
+	}
+	for i := range steps {
+		if err := ValidateDunningStep(&steps[i]); err != nil {
+			t.Fatalf("Expected step %q to be valid: %v", steps[i].Name, err)
+		}
+		db.Create(&steps[i])
+	}
+	bad := DunningStep{Name: "Early fee", DayOffset: -1, Subject: "x", Body: "y", LateFeeFlat: 25}
+	if err := ValidateDunningStep(&bad); !errors.Is(err, ErrInvalidDunningStep) {
+		t.Errorf("Expected a late fee before the due date to be refused, got %v", err)
+	}
+
+	var subjects, bodies []string
+	failNext := true
+	app.sendReminder = func(to, subject, htmlBody, replyTo string) error {
+		if failNext {
+			failNext = false
+			return errors.New("mail server unavailable")
+		}
+		subjects = append(subjects, subject)
+		bodies = append(bodies, htmlBody)
+		return nil
+	}
+
+	// The first send fails after the fee is charged
+	if sent, err := app.RunDunning(tenant.ID, now); err != nil || sent != 0 {
+		t.Fatalf("Expected the failed send not to count, got %d: %v", sent, err)
+	}
+	if sent, err := app.RunDunning(tenant.ID, now); err != nil || sent != 1 {
+		t.Fatalf("Expected the retry to send one reminder, got %d: %v", sent, err)
+	}
+	if len(subjects) != 1 || !strings.HasSuffix(subjects[0], " is 15 days overdue") {
+		t.Errorf("Expected the overdue reminder only, got %v", subjects)
+	}
+	if len(bodies) == 1 && !strings.Contains(bodies[0], "1020.00 including a 20.00 late fee") {
+		t.Errorf("Expected the body to show the balance with the fee, got %q", bodies[0])
+	}
+
+	var fees int64
+	db.Model(&Adjustment{}).Where("invoice_id = ? AND notes LIKE ?", overdue.ID, "Late fee%").Count(&fees)
+	if fees != 1 {
+		t.Errorf("Expected the late fee to be charged once, got %d", fees)
+	}
+	var reloaded Invoice
+	db.First(&reloaded, overdue.ID)
+	if reloaded.BalanceDue != 1020 || arBalance(db, overdue.ID) != 102000 {
+		t.Errorf("Expected the fee on the invoice and in AR, got %.2f and %d", reloaded.BalanceDue, arBalance(db, overdue.ID))
+	}
+
+	history, _ := app.ListDunningReminders(tenant.ID, overdue.ID)
+	if len(history) != 2 || history[0].Status != DunningReminderStatusSkipped.String() || history[1].Status != DunningReminderStatusSent.String() {
+		t.Fatalf("Expected the pre-due step skipped and the overdue step sent, got %+v", history)
+	}
+
+	// The account without an email is recorded but not sent to, and nothing repeats on the next run
+	quietHistory, _ := app.ListDunningReminders(tenant.ID, ancient.ID)
+	if len(quietHistory) != 3 || quietHistory[2].Status != DunningReminderStatusSkipped.String() {
+		t.Errorf("Expected every step skipped for the account without email, got %+v", quietHistory)
+	}
+	if sent, _ := app.RunDunning(tenant.ID, now.AddDate(0, 0, 1)); sent != 0 {
+		t.Errorf("Expected no repeat reminders, got %d", sent)
+	}
+}
@@ -121,6 +121,39 @@ func (a *App) SendTextEmail(email Email) error {
 	return nil
 }
 
+// SendReminderEmail sends a payment reminder with replies going to the tenant's billing address
+func (a *App) SendReminderEmail(to, subject, htmlBody, replyTo string) error {
+	message := mail.NewV3Mail()
+	message.SetFrom(mail.NewEmail("Cronos", CRONOS_SENDER_ADDRESS))
+	message.Subject = subject
+	if replyTo != "" {
+		message.SetReplyTo(mail.NewEmail("", replyTo))
+	}
+	p := mail.NewPersonalization()
+	p.AddTos(mail.NewEmail("", to))
+	message.AddPersonalizations(p)
+	message.AddContent(mail.NewContent("text/html", htmlBody))
+
+	client := sendgrid.NewSendClient(os.Getenv("SENDGRID_API_KEY"))
+	response, err := client.Send(message)
+	if err != nil {
+		return errors.Wrap(err, "error sending reminder email")
+	}
+	if response.StatusCode >= 400 {
+		return errors.New("SendGrid returned error status: " + response.Body)
+	}
+	return nil
+}
+
+// sendStaffNotice emails a notice to a staff member through sendNotice, or SendReminderEmail when it
+// isn't set
+func (a *App) sendStaffNotice(to, subject, htmlBody string) error {
+	if a.sendNotice != nil {
+		return a.sendNotice(to, subject, htmlBody)
+	}
+	return a.SendReminderEmail(to, subject, htmlBody, "")
+}
+
 // generateInvoiceFilename creates a clean, descriptive filename for the invoice PDF
 func generateInvoiceFilename(invoice *Invoice) string {
 	// Get account name or use "Invoice" as default
@@ -140,6 +173,12 @@ func generateInvoiceFilename(invoice *Invoice) string {
 	periodStart := invoice.PeriodStart.Format("2006-01-02")
 	periodEnd := invoice.PeriodEnd.Format("2006-01-02")
 
+	// Prefer the legal invoice number: invoice_INV-2025-00042_ClientName_2025-01-01_2025-01-31.pdf
+	if invoice.Number != "" {
+		cleanNumber := strings.Trim(reg.ReplaceAllString(invoice.Number, "_"), "_")
+		return fmt.Sprintf("invoice_%s_%s_%s_%s.pdf", cleanNumber, cleanAccountName, periodStart, periodEnd)
+	}
+
 	// Generate filename: invoice_123456_ClientName_2025-01-01_2025-01-31.pdf
 	filename := fmt.Sprintf("invoice_%06d_%s_%s_%s.pdf",
 		invoice.ID,
@@ -0,0 +1,452 @@
+package cronos
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrEntryImportInvalid = errors.New("entry import has rows with errors")
+var ErrEntryImportLocked = errors.New("import can't be undone once its entries are approved or invoiced")
+var ErrEntryImportUndone = errors.New("import has already been undone")
+
+// errDryRun rolls back a preview's transaction
+var errDryRun = errors.New("dry run")
+
+// entryImportDayStart is where rows with hours but no start time begin, stacked in file order
+const entryImportDayStart = 9 * time.Hour
+
+// EntryImportMapping maps CSV columns onto entry fields. Columns are zero-based and -1 leaves a field
+// unmapped. Rows need a start, from the date column or a start column holding a full date and time,
+// and either an end or hours.
+type EntryImportMapping struct {
+	EmailCol   int    `json:"email_col"`
+	CodeCol    int    `json:"code_col"`
+	DateCol    int    `json:"date_col"`
+	StartCol   int    `json:"start_col"` // A time of day, or a full date and time
+	EndCol     int    `json:"end_col"`
+	HoursCol   int    `json:"hours_col"` // Decimal hours or h:mm
+	NotesCol   int    `json:"notes_col"`
+	HasHeader  bool   `json:"has_header"`
+	DateFormat string `json:"date_format"` // e.g. MM/DD/YYYY; common formats are tried when empty
+}
+
+// EntryImportRecord is one row to import as it was read, before it's resolved
+type EntryImportRecord struct {
+	Line  int    `json:"line"`
+	Email string `json:"email"`
+	Code  string `json:"code"`
+	Date  string `json:"date"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Hours string `json:"hours"`
+	Notes string `json:"notes"`
+}
+
+// EntryImportRowResult is what importing a row did, or would do in a preview
+type EntryImportRowResult struct {
+	Line          int       `json:"line"`
+	EmployeeID    uint      `json:"employee_id,omitempty"`
+	BillingCodeID uint      `json:"billing_code_id,omitempty"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Hours         float64   `json:"hours"`
+	Fee           int       `json:"fee"` // in cents
+	State         string    `json:"state,omitempty"`
+	Errors        []string  `json:"errors,omitempty"`
+	Warnings      []string  `json:"warnings,omitempty"`
+}
+
+// EntryImportResult is the outcome of an import. Batch is nil for previews and failed imports.
+type EntryImportResult struct {
+	Rows       []EntryImportRowResult `json:"rows"`
+	ErrorCount int                    `json:"error_count"`
+	TotalHours float64                `json:"total_hours"`
+	Batch      *EntryImportBatch      `json:"batch,omitempty"`
+}
+
+// ParseEntryImportCSV reads entry rows from a CSV export using the column mapping
+func ParseEntryImportCSV(content []byte, mapping EntryImportMapping) ([]EntryImportRecord, error) {
+	if mapping.EmailCol < 0 || mapping.CodeCol < 0 {
+		return nil, errors.New("invalid column mapping: email and billing code columns are required")
+	}
+	content = bytes.TrimPrefix(content, []byte{0xEF, 0xBB, 0xBF})
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+
+	column := func(record []string, col int) string {
+		if col < 0 || col >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[col])
+	}
+	var records []EntryImportRecord
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV at line %d: %w", line, err)
+		}
+		if mapping.HasHeader && line == 1 {
+			continue
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		date := column(record, mapping.DateCol)
+		if date != "" && mapping.DateFormat != "" {
+			parsed, err := time.Parse(convertDateFormat(mapping.DateFormat), date)
+			if err == nil {
+				date = parsed.Format("2006-01-02")
+			}
+		}
+		records = append(records, EntryImportRecord{
+			Line:  line,
+			Email: column(record, mapping.EmailCol),
+			Code:  column(record, mapping.CodeCol),
+			Date:  date,
+			Start: column(record, mapping.StartCol),
+			End:   column(record, mapping.EndCol),
+			Hours: column(record, mapping.HoursCol),
+			Notes: column(record, mapping.NotesCol),
+		})
+	}
+	return records, nil
+}
+
+// ParseEntryImportJSON reads entry rows from a JSON array of objects with the EntryImportRecord
+// fields. Hours may be a number or a string.
+func ParseEntryImportJSON(content []byte) ([]EntryImportRecord, error) {
+	var rows []struct {
+		EntryImportRecord
+		Hours json.RawMessage `json:"hours"`
+	}
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return nil, fmt.Errorf("invalid JSON entry import: %w", err)
+	}
+	records := make([]EntryImportRecord, len(rows))
+	for i, row := range rows {
+		records[i] = row.EntryImportRecord
+		records[i].Line = i + 1
+		records[i].Hours = strings.Trim(string(row.Hours), `"`)
+		if records[i].Hours == "null" {
+			records[i].Hours = ""
+		}
+	}
+	return records, nil
+}
+
+// parseImportDate reads a date in ISO or one of the common export formats
+func parseImportDate(value string) (time.Time, error) {
+	for _, format := range []string{"2006-01-02", "01/02/2006", "1/2/2006", "2006/01/02", "Jan 2, 2006", "January 2, 2006"} {
+		if parsed, err := time.Parse(format, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseImportTime reads a full date and time, or a time of day on the given date. timeOfDay reports
+// which it was. All times are UTC.
+func parseImportTime(value string, date time.Time, hasDate bool) (t time.Time, timeOfDay bool, err error) {
+	for _, format := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if parsed, err := time.Parse(format, value); err == nil {
+			return parsed.UTC(), false, nil
+		}
+	}
+	for _, format := range []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3:04 pm", "3:04pm"} {
+		if parsed, err := time.Parse(format, value); err == nil {
+			if !hasDate {
+				return time.Time{}, true, fmt.Errorf("time %q needs a date", value)
+			}
+			return date.Add(time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute + time.Duration(parsed.Second())*time.Second), true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+}
+
+// parseImportHours reads decimal hours ("1.5") or hours and minutes ("1:30")
+func parseImportHours(value string) (time.Duration, error) {
+	if h, m, ok := strings.Cut(value, ":"); ok {
+		hours, errH := strconv.Atoi(h)
+		minutes, errM := strconv.Atoi(m)
+		if errH != nil || errM != nil || minutes < 0 || minutes >= 60 {
+			return 0, fmt.Errorf("invalid hours %q", value)
+		}
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+	}
+	hours, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hours %q", value)
+	}
+	return time.Duration(hours * float64(time.Hour)).Round(time.Second), nil
+}
+
+// PreviewEntryImport runs an import without saving anything, reporting what each row would create
+// and the errors that would stop the import
+func (a *App) PreviewEntryImport(tenantID uint, records []EntryImportRecord, now time.Time) (*EntryImportResult, error) {
+	return a.runEntryImport(tenantID, records, nil, now)
+}
+
+// CommitEntryImport imports the rows as entries and records the batch so it can be undone. It's all
+// or nothing: when any row has an error nothing is saved and ErrEntryImportInvalid is returned with
+// the row results.
+func (a *App) CommitEntryImport(tenantID uint, records []EntryImportRecord, source string, importedByID *uint, now time.Time) (*EntryImportResult, error) {
+	batch := &EntryImportBatch{TenantID: tenantID, Source: source, ImportedByID: importedByID}
+	return a.runEntryImport(tenantID, records, batch, now)
+}
+
+// runEntryImport creates the rows' entries one at a time in a transaction, so each row is checked
+// against the entry rules with the rows before it in place. The transaction is rolled back for a
+// preview (nil batch) or when any row has an error.
+func (a *App) runEntryImport(tenantID uint, records []EntryImportRecord, batch *EntryImportBatch, now time.Time) (*EntryImportResult, error) {
+	if len(records) == 0 {
+		return nil, errors.New("no rows to import")
+	}
+	result := &EntryImportResult{Rows: make([]EntryImportRowResult, 0, len(records))}
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		txApp := &App{DB: tx}
+		if batch != nil {
+			if err := tx.Omit("Entries").Create(batch).Error; err != nil {
+				return fmt.Errorf("failed to record import batch: %w", err)
+			}
+		}
+
+		employees := make(map[string]uint)
+		billingCodes := make(map[string]BillingCode)
+		nextStart := make(map[string]time.Time) // Where the next hours-only row on an employee's day begins
+		for _, record := range records {
+			row := EntryImportRowResult{Line: record.Line}
+			fail := func(format string, args ...interface{}) {
+				row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+			}
+
+			email := strings.ToLower(record.Email)
+			if _, ok := employees[email]; !ok && email != "" {
+				var employee Employee
+				tx.Scopes(TenantScope(tenantID)).
+					Where("user_id IN (?)", tx.Model(&User{}).Select("id").Where("tenant_id = ? AND LOWER(email) = ?", tenantID, email)).
+					Limit(1).Find(&employee)
+				employees[email] = employee.ID
+			}
+			row.EmployeeID = employees[email]
+			if row.EmployeeID == 0 {
+				fail("no employee with email %q", record.Email)
+			}
+			if _, ok := billingCodes[record.Code]; !ok && record.Code != "" {
+				var billingCode BillingCode
+				tx.Scopes(TenantScope(tenantID)).Where("code = ?", record.Code).Limit(1).Find(&billingCode)
+				billingCodes[record.Code] = billingCode
+			}
+			billingCode := billingCodes[record.Code]
+			row.BillingCodeID = billingCode.ID
+			if billingCode.ID == 0 {
+				fail("no billing code %q", record.Code)
+			}
+
+			var date time.Time
+			hasDate := record.Date != ""
+			if hasDate {
+				parsed, err := parseImportDate(record.Date)
+				if err != nil {
+					fail("%v", err)
+				}
+				date, hasDate = parsed, err == nil
+			}
+			var duration time.Duration
+			if record.Hours != "" {
+				parsed, err := parseImportHours(record.Hours)
+				if err != nil {
+					fail("%v", err)
+				} else if parsed <= 0 {
+					fail("hours must be positive")
+				}
+				duration = parsed
+			}
+			switch {
+			case record.Start != "":
+				start, _, err := parseImportTime(record.Start, date, hasDate)
+				if err != nil {
+					fail("%v", err)
+				}
+				row.Start = start
+			case hasDate:
+				dayKey := fmt.Sprintf("%d/%s", row.EmployeeID, date.Format("2006-01-02"))
+				if _, ok := nextStart[dayKey]; !ok {
+					nextStart[dayKey] = date.Add(entryImportDayStart)
+				}
+				row.Start = nextStart[dayKey]
+				nextStart[dayKey] = row.Start.Add(duration)
+			default:
+				fail("a date or start time is required")
+			}
+			switch {
+			case record.End != "":
+				end, timeOfDay, err := parseImportTime(record.End, date, hasDate)
+				if err != nil {
+					fail("%v", err)
+				} else if !row.Start.IsZero() {
+					// An end time of day before the start runs past midnight
+					if timeOfDay && !end.After(row.Start) {
+						end = end.Add(24 * time.Hour)
+					}
+					if !end.After(row.Start) {
+						fail("end is before start")
+					}
+				}
+				row.End = end
+			case duration > 0:
+				row.End = row.Start.Add(duration)
+			case record.Hours == "":
+				fail("an end time or hours is required")
+			}
+
+			if len(row.Errors) == 0 {
+				entry := Entry{
+					TenantID:      tenantID,
+					ProjectID:     billingCode.ProjectID,
+					BillingCodeID: billingCode.ID,
+					EmployeeID:    row.EmployeeID,
+					Start:         row.Start,
+					End:           row.End,
+					Notes:         record.Notes,
+					State:         EntryStateUnaffiliated.String(),
+				}
+				if batch != nil {
+					entry.ImportBatchID = &batch.ID
+				}
+				txApp.importEntry(&entry, &row, now)
+			}
+			if len(row.Errors) > 0 {
+				result.ErrorCount++
+			} else {
+				result.TotalHours += row.Hours
+			}
+			result.Rows = append(result.Rows, row)
+		}
+
+		if batch == nil {
+			return errDryRun
+		}
+		if result.ErrorCount > 0 {
+			return ErrEntryImportInvalid
+		}
+		batch.EntryCount = len(result.Rows)
+		batch.TotalHours = result.TotalHours
+		return tx.Omit("Entries").Save(batch).Error
+	})
+	if errors.Is(err, errDryRun) {
+		return result, nil
+	}
+	if err != nil {
+		if batch != nil {
+			batch.ID = 0
+		}
+		return result, err
+	}
+	result.Batch = batch
+	log.Printf("Imported %d entries (%.2f hours) for tenant %d as batch %d", batch.EntryCount, batch.TotalHours, tenantID, batch.ID)
+	return result, nil
+}
+
+// importEntry checks and creates one imported entry the way a manual entry is, recording any
+// problems on the row
+func (a *App) importEntry(entry *Entry, row *EntryImportRowResult, now time.Time) {
+	if err := a.CheckTimesheetOpen(entry.TenantID, entry.EmployeeID, entry.Start); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+		return
+	}
+	validation, err := a.ValidateEntry(entry, nil, now)
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+		return
+	}
+	for _, violation := range validation.Violations {
+		if violation.Mode == EntryRuleBlock {
+			row.Errors = append(row.Errors, violation.Message)
+		}
+	}
+	row.Warnings = validation.Warnings()
+	if len(row.Errors) > 0 {
+		return
+	}
+	if err := a.DB.Create(entry).Error; err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("failed to create entry: %v", err))
+		return
+	}
+	if err := a.AssociateEntry(entry, entry.ProjectID); err != nil {
+		row.Warnings = append(row.Warnings, fmt.Sprintf("left unaffiliated: %v", err))
+	}
+	row.Hours = entry.Duration().Hours()
+	row.Fee = entry.Fee
+	row.State = entry.State
+}
+
+// ListEntryImportBatches returns the tenant's imports, newest first
+func (a *App) ListEntryImportBatches(tenantID uint) ([]EntryImportBatch, error) {
+	var batches []EntryImportBatch
+	if err := a.DB.Scopes(TenantScope(tenantID)).Order("created_at desc").Find(&batches).Error; err != nil {
+		return nil, fmt.Errorf("failed to load entry imports: %w", err)
+	}
+	return batches, nil
+}
+
+// UndoEntryImport removes the entries an import created. It's refused once any of them has moved past
+// a draft invoice, or when one falls in a submitted timesheet.
+func (a *App) UndoEntryImport(tenantID, batchID uint, now time.Time) (*EntryImportBatch, error) {
+	var batch EntryImportBatch
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&batch, batchID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load import %d: %w", batchID, err)
+	}
+	if batch.UndoneAt != nil {
+		return nil, ErrEntryImportUndone
+	}
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		var entries []Entry
+		if err := tx.Where("import_batch_id = ?", batch.ID).Find(&entries).Error; err != nil {
+			return fmt.Errorf("failed to load imported entries: %w", err)
+		}
+		txApp := &App{DB: tx}
+		invoiceIDs := make(map[uint]bool)
+		for _, entry := range entries {
+			if entry.State != EntryStateUnaffiliated.String() && entry.State != EntryStateDraft.String() {
+				return fmt.Errorf("%w: entry %d is %s", ErrEntryImportLocked, entry.ID, entry.State)
+			}
+			if err := txApp.CheckTimesheetOpen(tenantID, entry.EmployeeID, entry.Start); err != nil {
+				return fmt.Errorf("%w: entry %d", err, entry.ID)
+			}
+			if entry.InvoiceID != nil {
+				invoiceIDs[*entry.InvoiceID] = true
+			}
+		}
+		if err := tx.Where("import_batch_id = ?", batch.ID).Delete(&entries).Error; err != nil {
+			return fmt.Errorf("failed to remove imported entries: %w", err)
+		}
+		// The draft invoices the entries were associated with drop their hours and fees
+		for invoiceID := range invoiceIDs {
+			var invoice Invoice
+			if err := tx.First(&invoice, invoiceID).Error; err != nil {
+				return fmt.Errorf("failed to load invoice %d: %w", invoiceID, err)
+			}
+			txApp.UpdateInvoiceTotals(&invoice)
+		}
+		batch.UndoneAt = &now
+		return tx.Omit("Entries").Save(&batch).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Undid entry import %d, removing %d entries", batch.ID, batch.EntryCount)
+	return &batch, nil
+}
@@ -0,0 +1,116 @@
+package cronos
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEntryImport tests that a dry run reports per-row errors without saving, that a commit is all or
+// nothing and goes through AssociateEntry, and that an import can be undone until it's approved
+func TestEntryImport(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "entry-import", Name: "Import Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	user := User{TenantID: tenant.ID, Email: "riley@example.com", Role: UserRoleStaff.String()}
+	db.Create(&user)
+	employee := Employee{TenantID: tenant.ID, FirstName: "Riley", UserID: user.ID}
+	db.Create(&employee)
+	account := Account{TenantID: tenant.ID, Name: "Import Client", Type: AccountTypeClient.String(), BillingFrequency: BillingFrequencyMonthly.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Import Project", AccountID: account.ID,
+		ActiveStart: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ActiveEnd: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 100}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Build", Code: "BLD", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15}
+	db.Create(&code)
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+
+	// A Harvest-style export with hours but no start times
+	csv := "Email,Code,Date,Hours,Notes\n" +
+		"Riley@example.com,BLD,03/10/2025,1.5,Schema work\n" +
+		"riley@example.com,BLD,03/10/2025,1:30,Migrations\n" +
+		"nobody@example.com,OPS,03/11/2025,2,Unknown\n"
+	mapping := EntryImportMapping{EmailCol: 0, CodeCol: 1, DateCol: 2, StartCol: -1, EndCol: -1, HoursCol: 3, NotesCol: 4, HasHeader: true, DateFormat: "MM/DD/YYYY"}
+	records, err := ParseEntryImportCSV([]byte(csv), mapping)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d: %v", len(records), err)
+	}
+
+	preview, err := app.PreviewEntryImport(tenant.ID, records, now)
+	if err != nil {
+		t.Fatalf("Failed to preview import: %v", err)
+	}
+	if preview.ErrorCount != 1 || len(preview.Rows[2].Errors) != 2 || !strings.Contains(preview.Rows[2].Errors[0], "nobody@example.com") {
+		t.Errorf("Expected the unknown email and code reported on line 4, got %+v", preview.Rows[2])
+	}
+	second := preview.Rows[1]
+	if !second.Start.Equal(time.Date(2025, 3, 10, 10, 30, 0, 0, time.UTC)) || second.Hours != 1.5 || second.Fee != 15000 {
+		t.Errorf("Expected the second row to follow the first at 10:30 for 150.00, got %s %.2f hours %d", second.Start, second.Hours, second.Fee)
+	}
+	countEntries := func() int64 {
+		var count int64
+		db.Model(&Entry{}).Where("employee_id = ?", employee.ID).Count(&count)
+		return count
+	}
+	if countEntries() != 0 {
+		t.Errorf("Expected a dry run to save nothing")
+	}
+
+	// Committing with an error saves nothing at all
+	if _, err := app.CommitEntryImport(tenant.ID, records, "harvest.csv", &user.ID, now); !errors.Is(err, ErrEntryImportInvalid) {
+		t.Errorf("Expected the import to be refused, got %v", err)
+	}
+	var batches int64
+	db.Model(&EntryImportBatch{}).Count(&batches)
+	if countEntries() != 0 || batches != 0 {
+		t.Errorf("Expected no entries or batch after a failed import, got %d and %d", countEntries(), batches)
+	}
+
+	result, err := app.CommitEntryImport(tenant.ID, records[:2], "harvest.csv", &user.ID, now)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if result.Batch == nil || result.Batch.EntryCount != 2 || result.Batch.TotalHours != 3 || result.Rows[0].State != EntryStateDraft.String() {
+		t.Fatalf("Expected 2 entries totalling 3 hours on a draft invoice, got %+v", result)
+	}
+
+	// JSON rows with times of day, one running past midnight
+	jsonRecords, err := ParseEntryImportJSON([]byte(`[{"email":"riley@example.com","code":"BLD","date":"2025-03-12","start":"22:00","end":"01:00","notes":"Cutover"}]`))
+	if err != nil {
+		t.Fatalf("Failed to parse JSON import: %v", err)
+	}
+	overnight, err := app.CommitEntryImport(tenant.ID, jsonRecords, "json", nil, now)
+	if err != nil || overnight.Rows[0].Hours != 3 {
+		t.Fatalf("Expected a 3 hour overnight entry, got %+v: %v", overnight, err)
+	}
+
+	// Undo is refused once an entry is approved
+	var imported Entry
+	db.Where("import_batch_id = ?", result.Batch.ID).First(&imported)
+	db.Model(&imported).Update("state", EntryStateApproved.String())
+	if _, err := app.UndoEntryImport(tenant.ID, result.Batch.ID, now); !errors.Is(err, ErrEntryImportLocked) {
+		t.Errorf("Expected undoing an approved import to be refused, got %v", err)
+	}
+	db.Model(&imported).Update("state", EntryStateDraft.String())
+	undone, err := app.UndoEntryImport(tenant.ID, result.Batch.ID, now)
+	if err != nil || undone.UndoneAt == nil {
+		t.Fatalf("Failed to undo import: %v", err)
+	}
+	if countEntries() != 1 {
+		t.Errorf("Expected only the overnight entry left, got %d", countEntries())
+	}
+	var draft Invoice
+	db.First(&draft, *imported.InvoiceID)
+	if draft.TotalHours != 3 {
+		t.Errorf("Expected the draft invoice to keep only the overnight hours, got %.2f", draft.TotalHours)
+	}
+	if _, err := app.UndoEntryImport(tenant.ID, result.Batch.ID, now); !errors.Is(err, ErrEntryImportUndone) {
+		t.Errorf("Expected a second undo to be refused, got %v", err)
+	}
+}
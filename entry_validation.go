@@ -0,0 +1,311 @@
+package cronos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrEntryBlocked = errors.New("entry breaks a validation rule")
+
+// Entry rules either warn, letting the entry through with a warning, or block it. "off" disables a
+// rule for the tenant.
+const EntryRuleOff = "off"
+const EntryRuleWarn = "warn"
+const EntryRuleBlock = "block"
+
+// EntryRuleSetting configures one rule for a tenant. Limit is the rule's threshold: hours for
+// daily_limit and days for future_date and lock.
+type EntryRuleSetting struct {
+	Mode  string  `json:"mode"`
+	Limit float64 `json:"limit,omitempty"`
+}
+
+// EntryCheck is what a rule sees: the entry as it would be saved, the stored entry when it's being
+// changed or deleted, and the billing code
+type EntryCheck struct {
+	DB          *gorm.DB
+	Entry       *Entry
+	Previous    *Entry // nil for new entries
+	Deleting    bool
+	BillingCode BillingCode
+	Setting     EntryRuleSetting
+	Now         time.Time
+}
+
+// EntryRule is one validation rule. Check returns a message describing the problem, or "" when the
+// entry passes.
+type EntryRule interface {
+	Name() string
+	Default() EntryRuleSetting
+	Check(check *EntryCheck) string
+}
+
+// EntryViolation is a rule an entry broke and whether it blocks the entry
+type EntryViolation struct {
+	Rule    string `json:"rule"`
+	Mode    string `json:"mode"`
+	Message string `json:"message"`
+}
+
+// EntryValidation is the outcome of checking an entry against the tenant's rules
+type EntryValidation struct {
+	Violations []EntryViolation `json:"violations"`
+}
+
+// Blocked reports whether any violated rule blocks the entry
+func (v EntryValidation) Blocked() bool {
+	for _, violation := range v.Violations {
+		if violation.Mode == EntryRuleBlock {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings returns the messages of the rules that only warn
+func (v EntryValidation) Warnings() []string {
+	var warnings []string
+	for _, violation := range v.Violations {
+		if violation.Mode == EntryRuleWarn {
+			warnings = append(warnings, violation.Message)
+		}
+	}
+	return warnings
+}
+
+// Err returns ErrEntryBlocked with the blocking messages, or nil when nothing blocks the entry
+func (v EntryValidation) Err() error {
+	var messages []string
+	for _, violation := range v.Violations {
+		if violation.Mode == EntryRuleBlock {
+			messages = append(messages, violation.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrEntryBlocked, strings.Join(messages, "; "))
+}
+
+var entryRules = []EntryRule{
+	overlapRule{},
+	dailyLimitRule{},
+	futureDateRule{},
+	requiredNotesRule{},
+	lockRule{},
+	budgetCapRule{},
+}
+
+// RegisterEntryRule adds a rule that every entry is checked against. A rule registered under an
+// existing name replaces it.
+func RegisterEntryRule(rule EntryRule) {
+	for i := range entryRules {
+		if entryRules[i].Name() == rule.Name() {
+			entryRules[i] = rule
+			return
+		}
+	}
+	entryRules = append(entryRules, rule)
+}
+
+// EntryRuleSettings reads the "entry_rules" tenant setting, filling in each rule's default
+func EntryRuleSettings(tenant *Tenant) map[string]EntryRuleSetting {
+	var settings struct {
+		EntryRules map[string]EntryRuleSetting `json:"entry_rules"`
+	}
+	if len(tenant.Settings) > 0 {
+		if err := json.Unmarshal(tenant.Settings, &settings); err != nil {
+			log.Printf("Warning: invalid settings for tenant %d, using default entry rules: %v", tenant.ID, err)
+		}
+	}
+	resolved := make(map[string]EntryRuleSetting, len(entryRules))
+	for _, rule := range entryRules {
+		setting := rule.Default()
+		if configured, ok := settings.EntryRules[rule.Name()]; ok {
+			if configured.Mode != "" {
+				setting.Mode = configured.Mode
+			}
+			if configured.Limit > 0 {
+				setting.Limit = configured.Limit
+			}
+		}
+		resolved[rule.Name()] = setting
+	}
+	return resolved
+}
+
+// ValidateEntry checks an entry against the tenant's rules before it's created or changed. Pass the
+// stored entry as previous when changing one. Blocking violations are returned by Err on the result.
+func (a *App) ValidateEntry(entry *Entry, previous *Entry, now time.Time) (EntryValidation, error) {
+	return a.validateEntry(entry, previous, false, now)
+}
+
+// ValidateEntryDelete checks that an entry can be removed. Only rules about the entry's age apply.
+func (a *App) ValidateEntryDelete(entry *Entry, now time.Time) (EntryValidation, error) {
+	return a.validateEntry(entry, entry, true, now)
+}
+
+func (a *App) validateEntry(entry *Entry, previous *Entry, deleting bool, now time.Time) (EntryValidation, error) {
+	var validation EntryValidation
+	var tenant Tenant
+	if err := a.DB.Limit(1).Find(&tenant, entry.TenantID).Error; err != nil {
+		return validation, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	check := EntryCheck{DB: a.DB, Entry: entry, Previous: previous, Deleting: deleting, Now: now}
+	a.DB.Limit(1).Find(&check.BillingCode, entry.BillingCodeID)
+
+	settings := EntryRuleSettings(&tenant)
+	for _, rule := range entryRules {
+		check.Setting = settings[rule.Name()]
+		if check.Setting.Mode != EntryRuleWarn && check.Setting.Mode != EntryRuleBlock {
+			continue
+		}
+		if message := rule.Check(&check); message != "" {
+			validation.Violations = append(validation.Violations, EntryViolation{Rule: rule.Name(), Mode: check.Setting.Mode, Message: message})
+		}
+	}
+	return validation, nil
+}
+
+// overlapRule stops an employee logging two entries over the same time
+type overlapRule struct{}
+
+func (overlapRule) Name() string              { return "overlap" }
+func (overlapRule) Default() EntryRuleSetting { return EntryRuleSetting{Mode: EntryRuleWarn} }
+func (overlapRule) Check(check *EntryCheck) string {
+	if check.Deleting {
+		return ""
+	}
+	e := check.Entry
+	var other Entry
+	check.DB.Where("employee_id = ? AND id != ? AND state != ? AND start < ? AND \"end\" > ?",
+		e.EmployeeID, e.ID, EntryStateVoid.String(), e.End, e.Start).Order("start").Limit(1).Find(&other)
+	if other.ID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("overlaps another entry from %s to %s", other.Start.Format("Jan 2 15:04"), other.End.Format("15:04"))
+}
+
+// dailyLimitRule caps the hours an employee logs in a day, 12 by default
+type dailyLimitRule struct{}
+
+func (dailyLimitRule) Name() string { return "daily_limit" }
+func (dailyLimitRule) Default() EntryRuleSetting {
+	return EntryRuleSetting{Mode: EntryRuleWarn, Limit: 12}
+}
+func (dailyLimitRule) Check(check *EntryCheck) string {
+	if check.Deleting {
+		return ""
+	}
+	e := check.Entry
+	dayStart, dayEnd := e.dayBounds()
+	var others []Entry
+	check.DB.Select("start", "end").
+		Where("employee_id = ? AND id != ? AND state != ? AND start >= ? AND start < ?", e.EmployeeID, e.ID, EntryStateVoid.String(), dayStart, dayEnd).
+		Find(&others)
+	hours := e.Duration().Hours()
+	for _, other := range others {
+		hours += other.Duration().Hours()
+	}
+	if hours <= check.Setting.Limit {
+		return ""
+	}
+	return fmt.Sprintf("%.2f hours logged on %s is over the daily limit of %.2f", hours, dayStart.Format("Jan 2"), check.Setting.Limit)
+}
+
+// futureDateRule stops entries being logged too far ahead, 7 days by default
+type futureDateRule struct{}
+
+func (futureDateRule) Name() string { return "future_date" }
+func (futureDateRule) Default() EntryRuleSetting {
+	return EntryRuleSetting{Mode: EntryRuleWarn, Limit: 7}
+}
+func (futureDateRule) Check(check *EntryCheck) string {
+	if check.Deleting {
+		return ""
+	}
+	latest := check.Now.Add(time.Duration(check.Setting.Limit * float64(24*time.Hour)))
+	if !check.Entry.Start.After(latest) {
+		return ""
+	}
+	return fmt.Sprintf("entries can't start more than %.0f days ahead", check.Setting.Limit)
+}
+
+// requiredNotesRule requires notes on entries against billing codes that ask for them
+type requiredNotesRule struct{}
+
+func (requiredNotesRule) Name() string              { return "required_notes" }
+func (requiredNotesRule) Default() EntryRuleSetting { return EntryRuleSetting{Mode: EntryRuleBlock} }
+func (requiredNotesRule) Check(check *EntryCheck) string {
+	if check.Deleting || !check.BillingCode.NotesRequired || strings.TrimSpace(check.Entry.Notes) != "" {
+		return ""
+	}
+	return fmt.Sprintf("notes are required for %s", check.BillingCode.Code)
+}
+
+// lockRule stops entries older than the limit in days being added, changed or removed. It's off
+// until a tenant sets it up.
+type lockRule struct{}
+
+func (lockRule) Name() string              { return "lock" }
+func (lockRule) Default() EntryRuleSetting { return EntryRuleSetting{Mode: EntryRuleOff, Limit: 30} }
+func (lockRule) Check(check *EntryCheck) string {
+	cutoff := check.Now.Add(-time.Duration(check.Setting.Limit * float64(24*time.Hour)))
+	locked := check.Entry.Start.Before(cutoff)
+	if check.Previous != nil && check.Previous.Start.Before(cutoff) {
+		locked = true
+	}
+	if !locked {
+		return ""
+	}
+	return fmt.Sprintf("entries older than %.0f days are locked", check.Setting.Limit)
+}
+
+// budgetCapRule stops time being logged on a project past its hard caps: an entry can't take its hours
+// over BudgetCapHours or its fees, in the project's budget currency, over BudgetCapDollars. Entries
+// that are shortened are let through.
+// It's off until a tenant sets it up.
+type budgetCapRule struct{}
+
+func (budgetCapRule) Name() string              { return "budget_cap" }
+func (budgetCapRule) Default() EntryRuleSetting { return EntryRuleSetting{Mode: EntryRuleOff} }
+func (budgetCapRule) Check(check *EntryCheck) string {
+	e := check.Entry
+	if check.Deleting || e.ProjectID == 0 {
+		return ""
+	}
+	if p := check.Previous; p != nil && p.ProjectID == e.ProjectID && e.Duration() <= p.Duration() {
+		return ""
+	}
+	var project Project
+	check.DB.Limit(1).Find(&project, e.ProjectID)
+	if project.BudgetCapHours == 0 && project.BudgetCapDollars == 0 {
+		return ""
+	}
+	var others []Entry
+	check.DB.Select("start", "end", "fee", "billing_code_id").
+		Where("project_id = ? AND id != ? AND state != ?", e.ProjectID, e.ID, EntryStateVoid.String()).Find(&others)
+	rates, err := budgetFeeRates(check.DB, &project, entryBillingCodes(append(others, *e)), check.Now)
+	if err != nil {
+		return fmt.Sprintf("%s's budget cap can't be checked: %v", project.Name, err)
+	}
+	var hours, dollars float64
+	for _, other := range others {
+		hours += other.Duration().Hours()
+		dollars += budgetFee(float64(other.Fee)/100, other.BillingCodeID, rates)
+	}
+	if project.BudgetCapHours > 0 && hours+e.Duration().Hours() > float64(project.BudgetCapHours) {
+		return fmt.Sprintf("%s has %.2f of its %d capped hours left", project.Name, math.Max(float64(project.BudgetCapHours)-hours, 0), project.BudgetCapHours)
+	}
+	if project.BudgetCapDollars > 0 && e.BillingCodeID != 0 && dollars+budgetFee(e.GetFee(check.DB), e.BillingCodeID, rates) > float64(project.BudgetCapDollars) {
+		return fmt.Sprintf("%s has $%.2f of its $%d budget cap left", project.Name, math.Max(float64(project.BudgetCapDollars)-dollars, 0), project.BudgetCapDollars)
+	}
+	return ""
+}
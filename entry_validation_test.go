@@ -0,0 +1,112 @@
+package cronos
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// weekendRule is a custom rule used to test registering rules
+type weekendRule struct{}
+
+func (weekendRule) Name() string              { return "weekend" }
+func (weekendRule) Default() EntryRuleSetting { return EntryRuleSetting{Mode: EntryRuleBlock} }
+func (weekendRule) Check(check *EntryCheck) string {
+	if day := check.Entry.Start.Weekday(); day == time.Saturday || day == time.Sunday {
+		return "no weekend entries"
+	}
+	return ""
+}
+
+// TestEntryValidationRules tests the default rules, tenant overrides that turn warnings into blocks,
+// the lock on old entries, and registering a custom rule
+func TestEntryValidationRules(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "entry-rules", Name: "Entry Rules Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Rules Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Rules Project", AccountID: account.ID}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 100}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Dev", Code: "DEV", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15}
+	noted := BillingCode{TenantID: tenant.ID, Name: "Support", Code: "SUP", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15, NotesRequired: true}
+	db.Create(&code)
+	db.Create(&noted)
+	employee := Employee{TenantID: tenant.ID, FirstName: "Vale"}
+	db.Create(&employee)
+
+	now := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	newEntry := func(billingCode *BillingCode, start time.Time, hours int, notes string) *Entry {
+		return &Entry{TenantID: tenant.ID, ProjectID: project.ID, BillingCodeID: billingCode.ID, EmployeeID: employee.ID,
+			Start: start, End: start.Add(time.Duration(hours) * time.Hour), Notes: notes, State: EntryStateDraft.String()}
+	}
+	monday := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	existing := newEntry(&code, monday, 8, "Build")
+	if err := db.Create(existing).Error; err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+
+	// By default an overlapping entry that takes the day over 12 hours only warns
+	validation, err := app.ValidateEntry(newEntry(&code, monday.Add(6*time.Hour), 6, "More build"), nil, now)
+	if err != nil {
+		t.Fatalf("Failed to validate entry: %v", err)
+	}
+	if validation.Blocked() || len(validation.Warnings()) != 2 {
+		t.Errorf("Expected overlap and daily limit warnings, got %+v", validation.Violations)
+	}
+	validation, _ = app.ValidateEntry(newEntry(&code, now.AddDate(0, 0, 10), 1, "Planning"), nil, now)
+	if len(validation.Violations) != 1 || validation.Violations[0].Rule != "future_date" {
+		t.Errorf("Expected a future date warning, got %+v", validation.Violations)
+	}
+
+	// Required notes block by default
+	validation, _ = app.ValidateEntry(newEntry(&noted, monday.Add(9*time.Hour), 1, "  "), nil, now)
+	if err := validation.Err(); !errors.Is(err, ErrEntryBlocked) || !strings.Contains(err.Error(), "SUP") {
+		t.Errorf("Expected an entry without notes on SUP to be blocked, got %v", err)
+	}
+
+	// Changing an entry doesn't count it against itself
+	moved := *existing
+	moved.End = moved.End.Add(time.Hour)
+	if validation, _ = app.ValidateEntry(&moved, existing, now); len(validation.Violations) != 0 {
+		t.Errorf("Expected extending an entry not to overlap itself, got %+v", validation.Violations)
+	}
+
+	// The tenant blocks days over 8 hours and locks entries older than a week
+	tenant.Settings = []byte(`{"entry_rules":{"daily_limit":{"mode":"block","limit":8},"lock":{"mode":"block","limit":7},"overlap":{"mode":"off"}}}`)
+	db.Save(&tenant)
+	validation, _ = app.ValidateEntry(newEntry(&code, monday.Add(9*time.Hour), 1, "Review"), nil, now)
+	if !validation.Blocked() || validation.Violations[0].Rule != "daily_limit" {
+		t.Errorf("Expected a 9 hour day to be blocked, got %+v", validation.Violations)
+	}
+	old := newEntry(&code, now.AddDate(0, 0, -10), 1, "Old")
+	db.Create(old)
+	validation, _ = app.ValidateEntryDelete(old, now)
+	if !validation.Blocked() || validation.Violations[0].Rule != "lock" {
+		t.Errorf("Expected deleting a locked entry to be blocked, got %+v", validation.Violations)
+	}
+	movedUp := *old
+	movedUp.Start, movedUp.End = now.Add(-2*time.Hour), now.Add(-time.Hour)
+	if validation, _ = app.ValidateEntry(&movedUp, old, now); !validation.Blocked() {
+		t.Errorf("Expected moving a locked entry out of the lock to be blocked, got %+v", validation.Violations)
+	}
+
+	// A registered rule applies to every entry, and replaces a rule registered under the same name
+	saved := append([]EntryRule(nil), entryRules...)
+	defer func() { entryRules = saved }()
+	RegisterEntryRule(weekendRule{})
+	saturday := time.Date(2025, 3, 8, 9, 0, 0, 0, time.UTC)
+	if validation, _ = app.ValidateEntry(newEntry(&code, saturday, 1, "Hotfix"), nil, now); validation.Err() == nil {
+		t.Errorf("Expected the weekend rule to block a Saturday entry")
+	}
+	RegisterEntryRule(overlapRule{})
+	if len(entryRules) != len(saved)+1 {
+		t.Errorf("Expected re-registering overlap to replace it, got %d rules", len(entryRules))
+	}
+}
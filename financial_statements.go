@@ -0,0 +1,518 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var ErrInvalidStatementPeriod = errors.New("invalid statement period")
+
+// Kinds of statement line. Headings carry no amounts; totals sum the lines above them.
+const (
+	StatementLineHeading = "heading"
+	StatementLineAccount = "account"
+	StatementLineTotal   = "total"
+)
+
+// StatementColumn is one period a statement reports on. End is exclusive. Balance sheet columns have
+// no start and report balances from the first entry.
+type StatementColumn struct {
+	Label string    `json:"label"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// StatementLine is one row of a financial statement, with an amount in cents per column. Account
+// lines include the balances of their sub-accounts in the chart of accounts.
+type StatementLine struct {
+	AccountCode string  `json:"account_code,omitempty"`
+	Label       string  `json:"label"`
+	Kind        string  `json:"kind"`
+	Depth       int     `json:"depth"`
+	Amounts     []int64 `json:"amounts,omitempty"`
+}
+
+// FinancialStatement is an income statement, balance sheet or cash-flow statement built from the GL
+type FinancialStatement struct {
+	Type        string            `json:"type"`
+	Title       string            `json:"title"`
+	TenantID    uint              `json:"tenant_id"`
+	Columns     []StatementColumn `json:"columns"`
+	Lines       []StatementLine   `json:"lines"`
+	Balanced    bool              `json:"balanced"` // balance sheet: assets equal liabilities and equity; cash flow: cash reconciles
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// Line returns the first line with the given label, or nil
+func (s *FinancialStatement) Line(label string) *StatementLine {
+	for i := range s.Lines {
+		if s.Lines[i].Label == label {
+			return &s.Lines[i]
+		}
+	}
+	return nil
+}
+
+func (s *FinancialStatement) heading(label string) {
+	s.Lines = append(s.Lines, StatementLine{Label: label, Kind: StatementLineHeading})
+}
+
+func (s *FinancialStatement) total(label string, amounts []int64) {
+	s.Lines = append(s.Lines, StatementLine{Label: label, Kind: StatementLineTotal, Amounts: amounts})
+}
+
+// statementChart is the chart of accounts arranged for walking the ParentID hierarchy
+type statementChart struct {
+	accounts map[string]ChartOfAccount
+	byID     map[uint]ChartOfAccount
+	children map[uint][]ChartOfAccount
+}
+
+// loadStatementChart loads the system accounts and the tenant's own, letting the tenant's win when
+// both define a code
+func (a *App) loadStatementChart(tenantID uint) (*statementChart, error) {
+	var accounts []ChartOfAccount
+	if err := a.DB.Where("tenant_id IN ?", []uint{0, tenantID}).Order("tenant_id, account_code").Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chart of accounts: %w", err)
+	}
+
+	chart := &statementChart{
+		accounts: make(map[string]ChartOfAccount),
+		byID:     make(map[uint]ChartOfAccount),
+		children: make(map[uint][]ChartOfAccount),
+	}
+	for _, account := range accounts {
+		chart.accounts[account.AccountCode] = account
+		chart.byID[account.ID] = account
+	}
+	for _, account := range chart.accounts {
+		if account.ParentID != nil {
+			chart.children[*account.ParentID] = append(chart.children[*account.ParentID], account)
+		}
+	}
+	for _, children := range chart.children {
+		sort.Slice(children, func(i, j int) bool { return children[i].AccountCode < children[j].AccountCode })
+	}
+	return chart, nil
+}
+
+// addUnmapped lists journal accounts missing from the chart as expenses, the same place
+// UNCLASSIFIED entries land, so nothing booked drops off a statement
+func (c *statementChart) addUnmapped(balances ...map[string]int64) {
+	for _, b := range balances {
+		for code := range b {
+			if _, ok := c.accounts[code]; !ok {
+				c.accounts[code] = ChartOfAccount{AccountCode: code, AccountName: code, AccountType: "EXPENSE"}
+			}
+		}
+	}
+}
+
+func (c *statementChart) accountType(code string) string {
+	if account, ok := c.accounts[code]; ok {
+		return account.AccountType
+	}
+	return "EXPENSE"
+}
+
+// isRoot reports whether an account starts its own tree within its type
+func (c *statementChart) isRoot(account ChartOfAccount) bool {
+	if account.ParentID == nil {
+		return true
+	}
+	parent, ok := c.byID[*account.ParentID]
+	return !ok || parent.AccountType != account.AccountType
+}
+
+// under reports whether code is the given account or one of its sub-accounts
+func (c *statementChart) under(code, ancestor string) bool {
+	account, ok := c.accounts[code]
+	for depth := 0; ok && depth < 16; depth++ {
+		if account.AccountCode == ancestor {
+			return true
+		}
+		if account.ParentID == nil {
+			return false
+		}
+		account, ok = c.byID[*account.ParentID]
+	}
+	return false
+}
+
+// codes returns the chart's account codes of the given types, sorted by type then code
+func (c *statementChart) codes(accountTypes ...string) []string {
+	var codes []string
+	for _, accountType := range accountTypes {
+		var ofType []string
+		for code, account := range c.accounts {
+			if account.AccountType == accountType {
+				ofType = append(ofType, code)
+			}
+		}
+		sort.Strings(ofType)
+		codes = append(codes, ofType...)
+	}
+	return codes
+}
+
+// naturalBalance turns debits minus credits into the account's normal sign: debits for assets and
+// expenses, credits for everything else
+func naturalBalance(accountType string, net int64) int64 {
+	if accountType == "ASSET" || accountType == "EXPENSE" {
+		return net
+	}
+	return -net
+}
+
+// section appends one block of account lines for an account type, one column per balance map, and
+// returns the block's totals. Accounts with nothing in any column, and no sub-account lines, are
+// left out.
+func (c *statementChart) section(s *FinancialStatement, accountType string, balances []map[string]int64) []int64 {
+	totals := make([]int64, len(balances))
+	for _, code := range c.codes(accountType) {
+		account := c.accounts[code]
+		if !c.isRoot(account) {
+			continue
+		}
+		sub := c.walk(s, account, 1, balances)
+		for i := range totals {
+			totals[i] += sub[i]
+		}
+	}
+	return totals
+}
+
+func (c *statementChart) walk(s *FinancialStatement, account ChartOfAccount, depth int, balances []map[string]int64) []int64 {
+	index := len(s.Lines)
+	s.Lines = append(s.Lines, StatementLine{AccountCode: account.AccountCode, Label: account.AccountName, Kind: StatementLineAccount, Depth: depth})
+
+	amounts := make([]int64, len(balances))
+	for i, b := range balances {
+		amounts[i] = naturalBalance(account.AccountType, b[account.AccountCode])
+	}
+	if account.ID != 0 && depth < 16 {
+		for _, child := range c.children[account.ID] {
+			if child.AccountType != account.AccountType {
+				continue
+			}
+			sub := c.walk(s, child, depth+1, balances)
+			for i := range amounts {
+				amounts[i] += sub[i]
+			}
+		}
+	}
+
+	if len(s.Lines) == index+1 && allZero(amounts) {
+		s.Lines = s.Lines[:index]
+		return amounts
+	}
+	s.Lines[index].Amounts = amounts
+	return amounts
+}
+
+// netIncome is revenue less expenses over a set of balances
+func (c *statementChart) netIncome(balances map[string]int64) int64 {
+	var total int64
+	for code, net := range balances {
+		switch c.accountType(code) {
+		case "REVENUE", "EXPENSE":
+			total -= net
+		}
+	}
+	return total
+}
+
+func allZero(amounts []int64) bool {
+	for _, amount := range amounts {
+		if amount != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func subtract(a, b []int64) []int64 {
+	result := make([]int64, len(a))
+	for i := range a {
+		result[i] = a[i] - b[i]
+	}
+	return result
+}
+
+// accountActivity nets debits minus credits per account for lines effective in [start, end). A zero
+// start reaches back to the first entry.
+func (a *App) accountActivity(tenantID uint, start, end time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Account string
+		Net     int64
+	}
+	query := a.DB.Model(&Journal{}).Scopes(TenantScope(tenantID)).
+		Select("account, COALESCE(SUM(debit - credit), 0) AS net").
+		Where("effective_date < ?", end)
+	if !start.IsZero() {
+		query = query.Where("effective_date >= ?", start)
+	}
+	if err := query.Group("account").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to total journal activity: %w", err)
+	}
+
+	balances := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		balances[row.Account] = row.Net
+	}
+	return balances, nil
+}
+
+// periodLabel names a [start, end) period, as a month when it is exactly one
+func periodLabel(start, end time.Time) string {
+	if start.Equal(monthStart(start)) && end.Equal(start.AddDate(0, 1, 0)) {
+		return start.Format("January 2006")
+	}
+	return start.Format("Jan 2, 2006") + " - " + end.AddDate(0, 0, -1).Format("Jan 2, 2006")
+}
+
+// StatementColumns returns the [start, end) period followed by count comparison periods. Compare is
+// "previous_period" for periods of the same length immediately before, or "previous_year" for the
+// same dates in earlier years; an empty compare gives a single column.
+func StatementColumns(start, end time.Time, compare string, count int) ([]StatementColumn, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidStatementPeriod)
+	}
+	columns := []StatementColumn{{Label: periodLabel(start, end), Start: start, End: end}}
+	if compare == "" {
+		return columns, nil
+	}
+
+	for i := 1; i <= count; i++ {
+		var column StatementColumn
+		switch compare {
+		case "previous_period":
+			// Whole months step back by months so February compares with January, not late December
+			if start.Equal(monthStart(start)) && end.Equal(monthStart(end)) {
+				months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+				column.Start, column.End = start.AddDate(0, -months*i, 0), end.AddDate(0, -months*i, 0)
+			} else {
+				length := end.Sub(start)
+				column.Start, column.End = start.Add(-length*time.Duration(i)), end.Add(-length*time.Duration(i))
+			}
+		case "previous_year":
+			column.Start, column.End = start.AddDate(-i, 0, 0), end.AddDate(-i, 0, 0)
+		default:
+			return nil, fmt.Errorf("%w: unknown comparison %q", ErrInvalidStatementPeriod, compare)
+		}
+		column.Label = periodLabel(column.Start, column.End)
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// GenerateIncomeStatement reports revenue, expenses and net income for each column's period
+func (a *App) GenerateIncomeStatement(tenantID uint, columns []StatementColumn) (*FinancialStatement, error) {
+	chart, err := a.loadStatementChart(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]map[string]int64, len(columns))
+	for i, column := range columns {
+		if balances[i], err = a.accountActivity(tenantID, column.Start, column.End); err != nil {
+			return nil, err
+		}
+	}
+	chart.addUnmapped(balances...)
+
+	statement := &FinancialStatement{
+		Type:        FinancialStatementIncome.String(),
+		Title:       "Income Statement",
+		TenantID:    tenantID,
+		Columns:     columns,
+		Balanced:    true,
+		GeneratedAt: time.Now(),
+	}
+	statement.heading("Revenue")
+	revenue := chart.section(statement, "REVENUE", balances)
+	statement.total("Total Revenue", revenue)
+	statement.heading("Expenses")
+	expenses := chart.section(statement, "EXPENSE", balances)
+	statement.total("Total Expenses", expenses)
+	statement.total("Net Income", subtract(revenue, expenses))
+	return statement, nil
+}
+
+// GenerateBalanceSheet reports assets, liabilities and equity at the end of each date. Income from
+// earlier fiscal (calendar) years is rolled into retained earnings; this year's is shown separately.
+func (a *App) GenerateBalanceSheet(tenantID uint, asOf []time.Time) (*FinancialStatement, error) {
+	chart, err := a.loadStatementChart(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &FinancialStatement{
+		Type:        FinancialStatementBalanceSheet.String(),
+		Title:       "Balance Sheet",
+		TenantID:    tenantID,
+		Balanced:    true,
+		GeneratedAt: time.Now(),
+	}
+	balances := make([]map[string]int64, len(asOf))
+	retained := make([]int64, len(asOf))
+	currentYear := make([]int64, len(asOf))
+	for i, date := range asOf {
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		end := day.AddDate(0, 0, 1)
+		statement.Columns = append(statement.Columns, StatementColumn{Label: "As of " + day.Format("Jan 2, 2006"), End: end})
+
+		if balances[i], err = a.accountActivity(tenantID, time.Time{}, end); err != nil {
+			return nil, err
+		}
+		prior, err := a.accountActivity(tenantID, time.Time{}, time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			return nil, err
+		}
+		chart.addUnmapped(balances[i], prior)
+		retained[i] = chart.netIncome(prior)
+		currentYear[i] = chart.netIncome(balances[i]) - retained[i]
+	}
+
+	statement.heading("Assets")
+	assets := chart.section(statement, "ASSET", balances)
+	statement.total("Total Assets", assets)
+
+	statement.heading("Liabilities")
+	liabilities := chart.section(statement, "LIABILITY", balances)
+	statement.total("Total Liabilities", liabilities)
+
+	statement.heading("Equity")
+	equity := chart.section(statement, "EQUITY", balances)
+	statement.Lines = append(statement.Lines,
+		StatementLine{Label: "Retained Earnings", Kind: StatementLineAccount, Depth: 1, Amounts: retained},
+		StatementLine{Label: "Current Year Earnings", Kind: StatementLineAccount, Depth: 1, Amounts: currentYear},
+	)
+	liabilitiesAndEquity := make([]int64, len(asOf))
+	for i := range equity {
+		equity[i] += retained[i] + currentYear[i]
+		liabilitiesAndEquity[i] = liabilities[i] + equity[i]
+		statement.Balanced = statement.Balanced && assets[i] == liabilitiesAndEquity[i]
+	}
+	statement.total("Total Equity", equity)
+	statement.total("Total Liabilities and Equity", liabilitiesAndEquity)
+	return statement, nil
+}
+
+// GenerateCashFlowStatement reports cash flows for each column's period by the indirect method:
+// net income adjusted for changes in working capital, then investing (equipment) and financing
+// (equity) flows, reconciled to the change in cash
+func (a *App) GenerateCashFlowStatement(tenantID uint, columns []StatementColumn) (*FinancialStatement, error) {
+	chart, err := a.loadStatementChart(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	activity := make([]map[string]int64, len(columns))
+	opening := make([]map[string]int64, len(columns))
+	for i, column := range columns {
+		if activity[i], err = a.accountActivity(tenantID, column.Start, column.End); err != nil {
+			return nil, err
+		}
+		if opening[i], err = a.accountActivity(tenantID, time.Time{}, column.Start); err != nil {
+			return nil, err
+		}
+	}
+	chart.addUnmapped(activity...)
+	chart.addUnmapped(opening...)
+
+	statement := &FinancialStatement{
+		Type:        FinancialStatementCashFlow.String(),
+		Title:       "Statement of Cash Flows",
+		TenantID:    tenantID,
+		Columns:     columns,
+		Balanced:    true,
+		GeneratedAt: time.Now(),
+	}
+
+	isCash := func(code string) bool { return chart.under(code, AccountCash.String()) }
+	// flows adds a line per balance sheet account the filter picks, showing its effect on cash:
+	// a debit to any non-cash account uses cash and a credit provides it
+	flows := func(accept func(code string) bool) []int64 {
+		totals := make([]int64, len(columns))
+		for _, code := range chart.codes("ASSET", "LIABILITY", "EQUITY") {
+			if isCash(code) || !accept(code) {
+				continue
+			}
+			amounts := make([]int64, len(columns))
+			for i := range columns {
+				amounts[i] = -activity[i][code]
+				totals[i] += amounts[i]
+			}
+			if !allZero(amounts) {
+				statement.Lines = append(statement.Lines, StatementLine{AccountCode: code, Label: chart.accounts[code].AccountName, Kind: StatementLineAccount, Depth: 1, Amounts: amounts})
+			}
+		}
+		return totals
+	}
+	isInvesting := func(code string) bool { return chart.under(code, AccountEquipment.String()) }
+	isFinancing := func(code string) bool { return chart.accountType(code) == "EQUITY" }
+
+	netIncome := make([]int64, len(columns))
+	for i := range columns {
+		netIncome[i] = chart.netIncome(activity[i])
+	}
+	statement.heading("Operating Activities")
+	statement.Lines = append(statement.Lines, StatementLine{Label: "Net Income", Kind: StatementLineAccount, Depth: 1, Amounts: netIncome})
+	operating := flows(func(code string) bool { return !isInvesting(code) && !isFinancing(code) })
+	for i := range operating {
+		operating[i] += netIncome[i]
+	}
+	statement.total("Net Cash from Operating Activities", operating)
+
+	statement.heading("Investing Activities")
+	investing := flows(isInvesting)
+	statement.total("Net Cash from Investing Activities", investing)
+
+	statement.heading("Financing Activities")
+	financing := flows(isFinancing)
+	statement.total("Net Cash from Financing Activities", financing)
+
+	change := make([]int64, len(columns))
+	beginning := make([]int64, len(columns))
+	ending := make([]int64, len(columns))
+	for i := range columns {
+		change[i] = operating[i] + investing[i] + financing[i]
+		for code, net := range opening[i] {
+			if isCash(code) {
+				beginning[i] += net
+			}
+		}
+		ending[i] = beginning[i]
+		for code, net := range activity[i] {
+			if isCash(code) {
+				ending[i] += net
+			}
+		}
+		statement.Balanced = statement.Balanced && beginning[i]+change[i] == ending[i]
+	}
+	statement.total("Net Change in Cash", change)
+	statement.Lines = append(statement.Lines, StatementLine{Label: "Cash at Beginning of Period", Kind: StatementLineAccount, Amounts: beginning})
+	statement.total("Cash at End of Period", ending)
+	return statement, nil
+}
+
+// BalanceSheetDates returns asOf followed by count comparison dates: month ends before it for
+// "previous_period", or the same date in earlier years for "previous_year"
+func BalanceSheetDates(asOf time.Time, compare string, count int) ([]time.Time, error) {
+	dates := []time.Time{asOf}
+	if compare == "" {
+		return dates, nil
+	}
+	for i := 1; i <= count; i++ {
+		switch compare {
+		case "previous_period":
+			dates = append(dates, monthStart(asOf).AddDate(0, 1-i, -1))
+		case "previous_year":
+			dates = append(dates, asOf.AddDate(-i, 0, 0))
+		default:
+			return nil, fmt.Errorf("%w: unknown comparison %q", ErrInvalidStatementPeriod, compare)
+		}
+	}
+	return dates, nil
+}
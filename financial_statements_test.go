@@ -0,0 +1,103 @@
+package cronos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFinancialStatements tests that statements follow the chart's types and hierarchy, that the
+// balance sheet rolls prior-year income into retained earnings, and that cash flows reconcile
+func TestFinancialStatements(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	if err := app.SeedSystemAccounts(); err != nil {
+		t.Fatalf("Failed to seed chart of accounts: %v", err)
+	}
+
+	tenant := Tenant{Slug: "statements", Name: "Statements Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	saas, _ := app.GetAccountForCode(AccountOperatingExpensesSaaS.String())
+	hosting := ChartOfAccount{TenantID: tenant.ID, AccountCode: "SAAS_HOSTING", AccountName: "Hosting", AccountType: "EXPENSE", ParentID: &saas.ID, IsActive: true}
+	db.Create(&hosting)
+
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	post := func(date time.Time, debit, credit string, amount int64) {
+		t.Helper()
+		err := app.PostJournalTransaction(&JournalTransaction{TenantID: tenant.ID, EffectiveDate: date, SourceType: JournalSourceManual.String(),
+			Lines: []Journal{{Account: debit, Debit: amount}, {Account: credit, Credit: amount}}})
+		if err != nil {
+			t.Fatalf("Failed to post %s/%s: %v", debit, credit, err)
+		}
+	}
+	post(day(2024, 6, 1), AccountCash.String(), AccountEquityOwnership.String(), 100000)
+	post(day(2024, 9, 1), AccountAccountsReceivable.String(), AccountRevenue.String(), 50000)
+	post(day(2025, 1, 10), AccountCash.String(), AccountAccountsReceivable.String(), 50000)
+	post(day(2025, 1, 15), AccountPayrollExpense.String(), AccountAccruedPayroll.String(), 20000)
+	post(day(2025, 1, 20), AccountEquipment.String(), AccountCash.String(), 30000)
+	post(day(2025, 1, 25), "SAAS_HOSTING", AccountCash.String(), 5000)
+
+	columns, err := StatementColumns(day(2025, 1, 1), day(2025, 2, 1), "previous_period", 1)
+	if err != nil {
+		t.Fatalf("Failed to build columns: %v", err)
+	}
+	if len(columns) != 2 || columns[1].Label != "December 2024" {
+		t.Fatalf("Expected January compared with December, got %+v", columns)
+	}
+	income, err := app.GenerateIncomeStatement(tenant.ID, columns)
+	if err != nil {
+		t.Fatalf("Failed to generate income statement: %v", err)
+	}
+	if got := income.Line("Net Income").Amounts; got[0] != -25000 || got[1] != 0 {
+		t.Errorf("Expected net income of -250.00 and 0, got %v", got)
+	}
+	parent, child := income.Line("Operating Expenses - SaaS"), income.Line("Hosting")
+	if parent == nil || child == nil || parent.Amounts[0] != 5000 || child.Depth != parent.Depth+1 {
+		t.Errorf("Expected hosting nested under SaaS with the parent carrying its total, got %+v and %+v", parent, child)
+	}
+
+	balance, err := app.GenerateBalanceSheet(tenant.ID, []time.Time{day(2025, 1, 31)})
+	if err != nil {
+		t.Fatalf("Failed to generate balance sheet: %v", err)
+	}
+	if !balance.Balanced {
+		t.Errorf("Expected the balance sheet to balance")
+	}
+	if got := balance.Line("Retained Earnings").Amounts[0]; got != 50000 {
+		t.Errorf("Expected last year's income in retained earnings, got %d", got)
+	}
+	if got := balance.Line("Total Assets").Amounts[0]; got != 145000 {
+		t.Errorf("Expected total assets of 1450.00, got %d", got)
+	}
+
+	cashFlow, err := app.GenerateCashFlowStatement(tenant.ID, columns[:1])
+	if err != nil {
+		t.Fatalf("Failed to generate cash flow statement: %v", err)
+	}
+	if !cashFlow.Balanced {
+		t.Errorf("Expected cash flows to reconcile to the change in cash")
+	}
+	for label, want := range map[string]int64{
+		"Net Cash from Operating Activities": 45000,
+		"Net Cash from Investing Activities": -30000,
+		"Cash at Beginning of Period":        100000,
+		"Cash at End of Period":              115000,
+	} {
+		if got := cashFlow.Line(label).Amounts[0]; got != want {
+			t.Errorf("Expected %s of %d, got %d", label, want, got)
+		}
+	}
+
+	csvData, err := StatementCSV(income)
+	if err != nil || !strings.Contains(string(csvData), "Net Income,-250.00,0.00") {
+		t.Errorf("Expected net income in the CSV, got %q: %v", csvData, err)
+	}
+	if pdf := app.GenerateStatementPDF(balance); !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Errorf("Expected a PDF document")
+	}
+}
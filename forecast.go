@@ -0,0 +1,366 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidScenario = errors.New("invalid staffing scenario")
+var ErrScenarioNotDraft = errors.New("only draft scenarios can be changed")
+
+const maxForecastWeeks = 52
+
+// ForecastWeek is the projected load and revenue of one employee, or of everyone, in a Sunday-start week
+type ForecastWeek struct {
+	WeekStart       string  `json:"week_start"`
+	CapacityHours   float64 `json:"capacity_hours"` // Weekly capacity less leave and holidays
+	LeaveHours      float64 `json:"leave_hours"`
+	CommittedHours  float64 `json:"committed_hours"` // Staffing assignments, less the share of the week off
+	ScenarioHours   float64 `json:"scenario_hours"`  // Scenario assignments weighted by probability
+	Utilization     float64 `json:"utilization"`     // Committed and scenario hours as a percentage of capacity
+	Revenue         float64 `json:"revenue"`
+	ScenarioRevenue float64 `json:"scenario_revenue"` // Weighted by probability
+}
+
+// EmployeeForecast is an employee's projected weeks
+type EmployeeForecast struct {
+	EmployeeID uint           `json:"employee_id"`
+	Name       string         `json:"name"`
+	Weeks      []ForecastWeek `json:"weeks"`
+}
+
+// StaffingForecast projects utilization and revenue over the coming weeks from staffing commitments,
+// rates and known leave, optionally layering in draft scenarios
+type StaffingForecast struct {
+	Start           string             `json:"start"`
+	Weeks           int                `json:"weeks"`
+	ScenarioIDs     []uint             `json:"scenario_ids"`
+	Employees       []EmployeeForecast `json:"employees"`
+	Totals          []ForecastWeek     `json:"totals"`
+	Revenue         float64            `json:"revenue"`
+	ScenarioRevenue float64            `json:"scenario_revenue"`
+}
+
+// ScenarioComparison summarizes the forecast with one scenario added, for comparing scenarios side by
+// side. The first comparison is always the committed baseline, with a ScenarioID of 0.
+type ScenarioComparison struct {
+	ScenarioID         uint           `json:"scenario_id"`
+	Name               string         `json:"name"`
+	Probability        float64        `json:"probability"`
+	Totals             []ForecastWeek `json:"totals"`
+	Revenue            float64        `json:"revenue"`
+	ScenarioRevenue    float64        `json:"scenario_revenue"`
+	AverageUtilization float64        `json:"average_utilization"`
+}
+
+// ValidateStaffingScenario checks a scenario before it's saved
+func ValidateStaffingScenario(scenario *StaffingScenario) error {
+	if strings.TrimSpace(scenario.Name) == "" {
+		return fmt.Errorf("%w: a scenario needs a name", ErrInvalidScenario)
+	}
+	if scenario.Probability < 0 || scenario.Probability > 1 {
+		return fmt.Errorf("%w: probability must be between 0 and 1", ErrInvalidScenario)
+	}
+	if scenario.State == "" {
+		scenario.State = ScenarioStateDraft.String()
+	}
+	return nil
+}
+
+// ValidateScenarioAssignment checks a scenario assignment before it's saved
+func ValidateScenarioAssignment(assignment *ScenarioAssignment) error {
+	if assignment.EmployeeID == 0 {
+		return fmt.Errorf("%w: an assignment needs an employee", ErrInvalidScenario)
+	}
+	if assignment.StartDate.IsZero() || assignment.EndDate.Before(assignment.StartDate) {
+		return fmt.Errorf("%w: an assignment needs a start date on or before its end date", ErrInvalidScenario)
+	}
+	if assignment.Commitment <= 0 && assignment.CommitmentSchedule == "" {
+		return fmt.Errorf("%w: an assignment needs a weekly commitment", ErrInvalidScenario)
+	}
+	if assignment.Rate < 0 {
+		return fmt.Errorf("%w: the rate can't be negative", ErrInvalidScenario)
+	}
+	return nil
+}
+
+// DraftScenario loads a scenario that can still be changed, with its assignments
+func (a *App) DraftScenario(tenantID, scenarioID uint) (*StaffingScenario, error) {
+	var scenario StaffingScenario
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Assignments").First(&scenario, scenarioID).Error; err != nil {
+		return nil, fmt.Errorf("scenario %d not found: %w", scenarioID, err)
+	}
+	if scenario.State != ScenarioStateDraft.String() {
+		return nil, ErrScenarioNotDraft
+	}
+	return &scenario, nil
+}
+
+// staffingAssignment is the staffing assignment a scenario assignment stands for
+func (sa *ScenarioAssignment) staffingAssignment() StaffingAssignment {
+	assignment := StaffingAssignment{
+		TenantID:           sa.TenantID,
+		EmployeeID:         sa.EmployeeID,
+		Commitment:         sa.Commitment,
+		StartDate:          sa.StartDate,
+		EndDate:            sa.EndDate,
+		CommitmentSchedule: sa.CommitmentSchedule,
+	}
+	if sa.ProjectID != nil {
+		assignment.ProjectID = *sa.ProjectID
+	}
+	return assignment
+}
+
+// weekCommitment is the assignment's commitment in the Sunday-start week, or 0 outside its dates
+func weekCommitment(assignment *StaffingAssignment, week time.Time) int {
+	if week.AddDate(0, 0, 6).Before(leaveDay(assignment.StartDate)) || week.After(leaveDay(assignment.EndDate)) {
+		return 0
+	}
+	return assignment.GetCommitmentForWeek(week)
+}
+
+// forecastRate is the hourly rate an employee's time on a project is expected to bill at in a week:
+// the rate card line in effect, or else the rate on the project's first billing code. Fixed-fee
+// projects bill milestones rather than hours, so their rate is 0.
+func (a *App) forecastRate(project *Project, employeeID uint, week time.Time, codeRates map[uint]float64) float64 {
+	if project == nil || project.ID == 0 || project.BillingMethod == BillingMethodFixedFee.String() {
+		return 0
+	}
+	if line := (&Entry{EmployeeID: employeeID, Start: week}).rateCardLine(a.DB, project); line != nil {
+		return line.Amount
+	}
+	if rate, ok := codeRates[project.ID]; ok {
+		return rate
+	}
+	var billingCode BillingCode
+	a.DB.Preload("Rate").Where("project_id = ?", project.ID).Order("id").Limit(1).Find(&billingCode)
+	codeRates[project.ID] = billingCode.Rate.Amount
+	return billingCode.Rate.Amount
+}
+
+// ForecastStaffing projects each active employee's capacity, committed hours and revenue for the given
+// number of Sunday-start weeks from the week containing from. Leave and holidays come off both capacity
+// and commitments. The draft scenarios listed are added in, weighted by their probability.
+func (a *App) ForecastStaffing(tenantID uint, from time.Time, weeks int, scenarioIDs []uint) (*StaffingForecast, error) {
+	if weeks <= 0 || weeks > maxForecastWeeks {
+		return nil, fmt.Errorf("%w: forecasts cover 1 to %d weeks", ErrInvalidScenario, maxForecastWeeks)
+	}
+	first := CapacityWeekStart(from)
+	last := first.AddDate(0, 0, 7*weeks-1)
+
+	var employees []Employee
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("is_active = ?", true).Order("first_name, last_name").
+		Find(&employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to load employees: %w", err)
+	}
+	var assignments []StaffingAssignment
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Employee").Preload("Project").
+		Where("start_date <= ? AND end_date >= ?", last.AddDate(0, 0, 1), first).
+		Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load staffing assignments: %w", err)
+	}
+	var scenarios []StaffingScenario
+	if len(scenarioIDs) > 0 {
+		if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Assignments.Employee").Preload("Assignments.Project").
+			Where("id IN ? AND state = ?", scenarioIDs, ScenarioStateDraft.String()).
+			Find(&scenarios).Error; err != nil {
+			return nil, fmt.Errorf("failed to load scenarios: %w", err)
+		}
+	}
+	timeOff, err := a.TimeOffByDay(tenantID, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := &StaffingForecast{Start: first.Format("2006-01-02"), Weeks: weeks, ScenarioIDs: scenarioIDs}
+	index := make(map[uint]int)
+	addEmployee := func(employee *Employee) *EmployeeForecast {
+		if i, ok := index[employee.ID]; ok {
+			return &forecast.Employees[i]
+		}
+		row := EmployeeForecast{EmployeeID: employee.ID, Name: strings.TrimSpace(employee.FirstName + " " + employee.LastName),
+			Weeks: make([]ForecastWeek, weeks)}
+		for w := range row.Weeks {
+			week := first.AddDate(0, 0, 7*w)
+			leave := TimeOffInWeek(timeOff[employee.ID], week)
+			row.Weeks[w] = ForecastWeek{
+				WeekStart:     week.Format("2006-01-02"),
+				LeaveHours:    leave,
+				CapacityHours: max0(DailyCapacity(employee)*5 - leave),
+			}
+		}
+		index[employee.ID] = len(forecast.Employees)
+		forecast.Employees = append(forecast.Employees, row)
+		return &forecast.Employees[len(forecast.Employees)-1]
+	}
+	for i := range employees {
+		addEmployee(&employees[i])
+	}
+
+	codeRates := make(map[uint]float64)
+	// addAssignment adds an assignment's hours and revenue to its employee's weeks, weighted by probability
+	addAssignment := func(assignment *StaffingAssignment, employee *Employee, project *Project, rate, probability float64, scenario bool) {
+		if employee.ID == 0 {
+			return
+		}
+		row := addEmployee(employee)
+		for w := range row.Weeks {
+			week := first.AddDate(0, 0, 7*w)
+			commitment := weekCommitment(assignment, week)
+			if commitment <= 0 {
+				continue
+			}
+			hours := AvailableCommitment(commitment, employee, row.Weeks[w].LeaveHours)
+			weekRate := rate
+			if weekRate == 0 {
+				weekRate = a.forecastRate(project, employee.ID, week, codeRates)
+			}
+			if scenario {
+				row.Weeks[w].ScenarioHours += hours * probability
+				row.Weeks[w].ScenarioRevenue += hours * weekRate * probability
+			} else {
+				row.Weeks[w].CommittedHours += hours
+				row.Weeks[w].Revenue += hours * weekRate
+			}
+		}
+	}
+	for i := range assignments {
+		addAssignment(&assignments[i], &assignments[i].Employee, &assignments[i].Project, 0, 1, false)
+	}
+	for _, scenario := range scenarios {
+		for i := range scenario.Assignments {
+			sa := &scenario.Assignments[i]
+			assignment := sa.staffingAssignment()
+			addAssignment(&assignment, &sa.Employee, sa.Project, sa.Rate, scenario.Probability, true)
+		}
+	}
+
+	forecast.Totals = make([]ForecastWeek, weeks)
+	for w := range forecast.Totals {
+		forecast.Totals[w].WeekStart = first.AddDate(0, 0, 7*w).Format("2006-01-02")
+	}
+	for e := range forecast.Employees {
+		for w := range forecast.Employees[e].Weeks {
+			week := &forecast.Employees[e].Weeks[w]
+			week.Utilization = utilization(week.CommittedHours+week.ScenarioHours, week.CapacityHours)
+			total := &forecast.Totals[w]
+			total.CapacityHours += week.CapacityHours
+			total.LeaveHours += week.LeaveHours
+			total.CommittedHours += week.CommittedHours
+			total.ScenarioHours += week.ScenarioHours
+			total.Revenue += week.Revenue
+			total.ScenarioRevenue += week.ScenarioRevenue
+		}
+	}
+	for w := range forecast.Totals {
+		total := &forecast.Totals[w]
+		total.Utilization = utilization(total.CommittedHours+total.ScenarioHours, total.CapacityHours)
+		forecast.Revenue += total.Revenue
+		forecast.ScenarioRevenue += total.ScenarioRevenue
+	}
+	return forecast, nil
+}
+
+// max0 clamps negative hours to zero
+func max0(hours float64) float64 {
+	if hours < 0 {
+		return 0
+	}
+	return hours
+}
+
+// utilization is hours as a percentage of capacity, 0 when there is no capacity
+func utilization(hours, capacity float64) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	return hours / capacity * 100
+}
+
+// CompareScenarios forecasts the committed baseline and then the baseline with each scenario added on
+// its own, so scenarios can be compared side by side
+func (a *App) CompareScenarios(tenantID uint, from time.Time, weeks int, scenarioIDs []uint) ([]ScenarioComparison, error) {
+	var scenarios []StaffingScenario
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("id IN ? AND state = ?", scenarioIDs, ScenarioStateDraft.String()).
+		Order("id").Find(&scenarios).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scenarios: %w", err)
+	}
+	comparisons := make([]ScenarioComparison, 0, len(scenarios)+1)
+	baseline := ScenarioComparison{Name: "Committed", Probability: 1}
+	scenarios = append([]StaffingScenario{{}}, scenarios...)
+	for _, scenario := range scenarios {
+		comparison := baseline
+		var ids []uint
+		if scenario.ID != 0 {
+			comparison = ScenarioComparison{ScenarioID: scenario.ID, Name: scenario.Name, Probability: scenario.Probability}
+			ids = []uint{scenario.ID}
+		}
+		forecast, err := a.ForecastStaffing(tenantID, from, weeks, ids)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Totals = forecast.Totals
+		comparison.Revenue = forecast.Revenue
+		comparison.ScenarioRevenue = forecast.ScenarioRevenue
+		for _, week := range forecast.Totals {
+			comparison.AverageUtilization += week.Utilization / float64(len(forecast.Totals))
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons, nil
+}
+
+// PromoteScenario turns a draft scenario's assignments into staffing assignments once the deal is
+// signed. Assignments without a project go on projectID, which is required if any lack one.
+func (a *App) PromoteScenario(tenantID, scenarioID uint, projectID *uint, now time.Time) (*StaffingScenario, error) {
+	scenario, err := a.DraftScenario(tenantID, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	if len(scenario.Assignments) == 0 {
+		return nil, fmt.Errorf("%w: the scenario has no assignments to promote", ErrInvalidScenario)
+	}
+	if projectID != nil {
+		var project Project
+		if err := a.DB.Scopes(TenantScope(tenantID)).First(&project, *projectID).Error; err != nil {
+			return nil, fmt.Errorf("project %d not found: %w", *projectID, err)
+		}
+	}
+	for i := range scenario.Assignments {
+		if scenario.Assignments[i].ProjectID == nil {
+			if projectID == nil {
+				return nil, fmt.Errorf("%w: assignment %d has no project", ErrInvalidScenario, scenario.Assignments[i].ID)
+			}
+			scenario.Assignments[i].ProjectID = projectID
+		}
+	}
+
+	now = now.UTC()
+	err = a.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range scenario.Assignments {
+			sa := &scenario.Assignments[i]
+			assignment := sa.staffingAssignment()
+			if err := tx.Create(&assignment).Error; err != nil {
+				return fmt.Errorf("failed to create staffing assignment: %w", err)
+			}
+			sa.StaffingAssignmentID = &assignment.ID
+			if err := tx.Omit("Employee", "Project").Save(sa).Error; err != nil {
+				return fmt.Errorf("failed to update scenario assignment: %w", err)
+			}
+		}
+		scenario.State = ScenarioStatePromoted.String()
+		scenario.PromotedAt = &now
+		return tx.Omit("Assignments").Save(scenario).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Scenario %d promoted to %d staffing assignments", scenario.ID, len(scenario.Assignments))
+	return scenario, nil
+}
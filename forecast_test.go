@@ -0,0 +1,95 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestForecastStaffing tests that the forecast follows commitment segments, takes holidays off capacity
+// and commitments, weights scenario assignments by probability, and that promoting a scenario turns its
+// assignments into staffing assignments
+func TestForecastStaffing(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "forecast", Name: "Forecast Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	avery := Employee{TenantID: tenant.ID, FirstName: "Avery", IsActive: true, CapacityWeekly: 40}
+	blake := Employee{TenantID: tenant.ID, FirstName: "Blake", IsActive: true, CapacityWeekly: 40}
+	db.Create(&avery)
+	db.Create(&blake)
+	account := Account{TenantID: tenant.ID, Name: "Forecast Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Platform", AccountID: account.ID}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Senior", Amount: 150}
+	db.Create(&rate)
+	db.Create(&BillingCode{TenantID: tenant.ID, Name: "Build", Code: "BLD", ProjectID: project.ID, RateID: rate.ID})
+	db.Create(&StaffingAssignment{TenantID: tenant.ID, EmployeeID: avery.ID, ProjectID: project.ID, Commitment: 20,
+		StartDate: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 3, 29, 0, 0, 0, 0, time.UTC),
+		CommitmentSchedule: `{"segments":[{"start_date":"2025-03-02","end_date":"2025-03-15","commitment":20},{"start_date":"2025-03-16","end_date":"2025-03-29","commitment":10}]}`})
+	db.Create(&Holiday{TenantID: tenant.ID, Date: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), Name: "Founders Day"})
+
+	scenario := StaffingScenario{TenantID: tenant.ID, Name: "Data migration deal", Probability: 0.5}
+	if err := ValidateStaffingScenario(&scenario); err != nil {
+		t.Fatalf("Expected the scenario to be valid: %v", err)
+	}
+	db.Create(&scenario)
+	draft := ScenarioAssignment{TenantID: tenant.ID, ScenarioID: scenario.ID, EmployeeID: blake.ID, Rate: 200, Commitment: 30,
+		StartDate: time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 3, 29, 0, 0, 0, 0, time.UTC)}
+	if err := ValidateScenarioAssignment(&draft); err != nil {
+		t.Fatalf("Expected the scenario assignment to be valid: %v", err)
+	}
+	db.Create(&draft)
+
+	from := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	forecast, err := app.ForecastStaffing(tenant.ID, from, 4, []uint{scenario.ID})
+	if err != nil {
+		t.Fatalf("Failed to forecast: %v", err)
+	}
+	if forecast.Start != "2025-03-02" || len(forecast.Employees) != 2 || len(forecast.Totals) != 4 {
+		t.Fatalf("Expected 4 weeks for 2 employees from March 2, got %+v", forecast)
+	}
+	if week := forecast.Totals[0]; week.CapacityHours != 80 || week.CommittedHours != 20 || week.Revenue != 3000 || week.Utilization != 25 {
+		t.Errorf("Expected 20 of 80 hours for 3000.00 in the first week, got %+v", week)
+	}
+	if week := forecast.Totals[1]; week.CapacityHours != 64 || week.CommittedHours != 16 || week.Revenue != 2400 {
+		t.Errorf("Expected the holiday to cut capacity to 64 and the commitment to 16, got %+v", week)
+	}
+	if week := forecast.Totals[2]; week.CommittedHours != 10 || week.ScenarioHours != 15 || week.ScenarioRevenue != 3000 || week.Utilization != 31.25 {
+		t.Errorf("Expected the second segment's 10 hours plus 15 weighted scenario hours, got %+v", week)
+	}
+	if forecast.Revenue != 8400 || forecast.ScenarioRevenue != 6000 {
+		t.Errorf("Expected 8400.00 committed and 6000.00 weighted revenue, got %.2f and %.2f", forecast.Revenue, forecast.ScenarioRevenue)
+	}
+
+	comparisons, err := app.CompareScenarios(tenant.ID, from, 4, []uint{scenario.ID})
+	if err != nil {
+		t.Fatalf("Failed to compare scenarios: %v", err)
+	}
+	if len(comparisons) != 2 || comparisons[0].ScenarioID != 0 || comparisons[0].ScenarioRevenue != 0 ||
+		comparisons[1].ScenarioID != scenario.ID || comparisons[1].ScenarioRevenue != 6000 {
+		t.Errorf("Expected the baseline beside the scenario, got %+v", comparisons)
+	}
+
+	// Promoting needs a project for the assignment that doesn't have one
+	if _, err := app.PromoteScenario(tenant.ID, scenario.ID, nil, from); !errors.Is(err, ErrInvalidScenario) {
+		t.Errorf("Expected promotion without a project to be refused, got %v", err)
+	}
+	promoted, err := app.PromoteScenario(tenant.ID, scenario.ID, &project.ID, from)
+	if err != nil {
+		t.Fatalf("Failed to promote scenario: %v", err)
+	}
+	if promoted.State != ScenarioStatePromoted.String() || promoted.Assignments[0].StaffingAssignmentID == nil {
+		t.Errorf("Expected the scenario promoted and linked to its staffing assignment, got %+v", promoted)
+	}
+	if _, err := app.PromoteScenario(tenant.ID, scenario.ID, &project.ID, from); !errors.Is(err, ErrScenarioNotDraft) {
+		t.Errorf("Expected a second promotion to be refused, got %v", err)
+	}
+	forecast, _ = app.ForecastStaffing(tenant.ID, from, 4, nil)
+	if week := forecast.Totals[2]; week.CommittedHours != 40 || week.ScenarioHours != 0 || week.Revenue != 6000 {
+		t.Errorf("Expected the promoted assignment committed at the project's rate, got %+v", week)
+	}
+}
@@ -0,0 +1,224 @@
+package cronos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// GenerateCreditNotePDF renders a credit note in the same layout as the invoice it credits
+func (a *App) GenerateCreditNotePDF(note *CreditNote) []byte {
+	var lineItems []CreditNoteLineItem
+	a.DB.Where("credit_note_id = ?", note.ID).Order("id").Find(&lineItems)
+
+	var invoice Invoice
+	a.DB.Where("id = ?", note.InvoiceID).First(&invoice)
+
+	var account Account
+	a.DB.Where("id = ?", note.AccountID).First(&account)
+
+	// Get the tenant's owner account for "From" information
+	var ownerAccount Account
+	a.DB.Preload("LogoAsset").Where("tenant_id = ? AND type = ?", note.TenantID, AccountTypeInternal.String()).First(&ownerAccount)
+
+	// Use owner account details or fall back to defaults
+	fromName := defaultFromName
+	fromAddress := defaultFromAddress
+	fromContact := defaultContact
+	var logoPath string // No default logo
+
+	if ownerAccount.ID != 0 {
+		if ownerAccount.LegalName != "" {
+			fromName = ownerAccount.LegalName
+		} else if ownerAccount.Name != "" {
+			fromName = ownerAccount.Name
+		}
+		if ownerAccount.Address != "" {
+			fromAddress = ownerAccount.Address
+		}
+		if ownerAccount.Email != "" {
+			fromContact = ownerAccount.Email
+		}
+		// Check if custom logo exists
+		if ownerAccount.LogoAsset != nil && ownerAccount.LogoAsset.GCSObjectPath != nil {
+			// Download logo from GCS to temp file
+			ctx := context.Background()
+			storageClient := a.InitializeStorageClient(a.Project, *ownerAccount.LogoAsset.BucketName)
+			bucket := storageClient.Bucket(*ownerAccount.LogoAsset.BucketName)
+
+			rc, err := bucket.Object(*ownerAccount.LogoAsset.GCSObjectPath).NewReader(ctx)
+			if err == nil {
+				defer rc.Close()
+
+				tmpFile, err := os.CreateTemp("", "logo-*"+filepath.Ext(*ownerAccount.LogoAsset.GCSObjectPath))
+				if err == nil {
+					defer os.Remove(tmpFile.Name())
+					defer tmpFile.Close()
+
+					if _, err := io.Copy(tmpFile, rc); err == nil {
+						logoPath = tmpFile.Name()
+					}
+				}
+			}
+		}
+	}
+
+	issuedAt := time.Now()
+	if note.IssuedAt != nil {
+		issuedAt = *note.IssuedAt
+	}
+	creditNoteNumber := "CN-" + strconv.Itoa(issuedAt.Year()) + "00" + strconv.Itoa(int(note.ID))
+	invoiceNumber := invoice.Number
+	if invoiceNumber == "" {
+		invoiceNumber = strconv.Itoa(invoice.SentAt.Year()) + "00" + strconv.Itoa(int(invoice.ID))
+	}
+
+	// Initialize the PDF document with set margins and add a page that we can work with
+	pdf := gofpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(marginX, marginY, marginX)
+	pdf.AddPage()
+	pageW, _ := pdf.GetPageSize()
+	safeAreaW := pageW - 2*marginX
+
+	// Build the header - add logo only if custom logo exists
+	if logoPath != "" {
+		ext := strings.ToLower(filepath.Ext(logoPath))
+		if ext == ".svg" {
+			svgBasic, err := gofpdf.SVGBasicFileParse(logoPath)
+			if err == nil {
+				scale := 30.0 / svgBasic.Wd
+				if svgBasic.Ht*scale > 30.0 {
+					scale = 30.0 / svgBasic.Ht
+				}
+				pdf.SVGBasicWrite(&svgBasic, scale)
+			}
+		} else {
+			imageType := "PNG"
+			if ext == ".jpg" || ext == ".jpeg" {
+				imageType = "JPG"
+			}
+			pdf.ImageOptions(logoPath, 10, 0, 30, 30, false, gofpdf.ImageOptions{ImageType: imageType, ReadDpi: true}, 0, "")
+		}
+	}
+	pdf.SetFont(defaultFont, "B", 16)
+	_, lineHeight := pdf.GetFontSize()
+	currentY := pdf.GetY() + lineHeight + gapY
+	pdf.SetXY(marginX, currentY)
+	pdf.Cell(headerWidth, headerHeight, fromName)
+
+	leftY := pdf.GetY() + lineHeight + gapY
+
+	// Build credit note title on right
+	pdf.SetXY(80, currentY-lineHeight)
+	pdf.MultiCell(120, 10, "CREDIT NOTE\n "+invoice.Name, "0", "R", false)
+
+	newY := leftY
+	if (pdf.GetY() + gapY) > newY {
+		newY = pdf.GetY() + gapY
+	}
+	newY += 10.0
+
+	pdf.SetXY(marginX, newY)
+	pdf.SetFont(defaultFont, "", 12)
+	_, lineHeight = pdf.GetFontSize()
+	lineBreak := lineHeight + float64(1)
+
+	// Left hand info
+	for _, add := range breakAddress(fromAddress) {
+		pdf.Cell(safeAreaW/2, lineHeight, add)
+		pdf.Ln(lineBreak)
+	}
+	pdf.SetFontStyle("I")
+	pdf.Cell(safeAreaW/2, lineHeight, fromContact)
+	pdf.Ln(lineBreak)
+	pdf.Ln(lineBreak)
+	pdf.Ln(lineBreak)
+
+	pdf.SetFontStyle("B")
+	pdf.Cell(safeAreaW/2, lineHeight, "Credit To:")
+	pdf.Line(marginX, pdf.GetY()+lineHeight, marginX+safeAreaW/2, pdf.GetY()+lineHeight)
+	pdf.Ln(lineBreak)
+	pdf.Cell(safeAreaW/2, lineHeight, account.LegalName)
+	pdf.SetFontStyle("")
+	pdf.Ln(lineBreak)
+	for _, add := range breakAddress(account.Address) {
+		pdf.Cell(safeAreaW/2, lineHeight, add)
+		pdf.Ln(lineBreak)
+	}
+	pdf.SetFontStyle("I")
+	pdf.Cell(safeAreaW/2, lineHeight, account.Email)
+
+	endOfDetailY := pdf.GetY() + lineHeight
+	pdf.SetFontStyle("")
+
+	// Right hand side info, credit note no, original invoice and date
+	detailW := float64(30)
+	pdf.SetXY(safeAreaW/2+30, newY)
+	pdf.Cell(detailW, lineHeight, "Credit No:")
+	pdf.Cell(detailW, lineHeight, creditNoteNumber)
+	pdf.Ln(lineBreak)
+	pdf.SetX(safeAreaW/2 + 30)
+	pdf.Cell(detailW, lineHeight, "Invoice No:")
+	pdf.Cell(detailW, lineHeight, invoiceNumber)
+	pdf.Ln(lineBreak)
+	pdf.SetX(safeAreaW/2 + 30)
+	pdf.Cell(detailW, lineHeight, "Issued Date:")
+	pdf.Cell(detailW, lineHeight, issuedAt.UTC().Format("01/02/2006"))
+	pdf.Ln(lineBreak)
+
+	// Draw the table
+	pdf.SetFontSize(10.0)
+	pdf.SetXY(marginX, endOfDetailY+10.0)
+	lineHt := 10.0
+	const colNumber = 2
+	header := [colNumber]string{"Description", "Credit ($)"}
+	colWidth := [colNumber]float64{160.0, 40.0}
+
+	pdf.SetFontStyle("B")
+	pdf.SetFillColor(200, 200, 200)
+	for colJ := 0; colJ < colNumber; colJ++ {
+		pdf.CellFormat(colWidth[colJ], lineHt, header[colJ], "1", 0, "CM", true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFillColor(255, 255, 255)
+
+	pdf.SetFontStyle("")
+	for _, item := range lineItems {
+		pdf.CellFormat(colWidth[0], lineHt, item.Description, "1", 0, "LM", true, 0, "")
+		pdf.CellFormat(colWidth[1], lineHt, fmt.Sprintf("$ %.2f", float64(item.Amount)/100), "1", 0, "RM", true, 0, "")
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFontStyle("B")
+	pdf.SetX(marginX + colWidth[0] - 40.0)
+	pdf.CellFormat(40.0, lineHt, "Total Credit", "1", 0, "LM", true, 0, "")
+	pdf.CellFormat(colWidth[1], lineHt, fmt.Sprintf("$ %.2f", float64(note.Amount)/100), "1", 0, "RM", true, 0, "")
+	pdf.Ln(lineHt)
+
+	pdf.SetFontStyle("")
+	pdf.Ln(lineBreak)
+	if note.Reason != "" {
+		pdf.MultiCell(safeAreaW, lineHeight, "Reason: "+note.Reason, "", "L", false)
+		pdf.Ln(lineBreak)
+	}
+	if note.Disposition == CreditNoteDispositionRefund.String() {
+		pdf.Cell(safeAreaW, lineHeight, "Any amount already paid on this invoice will be refunded.")
+	} else {
+		pdf.Cell(safeAreaW, lineHeight, "Any amount already paid on this invoice will be applied to future invoices.")
+	}
+
+	var buffer bytes.Buffer
+	err := pdf.Output(&buffer)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,130 @@
+package cronos
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// formatStatementAmount shows cents as dollars, with negatives in parentheses the way accountants expect
+func formatStatementAmount(cents int64) string {
+	if cents < 0 {
+		return fmt.Sprintf("(%.2f)", float64(-cents)/100)
+	}
+	return fmt.Sprintf("%.2f", float64(cents)/100)
+}
+
+// StatementCSV renders a statement as CSV with one amount column per statement column. Amounts are
+// plain signed dollars so the file drops straight into a spreadsheet.
+func StatementCSV(statement *FinancialStatement) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	header := []string{"Account Code", "Line"}
+	for _, column := range statement.Columns {
+		header = append(header, column.Label)
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	for _, line := range statement.Lines {
+		record := []string{line.AccountCode, strings.Repeat("  ", line.Depth) + line.Label}
+		for _, amount := range line.Amounts {
+			record = append(record, fmt.Sprintf("%.2f", float64(amount)/100))
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+// GenerateStatementPDF renders a statement under the tenant's name with one amount column per
+// statement column
+func (a *App) GenerateStatementPDF(statement *FinancialStatement) []byte {
+	// Get the tenant's owner account for the company name
+	var ownerAccount Account
+	a.DB.Where("tenant_id = ? AND type = ?", statement.TenantID, AccountTypeInternal.String()).First(&ownerAccount)
+	fromName := defaultFromName
+	if ownerAccount.LegalName != "" {
+		fromName = ownerAccount.LegalName
+	} else if ownerAccount.Name != "" {
+		fromName = ownerAccount.Name
+	}
+
+	orientation := "P"
+	if len(statement.Columns) > 3 {
+		orientation = "L"
+	}
+	pdf := gofpdf.New(orientation, "mm", "Letter", "")
+	pdf.SetMargins(marginX, marginY, marginX)
+	pdf.AddPage()
+	pageW, _ := pdf.GetPageSize()
+	safeAreaW := pageW - 2*marginX
+
+	pdf.SetFont(defaultFont, "B", 16)
+	pdf.CellFormat(safeAreaW, headerHeight, fromName, "", 1, "CM", false, 0, "")
+	pdf.SetFont(defaultFont, "B", 14)
+	pdf.CellFormat(safeAreaW, headerHeight-2, statement.Title, "", 1, "CM", false, 0, "")
+	pdf.SetFont(defaultFont, "I", 10)
+	pdf.CellFormat(safeAreaW, headerHeight-4, "Generated "+statement.GeneratedAt.Format("Jan 2, 2006"), "", 1, "CM", false, 0, "")
+	pdf.Ln(gapY * 2)
+
+	// The label column takes whatever the amount columns leave
+	const lineHt = 6.0
+	amountW := 32.0
+	labelW := safeAreaW - amountW*float64(len(statement.Columns))
+
+	pdf.SetFont(defaultFont, "B", 10)
+	pdf.SetFillColor(200, 200, 200)
+	pdf.CellFormat(labelW, lineHt, "", "1", 0, "LM", true, 0, "")
+	for _, column := range statement.Columns {
+		pdf.CellFormat(amountW, lineHt, column.Label, "1", 0, "CM", true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFillColor(255, 255, 255)
+
+	for _, line := range statement.Lines {
+		switch line.Kind {
+		case StatementLineHeading:
+			pdf.SetFontStyle("B")
+			pdf.Ln(gapY)
+		case StatementLineTotal:
+			pdf.SetFontStyle("B")
+		default:
+			pdf.SetFontStyle("")
+		}
+		label := strings.Repeat("    ", line.Depth) + line.Label
+		border := ""
+		if line.Kind == StatementLineTotal {
+			border = "T"
+		}
+		pdf.CellFormat(labelW, lineHt, label, border, 0, "LM", false, 0, "")
+		for i := range statement.Columns {
+			amount := ""
+			if i < len(line.Amounts) {
+				amount = formatStatementAmount(line.Amounts[i])
+			}
+			pdf.CellFormat(amountW, lineHt, amount, border, 0, "RM", false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	if !statement.Balanced {
+		pdf.Ln(gapY * 2)
+		pdf.SetFont(defaultFont, "I", 10)
+		pdf.SetTextColor(200, 0, 0)
+		pdf.CellFormat(safeAreaW, lineHt, "This statement does not balance. Check for unbalanced journal transactions.", "", 1, "LM", false, 0, "")
+	}
+
+	var buffer bytes.Buffer
+	err := pdf.Output(&buffer)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return buffer.Bytes()
+}
@@ -36,6 +36,7 @@ func (a *App) GenerateInvoicePDF(invoice *Invoice) []byte {
 	lineItems := a.GetInvoiceLineItems(invoice)
 	entryItems := a.GetInvoiceEntries(invoice)
 	adjustments := a.GetInvoiceAdjustments(invoice)
+	milestones := a.GetInvoiceMilestones(invoice)
 
 	// Load expenses with receipts for this invoice
 	var expenses []Expense
@@ -100,7 +101,10 @@ func (a *App) GenerateInvoicePDF(invoice *Invoice) []byte {
 		}
 	}
 
-	InvoiceNumber := strconv.Itoa(time.Now().Year()) + "00" + strconv.Itoa(int(invoice.ID))
+	InvoiceNumber := invoice.Number
+	if InvoiceNumber == "" {
+		InvoiceNumber = strconv.Itoa(time.Now().Year()) + "00" + strconv.Itoa(int(invoice.ID))
+	}
 
 	// Initialize the PDF document with set margins and add a page that we can work with
 	pdf := gofpdf.New("P", "mm", "Letter", "")
@@ -202,13 +206,24 @@ func (a *App) GenerateInvoicePDF(invoice *Invoice) []byte {
 	pdf.Cell(invoiceDetailW, lineHeight, "Due Date:")
 	pdf.Cell(invoiceDetailW, lineHeight, invoice.DueAt.UTC().Format("01/02/2006"))
 	pdf.Ln(lineBreak)
+	if invoice.PaymentTerms != "" {
+		pdf.SetX(safeAreaW/2 + 30)
+		pdf.Cell(invoiceDetailW, lineHeight, "Terms:")
+		pdf.Cell(invoiceDetailW, lineHeight, invoice.PaymentTerms)
+		pdf.Ln(lineBreak)
+	}
 
 	// Draw the table
 	pdf.SetFontSize(10.0)
 	pdf.SetXY(marginX, endOfInvoiceDetailY+10.0)
 	lineHt := 10.0
 	const colNumber = 5
-	header := [colNumber]string{"Billing Code", "Project", "Hours", "Rate ($)", "Total ($)"}
+	// Amounts are shown in the invoice's currency, with the code in place of $ for anything but USD
+	symbol, unit := "$ ", "$"
+	if invoice.Currency != "" && invoice.Currency != DefaultCurrency {
+		symbol, unit = invoice.Currency+" ", invoice.Currency
+	}
+	header := [colNumber]string{"Billing Code", "Project", "Hours", "Rate (" + unit + ")", "Total (" + unit + ")"}
 	colWidth := [colNumber]float64{40.0, 70.0, 25.0, 25.0, 40.0}
 
 	// Headers
@@ -235,8 +250,18 @@ func (a *App) GenerateInvoicePDF(invoice *Invoice) []byte {
 		pdf.CellFormat(colWidth[0], lineHt, billingCode, "1", 0, "CM", true, 0, "")
 		pdf.CellFormat(colWidth[1], lineHt, description, "1", 0, "LM", true, 0, "")
 		pdf.CellFormat(colWidth[2], lineHt, hours, "1", 0, "CM", true, 0, "")
-		pdf.CellFormat(colWidth[3], lineHt, "$ "+rate, "1", 0, "CM", true, 0, "")
-		pdf.CellFormat(colWidth[4], lineHt, "$ "+total, "1", 0, "RM", true, 0, "")
+		pdf.CellFormat(colWidth[3], lineHt, symbol+rate, "1", 0, "CM", true, 0, "")
+		pdf.CellFormat(colWidth[4], lineHt, symbol+total, "1", 0, "RM", true, 0, "")
+		pdf.Ln(-1)
+	}
+	// Completed milestones on fixed-fee projects are billed at their amount rather than by the hour
+	for _, milestone := range milestones {
+		amount := fmt.Sprintf("%.2f", float64(milestone.Amount)/100)
+		pdf.CellFormat(colWidth[0], lineHt, "MILESTONE", "1", 0, "CM", true, 0, "")
+		pdf.CellFormat(colWidth[1], lineHt, milestone.Name, "1", 0, "LM", true, 0, "")
+		pdf.CellFormat(colWidth[2], lineHt, "", "1", 0, "CM", true, 0, "")
+		pdf.CellFormat(colWidth[3], lineHt, "", "1", 0, "LM", true, 0, "")
+		pdf.CellFormat(colWidth[4], lineHt, symbol+amount, "1", 0, "RM", true, 0, "")
 		pdf.Ln(-1)
 	}
 	// add adjustments if they exist, text should be italic
@@ -259,7 +284,7 @@ func (a *App) GenerateInvoicePDF(invoice *Invoice) []byte {
 			pdf.CellFormat(colWidth[1], lineHt, description, "1", 0, "LM", true, 0, "")
 			pdf.CellFormat(colWidth[2], lineHt, "", "1", 0, "CM", true, 0, "")
 			pdf.CellFormat(colWidth[3], lineHt, "", "1", 0, "LM", true, 0, "")
-			pdf.CellFormat(colWidth[4], lineHt, "$ "+amount, "1", 0, "RM", true, 0, "")
+			pdf.CellFormat(colWidth[4], lineHt, symbol+amount, "1", 0, "RM", true, 0, "")
 			pdf.Ln(-1)
 		}
 	}
@@ -276,13 +301,13 @@ func (a *App) GenerateInvoicePDF(invoice *Invoice) []byte {
 	totalFees := fmt.Sprintf("%.2f", invoice.TotalFees)
 	pdf.SetX(marginX + leftIndent)
 	pdf.CellFormat(colWidth[3], lineHt, "Fees", "1", 0, "LM", true, 0, "")
-	pdf.CellFormat(colWidth[4], lineHt, "$ "+totalFees, "1", 0, "RM", true, 0, "")
+	pdf.CellFormat(colWidth[4], lineHt, symbol+totalFees, "1", 0, "RM", true, 0, "")
 	pdf.Ln(lineHt)
 
 	totalAdjustments := fmt.Sprintf("%.2f", invoice.TotalAdjustments)
 	pdf.SetX(marginX + leftIndent)
 	pdf.CellFormat(colWidth[3], lineHt, "Adjustments", "1", 0, "LM", true, 0, "")
-	pdf.CellFormat(colWidth[4], lineHt, "$ "+totalAdjustments, "1", 0, "RM", true, 0, "")
+	pdf.CellFormat(colWidth[4], lineHt, symbol+totalAdjustments, "1", 0, "RM", true, 0, "")
 	pdf.Ln(lineHt)
 
 	// Add expenses total if there are any
@@ -290,14 +315,23 @@ func (a *App) GenerateInvoicePDF(invoice *Invoice) []byte {
 		totalExpenses := fmt.Sprintf("%.2f", invoice.TotalExpenses)
 		pdf.SetX(marginX + leftIndent)
 		pdf.CellFormat(colWidth[3], lineHt, "Expenses", "1", 0, "LM", true, 0, "")
-		pdf.CellFormat(colWidth[4], lineHt, "$ "+totalExpenses, "1", 0, "RM", true, 0, "")
+		pdf.CellFormat(colWidth[4], lineHt, symbol+totalExpenses, "1", 0, "RM", true, 0, "")
+		pdf.Ln(lineHt)
+	}
+
+	// Tax subtotal, only shown when the invoice is taxed
+	if invoice.TotalTax != 0 {
+		totalTax := fmt.Sprintf("%.2f", invoice.TotalTax)
+		pdf.SetX(marginX + leftIndent)
+		pdf.CellFormat(colWidth[3], lineHt, "Tax", "1", 0, "LM", true, 0, "")
+		pdf.CellFormat(colWidth[4], lineHt, symbol+totalTax, "1", 0, "RM", true, 0, "")
 		pdf.Ln(lineHt)
 	}
 
 	grandTotal := fmt.Sprintf("%.2f", invoice.TotalAmount)
 	pdf.SetX(marginX + leftIndent)
 	pdf.CellFormat(colWidth[3], lineHt, "Total Due", "1", 0, "LM", true, 0, "")
-	pdf.CellFormat(colWidth[4], lineHt, "$ "+grandTotal, "1", 0, "RM", true, 0, "")
+	pdf.CellFormat(colWidth[4], lineHt, symbol+grandTotal, "1", 0, "RM", true, 0, "")
 	pdf.Ln(lineHt)
 
 	pdf.SetFontStyle("")
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	"gorm.io/gorm"
 )
 
 var ErrInvoiceOverlap = errors.New("new invoice overlaps with existing invoice")
@@ -43,6 +44,7 @@ func (a *App) CreateInvoice(accountID uint, projectID *uint, creationDate time.T
 	// Create new invoice
 	var newARInvoice Invoice
 	newARInvoice = Invoice{
+		TenantID:  account.TenantID,
 		AccountID: accountID,
 		Account:   account,
 		State:     InvoiceStateDraft.String(),
@@ -127,6 +129,100 @@ func (a *App) CreateInvoice(accountID uint, projectID *uint, creationDate time.T
 	return nil
 }
 
+// maxRolloverPeriods bounds how many consecutive billing periods RolloverDraftInvoices will create
+// for a single account or project in one pass, so a long scheduler outage can't flood the ledger.
+const maxRolloverPeriods = 6
+
+// RolloverDraftInvoices makes sure every active project in the tenant has a draft invoice for the
+// billing period containing now, based on the account's BillingFrequency. Project-billed accounts are
+// skipped because their single invoice spans the whole project and is created with the first entry.
+// It returns the number of invoices created.
+func (a *App) RolloverDraftInvoices(tenantID uint, now time.Time) (int, error) {
+	var projects []Project
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Account").
+		Where("active_start <= ? AND active_end >= ?", now, now).
+		Find(&projects).Error; err != nil {
+		return 0, fmt.Errorf("failed to load active projects: %w", err)
+	}
+
+	created := 0
+	seenAccounts := make(map[uint]bool)
+	for _, project := range projects {
+		account := project.Account
+		switch account.BillingFrequency {
+		case BillingFrequencyMonthly.String(), BillingFrequencyBiweekly.String(), BillingFrequencyWeekly.String():
+		default:
+			continue
+		}
+
+		var projectID *uint
+		if account.ProjectsSingleInvoice {
+			if seenAccounts[account.ID] {
+				continue
+			}
+			seenAccounts[account.ID] = true
+		} else {
+			id := project.ID
+			projectID = &id
+		}
+
+		// Weekly and biweekly invoices chain off the previous period, so if periods were missed we
+		// keep creating the next one until the current date is covered. A period that already starts
+		// after now means the chain has run past it, so nothing more is created.
+		for i := 0; i < maxRolloverPeriods; i++ {
+			covered, err := a.invoiceCoversDate(account.ID, projectID, now)
+			if err != nil {
+				return created, err
+			}
+			if covered {
+				break
+			}
+			ahead, err := a.invoicedAfter(account.ID, projectID, now)
+			if err != nil {
+				return created, err
+			}
+			if ahead {
+				break
+			}
+			if err := a.CreateInvoice(account.ID, projectID, now); err != nil {
+				return created, err
+			}
+			created++
+		}
+	}
+
+	log.Printf("Invoice rollover for tenant %d created %d draft invoices", tenantID, created)
+	return created, nil
+}
+
+// invoiceCoversDate reports whether a non-void AR invoice exists for the account (and project, when
+// invoiced separately) whose billing period contains date. Weekly and biweekly periods end at midnight
+// on their last day, so the end is compared by calendar day.
+func (a *App) invoiceCoversDate(accountID uint, projectID *uint, date time.Time) (bool, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return a.countRolloverInvoices(accountID, projectID, "period_start <= ? AND period_end >= ?", date, day)
+}
+
+// invoicedAfter reports whether the account (and project, when invoiced separately) already has a
+// non-void AR invoice for a period starting after date
+func (a *App) invoicedAfter(accountID uint, projectID *uint, date time.Time) (bool, error) {
+	return a.countRolloverInvoices(accountID, projectID, "period_start > ?", date)
+}
+
+func (a *App) countRolloverInvoices(accountID uint, projectID *uint, period string, args ...interface{}) (bool, error) {
+	query := a.DB.Model(&Invoice{}).
+		Where("account_id = ? AND type = ? AND state != ?", accountID, InvoiceTypeAR.String(), InvoiceStateVoid.String()).
+		Where(period, args...)
+	if projectID != nil {
+		query = query.Where("project_id = ?", *projectID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check invoice coverage: %w", err)
+	}
+	return count > 0, nil
+}
+
 // SaveBillPDFsForInvoice generates and saves PDFs for all bills associated with an invoice
 func (a *App) SaveBillPDFsForInvoice(invoice *Invoice) {
 	// Get all unique bill IDs from the invoice's entries
@@ -327,6 +423,17 @@ func (a *App) ApproveInvoice(invoiceID uint) error {
 	invoice.State = InvoiceStateApproved.String()
 	invoice.AcceptedAt = time.Now()
 
+	// Foreign-currency invoices are accrued at the rate on the approval date
+	if err := a.SetInvoiceCurrency(&invoice, invoice.AcceptedAt); err != nil {
+		return fmt.Errorf("failed to set invoice exchange rate: %w", err)
+	}
+
+	if a.shouldAssignInvoiceNumberAt(invoice.TenantID, InvoiceNumberAssignAtApprove) {
+		if err := a.AssignInvoiceNumber(&invoice, invoice.AcceptedAt); err != nil {
+			return fmt.Errorf("failed to assign invoice number: %w", err)
+		}
+	}
+
 	// Only approve entries that are still in draft state (others may have been approved individually)
 	draftEntryIDs := make([]uint, 0)
 	alreadyApprovedCount := 0
@@ -358,6 +465,11 @@ func (a *App) ApproveInvoice(invoiceID uint) error {
 		log.Printf("Batch invoiced %d approved expenses", result.RowsAffected)
 	}
 
+	// Work on retainer projects draws down the prepaid hours before anything is billed
+	if err := a.DrawDownRetainers(&invoice); err != nil {
+		return fmt.Errorf("failed to draw down retainer: %w", err)
+	}
+
 	// Update invoice totals to include adjustments and expenses
 	a.UpdateInvoiceTotals(&invoice)
 
@@ -373,16 +485,21 @@ func (a *App) ApproveInvoice(invoiceID uint) error {
 		return fmt.Errorf("failed to generate line items: %w", err)
 	}
 
+	// Line items carry the tax, so refresh the totals now that they exist
+	a.UpdateInvoiceTotals(&invoice)
+
 	// Book accrual journal entries for approved work
 	log.Printf("Booking accrual journal entries for invoice ID: %d", invoiceID)
 	if err := a.BookInvoiceAccrual(&invoice); err != nil {
 		log.Printf("Warning: Failed to book accrual for invoice %d: %v", invoiceID, err)
 	}
+	a.activateRetainerBlock(&invoice)
 
-	// Book adjustment accruals for all approved adjustments (only at approval - not at later states)
+	// Book adjustment accruals for all approved adjustments (only at approval - not at later states).
+	// Retainer adjustments were already booked through deferred revenue.
 	log.Printf("Booking accrual journal entries for adjustments on invoice ID: %d", invoiceID)
 	var adjustments []Adjustment
-	if err := a.DB.Where("invoice_id = ? AND state = ?", invoiceID, AdjustmentStateApproved.String()).Find(&adjustments).Error; err == nil {
+	if err := a.DB.Where("invoice_id = ? AND state = ? AND retainer = ?", invoiceID, AdjustmentStateApproved.String(), false).Find(&adjustments).Error; err == nil {
 		for _, adj := range adjustments {
 			if err := a.BookAdjustmentAccrual(&adj); err != nil {
 				log.Printf("Warning: Failed to book adjustment accrual for adjustment %d: %v", adj.ID, err)
@@ -453,8 +570,13 @@ func (a *App) SendInvoice(invoiceID uint) error {
 
 	invoice.State = InvoiceStateSent.String()
 	invoice.SentAt = time.Now()
-	// Set the due date based on invoice date (e.g., net 30)
-	invoice.DueAt = invoice.SentAt.AddDate(0, 0, 30) // Default to 30 days
+
+	// Numbered here when the tenant assigns at send, or if the invoice was approved before numbering existed
+	if err := a.AssignInvoiceNumber(&invoice, invoice.SentAt); err != nil {
+		return fmt.Errorf("failed to assign invoice number: %w", err)
+	}
+	// Set the due date from the project's or account's payment terms (Net 30 unless they say otherwise)
+	a.applyPaymentTerms(&invoice)
 
 	// Log if we're updating stale dates (PDF will be regenerated)
 	if hadPreviousDates {
@@ -587,7 +709,7 @@ func (a *App) MarkInvoicePaid(invoiceID uint, paymentDate time.Time) error {
 		log.Printf("Warning: No project associated with invoice ID: %d - continuing anyway", invoice.ID)
 	}
 
-	if invoice.State != InvoiceStateSent.String() {
+	if invoice.State != InvoiceStateSent.String() && invoice.State != InvoiceStatePartiallyPaid.String() {
 		log.Printf("Invalid prior state: %s, expected: %s", invoice.State, InvoiceStateSent.String())
 		return InvalidPriorState
 	}
@@ -726,12 +848,34 @@ func (a *App) VoidInvoice(invoiceID uint) error {
 	var invoice Invoice
 	a.DB.Preload("Entries").Preload("Account").Where("ID = ?", invoiceID).First(&invoice)
 
+	// A retainer can't be voided once work has been drawn against it or its hours have lapsed
+	block := a.retainerBlockForInvoice(invoiceID)
+	if block != nil && (block.HoursUsed > 0 || block.State == RetainerBlockStateExpired.String()) {
+		return ErrRetainerInUse
+	}
+
 	// Reverse all journal entries for this invoice
 	log.Printf("Reversing journal entries for invoice ID: %d", invoiceID)
 	if err := a.ReverseInvoiceJournalEntries(&invoice); err != nil {
+		// The invoice can't be voided without its reversal if the books are locked
+		if errors.Is(err, ErrPeriodLocked) {
+			return err
+		}
 		log.Printf("Warning: Failed to reverse journal entries for invoice %d: %v", invoiceID, err)
 	}
 
+	// Hours this invoice drew from retainers go back on their blocks
+	if err := a.releaseRetainerDrawdowns(invoiceID); err != nil {
+		log.Printf("Warning: Failed to release retainer hours for invoice %d: %v", invoiceID, err)
+	}
+	if block != nil {
+		a.DB.Model(block).Update("state", RetainerBlockStateVoid.String())
+	}
+	// Milestones billed on this invoice can be completed again onto a new one
+	if err := a.releaseMilestones(invoiceID); err != nil {
+		log.Printf("Warning: Failed to release milestones for invoice %d: %v", invoiceID, err)
+	}
+
 	// Invoice can be voided at any point
 	invoice.State = InvoiceStateVoid.String()
 	for _, entry := range invoice.Entries {
@@ -866,11 +1010,12 @@ func (a *App) SaveInvoiceToGCS(invoice *Invoice) error {
 	datesWereEmpty := invoice.SentAt.IsZero()
 	if datesWereEmpty {
 		invoice.SentAt = time.Now()
-		invoice.DueAt = invoice.SentAt.AddDate(0, 0, 30) // Default to 30 days
+		a.applyPaymentTerms(invoice)
 		// Save the dates to DB so they're consistent with the PDF
 		if err := a.DB.Model(invoice).Updates(map[string]interface{}{
-			"sent_at": invoice.SentAt,
-			"due_at":  invoice.DueAt,
+			"sent_at":       invoice.SentAt,
+			"due_at":        invoice.DueAt,
+			"payment_terms": invoice.PaymentTerms,
 		}).Error; err != nil {
 			log.Printf("Warning: Failed to update invoice dates: %v", err)
 		}
@@ -1020,12 +1165,16 @@ func (a *App) processProjectCommission(invoice *Invoice, projectID uint) {
 		projectInvoiceTotal = float64(invoice.TotalFees) / 100.0 // Convert cents to dollars
 		log.Printf("Using direct invoice total for project %d: $%.2f", projectID, projectInvoiceTotal)
 	} else {
-		// Otherwise, sum the fees from entries for this specific project
+		// Otherwise, sum the fees from entries and milestones for this specific project
 		for _, entry := range invoice.Entries {
 			if entry.ProjectID == projectID && entry.State != EntryStateVoid.String() {
 				projectInvoiceTotal += float64(entry.Fee) / 100.0
 			}
 		}
+		var milestoneFees int64
+		a.DB.Model(&Milestone{}).Where("invoice_id = ? AND project_id = ?", invoice.ID, projectID).
+			Select("COALESCE(SUM(amount), 0)").Scan(&milestoneFees)
+		projectInvoiceTotal += float64(milestoneFees) / 100.0
 		log.Printf("Calculated invoice total for project %d from entries: $%.2f", projectID, projectInvoiceTotal)
 	}
 
@@ -1183,9 +1332,6 @@ func (a *App) processCommission(project *Project, role string, staffID uint, sta
 		Debit:      int64(commissionAmount),
 		Credit:     0,
 	}
-	if err := a.DB.Create(&commissionExpense).Error; err != nil {
-		log.Printf("Warning: Failed to book commission expense: %v", err)
-	}
 
 	// CR: ACCOUNTS_PAYABLE
 	commissionAP := Journal{
@@ -1196,8 +1342,14 @@ func (a *App) processCommission(project *Project, role string, staffID uint, sta
 		Debit:      0,
 		Credit:     int64(commissionAmount),
 	}
-	if err := a.DB.Create(&commissionAP).Error; err != nil {
-		log.Printf("Warning: Failed to book commission AP: %v", err)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   bill.TenantID,
+		SourceType: JournalSourceCommission.String(),
+		SourceID:   &commission.ID,
+		Memo:       fmt.Sprintf("Commission for %s on project %s", role, project.Name),
+		Lines:      []Journal{commissionExpense, commissionAP},
+	}); err != nil {
+		log.Printf("Warning: Failed to book commission: %v", err)
 	}
 
 	log.Printf("Booked commission journal entries for $%.2f", float64(commissionAmount)/100)
@@ -1244,6 +1396,12 @@ func (a *App) BookInvoiceAccrual(invoice *Invoice) error {
 	// Create subaccount identifier for revenue
 	subAccount := fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name)
 
+	// Retainers are billed ahead of the work, so they're held as deferred revenue until drawn down
+	revenueAccount := AccountRevenue
+	if a.retainerBlockForInvoice(invoice.ID) != nil {
+		revenueAccount = AccountDeferredRevenue
+	}
+
 	// Book revenue side if there's revenue to recognize
 	if revenueAmount > 0 {
 		// Book: DR ACCRUED_RECEIVABLES
@@ -1255,26 +1413,37 @@ func (a *App) BookInvoiceAccrual(invoice *Invoice) error {
 			Debit:      revenueAmount,
 			Credit:     0,
 		}
-		if err := a.DB.Create(&accrualDR).Error; err != nil {
-			return fmt.Errorf("failed to book accrued receivables DR: %w", err)
-		}
+		accrualDR.translate(invoice.Currency, invoice.ExchangeRate)
 
 		// Book: CR REVENUE
 		revenueCR := Journal{
-			Account:    AccountRevenue.String(),
+			Account:    revenueAccount.String(),
 			SubAccount: subAccount,
 			InvoiceID:  &invoice.ID,
 			Memo:       fmt.Sprintf("Revenue recognized for approved work on invoice #%d", invoice.ID),
 			Debit:      0,
 			Credit:     revenueAmount,
 		}
-		if err := a.DB.Create(&revenueCR).Error; err != nil {
-			return fmt.Errorf("failed to book revenue CR: %w", err)
+		revenueCR.translate(invoice.Currency, invoice.ExchangeRate)
+		if err := a.PostJournalTransaction(&JournalTransaction{
+			TenantID:      invoice.TenantID,
+			EffectiveDate: invoice.AcceptedAt,
+			SourceType:    JournalSourceInvoice.String(),
+			SourceID:      &invoice.ID,
+			Memo:          fmt.Sprintf("Revenue accrual for approved invoice #%d", invoice.ID),
+			Lines:         []Journal{accrualDR, revenueCR},
+		}); err != nil {
+			return fmt.Errorf("failed to book revenue accrual: %w", err)
 		}
 
 		log.Printf("Booked revenue accrual for invoice ID %d: $%.2f", invoice.ID, float64(revenueAmount)/100)
 	}
 
+	// Book sales tax collected on the line items as owed to each jurisdiction
+	if err := a.BookInvoiceTaxAccrual(invoice, subAccount); err != nil {
+		return err
+	}
+
 	// Now book payroll expense accruals from bills
 	// We need to load bills associated with this invoice through entries
 	var bills []Bill
@@ -1321,10 +1490,6 @@ func (a *App) BookInvoiceAccrual(invoice *Invoice) error {
 			Debit:      totalPayrollExpense,
 			Credit:     0,
 		}
-		if err := a.DB.Create(&expenseDR).Error; err != nil {
-			log.Printf("Warning: Failed to book payroll expense DR for bill %d: %v", bill.ID, err)
-			continue
-		}
 
 		// Book: CR ACCRUED_PAYROLL
 		payrollCR := Journal{
@@ -1336,8 +1501,15 @@ func (a *App) BookInvoiceAccrual(invoice *Invoice) error {
 			Debit:      0,
 			Credit:     totalPayrollExpense,
 		}
-		if err := a.DB.Create(&payrollCR).Error; err != nil {
-			log.Printf("Warning: Failed to book accrued payroll CR for bill %d: %v", bill.ID, err)
+		if err := a.PostJournalTransaction(&JournalTransaction{
+			TenantID:      invoice.TenantID,
+			EffectiveDate: invoice.AcceptedAt,
+			SourceType:    JournalSourceBill.String(),
+			SourceID:      &bill.ID,
+			Memo:          fmt.Sprintf("Payroll accrual for bill #%d on invoice #%d", bill.ID, invoice.ID),
+			Lines:         []Journal{expenseDR, payrollCR},
+		}); err != nil {
+			log.Printf("Warning: Failed to book payroll accrual for bill %d: %v", bill.ID, err)
 			continue
 		}
 
@@ -1418,9 +1590,6 @@ func (a *App) BookBillAccrual(bill *Bill) error {
 		Debit:      totalAPAmount,
 		Credit:     0,
 	}
-	if err := a.DB.Create(&reverseAccrual).Error; err != nil {
-		log.Printf("Warning: Failed to reverse accrued payroll: %v", err)
-	}
 
 	// Book formal Accounts Payable
 	apEntry := Journal{
@@ -1431,7 +1600,13 @@ func (a *App) BookBillAccrual(bill *Bill) error {
 		Debit:      0,
 		Credit:     totalAPAmount,
 	}
-	if err := a.DB.Create(&apEntry).Error; err != nil {
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   bill.TenantID,
+		SourceType: JournalSourceBill.String(),
+		SourceID:   &bill.ID,
+		Memo:       fmt.Sprintf("Move accrued payroll to AP for bill #%d", bill.ID),
+		Lines:      []Journal{reverseAccrual, apEntry},
+	}); err != nil {
 		return fmt.Errorf("failed to book accounts payable: %w", err)
 	}
 
@@ -1487,9 +1662,6 @@ func (a *App) ReverseEntryAccruals(entryIDs []uint) error {
 			Debit:      totalAmount,
 			Credit:     0,
 		}
-		if err := a.DB.Create(&reversalDR).Error; err != nil {
-			log.Printf("Warning: Failed to reverse accrued payroll DR: %v", err)
-		}
 
 		reversalCR := Journal{
 			Account:    AccountPayrollExpense.String(),
@@ -1498,8 +1670,13 @@ func (a *App) ReverseEntryAccruals(entryIDs []uint) error {
 			Debit:      0,
 			Credit:     totalAmount,
 		}
-		if err := a.DB.Create(&reversalCR).Error; err != nil {
-			log.Printf("Warning: Failed to reverse payroll expense CR: %v", err)
+		if err := a.PostJournalTransaction(&JournalTransaction{
+			TenantID:   employee.TenantID,
+			SourceType: JournalSourceEntry.String(),
+			Memo:       fmt.Sprintf("VOID: Reverse payroll accrual for %d entries", len(empEntries)),
+			Lines:      []Journal{reversalDR, reversalCR},
+		}); err != nil {
+			log.Printf("Warning: Failed to reverse payroll accrual for employee %d: %v", employeeID, err)
 		}
 
 		log.Printf("Successfully reversed payroll accrual for employee %d: $%.2f", employeeID, float64(totalAmount)/100)
@@ -1552,9 +1729,6 @@ func (a *App) BookPayrollAccrual(bill *Bill, entryIDs []uint) error {
 		Debit:      totalAmount,
 		Credit:     0,
 	}
-	if err := a.DB.Create(&expenseDR).Error; err != nil {
-		return fmt.Errorf("failed to book payroll expense DR: %w", err)
-	}
 
 	// Book CR: Accrued Payroll
 	accrualCR := Journal{
@@ -1565,8 +1739,14 @@ func (a *App) BookPayrollAccrual(bill *Bill, entryIDs []uint) error {
 		Debit:      0,
 		Credit:     totalAmount,
 	}
-	if err := a.DB.Create(&accrualCR).Error; err != nil {
-		return fmt.Errorf("failed to book accrued payroll CR: %w", err)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   bill.TenantID,
+		SourceType: JournalSourceBill.String(),
+		SourceID:   &bill.ID,
+		Memo:       fmt.Sprintf("Payroll accrual for approved entries (bill #%d)", bill.ID),
+		Lines:      []Journal{expenseDR, accrualCR},
+	}); err != nil {
+		return fmt.Errorf("failed to book payroll accrual: %w", err)
 	}
 
 	log.Printf("Successfully booked payroll accrual for bill ID %d: $%.2f (DR: Payroll Expense, CR: Accrued Payroll)",
@@ -1617,8 +1797,9 @@ func (a *App) MoveInvoiceToAccountsReceivable(invoice *Invoice) error {
 		Debit:      totalCredits, // Reverse the credits
 		Credit:     totalDebits,  // Reverse the debits
 	}
-	if err := a.DB.Create(&reverseAccrual).Error; err != nil {
-		return fmt.Errorf("failed to reverse accrued receivables: %w", err)
+	if invoice.ExchangeRate != 0 {
+		reverseAccrual.Currency, reverseAccrual.ExchangeRate = invoice.Currency, invoice.ExchangeRate
+		reverseAccrual.OriginalAmount = originalBalance(accrualEntries)
 	}
 
 	// Book formal Accounts Receivable (net amount)
@@ -1630,7 +1811,18 @@ func (a *App) MoveInvoiceToAccountsReceivable(invoice *Invoice) error {
 		Debit:      netAmount,
 		Credit:     0,
 	}
-	if err := a.DB.Create(&arEntry).Error; err != nil {
+	// Carry the foreign amount over to AR so it can be settled at the payment date's rate
+	if invoice.ExchangeRate != 0 {
+		arEntry.Currency, arEntry.ExchangeRate = invoice.Currency, invoice.ExchangeRate
+		arEntry.OriginalAmount = originalBalance(accrualEntries)
+	}
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   invoice.TenantID,
+		SourceType: JournalSourceInvoice.String(),
+		SourceID:   &invoice.ID,
+		Memo:       fmt.Sprintf("Move invoice #%d to accounts receivable", invoice.ID),
+		Lines:      []Journal{reverseAccrual, arEntry},
+	}); err != nil {
 		return fmt.Errorf("failed to book accounts receivable: %w", err)
 	}
 
@@ -1689,9 +1881,9 @@ func (a *App) RecordInvoiceCashPayment(invoice *Invoice, paymentDate time.Time)
 		Debit:      0,
 		Credit:     totalAmount,
 	}
-	clearAR.CreatedAt = paymentDate
-	if err := a.DB.Create(&clearAR).Error; err != nil {
-		return fmt.Errorf("failed to clear accounts receivable: %w", err)
+	if invoice.ExchangeRate != 0 {
+		clearAR.Currency, clearAR.ExchangeRate = invoice.Currency, invoice.ExchangeRate
+		clearAR.OriginalAmount = originalBalance(arEntries)
 	}
 
 	// Record cash receipt - always use ChaseBusiness subaccount
@@ -1703,8 +1895,24 @@ func (a *App) RecordInvoiceCashPayment(invoice *Invoice, paymentDate time.Time)
 		Debit:      totalAmount,
 		Credit:     0,
 	}
-	cashEntry.CreatedAt = paymentDate
-	if err := a.DB.Create(&cashEntry).Error; err != nil {
+
+	// Foreign-currency receipts are worth the outstanding foreign amount at the payment date's rate
+	if invoice.ExchangeRate != 0 {
+		rate, err := a.foreignRate(invoice.TenantID, invoice.Currency, paymentDate)
+		if err != nil {
+			return fmt.Errorf("failed to get payment exchange rate: %w", err)
+		}
+		cashEntry.Debit = originalBalance(arEntries)
+		cashEntry.translate(invoice.Currency, rate)
+	}
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      invoice.TenantID,
+		EffectiveDate: paymentDate,
+		SourceType:    JournalSourceInvoice.String(),
+		SourceID:      &invoice.ID,
+		Memo:          fmt.Sprintf("Cash received for invoice #%d", invoice.ID),
+		Lines:         []Journal{clearAR, cashEntry, realizedFXLine(invoice, cashEntry.Debit-totalAmount)},
+	}); err != nil {
 		return fmt.Errorf("failed to record cash receipt: %w", err)
 	}
 
@@ -1750,9 +1958,6 @@ func (a *App) MoveBillToAccountsPayable(bill *Bill) error {
 		Debit:      totalAmount,
 		Credit:     0,
 	}
-	if err := a.DB.Create(&reverseAccrual).Error; err != nil {
-		return fmt.Errorf("failed to reverse accrued payroll: %w", err)
-	}
 
 	// Book formal Accounts Payable
 	apEntry := Journal{
@@ -1763,7 +1968,13 @@ func (a *App) MoveBillToAccountsPayable(bill *Bill) error {
 		Debit:      0,
 		Credit:     totalAmount,
 	}
-	if err := a.DB.Create(&apEntry).Error; err != nil {
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   bill.TenantID,
+		SourceType: JournalSourceBill.String(),
+		SourceID:   &bill.ID,
+		Memo:       fmt.Sprintf("Move bill #%d to accounts payable", bill.ID),
+		Lines:      []Journal{reverseAccrual, apEntry},
+	}); err != nil {
 		return fmt.Errorf("failed to book accounts payable: %w", err)
 	}
 
@@ -1813,23 +2024,25 @@ func (a *App) MoveBillToAccountsPayable(bill *Bill) error {
 						Debit:      totalExpenseAmount,
 						Credit:     0,
 					}
-					if err := a.DB.Create(&reverseExpenseAccrual).Error; err != nil {
-						log.Printf("Warning: Failed to reverse expense accruals for bill %d: %v", bill.ID, err)
+					// Book formal Accounts Payable for expenses
+					expenseAPEntry := Journal{
+						Account:    AccountAccountsPayable.String(),
+						SubAccount: employeeSubAccount,
+						BillID:     &bill.ID,
+						Memo:       fmt.Sprintf("Reimbursable expenses payable for accepted bill #%d", bill.ID),
+						Debit:      0,
+						Credit:     totalExpenseAmount,
+					}
+					if err := a.PostJournalTransaction(&JournalTransaction{
+						TenantID:   bill.TenantID,
+						SourceType: JournalSourceBill.String(),
+						SourceID:   &bill.ID,
+						Memo:       fmt.Sprintf("Move reimbursable expenses to AP for bill #%d", bill.ID),
+						Lines:      []Journal{reverseExpenseAccrual, expenseAPEntry},
+					}); err != nil {
+						log.Printf("Warning: Failed to move expense accruals to AP for bill %d: %v", bill.ID, err)
 					} else {
-						// Book formal Accounts Payable for expenses
-						expenseAPEntry := Journal{
-							Account:    AccountAccountsPayable.String(),
-							SubAccount: employeeSubAccount,
-							BillID:     &bill.ID,
-							Memo:       fmt.Sprintf("Reimbursable expenses payable for accepted bill #%d", bill.ID),
-							Debit:      0,
-							Credit:     totalExpenseAmount,
-						}
-						if err := a.DB.Create(&expenseAPEntry).Error; err != nil {
-							log.Printf("Warning: Failed to book expense AP for bill %d: %v", bill.ID, err)
-						} else {
-							log.Printf("Successfully moved reimbursable expense accruals for bill ID %d to AP: $%.2f", bill.ID, float64(totalExpenseAmount)/100)
-						}
+						log.Printf("Successfully moved reimbursable expense accruals for bill ID %d to AP: $%.2f", bill.ID, float64(totalExpenseAmount)/100)
 					}
 				}
 			}
@@ -1921,10 +2134,6 @@ func (a *App) RecordBillCashPayment(bill *Bill, paymentDate time.Time) error {
 					Debit:      totalAmount,
 					Credit:     0,
 				}
-				expenseEntry.CreatedAt = paymentDate
-				if err := a.DB.Create(&expenseEntry).Error; err != nil {
-					return fmt.Errorf("failed to record payroll expense: %w", err)
-				}
 
 				cashEntry := Journal{
 					Account:    AccountCash.String(),
@@ -1934,8 +2143,14 @@ func (a *App) RecordBillCashPayment(bill *Bill, paymentDate time.Time) error {
 					Debit:      0,
 					Credit:     totalAmount,
 				}
-				cashEntry.CreatedAt = paymentDate
-				if err := a.DB.Create(&cashEntry).Error; err != nil {
+				if err := a.PostJournalTransaction(&JournalTransaction{
+					TenantID:      bill.TenantID,
+					EffectiveDate: paymentDate,
+					SourceType:    JournalSourceBill.String(),
+					SourceID:      &bill.ID,
+					Memo:          fmt.Sprintf("Cash paid for bill #%d", bill.ID),
+					Lines:         []Journal{expenseEntry, cashEntry},
+				}); err != nil {
 					return fmt.Errorf("failed to record cash payment: %w", err)
 				}
 				log.Printf("WARNING: Created direct expense-to-cash entries for bill ID %d: $%.2f (DR PAYROLL_EXPENSE, CR CASH)", bill.ID, float64(totalAmount)/100)
@@ -1958,10 +2173,6 @@ func (a *App) RecordBillCashPayment(bill *Bill, paymentDate time.Time) error {
 		Debit:      totalAmount,
 		Credit:     0,
 	}
-	clearLiability.CreatedAt = paymentDate
-	if err := a.DB.Create(&clearLiability).Error; err != nil {
-		return fmt.Errorf("failed to clear %s: %w", clearingAccount, err)
-	}
 
 	// Record cash payment - always use ChaseBusiness subaccount
 	cashEntry := Journal{
@@ -1972,8 +2183,14 @@ func (a *App) RecordBillCashPayment(bill *Bill, paymentDate time.Time) error {
 		Debit:      0,
 		Credit:     totalAmount,
 	}
-	cashEntry.CreatedAt = paymentDate
-	if err := a.DB.Create(&cashEntry).Error; err != nil {
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      bill.TenantID,
+		EffectiveDate: paymentDate,
+		SourceType:    JournalSourceBill.String(),
+		SourceID:      &bill.ID,
+		Memo:          fmt.Sprintf("Cash paid for bill #%d", bill.ID),
+		Lines:         []Journal{clearLiability, cashEntry},
+	}); err != nil {
 		return fmt.Errorf("failed to record cash payment: %w", err)
 	}
 
@@ -2000,18 +2217,28 @@ func (a *App) ReverseInvoiceJournalEntries(invoice *Invoice) error {
 	log.Printf("Found %d journal entries to reverse for invoice ID %d", len(journalEntries), invoice.ID)
 
 	// Create reversing entries (swap debit and credit)
+	reversingEntries := make([]Journal, 0, len(journalEntries))
 	for _, entry := range journalEntries {
-		reversingEntry := Journal{
-			Account:    entry.Account,
-			SubAccount: entry.SubAccount,
-			InvoiceID:  &invoice.ID,
-			Memo:       fmt.Sprintf("VOID: Reverse %s", entry.Memo),
-			Debit:      entry.Credit, // Swap debit and credit
-			Credit:     entry.Debit,
-		}
-		if err := a.DB.Create(&reversingEntry).Error; err != nil {
-			log.Printf("Warning: Failed to create reversing entry for journal ID %d: %v", entry.ID, err)
-		}
+		reversingEntries = append(reversingEntries, Journal{
+			Account:        entry.Account,
+			SubAccount:     entry.SubAccount,
+			InvoiceID:      &invoice.ID,
+			Memo:           fmt.Sprintf("VOID: Reverse %s", entry.Memo),
+			Debit:          entry.Credit, // Swap debit and credit
+			Credit:         entry.Debit,
+			Currency:       entry.Currency,
+			OriginalAmount: entry.OriginalAmount,
+			ExchangeRate:   entry.ExchangeRate,
+		})
+	}
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   invoice.TenantID,
+		SourceType: JournalSourceReversal.String(),
+		SourceID:   &invoice.ID,
+		Memo:       fmt.Sprintf("VOID: Reverse invoice #%d", invoice.ID),
+		Lines:      reversingEntries,
+	}); err != nil {
+		return fmt.Errorf("failed to reverse journal entries: %w", err)
 	}
 
 	log.Printf("Successfully reversed %d journal entries for invoice ID %d", len(journalEntries), invoice.ID)
@@ -2037,18 +2264,28 @@ func (a *App) ReverseBillJournalEntries(bill *Bill) error {
 	log.Printf("Found %d journal entries to reverse for bill ID %d", len(journalEntries), bill.ID)
 
 	// Create reversing entries (swap debit and credit)
+	reversingEntries := make([]Journal, 0, len(journalEntries))
 	for _, entry := range journalEntries {
-		reversingEntry := Journal{
-			Account:    entry.Account,
-			SubAccount: entry.SubAccount,
-			BillID:     &bill.ID,
-			Memo:       fmt.Sprintf("VOID: Reverse %s", entry.Memo),
-			Debit:      entry.Credit, // Swap debit and credit
-			Credit:     entry.Debit,
-		}
-		if err := a.DB.Create(&reversingEntry).Error; err != nil {
-			log.Printf("Warning: Failed to create reversing entry for journal ID %d: %v", entry.ID, err)
-		}
+		reversingEntries = append(reversingEntries, Journal{
+			Account:        entry.Account,
+			SubAccount:     entry.SubAccount,
+			BillID:         &bill.ID,
+			Memo:           fmt.Sprintf("VOID: Reverse %s", entry.Memo),
+			Debit:          entry.Credit, // Swap debit and credit
+			Credit:         entry.Debit,
+			Currency:       entry.Currency,
+			OriginalAmount: entry.OriginalAmount,
+			ExchangeRate:   entry.ExchangeRate,
+		})
+	}
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   bill.TenantID,
+		SourceType: JournalSourceReversal.String(),
+		SourceID:   &bill.ID,
+		Memo:       fmt.Sprintf("VOID: Reverse bill #%d", bill.ID),
+		Lines:      reversingEntries,
+	}); err != nil {
+		return fmt.Errorf("failed to reverse journal entries: %w", err)
 	}
 
 	log.Printf("Successfully reversed %d journal entries for bill ID %d", len(journalEntries), bill.ID)
@@ -2078,46 +2315,62 @@ func (a *App) BookAdjustmentAccrual(adjustment *Adjustment) error {
 		subAccount := fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name)
 		isCredit := adjustment.Type == AdjustmentTypeCredit.String()
 
+		// Adjustments are in the invoice's currency
+		post := func(lines ...Journal) error {
+			for i := range lines {
+				lines[i].translate(invoice.Currency, invoice.ExchangeRate)
+			}
+			return a.PostJournalTransaction(&JournalTransaction{
+				TenantID:   invoice.TenantID,
+				SourceType: JournalSourceAdjustment.String(),
+				SourceID:   &adjustment.ID,
+				Memo:       fmt.Sprintf("Adjustment #%d on invoice #%d", adjustment.ID, invoice.ID),
+				Lines:      lines,
+			})
+		}
+
 		if isCredit {
 			// Credit reduces what we expect to receive
 			// CR: ACCRUED_RECEIVABLES (reduce asset)
 			// DR: CREDITS_ISSUED (contra-revenue)
-			a.DB.Create(&Journal{
+			if err := post(Journal{
 				Account:    string(AccountAccruedReceivables),
 				SubAccount: subAccount,
 				InvoiceID:  adjustment.InvoiceID,
 				Memo:       fmt.Sprintf("Adjustment: Credit issued - %s", adjustment.Notes),
 				Debit:      0,
 				Credit:     amountCents,
-			})
-			a.DB.Create(&Journal{
+			}, Journal{
 				Account:    string(AccountCreditsIssued),
 				SubAccount: subAccount,
 				InvoiceID:  adjustment.InvoiceID,
 				Memo:       fmt.Sprintf("Adjustment: Credit issued - %s", adjustment.Notes),
 				Debit:      amountCents,
 				Credit:     0,
-			})
+			}); err != nil {
+				return fmt.Errorf("failed to book adjustment: %w", err)
+			}
 		} else {
 			// Fee increases what we expect to receive
 			// DR: ACCRUED_RECEIVABLES (increase asset)
 			// CR: ADJUSTMENT_REVENUE
-			a.DB.Create(&Journal{
+			if err := post(Journal{
 				Account:    string(AccountAccruedReceivables),
 				SubAccount: subAccount,
 				InvoiceID:  adjustment.InvoiceID,
 				Memo:       fmt.Sprintf("Adjustment: Fee added - %s", adjustment.Notes),
 				Debit:      amountCents,
 				Credit:     0,
-			})
-			a.DB.Create(&Journal{
+			}, Journal{
 				Account:    string(AccountAdjustmentRevenue),
 				SubAccount: subAccount,
 				InvoiceID:  adjustment.InvoiceID,
 				Memo:       fmt.Sprintf("Adjustment: Fee added - %s", adjustment.Notes),
 				Debit:      0,
 				Credit:     amountCents,
-			})
+			}); err != nil {
+				return fmt.Errorf("failed to book adjustment: %w", err)
+			}
 		}
 
 		log.Printf("Recorded invoice adjustment accrual for adjustment ID %d: $%.2f", adjustment.ID, adjustment.Amount)
@@ -2132,24 +2385,34 @@ func (a *App) BookAdjustmentAccrual(adjustment *Adjustment) error {
 		}
 
 		subAccount := fmt.Sprintf("%d:%s %s", bill.EmployeeID, bill.Employee.FirstName, bill.Employee.LastName)
+		post := func(lines ...Journal) error {
+			return a.PostJournalTransaction(&JournalTransaction{
+				TenantID:   bill.TenantID,
+				SourceType: JournalSourceAdjustment.String(),
+				SourceID:   &adjustment.ID,
+				Memo:       fmt.Sprintf("Adjustment #%d on bill #%d", adjustment.ID, bill.ID),
+				Lines:      lines,
+			})
+		}
 
 		// DR: ADJUSTMENT_EXPENSE, CR: ACCRUED_PAYROLL
-		a.DB.Create(&Journal{
+		if err := post(Journal{
 			Account:    string(AccountAdjustmentExpense),
 			SubAccount: subAccount,
 			BillID:     adjustment.BillID,
 			Memo:       fmt.Sprintf("Adjustment: Expense addition - %s", adjustment.Notes),
 			Debit:      amountCents,
 			Credit:     0,
-		})
-		a.DB.Create(&Journal{
+		}, Journal{
 			Account:    string(AccountAccruedPayroll),
 			SubAccount: subAccount,
 			BillID:     adjustment.BillID,
 			Memo:       fmt.Sprintf("Adjustment: Accrued payroll - %s", adjustment.Notes),
 			Debit:      0,
 			Credit:     amountCents,
-		})
+		}); err != nil {
+			return fmt.Errorf("failed to book adjustment: %w", err)
+		}
 
 		log.Printf("Recorded bill adjustment accrual for adjustment ID %d: $%.2f", adjustment.ID, adjustment.Amount)
 		return nil
@@ -2188,6 +2451,19 @@ func (a *App) RecordAdjustmentJournal(adjustment *Adjustment) error {
 			revenueAccount = AccountAdjustmentRevenue
 		}
 
+		post := func(lines ...Journal) error {
+			for i := range lines {
+				lines[i].translate(invoice.Currency, invoice.ExchangeRate)
+			}
+			return a.PostJournalTransaction(&JournalTransaction{
+				TenantID:   invoice.TenantID,
+				SourceType: JournalSourceAdjustment.String(),
+				SourceID:   &adjustment.ID,
+				Memo:       fmt.Sprintf("Adjustment #%d on invoice #%d", adjustment.ID, invoice.ID),
+				Lines:      lines,
+			})
+		}
+
 		// Book based on invoice state
 		switch invoice.State {
 		case InvoiceStateDraft.String():
@@ -2201,84 +2477,88 @@ func (a *App) RecordAdjustmentJournal(adjustment *Adjustment) error {
 				// Credit reduces what we expect to receive
 				// CR: ACCRUED_RECEIVABLES (reduce asset)
 				// DR: CREDITS_ISSUED (contra-revenue)
-				a.DB.Create(&Journal{
+				if err := post(Journal{
 					Account:    string(AccountAccruedReceivables),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Credit issued - %s", adjustment.Notes),
 					Debit:      0,
 					Credit:     amountCents,
-				})
-				a.DB.Create(&Journal{
+				}, Journal{
 					Account:    string(revenueAccount),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Credit issued - %s", adjustment.Notes),
 					Debit:      amountCents,
 					Credit:     0,
-				})
+				}); err != nil {
+					return fmt.Errorf("failed to book adjustment: %w", err)
+				}
 			} else {
 				// Fee increases what we expect to receive
 				// DR: ACCRUED_RECEIVABLES (increase asset)
 				// CR: ADJUSTMENT_REVENUE
-				a.DB.Create(&Journal{
+				if err := post(Journal{
 					Account:    string(AccountAccruedReceivables),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Fee added - %s", adjustment.Notes),
 					Debit:      amountCents,
 					Credit:     0,
-				})
-				a.DB.Create(&Journal{
+				}, Journal{
 					Account:    string(revenueAccount),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Fee added - %s", adjustment.Notes),
 					Debit:      0,
 					Credit:     amountCents,
-				})
+				}); err != nil {
+					return fmt.Errorf("failed to book adjustment: %w", err)
+				}
 			}
 
-		case InvoiceStateSent.String():
+		case InvoiceStateSent.String(), InvoiceStatePartiallyPaid.String():
 			// Sent: Book to accounts receivable
 			if isCredit {
 				// CR: ACCOUNTS_RECEIVABLE (reduce asset)
 				// DR: CREDITS_ISSUED
-				a.DB.Create(&Journal{
+				if err := post(Journal{
 					Account:    string(AccountAccountsReceivable),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Credit issued - %s", adjustment.Notes),
 					Debit:      0,
 					Credit:     amountCents,
-				})
-				a.DB.Create(&Journal{
+				}, Journal{
 					Account:    string(revenueAccount),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Credit issued - %s", adjustment.Notes),
 					Debit:      amountCents,
 					Credit:     0,
-				})
+				}); err != nil {
+					return fmt.Errorf("failed to book adjustment: %w", err)
+				}
 			} else {
 				// DR: ACCOUNTS_RECEIVABLE (increase asset)
 				// CR: ADJUSTMENT_REVENUE
-				a.DB.Create(&Journal{
+				if err := post(Journal{
 					Account:    string(AccountAccountsReceivable),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Fee added - %s", adjustment.Notes),
 					Debit:      amountCents,
 					Credit:     0,
-				})
-				a.DB.Create(&Journal{
+				}, Journal{
 					Account:    string(revenueAccount),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Fee added - %s", adjustment.Notes),
 					Debit:      0,
 					Credit:     amountCents,
-				})
+				}); err != nil {
+					return fmt.Errorf("failed to book adjustment: %w", err)
+				}
 			}
 
 		case InvoiceStatePaid.String():
@@ -2287,41 +2567,43 @@ func (a *App) RecordAdjustmentJournal(adjustment *Adjustment) error {
 			if isCredit {
 				// We're giving back cash (refund) and reducing revenue
 				// DR: CREDITS_ISSUED, CR: CASH
-				a.DB.Create(&Journal{
+				if err := post(Journal{
 					Account:    string(revenueAccount),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Credit issued on paid invoice - %s", adjustment.Notes),
 					Debit:      amountCents,
 					Credit:     0,
-				})
-				a.DB.Create(&Journal{
+				}, Journal{
 					Account:    string(AccountCash),
 					SubAccount: "ChaseBusiness",
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Refund for credit - %s", adjustment.Notes),
 					Debit:      0,
 					Credit:     amountCents,
-				})
+				}); err != nil {
+					return fmt.Errorf("failed to book adjustment: %w", err)
+				}
 			} else {
 				// We're receiving additional cash and increasing revenue
 				// DR: CASH, CR: ADJUSTMENT_REVENUE
-				a.DB.Create(&Journal{
+				if err := post(Journal{
 					Account:    string(AccountCash),
 					SubAccount: "ChaseBusiness",
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Additional payment for fee - %s", adjustment.Notes),
 					Debit:      amountCents,
 					Credit:     0,
-				})
-				a.DB.Create(&Journal{
+				}, Journal{
 					Account:    string(revenueAccount),
 					SubAccount: subAccount,
 					InvoiceID:  adjustment.InvoiceID,
 					Memo:       fmt.Sprintf("Adjustment: Fee added to paid invoice - %s", adjustment.Notes),
 					Debit:      0,
 					Credit:     amountCents,
-				})
+				}); err != nil {
+					return fmt.Errorf("failed to book adjustment: %w", err)
+				}
 			}
 		}
 
@@ -2338,6 +2620,15 @@ func (a *App) RecordAdjustmentJournal(adjustment *Adjustment) error {
 		}
 
 		subAccount := fmt.Sprintf("%d:%s %s", bill.EmployeeID, bill.Employee.FirstName, bill.Employee.LastName)
+		post := func(lines ...Journal) error {
+			return a.PostJournalTransaction(&JournalTransaction{
+				TenantID:   bill.TenantID,
+				SourceType: JournalSourceAdjustment.String(),
+				SourceID:   &adjustment.ID,
+				Memo:       fmt.Sprintf("Adjustment #%d on bill #%d", adjustment.ID, bill.ID),
+				Lines:      lines,
+			})
+		}
 
 		// Determine bill state (draft, accepted, paid)
 		isDraft := bill.AcceptedAt == nil || bill.AcceptedAt.IsZero()
@@ -2350,41 +2641,43 @@ func (a *App) RecordAdjustmentJournal(adjustment *Adjustment) error {
 		} else if isPaid {
 			// Paid: Book as additional expense and cash payment
 			// DR: ADJUSTMENT_EXPENSE, CR: CASH
-			a.DB.Create(&Journal{
+			if err := post(Journal{
 				Account:    string(AccountAdjustmentExpense),
 				SubAccount: subAccount,
 				BillID:     adjustment.BillID,
 				Memo:       fmt.Sprintf("Adjustment: Expense on paid bill - %s", adjustment.Notes),
 				Debit:      amountCents,
 				Credit:     0,
-			})
-			a.DB.Create(&Journal{
+			}, Journal{
 				Account:    string(AccountCash),
 				SubAccount: "ChaseBusiness",
 				BillID:     adjustment.BillID,
 				Memo:       fmt.Sprintf("Adjustment: Additional payment - %s", adjustment.Notes),
 				Debit:      0,
 				Credit:     amountCents,
-			})
+			}); err != nil {
+				return fmt.Errorf("failed to book adjustment: %w", err)
+			}
 		} else {
 			// Accepted but unpaid: Book to accounts payable
 			// DR: ADJUSTMENT_EXPENSE, CR: ACCOUNTS_PAYABLE
-			a.DB.Create(&Journal{
+			if err := post(Journal{
 				Account:    string(AccountAdjustmentExpense),
 				SubAccount: subAccount,
 				BillID:     adjustment.BillID,
 				Memo:       fmt.Sprintf("Adjustment: Expense addition - %s", adjustment.Notes),
 				Debit:      amountCents,
 				Credit:     0,
-			})
-			a.DB.Create(&Journal{
+			}, Journal{
 				Account:    string(AccountAccountsPayable),
 				SubAccount: subAccount,
 				BillID:     adjustment.BillID,
 				Memo:       fmt.Sprintf("Adjustment: AP addition - %s", adjustment.Notes),
 				Debit:      0,
 				Credit:     amountCents,
-			})
+			}); err != nil {
+				return fmt.Errorf("failed to book adjustment: %w", err)
+			}
 		}
 
 		log.Printf("Recorded bill adjustment journal for bill ID %d: $%.2f", bill.ID, adjustment.Amount)
@@ -2394,6 +2687,52 @@ func (a *App) RecordAdjustmentJournal(adjustment *Adjustment) error {
 	return fmt.Errorf("adjustment must have either invoice_id or bill_id")
 }
 
+// VoidAdjustment voids an adjustment, reversing the journals booked for it as of voidDate. The reversal
+// and the state change are saved together, and a reversal dated in a locked period is refused with
+// ErrPeriodLocked.
+func (a *App) VoidAdjustment(adjustment *Adjustment, voidDate time.Time) error {
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		var booked []Journal
+		if err := tx.Where("tenant_id = ? AND transaction_id IN (?)", adjustment.TenantID,
+			tx.Model(&JournalTransaction{}).Select("id").
+				Where("source_type = ? AND source_id = ?", JournalSourceAdjustment.String(), adjustment.ID)).
+			Order("id").Find(&booked).Error; err != nil {
+			return fmt.Errorf("failed to load adjustment journals: %w", err)
+		}
+		if len(booked) > 0 {
+			txn := JournalTransaction{
+				TenantID:      adjustment.TenantID,
+				EffectiveDate: voidDate,
+				SourceType:    JournalSourceAdjustment.String(),
+				SourceID:      &adjustment.ID,
+				Memo:          fmt.Sprintf("VOID: Adjustment #%d", adjustment.ID),
+			}
+			for _, journal := range booked {
+				txn.Lines = append(txn.Lines, Journal{
+					Account:        journal.Account,
+					SubAccount:     journal.SubAccount,
+					InvoiceID:      journal.InvoiceID,
+					BillID:         journal.BillID,
+					Memo:           fmt.Sprintf("VOID: Reverse %s", journal.Memo),
+					Debit:          journal.Credit, // Swap
+					Credit:         journal.Debit,
+					Currency:       journal.Currency,
+					OriginalAmount: journal.OriginalAmount,
+					ExchangeRate:   journal.ExchangeRate,
+				})
+			}
+			if err := a.postJournalTransaction(tx, &txn); err != nil {
+				return fmt.Errorf("failed to reverse adjustment journals: %w", err)
+			}
+		}
+		adjustment.State = AdjustmentStateVoid.String()
+		if err := tx.Model(adjustment).Update("state", adjustment.State).Error; err != nil {
+			return fmt.Errorf("failed to void adjustment: %w", err)
+		}
+		return nil
+	})
+}
+
 // BookExpenseAccrual books the accrual for a pass-through expense when invoice is approved
 // For pass-through expenses, we book revenue (since client will reimburse) and track the expense separately
 func (a *App) BookExpenseAccrual(expense *Expense, invoice *Invoice) error {
@@ -2443,9 +2782,6 @@ func (a *App) BookExpenseAccrual(expense *Expense, invoice *Invoice) error {
 		Debit:      amountCents,
 		Credit:     0,
 	}
-	if err := a.DB.Create(&revenueAR).Error; err != nil {
-		return fmt.Errorf("failed to book expense AR debit: %w", err)
-	}
 
 	// CR: REVENUE (revenue from expense reimbursement)
 	revenueCR := Journal{
@@ -2456,9 +2792,6 @@ func (a *App) BookExpenseAccrual(expense *Expense, invoice *Invoice) error {
 		Debit:      0,
 		Credit:     amountCents,
 	}
-	if err := a.DB.Create(&revenueCR).Error; err != nil {
-		return fmt.Errorf("failed to book expense revenue credit: %w", err)
-	}
 
 	// Also book the expense side (we paid for this)
 	// DR: [Expense Account - configurable]
@@ -2470,9 +2803,6 @@ func (a *App) BookExpenseAccrual(expense *Expense, invoice *Invoice) error {
 		Debit:      amountCents,
 		Credit:     0,
 	}
-	if err := a.DB.Create(&expenseDR).Error; err != nil {
-		return fmt.Errorf("failed to book pass-through expense debit: %w", err)
-	}
 
 	// CR: ACCRUED_EXPENSES_PAYABLE (contra account - will be cleared when reconciled with bank statement)
 	// We DON'T book to the actual payment account (Cash/Credit Card) until we reconcile
@@ -2487,8 +2817,14 @@ func (a *App) BookExpenseAccrual(expense *Expense, invoice *Invoice) error {
 		Debit:      0,
 		Credit:     amountCents,
 	}
-	if err := a.DB.Create(&paymentCR).Error; err != nil {
-		return fmt.Errorf("failed to book expense payment credit: %w", err)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   invoice.TenantID,
+		SourceType: JournalSourceExpense.String(),
+		SourceID:   &expense.ID,
+		Memo:       fmt.Sprintf("Pass-through expense: %s", expense.Description),
+		Lines:      []Journal{revenueAR, revenueCR, expenseDR, paymentCR},
+	}); err != nil {
+		return fmt.Errorf("failed to book pass-through expense: %w", err)
 	}
 
 	log.Printf("Booked expense accrual for expense ID %d: account=%s, subaccount=%s, amount=$%.2f",
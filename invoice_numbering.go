@@ -0,0 +1,151 @@
+package cronos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errInvoiceAlreadyNumbered rolls back an assignment that lost the race to number the invoice
+var errInvoiceAlreadyNumbered = errors.New("invoice already has a number")
+
+const defaultInvoiceNumberPattern = "{PREFIX}-{YYYY}-{SEQ:5}"
+const defaultInvoiceNumberPrefix = "INV"
+
+// Invoice numbers can be assigned when the invoice is approved or held back until it is sent
+const InvoiceNumberAssignAtApprove = "approve"
+const InvoiceNumberAssignAtSend = "send"
+
+var invoiceNumberTokenPattern = regexp.MustCompile(`\{(PREFIX|YYYY|YY|MM|SEQ)(?::(\d+))?\}`)
+
+// InvoiceNumberSettings are the invoice numbering options read from Tenant.Settings
+type InvoiceNumberSettings struct {
+	Pattern  string `json:"invoice_number_pattern"`   // e.g. {PREFIX}-{YYYY}-{SEQ:5}
+	Prefix   string `json:"invoice_number_prefix"`    // substituted for {PREFIX}
+	AssignAt string `json:"invoice_number_assign_at"` // "approve" (default) or "send"
+}
+
+// GetInvoiceNumberSettings reads the tenant's numbering settings, falling back to the defaults
+func GetInvoiceNumberSettings(tenant *Tenant) InvoiceNumberSettings {
+	var settings InvoiceNumberSettings
+	if len(tenant.Settings) > 0 {
+		if err := json.Unmarshal(tenant.Settings, &settings); err != nil {
+			log.Printf("Warning: invalid settings for tenant %d, using default invoice numbering: %v", tenant.ID, err)
+		}
+	}
+	if settings.Pattern == "" {
+		settings.Pattern = defaultInvoiceNumberPattern
+	}
+	if settings.Prefix == "" {
+		settings.Prefix = defaultInvoiceNumberPrefix
+	}
+	if settings.AssignAt != InvoiceNumberAssignAtSend {
+		settings.AssignAt = InvoiceNumberAssignAtApprove
+	}
+	return settings
+}
+
+// invoiceNumberScope returns the sequence a number belongs to. Patterns with a month restart every
+// month, patterns with a year every year, and anything else counts up forever.
+func invoiceNumberScope(pattern string, date time.Time) string {
+	switch {
+	case strings.Contains(pattern, "{MM}"):
+		return date.Format("2006-01")
+	case strings.Contains(pattern, "{YYYY}") || strings.Contains(pattern, "{YY}"):
+		return date.Format("2006")
+	default:
+		return "all"
+	}
+}
+
+// FormatInvoiceNumber renders a numbering pattern for the given sequence value and date
+func FormatInvoiceNumber(settings InvoiceNumberSettings, seq int64, date time.Time) string {
+	return invoiceNumberTokenPattern.ReplaceAllStringFunc(settings.Pattern, func(token string) string {
+		parts := invoiceNumberTokenPattern.FindStringSubmatch(token)
+		switch parts[1] {
+		case "PREFIX":
+			return settings.Prefix
+		case "YYYY":
+			return date.Format("2006")
+		case "YY":
+			return date.Format("06")
+		case "MM":
+			return date.Format("01")
+		default:
+			width, _ := strconv.Atoi(parts[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+	})
+}
+
+// AssignInvoiceNumber gives an invoice the next number in its tenant's sequence. The sequence is advanced
+// and the number saved in one transaction, so numbers are gap-free and never handed out twice; invoices
+// keep their number if they are later voided. Invoices that already have a number are left alone.
+func (a *App) AssignInvoiceNumber(invoice *Invoice, date time.Time) error {
+	if invoice.Number != "" {
+		return nil
+	}
+
+	// A missing tenant row just means default settings
+	var tenant Tenant
+	if err := a.DB.Limit(1).Find(&tenant, invoice.TenantID).Error; err != nil {
+		return fmt.Errorf("failed to load tenant %d: %w", invoice.TenantID, err)
+	}
+	tenant.ID = invoice.TenantID
+	settings := GetInvoiceNumberSettings(&tenant)
+	scope := invoiceNumberScope(settings.Pattern, date)
+
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		sequence := InvoiceNumberSequence{TenantID: tenant.ID, Scope: scope, NextValue: 1}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error; err != nil {
+			return fmt.Errorf("failed to create invoice number sequence: %w", err)
+		}
+		// The increment takes a row lock, so concurrent assignments queue behind each other
+		if err := tx.Model(&InvoiceNumberSequence{}).
+			Where("tenant_id = ? AND scope = ?", tenant.ID, scope).
+			Update("next_value", gorm.Expr("next_value + 1")).Error; err != nil {
+			return fmt.Errorf("failed to advance invoice number sequence: %w", err)
+		}
+		if err := tx.Where("tenant_id = ? AND scope = ?", tenant.ID, scope).First(&sequence).Error; err != nil {
+			return fmt.Errorf("failed to read invoice number sequence: %w", err)
+		}
+
+		// Only an unnumbered invoice takes the number. If another request numbered it first, rolling
+		// back hands the sequence value back so no gap is left.
+		number := FormatInvoiceNumber(settings, sequence.NextValue-1, date)
+		result := tx.Model(&Invoice{}).Where("id = ? AND (number = '' OR number IS NULL)", invoice.ID).Update("number", number)
+		if result.Error != nil {
+			return fmt.Errorf("failed to save invoice number: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errInvoiceAlreadyNumbered
+		}
+		invoice.Number = number
+		log.Printf("Assigned invoice number %s to invoice ID: %d", number, invoice.ID)
+		return nil
+	})
+	if errors.Is(err, errInvoiceAlreadyNumbered) {
+		var current Invoice
+		if err := a.DB.Select("id", "number").First(&current, invoice.ID).Error; err != nil {
+			return fmt.Errorf("failed to load invoice %d: %w", invoice.ID, err)
+		}
+		invoice.Number = current.Number
+		return nil
+	}
+	return err
+}
+
+// shouldAssignInvoiceNumberAt reports whether the tenant numbers invoices at the given step
+func (a *App) shouldAssignInvoiceNumberAt(tenantID uint, step string) bool {
+	var tenant Tenant
+	a.DB.Limit(1).Find(&tenant, tenantID)
+	return GetInvoiceNumberSettings(&tenant).AssignAt == step
+}
@@ -1,6 +1,7 @@
 package cronos
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -398,3 +399,108 @@ func TestGetInvoiceLineItems(t *testing.T) {
 		}
 	}
 }
+
+// TestAssignInvoiceNumber tests that invoice numbers follow the tenant pattern and are never reissued
+func TestAssignInvoiceNumber(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{
+		Slug:     "numbering",
+		Name:     "Numbering Tenant",
+		Settings: []byte(`{"invoice_number_pattern": "{PREFIX}/{YY}/{SEQ:4}", "invoice_number_prefix": "SNW"}`),
+	}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	date := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var numbers []string
+	for i := 0; i < 3; i++ {
+		invoice := Invoice{TenantID: tenant.ID, Name: "Numbered", State: InvoiceStateDraft.String()}
+		db.Create(&invoice)
+		if err := app.AssignInvoiceNumber(&invoice, date); err != nil {
+			t.Fatalf("Failed to assign invoice number: %v", err)
+		}
+		numbers = append(numbers, invoice.Number)
+
+		// Voiding keeps the number, and assigning again is a no-op that doesn't use up a sequence value
+		db.Model(&invoice).Update("state", InvoiceStateVoid.String())
+		invoice.Number = ""
+		if err := app.AssignInvoiceNumber(&invoice, date); err != nil {
+			t.Fatalf("Failed to re-check invoice number: %v", err)
+		}
+		if invoice.Number != numbers[i] {
+			t.Errorf("Expected invoice to keep number %s, got %s", numbers[i], invoice.Number)
+		}
+	}
+
+	expected := []string{"SNW/25/0001", "SNW/25/0002", "SNW/25/0003"}
+	for i := range expected {
+		if numbers[i] != expected[i] {
+			t.Errorf("Expected number %s, got %s", expected[i], numbers[i])
+		}
+	}
+
+	// A new year starts a new sequence
+	next := Invoice{TenantID: tenant.ID, Name: "Next Year"}
+	db.Create(&next)
+	if err := app.AssignInvoiceNumber(&next, date.AddDate(1, 0, 0)); err != nil {
+		t.Fatalf("Failed to assign invoice number: %v", err)
+	}
+	if next.Number != "SNW/26/0001" {
+		t.Errorf("Expected SNW/26/0001, got %s", next.Number)
+	}
+
+	// Tenants without settings get the default pattern
+	if got := FormatInvoiceNumber(GetInvoiceNumberSettings(&Tenant{}), 42, date); got != "INV-2025-00042" {
+		t.Errorf("Expected INV-2025-00042, got %s", got)
+	}
+}
+
+// TestVoidAdjustment tests that voiding an adjustment reverses its journals in one balanced
+// transaction dated the day it's voided, and that a locked period refuses the void
+func TestVoidAdjustment(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "void-adjustment", Name: "Void Adjustment Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	adjustment := Adjustment{TenantID: tenant.ID, Type: AdjustmentTypeCredit.String(), State: AdjustmentStateApproved.String(), Amount: 50}
+	db.Create(&adjustment)
+	booked := JournalTransaction{TenantID: tenant.ID, EffectiveDate: time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC),
+		SourceType: JournalSourceAdjustment.String(), SourceID: &adjustment.ID, Lines: []Journal{
+			{Account: AccountCreditsIssued.String(), Debit: 5000},
+			{Account: AccountAccruedReceivables.String(), Credit: 5000},
+		}}
+	if err := app.PostJournalTransaction(&booked); err != nil {
+		t.Fatalf("Failed to book adjustment: %v", err)
+	}
+
+	// A void dated in a closed period is refused and leaves the adjustment standing
+	closed, _ := app.OpenAccountingPeriod(tenant.ID, booked.EffectiveDate)
+	db.Model(closed).Update("state", AccountingPeriodStateClosed.String())
+	if err := app.VoidAdjustment(&adjustment, booked.EffectiveDate); !errors.Is(err, ErrPeriodLocked) {
+		t.Errorf("Expected a void into a closed period to be refused, got %v", err)
+	}
+	if adjustment.State != AdjustmentStateApproved.String() {
+		t.Errorf("Expected the refused adjustment to stay approved, got %s", adjustment.State)
+	}
+
+	voided := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	if err := app.VoidAdjustment(&adjustment, voided); err != nil {
+		t.Fatalf("Failed to void adjustment: %v", err)
+	}
+	var reversal JournalTransaction
+	db.Preload("Lines").Where("id != ?", booked.ID).First(&reversal)
+	if !reversal.EffectiveDate.Equal(voided) || len(reversal.Lines) != 2 || reversal.Lines[0].Credit != 5000 ||
+		!reversal.Lines[0].EffectiveDate.Equal(voided) {
+		t.Errorf("Expected a reversal dated %s, got %+v", voided.Format("2006-01-02"), reversal)
+	}
+	db.First(&adjustment, adjustment.ID)
+	if adjustment.State != AdjustmentStateVoid.String() {
+		t.Errorf("Expected the adjustment voided, got %s", adjustment.State)
+	}
+}
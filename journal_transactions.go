@@ -0,0 +1,136 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrUnbalancedTransaction = errors.New("journal transaction does not balance")
+var ErrEmptyTransaction = errors.New("journal transaction has no lines")
+var ErrNegativeJournalAmount = errors.New("journal lines cannot have negative amounts")
+var ErrJournalTransactionNotFound = errors.New("journal transaction not found")
+
+// ValidateJournalLines checks that a set of lines can be posted: there is at least one line, no
+// negative amounts, and debits equal credits
+func ValidateJournalLines(lines []Journal) error {
+	if len(lines) == 0 {
+		return ErrEmptyTransaction
+	}
+	var debits, credits int64
+	for _, line := range lines {
+		if line.Debit < 0 || line.Credit < 0 {
+			return ErrNegativeJournalAmount
+		}
+		debits += line.Debit
+		credits += line.Credit
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: debits $%.2f, credits $%.2f", ErrUnbalancedTransaction, float64(debits)/100, float64(credits)/100)
+	}
+	return nil
+}
+
+// PostJournalTransaction saves a transaction and its lines together, refusing it if the lines don't
+// net to zero or its effective date falls in a locked accounting period. Each line takes the
+// transaction's tenant and effective date, which defaults to now.
+// All-zero lines are dropped so callers can pass optional legs without checking them first.
+func (a *App) PostJournalTransaction(txn *JournalTransaction) error {
+	return a.postJournalTransaction(a.DB, txn)
+}
+
+func (a *App) postJournalTransaction(db *gorm.DB, txn *JournalTransaction) error {
+	lines := make([]Journal, 0, len(txn.Lines))
+	for _, line := range txn.Lines {
+		if line.Debit != 0 || line.Credit != 0 {
+			lines = append(lines, line)
+		}
+	}
+	if err := ValidateJournalLines(lines); err != nil {
+		return err
+	}
+	if txn.EffectiveDate.IsZero() {
+		txn.EffectiveDate = time.Now()
+	}
+	overridden, err := a.checkPeriodLock(db, txn)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		txn.Lines = nil
+		if err := tx.Create(txn).Error; err != nil {
+			return fmt.Errorf("failed to create journal transaction: %w", err)
+		}
+		for i := range lines {
+			lines[i].TenantID = txn.TenantID
+			lines[i].TransactionID = &txn.ID
+			lines[i].EffectiveDate = txn.EffectiveDate
+			if lines[i].Memo == "" {
+				lines[i].Memo = txn.Memo
+			}
+		}
+		if err := tx.Create(&lines).Error; err != nil {
+			return fmt.Errorf("failed to create journal lines: %w", err)
+		}
+		txn.Lines = lines
+
+		if overridden != nil {
+			override := PeriodOverride{
+				TenantID:      txn.TenantID,
+				PeriodID:      overridden.ID,
+				TransactionID: txn.ID,
+				EffectiveDate: txn.EffectiveDate,
+				SourceType:    txn.SourceType,
+				UserID:        a.periodOverride.userID,
+				Reason:        a.periodOverride.reason,
+			}
+			if err := tx.Create(&override).Error; err != nil {
+				return fmt.Errorf("failed to log period override: %w", err)
+			}
+			log.Printf("Period override: user %d posted transaction %d into soft-closed %s (%s)",
+				override.UserID, txn.ID, overridden.PeriodStart.Format("2006-01"), override.Reason)
+		}
+		return nil
+	})
+}
+
+// GetJournalTransaction loads a transaction with its lines
+func (a *App) GetJournalTransaction(tenantID, id uint) (*JournalTransaction, error) {
+	var txn JournalTransaction
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Lines").First(&txn, id).Error; err != nil {
+		return nil, ErrJournalTransactionNotFound
+	}
+	return &txn, nil
+}
+
+// ListJournalTransactions returns a tenant's transactions with an effective date in [start, end)
+func (a *App) ListJournalTransactions(tenantID uint, start, end time.Time, sourceType string) ([]JournalTransaction, error) {
+	query := a.DB.Scopes(TenantScope(tenantID)).Preload("Lines").
+		Where("effective_date >= ? AND effective_date < ?", start, end)
+	if sourceType != "" {
+		query = query.Where("source_type = ?", sourceType)
+	}
+	var txns []JournalTransaction
+	if err := query.Order("effective_date asc, id asc").Find(&txns).Error; err != nil {
+		return nil, fmt.Errorf("failed to load journal transactions: %w", err)
+	}
+	return txns, nil
+}
+
+// VerifyJournalTransactionBalances returns the IDs of a tenant's transactions whose lines don't net to
+// zero, which can only happen if lines were edited or deleted outside PostJournalTransaction
+func (a *App) VerifyJournalTransactionBalances(tenantID uint) ([]uint, error) {
+	var ids []uint
+	if err := a.DB.Table("journals").Scopes(TenantScope(tenantID)).
+		Where("transaction_id IS NOT NULL AND deleted_at IS NULL").
+		Group("transaction_id").
+		Having("SUM(debit) <> SUM(credit)").
+		Pluck("transaction_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to check transaction balances: %w", err)
+	}
+	return ids, nil
+}
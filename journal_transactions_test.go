@@ -0,0 +1,111 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestJournalTransactions tests that transactions must balance, that lines take the transaction's
+// effective date, and that reversals and offline postings go through as whole transactions
+func TestJournalTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "ledger", Name: "Ledger Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	unbalanced := JournalTransaction{TenantID: tenant.ID, SourceType: JournalSourceManual.String(), Lines: []Journal{
+		{Account: AccountCash.String(), Debit: 10000},
+		{Account: AccountRevenue.String(), Credit: 9000},
+	}}
+	if err := app.PostJournalTransaction(&unbalanced); !errors.Is(err, ErrUnbalancedTransaction) {
+		t.Fatalf("Expected an unbalanced transaction to be refused, got %v", err)
+	}
+	var count int64
+	db.Model(&Journal{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("Expected nothing booked for a refused transaction, got %d lines", count)
+	}
+
+	effective := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	txn := JournalTransaction{TenantID: tenant.ID, EffectiveDate: effective, SourceType: JournalSourceManual.String(),
+		Memo: "January accrual", Lines: []Journal{
+			{Account: AccountPayrollExpense.String(), Debit: 25000},
+			{Account: AccountAccruedExpensesPayable.String(), Credit: 25000, Memo: "Accrued wages"},
+			{Account: AccountCash.String()}, // empty legs are dropped
+		}}
+	if err := app.PostJournalTransaction(&txn); err != nil {
+		t.Fatalf("Failed to post transaction: %v", err)
+	}
+
+	loaded, err := app.GetJournalTransaction(tenant.ID, txn.ID)
+	if err != nil {
+		t.Fatalf("Failed to load transaction: %v", err)
+	}
+	if len(loaded.Lines) != 2 {
+		t.Fatalf("Expected the empty line to be dropped, got %d lines", len(loaded.Lines))
+	}
+	for _, line := range loaded.Lines {
+		if line.TenantID != tenant.ID || !line.EffectiveDate.Equal(effective) {
+			t.Errorf("Expected line %d to take the transaction's tenant and date, got %d and %s", line.ID, line.TenantID, line.EffectiveDate)
+		}
+	}
+	if loaded.Lines[0].Memo != "January accrual" || loaded.Lines[1].Memo != "Accrued wages" {
+		t.Errorf("Expected lines without a memo to use the transaction's, got %q and %q", loaded.Lines[0].Memo, loaded.Lines[1].Memo)
+	}
+
+	// Reports pick lines up by effective date, not when they were saved
+	journals, _ := app.GetCombinedJournals(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	if len(journals) != 2 {
+		t.Errorf("Expected both lines in January, got %d", len(journals))
+	}
+
+	// Reversing one line without a correction reverses the whole transaction
+	if err := app.ReverseJournalEntry(loaded.Lines[0].ID, "Booked in error", nil); err != nil {
+		t.Fatalf("Failed to reverse entry: %v", err)
+	}
+	reversals, _ := app.ListJournalTransactions(tenant.ID, time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 1), JournalSourceReversal.String())
+	if len(reversals) != 1 || len(reversals[0].Lines) != 2 {
+		t.Fatalf("Expected one two-line reversal, got %+v", reversals)
+	}
+	var net int64
+	db.Model(&Journal{}).Select("COALESCE(SUM(debit - credit), 0)").Where("account = ?", AccountPayrollExpense.String()).Scan(&net)
+	if net != 0 {
+		t.Errorf("Expected the expense to be fully reversed, got %d", net)
+	}
+
+	// Offline journals post as one transaction per group, backdated to the bank date
+	bankDate := time.Date(2025, 2, 14, 0, 0, 0, 0, time.UTC)
+	offline := []OfflineJournal{
+		{TenantID: tenant.ID, Date: bankDate, Account: AccountPayrollExpense.String(), Description: "Software", Debit: 4900,
+			TransactionGroupID: "grp-1", ContentHash: "a", Status: "approved"},
+		{TenantID: tenant.ID, Date: bankDate, Account: AccountCash.String(), Description: "Software", Credit: 4900,
+			TransactionGroupID: "grp-1", ContentHash: "b", Status: "approved"},
+		{TenantID: tenant.ID, Date: bankDate, Account: AccountCash.String(), Description: "Half a transfer", Credit: 100,
+			TransactionGroupID: "grp-2", ContentHash: "c", Status: "approved"},
+	}
+	db.Create(&offline)
+	if err := app.PostOfflineJournalsToGL([]uint{offline[0].ID, offline[1].ID, offline[2].ID}); !errors.Is(err, ErrUnbalancedTransaction) {
+		t.Fatalf("Expected the one-sided group to be refused, got %v", err)
+	}
+	if err := app.PostOfflineJournalsToGL([]uint{offline[0].ID, offline[1].ID}); err != nil {
+		t.Fatalf("Failed to post offline journals: %v", err)
+	}
+	posted, _ := app.ListJournalTransactions(tenant.ID, bankDate, bankDate.AddDate(0, 0, 1), JournalSourceOfflineJournal.String())
+	if len(posted) != 1 || len(posted[0].Lines) != 2 {
+		t.Fatalf("Expected one two-line transaction on the bank date, got %+v", posted)
+	}
+
+	unbalancedIDs, err := app.VerifyJournalTransactionBalances(tenant.ID)
+	if err != nil || len(unbalancedIDs) != 0 {
+		t.Errorf("Expected every transaction to balance, got %v: %v", unbalancedIDs, err)
+	}
+	db.Model(&Journal{}).Where("id = ?", posted[0].Lines[0].ID).Update("debit", 5000)
+	unbalancedIDs, _ = app.VerifyJournalTransactionBalances(tenant.ID)
+	if len(unbalancedIDs) != 1 || unbalancedIDs[0] != posted[0].ID {
+		t.Errorf("Expected the edited transaction to be flagged, got %v", unbalancedIDs)
+	}
+}
@@ -0,0 +1,605 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidLeaveRequest = errors.New("invalid leave request")
+var ErrInsufficientLeave = errors.New("not enough leave balance")
+var ErrInvalidLeaveTransition = errors.New("invalid leave request state change")
+var ErrNotLeaveApprover = errors.New("not an approver for this leave request")
+
+const defaultDailyHours = 8
+
+// leaveDay truncates t to midnight UTC
+func leaveDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DailyCapacity is the hours an employee works on a normal weekday: a fifth of their weekly capacity,
+// or 8 when it isn't set
+func DailyCapacity(employee *Employee) float64 {
+	if employee.CapacityWeekly <= 0 {
+		return defaultDailyHours
+	}
+	return float64(employee.CapacityWeekly) / 5
+}
+
+// isSalariedEmployee reports whether leave for the employee is posted as internal entries, which is
+// the case for salaried and base plus variable compensation
+func isSalariedEmployee(employee *Employee) bool {
+	compType := strings.ToUpper(employee.CompensationType)
+	return compType == "SALARIED" || compType == CompensationTypeSalaried.String() ||
+		compType == "BASE-PLUS-VARIABLE" || compType == "COMPENSATION_TYPE_BASE_PLUS_VARIABLE"
+}
+
+// ValidateLeaveType checks a leave type before it's saved
+func ValidateLeaveType(leaveType *LeaveType) error {
+	if strings.TrimSpace(leaveType.Name) == "" {
+		return fmt.Errorf("%w: a leave type needs a name", ErrInvalidLeaveRequest)
+	}
+	switch LeaveAccrual(leaveType.Accrual) {
+	case "":
+		leaveType.Accrual = LeaveAccrualNone.String()
+	case LeaveAccrualNone, LeaveAccrualWeekly, LeaveAccrualBiweekly, LeaveAccrualSemimonthly, LeaveAccrualMonthly:
+	default:
+		return fmt.Errorf("%w: unknown accrual %q", ErrInvalidLeaveRequest, leaveType.Accrual)
+	}
+	if leaveType.AccrualHoursPerPeriod < 0 || leaveType.MaxBalanceHours < 0 {
+		return fmt.Errorf("%w: accrual hours and the balance cap can't be negative", ErrInvalidLeaveRequest)
+	}
+	return nil
+}
+
+// tracksBalance reports whether requests for the leave type are limited by a balance
+func (leaveType *LeaveType) tracksBalance() bool {
+	return leaveType.Accrual != "" && leaveType.Accrual != LeaveAccrualNone.String()
+}
+
+// HolidaysBetween returns the tenant's holidays from one day to another, inclusive, keyed by day
+func (a *App) HolidaysBetween(tenantID uint, from, to time.Time) (map[string]Holiday, error) {
+	var holidays []Holiday
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("date >= ? AND date <= ?", leaveDay(from), leaveDay(to)).
+		Find(&holidays).Error; err != nil {
+		return nil, fmt.Errorf("failed to load holidays: %w", err)
+	}
+	byDay := make(map[string]Holiday, len(holidays))
+	for _, holiday := range holidays {
+		byDay[holiday.Date.UTC().Format("2006-01-02")] = holiday
+	}
+	return byDay, nil
+}
+
+// LeaveDays returns the working days from one day to another, inclusive, leaving out weekends and
+// the tenant's holidays
+func (a *App) LeaveDays(tenantID uint, from, to time.Time) ([]time.Time, error) {
+	holidays, err := a.HolidaysBetween(tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var days []time.Time
+	for day := leaveDay(from); !day.After(leaveDay(to)); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		if _, ok := holidays[day.Format("2006-01-02")]; ok {
+			continue
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// GetLeaveBalance returns the employee's balance of a leave type, opening it if it doesn't exist yet
+func (a *App) GetLeaveBalance(tenantID, employeeID, leaveTypeID uint) (*LeaveBalance, error) {
+	balance := LeaveBalance{TenantID: tenantID, EmployeeID: employeeID, LeaveTypeID: leaveTypeID}
+	if err := a.DB.Where("tenant_id = ? AND employee_id = ? AND leave_type_id = ?", tenantID, employeeID, leaveTypeID).
+		FirstOrCreate(&balance).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leave balance: %w", err)
+	}
+	return &balance, nil
+}
+
+// Available is the hours of leave left to request
+func (balance *LeaveBalance) Available() float64 {
+	return balance.AccruedHours - balance.UsedHours
+}
+
+// ListLeaveBalances returns the employee's balance of every leave type that tracks one
+func (a *App) ListLeaveBalances(tenantID, employeeID uint) ([]LeaveBalance, error) {
+	var leaveTypes []LeaveType
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("accrual != ?", LeaveAccrualNone.String()).
+		Order("name").Find(&leaveTypes).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leave types: %w", err)
+	}
+	balances := make([]LeaveBalance, 0, len(leaveTypes))
+	for _, leaveType := range leaveTypes {
+		balance, err := a.GetLeaveBalance(tenantID, employeeID, leaveType.ID)
+		if err != nil {
+			return nil, err
+		}
+		balance.LeaveType = leaveType
+		balances = append(balances, *balance)
+	}
+	return balances, nil
+}
+
+// AdjustLeaveBalance adds hours, or takes them away when negative, from an employee's accrued leave,
+// e.g. to carry over a balance from before cronos
+func (a *App) AdjustLeaveBalance(tenantID, employeeID, leaveTypeID uint, hours float64) (*LeaveBalance, error) {
+	balance, err := a.GetLeaveBalance(tenantID, employeeID, leaveTypeID)
+	if err != nil {
+		return nil, err
+	}
+	balance.AccruedHours += hours
+	if err := a.DB.Omit("LeaveType").Save(balance).Error; err != nil {
+		return nil, fmt.Errorf("failed to adjust leave balance: %w", err)
+	}
+	return balance, nil
+}
+
+// pendingLeaveHours is the hours of the employee's requests of a leave type still awaiting approval
+func (a *App) pendingLeaveHours(tenantID, employeeID, leaveTypeID uint) float64 {
+	var hours float64
+	a.DB.Model(&LeaveRequest{}).Where("tenant_id = ? AND employee_id = ? AND leave_type_id = ? AND state = ?",
+		tenantID, employeeID, leaveTypeID, LeaveStatePending.String()).
+		Select("COALESCE(SUM(hours), 0)").Scan(&hours)
+	return hours
+}
+
+// RequestLeave records an employee's request for leave from one day to another, inclusive. Weekends
+// and holidays aren't counted, hoursPerDay defaults to the employee's daily capacity, and a request
+// can't overlap another pending or approved one or, for types that accrue, exceed the balance left
+// after pending requests.
+func (a *App) RequestLeave(tenantID, employeeID, leaveTypeID uint, start, end time.Time, hoursPerDay float64, reason string) (*LeaveRequest, error) {
+	var leaveType LeaveType
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&leaveType, leaveTypeID).Error; err != nil {
+		return nil, fmt.Errorf("leave type %d not found: %w", leaveTypeID, err)
+	}
+	var employee Employee
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&employee, employeeID).Error; err != nil {
+		return nil, fmt.Errorf("employee %d not found: %w", employeeID, err)
+	}
+	start, end = leaveDay(start), leaveDay(end)
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: the leave ends before it starts", ErrInvalidLeaveRequest)
+	}
+	dailyHours := DailyCapacity(&employee)
+	if hoursPerDay <= 0 {
+		hoursPerDay = dailyHours
+	}
+	if hoursPerDay > dailyHours {
+		return nil, fmt.Errorf("%w: %g hours a day is more than the employee's %g hour day", ErrInvalidLeaveRequest, hoursPerDay, dailyHours)
+	}
+	days, err := a.LeaveDays(tenantID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("%w: there are no working days between %s and %s", ErrInvalidLeaveRequest,
+			start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	var overlapping int64
+	a.DB.Model(&LeaveRequest{}).Where("tenant_id = ? AND employee_id = ? AND state IN ? AND start_date <= ? AND end_date >= ?",
+		tenantID, employeeID, []string{LeaveStatePending.String(), LeaveStateApproved.String()}, end, start).
+		Count(&overlapping)
+	if overlapping > 0 {
+		return nil, fmt.Errorf("%w: it overlaps another leave request", ErrInvalidLeaveRequest)
+	}
+
+	request := LeaveRequest{
+		TenantID:    tenantID,
+		EmployeeID:  employeeID,
+		LeaveTypeID: leaveType.ID,
+		StartDate:   start,
+		EndDate:     end,
+		HoursPerDay: hoursPerDay,
+		Hours:       hoursPerDay * float64(len(days)),
+		State:       LeaveStatePending.String(),
+		Reason:      reason,
+	}
+	if leaveType.tracksBalance() {
+		balance, err := a.GetLeaveBalance(tenantID, employeeID, leaveType.ID)
+		if err != nil {
+			return nil, err
+		}
+		if available := balance.Available() - a.pendingLeaveHours(tenantID, employeeID, leaveType.ID); request.Hours > available {
+			return nil, fmt.Errorf("%w: %g hours requested, %g available", ErrInsufficientLeave, request.Hours, available)
+		}
+	}
+	if err := a.DB.Omit("Employee", "LeaveType").Create(&request).Error; err != nil {
+		return nil, fmt.Errorf("failed to save leave request: %w", err)
+	}
+	request.LeaveType = leaveType
+	log.Printf("Employee %d requested %.2f hours of %s from %s", employeeID, request.Hours, leaveType.Name, start.Format("2006-01-02"))
+	return &request, nil
+}
+
+// CanApproveLeave reports whether an employee may approve or reject a leave request: the requester's
+// manager, or an admin. No one approves their own leave.
+func (a *App) CanApproveLeave(request *LeaveRequest, approverID uint, isAdmin bool) bool {
+	if isAdmin {
+		return true
+	}
+	if approverID == 0 || approverID == request.EmployeeID {
+		return false
+	}
+	var employee Employee
+	a.DB.Select("manager_id").Where("id = ?", request.EmployeeID).Limit(1).Find(&employee)
+	return employee.ManagerID != nil && *employee.ManagerID == approverID
+}
+
+// reviewableLeaveRequest loads a pending leave request the approver is allowed to act on
+func (a *App) reviewableLeaveRequest(tenantID, requestID, approverID uint, isAdmin bool) (*LeaveRequest, error) {
+	var request LeaveRequest
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("LeaveType").Preload("Employee.User").
+		First(&request, requestID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leave request %d: %w", requestID, err)
+	}
+	if request.State != LeaveStatePending.String() {
+		return nil, fmt.Errorf("%w: only pending requests can be reviewed, this one is %s", ErrInvalidLeaveTransition, request.State)
+	}
+	if !a.CanApproveLeave(&request, approverID, isAdmin) {
+		return nil, ErrNotLeaveApprover
+	}
+	return &request, nil
+}
+
+// leaveEntries are the internal entries approved leave posts for a salaried employee, one per working
+// day starting at 09:00. They're approved but never invoiced. Leave can't be posted into a submitted
+// week, so the timesheet has to be returned first.
+func (a *App) leaveEntries(request *LeaveRequest) ([]Entry, error) {
+	var billingCode BillingCode
+	if err := a.DB.Scopes(TenantScope(request.TenantID)).First(&billingCode, *request.LeaveType.BillingCodeID).Error; err != nil {
+		return nil, fmt.Errorf("leave billing code %d not found: %w", *request.LeaveType.BillingCodeID, err)
+	}
+	days, err := a.LeaveDays(request.TenantID, request.StartDate, request.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(days))
+	for _, day := range days {
+		start := day.Add(9 * time.Hour)
+		if err := a.CheckTimesheetOpen(request.TenantID, request.EmployeeID, start); err != nil {
+			return nil, fmt.Errorf("%w: leave on %s", err, day.Format("2006-01-02"))
+		}
+		entries = append(entries, Entry{
+			TenantID:       request.TenantID,
+			ProjectID:      billingCode.ProjectID,
+			BillingCodeID:  billingCode.ID,
+			EmployeeID:     request.EmployeeID,
+			Start:          start,
+			End:            start.Add(time.Duration(request.HoursPerDay * float64(time.Hour))),
+			Notes:          request.LeaveType.Name,
+			Internal:       true,
+			State:          EntryStateApproved.String(),
+			LeaveRequestID: &request.ID,
+		})
+	}
+	return entries, nil
+}
+
+// notifyLeaveReviewed emails the employee the outcome of their leave request
+func (a *App) notifyLeaveReviewed(request *LeaveRequest) {
+	if request.Employee.User.Email == "" {
+		return
+	}
+	outcome := "approved"
+	if request.State == LeaveStateRejected.String() {
+		outcome = "rejected"
+	}
+	dates := request.StartDate.Format("January 2, 2006")
+	if !request.EndDate.Equal(request.StartDate) {
+		dates += " to " + request.EndDate.Format("January 2, 2006")
+	}
+	body := fmt.Sprintf("<p>Your %s request for %s was %s.</p>", html.EscapeString(request.LeaveType.Name), dates, outcome)
+	if request.ReviewComment != "" {
+		body += fmt.Sprintf("<blockquote>%s</blockquote>", html.EscapeString(request.ReviewComment))
+	}
+	if err := a.sendStaffNotice(request.Employee.User.Email, "Leave "+outcome+": "+dates, body); err != nil {
+		log.Printf("Failed to notify employee %d of leave request %d: %v", request.EmployeeID, request.ID, err)
+	}
+}
+
+// ApproveLeave approves a pending leave request, deducting it from the balance. For salaried staff the
+// leave is posted as internal entries on the leave type's billing code.
+func (a *App) ApproveLeave(tenantID, requestID, approverID uint, isAdmin bool, comment string) (*LeaveRequest, error) {
+	request, err := a.reviewableLeaveRequest(tenantID, requestID, approverID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if isSalariedEmployee(&request.Employee) && request.LeaveType.BillingCodeID != nil {
+		if entries, err = a.leaveEntries(request); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	request.State = LeaveStateApproved.String()
+	request.ReviewedAt = &now
+	request.ReviewedByID = &approverID
+	request.ReviewComment = comment
+	err = a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Employee", "LeaveType").Save(request).Error; err != nil {
+			return fmt.Errorf("failed to approve leave request: %w", err)
+		}
+		if request.LeaveType.tracksBalance() {
+			if err := tx.Model(&LeaveBalance{}).
+				Where("tenant_id = ? AND employee_id = ? AND leave_type_id = ?", tenantID, request.EmployeeID, request.LeaveTypeID).
+				Update("used_hours", gorm.Expr("used_hours + ?", request.Hours)).Error; err != nil {
+				return fmt.Errorf("failed to update leave balance: %w", err)
+			}
+		}
+		for i := range entries {
+			if err := tx.Create(&entries[i]).Error; err != nil {
+				return fmt.Errorf("failed to post leave entry: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Leave request %d approved by employee %d, %d entries posted", request.ID, approverID, len(entries))
+	a.notifyLeaveReviewed(request)
+	return request, nil
+}
+
+// RejectLeave turns down a pending leave request and emails the employee the comment
+func (a *App) RejectLeave(tenantID, requestID, approverID uint, isAdmin bool, comment string) (*LeaveRequest, error) {
+	if comment == "" {
+		return nil, fmt.Errorf("%w: a comment is required to reject leave", ErrInvalidLeaveTransition)
+	}
+	request, err := a.reviewableLeaveRequest(tenantID, requestID, approverID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	request.State = LeaveStateRejected.String()
+	request.ReviewedAt = &now
+	request.ReviewedByID = &approverID
+	request.ReviewComment = comment
+	if err := a.DB.Omit("Employee", "LeaveType").Save(request).Error; err != nil {
+		return nil, fmt.Errorf("failed to reject leave request: %w", err)
+	}
+	a.notifyLeaveReviewed(request)
+	return request, nil
+}
+
+// CancelLeave withdraws a leave request. The employee can cancel a pending request; cancelling
+// approved leave is left to an approver and returns the hours to the balance and removes any entries
+// it posted, as long as none of them are in a submitted timesheet week.
+func (a *App) CancelLeave(tenantID, requestID, employeeID uint, isAdmin bool) (*LeaveRequest, error) {
+	var request LeaveRequest
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("LeaveType").First(&request, requestID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leave request %d: %w", requestID, err)
+	}
+	switch request.State {
+	case LeaveStatePending.String():
+		if request.EmployeeID != employeeID && !a.CanApproveLeave(&request, employeeID, isAdmin) {
+			return nil, ErrNotLeaveApprover
+		}
+	case LeaveStateApproved.String():
+		if !a.CanApproveLeave(&request, employeeID, isAdmin) {
+			return nil, ErrNotLeaveApprover
+		}
+	default:
+		return nil, fmt.Errorf("%w: a %s request can't be cancelled", ErrInvalidLeaveTransition, request.State)
+	}
+
+	// Leave posted into a submitted week stays until the timesheet is returned
+	approved := request.State == LeaveStateApproved.String()
+	if approved {
+		var posted []Entry
+		if err := a.DB.Where("leave_request_id = ?", request.ID).Find(&posted).Error; err != nil {
+			return nil, fmt.Errorf("failed to load leave entries: %w", err)
+		}
+		for _, entry := range posted {
+			if err := a.CheckTimesheetOpen(tenantID, entry.EmployeeID, entry.Start); err != nil {
+				return nil, fmt.Errorf("%w: leave on %s", err, entry.Start.Format("2006-01-02"))
+			}
+		}
+	}
+	request.State = LeaveStateCancelled.String()
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Employee", "LeaveType").Save(&request).Error; err != nil {
+			return fmt.Errorf("failed to cancel leave request: %w", err)
+		}
+		if !approved {
+			return nil
+		}
+		if request.LeaveType.tracksBalance() {
+			if err := tx.Model(&LeaveBalance{}).
+				Where("tenant_id = ? AND employee_id = ? AND leave_type_id = ?", tenantID, request.EmployeeID, request.LeaveTypeID).
+				Update("used_hours", gorm.Expr("used_hours - ?", request.Hours)).Error; err != nil {
+				return fmt.Errorf("failed to update leave balance: %w", err)
+			}
+		}
+		if err := tx.Where("leave_request_id = ?", request.ID).Delete(&Entry{}).Error; err != nil {
+			return fmt.Errorf("failed to remove leave entries: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ListEmployeeLeave returns an employee's leave requests, latest first
+func (a *App) ListEmployeeLeave(tenantID, employeeID uint) ([]LeaveRequest, error) {
+	var requests []LeaveRequest
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("LeaveType").Where("employee_id = ?", employeeID).
+		Order("start_date desc").Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leave requests: %w", err)
+	}
+	return requests, nil
+}
+
+// ListLeaveForApproval returns the pending leave requests an employee can approve, soonest first
+func (a *App) ListLeaveForApproval(tenantID, approverID uint, isAdmin bool) ([]LeaveRequest, error) {
+	var pending []LeaveRequest
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("LeaveType").Preload("Employee").
+		Where("state = ?", LeaveStatePending.String()).
+		Order("start_date, employee_id").Find(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leave requests: %w", err)
+	}
+	requests := make([]LeaveRequest, 0, len(pending))
+	for i := range pending {
+		if a.CanApproveLeave(&pending[i], approverID, isAdmin) {
+			requests = append(requests, pending[i])
+		}
+	}
+	return requests, nil
+}
+
+// nextAccrual returns when leave accrued at last next accrues under the policy
+func nextAccrual(accrual string, last time.Time) time.Time {
+	switch LeaveAccrual(accrual) {
+	case LeaveAccrualWeekly:
+		return last.AddDate(0, 0, 7)
+	case LeaveAccrualBiweekly:
+		return last.AddDate(0, 0, 14)
+	case LeaveAccrualSemimonthly:
+		if last.Day() < 16 {
+			return time.Date(last.Year(), last.Month(), 16, 0, 0, 0, 0, time.UTC)
+		}
+		return time.Date(last.Year(), last.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(last.Year(), last.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// AccrueLeave credits each active employee with the hours every accruing leave type earns for the
+// periods completed since it last accrued, up to the type's balance cap. A balance starts accruing
+// from the first run after it's opened. It returns the number of balances credited.
+func (a *App) AccrueLeave(tenantID uint, now time.Time) (int, error) {
+	var leaveTypes []LeaveType
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("accrual != ? AND accrual_hours_per_period > 0", LeaveAccrualNone.String()).
+		Find(&leaveTypes).Error; err != nil {
+		return 0, fmt.Errorf("failed to load leave types: %w", err)
+	}
+	if len(leaveTypes) == 0 {
+		return 0, nil
+	}
+	var employees []Employee
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("is_active = ?", true).Find(&employees).Error; err != nil {
+		return 0, fmt.Errorf("failed to load employees: %w", err)
+	}
+
+	now = now.UTC()
+	credited := 0
+	for _, leaveType := range leaveTypes {
+		for _, employee := range employees {
+			balance, err := a.GetLeaveBalance(tenantID, employee.ID, leaveType.ID)
+			if err != nil {
+				return credited, err
+			}
+			if balance.LastAccruedAt == nil {
+				balance.LastAccruedAt = &now
+				a.DB.Omit("LeaveType").Save(balance)
+				continue
+			}
+			last, periods := *balance.LastAccruedAt, 0
+			for next := nextAccrual(leaveType.Accrual, last); !next.After(now); next = nextAccrual(leaveType.Accrual, last) {
+				last = next
+				periods++
+			}
+			if periods == 0 {
+				continue
+			}
+			hours := leaveType.AccrualHoursPerPeriod * float64(periods)
+			if leaveType.MaxBalanceHours > 0 {
+				hours = math.Min(hours, leaveType.MaxBalanceHours-balance.Available())
+			}
+			if hours > 0 {
+				balance.AccruedHours += hours
+				credited++
+			}
+			balance.LastAccruedAt = &last
+			if err := a.DB.Omit("LeaveType").Save(balance).Error; err != nil {
+				return credited, fmt.Errorf("failed to accrue leave for employee %d: %w", employee.ID, err)
+			}
+		}
+	}
+	return credited, nil
+}
+
+// TimeOffByDay returns the hours each employee is off on each working day from one day to another,
+// keyed by employee and then "2006-01-02": approved leave, plus a full day for every holiday. Capacity
+// and utilization are measured against what's left.
+func (a *App) TimeOffByDay(tenantID uint, from, to time.Time) (map[uint]map[string]float64, error) {
+	from, to = leaveDay(from), leaveDay(to)
+	holidays, err := a.HolidaysBetween(tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var employees []Employee
+	if err := a.DB.Scopes(TenantScope(tenantID)).Find(&employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to load employees: %w", err)
+	}
+	var requests []LeaveRequest
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("state = ? AND start_date <= ? AND end_date >= ?",
+		LeaveStateApproved.String(), to, from).Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leave: %w", err)
+	}
+
+	timeOff := make(map[uint]map[string]float64, len(employees))
+	dailyHours := make(map[uint]float64, len(employees))
+	for i := range employees {
+		timeOff[employees[i].ID] = make(map[string]float64)
+		dailyHours[employees[i].ID] = DailyCapacity(&employees[i])
+		for key, holiday := range holidays {
+			if wd := holiday.Date.UTC().Weekday(); wd != time.Saturday && wd != time.Sunday {
+				timeOff[employees[i].ID][key] = dailyHours[employees[i].ID]
+			}
+		}
+	}
+	for _, request := range requests {
+		days, ok := timeOff[request.EmployeeID]
+		if !ok {
+			continue
+		}
+		for day := request.StartDate.UTC(); !day.After(request.EndDate.UTC()); day = day.AddDate(0, 0, 1) {
+			key := day.Format("2006-01-02")
+			if day.Before(from) || day.After(to) || day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+				continue
+			}
+			if _, holiday := holidays[key]; holiday {
+				continue
+			}
+			days[key] = math.Min(days[key]+request.HoursPerDay, dailyHours[request.EmployeeID])
+		}
+	}
+	return timeOff, nil
+}
+
+// TimeOffInWeek totals an employee's time off over the seven days from weekStart
+func TimeOffInWeek(days map[string]float64, weekStart time.Time) float64 {
+	var hours float64
+	for i := 0; i < 7; i++ {
+		hours += days[weekStart.AddDate(0, 0, i).Format("2006-01-02")]
+	}
+	return hours
+}
+
+// AvailableCommitment reduces a weekly staffing commitment by the share of the employee's week they're
+// off, so a week of leave leaves nothing to commit to
+func AvailableCommitment(commitment int, employee *Employee, timeOffHours float64) float64 {
+	weekly := DailyCapacity(employee) * 5
+	if timeOffHours <= 0 || weekly <= 0 {
+		return float64(commitment)
+	}
+	return float64(commitment) * math.Max(weekly-timeOffHours, 0) / weekly
+}
@@ -0,0 +1,126 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestLeave tests that leave accrues up to its cap, that requests skip holidays and are limited by the
+// balance, that approved leave posts internal entries for salaried staff and reduces capacity, and that
+// cancelling it gives the hours back
+func TestLeave(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db, sendNotice: func(to, subject, body string) error { return nil }}
+	tenant := Tenant{Slug: "leave", Name: "Leave Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	manager := Employee{TenantID: tenant.ID, FirstName: "Morgan", IsActive: true}
+	db.Create(&manager)
+	employee := Employee{TenantID: tenant.ID, FirstName: "Sam", IsActive: true, CapacityWeekly: 40,
+		CompensationType: "salaried", ManagerID: &manager.ID}
+	db.Create(&employee)
+	account := Account{TenantID: tenant.ID, Name: "Internal", Type: AccountTypeInternal.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Time Off", AccountID: account.ID}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Internal", Amount: 0}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "PTO", Code: "PTO", ProjectID: project.ID, RateID: rate.ID}
+	db.Create(&code)
+	db.Create(&Holiday{TenantID: tenant.ID, Date: time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC), Name: "Independence Day"})
+	vacation := LeaveType{TenantID: tenant.ID, Name: "Vacation", Paid: true, BillingCodeID: &code.ID,
+		Accrual: LeaveAccrualSemimonthly.String(), AccrualHoursPerPeriod: 5, MaxBalanceHours: 12}
+	if err := ValidateLeaveType(&vacation); err != nil {
+		t.Fatalf("Expected the leave type to be valid: %v", err)
+	}
+	db.Create(&vacation)
+
+	// The first run starts the clock, then the 16th and the 1st each accrue 5 hours up to the 12 hour cap
+	app.AccrueLeave(tenant.ID, time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC))
+	app.AccrueLeave(tenant.ID, time.Date(2025, 7, 2, 6, 0, 0, 0, time.UTC))
+	balance, _ := app.GetLeaveBalance(tenant.ID, employee.ID, vacation.ID)
+	if balance.AccruedHours != 10 {
+		t.Errorf("Expected 10 hours accrued over two periods, got %g", balance.AccruedHours)
+	}
+	app.AccrueLeave(tenant.ID, time.Date(2025, 8, 2, 6, 0, 0, 0, time.UTC))
+	balance, _ = app.GetLeaveBalance(tenant.ID, employee.ID, vacation.ID)
+	if balance.AccruedHours != 12 {
+		t.Errorf("Expected accrual to stop at the 12 hour cap, got %g", balance.AccruedHours)
+	}
+
+	// Tuesday to Friday, with Friday a holiday, is three days
+	start, end := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := app.RequestLeave(tenant.ID, employee.ID, vacation.ID, start, end, 0, "Beach"); !errors.Is(err, ErrInsufficientLeave) {
+		t.Errorf("Expected 24 hours against a 12 hour balance to be refused, got %v", err)
+	}
+	app.AdjustLeaveBalance(tenant.ID, employee.ID, vacation.ID, 20)
+	request, err := app.RequestLeave(tenant.ID, employee.ID, vacation.ID, start, end, 0, "Beach")
+	if err != nil {
+		t.Fatalf("Failed to request leave: %v", err)
+	}
+	if request.Hours != 24 {
+		t.Errorf("Expected 24 hours of leave, got %g", request.Hours)
+	}
+	if _, err := app.RequestLeave(tenant.ID, employee.ID, vacation.ID, end, end.AddDate(0, 0, 3), 4, ""); !errors.Is(err, ErrInvalidLeaveRequest) {
+		t.Errorf("Expected an overlapping request to be refused, got %v", err)
+	}
+
+	if _, err := app.ApproveLeave(tenant.ID, request.ID, employee.ID, false, ""); !errors.Is(err, ErrNotLeaveApprover) {
+		t.Errorf("Expected employees not to approve their own leave, got %v", err)
+	}
+	// Leave isn't posted into a submitted week until the timesheet is returned
+	timesheet := Timesheet{TenantID: tenant.ID, EmployeeID: employee.ID, WeekStart: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC),
+		State: TimesheetStateSubmitted.String()}
+	db.Create(&timesheet)
+	if _, err := app.ApproveLeave(tenant.ID, request.ID, manager.ID, false, "Enjoy"); !errors.Is(err, ErrTimesheetLocked) {
+		t.Errorf("Expected approving leave into a submitted week to be refused, got %v", err)
+	}
+	db.Model(&timesheet).Update("state", TimesheetStateReturned.String())
+	if _, err := app.ApproveLeave(tenant.ID, request.ID, manager.ID, false, "Enjoy"); err != nil {
+		t.Fatalf("Failed to approve leave: %v", err)
+	}
+	var entries []Entry
+	db.Where("leave_request_id = ?", request.ID).Order("start").Find(&entries)
+	if len(entries) != 3 || !entries[0].Internal || entries[0].State != EntryStateApproved.String() ||
+		entries[0].InvoiceID != nil || entries[0].DurationMinutes != 480 {
+		t.Errorf("Expected three approved 8 hour internal entries with no invoice, got %+v", entries)
+	}
+	balance, _ = app.GetLeaveBalance(tenant.ID, employee.ID, vacation.ID)
+	if balance.Available() != 8 {
+		t.Errorf("Expected 8 hours left, got %g", balance.Available())
+	}
+
+	// Leave plus the holiday takes 32 of 40 hours out of the week, leaving a fifth of a commitment
+	weekStart := time.Date(2025, 6, 29, 0, 0, 0, 0, time.UTC)
+	timeOff, err := app.TimeOffByDay(tenant.ID, weekStart, weekStart.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("Failed to load time off: %v", err)
+	}
+	off := TimeOffInWeek(timeOff[employee.ID], weekStart)
+	if off != 32 || TimeOffInWeek(timeOff[manager.ID], weekStart) != 8 {
+		t.Errorf("Expected 32 hours off for the employee and 8 for the manager, got %g and %g", off, TimeOffInWeek(timeOff[manager.ID], weekStart))
+	}
+	if available := AvailableCommitment(20, &employee, off); available != 4 {
+		t.Errorf("Expected a 20 hour commitment to drop to 4, got %g", available)
+	}
+
+	if _, err := app.CancelLeave(tenant.ID, request.ID, employee.ID, false); !errors.Is(err, ErrNotLeaveApprover) {
+		t.Errorf("Expected only an approver to cancel approved leave, got %v", err)
+	}
+	db.Model(&timesheet).Update("state", TimesheetStateSubmitted.String())
+	if _, err := app.CancelLeave(tenant.ID, request.ID, manager.ID, false); !errors.Is(err, ErrTimesheetLocked) {
+		t.Errorf("Expected cancelling leave in a submitted week to be refused, got %v", err)
+	}
+	db.Model(&timesheet).Update("state", TimesheetStateReturned.String())
+	if _, err := app.CancelLeave(tenant.ID, request.ID, manager.ID, false); err != nil {
+		t.Fatalf("Failed to cancel leave: %v", err)
+	}
+	var remaining int64
+	db.Model(&Entry{}).Where("leave_request_id = ?", request.ID).Count(&remaining)
+	balance, _ = app.GetLeaveBalance(tenant.ID, employee.ID, vacation.ID)
+	if remaining != 0 || balance.Available() != 32 {
+		t.Errorf("Expected the entries removed and 32 hours back, got %d entries and %g hours", remaining, balance.Available())
+	}
+}
@@ -3,6 +3,8 @@ package cronos
 import (
 	"fmt"
 	"log"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -18,6 +20,11 @@ type LedgerEntry struct {
 	InvoiceID   *uint     `json:"invoice_id,omitempty"`
 	BillID      *uint     `json:"bill_id,omitempty"`
 	Tags        []string  `json:"tags,omitempty"`
+
+	// Debit and Credit are in the functional currency; entries posted in another currency keep
+	// the posting's currency and amount (in dollars of that currency)
+	Currency       string  `json:"currency,omitempty"`
+	OriginalAmount float64 `json:"original_amount,omitempty"`
 }
 
 // AccountMapping maps Beancount accounts to Journal account types
@@ -155,6 +162,7 @@ func ConvertBeancountToLedgerEntries(ledger *BeancountLedger) []LedgerEntry {
 				Credit:      credit,
 				Source:      "beancount",
 				Tags:        tx.Tags,
+				Currency:    posting.Currency,
 			}
 
 			entries = append(entries, entry)
@@ -218,8 +226,13 @@ func ConvertJournalToLedgerEntries(journals []Journal) []LedgerEntry {
 	var entries []LedgerEntry
 
 	for _, j := range journals {
+		// Entries from before effective dates were recorded are dated by when they were created
+		date := j.EffectiveDate
+		if date.IsZero() {
+			date = j.CreatedAt
+		}
 		entry := LedgerEntry{
-			Date:        j.CreatedAt,
+			Date:        date,
 			Account:     j.Account,
 			SubAccount:  j.SubAccount,
 			Description: j.Memo,
@@ -229,6 +242,10 @@ func ConvertJournalToLedgerEntries(journals []Journal) []LedgerEntry {
 			InvoiceID:   j.InvoiceID,
 			BillID:      j.BillID,
 		}
+		if j.Currency != "" {
+			entry.Currency = j.Currency
+			entry.OriginalAmount = float64(j.OriginalAmount) / 100.0
+		}
 
 		entries = append(entries, entry)
 	}
@@ -236,8 +253,28 @@ func ConvertJournalToLedgerEntries(journals []Journal) []LedgerEntry {
 	return entries
 }
 
+// TranslateLedgerEntries converts Beancount entries posted in a foreign currency into the tenant's
+// functional currency at the rate on their date. Entries with no rate loaded are left as posted.
+func (a *App) TranslateLedgerEntries(tenantID uint, entries []LedgerEntry) {
+	functional := a.functionalCurrency(tenantID)
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Currency == "" || entry.OriginalAmount != 0 || strings.EqualFold(entry.Currency, functional) {
+			continue
+		}
+		rate, err := a.GetExchangeRate(tenantID, entry.Currency, functional, entry.Date)
+		if err != nil {
+			log.Printf("Warning: leaving %s posting untranslated on %s: %v", entry.Currency, entry.Date.Format("2006-01-02"), err)
+			continue
+		}
+		entry.OriginalAmount = entry.Debit + entry.Credit
+		entry.Debit = math.Round(entry.Debit*rate*100) / 100
+		entry.Credit = math.Round(entry.Credit*rate*100) / 100
+	}
+}
+
 // GetCombinedGeneralLedger retrieves and combines all ledger entries from both sources
-func (a *App) GetCombinedGeneralLedger(beancountPath string, startDate, endDate time.Time) ([]LedgerEntry, error) {
+func (a *App) GetCombinedGeneralLedger(tenantID uint, beancountPath string, startDate, endDate time.Time) ([]LedgerEntry, error) {
 	var allEntries []LedgerEntry
 
 	// Load and parse Beancount file
@@ -257,6 +294,7 @@ func (a *App) GetCombinedGeneralLedger(beancountPath string, startDate, endDate
 
 	// Convert Beancount to ledger entries
 	beancountEntries := ConvertBeancountToLedgerEntries(beancountLedger)
+	a.TranslateLedgerEntries(tenantID, beancountEntries)
 
 	// Filter by date range
 	var filteredBeancount []LedgerEntry
@@ -269,8 +307,8 @@ func (a *App) GetCombinedGeneralLedger(beancountPath string, startDate, endDate
 
 	// Load Journal DB entries
 	var journals []Journal
-	err = a.DB.Where("created_at >= ? AND created_at <= ?", startDate, endDate).
-		Order("created_at ASC").
+	err = a.DB.Where("effective_date >= ? AND effective_date <= ?", startDate, endDate).
+		Order("effective_date ASC").
 		Find(&journals).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to load journal entries: %w", err)
@@ -574,7 +612,7 @@ func (a *App) GenerateReconciliationReport(beancountPath string, asOfDate time.T
 	err = a.DB.Raw(`
 		SELECT SUM(debit) - SUM(credit)
 		FROM journals
-		WHERE account = ? AND created_at <= ?
+		WHERE account = ? AND effective_date <= ?
 	`, AccountCash.String(), asOfDate).Scan(&journalCash).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate journal cash: %w", err)
@@ -620,7 +658,7 @@ func findPotentialDuplicates(beancountLedger *BeancountLedger, app *App, asOfDat
 
 	// Load all Journal entries up to date
 	var journals []Journal
-	app.DB.Where("created_at <= ?", asOfDate).
+	app.DB.Where("effective_date <= ?", asOfDate).
 		Preload("Invoice").
 		Preload("Bill").
 		Find(&journals)
@@ -0,0 +1,199 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var ErrInvalidBillingMethod = errors.New("invalid billing method")
+var ErrNotFixedFeeProject = errors.New("project is not billed at a fixed fee")
+var ErrInvalidMilestone = errors.New("invalid milestone")
+var ErrMilestoneCompleted = errors.New("milestone has already been completed")
+var ErrMilestoneInvoiced = errors.New("milestone has already been invoiced")
+var ErrNoDraftInvoice = errors.New("no draft invoice is open for the project")
+
+// ValidateProjectBillingMethod checks a project's billing method. Fixed-fee projects bill milestones
+// rather than hours, so they can't also draw hours from a retainer.
+func ValidateProjectBillingMethod(project *Project) error {
+	switch project.BillingMethod {
+	case "", BillingMethodHourly.String():
+		return nil
+	case BillingMethodFixedFee.String():
+		if project.RetainerType != "" {
+			return fmt.Errorf("%w: fixed-fee projects can't have a retainer", ErrInvalidBillingMethod)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidBillingMethod, project.BillingMethod)
+	}
+}
+
+// ValidateMilestone checks a milestone has a name and an amount to bill
+func ValidateMilestone(milestone *Milestone) error {
+	if milestone.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidMilestone)
+	}
+	if milestone.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be greater than zero", ErrInvalidMilestone)
+	}
+	return nil
+}
+
+// isFixedFeeProject reports whether a project bills milestones instead of hours
+func (a *App) isFixedFeeProject(projectID uint) bool {
+	var project Project
+	a.DB.Select("billing_method").Limit(1).Find(&project, projectID)
+	return project.BillingMethod == BillingMethodFixedFee.String()
+}
+
+// milestoneLineItem is the invoice line billing a completed milestone. Milestones priced in another
+// currency than the invoice's are converted the way GenerateInvoiceLineItems converts rates.
+func (a *App) milestoneLineItem(invoice *Invoice, milestone *Milestone) (InvoiceLineItem, error) {
+	from := currencyOrFunctional(milestone.Currency, a.functionalCurrency(invoice.TenantID))
+	fx, err := a.invoiceFX(invoice, from)
+	if err != nil {
+		return InvoiceLineItem{}, fmt.Errorf("failed to convert %s milestone %s: %w", from, milestone.Name, err)
+	}
+	amount := convertAmount(milestone.Amount, fx)
+	return InvoiceLineItem{
+		TenantID:    invoice.TenantID,
+		InvoiceID:   invoice.ID,
+		Type:        LineItemTypeMilestone.String(),
+		Description: fmt.Sprintf("Milestone: %s", milestone.Name),
+		Quantity:    1,
+		Rate:        float64(amount) / 100,
+		Amount:      amount,
+		MilestoneID: &milestone.ID,
+	}, nil
+}
+
+// ListProjectMilestones returns a project's milestones in the order they fall due
+func (a *App) ListProjectMilestones(tenantID, projectID uint) ([]Milestone, error) {
+	var milestones []Milestone
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("project_id = ?", projectID).
+		Order("due_date, id").Find(&milestones).Error; err != nil {
+		return nil, fmt.Errorf("failed to load milestones: %w", err)
+	}
+	return milestones, nil
+}
+
+// milestoneDraftInvoice finds the project's draft invoice covering the given date, creating it the
+// same way entries do when there isn't one yet
+func (a *App) milestoneDraftInvoice(project *Project, on time.Time) (*Invoice, error) {
+	find := func() (Invoice, error) {
+		var invoice Invoice
+		query := a.DB.Where("account_id = ? AND type = ? AND state = ? AND period_start <= ? AND period_end >= ?",
+			project.AccountID, InvoiceTypeAR.String(), InvoiceStateDraft.String(), on, on)
+		if !project.Account.ProjectsSingleInvoice {
+			query = query.Where("project_id = ?", project.ID)
+		}
+		err := query.Order("period_end desc").Limit(1).Find(&invoice).Error
+		return invoice, err
+	}
+
+	invoice, err := find()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load draft invoice: %w", err)
+	}
+	if invoice.ID == 0 {
+		var projectID *uint
+		if !project.Account.ProjectsSingleInvoice {
+			projectID = &project.ID
+		}
+		if err := a.CreateInvoice(project.AccountID, projectID, on); err != nil {
+			return nil, err
+		}
+		if invoice, err = find(); err != nil {
+			return nil, fmt.Errorf("failed to load draft invoice: %w", err)
+		}
+	}
+	if invoice.ID == 0 {
+		return nil, ErrNoDraftInvoice
+	}
+	return &invoice, nil
+}
+
+// CompleteMilestone marks a fixed-fee milestone done and bills it on the project's draft invoice for
+// the completion date
+func (a *App) CompleteMilestone(tenantID, milestoneID uint, completedAt time.Time) (*Milestone, error) {
+	var milestone Milestone
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Project.Account").First(&milestone, milestoneID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load milestone %d: %w", milestoneID, err)
+	}
+	if milestone.Completed {
+		return nil, ErrMilestoneCompleted
+	}
+	if milestone.Project.BillingMethod != BillingMethodFixedFee.String() {
+		return nil, ErrNotFixedFeeProject
+	}
+
+	invoice, err := a.milestoneDraftInvoice(&milestone.Project, completedAt)
+	if err != nil {
+		return nil, err
+	}
+	milestone.Completed = true
+	milestone.CompletedAt = &completedAt
+	milestone.InvoiceID = &invoice.ID
+	if err := a.DB.Omit("Project").Save(&milestone).Error; err != nil {
+		return nil, fmt.Errorf("failed to complete milestone: %w", err)
+	}
+	lineItem, err := a.milestoneLineItem(invoice, &milestone)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.DB.Create(&lineItem).Error; err != nil {
+		return nil, fmt.Errorf("failed to add milestone to invoice: %w", err)
+	}
+	a.UpdateInvoiceTotals(invoice)
+
+	log.Printf("Completed milestone %d on project %d, billed $%.2f on invoice %d", milestone.ID, milestone.ProjectID,
+		float64(milestone.Amount)/100, invoice.ID)
+	return &milestone, nil
+}
+
+// ReopenMilestone takes a completed milestone back off its invoice. This is only possible while the
+// invoice is still a draft.
+func (a *App) ReopenMilestone(tenantID, milestoneID uint) (*Milestone, error) {
+	var milestone Milestone
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&milestone, milestoneID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load milestone %d: %w", milestoneID, err)
+	}
+	if !milestone.Completed {
+		return &milestone, nil
+	}
+
+	var invoice Invoice
+	if milestone.InvoiceID != nil {
+		a.DB.Limit(1).Find(&invoice, *milestone.InvoiceID)
+		if invoice.ID != 0 && invoice.State != InvoiceStateDraft.String() {
+			return nil, ErrMilestoneInvoiced
+		}
+	}
+	if err := a.DB.Model(&milestone).Updates(map[string]interface{}{
+		"completed":    false,
+		"completed_at": nil,
+		"invoice_id":   nil,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to reopen milestone: %w", err)
+	}
+	milestone.Completed, milestone.CompletedAt, milestone.InvoiceID = false, nil, nil
+	if invoice.ID != 0 {
+		a.DB.Where("milestone_id = ?", milestone.ID).Delete(&InvoiceLineItem{})
+		a.UpdateInvoiceTotals(&invoice)
+	}
+	return &milestone, nil
+}
+
+// releaseMilestones reopens the milestones billed on a voided invoice so they can be billed again
+func (a *App) releaseMilestones(invoiceID uint) error {
+	if err := a.DB.Model(&Milestone{}).Where("invoice_id = ?", invoiceID).Updates(map[string]interface{}{
+		"completed":    false,
+		"completed_at": nil,
+		"invoice_id":   nil,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to release milestones: %w", err)
+	}
+	return nil
+}
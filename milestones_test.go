@@ -0,0 +1,142 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestFixedFeeMilestones tests that hours on a fixed-fee project carry no fee, that completing a
+// milestone bills it on the draft invoice, and that approval books the milestone as revenue
+func TestFixedFeeMilestones(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "fixed-fee", Name: "Fixed Fee Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Fixed Fee Client", Type: AccountTypeClient.String(), BillingFrequency: BillingFrequencyMonthly.String()}
+	db.Create(&account)
+	project := Project{
+		TenantID:      tenant.ID,
+		Name:          "Website Rebuild",
+		AccountID:     account.ID,
+		ActiveStart:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		ActiveEnd:     time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC),
+		BillingMethod: BillingMethodFixedFee.String(),
+	}
+	project.RetainerType = RetainerTypeMonthly.String()
+	if err := ValidateProjectBillingMethod(&project); !errors.Is(err, ErrInvalidBillingMethod) {
+		t.Errorf("Expected a fixed-fee retainer to be refused, got %v", err)
+	}
+	project.RetainerType = ""
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 200}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Build", Code: "WEB", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15}
+	db.Create(&code)
+
+	discovery := Milestone{TenantID: tenant.ID, ProjectID: project.ID, Name: "Discovery", Amount: 500000, DueDate: time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)}
+	launch := Milestone{TenantID: tenant.ID, ProjectID: project.ID, Name: "Launch", Amount: 1500000, DueDate: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)}
+	for _, milestone := range []*Milestone{&discovery, &launch} {
+		if err := ValidateMilestone(milestone); err != nil {
+			t.Fatalf("Expected milestone to validate: %v", err)
+		}
+		db.Create(milestone)
+	}
+
+	completed, err := app.CompleteMilestone(tenant.ID, discovery.ID, time.Date(2025, 2, 20, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to complete milestone: %v", err)
+	}
+	if completed.InvoiceID == nil {
+		t.Fatalf("Expected the milestone to be put on an invoice")
+	}
+	if _, err := app.CompleteMilestone(tenant.ID, discovery.ID, time.Now()); !errors.Is(err, ErrMilestoneCompleted) {
+		t.Errorf("Expected completing twice to be refused, got %v", err)
+	}
+	var invoice Invoice
+	db.First(&invoice, *completed.InvoiceID)
+	if invoice.State != InvoiceStateDraft.String() || invoice.PeriodStart.Month() != time.February || invoice.TotalAmount != 5000 {
+		t.Errorf("Expected a 5000.00 February draft invoice, got %s %s for %.2f", invoice.State, invoice.PeriodStart, invoice.TotalAmount)
+	}
+	var lineItems int64
+	db.Model(&InvoiceLineItem{}).Where("invoice_id = ? AND type = ? AND amount = ?", invoice.ID, LineItemTypeMilestone.String(), 500000).Count(&lineItems)
+	if lineItems != 1 {
+		t.Errorf("Expected a milestone line item on the draft invoice, got %d", lineItems)
+	}
+
+	// Ten hours on the project are costed but not billed
+	start := time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC)
+	entry := Entry{TenantID: tenant.ID, ProjectID: project.ID, BillingCodeID: code.ID, InvoiceID: &invoice.ID, Start: start, End: start.Add(10 * time.Hour), State: EntryStateApproved.String()}
+	if err := db.Create(&entry).Error; err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	if entry.Fee != 0 {
+		t.Errorf("Expected no fee on fixed-fee hours, got %d", entry.Fee)
+	}
+
+	if err := app.ApproveInvoice(invoice.ID); err != nil {
+		t.Fatalf("Failed to approve invoice: %v", err)
+	}
+	db.First(&invoice, invoice.ID)
+	if invoice.TotalAmount != 5000 || invoice.TotalHours != 10 {
+		t.Errorf("Expected 5000.00 for 10 hours, got %.2f for %.2f hours", invoice.TotalAmount, invoice.TotalHours)
+	}
+	var timesheetLines int64
+	db.Model(&InvoiceLineItem{}).Where("invoice_id = ? AND type = ?", invoice.ID, LineItemTypeTimesheet.String()).Count(&timesheetLines)
+	if timesheetLines != 0 {
+		t.Errorf("Expected no timesheet lines on a fixed-fee invoice, got %d", timesheetLines)
+	}
+	if revenue := accountBalance(db, AccountRevenue); revenue != 500000 {
+		t.Errorf("Expected 5000.00 of revenue from the milestone, got %d", revenue)
+	}
+
+	if _, err := app.ReopenMilestone(tenant.ID, discovery.ID); !errors.Is(err, ErrMilestoneInvoiced) {
+		t.Errorf("Expected reopening an approved milestone to be refused, got %v", err)
+	}
+	if err := app.VoidInvoice(invoice.ID); err != nil {
+		t.Fatalf("Failed to void invoice: %v", err)
+	}
+	var released Milestone
+	db.First(&released, discovery.ID)
+	if released.Completed || released.InvoiceID != nil {
+		t.Errorf("Expected voiding the invoice to release the milestone, got completed=%v invoice=%v", released.Completed, released.InvoiceID)
+	}
+}
+
+// TestForeignCurrencyMilestone tests that a milestone billed to a client in another currency is
+// converted on its line item and in the invoice's totals
+func TestForeignCurrencyMilestone(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "fx-milestones", Name: "FX Milestones Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	if _, err := app.ImportExchangeRatesCSV(tenant.ID, []byte("date,from,to,rate\n2025-01-01,USD,EUR,0.90\n"), "rates.csv"); err != nil {
+		t.Fatalf("Failed to load rates: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Euro Client", Type: AccountTypeClient.String(), Currency: "EUR", BillingFrequency: BillingFrequencyMonthly.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Audit", AccountID: account.ID, BillingMethod: BillingMethodFixedFee.String(),
+		ActiveStart: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ActiveEnd: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)}
+	db.Create(&project)
+	milestone := Milestone{TenantID: tenant.ID, ProjectID: project.ID, Name: "Report", Amount: 100000}
+	db.Create(&milestone)
+
+	completed, err := app.CompleteMilestone(tenant.ID, milestone.ID, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to complete milestone: %v", err)
+	}
+	if err := app.ApproveInvoice(*completed.InvoiceID); err != nil {
+		t.Fatalf("Failed to approve invoice: %v", err)
+	}
+	var invoice Invoice
+	db.First(&invoice, *completed.InvoiceID)
+	var line InvoiceLineItem
+	db.Where("invoice_id = ? AND milestone_id = ?", invoice.ID, milestone.ID).First(&line)
+	if line.Amount != 90000 || invoice.TotalAmount != 900 {
+		t.Errorf("Expected 1000.00 USD billed as 900.00 EUR, got line item %d and total %.2f", line.Amount, invoice.TotalAmount)
+	}
+}
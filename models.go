@@ -129,6 +129,120 @@ func (e ExpenseState) String() string {
 	return string(e)
 }
 
+type CreditNoteState string
+
+func (s CreditNoteState) String() string {
+	return string(s)
+}
+
+type CreditNoteDisposition string
+
+func (d CreditNoteDisposition) String() string {
+	return string(d)
+}
+
+type JobRunStatus string
+
+func (s JobRunStatus) String() string {
+	return string(s)
+}
+
+type JobTrigger string
+
+func (t JobTrigger) String() string {
+	return string(t)
+}
+
+type JournalSourceType string
+
+func (s JournalSourceType) String() string {
+	return string(s)
+}
+
+type AccountingPeriodState string
+
+func (s AccountingPeriodState) String() string {
+	return string(s)
+}
+
+type FinancialStatementType string
+
+func (s FinancialStatementType) String() string {
+	return string(s)
+}
+
+type DunningReminderStatus string
+
+func (s DunningReminderStatus) String() string {
+	return string(s)
+}
+
+type RetainerType string
+
+func (s RetainerType) String() string {
+	return string(s)
+}
+
+type RetainerRolloverPolicy string
+
+func (s RetainerRolloverPolicy) String() string {
+	return string(s)
+}
+
+type RetainerBlockState string
+
+func (s RetainerBlockState) String() string {
+	return string(s)
+}
+
+type BillingMethod string
+
+func (s BillingMethod) String() string {
+	return string(s)
+}
+
+type RoundingPolicy string
+
+func (s RoundingPolicy) String() string {
+	return string(s)
+}
+
+type TimesheetState string
+
+func (s TimesheetState) String() string {
+	return string(s)
+}
+
+type CalendarMatchType string
+
+func (s CalendarMatchType) String() string {
+	return string(s)
+}
+
+type LeaveState string
+
+func (s LeaveState) String() string {
+	return string(s)
+}
+
+type LeaveAccrual string
+
+func (s LeaveAccrual) String() string {
+	return string(s)
+}
+
+type ScenarioState string
+
+func (s ScenarioState) String() string {
+	return string(s)
+}
+
+type BudgetAlertKind string
+
+func (s BudgetAlertKind) String() string {
+	return string(s)
+}
+
 const (
 	HOUR                      = 60.0
 	DEFAULT_PASSWORD          = "DEFAULT_PASSWORD"
@@ -174,6 +288,9 @@ const (
 	InvoiceStatePaid     InvoiceState = "INVOICE_STATE_PAID"
 	InvoiceStateVoid     InvoiceState = "INVOICE_STATE_VOID"
 
+	// Sent invoices that have received some, but not all, of their payment
+	InvoiceStatePartiallyPaid InvoiceState = "INVOICE_STATE_PARTIALLY_PAID"
+
 	BillStateDraft    BillState = "BILL_STATE_DRAFT"
 	BillStateAccepted BillState = "BILL_STATE_ACCEPTED"
 	BillStatePaid     BillState = "BILL_STATE_PAID"
@@ -201,6 +318,9 @@ const (
 	AccountAccruedPayroll         JournalAccountType = "ACCRUED_PAYROLL"
 	AccountAccountsPayable        JournalAccountType = "ACCOUNTS_PAYABLE"
 	AccountAccruedExpensesPayable JournalAccountType = "ACCRUED_EXPENSES_PAYABLE" // Contra account for unreconciled expenses
+	AccountCustomerCredits        JournalAccountType = "CUSTOMER_CREDITS"         // Unapplied client payments (overpayments)
+	AccountSalesTaxPayable        JournalAccountType = "SALES_TAX_PAYABLE"        // Sales tax / VAT collected, subaccount is the jurisdiction code
+	AccountDeferredRevenue        JournalAccountType = "DEFERRED_REVENUE"         // Retainers billed in advance and not yet worked
 
 	// Revenue
 	AccountRevenue           JournalAccountType = "REVENUE"
@@ -241,6 +361,7 @@ const (
 	AccountOtherLiabilities JournalAccountType = "OTHER_LIABILITIES"
 	AccountOtherIncome      JournalAccountType = "OTHER_INCOME"
 	AccountOtherExpenses    JournalAccountType = "OTHER_EXPENSES"
+	AccountRealizedFX       JournalAccountType = "REALIZED_FX_GAIN_LOSS" // Gains (credits) and losses (debits) on foreign-currency settlements
 	AccountEquity           JournalAccountType = "EQUITY"
 	AccountUnclassified     JournalAccountType = "UNCLASSIFIED"
 
@@ -274,6 +395,7 @@ const (
 	LineItemTypeCommission LineItemType = "LINE_ITEM_TYPE_COMMISSION"
 	LineItemTypeAdjustment LineItemType = "LINE_ITEM_TYPE_ADJUSTMENT"
 	LineItemTypeExpense    LineItemType = "LINE_ITEM_TYPE_EXPENSE"
+	LineItemTypeMilestone  LineItemType = "LINE_ITEM_TYPE_MILESTONE"
 
 	// Expense states
 	ExpenseStateDraft     ExpenseState = "EXPENSE_STATE_DRAFT"
@@ -286,6 +408,98 @@ const (
 	CompensationTypeFullyVariable    CompensationType = "COMPENSATION_TYPE_FULLY_VARIABLE"
 	CompensationTypeSalaried         CompensationType = "COMPENSATION_TYPE_SALARIED"
 	CompensationTypeBasePlusVariable CompensationType = "COMPENSATION_TYPE_BASE_PLUS_VARIABLE"
+
+	// Credit note states and what happens to credit beyond the invoice's open balance
+	CreditNoteStateDraft  CreditNoteState = "CREDIT_NOTE_STATE_DRAFT"
+	CreditNoteStateIssued CreditNoteState = "CREDIT_NOTE_STATE_ISSUED"
+	CreditNoteStateVoid   CreditNoteState = "CREDIT_NOTE_STATE_VOID"
+
+	CreditNoteDispositionCredit CreditNoteDisposition = "CREDIT_NOTE_DISPOSITION_CREDIT" // held as account credit
+	CreditNoteDispositionRefund CreditNoteDisposition = "CREDIT_NOTE_DISPOSITION_REFUND" // paid back in cash
+
+	// Scheduled job run states and triggers
+	JobRunStatusRunning   JobRunStatus = "JOB_RUN_STATUS_RUNNING"
+	JobRunStatusSucceeded JobRunStatus = "JOB_RUN_STATUS_SUCCEEDED"
+	JobRunStatusFailed    JobRunStatus = "JOB_RUN_STATUS_FAILED"
+
+	JobTriggerSchedule JobTrigger = "JOB_TRIGGER_SCHEDULE"
+	JobTriggerManual   JobTrigger = "JOB_TRIGGER_MANUAL"
+
+	// What a journal transaction was posted for
+	JournalSourceInvoice        JournalSourceType = "INVOICE"
+	JournalSourceBill           JournalSourceType = "BILL"
+	JournalSourceEntry          JournalSourceType = "ENTRY"
+	JournalSourceAdjustment     JournalSourceType = "ADJUSTMENT"
+	JournalSourceExpense        JournalSourceType = "EXPENSE"
+	JournalSourceCommission     JournalSourceType = "COMMISSION"
+	JournalSourcePayment        JournalSourceType = "PAYMENT"
+	JournalSourceCreditNote     JournalSourceType = "CREDIT_NOTE"
+	JournalSourceRecurringEntry JournalSourceType = "RECURRING_ENTRY"
+	JournalSourceOfflineJournal JournalSourceType = "OFFLINE_JOURNAL"
+	JournalSourceReversal       JournalSourceType = "REVERSAL"
+	JournalSourceManual         JournalSourceType = "MANUAL"
+
+	AccountingPeriodStateOpen       AccountingPeriodState = "OPEN"
+	AccountingPeriodStateSoftClosed AccountingPeriodState = "SOFT_CLOSED"
+	AccountingPeriodStateClosed     AccountingPeriodState = "CLOSED"
+
+	FinancialStatementIncome       FinancialStatementType = "INCOME_STATEMENT"
+	FinancialStatementBalanceSheet FinancialStatementType = "BALANCE_SHEET"
+	FinancialStatementCashFlow     FinancialStatementType = "CASH_FLOW"
+
+	DunningReminderStatusSent    DunningReminderStatus = "SENT"
+	DunningReminderStatusFailed  DunningReminderStatus = "FAILED"
+	DunningReminderStatusSkipped DunningReminderStatus = "SKIPPED" // superseded by a later step, or no address to send to
+
+	RetainerTypeMonthly RetainerType = "RETAINER_TYPE_MONTHLY" // A block of hours is billed in advance every month
+	RetainerTypeBlock   RetainerType = "RETAINER_TYPE_BLOCK"   // The client buys blocks of hours as they need them
+
+	RetainerRolloverPolicyRollover RetainerRolloverPolicy = "RETAINER_ROLLOVER" // Unused hours carry forward until drawn
+	RetainerRolloverPolicyExpire   RetainerRolloverPolicy = "RETAINER_EXPIRE"   // Unused hours lapse at the end of the block's period
+
+	RetainerBlockStatePending   RetainerBlockState = "RETAINER_BLOCK_PENDING" // Prepayment invoice not yet approved
+	RetainerBlockStateOpen      RetainerBlockState = "RETAINER_BLOCK_OPEN"
+	RetainerBlockStateExhausted RetainerBlockState = "RETAINER_BLOCK_EXHAUSTED"
+	RetainerBlockStateExpired   RetainerBlockState = "RETAINER_BLOCK_EXPIRED"
+	RetainerBlockStateVoid      RetainerBlockState = "RETAINER_BLOCK_VOID"
+
+	BillingMethodHourly   BillingMethod = "BILLING_METHOD_HOURLY"    // Entries are billed at their billing code's rate
+	BillingMethodFixedFee BillingMethod = "BILLING_METHOD_FIXED_FEE" // Milestones are billed as they're completed; entries only carry cost
+
+	RoundingPolicyDown    RoundingPolicy = "ROUNDING_POLICY_DOWN"    // Each entry is truncated to the increment (the default)
+	RoundingPolicyUp      RoundingPolicy = "ROUNDING_POLICY_UP"      // Each entry is rounded up to the next increment
+	RoundingPolicyNearest RoundingPolicy = "ROUNDING_POLICY_NEAREST" // Each entry is rounded to the nearest increment, halves up
+	RoundingPolicyMinimum RoundingPolicy = "ROUNDING_POLICY_MINIMUM" // Entries under one increment bill a full increment, longer ones bill the exact minutes
+	RoundingPolicyDaily   RoundingPolicy = "ROUNDING_POLICY_DAILY"   // An employee's daily total on the code is rounded to the nearest increment
+
+	TimesheetStateOpen      TimesheetState = "TIMESHEET_STATE_OPEN"
+	TimesheetStateSubmitted TimesheetState = "TIMESHEET_STATE_SUBMITTED" // Entries in the week are locked until it's approved or returned
+	TimesheetStateApproved  TimesheetState = "TIMESHEET_STATE_APPROVED"
+	TimesheetStateReturned  TimesheetState = "TIMESHEET_STATE_RETURNED" // Sent back to the employee with a comment to fix and resubmit
+
+	CalendarMatchDomain   CalendarMatchType = "CALENDAR_MATCH_DOMAIN"   // An attendee's email domain, or the domain of the code's account website or email when no pattern is set
+	CalendarMatchKeyword  CalendarMatchType = "CALENDAR_MATCH_KEYWORD"  // A word or phrase in the event title, ignoring case
+	CalendarMatchCalendar CalendarMatchType = "CALENDAR_MATCH_CALENDAR" // The ID of the calendar the event is on
+
+	LeaveStatePending   LeaveState = "LEAVE_STATE_PENDING"
+	LeaveStateApproved  LeaveState = "LEAVE_STATE_APPROVED" // Deducted from the balance and capacity, posted as internal entries for salaried staff
+	LeaveStateRejected  LeaveState = "LEAVE_STATE_REJECTED"
+	LeaveStateCancelled LeaveState = "LEAVE_STATE_CANCELLED"
+
+	LeaveAccrualNone        LeaveAccrual = "LEAVE_ACCRUAL_NONE" // No balance is kept, e.g. unpaid leave
+	LeaveAccrualWeekly      LeaveAccrual = "LEAVE_ACCRUAL_WEEKLY"
+	LeaveAccrualBiweekly    LeaveAccrual = "LEAVE_ACCRUAL_BIWEEKLY"
+	LeaveAccrualSemimonthly LeaveAccrual = "LEAVE_ACCRUAL_SEMIMONTHLY" // On the 1st and 16th
+	LeaveAccrualMonthly     LeaveAccrual = "LEAVE_ACCRUAL_MONTHLY"
+
+	ScenarioStateDraft    ScenarioState = "SCENARIO_STATE_DRAFT"    // Forecast only, weighted by its probability
+	ScenarioStatePromoted ScenarioState = "SCENARIO_STATE_PROMOTED" // Its assignments became real staffing assignments
+	ScenarioStateArchived ScenarioState = "SCENARIO_STATE_ARCHIVED" // The deal was lost
+
+	BudgetAlertHours            BudgetAlertKind = "BUDGET_ALERT_HOURS"             // Hours used crossed a threshold of the hours budget
+	BudgetAlertDollars          BudgetAlertKind = "BUDGET_ALERT_DOLLARS"           // Fees crossed a threshold of the dollar budget
+	BudgetAlertProjectedHours   BudgetAlertKind = "BUDGET_ALERT_PROJECTED_HOURS"   // The trailing burn rate runs past BudgetCapHours by ActiveEnd
+	BudgetAlertProjectedDollars BudgetAlertKind = "BUDGET_ALERT_PROJECTED_DOLLARS" // The trailing burn rate runs past BudgetCapDollars by ActiveEnd
 )
 
 // Tenant represents a multi-tenant organization using the platform
@@ -343,6 +557,8 @@ type Employee struct {
 	HasFixedInternalRate    bool         `json:"is_fixed_hourly"`
 	FixedHourlyRate         int          `json:"hourly_rate"`
 	EntryPayEligibleState   string       `json:"entry_pay_eligible_state"`
+	ManagerID               *uint        `json:"manager_id"` // Approves the employee's timesheets when no project lead does
+	Manager                 *Employee    `gorm:"foreignKey:ManagerID" json:"manager,omitempty"`
 }
 
 // RecurringEntry represents a template for auto-generating regular payroll entries
@@ -410,24 +626,32 @@ type GoogleAuth struct {
 type Account struct {
 	// Account is the specific customer account
 	gorm.Model
-	TenantID              uint      `gorm:"not null;index:idx_accounts_tenant_legal_name,priority:1" json:"tenant_id"`
-	Tenant                Tenant    `gorm:"foreignKey:TenantID" json:"-"`
-	Name                  string    `json:"name"`
-	Type                  string    `json:"type"`
-	LegalName             string    `gorm:"uniqueIndex:idx_accounts_tenant_legal_name,priority:2" json:"legal_name"`
-	Address               string    `json:"address"`
-	Email                 string    `json:"email"`
-	Website               string    `json:"website"`
-	LogoAssetID           *uint     `json:"logo_asset_id"`
-	LogoAsset             *Asset    `gorm:"foreignKey:LogoAssetID" json:"logo_asset,omitempty"`
-	Clients               []User    `json:"clients"`
-	Projects              []Project `json:"projects"`
-	Invoices              []Invoice `json:"invoices"`
-	BillingFrequency      string    `json:"billing_frequency"`
-	BudgetHours           int       `json:"budget_hours"`
-	BudgetDollars         int       `json:"budget_dollars"`
-	ProjectsSingleInvoice bool      `json:"projects_single_invoice"`
-	Assets                []Asset   `json:"assets"`
+	TenantID              uint        `gorm:"not null;index:idx_accounts_tenant_legal_name,priority:1" json:"tenant_id"`
+	Tenant                Tenant      `gorm:"foreignKey:TenantID" json:"-"`
+	Name                  string      `json:"name"`
+	Type                  string      `json:"type"`
+	LegalName             string      `gorm:"uniqueIndex:idx_accounts_tenant_legal_name,priority:2" json:"legal_name"`
+	Address               string      `json:"address"`
+	Email                 string      `json:"email"`
+	Website               string      `json:"website"`
+	LogoAssetID           *uint       `json:"logo_asset_id"`
+	LogoAsset             *Asset      `gorm:"foreignKey:LogoAssetID" json:"logo_asset,omitempty"`
+	Clients               []User      `json:"clients"`
+	Projects              []Project   `json:"projects"`
+	Invoices              []Invoice   `json:"invoices"`
+	BillingFrequency      string      `json:"billing_frequency"`
+	BudgetHours           int         `json:"budget_hours"`
+	BudgetDollars         int         `json:"budget_dollars"`
+	ProjectsSingleInvoice bool        `json:"projects_single_invoice"`
+	Assets                []Asset     `json:"assets"`
+	UnappliedCredit       int64       `json:"unapplied_credit"` // Overpayments held for future invoices, in cents
+	TaxProfileID          *uint       `json:"tax_profile_id"`   // Default tax treatment for this client's invoices
+	TaxProfile            *TaxProfile `gorm:"foreignKey:TaxProfileID" json:"tax_profile,omitempty"`
+	Currency              string      `gorm:"size:3" json:"currency"` // ISO 4217 code this client is billed in, empty for the functional currency
+	PaymentTerms          string      `json:"payment_terms"`          // e.g. "Net 30" or "2/10 Net 30", empty for the default Net 30
+	RateCardID            *uint       `json:"rate_card_id"`           // Rates for this client's projects, unless a project has its own card
+	RoundingPolicy        string      `json:"rounding_policy"`        // RoundingPolicy for billing codes without their own, empty to truncate
+	RateCard              *RateCard   `gorm:"foreignKey:RateCardID" json:"rate_card,omitempty"`
 }
 
 type Rate struct {
@@ -440,6 +664,7 @@ type Rate struct {
 	ActiveFrom   time.Time `json:"active_from"`
 	ActiveTo     time.Time `json:"active_to"`
 	InternalOnly bool      `json:"internal_only"`
+	Currency     string    `gorm:"size:3" json:"currency"` // ISO 4217 code of Amount, empty for the functional currency
 	BillingCodes []BillingCode
 }
 
@@ -460,6 +685,7 @@ type Project struct {
 	BudgetDollars       int                  `json:"budget_dollars"`
 	BudgetCapHours      int                  `json:"budget_cap_hours"`
 	BudgetCapDollars    int                  `json:"budget_cap_dollars"`
+	BudgetCheckDue      bool                 `gorm:"index" json:"-"` // Set when its entries change, cleared once the budget_checks job has checked it
 	Internal            bool                 `json:"internal"`
 	BillingCodes        []BillingCode        `json:"billing_codes"`
 	Entries             []Entry              `json:"entries"`
@@ -470,27 +696,42 @@ type Project struct {
 	AE                  *Employee            `json:"ae"`
 	SDRID               *uint                `json:"sdr_id"`
 	SDR                 *Employee            `json:"sdr"`
+	LeadID              *uint                `json:"lead_id"` // Approves timesheets for the project's hours
+	Lead                *Employee            `gorm:"foreignKey:LeadID" json:"lead,omitempty"`
 	StaffingAssignments []StaffingAssignment `json:"staffing_assignments"`
 	Assets              []Asset              `json:"assets"`
+	PaymentTerms        string               `json:"payment_terms"`     // Overrides the account's terms, empty to inherit them
+	RetainerType        string               `json:"retainer_type"`     // RetainerType, empty for time and materials
+	RetainerHours       float64              `json:"retainer_hours"`    // Hours in each prepaid block
+	RetainerAmount      int64                `json:"retainer_amount"`   // Price of each block in cents
+	RetainerRollover    string               `json:"retainer_rollover"` // RetainerRolloverPolicy for unused hours
+	BillingMethod       string               `json:"billing_method"`    // BillingMethod, empty for hourly
+	Milestones          []Milestone          `json:"milestones,omitempty"`
+	RateCardID          *uint                `json:"rate_card_id"` // Overrides the account's rate card
+	RateCard            *RateCard            `gorm:"foreignKey:RateCardID" json:"rate_card,omitempty"`
 }
 
 type BillingCode struct {
 	gorm.Model
-	TenantID       uint      `gorm:"not null;index:idx_billing_codes_tenant_code,priority:1" json:"tenant_id"`
-	Tenant         Tenant    `gorm:"foreignKey:TenantID" json:"-"`
-	Name           string    `json:"name"`
-	RateType       string    `json:"type"`
-	Category       string    `json:"category"`
-	Code           string    `gorm:"uniqueIndex:idx_billing_codes_tenant_code,priority:2" json:"code"`
-	RoundedTo      int       `gorm:"default:15" json:"rounded_to"`
-	ProjectID      uint      `json:"project_id"`
-	ActiveStart    time.Time `json:"active_start"`
-	ActiveEnd      time.Time `json:"active_end"`
-	RateID         uint      `json:"rate_id"`
-	Rate           Rate      `json:"rate"`
-	InternalRateID uint      `json:"internal_rate_id"`
-	InternalRate   Rate      `json:"internal_rate"`
-	Entries        []Entry   `json:"entries"`
+	TenantID       uint        `gorm:"not null;index:idx_billing_codes_tenant_code,priority:1" json:"tenant_id"`
+	Tenant         Tenant      `gorm:"foreignKey:TenantID" json:"-"`
+	Name           string      `json:"name"`
+	RateType       string      `json:"type"`
+	Category       string      `json:"category"`
+	Code           string      `gorm:"uniqueIndex:idx_billing_codes_tenant_code,priority:2" json:"code"`
+	RoundedTo      int         `gorm:"default:15" json:"rounded_to"`
+	ProjectID      uint        `json:"project_id"`
+	ActiveStart    time.Time   `json:"active_start"`
+	ActiveEnd      time.Time   `json:"active_end"`
+	RateID         uint        `json:"rate_id"`
+	Rate           Rate        `json:"rate"`
+	InternalRateID uint        `json:"internal_rate_id"`
+	InternalRate   Rate        `json:"internal_rate"`
+	TaxProfileID   *uint       `json:"tax_profile_id"` // Overrides the account's tax profile for this work
+	TaxProfile     *TaxProfile `gorm:"foreignKey:TaxProfileID" json:"tax_profile,omitempty"`
+	RoundingPolicy string      `json:"rounding_policy"` // Overrides the account's RoundingPolicy, empty to inherit it
+	NotesRequired  bool        `json:"notes_required"`  // Entries need notes, enforced by the required_notes entry rule
+	Entries        []Entry     `json:"entries"`
 }
 type Entry struct {
 	gorm.Model
@@ -508,8 +749,12 @@ type Entry struct {
 	Start                time.Time          `json:"start"`
 	End                  time.Time          `json:"end"`
 	DurationMinutes      float64            `json:"duration_minutes"` // Auto-calculated: End - Start in minutes
+	BilledMinutes        float64            `json:"billed_minutes"`   // Auto-calculated: DurationMinutes after the rounding policy
 	Internal             bool               `json:"internal" gorm:"index:idx_employee_internal"`
 	IsMeeting            bool               `json:"is_meeting" gorm:"default:false"`
+	ExternalEventID      string             `json:"external_event_id" gorm:"index"` // Calendar event the entry was imported from
+	ImportBatchID        *uint              `json:"import_batch_id" gorm:"index"`   // Bulk import that created the entry
+	LeaveRequestID       *uint              `json:"leave_request_id" gorm:"index"`  // Approved leave the entry was posted for
 	Bill                 Bill               `json:"bill"`
 	BillID               *uint              `json:"bill_id"`
 	Invoice              Invoice            `json:"invoice"`
@@ -524,7 +769,10 @@ func (e *Entry) BeforeSave(tx *gorm.DB) (err error) {
 	// Auto-calculate duration in minutes
 	e.DurationMinutes = e.End.Sub(e.Start).Minutes()
 
-	// Recalculate the fee
+	// Recalculate the billed time and the fee
+	var billingCode BillingCode
+	tx.Where("id = ?", e.BillingCodeID).Limit(1).Find(&billingCode)
+	e.BilledMinutes = e.GetBilledMinutes(tx, &billingCode)
 	e.Fee = int(e.GetFee(tx) * 100)
 	return nil
 }
@@ -537,6 +785,7 @@ type Invoice struct {
 	TenantID         uint              `gorm:"not null;index:idx_invoices_tenant_state,priority:1;index:idx_invoices_tenant_account,priority:1" json:"tenant_id"`
 	Tenant           Tenant            `gorm:"foreignKey:TenantID" json:"-"`
 	Name             string            `json:"name"`
+	Number           string            `gorm:"index" json:"number"` // Legal invoice number, assigned once at approval or sending
 	AccountID        uint              `gorm:"index:idx_invoices_tenant_account,priority:2" json:"account_id"`
 	Account          Account           `json:"account"`
 	ProjectID        *uint             `json:"project_id"`
@@ -550,17 +799,28 @@ type Invoice struct {
 	AcceptedAt       time.Time         `json:"accepted_at"`
 	SentAt           time.Time         `json:"sent_at"`
 	DueAt            time.Time         `json:"due_at"`
+	PaymentTerms     string            `json:"payment_terms"` // Terms the invoice was sent under, which set DueAt
 	ClosedAt         time.Time         `json:"closed_at"`
 	State            string            `gorm:"index:idx_invoices_tenant_state,priority:2" json:"state"`
 	Type             string            `json:"type"`
 	TotalHours       float64           `json:"total_hours"`
+	TotalBilledHours float64           `json:"total_billed_hours"` // TotalHours after each entry's rounding policy
 	TotalFees        float64           `json:"total_fees"`
 	TotalAdjustments float64           `json:"total_adjustments"`
 	TotalExpenses    float64           `json:"total_expenses"`
+	TotalTax         float64           `json:"total_tax"`
 	TotalAmount      float64           `json:"total_amount"`
+	AmountPaid       float64           `json:"amount_paid"`
+	AmountCredited   float64           `json:"amount_credited"`        // Credit notes applied against this invoice
+	AmountDiscounted float64           `json:"amount_discounted"`      // Early-payment discount taken under the payment terms
+	BalanceDue       float64           `json:"balance_due"`            // TotalAmount less AmountPaid, AmountCredited and AmountDiscounted
+	Currency         string            `gorm:"size:3" json:"currency"` // All amounts above are in this currency, set from the account at approval
+	ExchangeRate     float64           `json:"exchange_rate"`          // Functional units per unit of Currency at accrual, zero when not foreign
 	JournalID        *uint             `json:"journal_id"`
 	GCSFile          string            `json:"file"`
 
+	PaymentAllocations []PaymentAllocation `json:"payment_allocations,omitempty"`
+
 	// Reconciliation - link to bank transaction when payment is reconciled
 	ReconciledOfflineJournalID *uint           `json:"reconciled_offline_journal_id"`
 	ReconciledAt               *time.Time      `json:"reconciled_at"`
@@ -582,6 +842,11 @@ type InvoiceLineItem struct {
 	Rate        float64 `json:"rate"`     // Hourly rate (for display, in dollars)
 	Amount      int64   `json:"amount"`   // Total in cents
 
+	// Tax charged on top of Amount, broken down by rate in Taxes
+	TaxProfileID *uint                `json:"tax_profile_id,omitempty"`
+	TaxAmount    int64                `json:"tax_amount"` // in cents
+	Taxes        []InvoiceLineItemTax `json:"taxes,omitempty"`
+
 	// Source references for traceability
 	BillingCodeID *uint        `json:"billing_code_id,omitempty"`
 	BillingCode   *BillingCode `json:"billing_code,omitempty" gorm:"foreignKey:BillingCodeID"`
@@ -594,6 +859,8 @@ type InvoiceLineItem struct {
 	Commission    *Commission  `json:"commission,omitempty" gorm:"foreignKey:CommissionID"`
 	ExpenseID     *uint        `json:"expense_id,omitempty"`
 	Expense       *Expense     `json:"expense,omitempty" gorm:"foreignKey:ExpenseID"`
+	MilestoneID   *uint        `json:"milestone_id,omitempty"`
+	Milestone     *Milestone   `json:"milestone,omitempty" gorm:"foreignKey:MilestoneID"`
 }
 
 // Adjustment
@@ -614,6 +881,7 @@ type Adjustment struct {
 	State     string  `json:"state"`
 	Amount    float64 `json:"amount"`
 	Notes     string  `json:"notes"`
+	Retainer  bool    `gorm:"default:false" json:"retainer"` // Retainer prepayments and drawdowns, booked through deferred revenue
 }
 
 // Commission represents a commission payment to a staff member
@@ -696,14 +964,23 @@ type BillLineItem struct {
 // VerifyJournalBalance checks that all journal entries balance (total debits = total credits)
 // Returns the net balance (should be 0) and an error if they don't balance
 func (a *App) VerifyJournalBalance() (int64, error) {
+	return a.verifyJournalBalance()
+}
+
+// VerifyTenantJournalBalance performs the same check as VerifyJournalBalance restricted to a single tenant
+func (a *App) VerifyTenantJournalBalance(tenantID uint) (int64, error) {
+	return a.verifyJournalBalance(TenantScope(tenantID))
+}
+
+func (a *App) verifyJournalBalance(scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
 	var totalDebits int64
 	var totalCredits int64
 
-	if err := a.DB.Raw("SELECT COALESCE(SUM(debit), 0) FROM journals").Scan(&totalDebits).Error; err != nil {
+	if err := a.DB.Table("journals").Scopes(scopes...).Select("COALESCE(SUM(debit), 0)").Scan(&totalDebits).Error; err != nil {
 		return 0, fmt.Errorf("failed to sum debits: %w", err)
 	}
 
-	if err := a.DB.Raw("SELECT COALESCE(SUM(credit), 0) FROM journals").Scan(&totalCredits).Error; err != nil {
+	if err := a.DB.Table("journals").Scopes(scopes...).Select("COALESCE(SUM(credit), 0)").Scan(&totalCredits).Error; err != nil {
 		return 0, fmt.Errorf("failed to sum credits: %w", err)
 	}
 
@@ -718,6 +995,46 @@ func (a *App) VerifyJournalBalance() (int64, error) {
 	return 0, nil
 }
 
+// JournalTransaction is the header for a set of journal lines posted together. Its lines must net to
+// zero, and EffectiveDate (not when the rows were written) decides which period they report in.
+type JournalTransaction struct {
+	gorm.Model
+	TenantID      uint      `gorm:"not null;index:idx_journal_transactions_tenant_date,priority:1" json:"tenant_id"`
+	Tenant        Tenant    `gorm:"foreignKey:TenantID" json:"-"`
+	EffectiveDate time.Time `gorm:"not null;index:idx_journal_transactions_tenant_date,priority:2" json:"effective_date"`
+	SourceType    string    `gorm:"size:32;index:idx_journal_transactions_source,priority:1" json:"source_type"` // JournalSourceType
+	SourceID      *uint     `gorm:"index:idx_journal_transactions_source,priority:2" json:"source_id"`
+	Memo          string    `json:"memo"`
+	PostedByID    *uint     `json:"posted_by_id"` // User who posted it, nil for system postings
+	Lines         []Journal `gorm:"foreignKey:TransactionID" json:"lines,omitempty"`
+}
+
+// AccountingPeriod is a month of a tenant's books. Nothing can be posted into a closed period, and only
+// admins can post into a soft-closed one, with every such override logged.
+type AccountingPeriod struct {
+	gorm.Model
+	TenantID    uint       `gorm:"not null;uniqueIndex:idx_accounting_periods_tenant_start,priority:1" json:"tenant_id"`
+	Tenant      Tenant     `gorm:"foreignKey:TenantID" json:"-"`
+	PeriodStart time.Time  `gorm:"not null;uniqueIndex:idx_accounting_periods_tenant_start,priority:2" json:"period_start"`
+	PeriodEnd   time.Time  `gorm:"not null" json:"period_end"`          // exclusive: the first day of the next period
+	State       string     `gorm:"size:16;default:'OPEN'" json:"state"` // AccountingPeriodState
+	ClosedAt    *time.Time `json:"closed_at"`
+	ClosedByID  *uint      `json:"closed_by_id"`
+	Notes       string     `json:"notes"`
+}
+
+// PeriodOverride records a posting an admin made into a soft-closed period
+type PeriodOverride struct {
+	gorm.Model
+	TenantID      uint      `gorm:"not null;index" json:"tenant_id"`
+	PeriodID      uint      `gorm:"index" json:"period_id"`
+	TransactionID uint      `json:"transaction_id"`
+	EffectiveDate time.Time `json:"effective_date"`
+	SourceType    string    `json:"source_type"`
+	UserID        uint      `json:"user_id"`
+	Reason        string    `json:"reason"`
+}
+
 // Journal refers to a single entry in a journal, this is a single line item that is used to track
 // the debits and credits for a specific account.
 type Journal struct {
@@ -736,6 +1053,16 @@ type Journal struct {
 	Notes                   string                `json:"notes,omitempty"` // User-added notes/context
 	Debit                   int64                 `json:"debit"`
 	Credit                  int64                 `json:"credit"`
+
+	// Every line belongs to a balanced transaction and carries its accounting date
+	TransactionID *uint     `gorm:"index" json:"transaction_id"`
+	EffectiveDate time.Time `gorm:"index" json:"effective_date"`
+
+	// Debit and Credit are always in the functional currency. Entries booked from a foreign-currency
+	// amount keep the original amount (in cents of Currency) and the rate it was translated at.
+	Currency       string  `gorm:"size:3" json:"currency,omitempty"`
+	OriginalAmount int64   `json:"original_amount,omitempty"`
+	ExchangeRate   float64 `json:"exchange_rate,omitempty"`
 }
 
 // OfflineJournal represents journal entries imported from external sources (e.g., Beancount)
@@ -781,6 +1108,10 @@ type OfflineJournal struct {
 	ReconciledInvoiceID *uint    `json:"reconciled_invoice_id"`
 	ReconciledInvoice   *Invoice `json:"reconciled_invoice" gorm:"foreignKey:ReconciledInvoiceID"`
 
+	// Reconciliation - link to a client payment; one bank deposit matches one Payment
+	ReconciledPaymentID *uint    `json:"reconciled_payment_id"`
+	ReconciledPayment   *Payment `json:"reconciled_payment" gorm:"foreignKey:ReconciledPaymentID"`
+
 	// Duplicate booking flag - if true, this transaction was already booked elsewhere (e.g., via expense approval)
 	// and should not be counted in financial statements or posted to GL
 	IsAlreadyBooked bool `json:"is_already_booked" gorm:"default:false"`
@@ -966,12 +1297,14 @@ type Expense struct {
 // ExpenseCategory represents a required categorization for expenses
 type ExpenseCategory struct {
 	gorm.Model
-	TenantID      uint   `gorm:"not null;index:idx_expense_categories_tenant_name,priority:1" json:"tenant_id"`
-	Tenant        Tenant `gorm:"foreignKey:TenantID" json:"-"`
-	Name          string `json:"name" gorm:"type:varchar(255);uniqueIndex:idx_expense_categories_tenant_name,priority:2"`
-	Description   string `json:"description" gorm:"type:varchar(1024)"`
-	GLAccountCode string `json:"gl_account_code" gorm:"type:varchar(100)"` // Maps to Chart of Accounts (e.g., OPERATING_EXPENSES_TRAVEL)
-	Active        bool   `json:"active" gorm:"default:true"`
+	TenantID      uint        `gorm:"not null;index:idx_expense_categories_tenant_name,priority:1" json:"tenant_id"`
+	Tenant        Tenant      `gorm:"foreignKey:TenantID" json:"-"`
+	Name          string      `json:"name" gorm:"type:varchar(255);uniqueIndex:idx_expense_categories_tenant_name,priority:2"`
+	Description   string      `json:"description" gorm:"type:varchar(1024)"`
+	GLAccountCode string      `json:"gl_account_code" gorm:"type:varchar(100)"` // Maps to Chart of Accounts (e.g., OPERATING_EXPENSES_TRAVEL)
+	Active        bool        `json:"active" gorm:"default:true"`
+	TaxProfileID  *uint       `json:"tax_profile_id"` // Overrides the account's tax profile for rebilled expenses
+	TaxProfile    *TaxProfile `gorm:"foreignKey:TaxProfileID" json:"tax_profile,omitempty"`
 }
 
 // ExpenseTag represents an optional tag for grouping expenses with budget tracking
@@ -994,6 +1327,488 @@ type ExpenseTagAssignment struct {
 	TagID     uint `gorm:"primaryKey;column:expense_tag_id"`
 }
 
+// Payment is money received from a client account. A single payment can be allocated across several
+// invoices; anything not allocated is held as unapplied credit on the Account until applied later.
+type Payment struct {
+	gorm.Model
+	TenantID        uint                `gorm:"not null;index:idx_payments_tenant_account,priority:1" json:"tenant_id"`
+	Tenant          Tenant              `gorm:"foreignKey:TenantID" json:"-"`
+	AccountID       uint                `gorm:"index:idx_payments_tenant_account,priority:2" json:"account_id"`
+	Account         Account             `json:"account"`
+	Amount          int64               `json:"amount"`           // in cents
+	UnappliedAmount int64               `json:"unapplied_amount"` // portion not yet allocated to an invoice, in cents
+	ReceivedAt      time.Time           `json:"received_at"`
+	Method          string              `json:"method"` // e.g. "wire", "ach", "check"
+	Reference       string              `json:"reference"`
+	Notes           string              `json:"notes"`
+	Currency        string              `gorm:"size:3" json:"currency"` // Defaults to the account's currency; amounts above are in it
+	ExchangeRate    float64             `json:"exchange_rate"`          // Functional units per unit of Currency when received, zero when not foreign
+	Allocations     []PaymentAllocation `json:"allocations"`
+
+	// Reconciliation - link to the bank deposit line this payment arrived in
+	ReconciledOfflineJournalID *uint           `json:"reconciled_offline_journal_id"`
+	ReconciledAt               *time.Time      `json:"reconciled_at"`
+	ReconciledBy               *uint           `json:"reconciled_by"` // Staff ID who reconciled
+	ReconciledOfflineJournal   *OfflineJournal `json:"reconciled_offline_journal" gorm:"foreignKey:ReconciledOfflineJournalID"`
+}
+
+// PaymentAllocation applies part of a Payment, or of a CreditNote, to a single invoice
+type PaymentAllocation struct {
+	gorm.Model
+	TenantID     uint      `gorm:"not null;index:idx_payment_allocations_tenant,priority:1" json:"tenant_id"`
+	Tenant       Tenant    `gorm:"foreignKey:TenantID" json:"-"`
+	PaymentID    uint      `gorm:"index" json:"payment_id"`
+	CreditNoteID *uint     `gorm:"index" json:"credit_note_id"` // set (and PaymentID left 0) when the credit came from a credit note
+	InvoiceID    uint      `gorm:"index" json:"invoice_id"`
+	Amount       int64     `json:"amount"` // in cents
+	AppliedAt    time.Time `json:"applied_at"`
+}
+
+// CreditNote is a document issued against a sent invoice that credits back some or all of it, either
+// for chosen invoice line items or a fixed amount. Credit beyond the invoice's open balance is refunded
+// or held as account credit depending on Disposition.
+type CreditNote struct {
+	gorm.Model
+	TenantID        uint                 `gorm:"not null;index:idx_credit_notes_tenant_invoice,priority:1" json:"tenant_id"`
+	Tenant          Tenant               `gorm:"foreignKey:TenantID" json:"-"`
+	InvoiceID       uint                 `gorm:"index:idx_credit_notes_tenant_invoice,priority:2" json:"invoice_id"`
+	Invoice         Invoice              `json:"-"`
+	AccountID       uint                 `json:"account_id"`
+	Account         Account              `json:"account"`
+	State           string               `json:"state"`       // CreditNoteState
+	Disposition     string               `json:"disposition"` // CreditNoteDisposition
+	Reason          string               `json:"reason"`
+	Amount          int64                `json:"amount"`           // in cents
+	UnappliedAmount int64                `json:"unapplied_amount"` // credit still available to apply to other invoices, in cents
+	IssuedAt        *time.Time           `json:"issued_at"`
+	GCSFile         string               `json:"file"`
+	LineItems       []CreditNoteLineItem `json:"line_items"`
+}
+
+// CreditNoteLineItem is a single credited line, copied from an InvoiceLineItem or entered as a fixed amount
+type CreditNoteLineItem struct {
+	gorm.Model
+	TenantID          uint   `gorm:"not null;index:idx_credit_note_line_items_tenant,priority:1" json:"tenant_id"`
+	Tenant            Tenant `gorm:"foreignKey:TenantID" json:"-"`
+	CreditNoteID      uint   `gorm:"index" json:"credit_note_id"`
+	InvoiceLineItemID *uint  `json:"invoice_line_item_id,omitempty"`
+	Description       string `json:"description"`
+	Amount            int64  `json:"amount"` // in cents
+}
+
+// ScheduledJob is the persisted definition of a background job for a tenant. The scheduler
+// keeps one row per tenant for every registered job and fires it once NextRunAt has passed.
+type ScheduledJob struct {
+	gorm.Model
+	TenantID        uint       `gorm:"not null;uniqueIndex:idx_scheduled_jobs_tenant_name,priority:1" json:"tenant_id"`
+	Tenant          Tenant     `gorm:"foreignKey:TenantID" json:"-"`
+	Name            string     `gorm:"uniqueIndex:idx_scheduled_jobs_tenant_name,priority:2;size:100" json:"name"`
+	Description     string     `json:"description"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Paused          bool       `json:"paused"`
+	NextRunAt       time.Time  `gorm:"index" json:"next_run_at"`
+	LastRunAt       *time.Time `json:"last_run_at"`
+	LastStatus      string     `json:"last_status"` // JobRunStatus of the most recent run
+}
+
+// JobRun is the history record for a single execution of a ScheduledJob
+type JobRun struct {
+	gorm.Model
+	TenantID       uint         `gorm:"not null;index:idx_job_runs_tenant_job,priority:1" json:"tenant_id"`
+	Tenant         Tenant       `gorm:"foreignKey:TenantID" json:"-"`
+	ScheduledJobID uint         `gorm:"index:idx_job_runs_tenant_job,priority:2" json:"scheduled_job_id"`
+	ScheduledJob   ScheduledJob `json:"-"`
+	Trigger        string       `json:"trigger"` // JobTrigger
+	Status         string       `json:"status"`  // JobRunStatus
+	StartedAt      time.Time    `json:"started_at"`
+	FinishedAt     *time.Time   `json:"finished_at"`
+	Message        string       `json:"message"`
+	Error          string       `json:"error"`
+}
+
+// TaxJurisdiction is an authority that tax is collected for, such as a US state, a city or an EU member state
+type TaxJurisdiction struct {
+	gorm.Model
+	TenantID uint   `gorm:"not null;index:idx_tax_jurisdictions_tenant_code,priority:1" json:"tenant_id"`
+	Tenant   Tenant `gorm:"foreignKey:TenantID" json:"-"`
+	Name     string `json:"name"`
+	Code     string `gorm:"size:32;uniqueIndex:idx_tax_jurisdictions_tenant_code,priority:2" json:"code"` // e.g. US-NY, DE
+	Country  string `json:"country"`
+	Region   string `json:"region"`
+}
+
+// TaxRate is a percentage charged by a jurisdiction
+type TaxRate struct {
+	gorm.Model
+	TenantID       uint            `gorm:"not null;index:idx_tax_rates_tenant,priority:1" json:"tenant_id"`
+	Tenant         Tenant          `gorm:"foreignKey:TenantID" json:"-"`
+	JurisdictionID uint            `json:"jurisdiction_id"`
+	Jurisdiction   TaxJurisdiction `json:"jurisdiction"`
+	Name           string          `json:"name"` // e.g. "NY State Sales Tax", "VAT"
+	Rate           float64         `json:"rate"` // Percentage, e.g. 8.875
+	Active         bool            `gorm:"default:true" json:"active"`
+}
+
+// TaxProfile is the set of rates applied to a line item. Accounts, billing codes and expense categories
+// point at a profile; exempt profiles charge no tax.
+type TaxProfile struct {
+	gorm.Model
+	TenantID    uint      `gorm:"not null;index:idx_tax_profiles_tenant,priority:1" json:"tenant_id"`
+	Tenant      Tenant    `gorm:"foreignKey:TenantID" json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Exempt      bool      `json:"exempt"`
+	Rates       []TaxRate `gorm:"many2many:tax_profile_rates" json:"rates"`
+}
+
+// InvoiceLineItemTax is the tax charged on one invoice line item for one rate
+type InvoiceLineItemTax struct {
+	gorm.Model
+	TenantID          uint    `gorm:"not null;index:idx_invoice_line_item_taxes_tenant,priority:1" json:"tenant_id"`
+	Tenant            Tenant  `gorm:"foreignKey:TenantID" json:"-"`
+	InvoiceID         uint    `gorm:"index" json:"invoice_id"`
+	InvoiceLineItemID uint    `gorm:"index" json:"invoice_line_item_id"`
+	TaxRateID         uint    `json:"tax_rate_id"`
+	JurisdictionID    uint    `json:"jurisdiction_id"`
+	Name              string  `json:"name"`
+	Rate              float64 `json:"rate"`           // Percentage at the time the tax was calculated
+	TaxableAmount     int64   `json:"taxable_amount"` // in cents
+	TaxAmount         int64   `json:"tax_amount"`     // in cents
+}
+
+// DunningStep is one reminder in a tenant's dunning sequence, sent DayOffset days from an invoice's
+// due date (negative offsets remind before it). Subject and Body are templates filled from
+// DunningTemplateData. A step with a late fee also adds a fee adjustment to the invoice when it fires.
+type DunningStep struct {
+	gorm.Model
+	TenantID       uint    `gorm:"not null;index:idx_dunning_steps_tenant,priority:1" json:"tenant_id"`
+	Tenant         Tenant  `gorm:"foreignKey:TenantID" json:"-"`
+	Name           string  `json:"name"`
+	DayOffset      int     `json:"day_offset"`
+	Subject        string  `json:"subject"`
+	Body           string  `json:"body"`
+	LateFeePercent float64 `json:"late_fee_percent"` // Percentage of the open balance
+	LateFeeFlat    float64 `json:"late_fee_flat"`    // In the invoice's currency
+	Active         bool    `json:"active"`
+}
+
+// DunningReminder is the history of a dunning step against an invoice. There is at most one per
+// invoice and step; a failed send is retried on the same row.
+type DunningReminder struct {
+	gorm.Model
+	TenantID            uint        `gorm:"not null;index:idx_dunning_reminders_tenant,priority:1" json:"tenant_id"`
+	Tenant              Tenant      `gorm:"foreignKey:TenantID" json:"-"`
+	InvoiceID           uint        `gorm:"uniqueIndex:idx_dunning_reminders_invoice_step,priority:1" json:"invoice_id"`
+	DunningStepID       uint        `gorm:"uniqueIndex:idx_dunning_reminders_invoice_step,priority:2" json:"dunning_step_id"`
+	DunningStep         DunningStep `json:"dunning_step"`
+	Status              string      `json:"status"` // DunningReminderStatus
+	Recipient           string      `json:"recipient"`
+	Subject             string      `json:"subject"`
+	BalanceDue          float64     `json:"balance_due"` // Open balance when the reminder went out
+	SentAt              *time.Time  `json:"sent_at"`
+	Error               string      `json:"error"`
+	LateFeeAdjustmentID *uint       `json:"late_fee_adjustment_id"`
+}
+
+// RetainerBlock is a prepaid block of hours on a retainer project, billed on its own invoice into
+// deferred revenue. Approved work draws it down oldest block first, recognizing its revenue as it goes.
+type RetainerBlock struct {
+	gorm.Model
+	TenantID         uint       `gorm:"not null;index:idx_retainer_blocks_tenant_project,priority:1" json:"tenant_id"`
+	Tenant           Tenant     `gorm:"foreignKey:TenantID" json:"-"`
+	ProjectID        uint       `gorm:"index:idx_retainer_blocks_tenant_project,priority:2" json:"project_id"`
+	InvoiceID        uint       `gorm:"index" json:"invoice_id"` // The prepayment invoice
+	Hours            float64    `json:"hours"`
+	Amount           int64      `json:"amount"` // In cents, in the prepayment invoice's currency
+	HoursUsed        float64    `json:"hours_used"`
+	HoursExpired     float64    `json:"hours_expired"`
+	AmountRecognized int64      `json:"amount_recognized"` // Moved out of deferred revenue so far, in cents
+	PeriodStart      time.Time  `json:"period_start"`
+	PeriodEnd        time.Time  `json:"period_end"` // Unused hours lapse after this under the expire policy
+	State            string     `json:"state"`      // RetainerBlockState
+	ExpiredAt        *time.Time `json:"expired_at"`
+}
+
+// RateCard is a named set of hourly rates, by employee or by role, that can change over time. Attached
+// to an account or project, it sets the rates entries are billed at in place of their billing code's.
+type RateCard struct {
+	gorm.Model
+	TenantID    uint           `gorm:"not null;index:idx_rate_cards_tenant,priority:1" json:"tenant_id"`
+	Tenant      Tenant         `gorm:"foreignKey:TenantID" json:"-"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Lines       []RateCardLine `json:"lines"`
+}
+
+// RateCardLine is one rate on a card for the dates it's in effect. A line for an employee beats one
+// for their role (the employee's title), which beats a line for neither that covers everyone else.
+// Amounts are in the same currency as the billing code's rate.
+type RateCardLine struct {
+	gorm.Model
+	TenantID       uint       `gorm:"not null;index" json:"tenant_id"`
+	RateCardID     uint       `gorm:"index" json:"rate_card_id"`
+	EmployeeID     *uint      `json:"employee_id"`
+	Employee       *Employee  `gorm:"foreignKey:EmployeeID" json:"employee,omitempty"`
+	Role           string     `json:"role"`
+	Amount         float64    `json:"amount"`          // Hourly rate billed to the client
+	InternalAmount float64    `json:"internal_amount"` // Hourly internal cost, zero to keep the billing code's internal rate
+	EffectiveFrom  time.Time  `json:"effective_from"`
+	EffectiveTo    *time.Time `json:"effective_to"` // Last day the rate applies, nil while it's current
+}
+
+// Timesheet is an employee's week of entries, Monday to Sunday, going through approval. Approving it
+// approves the week's entries, which accrues their payroll.
+type Timesheet struct {
+	gorm.Model
+	TenantID      uint       `gorm:"not null;uniqueIndex:idx_timesheets_tenant_employee_week,priority:1" json:"tenant_id"`
+	EmployeeID    uint       `gorm:"uniqueIndex:idx_timesheets_tenant_employee_week,priority:2" json:"employee_id"`
+	Employee      Employee   `json:"employee"`
+	WeekStart     time.Time  `gorm:"uniqueIndex:idx_timesheets_tenant_employee_week,priority:3" json:"week_start"` // Monday, UTC
+	State         string     `json:"state"`
+	TotalHours    float64    `json:"total_hours"` // Hours in the week when it was last submitted
+	ApproverID    *uint      `json:"approver_id"` // Routed at submission, nil when only an admin can approve
+	Approver      *Employee  `gorm:"foreignKey:ApproverID" json:"approver,omitempty"`
+	SubmittedAt   *time.Time `json:"submitted_at"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	ReviewedByID  *uint      `json:"reviewed_by_id"`
+	ReturnComment string     `json:"return_comment"`
+}
+
+// CalendarMappingRule picks the billing code for imported calendar events. Rules are tried in
+// priority order, lowest first, and the first that matches an event wins.
+type CalendarMappingRule struct {
+	gorm.Model
+	TenantID      uint        `gorm:"not null;index:idx_calendar_mapping_rules_tenant" json:"tenant_id"`
+	Tenant        Tenant      `gorm:"foreignKey:TenantID" json:"-"`
+	MatchType     string      `json:"match_type"`
+	Pattern       string      `json:"pattern"`
+	BillingCodeID uint        `json:"billing_code_id"`
+	BillingCode   BillingCode `json:"billing_code"`
+	Priority      int         `json:"priority"`
+}
+
+// Timer is an employee's running timer. Each employee has at most one; stopping it records an entry
+// and removes the timer.
+type Timer struct {
+	gorm.Model
+	TenantID      uint        `gorm:"not null;uniqueIndex:idx_timers_tenant_employee,priority:1" json:"tenant_id"`
+	EmployeeID    uint        `gorm:"uniqueIndex:idx_timers_tenant_employee,priority:2" json:"employee_id"`
+	Employee      Employee    `json:"-"`
+	BillingCodeID uint        `json:"billing_code_id"`
+	BillingCode   BillingCode `json:"billing_code"`
+	Notes         string      `gorm:"type:varchar(2048)" json:"notes"`
+	IsMeeting     bool        `json:"is_meeting"`
+	StartedAt     time.Time   `json:"started_at"`
+}
+
+// EntryImportBatch records a bulk import of entries from another tool so it can be undone
+type EntryImportBatch struct {
+	gorm.Model
+	TenantID     uint       `gorm:"not null;index:idx_entry_import_batches_tenant" json:"tenant_id"`
+	Tenant       Tenant     `gorm:"foreignKey:TenantID" json:"-"`
+	Source       string     `json:"source"` // File name or "json"
+	EntryCount   int        `json:"entry_count"`
+	TotalHours   float64    `json:"total_hours"`
+	ImportedByID *uint      `json:"imported_by_id"` // User who ran the import
+	UndoneAt     *time.Time `json:"undone_at"`
+	Entries      []Entry    `gorm:"foreignKey:ImportBatchID" json:"-"`
+}
+
+// LeaveType is a kind of time off, such as vacation or sick leave, with the policy it accrues under.
+// Approved leave for salaried staff is posted as internal entries on the type's billing code.
+type LeaveType struct {
+	gorm.Model
+	TenantID              uint         `gorm:"not null;index:idx_leave_types_tenant" json:"tenant_id"`
+	Tenant                Tenant       `gorm:"foreignKey:TenantID" json:"-"`
+	Name                  string       `json:"name"`
+	Paid                  bool         `json:"paid"`
+	BillingCodeID         *uint        `json:"billing_code_id"` // Internal code leave entries are posted to
+	BillingCode           *BillingCode `gorm:"foreignKey:BillingCodeID" json:"billing_code,omitempty"`
+	Accrual               string       `json:"accrual"`
+	AccrualHoursPerPeriod float64      `json:"accrual_hours_per_period"`
+	MaxBalanceHours       float64      `json:"max_balance_hours"` // Accrual stops at this balance, 0 for no cap
+}
+
+// LeaveBalance is an employee's hours of a leave type: accrued, plus adjustments, less approved leave
+type LeaveBalance struct {
+	gorm.Model
+	TenantID      uint       `gorm:"not null;uniqueIndex:idx_leave_balances_employee_type,priority:1" json:"tenant_id"`
+	EmployeeID    uint       `gorm:"uniqueIndex:idx_leave_balances_employee_type,priority:2" json:"employee_id"`
+	LeaveTypeID   uint       `gorm:"uniqueIndex:idx_leave_balances_employee_type,priority:3" json:"leave_type_id"`
+	LeaveType     LeaveType  `json:"leave_type"`
+	AccruedHours  float64    `json:"accrued_hours"`
+	UsedHours     float64    `json:"used_hours"`
+	LastAccruedAt *time.Time `json:"last_accrued_at"`
+}
+
+// LeaveRequest is an employee's request for time off over a range of days
+type LeaveRequest struct {
+	gorm.Model
+	TenantID      uint       `gorm:"not null;index:idx_leave_requests_tenant_employee,priority:1" json:"tenant_id"`
+	EmployeeID    uint       `gorm:"index:idx_leave_requests_tenant_employee,priority:2" json:"employee_id"`
+	Employee      Employee   `json:"employee"`
+	LeaveTypeID   uint       `json:"leave_type_id"`
+	LeaveType     LeaveType  `json:"leave_type"`
+	StartDate     time.Time  `json:"start_date"` // First day off, UTC midnight
+	EndDate       time.Time  `json:"end_date"`   // Last day off, inclusive
+	HoursPerDay   float64    `json:"hours_per_day"`
+	Hours         float64    `json:"hours"` // Working days in the range, less holidays, times HoursPerDay
+	State         string     `json:"state"`
+	Reason        string     `json:"reason"`
+	ReviewedByID  *uint      `json:"reviewed_by_id"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	ReviewComment string     `json:"review_comment"`
+}
+
+// StaffingScenario is a what-if set of assignments, usually for a deal that isn't signed yet. Its hours
+// and revenue are forecast weighted by the chance it happens, and it can be promoted to real staffing
+// assignments once it does.
+type StaffingScenario struct {
+	gorm.Model
+	TenantID    uint                 `gorm:"not null;index:idx_staffing_scenarios_tenant" json:"tenant_id"`
+	Tenant      Tenant               `gorm:"foreignKey:TenantID" json:"-"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Probability float64              `json:"probability"` // 0 to 1
+	State       string               `json:"state"`
+	PromotedAt  *time.Time           `json:"promoted_at"`
+	Assignments []ScenarioAssignment `gorm:"foreignKey:ScenarioID" json:"assignments"`
+}
+
+// ScenarioAssignment is a draft staffing assignment in a scenario. The project can be left out until
+// the deal has one, in which case Rate prices the forecast.
+type ScenarioAssignment struct {
+	gorm.Model
+	TenantID             uint      `gorm:"not null;index" json:"tenant_id"`
+	ScenarioID           uint      `gorm:"index" json:"scenario_id"`
+	EmployeeID           uint      `json:"employee_id"`
+	Employee             Employee  `json:"employee"`
+	ProjectID            *uint     `json:"project_id"`
+	Project              *Project  `json:"project,omitempty"`
+	Rate                 float64   `json:"rate"` // Hourly rate to forecast with, 0 to use the project's
+	Commitment           int       `json:"commitment"`
+	StartDate            time.Time `json:"start_date"`
+	EndDate              time.Time `json:"end_date"`
+	CommitmentSchedule   string    `json:"commitment_schedule" gorm:"type:text"` // JSON-serialized CommitmentSchedule
+	StaffingAssignmentID *uint     `json:"staffing_assignment_id"`               // Set when the scenario is promoted
+}
+
+// Skill is an entry in the tenant's skills taxonomy, such as "dbt" in the "Data Engineering" category
+type Skill struct {
+	gorm.Model
+	TenantID uint   `gorm:"not null;uniqueIndex:idx_skills_tenant_name,priority:1" json:"tenant_id"`
+	Tenant   Tenant `gorm:"foreignKey:TenantID" json:"-"`
+	Name     string `gorm:"uniqueIndex:idx_skills_tenant_name,priority:2" json:"name"`
+	Category string `json:"category"`
+}
+
+// EmployeeSkill is how proficient an employee is in a skill, from 1 (learning) to 5 (expert)
+type EmployeeSkill struct {
+	gorm.Model
+	TenantID    uint  `gorm:"not null;index" json:"tenant_id"`
+	EmployeeID  uint  `gorm:"uniqueIndex:idx_employee_skills_employee_skill,priority:1" json:"employee_id"`
+	SkillID     uint  `gorm:"uniqueIndex:idx_employee_skills_employee_skill,priority:2" json:"skill_id"`
+	Skill       Skill `json:"skill"`
+	Proficiency int   `json:"proficiency"`
+}
+
+// SkillRequirement is a skill a project, or one staffing assignment on it, needs and the proficiency
+// it needs it at. Exactly one of ProjectID and StaffingAssignmentID is set.
+type SkillRequirement struct {
+	gorm.Model
+	TenantID             uint  `gorm:"not null;index" json:"tenant_id"`
+	ProjectID            *uint `gorm:"index" json:"project_id"`
+	StaffingAssignmentID *uint `gorm:"index" json:"staffing_assignment_id"`
+	SkillID              uint  `json:"skill_id"`
+	Skill                Skill `json:"skill"`
+	MinProficiency       int   `json:"min_proficiency"`
+}
+
+// BudgetAlert records a budget notice sent for a project so each threshold is only announced once.
+// It's removed when the project drops back under the threshold, say after its budget is raised, so
+// the alert fires again if it's crossed again.
+type BudgetAlert struct {
+	gorm.Model
+	TenantID  uint      `gorm:"not null;index:idx_budget_alerts_tenant,priority:1" json:"tenant_id"`
+	Tenant    Tenant    `gorm:"foreignKey:TenantID" json:"-"`
+	ProjectID uint      `gorm:"uniqueIndex:idx_budget_alerts_project_kind,priority:1" json:"project_id"`
+	Kind      string    `gorm:"uniqueIndex:idx_budget_alerts_project_kind,priority:2" json:"kind"`      // BudgetAlertKind
+	Threshold float64   `gorm:"uniqueIndex:idx_budget_alerts_project_kind,priority:3" json:"threshold"` // Percent of the budget, 0 for projections
+	Percent   float64   `json:"percent"`                                                                // Percent of the budget used when the alert went out
+	Message   string    `json:"message"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// Holiday is a day off for everyone in the tenant. It's left out of leave requests and capacity.
+type Holiday struct {
+	gorm.Model
+	TenantID uint      `gorm:"not null;uniqueIndex:idx_holidays_tenant_date,priority:1" json:"tenant_id"`
+	Tenant   Tenant    `gorm:"foreignKey:TenantID" json:"-"`
+	Date     time.Time `gorm:"uniqueIndex:idx_holidays_tenant_date,priority:2" json:"date"` // UTC midnight
+	Name     string    `json:"name"`
+}
+
+// Milestone is a deliverable on a fixed-fee project. Completing it puts its amount on the project's
+// draft invoice.
+type Milestone struct {
+	gorm.Model
+	TenantID    uint       `gorm:"not null;index:idx_milestones_tenant_project,priority:1" json:"tenant_id"`
+	ProjectID   uint       `gorm:"index:idx_milestones_tenant_project,priority:2" json:"project_id"`
+	Project     Project    `json:"-"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Amount      int64      `json:"amount"`                 // in cents
+	Currency    string     `gorm:"size:3" json:"currency"` // ISO 4217 code of Amount, empty for the functional currency
+	DueDate     time.Time  `json:"due_date"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	InvoiceID   *uint      `gorm:"index" json:"invoice_id"` // The invoice it was billed on once completed
+}
+
+// RetainerDrawdown records the hours an entry drew from a retainer block
+type RetainerDrawdown struct {
+	gorm.Model
+	TenantID        uint    `gorm:"not null;index" json:"tenant_id"`
+	RetainerBlockID uint    `gorm:"index" json:"retainer_block_id"`
+	EntryID         uint    `gorm:"index" json:"entry_id"`
+	InvoiceID       uint    `gorm:"index" json:"invoice_id"` // The invoice the entry was billed on
+	Hours           float64 `json:"hours"`
+	Credited        int64   `json:"credited"`   // Entry fees credited back on the invoice, in cents
+	Recognized      int64   `json:"recognized"` // Revenue recognized from the block, in cents
+}
+
+// InvoiceNumberSequence holds the next invoice number for a tenant. Scope separates sequences that
+// restart each year or month, depending on the date tokens in the tenant's numbering pattern.
+type InvoiceNumberSequence struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	TenantID  uint   `gorm:"not null;uniqueIndex:idx_invoice_number_sequences_scope,priority:1" json:"tenant_id"`
+	Scope     string `gorm:"size:16;not null;uniqueIndex:idx_invoice_number_sequences_scope,priority:2" json:"scope"`
+	NextValue int64  `gorm:"not null;default:1" json:"next_value"`
+	UpdatedAt time.Time
+}
+
+// ExchangeRate is the number of ToCurrency units one FromCurrency unit bought on Date. Rates are
+// loaded locally (typically from a CSV export) and the latest rate on or before a date is used.
+type ExchangeRate struct {
+	gorm.Model
+	TenantID     uint      `gorm:"not null;uniqueIndex:idx_exchange_rates_pair_date,priority:1" json:"tenant_id"`
+	Tenant       Tenant    `gorm:"foreignKey:TenantID" json:"-"`
+	FromCurrency string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_date,priority:2" json:"from_currency"`
+	ToCurrency   string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_date,priority:3" json:"to_currency"`
+	Date         time.Time `gorm:"not null;uniqueIndex:idx_exchange_rates_pair_date,priority:4" json:"date"`
+	Rate         float64   `gorm:"not null" json:"rate"`
+	Source       string    `json:"source"` // e.g. the CSV file name the rate was loaded from
+}
+
+// SchedulerLock is a lease row used to elect a single scheduler leader when several
+// instances of the server are running. Whoever holds an unexpired lease fires the jobs.
+type SchedulerLock struct {
+	Name      string    `gorm:"primaryKey;size:100" json:"name"`
+	Holder    string    `gorm:"size:255" json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type AssetType string
 
 func (s AssetType) String() string {
@@ -1041,11 +1856,18 @@ func (e *Entry) GetFee(tx *gorm.DB) float64 {
 	var billingCode BillingCode
 	var rate Rate
 	tx.Where("id = ?", e.BillingCodeID).First(&billingCode)
+	// Fixed-fee projects bill their milestones instead, so hours on them carry no fee
+	var project Project
+	tx.Select("billing_method", "account_id", "rate_card_id").Where("id = ?", billingCode.ProjectID).Limit(1).Find(&project)
+	if project.BillingMethod == BillingMethodFixedFee.String() {
+		return 0
+	}
 	tx.Where("id = ?", billingCode.RateID).First(&rate)
-	durationMinutes := e.Duration().Minutes()
-	roundingFactor := float64(billingCode.RoundedTo) / HOUR
-	hours := float64(durationMinutes) / HOUR
-	roundedHours := float64(int(hours/roundingFactor)) * roundingFactor
+	// A rate card on the project or account overrides the billing code's rate on the day of the entry
+	if line := e.rateCardLine(tx, &project); line != nil {
+		rate.Amount = line.Amount
+	}
+	roundedHours := e.GetBilledMinutes(tx, &billingCode) / HOUR
 	fee := roundedHours * rate.Amount
 	return fee
 }
@@ -1056,10 +1878,12 @@ func (e *Entry) GetInternalFee(tx *gorm.DB) float64 {
 	var rate Rate
 	tx.Where("id = ?", e.BillingCodeID).First(&billingCode)
 	tx.Where("id = ?", billingCode.InternalRateID).First(&rate)
-	durationMinutes := e.Duration().Minutes()
-	roundingFactor := float64(billingCode.RoundedTo) / HOUR
-	hours := float64(durationMinutes) / HOUR
-	roundedHours := float64(int(hours/roundingFactor)) * roundingFactor
+	var project Project
+	tx.Select("account_id", "rate_card_id").Where("id = ?", billingCode.ProjectID).Limit(1).Find(&project)
+	if line := e.rateCardLine(tx, &project); line != nil && line.InternalAmount > 0 {
+		rate.Amount = line.InternalAmount
+	}
+	roundedHours := e.GetBilledMinutes(tx, &billingCode) / HOUR
 	fee := roundedHours * rate.Amount
 	return fee
 }
@@ -1084,21 +1908,50 @@ func (a *App) GetEmployeeBillRate(employee *Employee, billingCodeID uint) float6
 // UpdateInvoiceTotals updates the totals for an invoice based on non-voided entries
 // associated with the invoice. This saves us from having to recalculate the totals
 func (a *App) UpdateInvoiceTotals(i *Invoice) {
-	var totalHours float64
+	var totalHours, totalBilledHours float64
 	var totalFeesInt int
 	var totalAdjustments float64
 	var totalExpensesInt int
+	var totalTax int64
 	var entries []Entry
-	a.DB.Where("invoice_id = ?", i.ID).Find(&entries)
+	a.DB.Preload("BillingCode.Rate").Where("invoice_id = ?", i.ID).Find(&entries)
 	var adjustments []Adjustment
 	a.DB.Where("invoice_id = ?", i.ID).Find(&adjustments)
 	var expenses []Expense
 	a.DB.Where("invoice_id = ? AND state = ?", i.ID, ExpenseStateInvoiced.String()).Find(&expenses)
+	var milestones []Milestone
+	a.DB.Select("id", "amount", "currency").Where("invoice_id = ?", i.ID).Find(&milestones)
 
+	// Fees are totalled by billing code and converted the way GenerateInvoiceLineItems converts them,
+	// so the totals match the line items the accrual is booked from
+	feesByCode := make(map[uint]int64)
+	currencyByCode := make(map[uint]string)
 	for _, entry := range entries {
 		if entry.State != EntryStateVoid.String() {
 			totalHours += entry.Duration().Hours()
-			totalFeesInt += entry.Fee
+			totalBilledHours += entry.BilledHours()
+			feesByCode[entry.BillingCodeID] += int64(entry.Fee)
+			currencyByCode[entry.BillingCodeID] = entry.BillingCode.Rate.Currency
+		}
+	}
+	for billingCodeID, fees := range feesByCode {
+		fx, err := a.invoiceFX(i, currencyByCode[billingCodeID])
+		if err != nil {
+			log.Printf("Warning: fees on billing code %d of invoice %d left unconverted: %v", billingCodeID, i.ID, err)
+			fx = 1
+		}
+		totalFeesInt += int(convertAmount(fees, fx))
+	}
+	// Completed milestones on fixed-fee projects are billed as fees, converted like their line items
+	if len(milestones) > 0 {
+		functional := a.functionalCurrency(i.TenantID)
+		for _, milestone := range milestones {
+			fx, err := a.invoiceFX(i, currencyOrFunctional(milestone.Currency, functional))
+			if err != nil {
+				log.Printf("Warning: milestone %d on invoice %d left unconverted: %v", milestone.ID, i.ID, err)
+				fx = 1
+			}
+			totalFeesInt += int(convertAmount(milestone.Amount, fx))
 		}
 	}
 	var multiplier float64
@@ -1117,14 +1970,44 @@ func (a *App) UpdateInvoiceTotals(i *Invoice) {
 	for _, expense := range expenses {
 		totalExpensesInt += expense.Amount
 	}
+	// Tax is calculated per line item, so it only exists once line items have been generated
+	a.DB.Model(&InvoiceLineItem{}).Where("invoice_id = ?", i.ID).Select("COALESCE(SUM(tax_amount), 0)").Scan(&totalTax)
 	i.TotalHours = totalHours
+	i.TotalBilledHours = totalBilledHours
 	i.TotalFees = float64(totalFeesInt) / 100.0
 	i.TotalAdjustments = totalAdjustments
 	i.TotalExpenses = float64(totalExpensesInt) / 100.0
-	i.TotalAmount = i.TotalFees + i.TotalAdjustments + i.TotalExpenses
+	i.TotalTax = float64(totalTax) / 100.0
+	i.TotalAmount = i.TotalFees + i.TotalAdjustments + i.TotalExpenses + i.TotalTax
+	if i.State == InvoiceStatePaid.String() {
+		i.AmountPaid = i.TotalAmount - i.AmountCredited - i.AmountDiscounted
+	}
+	i.BalanceDue = i.TotalAmount - i.AmountPaid - i.AmountCredited - i.AmountDiscounted
 	a.DB.Omit(clause.Associations).Save(&i)
 }
 
+// invoiceFX returns the rate that converts amounts quoted in `from` into the invoice's currency, taken
+// on the date the invoice was approved (today while it's a draft). It's 1 when there's nothing to
+// convert: `from` is empty or the invoice's currency, or the invoice's currency isn't set yet.
+func (a *App) invoiceFX(invoice *Invoice, from string) (float64, error) {
+	if from == "" || invoice.Currency == "" || strings.EqualFold(from, invoice.Currency) {
+		return 1, nil
+	}
+	on := invoice.AcceptedAt
+	if on.IsZero() {
+		on = time.Now()
+	}
+	return a.GetExchangeRate(invoice.TenantID, from, invoice.Currency, on)
+}
+
+// convertAmount converts cents at a rate from invoiceFX
+func convertAmount(amount int64, fx float64) int64 {
+	if fx == 1 {
+		return amount
+	}
+	return int64(math.Round(float64(amount) * fx))
+}
+
 // GenerateInvoiceLineItems creates line items for an invoice
 // Entries are rolled up by billing code, adjustments are separate line items
 func (a *App) GenerateInvoiceLineItems(invoice *Invoice) error {
@@ -1158,8 +2041,9 @@ func (a *App) GenerateInvoiceLineItems(invoice *Invoice) error {
 		var billingCode BillingCode
 		var rate float64
 
+		// Quantities are billed hours, after rounding, so they multiply out to the amount
 		for _, entry := range bcEntries {
-			totalHours += entry.Duration().Hours()
+			totalHours += entry.BilledHours()
 			totalAmount += int64(entry.Fee)
 			entryIDs = append(entryIDs, entry.ID)
 			billingCode = entry.BillingCode
@@ -1168,6 +2052,26 @@ func (a *App) GenerateInvoiceLineItems(invoice *Invoice) error {
 			}
 		}
 
+		// Hours on fixed-fee projects are billed through the project's milestones
+		if a.isFixedFeeProject(billingCode.ProjectID) {
+			continue
+		}
+
+		// Rate cards can bill the code's hours at several rates, so show the blended rate instead
+		if totalHours > 0 {
+			if blended := float64(totalAmount) / 100 / totalHours; math.Abs(blended-rate) >= 0.01 {
+				rate = math.Round(blended*100) / 100
+			}
+		}
+
+		// Rates quoted in another currency are converted into the invoice's currency
+		fx, err := a.invoiceFX(invoice, billingCode.Rate.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s rate for %s: %w", billingCode.Rate.Currency, billingCode.Name, err)
+		}
+		totalAmount = convertAmount(totalAmount, fx)
+		rate *= fx
+
 		// Marshal entry IDs to JSON
 		entryIDsJSON, _ := json.Marshal(entryIDs)
 
@@ -1248,7 +2152,23 @@ func (a *App) GenerateInvoiceLineItems(invoice *Invoice) error {
 		}
 	}
 
-	return nil
+	// Create line items for milestones completed on fixed-fee projects
+	var milestones []Milestone
+	if err := a.DB.Where("invoice_id = ?", invoice.ID).Order("completed_at, id").Find(&milestones).Error; err != nil {
+		return fmt.Errorf("failed to load milestones: %w", err)
+	}
+
+	for i := range milestones {
+		lineItem, err := a.milestoneLineItem(invoice, &milestones[i])
+		if err != nil {
+			return err
+		}
+		if err := a.DB.Create(&lineItem).Error; err != nil {
+			return fmt.Errorf("failed to create milestone line item: %w", err)
+		}
+	}
+
+	return a.CalculateInvoiceTaxes(invoice)
 }
 
 // GenerateBillLineItems creates line items for a bill
@@ -1523,6 +2443,12 @@ func (a *App) GetInvoiceAdjustments(i *Invoice) []Adjustment {
 	return adjustments
 }
 
+func (a *App) GetInvoiceMilestones(i *Invoice) []Milestone {
+	var milestones []Milestone
+	a.DB.Where("invoice_id = ?", i.ID).Order("completed_at, id").Find(&milestones)
+	return milestones
+}
+
 func (i *Invoice) GetInvoiceFilename() string {
 	filename := strings.Replace(i.Name, " ", "_", -1)
 	filename = strings.Replace(filename, ":", "", -1)
@@ -1569,6 +2495,7 @@ type ApiEntry struct {
 	EmployeeName        string    `json:"employee_name,omitempty"`
 	IsBeingImpersonated bool      `json:"is_being_impersonated,omitempty"`
 	IsMeeting           bool      `json:"is_meeting"`
+	Warnings            []string  `json:"warnings,omitempty"` // Entry rules the entry broke that only warn
 }
 
 func (e *Entry) GetAPIEntry() ApiEntry {
@@ -1623,6 +2550,7 @@ type DraftEntry struct {
 	Notes                 string  `json:"notes"`
 	StartDate             string  `json:"start_date"`
 	DurationHours         float64 `json:"duration_hours"`
+	BilledHours           float64 `json:"billed_hours"` // DurationHours after rounding
 	Fee                   float64 `json:"fee"`
 	EmployeeName          string  `json:"user_name"`
 	EmployeeRole          string  `json:"user_role"`
@@ -1682,6 +2610,7 @@ func (a *App) GetDraftEntry(e *Entry) DraftEntry {
 		Notes:                 e.Notes,
 		StartDate:             e.Start.Format("01/02/2006"),
 		DurationHours:         e.Duration().Hours(),
+		BilledHours:           e.BilledHours(),
 		Fee:                   float64(e.Fee) / 100.0,
 		EmployeeName:          employeeName,
 		EmployeeRole:          employeeRole,
@@ -1705,8 +2634,10 @@ type DraftInvoice struct {
 	LineItems        []DraftEntry `json:"line_items"`
 	Expenses         []Expense    `json:"expenses"`
 	Adjustments      []Adjustment `json:"adjustments"`
+	Milestones       []Milestone  `json:"milestones"`
 	TotalHours       float64      `json:"total_hours"`
-	TotalFees        float64      `json:"total_fees"`
+	TotalBilledHours float64      `json:"total_billed_hours"` // TotalHours after rounding, which the fees are billed on
+	TotalFees        float64      `json:"total_fees"`         // Includes completed milestones
 	TotalExpenses    float64      `json:"total_expenses"`
 	TotalAdjustments float64      `json:"total_adjustments"`
 	TotalAmount      float64      `json:"total_amount"`
@@ -1716,6 +2647,7 @@ type DraftInvoice struct {
 type AcceptedInvoice struct {
 	InvoiceID      uint                     `json:"ID"`
 	InvoiceName    string                   `json:"invoice_name"`
+	InvoiceNumber  string                   `json:"invoice_number"`
 	AccountID      uint                     `json:"account_id"`
 	AccountName    string                   `json:"account_name"`
 	ProjectID      uint                     `json:"project_id"`
@@ -1725,11 +2657,16 @@ type AcceptedInvoice struct {
 	File           string                   `json:"file"`
 	LineItemsCount int                      `json:"line_items_count"`
 	TotalHours     float64                  `json:"total_hours"`
+	BilledHours    float64                  `json:"billed_hours"`
 	TotalFees      float64                  `json:"total_fees"`
+	TotalTax       float64                  `json:"total_tax"`
+	Currency       string                   `json:"currency"`
 	State          string                   `json:"state"`
 	SentAt         string                   `json:"sent_at"`
 	DueAt          string                   `json:"due_at"`
 	ClosedAt       string                   `json:"closed_at"`
+	AmountPaid     float64                  `json:"amount_paid"`
+	BalanceDue     float64                  `json:"balance_due"`
 	LineItems      []InvoiceLineItemDisplay `json:"line_items"`
 }
 
@@ -1779,6 +2716,7 @@ func (a *App) GetDraftInvoice(i *Invoice) DraftInvoice {
 			Notes:                 entry.Notes,
 			StartDate:             entry.Start.In(time.UTC).Format("01/02/2006"),
 			DurationHours:         durationHours,
+			BilledHours:           entry.BilledHours(),
 			State:                 entry.State,
 			Fee:                   float64(entry.Fee) / 100.0,
 			EmployeeName:          employeeName,
@@ -1793,13 +2731,14 @@ func (a *App) GetDraftInvoice(i *Invoice) DraftInvoice {
 	}
 
 	// Calculate totals
-	var totalHours, totalFees, totalAmount float64
+	var totalHours, totalBilledHours, totalFees, totalAmount float64
 	var totalAdjustments float64
 
 	// Sum entry fees and hours
 	for _, entry := range draftEntries {
 		if entry.State != EntryStateVoid.String() {
 			totalHours += entry.DurationHours
+			totalBilledHours += entry.BilledHours
 			totalFees += entry.Fee
 		}
 	}
@@ -1833,6 +2772,12 @@ func (a *App) GetDraftInvoice(i *Invoice) DraftInvoice {
 		totalExpenses += float64(expense.Amount) / 100.0
 	}
 
+	// Completed milestones are billed as fees
+	milestones := a.GetInvoiceMilestones(i)
+	for _, milestone := range milestones {
+		totalFees += float64(milestone.Amount) / 100.0
+	}
+
 	totalAmount = totalFees + totalExpenses + totalAdjustments
 
 	return DraftInvoice{
@@ -1847,7 +2792,9 @@ func (a *App) GetDraftInvoice(i *Invoice) DraftInvoice {
 		LineItems:        draftEntries,
 		Expenses:         expenses,
 		Adjustments:      adjustments,
+		Milestones:       milestones,
 		TotalHours:       totalHours,
+		TotalBilledHours: totalBilledHours,
 		TotalFees:        totalFees,
 		TotalExpenses:    totalExpenses,
 		TotalAdjustments: totalAdjustments,
@@ -1863,19 +2810,25 @@ func (a *App) GetAcceptedInvoice(i *Invoice) AcceptedInvoice {
 	}
 
 	acceptedInvoice := AcceptedInvoice{
-		InvoiceID:   i.ID,
-		InvoiceName: i.Name,
-		AccountID:   i.AccountID,
-		AccountName: i.Account.Name,
-		PeriodStart: i.PeriodStart.In(time.UTC).Format("01/02/2006"),
-		PeriodEnd:   i.PeriodEnd.In(time.UTC).Format("01/02/2006"),
-		File:        i.GCSFile,
-		TotalHours:  i.TotalHours,
-		TotalFees:   i.TotalFees,
-		State:       i.State,
-		SentAt:      i.SentAt.Format("01/02/2006"),
-		DueAt:       i.DueAt.Format("01/02/2006"),
-		ClosedAt:    i.ClosedAt.Format("01/02/2006"),
+		InvoiceID:     i.ID,
+		InvoiceName:   i.Name,
+		InvoiceNumber: i.Number,
+		AccountID:     i.AccountID,
+		AccountName:   i.Account.Name,
+		PeriodStart:   i.PeriodStart.In(time.UTC).Format("01/02/2006"),
+		PeriodEnd:     i.PeriodEnd.In(time.UTC).Format("01/02/2006"),
+		File:          i.GCSFile,
+		TotalHours:    i.TotalHours,
+		BilledHours:   i.TotalBilledHours,
+		TotalFees:     i.TotalFees,
+		TotalTax:      i.TotalTax,
+		Currency:      i.Currency,
+		State:         i.State,
+		SentAt:        i.SentAt.Format("01/02/2006"),
+		DueAt:         i.DueAt.Format("01/02/2006"),
+		ClosedAt:      i.ClosedAt.Format("01/02/2006"),
+		AmountPaid:    i.AmountPaid,
+		BalanceDue:    i.BalanceDue,
 	}
 
 	// Handle project information if available
@@ -2779,9 +3732,6 @@ func (a *App) approveInternalExpense(expense *Expense, approverID uint) error {
 		Debit:      amountCents,
 		Credit:     0,
 	}
-	if err := a.DB.Create(&expenseDR).Error; err != nil {
-		return fmt.Errorf("failed to book internal expense debit: %w", err)
-	}
 
 	// Determine credit account based on reimbursable flag
 	var creditAccount string
@@ -2813,8 +3763,14 @@ func (a *App) approveInternalExpense(expense *Expense, approverID uint) error {
 		Debit:      0,
 		Credit:     amountCents,
 	}
-	if err := a.DB.Create(&accrualCR).Error; err != nil {
-		return fmt.Errorf("failed to book internal expense credit: %w", err)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:   expense.TenantID,
+		SourceType: JournalSourceExpense.String(),
+		SourceID:   &expense.ID,
+		Memo:       fmt.Sprintf("Internal expense: %s", expense.Description),
+		Lines:      []Journal{expenseDR, accrualCR},
+	}); err != nil {
+		return fmt.Errorf("failed to book internal expense: %w", err)
 	}
 
 	expenseType := "company paid"
@@ -196,8 +196,8 @@ func (a *App) GetCombinedJournals(startDate, endDate time.Time) ([]Journal, erro
 
 	// Get regular journals
 	var journals []Journal
-	err := a.DB.Where("created_at >= ? AND created_at <= ?", startDate, endDate).
-		Order("created_at ASC").
+	err := a.DB.Where("effective_date >= ? AND effective_date <= ?", startDate, endDate).
+		Order("effective_date ASC").
 		Find(&journals).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to load journals: %w", err)
@@ -222,6 +222,7 @@ func (a *App) GetCombinedJournals(startDate, endDate time.Time) ([]Journal, erro
 			Memo:       offline.Description,
 		}
 		journal.CreatedAt = offline.Date
+		journal.EffectiveDate = offline.Date
 		combinedJournals = append(combinedJournals, journal)
 	}
 
@@ -671,54 +672,92 @@ func (a *App) CategorizeCSVTransaction(date time.Time, description string,
 }
 
 // ApproveAndBookOfflineJournals approves offline journals and books them to the main GL
-// This approves matching pairs of debit/credit entries together
+// This approves matching pairs of debit/credit entries together: each transaction group is booked as one
+// journal transaction, and a group that doesn't balance is left pending review
 func (a *App) ApproveAndBookOfflineJournals(ids []uint, staffID uint) (int, error) {
+	var offlines []OfflineJournal
+	if err := a.DB.Where("id IN ?", ids).Order("id").Find(&offlines).Error; err != nil {
+		return 0, fmt.Errorf("failed to load offline journals: %w", err)
+	}
+
 	booked := 0
+	for _, group := range groupOfflineJournals(offlines) {
+		// Mark as approved
+		now := time.Now()
+		var groupIDs []uint
+		for _, offline := range group {
+			groupIDs = append(groupIDs, offline.ID)
+		}
+		if err := a.DB.Model(&OfflineJournal{}).Where("id IN ?", groupIDs).Updates(map[string]interface{}{
+			"status":      "approved",
+			"reviewed_at": now,
+			"reviewed_by": staffID,
+		}).Error; err != nil {
+			log.Printf("Error approving offline journals %v: %v", groupIDs, err)
+			continue
+		}
 
-	for _, id := range ids {
-		var offline OfflineJournal
-		if err := a.DB.First(&offline, id).Error; err != nil {
-			log.Printf("Error loading offline journal %d: %v", id, err)
+		// Create corresponding Journal transaction, dated to the original transaction
+		txn := offlineJournalTransaction(group)
+		if err := a.PostJournalTransaction(&txn); err != nil {
+			log.Printf("Error booking offline journals %v to GL: %v", groupIDs, err)
+			// Revert approval
+			a.DB.Model(&OfflineJournal{}).Where("id IN ?", groupIDs).Updates(map[string]interface{}{
+				"status":      "pending_review",
+				"reviewed_at": nil,
+				"reviewed_by": nil,
+			})
 			continue
 		}
 
-		// Mark as approved
-		now := time.Now()
-		offline.Status = "approved"
-		offline.ReviewedAt = &now
-		offline.ReviewedBy = &staffID
+		booked += len(group)
+		log.Printf("Booked offline journals %v to GL as journal transaction %d", groupIDs, txn.ID)
+	}
 
-		if err := a.DB.Save(&offline).Error; err != nil {
-			log.Printf("Error approving offline journal %d: %v", id, err)
-			continue
+	return booked, nil
+}
+
+// groupOfflineJournals splits offline journals into the transactions they came from, by
+// TransactionGroupID or, for legacy entries, by date and description
+func groupOfflineJournals(offlines []OfflineJournal) [][]OfflineJournal {
+	var groups [][]OfflineJournal
+	index := make(map[string]int)
+	for _, offline := range offlines {
+		key := offline.TransactionGroupID
+		if key == "" {
+			key = offline.Date.Format("2006-01-02") + "|" + offline.Description
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
 		}
+		groups[i] = append(groups[i], offline)
+	}
+	return groups
+}
 
-		// Create corresponding Journal entry
-		journal := Journal{
+// offlineJournalTransaction builds the GL transaction for one group of offline journals
+func offlineJournalTransaction(group []OfflineJournal) JournalTransaction {
+	txn := JournalTransaction{
+		TenantID:      group[0].TenantID,
+		EffectiveDate: group[0].Date,
+		SourceType:    JournalSourceOfflineJournal.String(),
+		SourceID:      &group[0].ID,
+		Memo:          group[0].Description,
+	}
+	for _, offline := range group {
+		txn.Lines = append(txn.Lines, Journal{
 			Account:    offline.Account,
 			SubAccount: offline.SubAccount,
 			Memo:       offline.Description, // Source description from original transaction
 			Notes:      offline.Notes,       // User-added notes/context
 			Debit:      offline.Debit,
 			Credit:     offline.Credit,
-		}
-		journal.CreatedAt = offline.Date // Backdate to transaction date
-
-		if err := a.DB.Create(&journal).Error; err != nil {
-			log.Printf("Error booking offline journal %d to GL: %v", id, err)
-			// Revert approval
-			offline.Status = "pending_review"
-			offline.ReviewedAt = nil
-			offline.ReviewedBy = nil
-			a.DB.Save(&offline)
-			continue
-		}
-
-		booked++
-		log.Printf("Booked offline journal %d to GL as journal entry %d", id, journal.ID)
+		})
 	}
-
-	return booked, nil
+	return txn
 }
 
 // ApproveTransactionPair approves both sides of a transaction (debit and credit)
@@ -837,24 +876,23 @@ func (a *App) PostOfflineJournalsToGL(ids []uint) error {
 		}
 	}
 
-	// Create Journal entries for each
-	for _, offline := range journals {
-		journal := Journal{
-			Account:    offline.Account,
-			SubAccount: offline.SubAccount,
-			Memo:       offline.Description, // Source description from original transaction
-			Notes:      offline.Notes,       // User-added notes/context
-			Debit:      offline.Debit,
-			Credit:     offline.Credit,
+	// Check every transaction balances before posting any of them
+	groups := groupOfflineJournals(journals)
+	for _, group := range groups {
+		txn := offlineJournalTransaction(group)
+		if err := ValidateJournalLines(txn.Lines); err != nil {
+			return fmt.Errorf("offline journal %d (%s): %w", group[0].ID, group[0].Description, err)
 		}
-		// Backdate to original transaction date
-		journal.CreatedAt = offline.Date
+	}
 
-		if err := a.DB.Create(&journal).Error; err != nil {
-			return fmt.Errorf("failed to create journal entry from offline journal %d: %w", offline.ID, err)
+	// Create a Journal transaction for each, backdated to the original transaction date
+	for _, group := range groups {
+		txn := offlineJournalTransaction(group)
+		if err := a.PostJournalTransaction(&txn); err != nil {
+			return fmt.Errorf("failed to create journal entries from offline journal %d: %w", group[0].ID, err)
 		}
 
-		log.Printf("Posted offline journal %d to GL as journal entry %d (memo: %s, notes: %s)", offline.ID, journal.ID, offline.Description, offline.Notes)
+		log.Printf("Posted %d offline journals to GL as journal transaction %d (memo: %s)", len(group), txn.ID, group[0].Description)
 	}
 
 	// Mark offline journals as posted
@@ -1039,29 +1077,42 @@ func (a *App) ReverseJournalEntry(journalID uint, reason string, correctedEntry
 		return fmt.Errorf("failed to find journal entry: %w", err)
 	}
 
-	// Create reversing entry (swap debit and credit)
-	reversing := Journal{
-		Account:    original.Account,
-		SubAccount: original.SubAccount,
-		Memo:       fmt.Sprintf("REVERSAL: %s (Original: %s)", reason, original.Memo),
-		Debit:      original.Credit, // Swap
-		Credit:     original.Debit,  // Swap
+	// Without a correction the rest of the original transaction has to be reversed with the entry to
+	// keep the books balanced. Legacy entries with no transaction can only be reversed with a correction.
+	originals := []Journal{original}
+	if correctedEntry == nil && original.TransactionID != nil {
+		if err := a.DB.Where("transaction_id = ?", *original.TransactionID).Order("id").Find(&originals).Error; err != nil {
+			return fmt.Errorf("failed to load journal transaction: %w", err)
+		}
 	}
 
-	if err := a.DB.Create(&reversing).Error; err != nil {
-		return fmt.Errorf("failed to create reversing entry: %w", err)
+	txn := JournalTransaction{
+		TenantID:   original.TenantID,
+		SourceType: JournalSourceReversal.String(),
+		SourceID:   &original.ID,
+		Memo:       fmt.Sprintf("REVERSAL: %s", reason),
+	}
+	// Create reversing entries (swap debit and credit)
+	for _, entry := range originals {
+		txn.Lines = append(txn.Lines, Journal{
+			Account:    entry.Account,
+			SubAccount: entry.SubAccount,
+			Memo:       fmt.Sprintf("REVERSAL: %s (Original: %s)", reason, entry.Memo),
+			Debit:      entry.Credit, // Swap
+			Credit:     entry.Debit,  // Swap
+		})
 	}
 
-	log.Printf("Created reversing entry %d for journal %d", reversing.ID, journalID)
-
-	// If a corrected entry is provided, create it
+	// If a corrected entry is provided, post it with the reversal
 	if correctedEntry != nil {
 		correctedEntry.Memo = fmt.Sprintf("CORRECTION: %s (Original entry: #%d)", correctedEntry.Memo, journalID)
-		if err := a.DB.Create(correctedEntry).Error; err != nil {
-			return fmt.Errorf("failed to create corrected entry: %w", err)
-		}
-		log.Printf("Created corrected entry %d", correctedEntry.ID)
+		txn.Lines = append(txn.Lines, *correctedEntry)
+	}
+
+	if err := a.PostJournalTransaction(&txn); err != nil {
+		return fmt.Errorf("failed to create reversing entry: %w", err)
 	}
 
+	log.Printf("Created reversing transaction %d for journal %d", txn.ID, journalID)
 	return nil
 }
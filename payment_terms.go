@@ -0,0 +1,213 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPaymentTerms = errors.New("invalid payment terms")
+
+// DefaultPaymentTerms applies to invoices whose account and project don't set their own
+const DefaultPaymentTerms = "Net 30"
+
+// PaymentTerms say when an invoice falls due and whether paying early earns a discount, written the
+// usual way: "Net 30", "Due on Receipt", "Net 15 EOM" or "2/10 Net 30" (2% off if paid within 10 days)
+type PaymentTerms struct {
+	NetDays         int     `json:"net_days"`
+	EndOfMonth      bool    `json:"end_of_month"`     // Count NetDays from the end of the month the invoice was sent in
+	DiscountPercent float64 `json:"discount_percent"` // Early-payment discount, zero for none
+	DiscountDays    int     `json:"discount_days"`    // Days after sending the discount is available for
+}
+
+// ParsePaymentTerms reads terms like "Net 45", "EOM", "Net 30 EOM" or "1.5/15 Net 45". Case and spacing
+// don't matter, and empty terms are the default Net 30.
+func ParsePaymentTerms(s string) (PaymentTerms, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return PaymentTerms{NetDays: 30}, nil
+	}
+	invalid := fmt.Errorf("%w: %q", ErrInvalidPaymentTerms, s)
+
+	var terms PaymentTerms
+	if percent, days, ok := strings.Cut(fields[0], "/"); ok {
+		var err error
+		if terms.DiscountPercent, err = strconv.ParseFloat(percent, 64); err != nil || terms.DiscountPercent <= 0 || terms.DiscountPercent >= 100 {
+			return terms, invalid
+		}
+		if terms.DiscountDays, err = strconv.Atoi(days); err != nil || terms.DiscountDays < 0 {
+			return terms, invalid
+		}
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && fields[len(fields)-1] == "eom" {
+		terms.EndOfMonth = true
+		fields = fields[:len(fields)-1]
+	}
+
+	switch {
+	case len(fields) == 0 && terms.EndOfMonth:
+		// "EOM" on its own: due at the end of the month
+	case len(fields) == 3 && fields[0] == "due" && fields[1] == "on" && fields[2] == "receipt" && !terms.EndOfMonth:
+	case len(fields) == 2 && fields[0] == "net":
+		days, err := strconv.Atoi(fields[1])
+		if err != nil || days < 0 {
+			return terms, invalid
+		}
+		terms.NetDays = days
+	default:
+		return terms, invalid
+	}
+	if terms.DiscountPercent > 0 && !terms.EndOfMonth && terms.DiscountDays >= terms.NetDays {
+		return terms, fmt.Errorf("%w: the discount period must end before the invoice is due", ErrInvalidPaymentTerms)
+	}
+	return terms, nil
+}
+
+// String writes the terms in their canonical form, as printed on invoices
+func (t PaymentTerms) String() string {
+	var s string
+	switch {
+	case t.EndOfMonth && t.NetDays == 0:
+		s = "EOM"
+	case t.EndOfMonth:
+		s = fmt.Sprintf("Net %d EOM", t.NetDays)
+	case t.NetDays == 0:
+		s = "Due on Receipt"
+	default:
+		s = fmt.Sprintf("Net %d", t.NetDays)
+	}
+	if t.DiscountPercent > 0 {
+		s = fmt.Sprintf("%s/%d %s", strconv.FormatFloat(t.DiscountPercent, 'f', -1, 64), t.DiscountDays, s)
+	}
+	return s
+}
+
+// DueDate is when an invoice sent at the given time falls due under these terms
+func (t PaymentTerms) DueDate(sentAt time.Time) time.Time {
+	if t.EndOfMonth {
+		// Day zero of the next month is the last day of this one
+		sentAt = time.Date(sentAt.Year(), sentAt.Month()+1, 0, sentAt.Hour(), sentAt.Minute(), sentAt.Second(), 0, sentAt.Location())
+	}
+	return sentAt.AddDate(0, 0, t.NetDays)
+}
+
+// DiscountAvailable reports whether a payment on the given day, for an invoice sent at sentAt, is early
+// enough to take the discount. The last day of the discount period counts.
+func (t PaymentTerms) DiscountAvailable(sentAt, paidAt time.Time) bool {
+	if t.DiscountPercent <= 0 || sentAt.IsZero() {
+		return false
+	}
+	deadline := sentAt.AddDate(0, 0, t.DiscountDays)
+	deadline = time.Date(deadline.Year(), deadline.Month(), deadline.Day(), 0, 0, 0, 0, time.UTC)
+	paid := time.Date(paidAt.Year(), paidAt.Month(), paidAt.Day(), 0, 0, 0, 0, time.UTC)
+	return !paid.After(deadline)
+}
+
+// Discount is the early-payment discount on an invoice total, in cents
+func (t PaymentTerms) Discount(totalAmount float64) int64 {
+	return int64(math.Round(totalAmount * t.DiscountPercent))
+}
+
+// invoicePaymentTerms works out which terms an invoice is sent under: the project's if it overrides
+// them, otherwise the account's, otherwise Net 30. Terms that no longer parse fall back to the default
+// rather than holding up the invoice.
+func (a *App) invoicePaymentTerms(invoice *Invoice) PaymentTerms {
+	source := ""
+	if invoice.ProjectID != nil {
+		var project Project
+		if err := a.DB.Select("payment_terms").Limit(1).Find(&project, *invoice.ProjectID).Error; err == nil {
+			source = project.PaymentTerms
+		}
+	}
+	if source == "" {
+		account := invoice.Account
+		if account.ID == 0 {
+			a.DB.Select("payment_terms").Limit(1).Find(&account, invoice.AccountID)
+		}
+		source = account.PaymentTerms
+	}
+	terms, err := ParsePaymentTerms(source)
+	if err != nil {
+		terms, _ = ParsePaymentTerms(DefaultPaymentTerms)
+	}
+	return terms
+}
+
+// applyPaymentTerms records the terms an invoice is sent under and sets its due date from them
+func (a *App) applyPaymentTerms(invoice *Invoice) {
+	terms := a.invoicePaymentTerms(invoice)
+	invoice.PaymentTerms = terms.String()
+	invoice.DueAt = terms.DueDate(invoice.SentAt)
+}
+
+// takeEarlyPaymentDiscount gives an invoice its early-payment discount when a payment arriving within
+// the discount period, together with the discount, pays it off. The discount is booked
+// DR DISCOUNTS / CR ACCOUNTS_RECEIVABLE so the payment itself then settles the invoice. Returns the
+// discount taken in cents, zero when none applies.
+func (a *App) takeEarlyPaymentDiscount(payment *Payment, invoice *Invoice, amount int64) (int64, error) {
+	if invoice.AmountDiscounted > 0 || invoice.PaymentTerms == "" {
+		return 0, nil
+	}
+	terms, err := ParsePaymentTerms(invoice.PaymentTerms)
+	if err != nil || !terms.DiscountAvailable(invoice.SentAt, payment.ReceivedAt) {
+		return 0, nil
+	}
+	shortfall := InvoiceBalanceDue(invoice) - amount
+	if shortfall <= 0 || shortfall > terms.Discount(invoice.TotalAmount) {
+		return 0, nil
+	}
+
+	if invoice.Account.ID == 0 {
+		a.DB.First(&invoice.Account, invoice.AccountID)
+	}
+	subAccount := fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name)
+	var arEntry Journal
+	if err := a.DB.Where("invoice_id = ? AND account = ? AND debit > 0", invoice.ID, AccountAccountsReceivable.String()).
+		First(&arEntry).Error; err == nil {
+		subAccount = arEntry.SubAccount
+	}
+
+	memo := fmt.Sprintf("Early payment discount (%s) on invoice #%d", invoice.PaymentTerms, invoice.ID)
+	discount := Journal{
+		TenantID:   invoice.TenantID,
+		Account:    AccountDiscounts.String(),
+		SubAccount: fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name),
+		InvoiceID:  &invoice.ID,
+		Memo:       memo,
+		Debit:      shortfall,
+	}
+	discount.translate(invoice.Currency, invoice.ExchangeRate)
+	clearAR := Journal{
+		TenantID:   invoice.TenantID,
+		Account:    AccountAccountsReceivable.String(),
+		SubAccount: subAccount,
+		InvoiceID:  &invoice.ID,
+		Memo:       memo,
+		Credit:     shortfall,
+	}
+	clearAR.translate(invoice.Currency, invoice.ExchangeRate)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      invoice.TenantID,
+		EffectiveDate: payment.ReceivedAt,
+		SourceType:    JournalSourcePayment.String(),
+		SourceID:      &payment.ID,
+		Memo:          memo,
+		Lines:         []Journal{discount, clearAR},
+	}); err != nil {
+		return 0, fmt.Errorf("failed to book early payment discount: %w", err)
+	}
+
+	invoice.AmountDiscounted = float64(shortfall) / 100
+	invoice.BalanceDue = invoice.TotalAmount - invoice.AmountPaid - invoice.AmountCredited - invoice.AmountDiscounted
+	if err := a.DB.Model(invoice).Updates(map[string]interface{}{
+		"amount_discounted": invoice.AmountDiscounted,
+		"balance_due":       invoice.BalanceDue,
+	}).Error; err != nil {
+		return 0, fmt.Errorf("failed to update invoice discount: %w", err)
+	}
+	return shortfall, nil
+}
@@ -0,0 +1,92 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPaymentTerms tests parsing and due dates, the project override, and that paying within the
+// discount period books the discount to DISCOUNTS and settles the invoice
+func TestPaymentTerms(t *testing.T) {
+	sent := time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC)
+	for input, want := range map[string]struct {
+		canonical string
+		due       time.Time
+	}{
+		"":               {"Net 30", time.Date(2025, 2, 19, 9, 0, 0, 0, time.UTC)},
+		"net 15":         {"Net 15", time.Date(2025, 2, 4, 9, 0, 0, 0, time.UTC)},
+		"Due on Receipt": {"Due on Receipt", sent},
+		"eom":            {"EOM", time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)},
+		"Net 45 EOM":     {"Net 45 EOM", time.Date(2025, 3, 17, 9, 0, 0, 0, time.UTC)},
+		"2/10  NET 30":   {"2/10 Net 30", time.Date(2025, 2, 19, 9, 0, 0, 0, time.UTC)},
+	} {
+		terms, err := ParsePaymentTerms(input)
+		if err != nil {
+			t.Errorf("Expected %q to parse: %v", input, err)
+			continue
+		}
+		if terms.String() != want.canonical || !terms.DueDate(sent).Equal(want.due) {
+			t.Errorf("Expected %q to be %s due %s, got %s due %s", input, want.canonical, want.due, terms, terms.DueDate(sent))
+		}
+	}
+	for _, input := range []string{"Net", "Net thirty", "5/30 Net 30", "Due whenever"} {
+		if _, err := ParsePaymentTerms(input); !errors.Is(err, ErrInvalidPaymentTerms) {
+			t.Errorf("Expected %q to be refused, got %v", input, err)
+		}
+	}
+
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "terms", Name: "Terms Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Payment Test Account", LegalName: "Early Payer LLC", Type: AccountTypeClient.String(), PaymentTerms: "2/10 Net 30"}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Rush Job", AccountID: account.ID, PaymentTerms: "Due on Receipt"}
+	db.Create(&project)
+
+	projectInvoice := Invoice{AccountID: account.ID, ProjectID: &project.ID, SentAt: sent}
+	app.applyPaymentTerms(&projectInvoice)
+	if projectInvoice.PaymentTerms != "Due on Receipt" || !projectInvoice.DueAt.Equal(sent) {
+		t.Errorf("Expected the project's terms to win, got %s due %s", projectInvoice.PaymentTerms, projectInvoice.DueAt)
+	}
+
+	// 2% of 1000.00 is 20.00, so 980.00 within ten days pays the invoice off
+	invoice := createSentInvoice(t, db, tenant.ID, account, 100000)
+	invoice.Account = account
+	invoice.SentAt = sent
+	app.applyPaymentTerms(&invoice)
+	db.Model(&invoice).Updates(map[string]interface{}{"sent_at": invoice.SentAt, "due_at": invoice.DueAt, "payment_terms": invoice.PaymentTerms})
+
+	late := createSentInvoice(t, db, tenant.ID, account, 50000)
+	db.Model(&late).Updates(map[string]interface{}{"sent_at": sent, "payment_terms": "2/10 Net 30"})
+
+	payment := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 98000, ReceivedAt: sent.AddDate(0, 0, 10)}
+	if err := app.RecordPayment(&payment, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 98000}}); err != nil {
+		t.Fatalf("Failed to record payment: %v", err)
+	}
+	var reloaded Invoice
+	db.First(&reloaded, invoice.ID)
+	if reloaded.State != InvoiceStatePaid.String() || reloaded.AmountDiscounted != 20 || reloaded.BalanceDue > 0.005 || arBalance(db, invoice.ID) != 0 {
+		t.Errorf("Expected the invoice paid with a 20.00 discount, got %s with %.2f discounted, %.2f due and %d in AR",
+			reloaded.State, reloaded.AmountDiscounted, reloaded.BalanceDue, arBalance(db, invoice.ID))
+	}
+	var discounts int64
+	db.Table("journals").Select("COALESCE(SUM(debit - credit), 0)").Where("account = ?", AccountDiscounts.String()).Scan(&discounts)
+	if discounts != 2000 {
+		t.Errorf("Expected 20.00 booked to DISCOUNTS, got %d", discounts)
+	}
+
+	// The same short payment after the discount period only pays part of the invoice
+	payment = Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 49000, ReceivedAt: sent.AddDate(0, 0, 11)}
+	if err := app.RecordPayment(&payment, []PaymentAllocationRequest{{InvoiceID: late.ID, Amount: 49000}}); err != nil {
+		t.Fatalf("Failed to record late payment: %v", err)
+	}
+	var partial Invoice
+	db.First(&partial, late.ID)
+	if partial.State != InvoiceStatePartiallyPaid.String() || partial.AmountDiscounted != 0 || partial.BalanceDue != 10 {
+		t.Errorf("Expected no discount after the period, got %s with %.2f discounted and %.2f due", partial.State, partial.AmountDiscounted, partial.BalanceDue)
+	}
+}
@@ -0,0 +1,521 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrPaymentNotFound = errors.New("payment not found")
+var ErrInvalidPaymentAmount = errors.New("payment amounts must be greater than zero")
+var ErrPaymentOverAllocated = errors.New("allocations exceed the payment amount")
+var ErrAllocationExceedsBalance = errors.New("allocation exceeds the invoice balance due")
+var ErrInvoiceNotPayable = errors.New("invoice is not awaiting payment")
+var ErrInsufficientCredit = errors.New("account does not have enough unapplied credit")
+var ErrPaymentAlreadyReconciled = errors.New("payment is already reconciled")
+var ErrPaymentNotReconciled = errors.New("payment is not reconciled")
+var ErrBankLineAlreadyReconciled = errors.New("bank transaction is already reconciled")
+
+// PaymentAllocationRequest asks for part of a payment to be applied to an invoice
+type PaymentAllocationRequest struct {
+	InvoiceID uint  `json:"invoice_id"`
+	Amount    int64 `json:"amount"` // in cents
+}
+
+// InvoiceBalanceDue returns the amount still owed on an invoice in cents
+func InvoiceBalanceDue(invoice *Invoice) int64 {
+	return int64(math.Round((invoice.TotalAmount - invoice.AmountPaid - invoice.AmountCredited - invoice.AmountDiscounted) * 100))
+}
+
+// RecordPayment saves a payment received from a client account and applies it to the requested invoices.
+// Each allocation books DR CASH / CR ACCOUNTS_RECEIVABLE on the invoice, moving it to PARTIALLY_PAID or,
+// once nothing is left owing, through the normal MarkInvoicePaid flow. A payment that pays an invoice off
+// within its terms' discount period gets the discount booked to DISCOUNTS first. Whatever isn't allocated is booked
+// DR CASH / CR CUSTOMER_CREDITS and held as unapplied credit on the account.
+func (a *App) RecordPayment(payment *Payment, allocations []PaymentAllocationRequest) error {
+	if payment.Amount <= 0 {
+		return ErrInvalidPaymentAmount
+	}
+	if payment.ReceivedAt.IsZero() {
+		payment.ReceivedAt = time.Now()
+	}
+
+	var account Account
+	if err := a.DB.Scopes(TenantScope(payment.TenantID)).First(&account, payment.AccountID).Error; err != nil {
+		return fmt.Errorf("failed to load account %d: %w", payment.AccountID, err)
+	}
+
+	// Payments are in the account's currency unless told otherwise, and foreign ones are booked at the
+	// rate on the day they arrive
+	functional := a.functionalCurrency(payment.TenantID)
+	if payment.Currency == "" {
+		payment.Currency = account.Currency
+	}
+	payment.Currency = currencyOrFunctional(payment.Currency, functional)
+	if payment.ExchangeRate == 0 {
+		rate, err := a.foreignRate(payment.TenantID, payment.Currency, payment.ReceivedAt)
+		if err != nil {
+			return err
+		}
+		payment.ExchangeRate = rate
+	}
+
+	// Validate every allocation before booking anything so a bad request leaves the ledger untouched
+	invoices := make([]Invoice, len(allocations))
+	requested := make(map[uint]int64)
+	var allocated int64
+	for i, allocation := range allocations {
+		if allocation.Amount <= 0 {
+			return ErrInvalidPaymentAmount
+		}
+		if err := a.loadPayableInvoice(payment.TenantID, account.ID, allocation.InvoiceID, &invoices[i]); err != nil {
+			return err
+		}
+		if currencyOrFunctional(invoices[i].Currency, functional) != payment.Currency {
+			return fmt.Errorf("%w: invoice #%d is in %s", ErrCurrencyMismatch, allocation.InvoiceID, invoices[i].Currency)
+		}
+		requested[allocation.InvoiceID] += allocation.Amount
+		if requested[allocation.InvoiceID] > InvoiceBalanceDue(&invoices[i]) {
+			return fmt.Errorf("%w: invoice #%d", ErrAllocationExceedsBalance, allocation.InvoiceID)
+		}
+		allocated += allocation.Amount
+	}
+	if allocated > payment.Amount {
+		return ErrPaymentOverAllocated
+	}
+
+	// The payment, its allocations and their journals are saved together, so a failure part way
+	// through (a locked period, say) rolls all of it back
+	payment.UnappliedAmount = payment.Amount - allocated
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		txApp := *a
+		txApp.DB = tx
+		if err := tx.Create(payment).Error; err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+
+		for i, allocation := range allocations {
+			// Reload so repeated allocations to the same invoice see the previous one
+			if err := tx.First(&invoices[i], allocation.InvoiceID).Error; err != nil {
+				return fmt.Errorf("failed to reload invoice %d: %w", allocation.InvoiceID, err)
+			}
+			// Paying in full within the discount period earns the early-payment discount on the rest
+			if _, err := txApp.takeEarlyPaymentDiscount(payment, &invoices[i], allocation.Amount); err != nil {
+				return err
+			}
+			if err := txApp.allocatePayment(payment, &invoices[i], allocation.Amount, payment.ReceivedAt, false); err != nil {
+				return err
+			}
+		}
+
+		if payment.UnappliedAmount > 0 {
+			return txApp.bookUnappliedCredit(payment, &account)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Recorded payment %d for account %d: $%.2f (%d allocations, $%.2f unapplied)",
+		payment.ID, account.ID, float64(payment.Amount)/100, len(allocations), float64(payment.UnappliedAmount)/100)
+	return nil
+}
+
+// ApplyAccountCredit applies an account's unapplied credit to one of its open invoices, drawing from
+// the oldest payments first and then from credit notes. Books DR CUSTOMER_CREDITS / CR ACCOUNTS_RECEIVABLE.
+func (a *App) ApplyAccountCredit(tenantID, invoiceID uint, amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidPaymentAmount
+	}
+
+	var invoice Invoice
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&invoice, invoiceID).Error; err != nil {
+		return fmt.Errorf("failed to load invoice %d: %w", invoiceID, err)
+	}
+	if err := a.loadPayableInvoice(tenantID, invoice.AccountID, invoiceID, &invoice); err != nil {
+		return err
+	}
+	if amount > InvoiceBalanceDue(&invoice) {
+		return ErrAllocationExceedsBalance
+	}
+
+	var account Account
+	if err := a.DB.First(&account, invoice.AccountID).Error; err != nil {
+		return fmt.Errorf("failed to load account %d: %w", invoice.AccountID, err)
+	}
+	if amount > account.UnappliedCredit {
+		return ErrInsufficientCredit
+	}
+
+	var payments []Payment
+	if err := a.DB.Scopes(TenantScope(tenantID)).
+		Where("account_id = ? AND unapplied_amount > 0", account.ID).
+		Order("received_at asc, id asc").
+		Find(&payments).Error; err != nil {
+		return fmt.Errorf("failed to load payments with unapplied credit: %w", err)
+	}
+
+	now := time.Now()
+	functional := a.functionalCurrency(tenantID)
+	remaining := amount
+	for i := range payments {
+		if remaining == 0 {
+			break
+		}
+		if currencyOrFunctional(payments[i].Currency, functional) != currencyOrFunctional(invoice.Currency, functional) {
+			continue
+		}
+		portion := payments[i].UnappliedAmount
+		if portion > remaining {
+			portion = remaining
+		}
+		// Reload the invoice each time since a previous portion may have changed its balance or state
+		if err := a.DB.First(&invoice, invoiceID).Error; err != nil {
+			return fmt.Errorf("failed to reload invoice %d: %w", invoiceID, err)
+		}
+		if err := a.allocatePayment(&payments[i], &invoice, portion, now, true); err != nil {
+			return err
+		}
+		if err := a.DB.Model(&payments[i]).Update("unapplied_amount", gorm.Expr("unapplied_amount - ?", portion)).Error; err != nil {
+			return fmt.Errorf("failed to update payment %d: %w", payments[i].ID, err)
+		}
+		remaining -= portion
+	}
+
+	if remaining > 0 {
+		var notes []CreditNote
+		if err := a.DB.Scopes(TenantScope(tenantID)).
+			Where("account_id = ? AND state = ? AND unapplied_amount > 0", account.ID, CreditNoteStateIssued.String()).
+			Order("issued_at asc, id asc").
+			Find(&notes).Error; err != nil {
+			return fmt.Errorf("failed to load credit notes with unapplied credit: %w", err)
+		}
+		for i := range notes {
+			if remaining == 0 {
+				break
+			}
+			portion := notes[i].UnappliedAmount
+			if portion > remaining {
+				portion = remaining
+			}
+			if err := a.DB.First(&invoice, invoiceID).Error; err != nil {
+				return fmt.Errorf("failed to reload invoice %d: %w", invoiceID, err)
+			}
+			if err := a.applyCreditNote(&notes[i], &invoice, portion, now, true); err != nil {
+				return err
+			}
+			if err := a.DB.Model(&notes[i]).Update("unapplied_amount", gorm.Expr("unapplied_amount - ?", portion)).Error; err != nil {
+				return fmt.Errorf("failed to update credit note %d: %w", notes[i].ID, err)
+			}
+			remaining -= portion
+		}
+	}
+
+	applied := amount - remaining
+	if err := a.DB.Model(&Account{}).Where("id = ?", account.ID).
+		Update("unapplied_credit", gorm.Expr("unapplied_credit - ?", applied)).Error; err != nil {
+		return fmt.Errorf("failed to update account credit: %w", err)
+	}
+	if remaining > 0 {
+		// The account balance and its payments disagree; apply what we could and surface the gap
+		return fmt.Errorf("%w: only $%.2f of $%.2f applied", ErrInsufficientCredit, float64(applied)/100, float64(amount)/100)
+	}
+
+	log.Printf("Applied $%.2f of account %d credit to invoice %d", float64(amount)/100, account.ID, invoiceID)
+	return nil
+}
+
+// loadPayableInvoice loads an invoice and checks that it belongs to the account and is awaiting payment
+func (a *App) loadPayableInvoice(tenantID, accountID, invoiceID uint, invoice *Invoice) error {
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(invoice, invoiceID).Error; err != nil {
+		return fmt.Errorf("failed to load invoice %d: %w", invoiceID, err)
+	}
+	if invoice.AccountID != accountID {
+		return fmt.Errorf("invoice #%d does not belong to account %d", invoiceID, accountID)
+	}
+	if invoice.State != InvoiceStateSent.String() && invoice.State != InvoiceStatePartiallyPaid.String() {
+		return fmt.Errorf("%w: invoice #%d is %s", ErrInvoiceNotPayable, invoiceID, invoice.State)
+	}
+	return nil
+}
+
+// allocatePayment records the allocation, books the journal entries for it and updates the invoice balance.
+// When fromCredit is set the debit side comes out of CUSTOMER_CREDITS instead of CASH.
+func (a *App) allocatePayment(payment *Payment, invoice *Invoice, amount int64, appliedAt time.Time, fromCredit bool) error {
+	allocation := PaymentAllocation{
+		TenantID:  invoice.TenantID,
+		PaymentID: payment.ID,
+		InvoiceID: invoice.ID,
+		Amount:    amount,
+		AppliedAt: appliedAt,
+	}
+	if err := a.DB.Create(&allocation).Error; err != nil {
+		return fmt.Errorf("failed to create payment allocation: %w", err)
+	}
+
+	if invoice.Account.ID == 0 {
+		a.DB.First(&invoice.Account, invoice.AccountID)
+	}
+
+	debit := Journal{
+		TenantID:   invoice.TenantID,
+		Account:    AccountCash.String(),
+		SubAccount: "ChaseBusiness",
+		InvoiceID:  &invoice.ID,
+		Memo:       fmt.Sprintf("Payment #%d received for invoice #%d", payment.ID, invoice.ID),
+		Debit:      amount,
+		Credit:     0,
+	}
+	if fromCredit {
+		debit.Account = AccountCustomerCredits.String()
+		debit.SubAccount = fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name)
+		debit.Memo = fmt.Sprintf("Credit from payment #%d applied to invoice #%d", payment.ID, invoice.ID)
+	}
+	// Cash (or the credit it was held as) is worth what it was when the payment arrived
+	debit.translate(payment.Currency, payment.ExchangeRate)
+	return a.reduceInvoiceBalance(invoice, debit, amount, appliedAt, payment.ID, fmt.Sprintf("Payment #%d applied to invoice #%d", payment.ID, invoice.ID), false)
+}
+
+// applyCreditNote applies part of a credit note to an invoice. A credit note settling its own invoice is
+// booked against CREDITS_ISSUED; credit held on the account is drawn from CUSTOMER_CREDITS.
+func (a *App) applyCreditNote(note *CreditNote, invoice *Invoice, amount int64, appliedAt time.Time, fromCredit bool) error {
+	allocation := PaymentAllocation{
+		TenantID:     invoice.TenantID,
+		CreditNoteID: &note.ID,
+		InvoiceID:    invoice.ID,
+		Amount:       amount,
+		AppliedAt:    appliedAt,
+	}
+	if err := a.DB.Create(&allocation).Error; err != nil {
+		return fmt.Errorf("failed to create credit note allocation: %w", err)
+	}
+
+	if invoice.Account.ID == 0 {
+		a.DB.First(&invoice.Account, invoice.AccountID)
+	}
+
+	debit := Journal{
+		TenantID:   invoice.TenantID,
+		Account:    AccountCreditsIssued.String(),
+		SubAccount: fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name),
+		InvoiceID:  &invoice.ID,
+		Memo:       fmt.Sprintf("Credit note #%d issued against invoice #%d", note.ID, invoice.ID),
+		Debit:      amount,
+		Credit:     0,
+	}
+	if fromCredit {
+		debit.Account = AccountCustomerCredits.String()
+		debit.Memo = fmt.Sprintf("Credit from credit note #%d applied to invoice #%d", note.ID, invoice.ID)
+	}
+	debit.translate(invoice.Currency, invoice.ExchangeRate)
+	return a.reduceInvoiceBalance(invoice, debit, amount, appliedAt, note.ID, fmt.Sprintf("Credit note #%d applied to invoice #%d", note.ID, invoice.ID), true)
+}
+
+// reduceInvoiceBalance books the given debit against a credit to the invoice's receivable and lowers its
+// balance due, as a payment or (when credited is set) as a credit. Once nothing is left owing the invoice
+// goes through the normal MarkInvoicePaid flow. For foreign-currency invoices the receivable is cleared at
+// the rate it was accrued at and any difference from the debit is booked as realized FX gain or loss.
+// sourceID is the payment, or for credits the credit note, the transaction is posted for.
+func (a *App) reduceInvoiceBalance(invoice *Invoice, debit Journal, amount int64, appliedAt time.Time, sourceID uint, memo string, credited bool) error {
+	settles := amount >= InvoiceBalanceDue(invoice)
+
+	// Use the same AR subaccount the invoice was booked with so the balance clears
+	var arEntry Journal
+	subAccount := fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name)
+	if err := a.DB.Where("invoice_id = ? AND account = ? AND debit > 0", invoice.ID, AccountAccountsReceivable.String()).
+		First(&arEntry).Error; err == nil {
+		subAccount = arEntry.SubAccount
+	}
+
+	clearAR := Journal{
+		TenantID:   invoice.TenantID,
+		Account:    AccountAccountsReceivable.String(),
+		SubAccount: subAccount,
+		InvoiceID:  &invoice.ID,
+		Memo:       memo,
+		Debit:      0,
+		Credit:     amount,
+	}
+	clearAR.translate(invoice.Currency, invoice.ExchangeRate)
+	if invoice.ExchangeRate != 0 && settles {
+		// Clear whatever is left so rounding on earlier allocations doesn't strand a few cents in AR
+		var carried int64
+		a.DB.Model(&Journal{}).Select("COALESCE(SUM(debit - credit), 0)").
+			Where("invoice_id = ? AND account = ?", invoice.ID, AccountAccountsReceivable.String()).Scan(&carried)
+		clearAR.Credit = carried
+	}
+	source := JournalSourcePayment
+	if credited {
+		source = JournalSourceCreditNote
+	}
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      invoice.TenantID,
+		EffectiveDate: appliedAt,
+		SourceType:    source.String(),
+		SourceID:      &sourceID,
+		Memo:          memo,
+		Lines:         []Journal{debit, clearAR, realizedFXLine(invoice, debit.Debit-clearAR.Credit)},
+	}); err != nil {
+		return fmt.Errorf("failed to clear accounts receivable: %w", err)
+	}
+
+	if credited {
+		invoice.AmountCredited += float64(amount) / 100
+	} else {
+		invoice.AmountPaid += float64(amount) / 100
+	}
+	invoice.BalanceDue = invoice.TotalAmount - invoice.AmountPaid - invoice.AmountCredited - invoice.AmountDiscounted
+	if err := a.DB.Model(invoice).Updates(map[string]interface{}{
+		"amount_paid":     invoice.AmountPaid,
+		"amount_credited": invoice.AmountCredited,
+		"balance_due":     invoice.BalanceDue,
+		"state":           InvoiceStatePartiallyPaid.String(),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update invoice balance: %w", err)
+	}
+
+	if InvoiceBalanceDue(invoice) <= 0 {
+		// Fully paid: run the regular paid flow (entries, bills, commissions). AR is already cleared
+		// by the allocations, so RecordInvoiceCashPayment won't book cash a second time.
+		if err := a.MarkInvoicePaid(invoice.ID, appliedAt); err != nil {
+			return fmt.Errorf("failed to mark invoice %d paid: %w", invoice.ID, err)
+		}
+	}
+	return nil
+}
+
+// bookUnappliedCredit books the unallocated part of a payment as a liability owed back to the client
+func (a *App) bookUnappliedCredit(payment *Payment, account *Account) error {
+	subAccount := fmt.Sprintf("%d:%s", account.ID, account.Name)
+	memo := fmt.Sprintf("Unapplied credit from payment #%d", payment.ID)
+
+	cashEntry := Journal{
+		TenantID:   payment.TenantID,
+		Account:    AccountCash.String(),
+		SubAccount: "ChaseBusiness",
+		Memo:       memo,
+		Debit:      payment.UnappliedAmount,
+		Credit:     0,
+	}
+	cashEntry.translate(payment.Currency, payment.ExchangeRate)
+
+	creditEntry := Journal{
+		TenantID:   payment.TenantID,
+		Account:    AccountCustomerCredits.String(),
+		SubAccount: subAccount,
+		Memo:       memo,
+		Debit:      0,
+		Credit:     payment.UnappliedAmount,
+	}
+	creditEntry.translate(payment.Currency, payment.ExchangeRate)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      payment.TenantID,
+		EffectiveDate: payment.ReceivedAt,
+		SourceType:    JournalSourcePayment.String(),
+		SourceID:      &payment.ID,
+		Memo:          memo,
+		Lines:         []Journal{cashEntry, creditEntry},
+	}); err != nil {
+		return fmt.Errorf("failed to book unapplied credit: %w", err)
+	}
+
+	if err := a.DB.Model(&Account{}).Where("id = ?", account.ID).
+		Update("unapplied_credit", gorm.Expr("unapplied_credit + ?", payment.UnappliedAmount)).Error; err != nil {
+		return fmt.Errorf("failed to update account credit: %w", err)
+	}
+	return nil
+}
+
+// ReconcilePayment links a payment to the bank deposit it arrived in. The cash side of the payment has
+// already been booked, so the bank line (and its categorized pair) is marked posted and already booked
+// rather than being posted to the GL again.
+func (a *App) ReconcilePayment(tenantID, paymentID, offlineJournalID, staffID uint) (*Payment, error) {
+	var payment Payment
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&payment, paymentID).Error; err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.ReconciledOfflineJournalID != nil {
+		return nil, ErrPaymentAlreadyReconciled
+	}
+
+	var bankLine OfflineJournal
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&bankLine, offlineJournalID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bank transaction %d: %w", offlineJournalID, err)
+	}
+	if bankLine.ReconciledPaymentID != nil || bankLine.ReconciledInvoiceID != nil ||
+		bankLine.ReconciledExpenseID != nil || bankLine.ReconciledBillID != nil {
+		return nil, ErrBankLineAlreadyReconciled
+	}
+
+	lineAmount := bankLine.Debit
+	if lineAmount == 0 {
+		lineAmount = bankLine.Credit
+	}
+	if lineAmount != payment.Amount {
+		return nil, fmt.Errorf("amounts do not match: payment=%d, transaction=%d", payment.Amount, lineAmount)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"reconciled_payment_id": payment.ID,
+		"reconciled_at":         now,
+		"reconciled_by":         staffID,
+		"status":                "posted",
+		"is_already_booked":     true,
+	}
+	query := a.DB.Model(&OfflineJournal{}).Scopes(TenantScope(tenantID))
+	if bankLine.TransactionGroupID != "" {
+		query = query.Where("transaction_group_id = ?", bankLine.TransactionGroupID)
+	} else {
+		query = query.Where("id = ?", bankLine.ID)
+	}
+	if err := query.Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update bank transaction: %w", err)
+	}
+
+	payment.ReconciledOfflineJournalID = &bankLine.ID
+	payment.ReconciledAt = &now
+	payment.ReconciledBy = &staffID
+	if err := a.DB.Save(&payment).Error; err != nil {
+		return nil, fmt.Errorf("failed to save payment reconciliation: %w", err)
+	}
+
+	log.Printf("Reconciled payment %d with bank transaction %d", payment.ID, bankLine.ID)
+	return &payment, nil
+}
+
+// UnreconcilePayment removes the link between a payment and its bank deposit
+func (a *App) UnreconcilePayment(tenantID, paymentID uint) (*Payment, error) {
+	var payment Payment
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&payment, paymentID).Error; err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.ReconciledOfflineJournalID == nil {
+		return nil, ErrPaymentNotReconciled
+	}
+
+	if err := a.DB.Model(&OfflineJournal{}).Scopes(TenantScope(tenantID)).
+		Where("reconciled_payment_id = ?", payment.ID).
+		Updates(map[string]interface{}{
+			"reconciled_payment_id": nil,
+			"reconciled_at":         nil,
+			"reconciled_by":         nil,
+			"status":                "pending_review",
+			"is_already_booked":     false,
+		}).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear bank transaction reconciliation: %w", err)
+	}
+
+	payment.ReconciledOfflineJournalID = nil
+	payment.ReconciledAt = nil
+	payment.ReconciledBy = nil
+	if err := a.DB.Save(&payment).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear payment reconciliation: %w", err)
+	}
+	return &payment, nil
+}
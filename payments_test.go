@@ -0,0 +1,213 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// createSentInvoice creates a sent invoice with its receivable booked, as SendInvoice would leave it
+func createSentInvoice(t *testing.T, db *gorm.DB, tenantID uint, account Account, totalCents int64) Invoice {
+	t.Helper()
+	invoice := Invoice{
+		TenantID:    tenantID,
+		Name:        "Payment Test Invoice",
+		AccountID:   account.ID,
+		State:       InvoiceStateSent.String(),
+		Type:        InvoiceTypeAR.String(),
+		TotalAmount: float64(totalCents) / 100,
+		BalanceDue:  float64(totalCents) / 100,
+		SentAt:      time.Now(),
+	}
+	if err := db.Create(&invoice).Error; err != nil {
+		t.Fatalf("Failed to create invoice: %v", err)
+	}
+	// Back the total with a fee so recalculating the invoice totals keeps it
+	db.Create(&Adjustment{TenantID: tenantID, InvoiceID: &invoice.ID, Type: AdjustmentTypeFee.String(), State: AdjustmentStateSent.String(), Amount: float64(totalCents) / 100})
+	subAccount := "1:Payment Test Account"
+	db.Create(&Journal{TenantID: tenantID, Account: AccountAccountsReceivable.String(), SubAccount: subAccount, InvoiceID: &invoice.ID, Debit: totalCents})
+	db.Create(&Journal{TenantID: tenantID, Account: AccountRevenue.String(), SubAccount: subAccount, InvoiceID: &invoice.ID, Credit: totalCents})
+	return invoice
+}
+
+func arBalance(db *gorm.DB, invoiceID uint) int64 {
+	var balance int64
+	db.Table("journals").Select("COALESCE(SUM(debit - credit), 0)").
+		Where("invoice_id = ? AND account = ?", invoiceID, AccountAccountsReceivable.String()).Scan(&balance)
+	return balance
+}
+
+// TestRecordPaymentPartialAndOverpayment tests instalment payments, overpayment credit and applying that credit
+func TestRecordPaymentPartialAndOverpayment(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "payments", Name: "Payments Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Payment Test Account", LegalName: "Payment Test Account LLC", Type: AccountTypeClient.String()}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	invoice := createSentInvoice(t, db, tenant.ID, account, 100000)
+
+	// First instalment
+	first := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 40000, ReceivedAt: time.Now().AddDate(0, 0, -10)}
+	if err := app.RecordPayment(&first, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 40000}}); err != nil {
+		t.Fatalf("Failed to record first payment: %v", err)
+	}
+	db.First(&invoice, invoice.ID)
+	if invoice.State != InvoiceStatePartiallyPaid.String() {
+		t.Errorf("Expected invoice to be partially paid, got %s", invoice.State)
+	}
+	if InvoiceBalanceDue(&invoice) != 60000 || invoice.BalanceDue != 600 {
+		t.Errorf("Expected $600 balance due, got %v", invoice.BalanceDue)
+	}
+	if got := arBalance(db, invoice.ID); got != 60000 {
+		t.Errorf("Expected AR balance of 60000, got %d", got)
+	}
+
+	// Allocating more than is owed is rejected without booking anything
+	tooMuch := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 70000}
+	err := app.RecordPayment(&tooMuch, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 70000}})
+	if !errors.Is(err, ErrAllocationExceedsBalance) {
+		t.Errorf("Expected ErrAllocationExceedsBalance, got %v", err)
+	}
+	if tooMuch.ID != 0 {
+		t.Errorf("Expected rejected payment not to be saved")
+	}
+
+	// Final instalment with an overpayment
+	second := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 80000, ReceivedAt: time.Now()}
+	if err := app.RecordPayment(&second, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 60000}}); err != nil {
+		t.Fatalf("Failed to record second payment: %v", err)
+	}
+	db.First(&invoice, invoice.ID)
+	if invoice.State != InvoiceStatePaid.String() {
+		t.Errorf("Expected invoice to be paid, got %s", invoice.State)
+	}
+	if got := arBalance(db, invoice.ID); got != 0 {
+		t.Errorf("Expected AR to be cleared, got %d", got)
+	}
+	if second.UnappliedAmount != 20000 {
+		t.Errorf("Expected 20000 unapplied, got %d", second.UnappliedAmount)
+	}
+	db.First(&account, account.ID)
+	if account.UnappliedCredit != 20000 {
+		t.Errorf("Expected account credit of 20000, got %d", account.UnappliedCredit)
+	}
+
+	// Apply part of the credit to a new invoice
+	next := createSentInvoice(t, db, tenant.ID, account, 15000)
+	if err := app.ApplyAccountCredit(tenant.ID, next.ID, 15000); err != nil {
+		t.Fatalf("Failed to apply credit: %v", err)
+	}
+	db.First(&next, next.ID)
+	if next.State != InvoiceStatePaid.String() {
+		t.Errorf("Expected credited invoice to be paid, got %s", next.State)
+	}
+	db.First(&account, account.ID)
+	if account.UnappliedCredit != 5000 {
+		t.Errorf("Expected remaining credit of 5000, got %d", account.UnappliedCredit)
+	}
+	if err := app.ApplyAccountCredit(tenant.ID, createSentInvoice(t, db, tenant.ID, account, 9000).ID, 9000); !errors.Is(err, ErrInsufficientCredit) {
+		t.Errorf("Expected ErrInsufficientCredit, got %v", err)
+	}
+
+	if _, err := app.VerifyTenantJournalBalance(tenant.ID); err != nil {
+		t.Errorf("Expected balanced journals: %v", err)
+	}
+}
+
+// TestRecordPaymentRollsBack tests that a payment failing part way through booking leaves no payment,
+// allocation or journal behind
+func TestRecordPaymentRollsBack(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "payments-rollback", Name: "Rollback Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Payment Test Account", LegalName: "Payment Test Account LLC", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	invoice := createSentInvoice(t, db, tenant.ID, account, 50000)
+
+	// The allocation books, then the unapplied credit's journal fails
+	posted := 0
+	db.Callback().Create().Before("gorm:create").Register("test:fail_second_posting", func(tx *gorm.DB) {
+		if tx.Statement.Table == "journal_transactions" {
+			if posted++; posted == 2 {
+				tx.AddError(errors.New("journal insert failed"))
+			}
+		}
+	})
+	payment := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 80000, ReceivedAt: time.Now()}
+	if err := app.RecordPayment(&payment, []PaymentAllocationRequest{{InvoiceID: invoice.ID, Amount: 30000}}); err == nil {
+		t.Fatalf("Expected the failed credit posting to fail the payment")
+	}
+
+	var payments, allocations int64
+	db.Model(&Payment{}).Count(&payments)
+	db.Model(&PaymentAllocation{}).Count(&allocations)
+	db.First(&invoice, invoice.ID)
+	if payments != 0 || allocations != 0 || arBalance(db, invoice.ID) != 50000 || invoice.State != InvoiceStateSent.String() {
+		t.Errorf("Expected nothing booked, got %d payments, %d allocations, AR %d, invoice %s",
+			payments, allocations, arBalance(db, invoice.ID), invoice.State)
+	}
+}
+
+// TestReconcilePayment tests matching a payment to a bank deposit line
+func TestReconcilePayment(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "payments-recon", Name: "Reconcile Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Payment Test Account", LegalName: "Reconcile LLC", Type: AccountTypeClient.String()}
+	db.Create(&account)
+
+	payment := Payment{TenantID: tenant.ID, AccountID: account.ID, Amount: 25000, ReceivedAt: time.Now()}
+	if err := app.RecordPayment(&payment, nil); err != nil {
+		t.Fatalf("Failed to record payment: %v", err)
+	}
+
+	wrongAmount := OfflineJournal{TenantID: tenant.ID, Date: time.Now(), Account: "CASH", Debit: 24000, ContentHash: "recon-1", Status: "pending_review"}
+	deposit := OfflineJournal{TenantID: tenant.ID, Date: time.Now(), Account: "CASH", Debit: 25000, ContentHash: "recon-2", Status: "pending_review"}
+	db.Create(&wrongAmount)
+	db.Create(&deposit)
+
+	if _, err := app.ReconcilePayment(tenant.ID, payment.ID, wrongAmount.ID, 1); err == nil {
+		t.Errorf("Expected mismatched amounts to be rejected")
+	}
+
+	reconciled, err := app.ReconcilePayment(tenant.ID, payment.ID, deposit.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to reconcile payment: %v", err)
+	}
+	if reconciled.ReconciledOfflineJournalID == nil || *reconciled.ReconciledOfflineJournalID != deposit.ID {
+		t.Errorf("Expected payment to reference deposit %d", deposit.ID)
+	}
+	db.First(&deposit, deposit.ID)
+	if deposit.ReconciledPaymentID == nil || !deposit.IsAlreadyBooked || deposit.Status != "posted" {
+		t.Errorf("Expected deposit to be linked, posted and marked booked: %+v", deposit)
+	}
+
+	if _, err := app.ReconcilePayment(tenant.ID, payment.ID, deposit.ID, 1); !errors.Is(err, ErrPaymentAlreadyReconciled) {
+		t.Errorf("Expected ErrPaymentAlreadyReconciled, got %v", err)
+	}
+
+	if _, err := app.UnreconcilePayment(tenant.ID, payment.ID); err != nil {
+		t.Fatalf("Failed to unreconcile: %v", err)
+	}
+	db.First(&deposit, deposit.ID)
+	if deposit.ReconciledPaymentID != nil || deposit.IsAlreadyBooked {
+		t.Errorf("Expected deposit reconciliation to be cleared")
+	}
+}
@@ -0,0 +1,179 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidRateCard = errors.New("invalid rate card")
+var ErrRateCardInUse = errors.New("rate card is attached to accounts or projects")
+
+// rateCardLine finds the rate in effect for the entry on the day it started, from the project's rate
+// card or else the account's. It returns nil when neither has a card or the card has no rate that
+// applies, leaving the billing code's rate in place.
+func (e *Entry) rateCardLine(tx *gorm.DB, project *Project) *RateCardLine {
+	cardID := project.RateCardID
+	if cardID == nil && project.AccountID != 0 {
+		var account Account
+		tx.Select("rate_card_id").Where("id = ?", project.AccountID).Limit(1).Find(&account)
+		cardID = account.RateCardID
+	}
+	if cardID == nil {
+		return nil
+	}
+
+	var employee Employee
+	tx.Select("title").Where("id = ?", e.EmployeeID).Limit(1).Find(&employee)
+	day := time.Date(e.Start.Year(), e.Start.Month(), e.Start.Day(), 0, 0, 0, 0, time.UTC)
+	var lines []RateCardLine
+	tx.Where("rate_card_id = ? AND effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", *cardID, day, day).
+		Where("employee_id = ? OR (employee_id IS NULL AND role IN ?)", e.EmployeeID, []string{employee.Title, ""}).
+		Find(&lines)
+
+	var best *RateCardLine
+	for i := range lines {
+		if best == nil || lines[i].precedence() < best.precedence() {
+			best = &lines[i]
+		}
+	}
+	return best
+}
+
+// precedence ranks a line by how specific it is, lowest first: employee, then role, then everyone
+func (l *RateCardLine) precedence() int {
+	switch {
+	case l.EmployeeID != nil:
+		return 0
+	case l.Role != "":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// ValidateRateCardLine checks a line's rates and dates, and that it doesn't overlap another line on
+// the card for the same employee or role
+func (a *App) ValidateRateCardLine(line *RateCardLine) error {
+	if line.EmployeeID != nil && line.Role != "" {
+		return fmt.Errorf("%w: a rate is for an employee or a role, not both", ErrInvalidRateCard)
+	}
+	if line.Amount < 0 || line.InternalAmount < 0 {
+		return fmt.Errorf("%w: rates cannot be negative", ErrInvalidRateCard)
+	}
+	if line.EffectiveFrom.IsZero() {
+		return fmt.Errorf("%w: effective_from is required", ErrInvalidRateCard)
+	}
+	if line.EffectiveTo != nil && line.EffectiveTo.Before(line.EffectiveFrom) {
+		return fmt.Errorf("%w: effective_to is before effective_from", ErrInvalidRateCard)
+	}
+
+	query := a.DB.Model(&RateCardLine{}).Where("rate_card_id = ? AND id != ?", line.RateCardID, line.ID).
+		Where("effective_to IS NULL OR effective_to >= ?", line.EffectiveFrom)
+	if line.EffectiveTo != nil {
+		query = query.Where("effective_from <= ?", *line.EffectiveTo)
+	}
+	if line.EmployeeID != nil {
+		query = query.Where("employee_id = ?", *line.EmployeeID)
+	} else {
+		query = query.Where("employee_id IS NULL AND role = ?", line.Role)
+	}
+	var overlapping int64
+	if err := query.Count(&overlapping).Error; err != nil {
+		return fmt.Errorf("failed to check rate card dates: %w", err)
+	}
+	if overlapping > 0 {
+		return fmt.Errorf("%w: another rate for the same employee or role is in effect on those dates", ErrInvalidRateCard)
+	}
+	return nil
+}
+
+// ListRateCards returns a tenant's rate cards with their rates, in name order
+func (a *App) ListRateCards(tenantID uint) ([]RateCard, error) {
+	var cards []RateCard
+	if err := a.DB.Scopes(TenantScope(tenantID)).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("employee_id, role, effective_from") }).
+		Preload("Lines.Employee").
+		Order("name").Find(&cards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rate cards: %w", err)
+	}
+	return cards, nil
+}
+
+// RerateDraftEntries recomputes the fees of draft entries on the given projects that started on or
+// after since, for when their rates change after the work was logged. The totals of the invoices
+// they sit on are refreshed. It returns the number of entries whose fee changed.
+func (a *App) RerateDraftEntries(projectIDs []uint, since time.Time) (int, error) {
+	if len(projectIDs) == 0 {
+		return 0, nil
+	}
+	var entries []Entry
+	if err := a.DB.Where("project_id IN ? AND state = ? AND start >= ?", projectIDs, EntryStateDraft.String(), since).
+		Order("start, id").Find(&entries).Error; err != nil {
+		return 0, fmt.Errorf("failed to load draft entries: %w", err)
+	}
+
+	changed := 0
+	invoiceIDs := make(map[uint]bool)
+	for i := range entries {
+		entry := &entries[i]
+		previous := entry.Fee
+		// BeforeSave recomputes the fee
+		if err := a.DB.Omit(clause.Associations).Save(entry).Error; err != nil {
+			return changed, fmt.Errorf("failed to re-rate entry %d: %w", entry.ID, err)
+		}
+		if entry.Fee == previous {
+			continue
+		}
+		changed++
+		if entry.InvoiceID != nil {
+			invoiceIDs[*entry.InvoiceID] = true
+		}
+	}
+	for invoiceID := range invoiceIDs {
+		var invoice Invoice
+		if err := a.DB.First(&invoice, invoiceID).Error; err == nil {
+			a.UpdateInvoiceTotals(&invoice)
+		}
+	}
+	log.Printf("Re-rated %d of %d draft entries since %s", changed, len(entries), since.Format("2006-01-02"))
+	return changed, nil
+}
+
+// RateCardProjects returns the projects billed from a rate card: those it's attached to directly, and
+// those without a card of their own on accounts it's attached to
+func (a *App) RateCardProjects(tenantID, rateCardID uint) ([]uint, error) {
+	var projectIDs []uint
+	if err := a.DB.Model(&Project{}).Scopes(TenantScope(tenantID)).
+		Where("rate_card_id = ?", rateCardID).Pluck("id", &projectIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rate card projects: %w", err)
+	}
+	var accountIDs []uint
+	if err := a.DB.Model(&Account{}).Scopes(TenantScope(tenantID)).
+		Where("rate_card_id = ?", rateCardID).Pluck("id", &accountIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rate card accounts: %w", err)
+	}
+	if len(accountIDs) > 0 {
+		var inherited []uint
+		if err := a.DB.Model(&Project{}).Scopes(TenantScope(tenantID)).
+			Where("account_id IN ? AND rate_card_id IS NULL", accountIDs).Pluck("id", &inherited).Error; err != nil {
+			return nil, fmt.Errorf("failed to load rate card projects: %w", err)
+		}
+		projectIDs = append(projectIDs, inherited...)
+	}
+	return projectIDs, nil
+}
+
+// RerateRateCard re-rates the draft entries on every project billed from the card that started on or
+// after since. Call it when a rate on the card changes retroactively.
+func (a *App) RerateRateCard(tenantID, rateCardID uint, since time.Time) (int, error) {
+	projectIDs, err := a.RateCardProjects(tenantID, rateCardID)
+	if err != nil {
+		return 0, err
+	}
+	return a.RerateDraftEntries(projectIDs, since)
+}
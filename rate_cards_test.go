@@ -0,0 +1,106 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRateCardEffectiveRates tests that entries bill at the rate card line in effect on the day they
+// started, that the most specific line wins, and that re-rating reprices draft entries after a
+// retroactive rate change
+func TestRateCardEffectiveRates(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "rate-cards", Name: "Rate Card Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	accountCard := RateCard{TenantID: tenant.ID, Name: "Account Standard"}
+	projectCard := RateCard{TenantID: tenant.ID, Name: "Project Special"}
+	db.Create(&accountCard)
+	db.Create(&projectCard)
+	account := Account{TenantID: tenant.ID, Name: "Rated Client", Type: AccountTypeClient.String(), RateCardID: &accountCard.ID}
+	db.Create(&account)
+	inherits := Project{TenantID: tenant.ID, Name: "Inherits", AccountID: account.ID}
+	overrides := Project{TenantID: tenant.ID, Name: "Overrides", AccountID: account.ID, RateCardID: &projectCard.ID}
+	db.Create(&inherits)
+	db.Create(&overrides)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 100}
+	db.Create(&rate)
+	inheritsCode := BillingCode{TenantID: tenant.ID, Name: "Inherits", Code: "INH", ProjectID: inherits.ID, RateID: rate.ID, RoundedTo: 15}
+	overridesCode := BillingCode{TenantID: tenant.ID, Name: "Overrides", Code: "OVR", ProjectID: overrides.ID, RateID: rate.ID, RoundedTo: 15}
+	db.Create(&inheritsCode)
+	db.Create(&overridesCode)
+	senior := Employee{TenantID: tenant.ID, FirstName: "Sam", Title: "Senior Consultant"}
+	junior := Employee{TenantID: tenant.ID, FirstName: "Jo", Title: "Analyst"}
+	db.Create(&senior)
+	db.Create(&junior)
+
+	january := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	endOfMarch := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	lines := []RateCardLine{
+		{TenantID: tenant.ID, RateCardID: accountCard.ID, Amount: 150, EffectiveFrom: january},
+		{TenantID: tenant.ID, RateCardID: accountCard.ID, Role: "Senior Consultant", Amount: 200, InternalAmount: 90, EffectiveFrom: january, EffectiveTo: &endOfMarch},
+		{TenantID: tenant.ID, RateCardID: accountCard.ID, Role: "Senior Consultant", Amount: 250, InternalAmount: 90, EffectiveFrom: endOfMarch.AddDate(0, 0, 1)},
+		{TenantID: tenant.ID, RateCardID: projectCard.ID, Role: "Senior Consultant", Amount: 180, EffectiveFrom: january},
+		{TenantID: tenant.ID, RateCardID: projectCard.ID, EmployeeID: &senior.ID, Amount: 300, EffectiveFrom: january},
+	}
+	for i := range lines {
+		if err := app.ValidateRateCardLine(&lines[i]); err != nil {
+			t.Fatalf("Expected rate card line %d to validate: %v", i, err)
+		}
+		db.Create(&lines[i])
+	}
+	overlapping := RateCardLine{TenantID: tenant.ID, RateCardID: accountCard.ID, Role: "Senior Consultant", Amount: 210, EffectiveFrom: endOfMarch}
+	if err := app.ValidateRateCardLine(&overlapping); !errors.Is(err, ErrInvalidRateCard) {
+		t.Errorf("Expected an overlapping rate to be refused, got %v", err)
+	}
+
+	addEntry := func(code *BillingCode, employee *Employee, start time.Time) Entry {
+		entry := Entry{TenantID: tenant.ID, ProjectID: code.ProjectID, BillingCodeID: code.ID, EmployeeID: employee.ID,
+			Start: start, End: start.Add(2 * time.Hour), State: EntryStateDraft.String()}
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+		return entry
+	}
+	tests := []struct {
+		name     string
+		code     *BillingCode
+		employee *Employee
+		start    time.Time
+		fee      int
+	}{
+		{"role rate from the account's card", &inheritsCode, &senior, time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC), 40000},
+		{"role rate changes mid-project", &inheritsCode, &senior, time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC), 50000},
+		{"default rate for other roles", &inheritsCode, &junior, time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC), 30000},
+		{"employee rate on the project's card", &overridesCode, &senior, time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC), 60000},
+		{"billing code rate when the project's card has no line", &overridesCode, &junior, time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC), 20000},
+	}
+	entries := make([]Entry, len(tests))
+	for i, tt := range tests {
+		entries[i] = addEntry(tt.code, tt.employee, tt.start)
+		if entries[i].Fee != tt.fee {
+			t.Errorf("%s: expected a fee of %d, got %d", tt.name, tt.fee, entries[i].Fee)
+		}
+	}
+	if internal := int(entries[0].GetInternalFee(db) * 100); internal != 18000 {
+		t.Errorf("Expected the internal cost from the card, got %d", internal)
+	}
+
+	// Raising the default rate from February re-rates the junior's draft entry on the inheriting project
+	db.Model(&lines[0]).Update("amount", 175)
+	changed, err := app.RerateRateCard(tenant.ID, accountCard.ID, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to re-rate: %v", err)
+	}
+	if changed != 1 {
+		t.Errorf("Expected one entry re-rated, got %d", changed)
+	}
+	var rerated Entry
+	db.First(&rerated, entries[2].ID)
+	if rerated.Fee != 35000 {
+		t.Errorf("Expected the re-rated fee to be 35000, got %d", rerated.Fee)
+	}
+}
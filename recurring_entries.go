@@ -5,16 +5,29 @@ import (
 	"log"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // GenerateRecurringEntriesForPeriod creates recurring bill line items for all active employees
 // for a given period (typically the current month). This is idempotent - safe to call multiple times.
 func (a *App) GenerateRecurringEntriesForPeriod(periodStart, periodEnd time.Time) error {
+	return a.generateRecurringEntries(periodStart, periodEnd)
+}
+
+// GenerateRecurringEntriesForTenantPeriod is the tenant-scoped variant of GenerateRecurringEntriesForPeriod
+// used by the scheduler, which runs each job once per tenant.
+func (a *App) GenerateRecurringEntriesForTenantPeriod(tenantID uint, periodStart, periodEnd time.Time) error {
+	return a.generateRecurringEntries(periodStart, periodEnd, TenantScope(tenantID))
+}
+
+func (a *App) generateRecurringEntries(periodStart, periodEnd time.Time, scopes ...func(*gorm.DB) *gorm.DB) error {
 	log.Printf("Generating recurring entries for period %s to %s", periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"))
 
 	// Find all active recurring entries
 	var recurringEntries []RecurringEntry
 	if err := a.DB.
+		Scopes(scopes...).
 		Preload("Employee").
 		Where("is_active = ?", true).
 		Where("start_date <= ?", periodEnd).
@@ -46,6 +59,7 @@ func (a *App) GenerateRecurringEntriesForPeriod(periodStart, periodEnd time.Time
 			entry.EmployeeID, periodStart, periodEnd).First(&bill).Error; err != nil {
 			// Bill doesn't exist, create it
 			bill = Bill{
+				TenantID:    entry.TenantID,
 				Name:        fmt.Sprintf("%s - %s", entry.Employee.FirstName+" "+entry.Employee.LastName, periodStart.Format("Jan 2006")),
 				State:       BillStateDraft,
 				EmployeeID:  entry.EmployeeID,
@@ -87,6 +101,7 @@ func (a *App) GenerateRecurringEntriesForPeriod(periodStart, periodEnd time.Time
 
 		// Create the recurring bill line item
 		lineItem = RecurringBillLineItem{
+			TenantID:         entry.TenantID,
 			BillID:           bill.ID,
 			RecurringEntryID: entry.ID,
 			Description:      entry.Description,
@@ -222,9 +237,6 @@ func (a *App) BookRecurringEntryAccrual(lineItem *RecurringBillLineItem, employe
 		Debit:                     int64(lineItem.Amount),
 		Credit:                    0,
 	}
-	if err := a.DB.Create(&debitEntry).Error; err != nil {
-		return fmt.Errorf("failed to book payroll expense: %w", err)
-	}
 
 	// CR: Accrued Payroll
 	creditEntry := Journal{
@@ -236,8 +248,15 @@ func (a *App) BookRecurringEntryAccrual(lineItem *RecurringBillLineItem, employe
 		Debit:                     0,
 		Credit:                    int64(lineItem.Amount),
 	}
-	if err := a.DB.Create(&creditEntry).Error; err != nil {
-		return fmt.Errorf("failed to book accrued payroll: %w", err)
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      lineItem.TenantID,
+		EffectiveDate: lineItem.PeriodEnd,
+		SourceType:    JournalSourceRecurringEntry.String(),
+		SourceID:      &lineItem.RecurringEntryID,
+		Memo:          fmt.Sprintf("Recurring payroll: %s", lineItem.Description),
+		Lines:         []Journal{debitEntry, creditEntry},
+	}); err != nil {
+		return fmt.Errorf("failed to book recurring payroll accrual: %w", err)
 	}
 
 	// Update line item state
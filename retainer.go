@@ -0,0 +1,477 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidRetainer = errors.New("invalid retainer")
+var ErrNotRetainerProject = errors.New("project does not have a retainer")
+var ErrRetainerAlreadyIssued = errors.New("retainer has already been invoiced for this period")
+var ErrRetainerInUse = errors.New("retainer hours have already been drawn down or expired")
+
+// retainerHourEpsilon absorbs float error when comparing hours left on a block
+const retainerHourEpsilon = 0.0001
+
+// HoursRemaining is what's left on the block to draw
+func (b *RetainerBlock) HoursRemaining() float64 {
+	return math.Max(b.Hours-b.HoursUsed-b.HoursExpired, 0)
+}
+
+// covers reports whether work started at the given time can draw on the block. Unused hours carry
+// forward under the rollover policy; otherwise only work within the block's period counts.
+func (b *RetainerBlock) covers(start time.Time, rollover string) bool {
+	if start.Before(b.PeriodStart) {
+		return false
+	}
+	return rollover == RetainerRolloverPolicyRollover.String() || b.PeriodEnd.IsZero() || !start.After(b.PeriodEnd)
+}
+
+// ValidateProjectRetainer checks a project's retainer settings, defaulting the rollover policy to expire
+func ValidateProjectRetainer(project *Project) error {
+	switch project.RetainerType {
+	case "":
+		return nil
+	case RetainerTypeMonthly.String(), RetainerTypeBlock.String():
+	default:
+		return fmt.Errorf("%w: unknown retainer type %q", ErrInvalidRetainer, project.RetainerType)
+	}
+	if project.RetainerHours <= 0 || project.RetainerAmount <= 0 {
+		return fmt.Errorf("%w: retainer hours and amount must be greater than zero", ErrInvalidRetainer)
+	}
+	switch project.RetainerRollover {
+	case "":
+		project.RetainerRollover = RetainerRolloverPolicyExpire.String()
+	case RetainerRolloverPolicyRollover.String(), RetainerRolloverPolicyExpire.String():
+	default:
+		return fmt.Errorf("%w: unknown rollover policy %q", ErrInvalidRetainer, project.RetainerRollover)
+	}
+	return nil
+}
+
+// IssueRetainerInvoice creates the draft prepayment invoice for a retainer project along with the
+// block of hours it buys. Monthly retainers cover the month containing periodStart and are only
+// issued once per month; block retainers can cover any work on the project from its start. The
+// block can be drawn on once the invoice is approved.
+func (a *App) IssueRetainerInvoice(tenantID, projectID uint, periodStart time.Time) (*Invoice, error) {
+	var project Project
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Account").First(&project, projectID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load project %d: %w", projectID, err)
+	}
+	if project.RetainerType == "" {
+		return nil, ErrNotRetainerProject
+	}
+
+	block := RetainerBlock{
+		TenantID:  tenantID,
+		ProjectID: project.ID,
+		Hours:     project.RetainerHours,
+		Amount:    project.RetainerAmount,
+		State:     RetainerBlockStatePending.String(),
+	}
+	label := "block of " + formatRetainerHours(project.RetainerHours) + " hours"
+	if project.RetainerType == RetainerTypeMonthly.String() {
+		block.PeriodStart = time.Date(periodStart.Year(), periodStart.Month(), 1, 0, 0, 0, 0, time.UTC)
+		block.PeriodEnd = block.PeriodStart.AddDate(0, 1, 0).Add(-time.Nanosecond)
+		label = block.PeriodStart.Format("January 2006")
+
+		var existing int64
+		a.DB.Model(&RetainerBlock{}).Where("project_id = ? AND period_start = ? AND state != ?",
+			project.ID, block.PeriodStart, RetainerBlockStateVoid.String()).Count(&existing)
+		if existing > 0 {
+			return nil, ErrRetainerAlreadyIssued
+		}
+	} else {
+		block.PeriodStart = project.ActiveStart
+		block.PeriodEnd = project.ActiveEnd
+	}
+
+	// The invoice is left without a billing period so time entries are never attached to it and it
+	// doesn't stand in for the project's regular invoice
+	invoice := Invoice{
+		TenantID:  tenantID,
+		AccountID: project.AccountID,
+		ProjectID: &project.ID,
+		Name:      fmt.Sprintf("%s - %s: Retainer %s", project.Account.Name, project.Name, label),
+		State:     InvoiceStateDraft.String(),
+		Type:      InvoiceTypeAR.String(),
+	}
+	if err := a.DB.Create(&invoice).Error; err != nil {
+		return nil, fmt.Errorf("failed to create retainer invoice: %w", err)
+	}
+	adjustment := Adjustment{
+		TenantID:  tenantID,
+		InvoiceID: &invoice.ID,
+		Type:      AdjustmentTypeFee.String(),
+		State:     AdjustmentStateDraft.String(),
+		Amount:    float64(project.RetainerAmount) / 100,
+		Retainer:  true,
+		Notes:     fmt.Sprintf("Retainer, %s hours prepaid (%s)", formatRetainerHours(project.RetainerHours), label),
+	}
+	if err := a.DB.Create(&adjustment).Error; err != nil {
+		return nil, fmt.Errorf("failed to add retainer fee: %w", err)
+	}
+	block.InvoiceID = invoice.ID
+	if err := a.DB.Create(&block).Error; err != nil {
+		return nil, fmt.Errorf("failed to create retainer block: %w", err)
+	}
+	a.UpdateInvoiceTotals(&invoice)
+
+	log.Printf("Issued retainer invoice %d for project %d: %s hours for $%.2f", invoice.ID, project.ID,
+		formatRetainerHours(block.Hours), float64(block.Amount)/100)
+	return &invoice, nil
+}
+
+// IssueMonthlyRetainers makes sure every active monthly retainer in the tenant has been invoiced for
+// the month containing now. It returns the number of invoices created.
+func (a *App) IssueMonthlyRetainers(tenantID uint, now time.Time) (int, error) {
+	var projects []Project
+	if err := a.DB.Scopes(TenantScope(tenantID)).
+		Where("retainer_type = ? AND active_start <= ? AND active_end >= ?", RetainerTypeMonthly.String(), now, now).
+		Find(&projects).Error; err != nil {
+		return 0, fmt.Errorf("failed to load retainer projects: %w", err)
+	}
+	issued := 0
+	for _, project := range projects {
+		if _, err := a.IssueRetainerInvoice(tenantID, project.ID, now); err != nil {
+			if errors.Is(err, ErrRetainerAlreadyIssued) {
+				continue
+			}
+			return issued, err
+		}
+		issued++
+	}
+	return issued, nil
+}
+
+// retainerBlockForInvoice returns the block a prepayment invoice bought, or nil for any other invoice
+func (a *App) retainerBlockForInvoice(invoiceID uint) *RetainerBlock {
+	var block RetainerBlock
+	if err := a.DB.Where("invoice_id = ?", invoiceID).Limit(1).Find(&block).Error; err != nil || block.ID == 0 {
+		return nil
+	}
+	return &block
+}
+
+// activateRetainerBlock opens a block for drawing once its prepayment invoice has been approved
+func (a *App) activateRetainerBlock(invoice *Invoice) {
+	a.DB.Model(&RetainerBlock{}).Where("invoice_id = ? AND state = ?", invoice.ID, RetainerBlockStatePending.String()).
+		Update("state", RetainerBlockStateOpen.String())
+}
+
+// DrawDownRetainers applies an invoice's work on retainer projects against their prepaid blocks, oldest
+// first. Covered hours are credited back on the invoice, so only overage is billed at the normal rates,
+// and the block's share of the prepayment is moved from DEFERRED_REVENUE to REVENUE. Entries already
+// drawn for this invoice are skipped, so it is safe to call again.
+func (a *App) DrawDownRetainers(invoice *Invoice) error {
+	var entries []Entry
+	if err := a.DB.Where("invoice_id = ? AND state NOT IN ?", invoice.ID, []string{
+		EntryStateVoid.String(),
+		EntryStateRejected.String(),
+		EntryStateExcluded.String(),
+	}).Order("start, id").Find(&entries).Error; err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var drawnIDs []uint
+	a.DB.Model(&RetainerDrawdown{}).Where("invoice_id = ?", invoice.ID).Pluck("entry_id", &drawnIDs)
+	drawn := make(map[uint]bool, len(drawnIDs))
+	for _, id := range drawnIDs {
+		drawn[id] = true
+	}
+
+	projects := make(map[uint]*Project)
+	blocks := make(map[uint][]RetainerBlock)
+	touched := make(map[uint]*RetainerBlock)
+	recognized := make(map[uint]int64)
+	var credited int64
+	var hours float64
+	for _, entry := range entries {
+		if drawn[entry.ID] {
+			continue
+		}
+		project, ok := projects[entry.ProjectID]
+		if !ok {
+			project = &Project{}
+			a.DB.Limit(1).Find(project, entry.ProjectID)
+			projects[entry.ProjectID] = project
+			if project.RetainerType != "" {
+				var open []RetainerBlock
+				if err := a.DB.Where("project_id = ? AND state = ?", project.ID, RetainerBlockStateOpen.String()).
+					Order("period_start, id").Find(&open).Error; err != nil {
+					return fmt.Errorf("failed to load retainer blocks: %w", err)
+				}
+				blocks[project.ID] = open
+			}
+		}
+		entryHours := entry.Duration().Hours()
+		if project.RetainerType == "" || entryHours <= 0 {
+			continue
+		}
+
+		remaining := entryHours
+		for i := range blocks[project.ID] {
+			block := &blocks[project.ID][i]
+			left := block.HoursRemaining()
+			if remaining <= retainerHourEpsilon {
+				break
+			}
+			if left <= retainerHourEpsilon || !block.covers(entry.Start, project.RetainerRollover) {
+				continue
+			}
+			take := math.Min(remaining, left)
+			credit := int64(math.Round(float64(entry.Fee) * take / entryHours))
+			// The last hours on a block recognize whatever is left of it so rounding never strands cents
+			revenue := int64(math.Round(float64(block.Amount) * take / block.Hours))
+			if left-take <= retainerHourEpsilon {
+				revenue = block.Amount - block.AmountRecognized
+				block.State = RetainerBlockStateExhausted.String()
+			}
+			block.HoursUsed += take
+			block.AmountRecognized += revenue
+
+			if err := a.DB.Create(&RetainerDrawdown{
+				TenantID:        invoice.TenantID,
+				RetainerBlockID: block.ID,
+				EntryID:         entry.ID,
+				InvoiceID:       invoice.ID,
+				Hours:           take,
+				Credited:        credit,
+				Recognized:      revenue,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to record retainer drawdown: %w", err)
+			}
+			touched[block.ID] = block
+			recognized[block.ID] += revenue
+			credited += credit
+			hours += take
+			remaining -= take
+		}
+	}
+	if len(touched) == 0 {
+		return nil
+	}
+
+	if invoice.Account.ID == 0 {
+		a.DB.First(&invoice.Account, invoice.AccountID)
+	}
+	subAccount := fmt.Sprintf("%d:%s", invoice.AccountID, invoice.Account.Name)
+	var lines []Journal
+	for id, block := range touched {
+		if err := a.DB.Save(block).Error; err != nil {
+			return fmt.Errorf("failed to update retainer block %d: %w", id, err)
+		}
+		if recognized[id] == 0 {
+			continue
+		}
+		// Revenue comes out of deferred revenue at the rate the prepayment was accrued at
+		var prepayment Invoice
+		a.DB.Select("id", "currency", "exchange_rate").Limit(1).Find(&prepayment, block.InvoiceID)
+		memo := fmt.Sprintf("Retainer revenue recognized from invoice #%d for work on invoice #%d", block.InvoiceID, invoice.ID)
+		deferred := Journal{
+			TenantID:   invoice.TenantID,
+			Account:    AccountDeferredRevenue.String(),
+			SubAccount: subAccount,
+			InvoiceID:  &invoice.ID,
+			Memo:       memo,
+			Debit:      recognized[id],
+		}
+		deferred.translate(prepayment.Currency, prepayment.ExchangeRate)
+		revenue := Journal{
+			TenantID:   invoice.TenantID,
+			Account:    AccountRevenue.String(),
+			SubAccount: subAccount,
+			InvoiceID:  &invoice.ID,
+			Memo:       memo,
+			Credit:     recognized[id],
+		}
+		revenue.translate(prepayment.Currency, prepayment.ExchangeRate)
+		lines = append(lines, deferred, revenue)
+	}
+	if len(lines) > 0 {
+		if err := a.PostJournalTransaction(&JournalTransaction{
+			TenantID:      invoice.TenantID,
+			EffectiveDate: invoice.AcceptedAt,
+			SourceType:    JournalSourceInvoice.String(),
+			SourceID:      &invoice.ID,
+			Memo:          fmt.Sprintf("Retainer drawdown for invoice #%d", invoice.ID),
+			Lines:         lines,
+		}); err != nil {
+			return fmt.Errorf("failed to recognize retainer revenue: %w", err)
+		}
+	}
+
+	if credited > 0 {
+		if err := a.DB.Create(&Adjustment{
+			TenantID:  invoice.TenantID,
+			InvoiceID: &invoice.ID,
+			Type:      AdjustmentTypeCredit.String(),
+			State:     AdjustmentStateApproved.String(),
+			Amount:    float64(credited) / 100,
+			Retainer:  true,
+			Notes:     fmt.Sprintf("Retainer, %s hours drawn from prepaid balance", formatRetainerHours(hours)),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to credit retainer hours: %w", err)
+		}
+	}
+	log.Printf("Drew %s retainer hours for invoice %d", formatRetainerHours(hours), invoice.ID)
+	return nil
+}
+
+// releaseRetainerDrawdowns puts the hours a voided invoice drew back on their blocks. The revenue it
+// recognized is undone with the rest of the invoice's journals.
+func (a *App) releaseRetainerDrawdowns(invoiceID uint) error {
+	var drawdowns []RetainerDrawdown
+	if err := a.DB.Where("invoice_id = ?", invoiceID).Find(&drawdowns).Error; err != nil {
+		return fmt.Errorf("failed to load retainer drawdowns: %w", err)
+	}
+	for _, drawdown := range drawdowns {
+		if err := a.DB.Model(&RetainerBlock{}).Where("id = ?", drawdown.RetainerBlockID).Updates(map[string]interface{}{
+			"hours_used":        gorm.Expr("hours_used - ?", drawdown.Hours),
+			"amount_recognized": gorm.Expr("amount_recognized - ?", drawdown.Recognized),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to release retainer hours: %w", err)
+		}
+		a.DB.Model(&RetainerBlock{}).Where("id = ? AND state = ?", drawdown.RetainerBlockID, RetainerBlockStateExhausted.String()).
+			Update("state", RetainerBlockStateOpen.String())
+	}
+	if len(drawdowns) > 0 {
+		a.DB.Where("invoice_id = ?", invoiceID).Delete(&RetainerDrawdown{})
+	}
+	return nil
+}
+
+// ExpireRetainerBlocks lapses the unused hours on blocks past their period for projects whose policy
+// is to expire them. What the client prepaid for those hours is recognized as revenue. It returns the
+// number of blocks expired.
+func (a *App) ExpireRetainerBlocks(tenantID uint, now time.Time) (int, error) {
+	var projectIDs []uint
+	if err := a.DB.Model(&Project{}).Scopes(TenantScope(tenantID)).
+		Where("retainer_type != ? AND retainer_rollover = ?", "", RetainerRolloverPolicyExpire.String()).
+		Pluck("id", &projectIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to load retainer projects: %w", err)
+	}
+	if len(projectIDs) == 0 {
+		return 0, nil
+	}
+	var blocks []RetainerBlock
+	if err := a.DB.Where("project_id IN ? AND state = ? AND period_end > ? AND period_end < ?",
+		projectIDs, RetainerBlockStateOpen.String(), time.Time{}, now).Find(&blocks).Error; err != nil {
+		return 0, fmt.Errorf("failed to load retainer blocks: %w", err)
+	}
+
+	expired := 0
+	for i := range blocks {
+		block := &blocks[i]
+		if leftover := block.Amount - block.AmountRecognized; leftover > 0 {
+			var prepayment Invoice
+			a.DB.Preload("Account").Limit(1).Find(&prepayment, block.InvoiceID)
+			subAccount := fmt.Sprintf("%d:%s", prepayment.AccountID, prepayment.Account.Name)
+			memo := fmt.Sprintf("Unused retainer hours expired on invoice #%d", block.InvoiceID)
+			deferred := Journal{TenantID: tenantID, Account: AccountDeferredRevenue.String(), SubAccount: subAccount, InvoiceID: &block.InvoiceID, Memo: memo, Debit: leftover}
+			deferred.translate(prepayment.Currency, prepayment.ExchangeRate)
+			revenue := Journal{TenantID: tenantID, Account: AccountRevenue.String(), SubAccount: subAccount, InvoiceID: &block.InvoiceID, Memo: memo, Credit: leftover}
+			revenue.translate(prepayment.Currency, prepayment.ExchangeRate)
+			if err := a.PostJournalTransaction(&JournalTransaction{
+				TenantID:      tenantID,
+				EffectiveDate: now,
+				SourceType:    JournalSourceInvoice.String(),
+				SourceID:      &block.InvoiceID,
+				Memo:          memo,
+				Lines:         []Journal{deferred, revenue},
+			}); err != nil {
+				return expired, fmt.Errorf("failed to recognize expired retainer: %w", err)
+			}
+		}
+		block.HoursExpired = block.HoursRemaining()
+		block.AmountRecognized = block.Amount
+		block.State = RetainerBlockStateExpired.String()
+		block.ExpiredAt = &now
+		if err := a.DB.Save(block).Error; err != nil {
+			return expired, fmt.Errorf("failed to expire retainer block %d: %w", block.ID, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// RetainerBalance is where a project's prepaid hours stand
+type RetainerBalance struct {
+	ProjectID       uint            `json:"project_id"`
+	ProjectName     string          `json:"project_name"`
+	RetainerType    string          `json:"retainer_type"`
+	Rollover        string          `json:"rollover"`
+	HoursPurchased  float64         `json:"hours_purchased"`
+	HoursUsed       float64         `json:"hours_used"`
+	HoursExpired    float64         `json:"hours_expired"`
+	HoursRemaining  float64         `json:"hours_remaining"`
+	AmountRemaining int64           `json:"amount_remaining"` // Prepaid but not yet recognized, in cents
+	Blocks          []RetainerBlock `json:"blocks"`
+}
+
+// GetRetainerBalance totals the blocks on a retainer project. Blocks still awaiting approval of their
+// invoice are listed but don't count towards the balance.
+func (a *App) GetRetainerBalance(tenantID, projectID uint) (*RetainerBalance, error) {
+	var project Project
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&project, projectID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load project %d: %w", projectID, err)
+	}
+	if project.RetainerType == "" {
+		return nil, ErrNotRetainerProject
+	}
+
+	balance := &RetainerBalance{
+		ProjectID:    project.ID,
+		ProjectName:  project.Name,
+		RetainerType: project.RetainerType,
+		Rollover:     project.RetainerRollover,
+		Blocks:       []RetainerBlock{},
+	}
+	if err := a.DB.Where("project_id = ? AND state != ?", project.ID, RetainerBlockStateVoid.String()).
+		Order("period_start, id").Find(&balance.Blocks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load retainer blocks: %w", err)
+	}
+	for _, block := range balance.Blocks {
+		if block.State == RetainerBlockStatePending.String() {
+			continue
+		}
+		balance.HoursPurchased += block.Hours
+		balance.HoursUsed += block.HoursUsed
+		balance.HoursExpired += block.HoursExpired
+		balance.HoursRemaining += block.HoursRemaining()
+		balance.AmountRemaining += block.Amount - block.AmountRecognized
+	}
+	return balance, nil
+}
+
+// ListRetainerBalances returns the balance of every retainer project on an account
+func (a *App) ListRetainerBalances(tenantID, accountID uint) ([]RetainerBalance, error) {
+	var projectIDs []uint
+	if err := a.DB.Model(&Project{}).Scopes(TenantScope(tenantID)).
+		Where("account_id = ? AND retainer_type != ?", accountID, "").Order("name").
+		Pluck("id", &projectIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load retainer projects: %w", err)
+	}
+	balances := make([]RetainerBalance, 0, len(projectIDs))
+	for _, id := range projectIDs {
+		balance, err := a.GetRetainerBalance(tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, *balance)
+	}
+	return balances, nil
+}
+
+// formatRetainerHours prints hours without trailing zeros, e.g. 20 or 12.5
+func formatRetainerHours(hours float64) string {
+	return fmt.Sprintf("%g", math.Round(hours*100)/100)
+}
@@ -0,0 +1,143 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// accountBalance nets the credits against the debits booked to an account
+func accountBalance(db *gorm.DB, account JournalAccountType) int64 {
+	var balance int64
+	db.Table("journals").Select("COALESCE(SUM(credit - debit), 0)").Where("account = ?", account.String()).Scan(&balance)
+	return balance
+}
+
+// TestRetainerDrawdownAndExpiry tests that a monthly retainer is held in deferred revenue until work
+// draws it down, that only the overage is billed, and that unused hours expire into revenue
+func TestRetainerDrawdownAndExpiry(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "retainer", Name: "Retainer Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Retainer Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	project := Project{
+		TenantID:       tenant.ID,
+		Name:           "Support",
+		AccountID:      account.ID,
+		ActiveStart:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		ActiveEnd:      time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+		RetainerType:   RetainerTypeMonthly.String(),
+		RetainerHours:  10,
+		RetainerAmount: 150000,
+	}
+	if err := ValidateProjectRetainer(&project); err != nil || project.RetainerRollover != RetainerRolloverPolicyExpire.String() {
+		t.Fatalf("Expected the retainer to validate and default to expiring, got %q: %v", project.RetainerRollover, err)
+	}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 150}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Support Hours", Code: "SUP", ProjectID: project.ID, RateID: rate.ID}
+	db.Create(&code)
+
+	january := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	prepayment, err := app.IssueRetainerInvoice(tenant.ID, project.ID, january)
+	if err != nil {
+		t.Fatalf("Failed to issue retainer invoice: %v", err)
+	}
+	if _, err := app.IssueRetainerInvoice(tenant.ID, project.ID, january.AddDate(0, 0, 10)); !errors.Is(err, ErrRetainerAlreadyIssued) {
+		t.Errorf("Expected a second January retainer to be refused, got %v", err)
+	}
+	if err := app.ApproveInvoice(prepayment.ID); err != nil {
+		t.Fatalf("Failed to approve retainer invoice: %v", err)
+	}
+	if deferred := accountBalance(db, AccountDeferredRevenue); deferred != 150000 {
+		t.Errorf("Expected 1500.00 held in deferred revenue, got %d", deferred)
+	}
+	if revenue := accountBalance(db, AccountAdjustmentRevenue); revenue != 0 {
+		t.Errorf("Expected the retainer fee not to be booked as adjustment revenue, got %d", revenue)
+	}
+
+	// 12 hours in January at 150.00: ten come out of the retainer and two are billed
+	work := Invoice{TenantID: tenant.ID, AccountID: account.ID, ProjectID: &project.ID, Name: "January", State: InvoiceStateDraft.String(), Type: InvoiceTypeAR.String()}
+	db.Create(&work)
+	addEntry := func(invoice *Invoice, start time.Time, hours int) {
+		entry := Entry{
+			TenantID:      tenant.ID,
+			ProjectID:     project.ID,
+			BillingCodeID: code.ID,
+			InvoiceID:     &invoice.ID,
+			Start:         start,
+			End:           start.Add(time.Duration(hours) * time.Hour),
+			State:         EntryStateApproved.String(),
+			Fee:           hours * 15000,
+		}
+		if err := db.Session(&gorm.Session{SkipHooks: true}).Create(&entry).Error; err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+	}
+	addEntry(&work, january.Add(9*time.Hour), 8)
+	addEntry(&work, january.AddDate(0, 0, 1).Add(9*time.Hour), 4)
+	if err := app.ApproveInvoice(work.ID); err != nil {
+		t.Fatalf("Failed to approve invoice: %v", err)
+	}
+
+	var billed Invoice
+	db.First(&billed, work.ID)
+	if billed.TotalAmount != 300 {
+		t.Errorf("Expected only the two hours of overage billed, got %.2f", billed.TotalAmount)
+	}
+	var block RetainerBlock
+	db.Where("invoice_id = ?", prepayment.ID).First(&block)
+	if block.State != RetainerBlockStateExhausted.String() || block.HoursUsed != 10 || block.AmountRecognized != 150000 {
+		t.Errorf("Expected the block exhausted, got %s with %.2f hours used and %d recognized", block.State, block.HoursUsed, block.AmountRecognized)
+	}
+	if deferred, revenue := accountBalance(db, AccountDeferredRevenue), accountBalance(db, AccountRevenue); deferred != 0 || revenue != 180000 {
+		t.Errorf("Expected deferred revenue drawn down to 0 and 1800.00 of revenue, got %d and %d", deferred, revenue)
+	}
+	if credits := accountBalance(db, AccountCreditsIssued); credits != 0 {
+		t.Errorf("Expected the drawdown not to be booked as a credit issued, got %d", credits)
+	}
+
+	// Four of February's ten hours are used and the other six lapse at the end of the month
+	february := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)
+	second, err := app.IssueRetainerInvoice(tenant.ID, project.ID, february)
+	if err != nil {
+		t.Fatalf("Failed to issue February retainer: %v", err)
+	}
+	app.ApproveInvoice(second.ID)
+	march := Invoice{TenantID: tenant.ID, AccountID: account.ID, ProjectID: &project.ID, Name: "February", State: InvoiceStateDraft.String(), Type: InvoiceTypeAR.String()}
+	db.Create(&march)
+	addEntry(&march, february.Add(9*time.Hour), 4)
+	if err := app.ApproveInvoice(march.ID); err != nil {
+		t.Fatalf("Failed to approve February invoice: %v", err)
+	}
+
+	balance, err := app.GetRetainerBalance(tenant.ID, project.ID)
+	if err != nil {
+		t.Fatalf("Failed to get retainer balance: %v", err)
+	}
+	if balance.HoursPurchased != 20 || balance.HoursUsed != 14 || balance.HoursRemaining != 6 || balance.AmountRemaining != 90000 {
+		t.Errorf("Expected 6 of 20 hours left worth 900.00, got %+v", balance)
+	}
+
+	if expired, err := app.ExpireRetainerBlocks(tenant.ID, time.Date(2025, 3, 1, 6, 0, 0, 0, time.UTC)); err != nil || expired != 1 {
+		t.Fatalf("Expected one block to expire, got %d: %v", expired, err)
+	}
+	if deferred, revenue := accountBalance(db, AccountDeferredRevenue), accountBalance(db, AccountRevenue); deferred != 0 || revenue != 330000 {
+		t.Errorf("Expected the unused hours recognized as revenue, got %d deferred and %d revenue", deferred, revenue)
+	}
+	balance, _ = app.GetRetainerBalance(tenant.ID, project.ID)
+	if balance.HoursExpired != 6 || balance.HoursRemaining != 0 {
+		t.Errorf("Expected 6 hours expired and none left, got %+v", balance)
+	}
+
+	if err := app.VoidInvoice(second.ID); !errors.Is(err, ErrRetainerInUse) {
+		t.Errorf("Expected voiding a drawn retainer invoice to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidRoundingPolicy = errors.New("invalid rounding policy")
+
+// ValidateRoundingPolicy checks a billing code's or account's rounding policy. Empty means inherit,
+// or truncate when there's nothing to inherit.
+func ValidateRoundingPolicy(policy string) error {
+	switch RoundingPolicy(policy) {
+	case "", RoundingPolicyDown, RoundingPolicyUp, RoundingPolicyNearest, RoundingPolicyMinimum, RoundingPolicyDaily:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidRoundingPolicy, policy)
+	}
+}
+
+// RoundMinutes applies a rounding policy to a number of minutes, in increments of the given size.
+// Daily rounding rounds to the nearest increment, since it's applied to the day's total.
+func RoundMinutes(minutes float64, increment int, policy RoundingPolicy) float64 {
+	if increment <= 0 || minutes <= 0 {
+		return math.Max(minutes, 0)
+	}
+	step := float64(increment)
+	// Durations come from timestamps, so allow for floating point error on exact multiples
+	units := minutes / step
+	switch policy {
+	case RoundingPolicyUp:
+		return math.Ceil(units-1e-9) * step
+	case RoundingPolicyNearest, RoundingPolicyDaily:
+		return math.Floor(units+0.5+1e-9) * step
+	case RoundingPolicyMinimum:
+		return math.Max(minutes, step)
+	default:
+		return math.Floor(units+1e-9) * step
+	}
+}
+
+// roundingPolicy resolves the policy for an entry's billing code, falling back to the account's
+func roundingPolicy(tx *gorm.DB, billingCode *BillingCode) RoundingPolicy {
+	if billingCode.RoundingPolicy != "" {
+		return RoundingPolicy(billingCode.RoundingPolicy)
+	}
+	var project Project
+	tx.Select("account_id").Where("id = ?", billingCode.ProjectID).Limit(1).Find(&project)
+	var account Account
+	if project.AccountID != 0 {
+		tx.Select("rounding_policy").Where("id = ?", project.AccountID).Limit(1).Find(&account)
+	}
+	if account.RoundingPolicy != "" {
+		return RoundingPolicy(account.RoundingPolicy)
+	}
+	return RoundingPolicyDown
+}
+
+// GetBilledMinutes returns the minutes of the entry that are billed under its rounding policy. With
+// daily rounding, the employee's entries on the code that day are taken in order and each is billed
+// the difference its minutes make to the rounded running total, so the day adds up to the rounded
+// daily total.
+func (e *Entry) GetBilledMinutes(tx *gorm.DB, billingCode *BillingCode) float64 {
+	minutes := e.Duration().Minutes()
+	policy := roundingPolicy(tx, billingCode)
+	if policy != RoundingPolicyDaily {
+		return RoundMinutes(minutes, billingCode.RoundedTo, policy)
+	}
+
+	before := e.minutesEarlierInDay(tx)
+	return RoundMinutes(before+minutes, billingCode.RoundedTo, policy) - RoundMinutes(before, billingCode.RoundedTo, policy)
+}
+
+// dayBounds is the UTC day an entry started on
+func (e *Entry) dayBounds() (time.Time, time.Time) {
+	day := time.Date(e.Start.Year(), e.Start.Month(), e.Start.Day(), 0, 0, 0, 0, time.UTC)
+	return day, day.AddDate(0, 0, 1)
+}
+
+// sameDayEntries loads the employee's other entries on the code that started the same day, in order
+func (e *Entry) sameDayEntries(tx *gorm.DB) []Entry {
+	dayStart, dayEnd := e.dayBounds()
+	var entries []Entry
+	tx.Select("id", "employee_id", "billing_code_id", "start", "end", "state", "billed_minutes", "fee").
+		Where("employee_id = ? AND billing_code_id = ? AND start >= ? AND start < ? AND state != ? AND id != ?",
+			e.EmployeeID, e.BillingCodeID, dayStart, dayEnd, EntryStateVoid.String(), e.ID).
+		Order("start, id").Find(&entries)
+	return entries
+}
+
+// follows reports whether the entry comes after another in the day's running total
+func (e *Entry) follows(other *Entry) bool {
+	if other.Start.Equal(e.Start) {
+		return other.ID < e.ID || e.ID == 0
+	}
+	return other.Start.Before(e.Start)
+}
+
+// minutesEarlierInDay totals the minutes of the employee's entries on the code earlier the same day
+func (e *Entry) minutesEarlierInDay(tx *gorm.DB) float64 {
+	var minutes float64
+	for _, other := range e.sameDayEntries(tx) {
+		if e.follows(&other) {
+			minutes += other.Duration().Minutes()
+		}
+	}
+	return minutes
+}
+
+// AfterSave rebalances the day under daily rounding. Changing or voiding an entry changes the running
+// total for the entries after it, so their billed minutes and fees are recomputed while they're still unbilled.
+// It also queues a check of the project's budget.
+func (e *Entry) AfterSave(tx *gorm.DB) (err error) {
+	if err := queueBudgetCheck(tx, e.ProjectID); err != nil {
+		return err
+	}
+	var billingCode BillingCode
+	tx.Where("id = ?", e.BillingCodeID).Limit(1).Find(&billingCode)
+	if billingCode.ID == 0 || roundingPolicy(tx, &billingCode) != RoundingPolicyDaily {
+		return nil
+	}
+	for _, later := range e.sameDayEntries(tx) {
+		if !later.follows(e) || (later.State != EntryStateDraft.String() && later.State != EntryStateUnaffiliated.String()) {
+			continue
+		}
+		billed := later.GetBilledMinutes(tx, &billingCode)
+		fee := int(later.GetFee(tx) * 100)
+		if billed == later.BilledMinutes && fee == later.Fee {
+			continue
+		}
+		if err := tx.Model(&Entry{}).Where("id = ?", later.ID).
+			UpdateColumns(map[string]interface{}{"billed_minutes": billed, "fee": fee}).Error; err != nil {
+			return fmt.Errorf("failed to rebalance daily rounding: %w", err)
+		}
+	}
+	return nil
+}
+
+// BilledHours is the entry's time after rounding. Entries saved before billed minutes were recorded
+// fall back to their raw duration.
+func (e *Entry) BilledHours() float64 {
+	if e.BilledMinutes == 0 && e.Fee != 0 {
+		return e.Duration().Hours()
+	}
+	return e.BilledMinutes / HOUR
+}
@@ -0,0 +1,115 @@
+package cronos
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestRoundMinutes tests each rounding policy against a 15-minute increment
+func TestRoundMinutes(t *testing.T) {
+	tests := []struct {
+		policy  RoundingPolicy
+		minutes float64
+		want    float64
+	}{
+		{RoundingPolicyDown, 14, 0},
+		{RoundingPolicyDown, 44, 30},
+		{RoundingPolicyUp, 14, 15},
+		{RoundingPolicyUp, 30, 30},
+		{RoundingPolicyUp, 31, 45},
+		{RoundingPolicyNearest, 7, 0},
+		{RoundingPolicyNearest, 7.5, 15},
+		{RoundingPolicyNearest, 52, 45},
+		{RoundingPolicyMinimum, 5, 15},
+		{RoundingPolicyMinimum, 52, 52},
+		{"", 14, 0},
+	}
+	for _, tt := range tests {
+		if got := RoundMinutes(tt.minutes, 15, tt.policy); got != tt.want {
+			t.Errorf("RoundMinutes(%v, 15, %q) = %v, want %v", tt.minutes, tt.policy, got, tt.want)
+		}
+	}
+	if got := RoundMinutes(14, 0, RoundingPolicyUp); got != 14 {
+		t.Errorf("Expected no rounding without an increment, got %v", got)
+	}
+	if err := ValidateRoundingPolicy("ROUNDING_POLICY_SIDEWAYS"); !errors.Is(err, ErrInvalidRoundingPolicy) {
+		t.Errorf("Expected an unknown policy to be refused, got %v", err)
+	}
+}
+
+// TestEntryRoundingPolicies tests that entries take their billing code's rounding policy or else the
+// account's, and that daily rounding bills the rounded daily total across the day's entries
+func TestEntryRoundingPolicies(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "rounding", Name: "Rounding Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	account := Account{TenantID: tenant.ID, Name: "Rounded Client", Type: AccountTypeClient.String(), RoundingPolicy: RoundingPolicyUp.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Rounded Project", AccountID: account.ID}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 200}
+	db.Create(&rate)
+	inherited := BillingCode{TenantID: tenant.ID, Name: "Inherited", Code: "INH", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15}
+	daily := BillingCode{TenantID: tenant.ID, Name: "Daily", Code: "DAY", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15, RoundingPolicy: RoundingPolicyDaily.String()}
+	db.Create(&inherited)
+	db.Create(&daily)
+	employee := Employee{TenantID: tenant.ID, FirstName: "Robin"}
+	db.Create(&employee)
+	invoice := Invoice{TenantID: tenant.ID, AccountID: account.ID, ProjectID: &project.ID, Name: "Rounding", State: InvoiceStateDraft.String(), Type: InvoiceTypeAR.String()}
+	db.Create(&invoice)
+
+	addEntry := func(code *BillingCode, start time.Time, minutes int) *Entry {
+		entry := &Entry{TenantID: tenant.ID, ProjectID: project.ID, BillingCodeID: code.ID, EmployeeID: employee.ID, InvoiceID: &invoice.ID,
+			Start: start, End: start.Add(time.Duration(minutes) * time.Minute), State: EntryStateDraft.String()}
+		if err := db.Create(entry).Error; err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+		return entry
+	}
+
+	// The account rounds up, so 14 minutes bills a quarter hour instead of nothing
+	short := addEntry(&inherited, time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC), 14)
+	if short.BilledMinutes != 15 || short.Fee != 5000 {
+		t.Errorf("Expected 14 minutes billed as 15 for 50.00, got %v minutes for %d", short.BilledMinutes, short.Fee)
+	}
+
+	// Three 10-minute entries in a day bill the 30-minute daily total between them
+	day := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	entries := []*Entry{addEntry(&daily, day, 10), addEntry(&daily, day.Add(time.Hour), 10), addEntry(&daily, day.Add(2*time.Hour), 10)}
+	dayTotal := func() (minutes float64, fee int) {
+		for _, entry := range entries {
+			var saved Entry
+			db.First(&saved, entry.ID)
+			if saved.State != EntryStateVoid.String() {
+				minutes += saved.BilledMinutes
+				fee += saved.Fee
+			}
+		}
+		return minutes, fee
+	}
+	if minutes, fee := dayTotal(); minutes != 30 || fee != 10000 {
+		t.Errorf("Expected the day to bill 30 minutes for 100.00, got %v minutes for %d", minutes, fee)
+	}
+
+	// Voiding the middle entry rebalances the day to the rounded 20 minutes that are left
+	entries[1].State = EntryStateVoid.String()
+	db.Save(entries[1])
+	if minutes, fee := dayTotal(); minutes != 15 || fee != 5000 {
+		t.Errorf("Expected the day to bill 15 minutes for 50.00 after the void, got %v minutes for %d", minutes, fee)
+	}
+
+	db.Preload("Entries").First(&invoice, invoice.ID)
+	draft := app.GetDraftInvoice(&invoice)
+	if draft.TotalBilledHours != 0.5 || math.Abs(draft.TotalHours-34.0/60) > 1e-9 {
+		t.Errorf("Expected 0.5 billed hours for 34 minutes worked, got %v billed and %v raw", draft.TotalBilledHours, draft.TotalHours)
+	}
+	app.UpdateInvoiceTotals(&invoice)
+	if invoice.TotalBilledHours != 0.5 || invoice.TotalFees != 100 {
+		t.Errorf("Expected 0.5 billed hours for 100.00, got %v for %.2f", invoice.TotalBilledHours, invoice.TotalFees)
+	}
+}
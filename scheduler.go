@@ -0,0 +1,580 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Names of the built-in scheduled jobs
+const (
+	JobInvoiceRollover     = "invoice_rollover"
+	JobRecurringPayroll    = "recurring_payroll"
+	JobJournalBalanceCheck = "journal_balance_check"
+	JobDunningReminders    = "dunning_reminders"
+	JobRetainers           = "retainers"
+	JobTimerAutoStop       = "timer_auto_stop"
+	JobLeaveAccrual        = "leave_accrual"
+	JobBudgetAlerts        = "budget_alerts"
+	JobBudgetChecks        = "budget_checks"
+)
+
+const (
+	schedulerLockName    = "cronos_scheduler"
+	schedulerLockTTL     = 2 * time.Minute
+	schedulerLockRenewal = 40 * time.Second // the lease is renewed this often while jobs run
+	schedulerTickPeriod  = time.Minute
+	journalCheckHourUTC  = 2  // the nightly balance check fires at 02:00 UTC
+	dunningHourUTC       = 15 // reminders go out mid-morning in US time zones
+	retainerHourUTC      = 6  // retainers are invoiced and expired before the working day starts
+	maxJobRunHistoryRows = 100
+)
+
+var ErrJobNotFound = errors.New("scheduled job not found")
+var ErrJobAlreadyRunning = errors.New("scheduled job is already running")
+
+// JobFunc performs the work of a scheduled job for a single tenant. The returned string is a short
+// human readable summary that is stored on the JobRun.
+type JobFunc func(a *App, tenantID uint, now time.Time) (string, error)
+
+// JobDefinition describes a job the scheduler knows how to run. Definitions live in code while
+// the per-tenant ScheduledJob rows hold the state (pause flag, next run, last status).
+type JobDefinition struct {
+	Name        string
+	Description string
+	Interval    time.Duration
+	// FirstRun returns the initial NextRunAt for a newly persisted job. When nil the job is due immediately.
+	FirstRun func(now time.Time) time.Time
+	Run      JobFunc
+}
+
+// Scheduler is an in-process job runner. Every instance ticks, but only the instance holding the
+// SchedulerLock lease fires scheduled jobs, so running several App Engine instances is safe.
+type Scheduler struct {
+	app     *App
+	holder  string
+	jobs    map[string]JobDefinition
+	order   []string
+	mu      sync.Mutex
+	running map[uint]bool
+	stop    chan struct{}
+	done    chan struct{}
+	// renewEvery is how often the lease is renewed while a tick's jobs run
+	renewEvery time.Duration
+}
+
+// NewScheduler creates a scheduler with the default jobs registered
+func (a *App) NewScheduler() *Scheduler {
+	holder := os.Getenv("GAE_INSTANCE")
+	if holder == "" {
+		hostname, _ := os.Hostname()
+		holder = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	s := &Scheduler{
+		app:        a,
+		holder:     holder,
+		jobs:       make(map[string]JobDefinition),
+		running:    make(map[uint]bool),
+		renewEvery: schedulerLockRenewal,
+	}
+	for _, def := range DefaultJobs() {
+		s.Register(def)
+	}
+	return s
+}
+
+// DefaultJobs returns the job definitions that ship with cronos
+func DefaultJobs() []JobDefinition {
+	return []JobDefinition{
+		{
+			Name:        JobInvoiceRollover,
+			Description: "Create the next draft invoice for each active project once its billing period rolls over",
+			Interval:    time.Hour,
+			Run:         runInvoiceRollover,
+		},
+		{
+			Name:        JobRecurringPayroll,
+			Description: "Generate recurring payroll line items and accruals for the current month",
+			Interval:    6 * time.Hour,
+			Run:         runRecurringPayroll,
+		},
+		{
+			Name:        JobJournalBalanceCheck,
+			Description: "Verify that journal debits and credits balance",
+			Interval:    24 * time.Hour,
+			FirstRun:    nextJournalCheck,
+			Run:         runJournalBalanceCheck,
+		},
+		{
+			Name:        JobDunningReminders,
+			Description: "Send payment reminders and charge late fees on open invoices per the dunning sequence",
+			Interval:    24 * time.Hour,
+			FirstRun:    nextDunningRun,
+			Run:         runDunningReminders,
+		},
+		{
+			Name:        JobRetainers,
+			Description: "Invoice monthly retainers and expire unused retainer hours under the expire policy",
+			Interval:    24 * time.Hour,
+			FirstRun:    nextRetainerRun,
+			Run:         runRetainers,
+		},
+		{
+			Name:        JobTimerAutoStop,
+			Description: "Stop timers that have run past the tenant's limit and notify their employees",
+			Interval:    15 * time.Minute,
+			Run:         runTimerAutoStop,
+		},
+		{
+			Name:        JobLeaveAccrual,
+			Description: "Credit employees with the leave their leave types accrue each pay period",
+			Interval:    24 * time.Hour,
+			Run:         runLeaveAccrual,
+		},
+		{
+			Name:        JobBudgetAlerts,
+			Description: "Project each active project's budget from its burn rate and alert its team when it crosses a threshold",
+			Interval:    6 * time.Hour,
+			Run:         runBudgetAlerts,
+		},
+		{
+			Name:        JobBudgetChecks,
+			Description: "Check the budgets of projects whose entries have changed and alert their teams when they cross a threshold",
+			Interval:    5 * time.Minute,
+			Run:         runBudgetChecks,
+		},
+	}
+}
+
+// Register adds or replaces a job definition
+func (s *Scheduler) Register(def JobDefinition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[def.Name]; !exists {
+		s.order = append(s.order, def.Name)
+	}
+	s.jobs[def.Name] = def
+}
+
+// Start begins ticking in the background until Stop is called
+func (s *Scheduler) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(schedulerTickPeriod)
+		defer ticker.Stop()
+		log.Printf("Scheduler started as %s", s.holder)
+		for {
+			if err := s.Tick(time.Now().UTC()); err != nil {
+				log.Printf("Scheduler tick failed: %v", err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop halts the background loop and gives up the leader lease so another instance can take over
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	if err := s.app.ReleaseSchedulerLock(s.holder); err != nil {
+		log.Printf("Failed to release scheduler lock: %v", err)
+	}
+}
+
+// Tick runs a single scheduling pass: it takes (or renews) the leader lease, makes sure every active
+// tenant has its job rows, and runs each unpaused job whose NextRunAt has passed.
+func (s *Scheduler) Tick(now time.Time) error {
+	leader, err := s.app.AcquireSchedulerLock(s.holder, now, schedulerLockTTL)
+	if err != nil {
+		return err
+	}
+	if !leader {
+		return nil
+	}
+
+	var tenants []Tenant
+	if err := s.app.DB.Where("status = ?", "active").Find(&tenants).Error; err != nil {
+		return fmt.Errorf("failed to load tenants: %w", err)
+	}
+	if len(tenants) == 0 {
+		return nil
+	}
+
+	tenantIDs := make([]uint, 0, len(tenants))
+	for _, tenant := range tenants {
+		if err := s.EnsureJobs(tenant.ID, now); err != nil {
+			log.Printf("Failed to ensure scheduled jobs for tenant %d: %v", tenant.ID, err)
+			continue
+		}
+		tenantIDs = append(tenantIDs, tenant.ID)
+	}
+
+	var due []ScheduledJob
+	if err := s.app.DB.Where("tenant_id IN ? AND paused = ? AND next_run_at <= ?", tenantIDs, false, now).
+		Order("next_run_at").Find(&due).Error; err != nil {
+		return fmt.Errorf("failed to load due jobs: %w", err)
+	}
+
+	// Jobs can run longer than the lease, so keep renewing it until they're done
+	lost := make(chan struct{})
+	release := s.holdLease(lost)
+	defer release()
+	for i := range due {
+		select {
+		case <-lost:
+			log.Printf("Scheduler lost the leader lease, leaving %d due jobs to the new leader", len(due)-i)
+			return nil
+		default:
+		}
+		if _, err := s.runJob(&due[i], JobTriggerSchedule, now); err != nil && !errors.Is(err, ErrJobAlreadyRunning) {
+			log.Printf("Scheduled job %s for tenant %d failed: %v", due[i].Name, due[i].TenantID, err)
+		}
+	}
+	return nil
+}
+
+// holdLease renews the leader lease every renewEvery until the returned function is called. If the
+// lease can't be renewed, lost is closed so no further jobs are started.
+func (s *Scheduler) holdLease(lost chan struct{}) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.renewEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			leader, err := s.app.AcquireSchedulerLock(s.holder, time.Now().UTC(), schedulerLockTTL)
+			if err != nil {
+				log.Printf("Failed to renew scheduler lock: %v", err)
+				continue
+			}
+			if !leader {
+				close(lost)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// EnsureJobs creates the ScheduledJob rows for any registered job the tenant doesn't have yet.
+// Existing rows keep their pause flag and schedule, but pick up description and interval changes.
+func (s *Scheduler) EnsureJobs(tenantID uint, now time.Time) error {
+	s.mu.Lock()
+	defs := make([]JobDefinition, 0, len(s.order))
+	for _, name := range s.order {
+		defs = append(defs, s.jobs[name])
+	}
+	s.mu.Unlock()
+
+	for _, def := range defs {
+		var job ScheduledJob
+		err := s.app.DB.Where("tenant_id = ? AND name = ?", tenantID, def.Name).First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			job = ScheduledJob{
+				TenantID:        tenantID,
+				Name:            def.Name,
+				Description:     def.Description,
+				IntervalMinutes: int(def.Interval / time.Minute),
+				NextRunAt:       now,
+			}
+			if def.FirstRun != nil {
+				job.NextRunAt = def.FirstRun(now)
+			}
+			if err := s.app.DB.Create(&job).Error; err != nil {
+				return fmt.Errorf("failed to create scheduled job %s: %w", def.Name, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load scheduled job %s: %w", def.Name, err)
+		}
+
+		interval := int(def.Interval / time.Minute)
+		if job.Description != def.Description || job.IntervalMinutes != interval {
+			job.Description = def.Description
+			job.IntervalMinutes = interval
+			if err := s.app.DB.Save(&job).Error; err != nil {
+				return fmt.Errorf("failed to update scheduled job %s: %w", def.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// TriggerJob runs a tenant's job immediately, regardless of its schedule or pause flag.
+// Manual runs don't move NextRunAt.
+func (s *Scheduler) TriggerJob(tenantID, jobID uint) (*JobRun, error) {
+	var job ScheduledJob
+	if err := s.app.DB.Scopes(TenantScope(tenantID)).First(&job, jobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return s.runJob(&job, JobTriggerManual, time.Now().UTC())
+}
+
+// SetJobPaused pauses or resumes a tenant's job
+func (s *Scheduler) SetJobPaused(tenantID, jobID uint, paused bool) (*ScheduledJob, error) {
+	var job ScheduledJob
+	if err := s.app.DB.Scopes(TenantScope(tenantID)).First(&job, jobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	job.Paused = paused
+	if err := s.app.DB.Save(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to update scheduled job: %w", err)
+	}
+	return &job, nil
+}
+
+// runJob executes a job, records its JobRun and updates the job's status. A failing job returns
+// its run alongside the error so callers can surface both.
+func (s *Scheduler) runJob(job *ScheduledJob, trigger JobTrigger, now time.Time) (*JobRun, error) {
+	s.mu.Lock()
+	def, ok := s.jobs[job.Name]
+	if ok && s.running[job.ID] {
+		s.mu.Unlock()
+		return nil, ErrJobAlreadyRunning
+	}
+	s.running[job.ID] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+
+	run := JobRun{
+		TenantID:       job.TenantID,
+		ScheduledJobID: job.ID,
+		Trigger:        trigger.String(),
+		Status:         JobRunStatusRunning.String(),
+		StartedAt:      now,
+	}
+	if err := s.app.DB.Create(&run).Error; err != nil {
+		return nil, fmt.Errorf("failed to record job run: %w", err)
+	}
+
+	var message string
+	var runErr error
+	if !ok {
+		runErr = fmt.Errorf("no job registered with name %q", job.Name)
+	} else {
+		message, runErr = safeRunJob(def.Run, s.app, job.TenantID, now)
+	}
+
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	run.Message = message
+	run.Status = JobRunStatusSucceeded.String()
+	if runErr != nil {
+		run.Status = JobRunStatusFailed.String()
+		run.Error = runErr.Error()
+	}
+	if err := s.app.DB.Save(&run).Error; err != nil {
+		log.Printf("Failed to save job run %d: %v", run.ID, err)
+	}
+
+	// Only the run's own columns are written, so a pause or edit made while it ran isn't lost
+	job.LastRunAt = &now
+	job.LastStatus = run.Status
+	updates := map[string]interface{}{"last_run_at": job.LastRunAt, "last_status": job.LastStatus}
+	if trigger == JobTriggerSchedule {
+		job.NextRunAt = nextRunAfter(job.NextRunAt, time.Duration(job.IntervalMinutes)*time.Minute, now)
+		updates["next_run_at"] = job.NextRunAt
+	}
+	if err := s.app.DB.Model(job).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update scheduled job %d: %v", job.ID, err)
+	}
+
+	s.app.pruneJobRuns(job.ID)
+	return &run, runErr
+}
+
+// safeRunJob converts a panic inside a job into an error so one bad job can't take down the scheduler
+func safeRunJob(fn JobFunc, a *App, tenantID uint, now time.Time) (message string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(a, tenantID, now)
+}
+
+// nextRunAfter advances from the previous scheduled time in whole intervals until it is in the
+// future, which keeps jobs aligned to their original slot (e.g. 02:00) even after downtime.
+func nextRunAfter(previous time.Time, interval time.Duration, now time.Time) time.Time {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	next := previous
+	if next.IsZero() {
+		next = now
+	}
+	for !next.After(now) {
+		next = next.Add(interval)
+	}
+	return next
+}
+
+// nextJournalCheck returns the next occurrence of the nightly balance check slot
+func nextJournalCheck(now time.Time) time.Time {
+	return nextDailySlot(now, journalCheckHourUTC)
+}
+
+// nextDunningRun returns the next occurrence of the daily reminder slot
+func nextDunningRun(now time.Time) time.Time {
+	return nextDailySlot(now, dunningHourUTC)
+}
+
+// nextRetainerRun returns the next occurrence of the daily retainer slot
+func nextRetainerRun(now time.Time) time.Time {
+	return nextDailySlot(now, retainerHourUTC)
+}
+
+// nextDailySlot returns the next time it is the given hour UTC
+func nextDailySlot(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// pruneJobRuns keeps the run history for a job bounded
+func (a *App) pruneJobRuns(jobID uint) {
+	var cutoff JobRun
+	err := a.DB.Where("scheduled_job_id = ?", jobID).Order("id desc").Offset(maxJobRunHistoryRows).First(&cutoff).Error
+	if err != nil {
+		return
+	}
+	a.DB.Unscoped().Where("scheduled_job_id = ? AND id <= ?", jobID, cutoff.ID).Delete(&JobRun{})
+}
+
+// AcquireSchedulerLock takes the scheduler leader lease for holder, or renews it if holder already
+// owns it. It returns false when another holder has an unexpired lease.
+func (a *App) AcquireSchedulerLock(holder string, now time.Time, ttl time.Duration) (bool, error) {
+	lock := SchedulerLock{Name: schedulerLockName, Holder: holder, ExpiresAt: now.Add(ttl)}
+	// The first instance to start creates the lease row; afterwards this is a no-op
+	if err := a.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock).Error; err != nil {
+		return false, fmt.Errorf("failed to create scheduler lock: %w", err)
+	}
+
+	result := a.DB.Model(&SchedulerLock{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", schedulerLockName, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": now.Add(ttl)})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to acquire scheduler lock: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseSchedulerLock expires the lease if holder owns it
+func (a *App) ReleaseSchedulerLock(holder string) error {
+	return a.DB.Model(&SchedulerLock{}).
+		Where("name = ? AND holder = ?", schedulerLockName, holder).
+		Update("expires_at", time.Time{}).Error
+}
+
+func runInvoiceRollover(a *App, tenantID uint, now time.Time) (string, error) {
+	created, err := a.RolloverDraftInvoices(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Created %d draft invoices", created), nil
+}
+
+func runRecurringPayroll(a *App, tenantID uint, now time.Time) (string, error) {
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, -1)
+	if err := a.GenerateRecurringEntriesForTenantPeriod(tenantID, periodStart, periodEnd); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Generated recurring entries for %s", periodStart.Format("Jan 2006")), nil
+}
+
+func runJournalBalanceCheck(a *App, tenantID uint, now time.Time) (string, error) {
+	if _, err := a.VerifyTenantJournalBalance(tenantID); err != nil {
+		return "", err
+	}
+	return "Journals are balanced", nil
+}
+
+func runDunningReminders(a *App, tenantID uint, now time.Time) (string, error) {
+	sent, err := a.RunDunning(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Sent %d payment reminders", sent), nil
+}
+
+func runRetainers(a *App, tenantID uint, now time.Time) (string, error) {
+	issued, err := a.IssueMonthlyRetainers(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	expired, err := a.ExpireRetainerBlocks(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Issued %d retainer invoices, expired %d retainer blocks", issued, expired), nil
+}
+
+func runTimerAutoStop(a *App, tenantID uint, now time.Time) (string, error) {
+	stopped, err := a.StopExpiredTimers(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Stopped %d timers", stopped), nil
+}
+
+func runLeaveAccrual(a *App, tenantID uint, now time.Time) (string, error) {
+	credited, err := a.AccrueLeave(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Accrued leave for %d balances", credited), nil
+}
+
+func runBudgetAlerts(a *App, tenantID uint, now time.Time) (string, error) {
+	sent, err := a.MonitorBudgets(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Sent %d budget alerts", sent), nil
+}
+
+func runBudgetChecks(a *App, tenantID uint, now time.Time) (string, error) {
+	sent, err := a.CheckDueBudgets(tenantID, now)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Sent %d budget alerts", sent), nil
+}
@@ -0,0 +1,306 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSchedulerLockElection tests that only one holder can own the scheduler lease at a time
+func TestSchedulerLockElection(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	leader, err := app.AcquireSchedulerLock("instance-a", now, time.Minute)
+	if err != nil || !leader {
+		t.Fatalf("Expected instance-a to acquire the lock, got leader=%v err=%v", leader, err)
+	}
+
+	leader, err = app.AcquireSchedulerLock("instance-b", now.Add(30*time.Second), time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if leader {
+		t.Errorf("Expected instance-b to be refused while instance-a holds the lease")
+	}
+
+	// instance-a can renew its own lease
+	leader, _ = app.AcquireSchedulerLock("instance-a", now.Add(45*time.Second), time.Minute)
+	if !leader {
+		t.Errorf("Expected instance-a to renew its lease")
+	}
+
+	// Once the lease expires another instance takes over
+	leader, _ = app.AcquireSchedulerLock("instance-b", now.Add(3*time.Minute), time.Minute)
+	if !leader {
+		t.Errorf("Expected instance-b to take over an expired lease")
+	}
+
+	// Releasing lets the other instance in immediately
+	if err := app.ReleaseSchedulerLock("instance-b"); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	leader, _ = app.AcquireSchedulerLock("instance-a", now.Add(3*time.Minute), time.Minute)
+	if !leader {
+		t.Errorf("Expected instance-a to acquire a released lease")
+	}
+}
+
+// TestSchedulerTickRunsDueJobs tests that a tick persists jobs per tenant, runs due jobs and records history
+func TestSchedulerTickRunsDueJobs(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "sched", Name: "Scheduler Tenant", Status: "active"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	calls := 0
+	pauseWhileRunning := false
+	s := app.NewScheduler()
+	s.jobs = map[string]JobDefinition{}
+	s.order = nil
+	s.Register(JobDefinition{
+		Name:        "test_job",
+		Description: "counts calls",
+		Interval:    time.Hour,
+		Run: func(a *App, tenantID uint, now time.Time) (string, error) {
+			calls++
+			if pauseWhileRunning {
+				a.DB.Model(&ScheduledJob{}).Where("tenant_id = ? AND name = ?", tenantID, "test_job").Update("paused", true)
+			}
+			if tenantID != tenant.ID {
+				t.Errorf("Expected tenant %d, got %d", tenant.ID, tenantID)
+			}
+			return "ok", nil
+		},
+	})
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	if err := s.Tick(now); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected job to run once, ran %d times", calls)
+	}
+
+	var job ScheduledJob
+	if err := db.Where("tenant_id = ? AND name = ?", tenant.ID, "test_job").First(&job).Error; err != nil {
+		t.Fatalf("Expected scheduled job row: %v", err)
+	}
+	if !job.NextRunAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected next run at %v, got %v", now.Add(time.Hour), job.NextRunAt)
+	}
+	if job.LastStatus != JobRunStatusSucceeded.String() {
+		t.Errorf("Expected last status succeeded, got %s", job.LastStatus)
+	}
+
+	// Not due yet, so a second tick doesn't run it
+	if err := s.Tick(now.Add(30 * time.Minute)); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected job not to run before it is due, ran %d times", calls)
+	}
+
+	// Paused jobs are skipped even when due
+	if _, err := s.SetJobPaused(tenant.ID, job.ID, true); err != nil {
+		t.Fatalf("Failed to pause job: %v", err)
+	}
+	if err := s.Tick(now.Add(2 * time.Hour)); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected paused job not to run, ran %d times", calls)
+	}
+
+	// Manual triggers run paused jobs and are recorded
+	run, err := s.TriggerJob(tenant.ID, job.ID)
+	if err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+	if calls != 2 || run.Trigger != JobTriggerManual.String() || run.Message != "ok" {
+		t.Errorf("Unexpected manual run: calls=%d run=%+v", calls, run)
+	}
+
+	var runCount int64
+	db.Model(&JobRun{}).Where("scheduled_job_id = ?", job.ID).Count(&runCount)
+	if runCount != 2 {
+		t.Errorf("Expected 2 job runs recorded, got %d", runCount)
+	}
+
+	// Pausing a job while it runs sticks once the run is recorded
+	s.SetJobPaused(tenant.ID, job.ID, false)
+	pauseWhileRunning = true
+	if err := s.Tick(now.Add(3 * time.Hour)); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	db.First(&job, job.ID)
+	if calls != 3 || !job.Paused || job.LastStatus != JobRunStatusSucceeded.String() {
+		t.Errorf("Expected the run recorded and the job left paused, got calls=%d %+v", calls, job)
+	}
+
+	// Jobs belong to their tenant
+	if _, err := s.TriggerJob(tenant.ID+1, job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound for another tenant, got %v", err)
+	}
+}
+
+// TestSchedulerRecordsFailures tests that job errors and panics are captured on the run
+func TestSchedulerRecordsFailures(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "sched-fail", Name: "Failing Tenant", Status: "active"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	s := app.NewScheduler()
+	s.jobs = map[string]JobDefinition{}
+	s.order = nil
+	s.Register(JobDefinition{
+		Name:     "panics",
+		Interval: time.Hour,
+		Run: func(a *App, tenantID uint, now time.Time) (string, error) {
+			panic("boom")
+		},
+	})
+
+	if err := s.Tick(time.Now().UTC()); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+
+	var run JobRun
+	if err := db.Where("tenant_id = ?", tenant.ID).First(&run).Error; err != nil {
+		t.Fatalf("Expected a job run: %v", err)
+	}
+	if run.Status != JobRunStatusFailed.String() || run.Error == "" {
+		t.Errorf("Expected a failed run with an error, got %+v", run)
+	}
+}
+
+// TestSchedulerRenewsLeaseDuringJobs tests that the leader keeps its lease while a job runs past the
+// point it would have expired, so another instance can't run the same jobs
+func TestSchedulerRenewsLeaseDuringJobs(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "sched-lease", Name: "Lease Tenant", Status: "active"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	s := app.NewScheduler()
+	s.holder = "instance-a"
+	s.renewEvery = 10 * time.Millisecond
+	s.jobs = map[string]JobDefinition{}
+	s.order = nil
+	var expiresAt time.Time
+	s.Register(JobDefinition{
+		Name:     "slow",
+		Interval: time.Hour,
+		Run: func(a *App, tenantID uint, now time.Time) (string, error) {
+			time.Sleep(50 * time.Millisecond)
+			var lock SchedulerLock
+			a.DB.Where("name = ?", schedulerLockName).First(&lock)
+			expiresAt = lock.ExpiresAt
+			return "ok", nil
+		},
+	})
+
+	// The tick's own clock is long past, so without renewal the lease would already have expired
+	if err := s.Tick(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if !expiresAt.After(time.Now()) {
+		t.Errorf("Expected the lease renewed while the job ran, it expires at %v", expiresAt)
+	}
+	if leader, _ := app.AcquireSchedulerLock("instance-b", time.Now().UTC(), time.Minute); leader {
+		t.Errorf("Expected instance-b to be refused the renewed lease")
+	}
+}
+
+// TestRolloverDraftInvoices tests that the rollover job creates a draft invoice for the current period once,
+// including mid-day on the last day of a weekly period
+func TestRolloverDraftInvoices(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "rollover", Name: "Rollover Tenant", Status: "active"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	account := Account{
+		TenantID:         tenant.ID,
+		Name:             "Rollover Account",
+		LegalName:        "Rollover Account LLC",
+		Type:             AccountTypeClient.String(),
+		BillingFrequency: BillingFrequencyMonthly.String(),
+	}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	now := time.Date(2025, 4, 15, 9, 0, 0, 0, time.UTC)
+	project := Project{
+		TenantID:    tenant.ID,
+		Name:        "Rollover Project",
+		AccountID:   account.ID,
+		ActiveStart: now.AddDate(0, -3, 0),
+		ActiveEnd:   now.AddDate(0, 3, 0),
+	}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	created, err := app.RolloverDraftInvoices(tenant.ID, now)
+	if err != nil {
+		t.Fatalf("Rollover failed: %v", err)
+	}
+	if created != 1 {
+		t.Fatalf("Expected 1 invoice created, got %d", created)
+	}
+
+	var invoice Invoice
+	if err := db.Where("account_id = ? AND project_id = ?", account.ID, project.ID).First(&invoice).Error; err != nil {
+		t.Fatalf("Expected draft invoice: %v", err)
+	}
+	if invoice.State != InvoiceStateDraft.String() || invoice.TenantID != tenant.ID {
+		t.Errorf("Unexpected invoice state %s / tenant %d", invoice.State, invoice.TenantID)
+	}
+	if invoice.PeriodStart.Month() != time.April || invoice.PeriodEnd.Month() != time.April {
+		t.Errorf("Expected April billing period, got %v - %v", invoice.PeriodStart, invoice.PeriodEnd)
+	}
+
+	// Running again in the same period is a no-op
+	created, _ = app.RolloverDraftInvoices(tenant.ID, now.AddDate(0, 0, 5))
+	if created != 0 {
+		t.Errorf("Expected no new invoices within the same period, got %d", created)
+	}
+
+	// The next month gets its own draft
+	created, _ = app.RolloverDraftInvoices(tenant.ID, now.AddDate(0, 1, 0))
+	if created != 1 {
+		t.Errorf("Expected a new invoice for the next period, got %d", created)
+	}
+
+	// Weekly periods end at midnight on Saturday, which still covers the rest of that day
+	weekly := Account{TenantID: tenant.ID, Name: "Weekly Account", Type: AccountTypeClient.String(),
+		BillingFrequency: BillingFrequencyWeekly.String(), ProjectsSingleInvoice: true}
+	db.Create(&weekly)
+	db.Create(&Project{TenantID: tenant.ID, Name: "Weekly Project", AccountID: weekly.ID,
+		ActiveStart: now.AddDate(0, -3, 0), ActiveEnd: now.AddDate(0, 3, 0)})
+	saturday := time.Date(2025, 4, 19, 10, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{saturday, saturday.Add(time.Hour)} {
+		app.RolloverDraftInvoices(tenant.ID, at)
+	}
+	var weeks []Invoice
+	db.Where("account_id = ?", weekly.ID).Find(&weeks)
+	if len(weeks) != 1 || weeks[0].PeriodStart.Format("2006-01-02") != "2025-04-13" {
+		t.Errorf("Expected a single draft for the week of April 13, got %d", len(weeks))
+	}
+}
@@ -0,0 +1,319 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSkill = errors.New("invalid skill")
+
+// Proficiency runs from learning the skill to being the person others go to for it
+const (
+	MinProficiency = 1
+	MaxProficiency = 5
+)
+
+// SkillLevel is a skill at a proficiency: what an employee has, or the least a project needs
+type SkillLevel struct {
+	SkillID     uint `json:"skill_id"`
+	Proficiency int  `json:"proficiency"`
+}
+
+// StaffingSearch describes the work to find people for: the skills it needs, its dates, and the
+// weekly hours it takes
+type StaffingSearch struct {
+	Skills []SkillLevel `json:"skills"`
+	Start  time.Time    `json:"start"`
+	End    time.Time    `json:"end"`
+	Hours  float64      `json:"hours"` // Weekly hours needed, 0 to rank by free capacity alone
+	Limit  int          `json:"limit"` // Most candidates to return, 0 for all
+}
+
+// SkillMatch is how an employee measures up against one required skill
+type SkillMatch struct {
+	SkillID     uint   `json:"skill_id"`
+	Name        string `json:"name"`
+	Required    int    `json:"required"`
+	Proficiency int    `json:"proficiency"` // 0 when the employee doesn't have the skill
+}
+
+// StaffingCandidate is an employee ranked for a staffing search. SkillMatch is the share of the
+// required proficiency the employee has, averaged over the skills; FreeHours come from the weekly
+// capacity left after leave, holidays and staffing commitments.
+type StaffingCandidate struct {
+	Employee         Employee     `json:"employee"`
+	SkillMatch       float64      `json:"skill_match"` // 0 to 1
+	Skills           []SkillMatch `json:"skills"`
+	AverageFreeHours float64      `json:"average_free_hours"`
+	MinFreeHours     float64      `json:"min_free_hours"` // The busiest week in the range
+	Score            float64      `json:"score"`          // 0 to 1, skills counting twice as much as capacity
+}
+
+// ValidateSkill checks a skill before it's saved
+func ValidateSkill(skill *Skill) error {
+	skill.Name = strings.TrimSpace(skill.Name)
+	if skill.Name == "" {
+		return fmt.Errorf("%w: a skill needs a name", ErrInvalidSkill)
+	}
+	return nil
+}
+
+// validateSkillLevels checks that every skill is the tenant's, listed once, at a proficiency in range
+func (a *App) validateSkillLevels(tenantID uint, levels []SkillLevel) error {
+	seen := make(map[uint]bool, len(levels))
+	ids := make([]uint, 0, len(levels))
+	for _, level := range levels {
+		if level.Proficiency < MinProficiency || level.Proficiency > MaxProficiency {
+			return fmt.Errorf("%w: proficiency must be from %d to %d", ErrInvalidSkill, MinProficiency, MaxProficiency)
+		}
+		if seen[level.SkillID] {
+			return fmt.Errorf("%w: skill %d is listed twice", ErrInvalidSkill, level.SkillID)
+		}
+		seen[level.SkillID] = true
+		ids = append(ids, level.SkillID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var found int64
+	a.DB.Model(&Skill{}).Scopes(TenantScope(tenantID)).Where("id IN ?", ids).Count(&found)
+	if int(found) != len(ids) {
+		return fmt.Errorf("%w: unknown skill", ErrInvalidSkill)
+	}
+	return nil
+}
+
+// EmployeeSkills returns an employee's skills, by name
+func (a *App) EmployeeSkills(tenantID, employeeID uint) ([]EmployeeSkill, error) {
+	var skills []EmployeeSkill
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Skill").Where("employee_id = ?", employeeID).
+		Find(&skills).Error; err != nil {
+		return nil, fmt.Errorf("failed to load employee skills: %w", err)
+	}
+	sort.Slice(skills, func(i, j int) bool { return skills[i].Skill.Name < skills[j].Skill.Name })
+	return skills, nil
+}
+
+// SetEmployeeSkills replaces an employee's skills
+func (a *App) SetEmployeeSkills(tenantID, employeeID uint, levels []SkillLevel) ([]EmployeeSkill, error) {
+	if err := a.validateSkillLevels(tenantID, levels); err != nil {
+		return nil, err
+	}
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("tenant_id = ? AND employee_id = ?", tenantID, employeeID).Delete(&EmployeeSkill{}).Error; err != nil {
+			return fmt.Errorf("failed to clear employee skills: %w", err)
+		}
+		for _, level := range levels {
+			skill := EmployeeSkill{TenantID: tenantID, EmployeeID: employeeID, SkillID: level.SkillID, Proficiency: level.Proficiency}
+			if err := tx.Omit("Skill").Create(&skill).Error; err != nil {
+				return fmt.Errorf("failed to save employee skill: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.EmployeeSkills(tenantID, employeeID)
+}
+
+// skillRequirements returns the skills a project or assignment needs, keyed by the column it's on
+func (a *App) skillRequirements(tenantID uint, column string, id uint) ([]SkillRequirement, error) {
+	var requirements []SkillRequirement
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Skill").Where(column+" = ?", id).
+		Find(&requirements).Error; err != nil {
+		return nil, fmt.Errorf("failed to load skill requirements: %w", err)
+	}
+	sort.Slice(requirements, func(i, j int) bool { return requirements[i].Skill.Name < requirements[j].Skill.Name })
+	return requirements, nil
+}
+
+// setSkillRequirements replaces the skills a project or assignment needs
+func (a *App) setSkillRequirements(tenantID uint, column string, id uint, levels []SkillLevel) ([]SkillRequirement, error) {
+	if err := a.validateSkillLevels(tenantID, levels); err != nil {
+		return nil, err
+	}
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("tenant_id = ? AND "+column+" = ?", tenantID, id).Delete(&SkillRequirement{}).Error; err != nil {
+			return fmt.Errorf("failed to clear skill requirements: %w", err)
+		}
+		for _, level := range levels {
+			requirement := SkillRequirement{TenantID: tenantID, SkillID: level.SkillID, MinProficiency: level.Proficiency}
+			if column == "project_id" {
+				requirement.ProjectID = &id
+			} else {
+				requirement.StaffingAssignmentID = &id
+			}
+			if err := tx.Omit("Skill").Create(&requirement).Error; err != nil {
+				return fmt.Errorf("failed to save skill requirement: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.skillRequirements(tenantID, column, id)
+}
+
+// ProjectSkills returns the skills a project needs
+func (a *App) ProjectSkills(tenantID, projectID uint) ([]SkillRequirement, error) {
+	return a.skillRequirements(tenantID, "project_id", projectID)
+}
+
+// SetProjectSkills replaces the skills a project needs
+func (a *App) SetProjectSkills(tenantID, projectID uint, levels []SkillLevel) ([]SkillRequirement, error) {
+	return a.setSkillRequirements(tenantID, "project_id", projectID, levels)
+}
+
+// AssignmentSkills returns the skills a staffing assignment needs beyond its project's
+func (a *App) AssignmentSkills(tenantID, assignmentID uint) ([]SkillRequirement, error) {
+	return a.skillRequirements(tenantID, "staffing_assignment_id", assignmentID)
+}
+
+// SetAssignmentSkills replaces the skills a staffing assignment needs beyond its project's
+func (a *App) SetAssignmentSkills(tenantID, assignmentID uint, levels []SkillLevel) ([]SkillRequirement, error) {
+	return a.setSkillRequirements(tenantID, "staffing_assignment_id", assignmentID, levels)
+}
+
+// AssignmentSearch builds the search for people to fill a staffing assignment: its project's skills
+// with the assignment's own on top, over the assignment's dates at its commitment
+func (a *App) AssignmentSearch(tenantID, assignmentID uint) (StaffingSearch, error) {
+	var assignment StaffingAssignment
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&assignment, assignmentID).Error; err != nil {
+		return StaffingSearch{}, fmt.Errorf("staffing assignment %d not found: %w", assignmentID, err)
+	}
+	projectSkills, err := a.ProjectSkills(tenantID, assignment.ProjectID)
+	if err != nil {
+		return StaffingSearch{}, err
+	}
+	assignmentSkills, err := a.AssignmentSkills(tenantID, assignment.ID)
+	if err != nil {
+		return StaffingSearch{}, err
+	}
+	levels := make(map[uint]int)
+	var order []uint
+	for _, requirement := range append(projectSkills, assignmentSkills...) {
+		if _, ok := levels[requirement.SkillID]; !ok {
+			order = append(order, requirement.SkillID)
+		}
+		levels[requirement.SkillID] = requirement.MinProficiency
+	}
+	search := StaffingSearch{Start: assignment.StartDate, End: assignment.EndDate, Hours: float64(assignment.Commitment)}
+	for _, skillID := range order {
+		search.Skills = append(search.Skills, SkillLevel{SkillID: skillID, Proficiency: levels[skillID]})
+	}
+	return search, nil
+}
+
+// SearchStaffing ranks the tenant's active employees for a piece of work by how well their skills
+// match and how much of their weekly capacity is free over its dates. Free capacity uses the same
+// commitments as the capacity view and forecast, less leave and holidays.
+func (a *App) SearchStaffing(tenantID uint, search StaffingSearch) ([]StaffingCandidate, error) {
+	if search.Start.IsZero() || search.End.Before(search.Start) {
+		return nil, fmt.Errorf("%w: the search needs a start on or before its end", ErrInvalidSkill)
+	}
+	for i := range search.Skills {
+		// A skill listed without a level only needs to be there at all
+		if search.Skills[i].Proficiency == 0 {
+			search.Skills[i].Proficiency = MinProficiency
+		}
+	}
+	if err := a.validateSkillLevels(tenantID, search.Skills); err != nil {
+		return nil, err
+	}
+	first, last := CapacityWeekStart(search.Start), leaveDay(search.End)
+
+	var employees []Employee
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("is_active = ?", true).Find(&employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to load employees: %w", err)
+	}
+	skillNames := make(map[uint]string)
+	var skills []Skill
+	a.DB.Scopes(TenantScope(tenantID)).Find(&skills)
+	for _, skill := range skills {
+		skillNames[skill.ID] = skill.Name
+	}
+	var employeeSkills []EmployeeSkill
+	if err := a.DB.Scopes(TenantScope(tenantID)).Find(&employeeSkills).Error; err != nil {
+		return nil, fmt.Errorf("failed to load employee skills: %w", err)
+	}
+	proficiency := make(map[uint]map[uint]int)
+	for _, skill := range employeeSkills {
+		if proficiency[skill.EmployeeID] == nil {
+			proficiency[skill.EmployeeID] = make(map[uint]int)
+		}
+		proficiency[skill.EmployeeID][skill.SkillID] = skill.Proficiency
+	}
+	var assignments []StaffingAssignment
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("start_date <= ? AND end_date >= ?", last.AddDate(0, 0, 1), first).
+		Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load staffing assignments: %w", err)
+	}
+	timeOff, err := a.TimeOffByDay(tenantID, first, last.AddDate(0, 0, 6))
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]StaffingCandidate, 0, len(employees))
+	for i := range employees {
+		employee := &employees[i]
+		candidate := StaffingCandidate{Employee: *employee, SkillMatch: 1, MinFreeHours: math.Inf(1)}
+		if len(search.Skills) > 0 {
+			candidate.SkillMatch = 0
+			for _, required := range search.Skills {
+				has := proficiency[employee.ID][required.SkillID]
+				candidate.Skills = append(candidate.Skills, SkillMatch{SkillID: required.SkillID, Name: skillNames[required.SkillID],
+					Required: required.Proficiency, Proficiency: has})
+				candidate.SkillMatch += math.Min(float64(has)/float64(required.Proficiency), 1) / float64(len(search.Skills))
+			}
+		}
+
+		weekly := DailyCapacity(employee) * 5
+		weeks := 0
+		for week := first; !week.After(last); week = week.AddDate(0, 0, 7) {
+			leave := TimeOffInWeek(timeOff[employee.ID], week)
+			free := weekly - leave
+			for j := range assignments {
+				if assignments[j].EmployeeID == employee.ID {
+					free -= AvailableCommitment(weekCommitment(&assignments[j], week), employee, leave)
+				}
+			}
+			free = max0(free)
+			candidate.AverageFreeHours += free
+			candidate.MinFreeHours = math.Min(candidate.MinFreeHours, free)
+			weeks++
+		}
+		candidate.AverageFreeHours /= float64(weeks)
+
+		capacityFit := 0.0
+		switch {
+		case search.Hours > 0:
+			capacityFit = math.Min(candidate.AverageFreeHours/search.Hours, 1)
+		case weekly > 0:
+			capacityFit = candidate.AverageFreeHours / weekly
+		}
+		candidate.Score = (2*candidate.SkillMatch + capacityFit) / 3
+		candidates = append(candidates, candidate)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].AverageFreeHours != candidates[j].AverageFreeHours {
+			return candidates[i].AverageFreeHours > candidates[j].AverageFreeHours
+		}
+		return candidates[i].Employee.ID < candidates[j].Employee.ID
+	})
+	if search.Limit > 0 && len(candidates) > search.Limit {
+		candidates = candidates[:search.Limit]
+	}
+	return candidates, nil
+}
@@ -0,0 +1,75 @@
+package cronos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSearchStaffing tests that skill levels are validated, that an assignment's search combines its
+// project's skills with its own, and that candidates are ranked by skill match and free capacity
+func TestSearchStaffing(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+	tenant := Tenant{Slug: "skills", Name: "Skills Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	dbt := Skill{TenantID: tenant.ID, Name: "dbt", Category: "Data Engineering"}
+	airflow := Skill{TenantID: tenant.ID, Name: "Airflow", Category: "Data Engineering"}
+	db.Create(&dbt)
+	db.Create(&airflow)
+	casey := Employee{TenantID: tenant.ID, FirstName: "Casey", IsActive: true, CapacityWeekly: 40}
+	devon := Employee{TenantID: tenant.ID, FirstName: "Devon", IsActive: true, CapacityWeekly: 40}
+	emery := Employee{TenantID: tenant.ID, FirstName: "Emery", IsActive: true, CapacityWeekly: 40}
+	db.Create(&casey)
+	db.Create(&devon)
+	db.Create(&emery)
+
+	if _, err := app.SetEmployeeSkills(tenant.ID, casey.ID, []SkillLevel{{SkillID: dbt.ID, Proficiency: 6}}); !errors.Is(err, ErrInvalidSkill) {
+		t.Errorf("Expected a proficiency of 6 to be refused, got %v", err)
+	}
+	app.SetEmployeeSkills(tenant.ID, casey.ID, []SkillLevel{{SkillID: dbt.ID, Proficiency: 5}, {SkillID: airflow.ID, Proficiency: 3}})
+	app.SetEmployeeSkills(tenant.ID, devon.ID, []SkillLevel{{SkillID: dbt.ID, Proficiency: 2}})
+	app.SetEmployeeSkills(tenant.ID, emery.ID, []SkillLevel{{SkillID: dbt.ID, Proficiency: 4}, {SkillID: airflow.ID, Proficiency: 4}})
+	if skills, _ := app.EmployeeSkills(tenant.ID, casey.ID); len(skills) != 2 || skills[0].Skill.Name != "Airflow" {
+		t.Errorf("Expected Casey's two skills by name, got %+v", skills)
+	}
+
+	account := Account{TenantID: tenant.ID, Name: "Skills Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Warehouse", AccountID: account.ID}
+	db.Create(&project)
+	start, end := time.Date(2025, 4, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 26, 0, 0, 0, 0, time.UTC)
+	// Emery is already fully booked for the period
+	db.Create(&StaffingAssignment{TenantID: tenant.ID, EmployeeID: emery.ID, ProjectID: project.ID, Commitment: 40, StartDate: start, EndDate: end})
+	opening := StaffingAssignment{TenantID: tenant.ID, ProjectID: project.ID, Commitment: 20, StartDate: start, EndDate: end}
+	db.Create(&opening)
+	app.SetProjectSkills(tenant.ID, project.ID, []SkillLevel{{SkillID: dbt.ID, Proficiency: 3}})
+	app.SetAssignmentSkills(tenant.ID, opening.ID, []SkillLevel{{SkillID: dbt.ID, Proficiency: 4}, {SkillID: airflow.ID, Proficiency: 3}})
+
+	search, err := app.AssignmentSearch(tenant.ID, opening.ID)
+	if err != nil {
+		t.Fatalf("Failed to build the assignment search: %v", err)
+	}
+	if len(search.Skills) != 2 || search.Skills[0] != (SkillLevel{SkillID: dbt.ID, Proficiency: 4}) || search.Hours != 20 {
+		t.Fatalf("Expected dbt at 4 and Airflow at 3 for 20 hours, got %+v", search)
+	}
+
+	candidates, err := app.SearchStaffing(tenant.ID, search)
+	if err != nil {
+		t.Fatalf("Failed to search staffing: %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("Expected all three employees ranked, got %d", len(candidates))
+	}
+	if candidates[0].Employee.ID != casey.ID || candidates[0].SkillMatch != 1 || candidates[0].AverageFreeHours != 40 || candidates[0].Score != 1 {
+		t.Errorf("Expected Casey first with a full match and a free week, got %+v", candidates[0])
+	}
+	if candidates[1].Employee.ID != emery.ID || candidates[1].MinFreeHours != 0 {
+		t.Errorf("Expected Emery second, matching but fully booked, got %+v", candidates[1])
+	}
+	if candidates[2].Employee.ID != devon.ID || candidates[2].SkillMatch != 0.25 || candidates[2].Skills[1].Proficiency != 0 {
+		t.Errorf("Expected Devon last with half of dbt and no Airflow, got %+v", candidates[2])
+	}
+}
@@ -0,0 +1,279 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+var ErrTaxProfileNotFound = errors.New("tax profile not found")
+
+// CalculateInvoiceTaxes works out the tax on every line item of an invoice. Each line uses the most
+// specific tax profile available: the billing code's for timesheet lines, the expense category's for
+// expenses, and otherwise the account's. Lines with no profile, or an exempt one, carry no tax.
+func (a *App) CalculateInvoiceTaxes(invoice *Invoice) error {
+	if err := a.DB.Where("invoice_id = ?", invoice.ID).Delete(&InvoiceLineItemTax{}).Error; err != nil {
+		return fmt.Errorf("failed to clear line item taxes: %w", err)
+	}
+
+	var account Account
+	if err := a.DB.First(&account, invoice.AccountID).Error; err != nil {
+		return fmt.Errorf("failed to load account %d: %w", invoice.AccountID, err)
+	}
+
+	var lineItems []InvoiceLineItem
+	if err := a.DB.Preload("BillingCode").Preload("Expense").Preload("Expense.Category").
+		Where("invoice_id = ?", invoice.ID).Find(&lineItems).Error; err != nil {
+		return fmt.Errorf("failed to load invoice line items: %w", err)
+	}
+
+	profiles := make(map[uint]*TaxProfile)
+	for _, lineItem := range lineItems {
+		profileID := account.TaxProfileID
+		if lineItem.BillingCode != nil && lineItem.BillingCode.TaxProfileID != nil {
+			profileID = lineItem.BillingCode.TaxProfileID
+		}
+		if lineItem.Expense != nil && lineItem.Expense.Category.TaxProfileID != nil {
+			profileID = lineItem.Expense.Category.TaxProfileID
+		}
+
+		var taxAmount int64
+		if profileID != nil {
+			profile, ok := profiles[*profileID]
+			if !ok {
+				profile = &TaxProfile{}
+				if err := a.DB.Preload("Rates", "active = ?", true).First(profile, *profileID).Error; err != nil {
+					return fmt.Errorf("%w: %d", ErrTaxProfileNotFound, *profileID)
+				}
+				profiles[*profileID] = profile
+			}
+
+			if !profile.Exempt {
+				for _, rate := range profile.Rates {
+					amount := int64(math.Round(float64(lineItem.Amount) * rate.Rate / 100))
+					if amount == 0 {
+						continue
+					}
+					tax := InvoiceLineItemTax{
+						TenantID:          invoice.TenantID,
+						InvoiceID:         invoice.ID,
+						InvoiceLineItemID: lineItem.ID,
+						TaxRateID:         rate.ID,
+						JurisdictionID:    rate.JurisdictionID,
+						Name:              rate.Name,
+						Rate:              rate.Rate,
+						TaxableAmount:     lineItem.Amount,
+						TaxAmount:         amount,
+					}
+					if err := a.DB.Create(&tax).Error; err != nil {
+						return fmt.Errorf("failed to save line item tax: %w", err)
+					}
+					taxAmount += amount
+				}
+			}
+		}
+
+		if err := a.DB.Model(&InvoiceLineItem{}).Where("id = ?", lineItem.ID).Updates(map[string]interface{}{
+			"tax_profile_id": profileID,
+			"tax_amount":     taxAmount,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update line item tax: %w", err)
+		}
+	}
+	return nil
+}
+
+// BookInvoiceTaxAccrual books the tax on an approved invoice as owed to each jurisdiction:
+// DR ACCRUED_RECEIVABLES, CR SALES_TAX_PAYABLE (subaccount is the jurisdiction code).
+// MoveInvoiceToAccountsReceivable carries the receivable side over to AR with the rest of the invoice.
+func (a *App) BookInvoiceTaxAccrual(invoice *Invoice, subAccount string) error {
+	var totals []struct {
+		JurisdictionID uint
+		Code           string
+		Amount         int64
+	}
+	if err := a.DB.Table("invoice_line_item_taxes").
+		Select("invoice_line_item_taxes.jurisdiction_id, tax_jurisdictions.code, SUM(invoice_line_item_taxes.tax_amount) AS amount").
+		Joins("LEFT JOIN tax_jurisdictions ON tax_jurisdictions.id = invoice_line_item_taxes.jurisdiction_id").
+		Where("invoice_line_item_taxes.invoice_id = ? AND invoice_line_item_taxes.deleted_at IS NULL", invoice.ID).
+		Group("invoice_line_item_taxes.jurisdiction_id, tax_jurisdictions.code").
+		Scan(&totals).Error; err != nil {
+		return fmt.Errorf("failed to total invoice taxes: %w", err)
+	}
+
+	lines := make([]Journal, 0, 2*len(totals))
+	for _, total := range totals {
+		if total.Amount == 0 {
+			continue
+		}
+		debit, credit := total.Amount, int64(0)
+		if total.Amount < 0 {
+			debit, credit = 0, -total.Amount
+		}
+
+		receivable := Journal{
+			Account:    AccountAccruedReceivables.String(),
+			SubAccount: subAccount,
+			InvoiceID:  &invoice.ID,
+			Memo:       fmt.Sprintf("Accrued %s tax for approved invoice #%d", total.Code, invoice.ID),
+			Debit:      debit,
+			Credit:     credit,
+		}
+		receivable.translate(invoice.Currency, invoice.ExchangeRate)
+
+		payable := Journal{
+			Account:    AccountSalesTaxPayable.String(),
+			SubAccount: total.Code,
+			InvoiceID:  &invoice.ID,
+			Memo:       fmt.Sprintf("%s tax collected on invoice #%d", total.Code, invoice.ID),
+			Debit:      credit,
+			Credit:     debit,
+		}
+		payable.translate(invoice.Currency, invoice.ExchangeRate)
+		lines = append(lines, receivable, payable)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if err := a.PostJournalTransaction(&JournalTransaction{
+		TenantID:      invoice.TenantID,
+		EffectiveDate: invoice.AcceptedAt,
+		SourceType:    JournalSourceInvoice.String(),
+		SourceID:      &invoice.ID,
+		Memo:          fmt.Sprintf("Tax accrual for invoice #%d", invoice.ID),
+		Lines:         lines,
+	}); err != nil {
+		return fmt.Errorf("failed to book tax accrual: %w", err)
+	}
+
+	log.Printf("Booked tax accrual for invoice ID %d across %d jurisdictions", invoice.ID, len(totals))
+	return nil
+}
+
+// TaxLiabilityLine is the tax charged for one rate in a jurisdiction over a period
+type TaxLiabilityLine struct {
+	JurisdictionID   uint    `json:"jurisdiction_id"`
+	JurisdictionCode string  `json:"jurisdiction_code"`
+	JurisdictionName string  `json:"jurisdiction_name"`
+	RateName         string  `json:"rate_name"`
+	Rate             float64 `json:"rate"`
+	TaxableAmount    int64   `json:"taxable_amount"` // in cents
+	TaxAmount        int64   `json:"tax_amount"`     // in cents
+	InvoiceCount     int     `json:"invoice_count"`
+}
+
+// TaxLiabilityReport summarizes the tax owed to each jurisdiction for invoices approved in a period
+type TaxLiabilityReport struct {
+	PeriodStart time.Time          `json:"period_start"`
+	PeriodEnd   time.Time          `json:"period_end"`
+	Lines       []TaxLiabilityLine `json:"lines"`
+	TotalTax    int64              `json:"total_tax"` // in cents
+}
+
+// GetTaxLiabilityReport totals tax by jurisdiction and rate for invoices approved between start and end
+// (inclusive of start, exclusive of end). Tax is owed from approval, matching when it is accrued; voided
+// and draft invoices are left out.
+func (a *App) GetTaxLiabilityReport(tenantID uint, start, end time.Time) (*TaxLiabilityReport, error) {
+	var rows []struct {
+		InvoiceID      uint
+		JurisdictionID uint
+		Code           string
+		Jurisdiction   string
+		Name           string
+		Rate           float64
+		TaxableAmount  int64
+		TaxAmount      int64
+	}
+	if err := a.DB.Table("invoice_line_item_taxes").
+		Select("invoice_line_item_taxes.invoice_id, invoice_line_item_taxes.jurisdiction_id, "+
+			"tax_jurisdictions.code, tax_jurisdictions.name AS jurisdiction, invoice_line_item_taxes.name, "+
+			"invoice_line_item_taxes.rate, invoice_line_item_taxes.taxable_amount, invoice_line_item_taxes.tax_amount").
+		Joins("JOIN invoices ON invoices.id = invoice_line_item_taxes.invoice_id").
+		Joins("LEFT JOIN tax_jurisdictions ON tax_jurisdictions.id = invoice_line_item_taxes.jurisdiction_id").
+		Where("invoices.tenant_id = ? AND invoices.deleted_at IS NULL AND invoice_line_item_taxes.deleted_at IS NULL", tenantID).
+		Where("invoices.state NOT IN ?", []string{InvoiceStateDraft.String(), InvoiceStateVoid.String()}).
+		Where("invoices.accepted_at >= ? AND invoices.accepted_at < ?", start, end).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load invoice taxes: %w", err)
+	}
+
+	type lineKey struct {
+		jurisdictionID uint
+		name           string
+		rate           float64
+	}
+	lines := make(map[lineKey]*TaxLiabilityLine)
+	invoices := make(map[lineKey]map[uint]bool)
+	report := &TaxLiabilityReport{PeriodStart: start, PeriodEnd: end}
+	for _, row := range rows {
+		key := lineKey{row.JurisdictionID, row.Name, row.Rate}
+		line, ok := lines[key]
+		if !ok {
+			line = &TaxLiabilityLine{
+				JurisdictionID:   row.JurisdictionID,
+				JurisdictionCode: row.Code,
+				JurisdictionName: row.Jurisdiction,
+				RateName:         row.Name,
+				Rate:             row.Rate,
+			}
+			lines[key] = line
+			invoices[key] = make(map[uint]bool)
+		}
+		line.TaxableAmount += row.TaxableAmount
+		line.TaxAmount += row.TaxAmount
+		invoices[key][row.InvoiceID] = true
+		report.TotalTax += row.TaxAmount
+	}
+
+	for key, line := range lines {
+		line.InvoiceCount = len(invoices[key])
+		report.Lines = append(report.Lines, *line)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		if report.Lines[i].JurisdictionCode != report.Lines[j].JurisdictionCode {
+			return report.Lines[i].JurisdictionCode < report.Lines[j].JurisdictionCode
+		}
+		return report.Lines[i].RateName < report.Lines[j].RateName
+	})
+	return report, nil
+}
+
+// Things a tax profile can be assigned to
+const TaxProfileTargetAccount = "account"
+const TaxProfileTargetBillingCode = "billing_code"
+const TaxProfileTargetExpenseCategory = "expense_category"
+
+// AssignTaxProfile sets (or with a nil profileID clears) the tax profile on an account, billing code or
+// expense category. Invoices pick up the change the next time their line items are generated.
+func (a *App) AssignTaxProfile(tenantID uint, target string, targetID uint, profileID *uint) error {
+	if profileID != nil {
+		var profile TaxProfile
+		if err := a.DB.Scopes(TenantScope(tenantID)).First(&profile, *profileID).Error; err != nil {
+			return ErrTaxProfileNotFound
+		}
+	}
+
+	var model interface{}
+	switch target {
+	case TaxProfileTargetAccount:
+		model = &Account{}
+	case TaxProfileTargetBillingCode:
+		model = &BillingCode{}
+	case TaxProfileTargetExpenseCategory:
+		model = &ExpenseCategory{}
+	default:
+		return fmt.Errorf("unknown tax profile target %q", target)
+	}
+
+	result := a.DB.Model(model).Scopes(TenantScope(tenantID)).Where("id = ?", targetID).Update("tax_profile_id", profileID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to assign tax profile: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%s %d not found", target, targetID)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package cronos
+
+import (
+	"testing"
+	"time"
+)
+
+// TestInvoiceTaxes tests per-line tax from account and billing code profiles, the tax accrual and the liability report
+func TestInvoiceTaxes(t *testing.T) {
+	db := setupTestDB(t)
+	app := &App{DB: db}
+
+	tenant := Tenant{Slug: "tax", Name: "Tax Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	ny := TaxJurisdiction{TenantID: tenant.ID, Name: "New York State", Code: "US-NY"}
+	nyc := TaxJurisdiction{TenantID: tenant.ID, Name: "New York City", Code: "US-NYC"}
+	db.Create(&ny)
+	db.Create(&nyc)
+	stateRate := TaxRate{TenantID: tenant.ID, JurisdictionID: ny.ID, Name: "NY State Sales Tax", Rate: 4}
+	cityRate := TaxRate{TenantID: tenant.ID, JurisdictionID: nyc.ID, Name: "NYC Sales Tax", Rate: 4.5}
+	db.Create(&stateRate)
+	db.Create(&cityRate)
+
+	taxable := TaxProfile{TenantID: tenant.ID, Name: "NYC", Rates: []TaxRate{stateRate, cityRate}}
+	exempt := TaxProfile{TenantID: tenant.ID, Name: "Exempt services", Exempt: true}
+	db.Create(&taxable)
+	db.Create(&exempt)
+
+	account := Account{TenantID: tenant.ID, Name: "Tax Account", LegalName: "Tax Account LLC", Type: AccountTypeClient.String(), TaxProfileID: &taxable.ID}
+	db.Create(&account)
+	if err := app.AssignTaxProfile(tenant.ID, TaxProfileTargetAccount, account.ID, &taxable.ID); err != nil {
+		t.Fatalf("Failed to assign tax profile: %v", err)
+	}
+	exemptCode := BillingCode{TenantID: tenant.ID, Name: "Training", Code: "TRAIN", TaxProfileID: &exempt.ID}
+	db.Create(&exemptCode)
+
+	approvedAt := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+	invoice := Invoice{TenantID: tenant.ID, AccountID: account.ID, State: InvoiceStateApproved.String(), AcceptedAt: approvedAt}
+	db.Create(&invoice)
+	consulting := InvoiceLineItem{TenantID: tenant.ID, InvoiceID: invoice.ID, Type: LineItemTypeAdjustment.String(), Description: "Consulting", Amount: 100000}
+	training := InvoiceLineItem{TenantID: tenant.ID, InvoiceID: invoice.ID, Type: LineItemTypeTimesheet.String(), Description: "Training", Amount: 50000, BillingCodeID: &exemptCode.ID}
+	db.Create(&consulting)
+	db.Create(&training)
+
+	if err := app.CalculateInvoiceTaxes(&invoice); err != nil {
+		t.Fatalf("Failed to calculate taxes: %v", err)
+	}
+	db.First(&consulting, consulting.ID)
+	db.First(&training, training.ID)
+	if consulting.TaxAmount != 8500 {
+		t.Errorf("Expected 8500 tax on the consulting line, got %d", consulting.TaxAmount)
+	}
+	if training.TaxAmount != 0 || training.TaxProfileID == nil || *training.TaxProfileID != exempt.ID {
+		t.Errorf("Expected the exempt billing code profile to win with no tax, got %d", training.TaxAmount)
+	}
+
+	// Recalculating replaces the previous breakdown rather than adding to it
+	if err := app.CalculateInvoiceTaxes(&invoice); err != nil {
+		t.Fatalf("Failed to recalculate taxes: %v", err)
+	}
+	var taxRows int64
+	db.Model(&InvoiceLineItemTax{}).Where("invoice_id = ?", invoice.ID).Count(&taxRows)
+	if taxRows != 2 {
+		t.Errorf("Expected 2 line item tax rows, got %d", taxRows)
+	}
+
+	app.UpdateInvoiceTotals(&invoice)
+	if invoice.TotalTax != 85 {
+		t.Errorf("Expected $85 total tax, got %v", invoice.TotalTax)
+	}
+
+	if err := app.BookInvoiceTaxAccrual(&invoice, "1:Tax Account"); err != nil {
+		t.Fatalf("Failed to book tax accrual: %v", err)
+	}
+	var payable int64
+	db.Table("journals").Select("COALESCE(SUM(credit - debit), 0)").
+		Where("account = ? AND sub_account = ?", AccountSalesTaxPayable.String(), "US-NYC").Scan(&payable)
+	if payable != 4500 {
+		t.Errorf("Expected 4500 owed to US-NYC, got %d", payable)
+	}
+	if _, err := app.VerifyTenantJournalBalance(tenant.ID); err != nil {
+		t.Errorf("Expected balanced journals: %v", err)
+	}
+
+	report, err := app.GetTaxLiabilityReport(tenant.ID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to build report: %v", err)
+	}
+	if report.TotalTax != 8500 || len(report.Lines) != 2 {
+		t.Fatalf("Expected 8500 tax over 2 lines, got %d over %d", report.TotalTax, len(report.Lines))
+	}
+	if report.Lines[0].JurisdictionCode != "US-NY" || report.Lines[0].TaxAmount != 4000 || report.Lines[0].TaxableAmount != 100000 {
+		t.Errorf("Unexpected US-NY line: %+v", report.Lines[0])
+	}
+
+	// Outside the period nothing is owed
+	report, _ = app.GetTaxLiabilityReport(tenant.ID, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC))
+	if report.TotalTax != 0 {
+		t.Errorf("Expected no tax in Q2, got %d", report.TotalTax)
+	}
+}
@@ -0,0 +1,236 @@
+package cronos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrTimerRunning = errors.New("a timer is already running")
+var ErrNoTimer = errors.New("no timer is running")
+
+const defaultTimerMaxHours = 12
+
+// TimerMaxHours reads the "timer_max_hours" tenant setting, how long a timer can run before it's
+// stopped automatically, defaulting to 12 hours
+func TimerMaxHours(tenant *Tenant) float64 {
+	var settings struct {
+		TimerMaxHours float64 `json:"timer_max_hours"`
+	}
+	if len(tenant.Settings) > 0 {
+		if err := json.Unmarshal(tenant.Settings, &settings); err != nil {
+			log.Printf("Warning: invalid settings for tenant %d, using the default timer limit: %v", tenant.ID, err)
+		}
+	}
+	if settings.TimerMaxHours <= 0 {
+		return defaultTimerMaxHours
+	}
+	return settings.TimerMaxHours
+}
+
+// GetTimer returns the employee's running timer, or ErrNoTimer
+func (a *App) GetTimer(tenantID, employeeID uint) (*Timer, error) {
+	var timer Timer
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("BillingCode").
+		Where("employee_id = ?", employeeID).Limit(1).Find(&timer).Error; err != nil {
+		return nil, fmt.Errorf("failed to load timer: %w", err)
+	}
+	if timer.ID == 0 {
+		return nil, ErrNoTimer
+	}
+	return &timer, nil
+}
+
+// timerBillingCode loads a billing code a timer can run against
+func (a *App) timerBillingCode(tenantID, billingCodeID uint) (BillingCode, error) {
+	var billingCode BillingCode
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&billingCode, billingCodeID).Error; err != nil {
+		return billingCode, fmt.Errorf("billing code %d not found: %w", billingCodeID, err)
+	}
+	return billingCode, nil
+}
+
+// StartTimer starts a timer for the employee against a billing code. It returns ErrTimerRunning when
+// the employee already has one.
+func (a *App) StartTimer(tenantID, employeeID, billingCodeID uint, notes string, isMeeting bool, now time.Time) (*Timer, error) {
+	billingCode, err := a.timerBillingCode(tenantID, billingCodeID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.GetTimer(tenantID, employeeID); !errors.Is(err, ErrNoTimer) {
+		if err == nil {
+			return nil, ErrTimerRunning
+		}
+		return nil, err
+	}
+	timer := Timer{TenantID: tenantID, EmployeeID: employeeID, BillingCodeID: billingCode.ID, Notes: notes,
+		IsMeeting: isMeeting, StartedAt: now.UTC().Truncate(time.Second)}
+	// The unique index catches a timer started by a concurrent request
+	if err := a.DB.Omit("BillingCode", "Employee").Create(&timer).Error; err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrTimerRunning
+		}
+		return nil, fmt.Errorf("failed to start timer: %w", err)
+	}
+	timer.BillingCode = billingCode
+	return &timer, nil
+}
+
+// isUniqueViolation reports whether a write failed on a unique index, on postgres or sqlite
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "duplicate key value") || strings.Contains(message, "UNIQUE constraint failed")
+}
+
+// UpdateTimer changes the notes or billing code of the employee's running timer. Nil leaves a field
+// as it is.
+func (a *App) UpdateTimer(tenantID, employeeID uint, billingCodeID *uint, notes *string) (*Timer, error) {
+	timer, err := a.GetTimer(tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if billingCodeID != nil && *billingCodeID != timer.BillingCodeID {
+		billingCode, err := a.timerBillingCode(tenantID, *billingCodeID)
+		if err != nil {
+			return nil, err
+		}
+		timer.BillingCodeID = billingCode.ID
+		timer.BillingCode = billingCode
+	}
+	if notes != nil {
+		timer.Notes = *notes
+	}
+	if err := a.DB.Omit("BillingCode", "Employee").Save(timer).Error; err != nil {
+		return nil, fmt.Errorf("failed to update timer: %w", err)
+	}
+	return timer, nil
+}
+
+// timerEntry is the entry a timer records when it stops at end
+func (timer *Timer) timerEntry(end time.Time) Entry {
+	return Entry{
+		TenantID:      timer.TenantID,
+		ProjectID:     timer.BillingCode.ProjectID,
+		BillingCodeID: timer.BillingCodeID,
+		EmployeeID:    timer.EmployeeID,
+		Start:         timer.StartedAt,
+		End:           end.UTC().Truncate(time.Second),
+		Notes:         timer.Notes,
+		IsMeeting:     timer.IsMeeting,
+		State:         EntryStateUnaffiliated.String(),
+	}
+}
+
+// recordTimer creates the timer's entry and removes the timer, then puts the entry on its project's
+// draft invoice the same way a manually created entry is
+func (a *App) recordTimer(timer *Timer, entry *Entry) error {
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to create entry: %w", err)
+		}
+		if err := tx.Unscoped().Delete(&Timer{}, timer.ID).Error; err != nil {
+			return fmt.Errorf("failed to remove timer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := a.AssociateEntry(entry, entry.ProjectID); err != nil {
+		log.Printf("Entry %d from timer %d was left unaffiliated: %v", entry.ID, timer.ID, err)
+	}
+	return nil
+}
+
+// StopTimer stops the employee's timer and records the time as an entry. The entry is checked like
+// a manual one: when its week is submitted or an entry rule blocks it, the timer keeps running so the
+// employee can fix it, and the validation is returned with the error.
+func (a *App) StopTimer(tenantID, employeeID uint, now time.Time) (*Entry, EntryValidation, error) {
+	timer, err := a.GetTimer(tenantID, employeeID)
+	if err != nil {
+		return nil, EntryValidation{}, err
+	}
+	entry := timer.timerEntry(now)
+	if err := a.CheckTimesheetOpen(tenantID, employeeID, entry.Start); err != nil {
+		return nil, EntryValidation{}, err
+	}
+	validation, err := a.ValidateEntry(&entry, nil, now)
+	if err != nil {
+		return nil, validation, err
+	}
+	if err := validation.Err(); err != nil {
+		return nil, validation, err
+	}
+	if err := a.recordTimer(timer, &entry); err != nil {
+		return nil, validation, err
+	}
+	return &entry, validation, nil
+}
+
+// DiscardTimer removes the employee's running timer without recording anything
+func (a *App) DiscardTimer(tenantID, employeeID uint) error {
+	result := a.DB.Scopes(TenantScope(tenantID)).Unscoped().Where("employee_id = ?", employeeID).Delete(&Timer{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to discard timer: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoTimer
+	}
+	return nil
+}
+
+// StopExpiredTimers stops the tenant's timers that have run longer than its timer limit, recording
+// entries that end at the limit, and emails each employee to check the entry. Entry rules aren't
+// applied since nobody is there to fix the entry, but a timer that started in a submitted week is
+// discarded instead. It returns how many timers were stopped.
+func (a *App) StopExpiredTimers(tenantID uint, now time.Time) (int, error) {
+	var tenant Tenant
+	if err := a.DB.First(&tenant, tenantID).Error; err != nil {
+		return 0, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	maxHours := TimerMaxHours(&tenant)
+	limit := time.Duration(maxHours * float64(time.Hour))
+	var timers []Timer
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("BillingCode").Preload("Employee.User").
+		Where("started_at < ?", now.Add(-limit)).Find(&timers).Error; err != nil {
+		return 0, fmt.Errorf("failed to load expired timers: %w", err)
+	}
+
+	stopped := 0
+	for i := range timers {
+		timer := &timers[i]
+		entry := timer.timerEntry(timer.StartedAt.Add(limit))
+		var body string
+		if err := a.CheckTimesheetOpen(tenantID, timer.EmployeeID, entry.Start); err != nil {
+			if err := a.DB.Unscoped().Delete(&Timer{}, timer.ID).Error; err != nil {
+				return stopped, fmt.Errorf("failed to remove timer %d: %w", timer.ID, err)
+			}
+			body = fmt.Sprintf("<p>Your timer on %s started %s ran past the %g hour limit and was stopped. Its week's timesheet has been submitted, so no entry was recorded.</p>",
+				html.EscapeString(timer.BillingCode.Code), timer.StartedAt.Format("Jan 2 15:04 MST"), maxHours)
+		} else {
+			if err := a.recordTimer(timer, &entry); err != nil {
+				return stopped, fmt.Errorf("failed to stop timer %d: %w", timer.ID, err)
+			}
+			body = fmt.Sprintf("<p>Your timer on %s started %s ran past the %g hour limit and was stopped, recording an entry that ends at the limit.</p><p>Please correct the entry's end time if you stopped working earlier.</p>",
+				html.EscapeString(timer.BillingCode.Code), timer.StartedAt.Format("Jan 2 15:04 MST"), maxHours)
+		}
+		stopped++
+		log.Printf("Auto-stopped timer %d for employee %d after %g hours", timer.ID, timer.EmployeeID, maxHours)
+
+		if email := timer.Employee.User.Email; email != "" {
+			if err := a.sendStaffNotice(email, "Your timer was stopped", body); err != nil {
+				log.Printf("Failed to notify employee %d of auto-stopped timer %d: %v", timer.EmployeeID, timer.ID, err)
+			}
+		}
+	}
+	return stopped, nil
+}
@@ -0,0 +1,96 @@
+package cronos
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimers tests that an employee runs one timer at a time, that stopping it records an entry with
+// the notes edited while it ran, and that timers left running past the limit are stopped with a notice
+func TestTimers(t *testing.T) {
+	db := setupTestDB(t)
+	var notices []string
+	app := &App{DB: db, sendNotice: func(to, subject, body string) error {
+		notices = append(notices, to+": "+body)
+		return nil
+	}}
+	tenant := Tenant{Slug: "timers", Name: "Timer Tenant", Settings: []byte(`{"timer_max_hours":10}`)}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	user := User{TenantID: tenant.ID, Email: "alex@example.com", Role: UserRoleStaff.String()}
+	db.Create(&user)
+	employee := Employee{TenantID: tenant.ID, FirstName: "Alex", UserID: user.ID}
+	db.Create(&employee)
+	account := Account{TenantID: tenant.ID, Name: "Timer Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	project := Project{TenantID: tenant.ID, Name: "Timer Project", AccountID: account.ID}
+	db.Create(&project)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 120}
+	db.Create(&rate)
+	code := BillingCode{TenantID: tenant.ID, Name: "Build", Code: "BLD", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15}
+	other := BillingCode{TenantID: tenant.ID, Name: "Review", Code: "REV", ProjectID: project.ID, RateID: rate.ID, RoundedTo: 15}
+	db.Create(&code)
+	db.Create(&other)
+
+	start := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	if _, err := app.StartTimer(tenant.ID, employee.ID, code.ID, "Pipeline", false, start); err != nil {
+		t.Fatalf("Failed to start timer: %v", err)
+	}
+	if _, err := app.StartTimer(tenant.ID, employee.ID, other.ID, "", false, start.Add(time.Minute)); !errors.Is(err, ErrTimerRunning) {
+		t.Errorf("Expected a second timer to be refused, got %v", err)
+	}
+	notes := "Pipeline review"
+	if _, err := app.UpdateTimer(tenant.ID, employee.ID, &other.ID, &notes); err != nil {
+		t.Fatalf("Failed to update timer: %v", err)
+	}
+
+	entry, _, err := app.StopTimer(tenant.ID, employee.ID, start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to stop timer: %v", err)
+	}
+	if entry.BillingCodeID != other.ID || entry.Notes != notes || entry.DurationMinutes != 90 || entry.Fee != 18000 {
+		t.Errorf("Expected 90 minutes on REV with the edited notes for 180.00, got %+v", entry)
+	}
+	if _, err := app.GetTimer(tenant.ID, employee.ID); !errors.Is(err, ErrNoTimer) {
+		t.Errorf("Expected the timer to be removed once stopped, got %v", err)
+	}
+	if _, _, err := app.StopTimer(tenant.ID, employee.ID, start.Add(2*time.Hour)); !errors.Is(err, ErrNoTimer) {
+		t.Errorf("Expected stopping with no timer to fail, got %v", err)
+	}
+
+	// A timer still running after 10 hours is stopped at the limit and the employee is told
+	late := start.AddDate(0, 0, 1)
+	if _, err := app.StartTimer(tenant.ID, employee.ID, code.ID, "Forgot to stop", false, late); err != nil {
+		t.Fatalf("Failed to restart timer: %v", err)
+	}
+	if stopped, _ := app.StopExpiredTimers(tenant.ID, late.Add(9*time.Hour)); stopped != 0 {
+		t.Errorf("Expected a 9 hour timer to keep running, got %d stopped", stopped)
+	}
+	if stopped, err := app.StopExpiredTimers(tenant.ID, late.Add(15*time.Hour)); err != nil || stopped != 1 {
+		t.Fatalf("Expected the timer to be auto-stopped, got %d: %v", stopped, err)
+	}
+	var recorded Entry
+	db.Where("employee_id = ? AND notes = ?", employee.ID, "Forgot to stop").First(&recorded)
+	if !recorded.End.Equal(late.Add(10 * time.Hour)) {
+		t.Errorf("Expected the entry to end at the 10 hour limit, got %s", recorded.End)
+	}
+	if len(notices) != 1 || !strings.HasPrefix(notices[0], "alex@example.com") || !strings.Contains(notices[0], "10 hour limit") {
+		t.Errorf("Expected the employee to be notified of the auto-stop, got %v", notices)
+	}
+	if _, err := app.GetTimer(tenant.ID, employee.ID); !errors.Is(err, ErrNoTimer) {
+		t.Errorf("Expected the auto-stopped timer to be removed, got %v", err)
+	}
+
+	// Only a clash on the unique index means a timer is already running
+	duplicate := Timer{TenantID: tenant.ID, EmployeeID: employee.ID, StartedAt: late}
+	db.Create(&Timer{TenantID: tenant.ID, EmployeeID: employee.ID, StartedAt: late})
+	if err := db.Create(&duplicate).Error; !isUniqueViolation(err) {
+		t.Errorf("Expected a second timer for the employee to break the unique index, got %v", err)
+	}
+	if isUniqueViolation(errors.New("connection refused")) {
+		t.Error("Expected other errors not to be reported as a running timer")
+	}
+}
@@ -0,0 +1,259 @@
+package cronos
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"time"
+)
+
+var ErrTimesheetLocked = errors.New("the week's timesheet has been submitted and its entries are locked")
+var ErrInvalidTimesheetTransition = errors.New("invalid timesheet state change")
+var ErrNotTimesheetApprover = errors.New("not an approver for this timesheet")
+
+// TimesheetWeek is a timesheet with the entries it covers
+type TimesheetWeek struct {
+	Timesheet Timesheet `json:"timesheet"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// WeekStart returns the Monday, in UTC, of the week containing t
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// GetTimesheet returns an employee's timesheet for the week containing the given day, opening it if
+// it doesn't exist yet
+func (a *App) GetTimesheet(tenantID, employeeID uint, day time.Time) (*Timesheet, error) {
+	timesheet := Timesheet{TenantID: tenantID, EmployeeID: employeeID, WeekStart: WeekStart(day)}
+	if err := a.DB.Where("tenant_id = ? AND employee_id = ? AND week_start = ?", tenantID, employeeID, timesheet.WeekStart).
+		Attrs(Timesheet{State: TimesheetStateOpen.String()}).FirstOrCreate(&timesheet).Error; err != nil {
+		return nil, fmt.Errorf("failed to load timesheet: %w", err)
+	}
+	return &timesheet, nil
+}
+
+// TimesheetEntries returns the employee's entries that started in the timesheet's week
+func (a *App) TimesheetEntries(timesheet *Timesheet) ([]Entry, error) {
+	var entries []Entry
+	if err := a.DB.Scopes(TenantScope(timesheet.TenantID)).Preload("BillingCode").Preload("Project").
+		Where("employee_id = ? AND start >= ? AND start < ? AND state != ?",
+			timesheet.EmployeeID, timesheet.WeekStart, timesheet.WeekStart.AddDate(0, 0, 7), EntryStateVoid.String()).
+		Order("start").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load timesheet entries: %w", err)
+	}
+	return entries, nil
+}
+
+// CheckTimesheetOpen returns ErrTimesheetLocked when the employee has submitted the week containing
+// start, so its entries can't be added, changed or removed until it's returned
+func (a *App) CheckTimesheetOpen(tenantID, employeeID uint, start time.Time) error {
+	var timesheet Timesheet
+	a.DB.Where("tenant_id = ? AND employee_id = ? AND week_start = ?", tenantID, employeeID, WeekStart(start)).
+		Limit(1).Find(&timesheet)
+	if timesheet.State == TimesheetStateSubmitted.String() || timesheet.State == TimesheetStateApproved.String() {
+		return ErrTimesheetLocked
+	}
+	return nil
+}
+
+// timesheetApprover routes a timesheet to the lead of the project the employee logged the most hours
+// on that week, or to the employee's manager when that project has no lead. It returns nil when
+// neither is set, or only the employee themselves is, leaving approval to an admin.
+func (a *App) timesheetApprover(timesheet *Timesheet, entries []Entry) *uint {
+	hours := make(map[uint]float64)
+	for _, entry := range entries {
+		hours[entry.ProjectID] += entry.Duration().Hours()
+	}
+	var busiest uint
+	for projectID, h := range hours {
+		if busiest == 0 || h > hours[busiest] || (h == hours[busiest] && projectID < busiest) {
+			busiest = projectID
+		}
+	}
+	if busiest != 0 {
+		var project Project
+		a.DB.Select("lead_id").Where("id = ?", busiest).Limit(1).Find(&project)
+		if project.LeadID != nil && *project.LeadID != timesheet.EmployeeID {
+			return project.LeadID
+		}
+	}
+	var employee Employee
+	a.DB.Select("manager_id").Where("id = ?", timesheet.EmployeeID).Limit(1).Find(&employee)
+	if employee.ManagerID != nil && *employee.ManagerID != timesheet.EmployeeID {
+		return employee.ManagerID
+	}
+	return nil
+}
+
+// CanApproveTimesheet reports whether an employee may approve or return a timesheet: its routed
+// approver, the employee's manager, or the lead of a project in the week. Admins can approve any
+// timesheet; no one else can approve their own.
+func (a *App) CanApproveTimesheet(timesheet *Timesheet, approverID uint, isAdmin bool) bool {
+	if isAdmin {
+		return true
+	}
+	if approverID == 0 || approverID == timesheet.EmployeeID {
+		return false
+	}
+	if timesheet.ApproverID != nil && *timesheet.ApproverID == approverID {
+		return true
+	}
+	var employee Employee
+	a.DB.Select("manager_id").Where("id = ?", timesheet.EmployeeID).Limit(1).Find(&employee)
+	if employee.ManagerID != nil && *employee.ManagerID == approverID {
+		return true
+	}
+	var led int64
+	a.DB.Model(&Entry{}).
+		Where("employee_id = ? AND start >= ? AND start < ? AND state != ?",
+			timesheet.EmployeeID, timesheet.WeekStart, timesheet.WeekStart.AddDate(0, 0, 7), EntryStateVoid.String()).
+		Where("project_id IN (?)", a.DB.Model(&Project{}).Select("id").Where("lead_id = ?", approverID)).
+		Count(&led)
+	return led > 0
+}
+
+// SubmitTimesheet submits an employee's week for approval, locking its entries. Returned timesheets
+// can be submitted again once they've been fixed.
+func (a *App) SubmitTimesheet(tenantID, employeeID uint, day time.Time) (*Timesheet, error) {
+	timesheet, err := a.GetTimesheet(tenantID, employeeID, day)
+	if err != nil {
+		return nil, err
+	}
+	if timesheet.State != TimesheetStateOpen.String() && timesheet.State != TimesheetStateReturned.String() {
+		return nil, fmt.Errorf("%w: a %s timesheet can't be submitted", ErrInvalidTimesheetTransition, timesheet.State)
+	}
+	entries, err := a.TimesheetEntries(timesheet)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	timesheet.TotalHours = 0
+	for _, entry := range entries {
+		timesheet.TotalHours += entry.Duration().Hours()
+	}
+	timesheet.State = TimesheetStateSubmitted.String()
+	timesheet.SubmittedAt = &now
+	timesheet.ApproverID = a.timesheetApprover(timesheet, entries)
+	if err := a.DB.Omit("Employee", "Approver").Save(timesheet).Error; err != nil {
+		return nil, fmt.Errorf("failed to submit timesheet: %w", err)
+	}
+	log.Printf("Employee %d submitted timesheet for week of %s with %.2f hours", employeeID,
+		timesheet.WeekStart.Format("2006-01-02"), timesheet.TotalHours)
+	return timesheet, nil
+}
+
+// reviewableTimesheet loads a submitted timesheet the approver is allowed to act on
+func (a *App) reviewableTimesheet(tenantID, timesheetID, approverID uint, isAdmin bool) (*Timesheet, error) {
+	var timesheet Timesheet
+	if err := a.DB.Scopes(TenantScope(tenantID)).First(&timesheet, timesheetID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load timesheet %d: %w", timesheetID, err)
+	}
+	if timesheet.State != TimesheetStateSubmitted.String() {
+		return nil, fmt.Errorf("%w: only submitted timesheets can be reviewed, this one is %s", ErrInvalidTimesheetTransition, timesheet.State)
+	}
+	if !a.CanApproveTimesheet(&timesheet, approverID, isAdmin) {
+		return nil, ErrNotTimesheetApprover
+	}
+	return &timesheet, nil
+}
+
+// ApproveTimesheet approves a submitted week. Its draft and unaffiliated entries are approved through
+// ApproveEntries, which accrues their payroll.
+func (a *App) ApproveTimesheet(tenantID, timesheetID, approverID uint, isAdmin bool) (*Timesheet, error) {
+	timesheet, err := a.reviewableTimesheet(tenantID, timesheetID, approverID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := a.TimesheetEntries(timesheet)
+	if err != nil {
+		return nil, err
+	}
+	var entryIDs []uint
+	for _, entry := range entries {
+		if entry.State == EntryStateDraft.String() || entry.State == EntryStateUnaffiliated.String() {
+			entryIDs = append(entryIDs, entry.ID)
+		}
+	}
+	if len(entryIDs) > 0 {
+		if err := a.ApproveEntries(entryIDs); err != nil {
+			return nil, fmt.Errorf("failed to approve timesheet entries: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	timesheet.State = TimesheetStateApproved.String()
+	timesheet.ReviewedAt = &now
+	timesheet.ReviewedByID = &approverID
+	timesheet.ReturnComment = ""
+	if err := a.DB.Omit("Employee", "Approver").Save(timesheet).Error; err != nil {
+		return nil, fmt.Errorf("failed to approve timesheet: %w", err)
+	}
+	log.Printf("Timesheet %d approved by employee %d, %d entries approved", timesheet.ID, approverID, len(entryIDs))
+	return timesheet, nil
+}
+
+// ReturnTimesheet sends a submitted week back to the employee with a comment, unlocking its entries,
+// and emails them the comment
+func (a *App) ReturnTimesheet(tenantID, timesheetID, approverID uint, isAdmin bool, comment string) (*Timesheet, error) {
+	if comment == "" {
+		return nil, fmt.Errorf("%w: a comment is required to return a timesheet", ErrInvalidTimesheetTransition)
+	}
+	timesheet, err := a.reviewableTimesheet(tenantID, timesheetID, approverID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	timesheet.State = TimesheetStateReturned.String()
+	timesheet.ReviewedAt = &now
+	timesheet.ReviewedByID = &approverID
+	timesheet.ReturnComment = comment
+	if err := a.DB.Omit("Employee", "Approver").Save(timesheet).Error; err != nil {
+		return nil, fmt.Errorf("failed to return timesheet: %w", err)
+	}
+
+	var employee Employee
+	a.DB.Preload("User").Limit(1).Find(&employee, timesheet.EmployeeID)
+	if employee.User.Email != "" {
+		week := timesheet.WeekStart.Format("January 2, 2006")
+		body := fmt.Sprintf("<p>Your timesheet for the week of %s was returned:</p><blockquote>%s</blockquote><p>Please update your entries and submit it again.</p>",
+			week, html.EscapeString(comment))
+		if err := a.sendStaffNotice(employee.User.Email, "Timesheet returned: week of "+week, body); err != nil {
+			log.Printf("Failed to notify employee %d of returned timesheet %d: %v", employee.ID, timesheet.ID, err)
+		}
+	}
+	return timesheet, nil
+}
+
+// ListEmployeeTimesheets returns an employee's timesheets, most recent week first
+func (a *App) ListEmployeeTimesheets(tenantID, employeeID uint) ([]Timesheet, error) {
+	var timesheets []Timesheet
+	if err := a.DB.Scopes(TenantScope(tenantID)).Where("employee_id = ?", employeeID).
+		Order("week_start desc").Find(&timesheets).Error; err != nil {
+		return nil, fmt.Errorf("failed to load timesheets: %w", err)
+	}
+	return timesheets, nil
+}
+
+// ListTimesheetsForApproval returns the submitted timesheets an employee can approve, oldest first
+func (a *App) ListTimesheetsForApproval(tenantID, approverID uint, isAdmin bool) ([]Timesheet, error) {
+	var submitted []Timesheet
+	if err := a.DB.Scopes(TenantScope(tenantID)).Preload("Employee").
+		Where("state = ?", TimesheetStateSubmitted.String()).
+		Order("week_start, employee_id").Find(&submitted).Error; err != nil {
+		return nil, fmt.Errorf("failed to load timesheets: %w", err)
+	}
+	timesheets := make([]Timesheet, 0, len(submitted))
+	for i := range submitted {
+		if a.CanApproveTimesheet(&submitted[i], approverID, isAdmin) {
+			timesheets = append(timesheets, submitted[i])
+		}
+	}
+	return timesheets, nil
+}
@@ -0,0 +1,113 @@
+package cronos
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimesheetApprovalWorkflow tests that a submitted week is routed to the project lead and locked,
+// that a returned week reaches the employee with its comment, and that approval approves the entries
+func TestTimesheetApprovalWorkflow(t *testing.T) {
+	db := setupTestDB(t)
+	var notices []string
+	app := &App{DB: db, sendNotice: func(to, subject, body string) error {
+		notices = append(notices, to+": "+body)
+		return nil
+	}}
+	tenant := Tenant{Slug: "timesheets", Name: "Timesheet Tenant"}
+	if err := db.Create(&tenant).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	user := User{TenantID: tenant.ID, Email: "casey@example.com", Role: UserRoleStaff.String()}
+	db.Create(&user)
+	manager := Employee{TenantID: tenant.ID, FirstName: "Morgan"}
+	lead := Employee{TenantID: tenant.ID, FirstName: "Lee"}
+	outsider := Employee{TenantID: tenant.ID, FirstName: "Otto"}
+	for _, e := range []*Employee{&manager, &lead, &outsider} {
+		db.Create(e)
+	}
+	employee := Employee{TenantID: tenant.ID, FirstName: "Casey", UserID: user.ID, ManagerID: &manager.ID}
+	db.Create(&employee)
+
+	account := Account{TenantID: tenant.ID, Name: "Timesheet Client", Type: AccountTypeClient.String()}
+	db.Create(&account)
+	led := Project{TenantID: tenant.ID, Name: "Led", AccountID: account.ID, LeadID: &lead.ID}
+	unled := Project{TenantID: tenant.ID, Name: "Unled", AccountID: account.ID}
+	db.Create(&led)
+	db.Create(&unled)
+	rate := Rate{TenantID: tenant.ID, Name: "Standard", Amount: 100}
+	db.Create(&rate)
+	ledCode := BillingCode{TenantID: tenant.ID, Name: "Led", Code: "LED", ProjectID: led.ID, RateID: rate.ID, RoundedTo: 15}
+	unledCode := BillingCode{TenantID: tenant.ID, Name: "Unled", Code: "UNL", ProjectID: unled.ID, RateID: rate.ID, RoundedTo: 15}
+	db.Create(&ledCode)
+	db.Create(&unledCode)
+
+	wednesday := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	if monday := WeekStart(time.Date(2025, 3, 9, 23, 0, 0, 0, time.UTC)); !monday.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected Sunday to fall in the week starting Monday the 3rd, got %s", monday)
+	}
+	var entryIDs []uint
+	for i, code := range []BillingCode{ledCode, ledCode, unledCode} {
+		start := wednesday.AddDate(0, 0, i).Add(9 * time.Hour)
+		entry := Entry{TenantID: tenant.ID, ProjectID: code.ProjectID, BillingCodeID: code.ID, EmployeeID: employee.ID,
+			Start: start, End: start.Add(4 * time.Hour), State: EntryStateUnaffiliated.String()}
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+		entryIDs = append(entryIDs, entry.ID)
+	}
+
+	timesheet, err := app.SubmitTimesheet(tenant.ID, employee.ID, wednesday)
+	if err != nil {
+		t.Fatalf("Failed to submit timesheet: %v", err)
+	}
+	if timesheet.ApproverID == nil || *timesheet.ApproverID != lead.ID || timesheet.TotalHours != 12 {
+		t.Errorf("Expected 12 hours routed to the lead of the busiest project, got %.2f to %v", timesheet.TotalHours, timesheet.ApproverID)
+	}
+	if err := app.CheckTimesheetOpen(tenant.ID, employee.ID, wednesday.Add(10*time.Hour)); !errors.Is(err, ErrTimesheetLocked) {
+		t.Errorf("Expected the submitted week to be locked, got %v", err)
+	}
+	if err := app.CheckTimesheetOpen(tenant.ID, employee.ID, wednesday.AddDate(0, 0, 7)); err != nil {
+		t.Errorf("Expected the next week to be open, got %v", err)
+	}
+	if _, err := app.ApproveTimesheet(tenant.ID, timesheet.ID, outsider.ID, false); !errors.Is(err, ErrNotTimesheetApprover) {
+		t.Errorf("Expected an outsider's approval to be refused, got %v", err)
+	}
+	if _, err := app.ApproveTimesheet(tenant.ID, timesheet.ID, employee.ID, false); !errors.Is(err, ErrNotTimesheetApprover) {
+		t.Errorf("Expected approving your own timesheet to be refused, got %v", err)
+	}
+
+	// The manager can also review it, and returns it with a comment for the employee
+	returned, err := app.ReturnTimesheet(tenant.ID, timesheet.ID, manager.ID, false, "Friday belongs on the internal code")
+	if err != nil {
+		t.Fatalf("Failed to return timesheet: %v", err)
+	}
+	if returned.State != TimesheetStateReturned.String() || len(notices) != 1 ||
+		!strings.HasPrefix(notices[0], "casey@example.com") || !strings.Contains(notices[0], "Friday belongs on the internal code") {
+		t.Errorf("Expected the employee to be sent the return comment, got %s and %v", returned.State, notices)
+	}
+	if err := app.CheckTimesheetOpen(tenant.ID, employee.ID, wednesday); err != nil {
+		t.Errorf("Expected the returned week to be unlocked, got %v", err)
+	}
+
+	if _, err := app.SubmitTimesheet(tenant.ID, employee.ID, wednesday); err != nil {
+		t.Fatalf("Failed to resubmit timesheet: %v", err)
+	}
+	approved, err := app.ApproveTimesheet(tenant.ID, timesheet.ID, lead.ID, false)
+	if err != nil {
+		t.Fatalf("Failed to approve timesheet: %v", err)
+	}
+	if approved.State != TimesheetStateApproved.String() || approved.ReturnComment != "" {
+		t.Errorf("Expected an approved timesheet with the comment cleared, got %s %q", approved.State, approved.ReturnComment)
+	}
+	var approvedEntries int64
+	db.Model(&Entry{}).Where("id IN ? AND state = ?", entryIDs, EntryStateApproved.String()).Count(&approvedEntries)
+	if approvedEntries != 3 {
+		t.Errorf("Expected the week's 3 entries approved, got %d", approvedEntries)
+	}
+	if _, err := app.ApproveTimesheet(tenant.ID, timesheet.ID, lead.ID, false); !errors.Is(err, ErrInvalidTimesheetTransition) {
+		t.Errorf("Expected approving twice to be refused, got %v", err)
+	}
+}